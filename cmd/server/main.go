@@ -2,24 +2,40 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors" // เพิ่ม import errors สำหรับ ErrorHandler
 	"log"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
-	"api-genarator/internal/api"      // <-- เปลี่ยน dynamic-api-project เป็นชื่อโมดูลของคุณ
+	"api-genarator/internal/api" // <-- เปลี่ยน dynamic-api-project เป็นชื่อโมดูลของคุณ
+	"api-genarator/internal/auth"
+	"api-genarator/internal/core"
 	"api-genarator/internal/database" // <-- เปลี่ยน dynamic-api-project เป็นชื่อโมดูลของคุณ
 	"api-genarator/internal/models"   // <-- เปลี่ยน dynamic-api-project เป็นชื่อโมดูลของคุณ
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors" // Add this import
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	// "github.com/gofiber/fiber/v2/middleware/logger" // ย้ายไปใส่ใน routes.go หรือใส่ที่นี่ก็ได้
 	"os/signal"
-    "syscall"
+	"syscall"
 )
 
+// version is the release version reported by GET /version, set at build time with
+// -ldflags "-X main.version=1.4.0".
+var version = "dev"
+
 func main() {
+	// --- Instance Identity (prefixes every log line) ---
+	instanceID := os.Getenv("INSTANCE_ID")
+	if instanceID == "" {
+		instanceID = api.DefaultInstanceID()
+	}
+	log.SetPrefix("[" + instanceID + "] ")
+
 	// --- Configuration ---
 	// Consider adding a configuration file option in addition to environment variables
 	// For example, you could check for a config.json file first, then fall back to env vars
@@ -44,6 +60,59 @@ func main() {
 	}
 	listenAddr := ":" + serverPort
 
+	// Server-level settings used by flow actions (signed URLs, object storage)
+	core.Configure(core.Settings{
+		PublicBaseURL: os.Getenv("PUBLIC_BASE_URL"),
+		URLSigningKey: os.Getenv("URL_SIGNING_KEY"),
+		S3: core.S3Settings{
+			Region:          os.Getenv("S3_REGION"),
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("S3_SESSION_TOKEN"),
+		},
+		JWTKeys:   parseKeyList(os.Getenv("JWT_KEYS"), os.Getenv("JWT_SECRET")),
+		JWTIssuer: os.Getenv("JWT_ISSUER"),
+		CaptchaSecrets: map[string]string{
+			"recaptcha": os.Getenv("RECAPTCHA_SECRET"),
+			"hcaptcha":  os.Getenv("HCAPTCHA_SECRET"),
+			"turnstile": os.Getenv("TURNSTILE_SECRET"),
+		},
+		WebhookSecrets:   parseKeyList(os.Getenv("WEBHOOK_SECRETS"), ""), // "stripe=whsec_...,github=..."
+		SFTPTargets:      parseSFTPTargets(os.Getenv("SFTP_TARGETS")),
+		LDAPDirectories:  parseLDAPDirectories(os.Getenv("LDAP_DIRECTORIES")),
+		PaymentProviders: parsePaymentProviders(os.Getenv("PAYMENT_PROVIDERS")),
+		Push: core.PushSettings{
+			FCMCredentialsFile: os.Getenv("FCM_CREDENTIALS_FILE"),
+			APNsKeyFile:        os.Getenv("APNS_KEY_FILE"),
+			APNsKeyID:          os.Getenv("APNS_KEY_ID"),
+			APNsTeamID:         os.Getenv("APNS_TEAM_ID"),
+			APNsTopic:          os.Getenv("APNS_TOPIC"),
+			APNsSandbox:        os.Getenv("APNS_SANDBOX") == "true",
+		},
+		SMSProviders:   parseSMSProviders(os.Getenv("SMS_PROVIDERS")),
+		OTPSecret:      os.Getenv("OTP_SECRET"),
+		GeoIPDatabases: parseGeoIPDatabases(os.Getenv("GEOIP_DB")), // "/data/GeoLite2-City.mmdb,/data/GeoLite2-ASN.mmdb"
+		Environment:    os.Getenv("APP_ENV"),
+		FeatureFlags: core.FeatureFlagSettings{
+			ProviderURL:   os.Getenv("FEATURE_FLAG_PROVIDER_URL"), // OFREP service; empty uses /api-generator/flags
+			ProviderToken: os.Getenv("FEATURE_FLAG_PROVIDER_TOKEN"),
+			TenantField:   os.Getenv("FEATURE_FLAG_TENANT_FIELD"),
+		},
+		Slow: core.SlowThresholds{ // Defaults: request 2000ms, step 1000ms, query 500ms
+			Request: envMillis("SLOW_REQUEST_MS"),
+			Step:    envMillis("SLOW_STEP_MS"),
+			Query:   envMillis("SLOW_QUERY_MS"),
+		},
+		I18n: core.I18nSettings{
+			DefaultLocale: os.Getenv("I18N_DEFAULT_LOCALE"),
+			LocaleField:   os.Getenv("I18N_LOCALE_FIELD"),
+		},
+		Debug: os.Getenv("DEBUG") == "true",
+	})
+
+	database.SetQueryObserver(core.ObserveQuery) // Query timings for GET /api-generator/slow-report
+
 	// --- Database Connection ---
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second) // เพิ่มเวลา timeout เล็กน้อย
 	defer cancel()
@@ -59,21 +128,273 @@ func main() {
 		}
 	}()
 
+	// --- Flow Cache (cacheGet/cacheSet) ---
+	if redisURL := os.Getenv("REDIS_URL"); redisURL != "" {
+		flowCache, err := core.NewRedisCache(ctx, redisURL)
+		if err != nil {
+			log.Printf("WARN: Could not connect to Redis, flow cache stays in-memory: %v", err)
+		} else {
+			core.SetCache(flowCache)
+			log.Println("INFO: Flow cache is backed by Redis.")
+		}
+	}
+
 	// --- Load Initial APIs ---
 	loadCtx, loadCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer loadCancel()
-	initialAPIs, err := store.LoadAPIs(loadCtx)
+	strictLoad := os.Getenv("STRICT_LOAD") == "true"
+	if err := store.EnsureIndexes(loadCtx); err != nil {
+		if strictLoad {
+			log.Fatalf("FATAL: STRICT_LOAD is enabled and api-definitions indexes could not be ensured: %v", err)
+		}
+		log.Printf("WARN: Could not ensure api-definitions indexes, uniqueness falls back to best-effort checks: %v", err)
+	}
+	initialAPIs, rejectedAPIs, err := store.LoadAPIs(loadCtx)
 	if err != nil {
+		if strictLoad {
+			log.Fatalf("FATAL: STRICT_LOAD is enabled and API definitions could not be loaded: %v", err)
+		}
 		log.Printf("ERROR: Failed to load initial APIs: %v. Server starting with potentially empty routes.", err)
 		if initialAPIs == nil {
 			initialAPIs = make(map[string]models.ApiDefinition) // Ensure map is not nil
 		}
 	}
 
+	// FLOW_COMPILATION=eager (default) checks every flow now; lazy defers it to the first request
+	compileMode := os.Getenv("FLOW_COMPILATION")
+	if compileMode != api.CompileLazy {
+		compileStart := time.Now()
+		initialAPIs, rejectedAPIs = api.CheckLoadedDefinitions(initialAPIs, rejectedAPIs)
+		api.LogCompileSummary(len(initialAPIs), time.Since(compileStart))
+	}
+	if len(rejectedAPIs) > 0 {
+		if strictLoad {
+			for _, rejected := range rejectedAPIs {
+				log.Printf("ERROR: Rejected API definition '%s' (%s): %s", rejected.Name, rejected.Key, strings.Join(rejected.Reasons, "; "))
+			}
+			log.Fatalf("FATAL: STRICT_LOAD is enabled and %d API definitions were rejected (including duplicate route keys)", len(rejectedAPIs))
+		}
+		log.Printf("WARN: %d API definitions were rejected during load, see GET /api-generator/load-report", len(rejectedAPIs))
+	}
+
 	// --- Initialize Handler ---
 	apiHandler := api.NewHandler(store, initialAPIs)
+	apiHandler.SetBuildInfo(api.NewBuildInfo(version))
+	if err := apiHandler.ConfigureCompilation(compileMode); err != nil {
+		log.Fatalf("FATAL: Invalid FLOW_COMPILATION: %v", err)
+	}
+	if compileMode == api.CompileLazy {
+		log.Printf("INFO: Lazy flow compilation, %d definitions are checked on their first request", len(initialAPIs))
+	}
+	if notifyCfg := notificationConfig(); notifyCfg != nil {
+		apiHandler.ConfigureNotifications(*notifyCfg) // Before the load report, so load rejections are announced
+		log.Println("INFO: Definition lifecycle notifications enabled")
+	}
+	apiHandler.SetLoadReport(api.LoadReport{LoadedAt: time.Now().UTC(), Loaded: len(initialAPIs), Rejected: rejectedAPIs})
+	if trusted := os.Getenv("TRUSTED_PROXIES"); trusted != "" {
+		proxyHeader := os.Getenv("PROXY_HEADER")
+		if proxyHeader == "" {
+			proxyHeader = fiber.HeaderXForwardedFor
+		}
+		if err := apiHandler.ConfigureProxies(proxyHeader, strings.Split(trusted, ",")); err != nil {
+			log.Fatalf("FATAL: Invalid TRUSTED_PROXIES: %v", err)
+		}
+		log.Printf("INFO: Resolving client IP from '%s' behind trusted proxies: %s", proxyHeader, trusted)
+	}
+
+	// --- Access Log ---
+	sampleRate, _ := strconv.ParseFloat(os.Getenv("ACCESS_LOG_SAMPLE_RATE"), 64)
+	err = apiHandler.ConfigureAccessLog(api.AccessLogConfig{
+		Format:     os.Getenv("ACCESS_LOG_FORMAT"),                     // text (default) or json
+		Fields:     strings.Split(os.Getenv("ACCESS_LOG_FIELDS"), ","), // e.g. "time,ip,method,route,api,status,latency,bytesOut"
+		SampleRate: sampleRate,
+		Disabled:   os.Getenv("ACCESS_LOG") == "off",
+	})
+	if err != nil {
+		log.Fatalf("FATAL: Invalid access log configuration: %v", err)
+	}
+
+	indexCtx, indexCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	apiHandler.PrepareCollections(indexCtx)
+	indexCancel()
+
+	// --- Cluster (several instances sharing the definitions collection) ---
+	clusterCfgCtx, clusterCfgCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err = apiHandler.ConfigureCluster(clusterCfgCtx, api.ClusterConfig{
+		InstanceID:   instanceID,
+		Region:       os.Getenv("INSTANCE_REGION"),
+		Invalidation: os.Getenv("CLUSTER_INVALIDATION"), // none (default), changestream or redis
+		RedisURL:     os.Getenv("REDIS_URL"),
+	})
+	clusterCfgCancel()
+	if err != nil {
+		log.Fatalf("FATAL: Invalid cluster configuration: %v", err)
+	}
+	clusterCtx, stopCluster := context.WithCancel(context.Background())
+	defer stopCluster()
+	go apiHandler.RunCluster(clusterCtx)
+
+	// --- Lookup tables ($lookup.<name>[<key>]), feature flags, message catalogs, the error catalog and consumer keys, reloaded from the database ---
+	lookupInterval, _ := strconv.Atoi(os.Getenv("LOOKUP_REFRESH_SECONDS"))
+	if lookupInterval <= 0 {
+		lookupInterval = 60
+	}
+	go apiHandler.RefreshLookupTables(clusterCtx, time.Duration(lookupInterval)*time.Second)
+	go apiHandler.RefreshMessageCatalogs(clusterCtx, time.Duration(lookupInterval)*time.Second)
+	go apiHandler.RefreshErrorCatalog(clusterCtx, time.Duration(lookupInterval)*time.Second)
+	go apiHandler.RefreshConsumers(clusterCtx, time.Duration(lookupInterval)*time.Second)
+	if !core.RemoteFeatureFlags() {
+		go apiHandler.RefreshFeatureFlags(clusterCtx, time.Duration(lookupInterval)*time.Second)
+	}
+
+	// --- Scheduled definition backups to object storage (S3_* settings; GCS with S3_ENDPOINT=https://storage.googleapis.com and HMAC keys) ---
+	// Set BACKUP_BUCKET on one instance only, every instance with it set runs its own schedule.
+	if bucket := os.Getenv("BACKUP_BUCKET"); bucket != "" {
+		backupCfg := api.BackupConfig{Bucket: bucket, Prefix: os.Getenv("BACKUP_PREFIX"), Interval: 24 * time.Hour, Keep: 30}
+		if backupCfg.Prefix == "" {
+			backupCfg.Prefix = "api-generator/backups/"
+		}
+		if minutes, err := strconv.Atoi(os.Getenv("BACKUP_INTERVAL_MINUTES")); err == nil && minutes > 0 {
+			backupCfg.Interval = time.Duration(minutes) * time.Minute
+		}
+		if keep, err := strconv.Atoi(os.Getenv("BACKUP_KEEP")); err == nil && keep > 0 {
+			backupCfg.Keep = keep
+		}
+		if days, err := strconv.Atoi(os.Getenv("BACKUP_MAX_AGE_DAYS")); err == nil && days > 0 {
+			backupCfg.MaxAge = time.Duration(days) * 24 * time.Hour
+		}
+		if err := apiHandler.ConfigureBackups(backupCfg); err != nil {
+			log.Fatalf("FATAL: Invalid backup configuration: %v", err)
+		}
+		go apiHandler.RunBackups(clusterCtx)
+		log.Printf("INFO: Backing up API definitions to %s/%s every %s (keeping %d)", bucket, backupCfg.Prefix, backupCfg.Interval, backupCfg.Keep)
+	}
+
+	// --- Limits of streaming GETs (Tail definitions) ---
+	tailPerClient, _ := strconv.Atoi(os.Getenv("TAIL_MAX_PER_CLIENT"))
+	tailTotal, _ := strconv.Atoi(os.Getenv("TAIL_MAX_TOTAL"))
+	tailMinutes, _ := strconv.Atoi(os.Getenv("TAIL_MAX_MINUTES"))
+	if err := apiHandler.ConfigureTails(api.TailConfig{
+		MaxPerClient: tailPerClient,
+		MaxTotal:     tailTotal,
+		MaxDuration:  time.Duration(tailMinutes) * time.Minute,
+	}); err != nil {
+		log.Fatalf("FATAL: Invalid tail configuration: %v", err)
+	}
+
+	// --- Read-through fallback for definitions created on other instances ---
+	if os.Getenv("READ_THROUGH_DEFINITIONS") == "true" {
+		negativeTTL, _ := strconv.Atoi(os.Getenv("READ_THROUGH_NEGATIVE_TTL"))
+		apiHandler.ConfigureReadThrough(true, time.Duration(negativeTTL)*time.Second)
+		log.Println("INFO: Route cache misses fall back to a database lookup (read-through)")
+	}
+
+	// --- Operator Login (OIDC) for the management API ---
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		scopes := strings.Fields(os.Getenv("OIDC_SCOPES"))
+		if len(scopes) == 0 {
+			scopes = []string{"email", "profile"}
+		}
+		redirectURL := os.Getenv("OIDC_REDIRECT_URL")
+		oidcCtx, oidcCancel := context.WithTimeout(context.Background(), 15*time.Second)
+		provider, err := auth.NewOIDCProvider(oidcCtx, auth.OIDCConfig{
+			Issuer:       issuer,
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  redirectURL,
+			Scopes:       scopes,
+		})
+		oidcCancel()
+		if err != nil {
+			log.Fatalf("FATAL: Could not set up OIDC provider '%s': %v", issuer, err)
+		}
+		sessionTTL, _ := strconv.Atoi(os.Getenv("OIDC_SESSION_TTL"))
+		err = apiHandler.ConfigureManagementAuth(api.ManagementAuthConfig{
+			Provider:    provider,
+			GroupsClaim: os.Getenv("OIDC_GROUPS_CLAIM"),
+			RoleMapping: parseKeyList(os.Getenv("OIDC_ROLE_MAP"), ""), // "idp-group=admin,other-group=viewer"
+			SessionKey:  []byte(os.Getenv("SESSION_KEY")),
+			SessionTTL:  time.Duration(sessionTTL) * time.Second,
+			Secure:      strings.HasPrefix(redirectURL, "https://"),
+		})
+		if err != nil {
+			log.Fatalf("FATAL: Invalid operator login configuration: %v", err)
+		}
+		log.Printf("INFO: Management API requires operator login via %s", issuer)
+	} else {
+		log.Println("WARN: OIDC_ISSUER not set, the management API (/api-generator/*) is unauthenticated")
+	}
+
+	// --- Guardrails (a pathological definition must not take down the process) ---
+	err = apiHandler.ConfigureGuardrails(api.GuardrailConfig{Pprof: os.Getenv("PPROF") == "true"}) // Limits come with the runtime config below
+	if err != nil {
+		log.Fatalf("FATAL: Invalid guardrail configuration: %v", err)
+	}
+	if err := apiHandler.ConfigureSeeding(os.Getenv("SEED_ENABLED") == "true", os.Getenv("APP_ENV")); err != nil {
+		log.Fatalf("FATAL: Invalid seeding configuration: %v", err)
+	}
+
+	// --- Runtime Configuration (CONFIG_FILE overrides these env values; reloaded on SIGHUP) ---
+	maxConcurrent, _ := strconv.Atoi(os.Getenv("MAX_CONCURRENT_REQUESTS"))
+	concurrencyQueueMs, _ := strconv.Atoi(os.Getenv("CONCURRENCY_QUEUE_MS"))
+	maxInFlight, _ := strconv.Atoi(os.Getenv("MAX_IN_FLIGHT_REQUESTS"))
+	maxResponseBytes, _ := strconv.Atoi(os.Getenv("MAX_RESPONSE_BYTES"))
+	maxFindDocuments, _ := strconv.ParseInt(os.Getenv("MAX_FIND_DOCUMENTS"), 10, 64)
+	cacheMaxEntries, _ := strconv.Atoi(os.Getenv("CACHE_MAX_ENTRIES"))
+	err = apiHandler.ConfigureRuntime(api.RuntimeConfig{
+		LogLevel:              os.Getenv("LOG_LEVEL"),
+		MaxConcurrentRequests: maxConcurrent,
+		ConcurrencyQueueMs:    concurrencyQueueMs,
+		MaxInFlightRequests:   maxInFlight,
+		MaxResponseBytes:      maxResponseBytes,
+		MaxFindDocuments:      maxFindDocuments,
+		CacheMaxEntries:       cacheMaxEntries,
+		CORS: api.CORSConfig{
+			AllowOrigins:     "*", // Allow all origins
+			AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS,PATCH",
+			AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-Environment,X-Consumer-Key",
+			AllowCredentials: false, // Must stay false with the wildcard origin
+			ExposeHeaders:    "Content-Length,Link,X-Next-Cursor,X-Save-Result,X-Modified-Fields,X-Environment,X-Definition-Checksum,Deprecation,Sunset",
+			MaxAge:           86400, // 24 hours
+		},
+	}, os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatalf("FATAL: Invalid runtime configuration: %v", err)
+	}
+
+	// --- Approval Workflow ---
+	if os.Getenv("APPROVAL_REQUIRED") == "true" {
+		apiHandler.ConfigureApproval(true)
+		log.Println("INFO: Definition changes require approval before they are published")
+	}
+
+	// --- Async Jobs ---
+	jobWorkers, _ := strconv.Atoi(os.Getenv("JOB_WORKERS"))
+	jobQueueSize, _ := strconv.Atoi(os.Getenv("JOB_QUEUE_SIZE"))
+	apiHandler.ConfigureJobs(jobWorkers, jobQueueSize) // Zero values fall back to the defaults
+	jobsCtx, jobsCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	apiHandler.RecoverJobs(jobsCtx)
+	jobsCancel()
+
+	if os.Getenv("MAINTENANCE_MODE") == "true" {
+		retryAfter, _ := strconv.Atoi(os.Getenv("MAINTENANCE_RETRY_AFTER"))
+		apiHandler.SetMaintenance(models.MaintenanceConfig{
+			Enabled:    true,
+			Message:    os.Getenv("MAINTENANCE_MESSAGE"),
+			RetryAfter: retryAfter,
+		})
+		log.Println("WARN: Starting in maintenance mode, dynamic APIs will return 503")
+	}
+
+	// --- Outbox Dispatcher (webhook events saved with data) ---
+	dispatcherCtx, stopDispatcher := context.WithCancel(context.Background())
+	defer stopDispatcher()
+	go core.RunOutboxDispatcher(dispatcherCtx, store, 2*time.Second)
 
 	// --- Create Fiber App ---
+	// --- Error Format (PROBLEM_JSON=true: RFC 7807 application/problem+json) ---
+	problemJSON := os.Getenv("PROBLEM_JSON") == "true"
+
 	app := fiber.New(fiber.Config{
 		BodyLimit: 10 * 1024 * 1024, // 10 MB
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
@@ -87,10 +408,13 @@ func main() {
 			}
 
 			// Log the full error internally for debugging
-			log.Printf("ERROR Handler: Path=%s, Error=%v", c.Path(), err)
+			log.Printf("ERROR Handler: Path=%s, RequestID=%s, Error=%v", c.Path(), core.RequestID(c.Context()), err)
 
 			// Send JSON error response
 			// Avoid sending detailed internal errors to the client in production
+			if problemJSON {
+				return api.SendProblem(c, code, message)
+			}
 			return c.Status(code).JSON(fiber.Map{
 				"error": message,
 			})
@@ -99,43 +423,228 @@ func main() {
 
 	// --- Middleware ---
 	app.Use(recover.New()) // Recover from panics
+	if problemJSON {
+		app.Use(api.ProblemDetails(os.Getenv("PROBLEM_TYPE_BASE"))) // e.g. "https://errors.example.com" -> type ".../ORDER_NOT_FOUND"
+		log.Println("INFO: Error responses use application/problem+json.")
+	}
 
-	// Add CORS middleware
-	app.Use(cors.New(cors.Config{
-		AllowOrigins:     "*", // Allow all origins
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS,PATCH",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
-		AllowCredentials: false, // Set to false when using wildcard origin
-		ExposeHeaders:    "Content-Length",
-		MaxAge:           86400, // 24 hours
-	}))
+	// Add CORS middleware (settings from the runtime configuration, reloadable)
+	app.Use(apiHandler.CORS())
 
 	// --- Register Routes ---
 	api.RegisterRoutes(app, apiHandler) // Pass the app and handler
 
-	// --- Start Server ---
-	log.Printf("INFO: Starting Fiber server on address %s", listenAddr)
-	if err := app.Listen(listenAddr); err != nil {
-		log.Fatalf("FATAL: Failed to start server: %v", err)
+	// --- gRPC Gateway (same pipeline as HTTP, see GET /api-generator/grpc.proto) ---
+	var grpcServer *http.Server
+	if grpcAddr := os.Getenv("GRPC_ADDR"); grpcAddr != "" {
+		grpcServer = apiHandler.NewGRPCServer(grpcAddr, app)
+		go func() {
+			log.Printf("INFO: Starting gRPC gateway on address %s", grpcAddr)
+			if err := grpcServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("ERROR: gRPC gateway stopped: %v", err)
+			}
+		}()
+	}
+
+	// --- MQTT Ingestion Bridge (topic -> definition) ---
+	if broker := os.Getenv("MQTT_BROKER"); broker != "" {
+		clientID := os.Getenv("MQTT_CLIENT_ID")
+		if clientID == "" {
+			clientID = "api-generator-" + instanceID
+		}
+		keepAlive, _ := strconv.Atoi(os.Getenv("MQTT_KEEPALIVE"))
+		err := apiHandler.ConfigureMQTT(api.MQTTConfig{
+			Broker:        broker,
+			ClientID:      clientID,
+			Username:      os.Getenv("MQTT_USERNAME"),
+			Password:      os.Getenv("MQTT_PASSWORD"),
+			KeepAlive:     time.Duration(keepAlive) * time.Second,
+			Subscriptions: parseMQTTSubscriptions(os.Getenv("MQTT_SUBSCRIPTIONS")),
+		})
+		if err != nil {
+			log.Fatalf("FATAL: Invalid MQTT bridge configuration: %v", err)
+		}
+		mqttCtx, stopMQTT := context.WithCancel(context.Background())
+		defer stopMQTT()
+		go apiHandler.RunMQTTBridge(mqttCtx, app)
 	}
 
 	// --- Graceful Shutdown ---
-	// Add graceful shutdown handling with OS signals
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	
+	// Stop accepting requests on SIGINT/SIGTERM; app.Listen then returns below
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 	go func() {
-		<-c
+		<-sigCh
 		log.Println("INFO: Graceful shutdown initiated...")
+		apiHandler.CloseTails() // Streaming GETs would otherwise hold their connections open
 		// Give active connections time to finish
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
-		
 		if err := app.ShutdownWithContext(ctx); err != nil {
 			log.Printf("ERROR: Server shutdown failed: %v", err)
 		}
-		
-		log.Println("INFO: Server shutdown complete")
-		os.Exit(0)
+		if grpcServer != nil {
+			if err := grpcServer.Shutdown(ctx); err != nil {
+				log.Printf("ERROR: gRPC gateway shutdown failed: %v", err)
+			}
+		}
 	}()
+
+	// --- Config Reload (SIGHUP, or POST /api-generator/config/reload) ---
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	go func() {
+		for range hupCh {
+			log.Println("INFO: SIGHUP received, reloading configuration...")
+			if _, err := apiHandler.ReloadRuntimeConfig(); err != nil {
+				log.Printf("ERROR: Config reload failed, keeping the current settings: %v", err)
+			}
+		}
+	}()
+
+	// --- Start Server ---
+	log.Printf("INFO: Starting Fiber server on address %s", listenAddr)
+	if err := app.Listen(listenAddr); err != nil {
+		log.Fatalf("FATAL: Failed to start server: %v", err)
+	}
+
+	// Finish async jobs and flush write-behind buffers before the database connection is closed
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer flushCancel()
+	if err := apiHandler.Shutdown(flushCtx); err != nil {
+		log.Printf("ERROR: %v", err)
+	}
+	log.Println("INFO: Server shutdown complete")
+}
+
+// notificationConfig reads the lifecycle notification channels; nil when none is set.
+func notificationConfig() *api.NotificationConfig {
+	cfg := api.NotificationConfig{
+		WebhookURL:      os.Getenv("NOTIFY_WEBHOOK_URL"),
+		SlackWebhookURL: os.Getenv("NOTIFY_SLACK_WEBHOOK_URL"),
+		Email: api.EmailConfig{
+			SMTPAddr: os.Getenv("SMTP_ADDR"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("NOTIFY_EMAIL_FROM"),
+		},
+	}
+	if to := os.Getenv("NOTIFY_EMAIL_TO"); to != "" {
+		cfg.Email.To = strings.Split(to, ",")
+	}
+	if events := os.Getenv("NOTIFY_EVENTS"); events != "" {
+		cfg.Events = strings.Split(events, ",") // e.g. "definition.created,definition.rejected"
+	}
+	if cfg.WebhookURL == "" && cfg.SlackWebhookURL == "" && len(cfg.Email.To) == 0 {
+		return nil
+	}
+	return &cfg
+}
+
+// parseMQTTSubscriptions parses "topic=api-name:qos,..." (qos defaults to 0), e.g.
+// "sensors/+/temperature=ingest-temperature:1".
+func parseMQTTSubscriptions(list string) []api.MQTTSubscription {
+	var subs []api.MQTTSubscription
+	for _, entry := range strings.Split(list, ",") {
+		topic, target, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			continue
+		}
+		sub := api.MQTTSubscription{Topic: topic, API: target}
+		if name, qos, found := strings.Cut(target, ":"); found {
+			n, _ := strconv.Atoi(qos)
+			sub.API, sub.QoS = name, byte(n)
+		}
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+// parseSFTPTargets reads the named SFTP targets of the fileDrop action from JSON, e.g.
+// {"partner": {"addr": "sftp.example.com:22", "user": "feed", "privateKeyFile": "/keys/feed", "hostKey": "ssh-ed25519 AAAA...", "dir": "/inbound"}}.
+func parseSFTPTargets(raw string) map[string]core.SFTPTarget {
+	if raw == "" {
+		return nil
+	}
+	var targets map[string]core.SFTPTarget
+	if err := json.Unmarshal([]byte(raw), &targets); err != nil {
+		log.Fatalf("FATAL: Invalid SFTP_TARGETS: %v", err)
+	}
+	return targets
+}
+
+// parseLDAPDirectories reads the named directories of the ldapLookup action from JSON, e.g.
+// {"default": {"url": "ldaps://dc1.corp.example.com", "bindDn": "svc-api@corp.example.com", "bindPassword": "...", "baseDn": "DC=corp,DC=example,DC=com"}}.
+func parseLDAPDirectories(raw string) map[string]core.LDAPDirectory {
+	if raw == "" {
+		return nil
+	}
+	var directories map[string]core.LDAPDirectory
+	if err := json.Unmarshal([]byte(raw), &directories); err != nil {
+		log.Fatalf("FATAL: Invalid LDAP_DIRECTORIES: %v", err)
+	}
+	return directories
+}
+
+// parsePaymentProviders reads the named provider accounts of the payment action from JSON, e.g.
+// {"default": {"apiKey": "sk_live_...", "webhookSecret": "whsec_..."}}.
+func parsePaymentProviders(raw string) map[string]core.PaymentProvider {
+	if raw == "" {
+		return nil
+	}
+	var providers map[string]core.PaymentProvider
+	if err := json.Unmarshal([]byte(raw), &providers); err != nil {
+		log.Fatalf("FATAL: Invalid PAYMENT_PROVIDERS: %v", err)
+	}
+	return providers
+}
+
+// parseSMSProviders reads the named gateway accounts of the sendSms action from JSON, e.g.
+// {"default": {"accountSid": "AC...", "authToken": "...", "from": "+15550100"}}.
+func parseSMSProviders(raw string) map[string]core.SMSProvider {
+	if raw == "" {
+		return nil
+	}
+	var providers map[string]core.SMSProvider
+	if err := json.Unmarshal([]byte(raw), &providers); err != nil {
+		log.Fatalf("FATAL: Invalid SMS_PROVIDERS: %v", err)
+	}
+	return providers
+}
+
+// parseGeoIPDatabases reads a comma-separated list of MaxMind DB files and checks that
+// they exist, so a wrong path fails at startup rather than on the first lookup.
+func parseGeoIPDatabases(raw string) []string {
+	var paths []string
+	for _, path := range strings.Split(raw, ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			log.Fatalf("FATAL: Invalid GEOIP_DB: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// parseKeyList parses "name=secret,name2=secret2" into a map, registering defaultKey (if set) as "default".
+func parseKeyList(list, defaultKey string) map[string]string {
+	keys := make(map[string]string)
+	if defaultKey != "" {
+		keys["default"] = defaultKey
+	}
+	for _, entry := range strings.Split(list, ",") {
+		name, secret, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if found && name != "" && secret != "" {
+			keys[name] = secret
+		}
+	}
+	return keys
+}
+
+// envMillis reads a duration in milliseconds; unset or invalid values give 0 (the default).
+func envMillis(name string) time.Duration {
+	ms, _ := strconv.Atoi(os.Getenv(name))
+	return time.Duration(ms) * time.Millisecond
 }
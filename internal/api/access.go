@@ -0,0 +1,125 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/netip"
+	"strings"
+
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// proxyConfig describes which upstream proxies are trusted to report the client IP.
+type proxyConfig struct {
+	header  string         // e.g. "X-Forwarded-For" or "X-Real-Ip"; empty disables header handling
+	trusted []netip.Prefix // Proxies allowed to set the header
+}
+
+// ConfigureProxies sets the header carrying the client IP and the proxies trusted to set it.
+// Entries may be plain IPs or CIDRs. With no trusted proxies the header is ignored.
+func (h *Handler) ConfigureProxies(header string, trusted []string) error {
+	cfg := proxyConfig{header: header}
+	for _, entry := range trusted {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return fmt.Errorf("invalid trusted proxy '%s': %w", entry, err)
+			}
+			addr = addr.Unmap()
+			entry = netip.PrefixFrom(addr, addr.BitLen()).String()
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy '%s': %w", entry, err)
+		}
+		cfg.trusted = append(cfg.trusted, prefix.Masked())
+	}
+	h.proxies = cfg
+	return nil
+}
+
+func (p proxyConfig) trusts(ip netip.Addr) bool {
+	for _, prefix := range p.trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the real client address. When the direct peer is a trusted proxy,
+// the proxy header is walked from right to left and the first untrusted hop is the client;
+// everything left of it could have been supplied by the client and is ignored.
+func (h *Handler) clientIP(c *fiber.Ctx) string {
+	remote := c.Context().RemoteIP().String()
+	if h.proxies.header == "" || len(h.proxies.trusted) == 0 {
+		return remote
+	}
+	remoteAddr, err := netip.ParseAddr(remote)
+	if err != nil || !h.proxies.trusts(remoteAddr.Unmap()) {
+		return remote
+	}
+
+	hops := strings.Split(c.Get(h.proxies.header), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		addr, err := netip.ParseAddr(hop)
+		if err != nil {
+			break // Malformed entry: stop trusting the chain
+		}
+		if !h.proxies.trusts(addr.Unmap()) {
+			return addr.Unmap().String()
+		}
+	}
+	return remote
+}
+
+// ipAllowed applies a definition's access control lists to an IP.
+// Deny entries win over allow entries; an empty allow list allows everyone not denied.
+func ipAllowed(ac *models.AccessControl, ipStr string) bool {
+	if ac == nil || (len(ac.AllowCidrs) == 0 && len(ac.DenyCidrs) == 0) {
+		return true
+	}
+	ip, err := netip.ParseAddr(ipStr)
+	if err != nil {
+		log.Printf("WARN: Cannot parse client IP '%s' for access control, denying", ipStr)
+		return false
+	}
+	ip = ip.Unmap() // Treat ::ffff:a.b.c.d as IPv4
+
+	if matchesAny(ac.DenyCidrs, ip) {
+		return false
+	}
+	if len(ac.AllowCidrs) == 0 {
+		return true
+	}
+	return matchesAny(ac.AllowCidrs, ip)
+}
+
+// matchesAny reports whether ip falls within any of the CIDRs (plain IPs are accepted too).
+func matchesAny(cidrs []string, ip netip.Addr) bool {
+	for _, entry := range cidrs {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			if addr, err := netip.ParseAddr(entry); err == nil && addr.Unmap() == ip {
+				return true
+			}
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			log.Printf("WARN: Ignoring invalid CIDR '%s' in access control", entry)
+			continue
+		}
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
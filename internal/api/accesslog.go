@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"api-genarator/internal/core"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// apiNameLocal is the fiber Locals key holding the name of the definition serving a request.
+const apiNameLocal = "apiName"
+
+// accessLogFields are the fields an access log line can carry.
+var accessLogFields = map[string]bool{
+	"time": true, "ip": true, "port": true, "method": true, "path": true, "route": true, "api": true,
+	"status": true, "latency": true, "bytesIn": true, "bytesOut": true, "userAgent": true, "instance": true,
+	"requestId": true, "traceparent": true,
+}
+
+// defaultAccessLogFields are used in JSON and key=value lines when no fields are chosen.
+var defaultAccessLogFields = []string{"time", "ip", "method", "path", "route", "api", "status", "latency", "bytesOut", "requestId"}
+
+// AccessLogConfig configures the request log written to stdout.
+type AccessLogConfig struct {
+	Format     string   // "text" (default) or "json" (one object per line, for ELK/Datadog)
+	Fields     []string // Fields to write; empty keeps the classic text line or the defaults for JSON
+	SampleRate float64  // Fraction of successful requests to log (0 logs all); errors are always logged
+	Disabled   bool
+}
+
+// ConfigureAccessLog validates and sets the access log format. Call before serving.
+func (h *Handler) ConfigureAccessLog(cfg AccessLogConfig) error {
+	switch cfg.Format {
+	case "":
+		cfg.Format = "text"
+	case "text", "json":
+	default:
+		return fmt.Errorf("unknown access log format '%s' (use text or json)", cfg.Format)
+	}
+	var fields []string
+	for _, f := range cfg.Fields {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		if !accessLogFields[f] {
+			return fmt.Errorf("unknown access log field '%s'", f)
+		}
+		fields = append(fields, f)
+	}
+	cfg.Fields = fields
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		return fmt.Errorf("access log sample rate must be between 0 and 1, got %v", cfg.SampleRate)
+	}
+	if cfg.Format == "json" && len(cfg.Fields) == 0 {
+		cfg.Fields = defaultAccessLogFields
+	}
+	h.accessLog = cfg
+	return nil
+}
+
+// logAccess is the request logging middleware registered by RegisterRoutes.
+func (h *Handler) logAccess(c *fiber.Ctx) error {
+	cfg := h.accessLog
+	if cfg.Disabled {
+		return c.Next()
+	}
+	start := time.Now()
+	if err := c.Next(); err != nil {
+		// Let the ErrorHandler write the response now, so the logged status and size are final
+		if handlerErr := c.App().Config().ErrorHandler(c, err); handlerErr != nil {
+			_ = c.SendStatus(http.StatusInternalServerError)
+		}
+	}
+	status := c.Response().StatusCode()
+	if status < http.StatusBadRequest && cfg.SampleRate > 0 && cfg.SampleRate < 1 && rand.Float64() >= cfg.SampleRate {
+		return nil
+	}
+
+	if cfg.Format != "json" && len(cfg.Fields) == 0 {
+		fmt.Fprintf(os.Stdout, "[%s]:%s %d - %s %s %s\n", h.clientIP(c), c.Port(), status, c.Method(), c.Path(), core.RequestID(c.Context()))
+		return nil
+	}
+	values := make(map[string]interface{}, len(cfg.Fields))
+	for _, f := range cfg.Fields {
+		values[f] = h.accessLogValue(c, f, status, start)
+	}
+	if cfg.Format == "json" {
+		line, err := json.Marshal(values)
+		if err == nil {
+			os.Stdout.Write(append(line, '\n'))
+		}
+		return nil
+	}
+	var b strings.Builder
+	for i, f := range cfg.Fields {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%s=%v", f, values[f])
+	}
+	b.WriteByte('\n')
+	os.Stdout.WriteString(b.String())
+	return nil
+}
+
+func (h *Handler) accessLogValue(c *fiber.Ctx, field string, status int, start time.Time) interface{} {
+	switch field {
+	case "time":
+		return start.UTC().Format(time.RFC3339Nano)
+	case "ip":
+		return h.clientIP(c)
+	case "port":
+		return c.Port()
+	case "method":
+		return c.Method()
+	case "path":
+		return c.Path()
+	case "route":
+		return c.Route().Path
+	case "api":
+		name, _ := c.Locals(apiNameLocal).(string)
+		return name
+	case "status":
+		return status
+	case "latency":
+		return float64(time.Since(start).Microseconds()) / 1000 // Milliseconds
+	case "bytesIn":
+		return len(c.Request().Body())
+	case "bytesOut":
+		return len(c.Response().Body())
+	case "userAgent":
+		return c.Get(fiber.HeaderUserAgent)
+	case "instance":
+		return h.cluster.cfg.InstanceID
+	case "requestId":
+		return core.RequestID(c.Context())
+	case "traceparent":
+		corr, _ := core.CorrelationFrom(c.Context())
+		return corr.TraceParent
+	}
+	return nil
+}
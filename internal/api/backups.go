@@ -0,0 +1,215 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxBackupBytes bounds the bundle read back for a restore.
+const maxBackupBytes = 256 << 20
+
+// BackupConfig configures scheduled exports of the definitions to object storage
+// (S3_* settings; GCS through https://storage.googleapis.com with HMAC keys).
+type BackupConfig struct {
+	Bucket   string
+	Prefix   string        // Object key prefix, e.g. "api-generator/backups/"
+	Interval time.Duration // Time between backups (default 24h)
+	Keep     int           // Newest backups kept (default 30)
+	MaxAge   time.Duration // Older backups are deleted even within Keep (0 = no age limit)
+}
+
+// backupBundle is the object written for every backup.
+type backupBundle struct {
+	ExportedAt  time.Time              `json:"exportedAt"`
+	Instance    string                 `json:"instance"`
+	Count       int                    `json:"count"`
+	Definitions []models.ApiDefinition `json:"definitions"`
+}
+
+// backupState holds the backup configuration (empty bucket = disabled).
+type backupState struct {
+	cfg BackupConfig
+}
+
+// ConfigureBackups enables backups. Run RunBackups on one instance only, or several
+// instances will export the same catalog.
+func (h *Handler) ConfigureBackups(cfg BackupConfig) error {
+	if cfg.Bucket == "" {
+		return errors.New("backup bucket is required")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 24 * time.Hour
+	}
+	if cfg.Keep <= 0 {
+		cfg.Keep = 30
+	}
+	if cfg.Prefix != "" && !strings.HasSuffix(cfg.Prefix, "/") {
+		cfg.Prefix += "/"
+	}
+	h.backups.cfg = cfg
+	return nil
+}
+
+// RunBackups exports the definitions every interval until ctx is cancelled.
+func (h *Handler) RunBackups(ctx context.Context) {
+	ticker := time.NewTicker(h.backups.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := h.backupDefinitions(ctx); err != nil {
+				log.Printf("ERROR: Scheduled backup of API definitions failed: %v", err)
+			}
+		}
+	}
+}
+
+// backupDefinitions writes one bundle and then applies the retention policy.
+func (h *Handler) backupDefinitions(ctx context.Context) (core.ObjectInfo, error) {
+	cfg := h.backups.cfg
+	exportCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	defs, err := h.store.ListAPIDefinitions(exportCtx)
+	if err != nil {
+		return core.ObjectInfo{}, fmt.Errorf("export definitions: %w", err)
+	}
+	now := time.Now().UTC()
+	body, err := json.Marshal(backupBundle{ExportedAt: now, Instance: h.InstanceID(), Count: len(defs), Definitions: defs})
+	if err != nil {
+		return core.ObjectInfo{}, fmt.Errorf("encode bundle: %w", err)
+	}
+	key := cfg.Prefix + "definitions-" + now.Format("20060102T150405Z") + ".json" // Sorts by time
+	if err := core.PutObject(exportCtx, cfg.Bucket, key, body, fiber.MIMEApplicationJSON); err != nil {
+		return core.ObjectInfo{}, err
+	}
+	log.Printf("INFO: Backed up %d API definitions to %s/%s (%d bytes)", len(defs), cfg.Bucket, key, len(body))
+	h.pruneBackups(exportCtx, now)
+	return core.ObjectInfo{Key: key, Size: int64(len(body)), LastModified: now}, nil
+}
+
+// pruneBackups deletes backups beyond Keep or older than MaxAge. The newest backup is always kept.
+func (h *Handler) pruneBackups(ctx context.Context, now time.Time) {
+	cfg := h.backups.cfg
+	backups, err := h.listBackups(ctx)
+	if err != nil {
+		log.Printf("WARN: Could not list backups for retention: %v", err)
+		return
+	}
+	for i, b := range backups { // Newest first
+		if i == 0 || (i < cfg.Keep && (cfg.MaxAge <= 0 || now.Sub(b.LastModified) <= cfg.MaxAge)) {
+			continue
+		}
+		if err := core.DeleteObject(ctx, cfg.Bucket, b.Key); err != nil {
+			log.Printf("WARN: Could not delete expired backup %s: %v", b.Key, err)
+			continue
+		}
+		log.Printf("INFO: Deleted expired backup %s/%s", cfg.Bucket, b.Key)
+	}
+}
+
+// listBackups returns the backup bundles, newest first.
+func (h *Handler) listBackups(ctx context.Context) ([]core.ObjectInfo, error) {
+	objects, err := core.ListObjects(ctx, h.backups.cfg.Bucket, h.backups.cfg.Prefix+"definitions-")
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key > objects[j].Key })
+	if objects == nil {
+		objects = []core.ObjectInfo{}
+	}
+	return objects, nil
+}
+
+// backupsEnabled answers 404 when no backup bucket is configured.
+func (h *Handler) backupsEnabled(c *fiber.Ctx) bool {
+	if h.backups.cfg.Bucket != "" {
+		return true
+	}
+	c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Backups are not configured (BACKUP_BUCKET)"})
+	return false
+}
+
+// ListBackups lists the backup bundles in object storage, newest first
+func (h *Handler) ListBackups(c *fiber.Ctx) error {
+	if !h.backupsEnabled(c) {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 30*time.Second)
+	defer cancel()
+	backups, err := h.listBackups(ctx)
+	if err != nil {
+		log.Printf("ERROR: Handler failed to list backups: %v", err)
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{"error": "Failed to list backups: " + err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   backups,
+	})
+}
+
+// CreateBackup runs a backup now, outside the schedule
+func (h *Handler) CreateBackup(c *fiber.Ctx) error {
+	if !h.backupsEnabled(c) {
+		return nil
+	}
+	backup, err := h.backupDefinitions(c.Context())
+	if err != nil {
+		log.Printf("ERROR: Backup requested by %s failed: %v", actorName(c), err)
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{"error": "Backup failed: " + err.Error()})
+	}
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusCreated,
+		"data":   backup,
+	})
+}
+
+// RestoreBackup handles POST /api-generator/backups/:name/restore: the bundle is imported
+// as a snapshot and restored like one, so the current catalog is snapshotted first.
+func (h *Handler) RestoreBackup(c *fiber.Ctx) error {
+	if !h.backupsEnabled(c) {
+		return nil
+	}
+	name := c.Params("name")
+	if !strings.HasPrefix(name, "definitions-") || strings.Contains(name, "/") {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "name must be a backup name from GET /api-generator/backups, e.g. definitions-20260101T000000Z.json"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 2*time.Minute)
+	defer cancel()
+
+	key := h.backups.cfg.Prefix + name
+	body, err := core.GetObject(ctx, h.backups.cfg.Bucket, key, maxBackupBytes)
+	if err != nil {
+		log.Printf("ERROR: Could not read backup %s: %v", key, err)
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{"error": "Failed to read backup: " + err.Error()})
+	}
+	var bundle backupBundle
+	if err := json.Unmarshal(body, &bundle); err != nil {
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Backup is not a definitions bundle: " + err.Error()})
+	}
+	if len(bundle.Definitions) != bundle.Count {
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{"error": fmt.Sprintf("Backup is incomplete: %d of %d definitions", len(bundle.Definitions), bundle.Count)})
+	}
+	snapshot, err := h.store.ImportSnapshot(ctx, "backup "+name, actorName(c), bundle.Definitions)
+	if err != nil {
+		return sendSnapshotError(c, name, err)
+	}
+	log.Printf("INFO: Backup %s imported as snapshot %s, restoring", key, snapshot.ID.Hex())
+	return h.restoreSnapshot(ctx, c, snapshot.ID.Hex())
+}
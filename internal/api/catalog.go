@@ -0,0 +1,142 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CatalogEntry is the public description of one generated API. Storage details
+// (database, collection, flow) are left out.
+type CatalogEntry struct {
+	Name        string              `json:"name"`
+	Method      string              `json:"method"`
+	Endpoint    string              `json:"endpoint"`
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Parameters  []models.Parameter  `json:"parameters,omitempty"`
+	Examples    []models.ApiExample `json:"examples,omitempty"`
+	Contact     *models.ContactInfo `json:"contact,omitempty"`
+	Maintenance bool                `json:"maintenance,omitempty"`
+	Async       bool                `json:"async,omitempty"`
+	Deprecated  *models.Deprecation `json:"deprecated,omitempty"`
+}
+
+// sortedCachedAPIs returns the served definitions ordered by endpoint, then method.
+func (h *Handler) sortedCachedAPIs() []models.ApiDefinition {
+	apis := h.cachedAPIs()
+	sort.Slice(apis, func(i, j int) bool {
+		if apis[i].Endpoint != apis[j].Endpoint {
+			return apis[i].Endpoint < apis[j].Endpoint
+		}
+		return apis[i].Method < apis[j].Method
+	})
+	return apis
+}
+
+// GetCatalog lists the served APIs with their documentation (?q= filters by name, endpoint or summary)
+func (h *Handler) GetCatalog(c *fiber.Ctx) error {
+	q := c.Query("q")
+	entries := make([]CatalogEntry, 0)
+	for _, api := range h.sortedCachedAPIs() {
+		if q != "" && !containsFold(api.Name, q) && !containsFold(api.Endpoint, q) && !containsFold(api.Summary, q) {
+			continue
+		}
+		_, inMaintenance := h.activeMaintenance(api)
+		entries = append(entries, CatalogEntry{
+			Name:        api.Name,
+			Method:      api.Method,
+			Endpoint:    api.Endpoint,
+			Summary:     api.Summary,
+			Description: api.Description,
+			Parameters:  api.Parameters,
+			Examples:    api.Examples,
+			Contact:     api.Contact,
+			Maintenance: inMaintenance,
+			Async:       api.Async,
+			Deprecated:  api.Deprecated,
+		})
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   entries,
+	})
+}
+
+// GetOpenAPI exports the served APIs as an OpenAPI 3.0 document
+func (h *Handler) GetOpenAPI(c *fiber.Ctx) error {
+	return c.JSON(buildOpenAPI(h.sortedCachedAPIs(), c.BaseURL()))
+}
+
+// GetDocs serves a small browsable page over the catalog, including the examples
+func (h *Handler) GetDocs(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.SendString(docsPage)
+}
+
+// docsPage renders /catalog client-side; all values are inserted as text, never as HTML.
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API catalog</title>
+<style>
+body { font-family: sans-serif; margin: 2rem; max-width: 960px; }
+section { border-bottom: 1px solid #ddd; padding: 1rem 0; }
+.method { font-weight: bold; margin-right: .5rem; }
+pre { background: #f6f6f6; padding: .5rem; overflow-x: auto; }
+.muted { color: #666; }
+</style>
+</head>
+<body>
+<h1>API catalog</h1>
+<p class="muted">Machine-readable: <a href="openapi.json">openapi.json</a></p>
+<input id="q" placeholder="Filter" autofocus>
+<div id="apis"></div>
+<script>
+function el(tag, text, cls) {
+  const e = document.createElement(tag);
+  if (text !== undefined) e.textContent = text;
+  if (cls) e.className = cls;
+  return e;
+}
+function render(apis) {
+  const root = document.getElementById("apis");
+  root.replaceChildren();
+  for (const api of apis) {
+    const s = el("section");
+    const h = el("h2");
+    h.append(el("span", api.method, "method"), el("code", api.endpoint));
+    s.append(h, el("div", api.name, "muted"));
+    if (api.summary) s.append(el("p", api.summary));
+    if (api.description) s.append(el("p", api.description));
+    if (api.parameters) {
+      const ul = el("ul");
+      for (const p of api.parameters) ul.append(el("li", p.name + " (" + (p.type || "string") + (p.required ? ", required" : "") + ")"));
+      s.append(el("h3", "Parameters"), ul);
+    }
+    for (const ex of api.examples || []) {
+      s.append(el("h3", "Example: " + ex.name + (ex.status ? " (" + ex.status + ")" : "")));
+      if (ex.summary) s.append(el("p", ex.summary));
+      if (ex.request) s.append(el("div", "Request"), el("pre", JSON.stringify(ex.request, null, 2)));
+      if (ex.response !== undefined) s.append(el("div", "Response"), el("pre", JSON.stringify(ex.response, null, 2)));
+    }
+    if (api.contact) s.append(el("p", "Contact: " + [api.contact.name, api.contact.email, api.contact.url].filter(Boolean).join(", "), "muted"));
+    root.append(s);
+  }
+}
+async function load() {
+  const q = document.getElementById("q").value;
+  const res = await fetch("catalog?q=" + encodeURIComponent(q));
+  render((await res.json()).data || []);
+}
+document.getElementById("q").addEventListener("input", load);
+load();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,327 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"reflect"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// Cache invalidation modes for instances sharing one definitions collection.
+const (
+	InvalidationNone         = "none"
+	InvalidationChangeStream = "changestream"
+	InvalidationRedis        = "redis"
+)
+
+const (
+	clusterHeartbeat      = 10 * time.Second
+	clusterChannel        = "apigen:definitions" // Redis pub/sub channel for definition changes
+	watchRetryMaxInterval = time.Minute
+)
+
+// ClusterConfig describes how this instance cooperates with its peers.
+type ClusterConfig struct {
+	InstanceID   string // Identifies this process in logs, stats and the cluster view
+	Region       string // (Optional) Free-form location label
+	Invalidation string // InvalidationNone, InvalidationChangeStream or InvalidationRedis
+	RedisURL     string // Required for InvalidationRedis
+}
+
+// clusterState holds this instance's identity and the peer notification channel.
+type clusterState struct {
+	cfg       ClusterConfig
+	startedAt time.Time
+	redis     *redis.Client
+}
+
+// definitionNotice is published on Redis when this instance changes a definition.
+type definitionNotice struct {
+	Origin string `json:"origin"`
+	Name   string `json:"name"`
+}
+
+// DefaultInstanceID builds an instance ID from the hostname and process ID.
+func DefaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// ConfigureCluster sets the instance identity and connects the invalidation channel.
+func (h *Handler) ConfigureCluster(ctx context.Context, cfg ClusterConfig) error {
+	if cfg.InstanceID == "" {
+		cfg.InstanceID = DefaultInstanceID()
+	}
+	if cfg.Invalidation == "" {
+		cfg.Invalidation = InvalidationNone
+	}
+	state := clusterState{cfg: cfg, startedAt: time.Now().UTC()}
+
+	switch cfg.Invalidation {
+	case InvalidationNone, InvalidationChangeStream:
+	case InvalidationRedis:
+		if cfg.RedisURL == "" {
+			return errors.New("redis invalidation requires REDIS_URL")
+		}
+		opts, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			return fmt.Errorf("invalid redis url: %w", err)
+		}
+		client := redis.NewClient(opts)
+		if err := client.Ping(ctx).Err(); err != nil {
+			_ = client.Close()
+			return fmt.Errorf("failed to connect to redis: %w", err)
+		}
+		state.redis = client
+	default:
+		return fmt.Errorf("unknown invalidation mode '%s' (use none, changestream or redis)", cfg.Invalidation)
+	}
+	h.cluster = state
+	return nil
+}
+
+// InstanceID returns this instance's identity.
+func (h *Handler) InstanceID() string {
+	return h.cluster.cfg.InstanceID
+}
+
+// RunCluster publishes this instance's heartbeat and applies definition changes made
+// by peers until ctx is done. Call it once, after ConfigureCluster.
+func (h *Handler) RunCluster(ctx context.Context) {
+	switch h.cluster.cfg.Invalidation {
+	case InvalidationChangeStream:
+		go h.watchDefinitions(ctx)
+	case InvalidationRedis:
+		go h.subscribeDefinitions(ctx)
+	}
+	log.Printf("INFO: Instance '%s' running with '%s' cache invalidation", h.cluster.cfg.InstanceID, h.cluster.cfg.Invalidation)
+
+	ticker := time.NewTicker(clusterHeartbeat)
+	defer ticker.Stop()
+	for {
+		h.heartbeat(ctx)
+		select {
+		case <-ctx.Done():
+			removeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := h.store.RemoveInstance(removeCtx, h.cluster.cfg.InstanceID); err != nil {
+				log.Printf("WARN: Could not remove instance record: %v", err)
+			}
+			cancel()
+			if h.cluster.redis != nil {
+				_ = h.cluster.redis.Close()
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Handler) heartbeat(ctx context.Context) {
+	h.routesMutex.RLock()
+	routes := len(h.dynamicRoutes)
+	h.routesMutex.RUnlock()
+
+	hbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	host, _ := os.Hostname()
+	err := h.store.HeartbeatInstance(hbCtx, models.Instance{
+		ID:           h.cluster.cfg.InstanceID,
+		Hostname:     host,
+		PID:          os.Getpid(),
+		Region:       h.cluster.cfg.Region,
+		Invalidation: h.cluster.cfg.Invalidation,
+		Routes:       routes,
+		StartedAt:    h.cluster.startedAt,
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Printf("WARN: Instance heartbeat failed: %v", err)
+	}
+}
+
+// notifyDefinitionChange tells peers that a definition changed. With change streams
+// peers see the write itself, so only Redis invalidation publishes.
+func (h *Handler) notifyDefinitionChange(name string) {
+	if h.cluster.redis == nil {
+		return
+	}
+	payload, _ := json.Marshal(definitionNotice{Origin: h.cluster.cfg.InstanceID, Name: name})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := h.cluster.redis.Publish(ctx, clusterChannel, payload).Err(); err != nil {
+		log.Printf("WARN: Could not publish change of API '%s' to peers: %v", name, err)
+	}
+}
+
+// subscribeDefinitions applies changes published by peers on Redis.
+func (h *Handler) subscribeDefinitions(ctx context.Context) {
+	sub := h.cluster.redis.Subscribe(ctx, clusterChannel)
+	defer sub.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-sub.Channel():
+			if !ok {
+				return
+			}
+			var notice definitionNotice
+			if err := json.Unmarshal([]byte(msg.Payload), &notice); err != nil {
+				log.Printf("WARN: Ignoring malformed definition notice: %v", err)
+				continue
+			}
+			if notice.Origin == h.cluster.cfg.InstanceID {
+				continue
+			}
+			h.refreshDefinition(ctx, notice.Name)
+		}
+	}
+}
+
+// watchDefinitions follows the definitions change stream, reconnecting with backoff.
+// After a reconnect the whole cache is reloaded, since changes may have been missed.
+func (h *Handler) watchDefinitions(ctx context.Context) {
+	retry := time.Second
+	for attempt := 0; ctx.Err() == nil; attempt++ {
+		if attempt > 0 {
+			h.reloadRoutes(ctx)
+		}
+		err := h.store.WatchAPIDefinitions(ctx, h.applyDefinitionChange)
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, database.ErrConfigError) {
+			log.Printf("ERROR: Change stream invalidation unavailable, peers' changes will not be seen: %v", err)
+			return
+		}
+		log.Printf("WARN: Definition change stream stopped, retrying in %s: %v", retry, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		retry = min(retry*2, watchRetryMaxInterval)
+	}
+}
+
+// applyDefinitionChange updates the route cache from one change stream event.
+func (h *Handler) applyDefinitionChange(change database.DefinitionChange) {
+	if change.Op == database.DefinitionDeleted {
+		if key, found := h.findRouteKey(func(api models.ApiDefinition) bool { return api.ID == change.ID }); found {
+			h.removeRoute(key)
+			log.Printf("INFO: Removed route key '%s' deleted by a peer", key)
+		}
+		return
+	}
+	h.installPeerDefinition(*change.API)
+}
+
+// refreshDefinition re-reads a definition by name and updates (or removes) its route.
+func (h *Handler) refreshDefinition(ctx context.Context, name string) {
+	lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	api, err := h.store.GetAPIDefinitionByName(lookupCtx, name)
+	if errors.Is(err, database.ErrNotFound) {
+		if key, found := h.findRouteKey(func(cached models.ApiDefinition) bool { return cached.Name == name }); found {
+			h.removeRoute(key)
+			log.Printf("INFO: Removed route key '%s' for API '%s' deleted by a peer", key, name)
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR: Could not refresh API '%s' changed by a peer: %v", name, err)
+		return
+	}
+	h.installPeerDefinition(*api)
+}
+
+// installPeerDefinition swaps in a definition changed by a peer, moving its route if needed.
+func (h *Handler) installPeerDefinition(api models.ApiDefinition) {
+	oldKey, _ := h.findRouteKey(func(cached models.ApiDefinition) bool {
+		return cached.ID == api.ID || cached.Name == api.Name
+	})
+	key := api.Method + ":" + api.Endpoint
+	if problems := core.CheckDefinition(api); len(problems) > 0 {
+		log.Printf("WARN: API '%s' changed by a peer is invalid, not serving it: %v", api.Name, problems)
+		if oldKey != "" {
+			h.removeRoute(oldKey)
+		}
+		return
+	}
+	if handle, ok := h.acquireRoute(key); ok {
+		unchanged := reflect.DeepEqual(handle.api, api)
+		handle.release()
+		if unchanged && oldKey == key {
+			return // Our own write coming back through the change stream
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	h.prepareCollection(ctx, api)
+	cancel()
+	version := h.swapRoute(oldKey, key, api)
+	log.Printf("INFO: API '%s' updated by a peer (key '%s', version %d)", api.Name, key, version)
+}
+
+// findRouteKey returns the cache key of the first definition matching fn.
+func (h *Handler) findRouteKey(fn func(models.ApiDefinition) bool) (string, bool) {
+	h.routesMutex.RLock()
+	defer h.routesMutex.RUnlock()
+	for key, handle := range h.dynamicRoutes {
+		if fn(handle.api) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// reloadRoutes reloads every definition from the store into the cache.
+func (h *Handler) reloadRoutes(ctx context.Context) {
+	loadCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	apis, rejected, err := h.store.LoadAPIs(loadCtx)
+	if err != nil {
+		log.Printf("ERROR: Failed to reload API definitions: %v", err)
+		return
+	}
+	if !h.compile.lazy {
+		apis, rejected = CheckLoadedDefinitions(apis, rejected)
+	}
+	h.replaceRoutes(apis)
+	h.SetLoadReport(LoadReport{LoadedAt: time.Now().UTC(), Loaded: len(apis), Rejected: rejected})
+	log.Printf("INFO: Reloaded %d API definitions into the route cache", len(apis))
+}
+
+// GetCluster lists the instances sharing the definitions collection and their loaded route counts
+func (h *Handler) GetCluster(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	peers, err := h.store.ListInstances(ctx, time.Now().UTC().Add(-3*clusterHeartbeat))
+	if err != nil {
+		log.Printf("ERROR: Handler failed to list instances: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list instances"})
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data": fiber.Map{
+			"self":         h.cluster.cfg.InstanceID,
+			"invalidation": h.cluster.cfg.Invalidation,
+			"peers":        peers,
+		},
+	})
+}
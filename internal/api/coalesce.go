@@ -0,0 +1,129 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// capturedResponse is a copy of a finished response that can be replayed to other callers.
+type capturedResponse struct {
+	status      int
+	contentType string
+	body        []byte
+	ok          bool // false when the leader returned an error instead of writing a response
+}
+
+// inflightCall is one execution shared by all identical concurrent requests.
+type inflightCall struct {
+	done   chan struct{}
+	result capturedResponse
+	dups   int
+}
+
+// requestGroup coalesces calls with the same key (a minimal singleflight).
+type requestGroup struct {
+	mu    sync.Mutex
+	calls map[string]*inflightCall
+}
+
+// do runs fn once per key at a time; concurrent callers with the same key wait and
+// receive the same result. shared reports whether the result came from another caller.
+func (g *requestGroup) do(key string, fn func() capturedResponse) (result capturedResponse, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*inflightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		call.dups++
+		g.mu.Unlock()
+		<-call.done
+		return call.result, true
+	}
+	call := &inflightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.result = fn()
+	if call.dups > 0 {
+		log.Printf("DEBUG: Coalesced %d concurrent requests for key '%s'", call.dups, key)
+	}
+	return call.result, false
+}
+
+// coalesceKey normalizes a GET so that requests differing only in query parameter
+// order share a key. The Authorization header is part of the key so callers never
+// receive a response produced for different credentials, and Accept-Language so they
+// never receive one translated for another language.
+func coalesceKey(c *fiber.Ctx, api models.ApiDefinition) string {
+	query, _ := url.ParseQuery(string(c.Request().URI().QueryString()))
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(api.Name)
+	b.WriteString("|" + api.Database + "." + api.Collection) // Tenant-routed definitions never share results
+	b.WriteString("|")
+	b.WriteString(c.Path())
+	for _, name := range names {
+		values := query[name]
+		sort.Strings(values)
+		for _, v := range values {
+			b.WriteString("|" + url.QueryEscape(name) + "=" + url.QueryEscape(v))
+		}
+	}
+	if lang := c.Get(fiber.HeaderAcceptLanguage); lang != "" {
+		b.WriteString("|lang=" + strings.ToLower(strings.ReplaceAll(lang, " ", ""))) // $t() localizes per request
+	}
+	if auth := c.Get(fiber.HeaderAuthorization); auth != "" {
+		sum := sha256.Sum256([]byte(auth))
+		b.WriteString("|auth=" + hex.EncodeToString(sum[:8]))
+	}
+	return b.String()
+}
+
+// serveCoalesced executes a GET once for all identical concurrent requests and replays
+// the leader's response to the others.
+func (h *Handler) serveCoalesced(c *fiber.Ctx, api models.ApiDefinition) error {
+	var leaderErr error
+	result, shared := h.coalescer.do(coalesceKey(c, api), func() capturedResponse {
+		if leaderErr = h.processLimited(c, api); leaderErr != nil {
+			return capturedResponse{}
+		}
+		resp := c.Response()
+		return capturedResponse{
+			status:      resp.StatusCode(),
+			contentType: string(resp.Header.ContentType()),
+			body:        append([]byte(nil), resp.Body()...),
+			ok:          true,
+		}
+	})
+
+	if !shared {
+		return leaderErr
+	}
+	if !result.ok {
+		// The shared execution failed outside the normal response path; run this request on its own
+		return h.processLimited(c, api)
+	}
+	c.Set("X-Coalesced", "true")
+	c.Set(fiber.HeaderContentType, result.contentType)
+	return c.Status(result.status).Send(result.body)
+}
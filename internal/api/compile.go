@@ -0,0 +1,173 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Flow compilation modes. Compiling a definition runs the engine checks, which also
+// parse and cache its JSONPath expressions.
+const (
+	CompileEager = "eager" // At startup and reload: invalid definitions are rejected before serving (with STRICT_LOAD, startup fails)
+	CompileLazy  = "lazy"  // On the first request of each route: fast startup, the first caller pays the cost
+)
+
+// compileTiming is the last compilation of one definition.
+type compileTiming struct {
+	Name       string    `json:"name"`
+	Key        string    `json:"key"`
+	DurationMs float64   `json:"durationMs"`
+	CompiledAt time.Time `json:"compiledAt"`
+	Lazy       bool      `json:"lazy"`
+	Problems   int       `json:"problems"`
+}
+
+// compileTimings is shared by CheckLoadedDefinitions (no handler) and lazy compilation.
+var compileTimings = struct {
+	mu     sync.Mutex
+	byName map[string]compileTiming
+}{byName: make(map[string]compileTiming)}
+
+// compileState holds the compilation mode of the handler.
+type compileState struct {
+	lazy bool
+}
+
+// ConfigureCompilation selects eager (default) or lazy flow compilation. Call before
+// serving; in lazy mode skip CheckLoadedDefinitions for the initial definitions.
+func (h *Handler) ConfigureCompilation(mode string) error {
+	switch mode {
+	case "", CompileEager:
+		h.compile.lazy = false
+	case CompileLazy:
+		h.compile.lazy = true
+	default:
+		return fmt.Errorf("unknown flow compilation mode '%s' (use eager or lazy)", mode)
+	}
+	return nil
+}
+
+// compileDefinition checks a definition and records how long it took.
+func compileDefinition(key string, api models.ApiDefinition, lazy bool) []core.DefinitionProblem {
+	start := time.Now()
+	problems := core.CheckDefinition(api)
+	elapsed := time.Since(start)
+	compileTimings.mu.Lock()
+	compileTimings.byName[api.Name] = compileTiming{
+		Name:       api.Name,
+		Key:        key,
+		DurationMs: float64(elapsed.Microseconds()) / 1000,
+		CompiledAt: time.Now().UTC(),
+		Lazy:       lazy,
+		Problems:   len(problems),
+	}
+	compileTimings.mu.Unlock()
+	return problems
+}
+
+// compileOnFirstHit compiles a lazily loaded route once. An invalid definition is taken
+// out of the route cache and added to the load report, like an eager rejection.
+func (h *Handler) compileOnFirstHit(key string, handle *routeHandle) []core.DefinitionProblem {
+	handle.compileOnce.Do(func() {
+		handle.problems = compileDefinition(key, handle.api, true)
+		handle.compiled.Store(true)
+		if len(handle.problems) == 0 {
+			log.Printf("DEBUG: Compiled API '%s' on first request", handle.api.Name)
+			return
+		}
+		reasons := make([]string, 0, len(handle.problems))
+		for _, p := range handle.problems {
+			reasons = append(reasons, p.Path+": "+p.Message)
+		}
+		log.Printf("WARN: Rejecting API definition '%s' (%s) on first request: %v", handle.api.Name, key, reasons)
+		h.routesMutex.Lock()
+		if h.dynamicRoutes[key] == handle { // Not replaced by an update in the meantime
+			delete(h.dynamicRoutes, key)
+			h.routesAt = time.Now().UTC()
+			go drainRoute(handle)
+		}
+		h.routesMutex.Unlock()
+		h.loadReport.mu.Lock()
+		h.loadReport.report.Loaded--
+		h.loadReport.report.Rejected = append(h.loadReport.report.Rejected,
+			models.RejectedDefinition{ID: handle.api.ID.Hex(), Name: handle.api.Name, Key: key, Reasons: reasons})
+		h.loadReport.mu.Unlock()
+	})
+	return handle.problems
+}
+
+// LogCompileSummary reports the total and slowest compilation after an eager load.
+func LogCompileSummary(loaded int, elapsed time.Duration) {
+	slowest := compileReport(1)
+	if len(slowest) == 0 {
+		return
+	}
+	log.Printf("INFO: Compiled %d API definitions eagerly in %s (slowest: '%s', %.2fms)", loaded, elapsed.Round(time.Millisecond), slowest[0].Name, slowest[0].DurationMs)
+}
+
+// compileReport returns the recorded compilations, slowest first.
+func compileReport(limit int) []compileTiming {
+	compileTimings.mu.Lock()
+	timings := make([]compileTiming, 0, len(compileTimings.byName))
+	for _, t := range compileTimings.byName {
+		timings = append(timings, t)
+	}
+	compileTimings.mu.Unlock()
+	sort.Slice(timings, func(i, j int) bool {
+		if timings[i].DurationMs != timings[j].DurationMs {
+			return timings[i].DurationMs > timings[j].DurationMs
+		}
+		return timings[i].Name < timings[j].Name
+	})
+	if limit > 0 && len(timings) > limit {
+		timings = timings[:limit]
+	}
+	return timings
+}
+
+// GetCompileReport lists the compilation time per definition (?limit=50), slowest first.
+func (h *Handler) GetCompileReport(c *fiber.Ctx) error {
+	timings := compileReport(0)
+	compiled := len(timings)
+	var totalMs float64
+	for _, t := range timings {
+		totalMs += t.DurationMs
+	}
+	if limit := c.QueryInt("limit", 50); limit > 0 && len(timings) > limit {
+		timings = timings[:limit]
+	}
+	pending := 0
+	if h.compile.lazy {
+		h.routesMutex.RLock()
+		for _, handle := range h.dynamicRoutes {
+			if !handle.compiled.Load() {
+				pending++
+			}
+		}
+		h.routesMutex.RUnlock()
+	}
+	mode := CompileEager
+	if h.compile.lazy {
+		mode = CompileLazy
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data": fiber.Map{
+			"mode":        mode,
+			"pending":     pending, // Lazy mode: routes not requested yet
+			"compiled":    compiled,
+			"totalMs":     totalMs,
+			"definitions": timings,
+		},
+	})
+}
@@ -0,0 +1,399 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	headerConsumerKey = "X-Consumer-Key" // Names the registered consumer of a request
+	consumerKeyPrefix = "ck_"
+)
+
+// consumerState resolves consumer keys and counts calls per consumer and definition
+// until they are written to the store.
+type consumerState struct {
+	mu      sync.RWMutex
+	byHash  map[string]string // Key hash -> consumer name
+	usageMu sync.Mutex
+	pending map[consumerCall]*models.ConsumerUsage
+}
+
+type consumerCall struct{ consumer, api string }
+
+// consumerReportEntry lists the consumers of one definition.
+type consumerReportEntry struct {
+	API        string              `json:"api"`
+	Method     string              `json:"method,omitempty"`
+	Endpoint   string              `json:"endpoint,omitempty"`
+	Deprecated *models.Deprecation `json:"deprecated,omitempty"`
+	Removed    bool                `json:"removed,omitempty"` // Called in the past, no longer defined
+	Consumers  []reportedConsumer  `json:"consumers"`
+}
+
+type reportedConsumer struct {
+	Name          string              `json:"name"`
+	Contact       *models.ContactInfo `json:"contact,omitempty"`
+	Calls         int64               `json:"calls"`
+	FirstCalledAt time.Time           `json:"firstCalledAt"`
+	LastCalledAt  time.Time           `json:"lastCalledAt"`
+}
+
+func hashConsumerKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// newConsumerKey returns a key with its hash and the prefix shown in listings.
+func newConsumerKey() (key, hash, prefix string) {
+	key = consumerKeyPrefix + randomToken() + randomToken()
+	return key, hashConsumerKey(key), key[:len(consumerKeyPrefix)+6]
+}
+
+func (s *consumerState) setKeys(consumers []models.Consumer) {
+	byHash := make(map[string]string, len(consumers))
+	for _, consumer := range consumers {
+		byHash[consumer.KeyHash] = consumer.Name
+	}
+	s.mu.Lock()
+	s.byHash = byHash
+	s.mu.Unlock()
+}
+
+// putKey replaces the key of a consumer on this instance; an empty hash drops the consumer.
+func (s *consumerState) putKey(name, hash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for h, n := range s.byHash {
+		if n == name {
+			delete(s.byHash, h)
+		}
+	}
+	if hash == "" {
+		return
+	}
+	if s.byHash == nil {
+		s.byHash = map[string]string{}
+	}
+	s.byHash[hash] = name
+}
+
+func (s *consumerState) lookup(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	name, ok := s.byHash[hashConsumerKey(key)]
+	return name, ok
+}
+
+func (s *consumerState) record(consumer, api string, at time.Time) {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+	if s.pending == nil {
+		s.pending = map[consumerCall]*models.ConsumerUsage{}
+	}
+	call := consumerCall{consumer, api}
+	if u, ok := s.pending[call]; ok {
+		u.Calls++
+		u.LastCalledAt = at
+		return
+	}
+	s.pending[call] = &models.ConsumerUsage{Consumer: consumer, API: api, Calls: 1, FirstCalledAt: at, LastCalledAt: at}
+}
+
+// take removes the counted calls for writing.
+func (s *consumerState) take() []models.ConsumerUsage {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+	usage := make([]models.ConsumerUsage, 0, len(s.pending))
+	for _, u := range s.pending {
+		usage = append(usage, *u)
+	}
+	s.pending = nil
+	return usage
+}
+
+// restore puts back calls that could not be written, merged with the ones counted since.
+func (s *consumerState) restore(usage []models.ConsumerUsage) {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+	if s.pending == nil {
+		s.pending = map[consumerCall]*models.ConsumerUsage{}
+	}
+	for _, u := range usage {
+		call := consumerCall{u.Consumer, u.API}
+		if p, ok := s.pending[call]; ok {
+			p.Calls += u.Calls
+			p.FirstCalledAt = u.FirstCalledAt
+			continue
+		}
+		restored := u
+		s.pending[call] = &restored
+	}
+}
+
+// trackConsumer counts a call of the consumer named by the X-Consumer-Key header.
+// Unknown keys are ignored, registration does not gate access to the APIs.
+func (h *Handler) trackConsumer(c *fiber.Ctx, api models.ApiDefinition) {
+	key := c.Get(headerConsumerKey)
+	if key == "" {
+		return
+	}
+	name, ok := h.consumers.lookup(key)
+	if !ok {
+		log.Printf("DEBUG: Unknown consumer key on API '%s'", api.Name)
+		return
+	}
+	h.consumers.record(name, api.Name, time.Now().UTC())
+}
+
+// flushConsumerUsage writes the counted calls to the store.
+func (h *Handler) flushConsumerUsage(ctx context.Context) error {
+	usage := h.consumers.take()
+	if err := h.store.RecordConsumerUsage(ctx, usage); err != nil {
+		h.consumers.restore(usage)
+		return err
+	}
+	return nil
+}
+
+// RefreshConsumers writes the counted calls and reloads the consumer keys every
+// interval, so consumers registered through other instances are recognized.
+func (h *Handler) RefreshConsumers(ctx context.Context, interval time.Duration) {
+	refreshEvery(ctx, interval, "consumers", func(loadCtx context.Context) error {
+		flushErr := h.flushConsumerUsage(loadCtx)
+		consumers, err := h.store.ListConsumers(loadCtx)
+		if err == nil {
+			h.consumers.setKeys(consumers)
+		}
+		return errors.Join(flushErr, err)
+	})
+}
+
+// RegisterConsumer registers an API consumer and returns its key. The key is only
+// shown once, the store keeps a hash.
+func (h *Handler) RegisterConsumer(c *fiber.Ctx) error {
+	var consumer models.Consumer
+	if err := c.BodyParser(&consumer); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body", "details": err.Error()})
+	}
+	consumer.Name = strings.TrimSpace(consumer.Name)
+	if consumer.Name == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "name is required"})
+	}
+	if consumer.Contact == nil || (consumer.Contact.Email == "" && consumer.Contact.URL == "") {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "contact.email or contact.url is required, it is used to announce changes"})
+	}
+	key, hash, prefix := newConsumerKey()
+	consumer.KeyHash, consumer.KeyPrefix = hash, prefix
+	consumer.CreatedBy = actorName(c)
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+	if err := h.store.CreateConsumer(ctx, &consumer); err != nil {
+		if errors.Is(err, database.ErrConsumerExists) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Printf("ERROR: Handler failed to register consumer '%s': %v", consumer.Name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to register consumer"})
+	}
+	h.consumers.putKey(consumer.Name, hash)
+	log.Printf("INFO: Consumer '%s' registered by %s", consumer.Name, consumer.CreatedBy)
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"status":  "success",
+		"code":    http.StatusCreated,
+		"message": "Send the key in the " + headerConsumerKey + " header, it is not shown again",
+		"data":    fiber.Map{"consumer": consumer, "key": key},
+	})
+}
+
+// ListConsumers returns all registered consumers
+func (h *Handler) ListConsumers(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	consumers, err := h.store.ListConsumers(ctx)
+	if err != nil {
+		log.Printf("ERROR: Handler failed to list consumers: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list consumers"})
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   consumers,
+	})
+}
+
+// GetConsumer returns a consumer with the definitions it calls.
+func (h *Handler) GetConsumer(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	name := nameParam(c)
+	consumer, err := h.store.GetConsumer(ctx, name)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Consumer not found"})
+		}
+		log.Printf("ERROR: Handler failed to get consumer: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve consumer"})
+	}
+	if err := h.flushConsumerUsage(ctx); err != nil {
+		log.Printf("WARN: Could not write consumer usage, the report misses the latest calls: %v", err)
+	}
+	usage, err := h.store.ListConsumerUsage(ctx, bson.M{"consumer": name})
+	if err != nil {
+		log.Printf("ERROR: Handler failed to list usage of consumer '%s': %v", name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve consumer usage"})
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   fiber.Map{"consumer": consumer, "usage": usage},
+	})
+}
+
+// RotateConsumerKey issues a new key for a consumer; the old key stops being recognized.
+func (h *Handler) RotateConsumerKey(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	name := nameParam(c)
+	key, hash, prefix := newConsumerKey()
+	if err := h.store.SetConsumerKey(ctx, name, hash, prefix); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Consumer not found"})
+		}
+		log.Printf("ERROR: Handler failed to rotate key of consumer '%s': %v", name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to rotate consumer key"})
+	}
+	h.consumers.putKey(name, hash)
+	log.Printf("INFO: Key of consumer '%s' rotated by %s", name, actorName(c))
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"code":    http.StatusOK,
+		"message": "Send the key in the " + headerConsumerKey + " header, it is not shown again",
+		"data":    fiber.Map{"name": name, "key": key, "keyPrefix": prefix},
+	})
+}
+
+// DeleteConsumer removes a consumer and its usage history.
+func (h *Handler) DeleteConsumer(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	name := nameParam(c)
+	if err := h.store.DeleteConsumer(ctx, name); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Consumer not found"})
+		}
+		log.Printf("ERROR: Handler failed to delete consumer '%s': %v", name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete consumer"})
+	}
+	h.consumers.putKey(name, "")
+	log.Printf("INFO: Consumer '%s' deleted by %s", name, actorName(c))
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"code":    http.StatusOK,
+		"message": "Consumer deleted",
+	})
+}
+
+// GetConsumerReport lists the consumers of each definition, so owners know whom to
+// notify before a breaking change or a deprecation. ?api=<name> limits it to one
+// definition, ?deprecated=true to deprecated ones and ?since=<date> to consumers that
+// called after the date.
+func (h *Handler) GetConsumerReport(c *fiber.Ctx) error {
+	var since time.Time
+	if s := c.Query("since"); s != "" {
+		t, err := core.ParseDeprecationDate(s)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid since", "details": err.Error()})
+		}
+		since = t
+	}
+	apiName, onlyDeprecated := c.Query("api"), c.QueryBool("deprecated")
+
+	ctx, cancel := context.WithTimeout(c.Context(), 15*time.Second)
+	defer cancel()
+
+	if err := h.flushConsumerUsage(ctx); err != nil {
+		log.Printf("WARN: Could not write consumer usage, the report misses the latest calls: %v", err)
+	}
+	apis, err := h.store.ListAPIDefinitions(ctx)
+	if err != nil {
+		log.Printf("ERROR: Handler failed to list APIs for the consumer report: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to build consumer report"})
+	}
+	consumers, err := h.store.ListConsumers(ctx)
+	if err != nil {
+		log.Printf("ERROR: Handler failed to list consumers for the consumer report: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to build consumer report"})
+	}
+	filter := bson.M{}
+	if apiName != "" {
+		filter["api"] = apiName
+	}
+	if !since.IsZero() {
+		filter["lastCalledAt"] = bson.M{"$gte": since}
+	}
+	usage, err := h.store.ListConsumerUsage(ctx, filter)
+	if err != nil {
+		log.Printf("ERROR: Handler failed to list consumer usage: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to build consumer report"})
+	}
+
+	contacts := make(map[string]*models.ContactInfo, len(consumers))
+	for _, consumer := range consumers {
+		contacts[consumer.Name] = consumer.Contact
+	}
+	entries := map[string]*consumerReportEntry{}
+	for _, api := range apis {
+		if (apiName != "" && api.Name != apiName) || (onlyDeprecated && api.Deprecated == nil) {
+			continue
+		}
+		entries[api.Name] = &consumerReportEntry{API: api.Name, Method: api.Method, Endpoint: api.Endpoint, Deprecated: api.Deprecated, Consumers: []reportedConsumer{}}
+	}
+	for _, u := range usage {
+		entry, ok := entries[u.API]
+		if !ok {
+			if onlyDeprecated {
+				continue
+			}
+			entry = &consumerReportEntry{API: u.API, Removed: true, Consumers: []reportedConsumer{}}
+			entries[u.API] = entry
+		}
+		entry.Consumers = append(entry.Consumers, reportedConsumer{
+			Name:          u.Consumer,
+			Contact:       contacts[u.Consumer],
+			Calls:         u.Calls,
+			FirstCalledAt: u.FirstCalledAt,
+			LastCalledAt:  u.LastCalledAt,
+		})
+	}
+
+	report := make([]consumerReportEntry, 0, len(entries))
+	for _, entry := range entries {
+		report = append(report, *entry)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].API < report[j].API })
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   report,
+	})
+}
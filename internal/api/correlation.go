@@ -0,0 +1,16 @@
+package api
+
+import (
+	"api-genarator/internal/core"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// correlate accepts or generates the request's X-Request-Id and traceparent, returns the
+// ID to the client and makes both available to flows, outbound calls and logs.
+func correlate(c *fiber.Ctx) error {
+	corr := core.NewCorrelation(c.Get(fiber.HeaderXRequestID), c.Get("traceparent"))
+	core.StoreCorrelation(c.Context(), corr)
+	c.Set(fiber.HeaderXRequestID, corr.RequestID)
+	return c.Next()
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Cursor pagination of a default GET (?limit=50, then ?after=<token>&limit=50). Like
+// ?fields=, the parameters are reserved unless the definition declares a parameter of
+// the same name.
+const (
+	paramAfter = "after"
+	paramLimit = "limit"
+)
+
+const (
+	defaultCursorLimit = 50
+	maxCursorLimit     = 1000
+)
+
+// headerNextCursor carries the token of the next page; it is absent on the last page.
+const headerNextCursor = "X-Next-Cursor"
+
+var errInvalidCursor = errors.New("invalid or expired cursor in " + paramAfter)
+
+// cursorPage is a keyset page: documents sorted by the cursor field then _id, starting
+// after the position encoded in the token.
+type cursorPage struct {
+	Set   bool
+	Field string // Sort key, CursorField of the definition or "_id"
+	Limit int64
+	After *cursorPosition
+}
+
+// cursorPosition is the sort key and _id of the last document of the previous page.
+type cursorPosition struct {
+	Field string      `bson:"f"`
+	Value interface{} `bson:"v"`
+	ID    interface{} `bson:"id"`
+}
+
+// cursorField returns the sort key of cursor pagination for a definition.
+func cursorField(api models.ApiDefinition) string {
+	if api.CursorField != "" {
+		return api.CursorField
+	}
+	return "_id"
+}
+
+// cursorReserved reports whether param (?after= or ?limit=) pages the results of this definition.
+func cursorReserved(api models.ApiDefinition, param string) bool {
+	for _, p := range api.Parameters {
+		if p.Name == param {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCursorPage reads ?after= and ?limit=. Cursor pagination is used as soon as either
+// is sent; the first page is requested with ?limit= alone.
+func parseCursorPage(c *fiber.Ctx, api models.ApiDefinition) (cursorPage, error) {
+	page := cursorPage{Field: cursorField(api), Limit: defaultCursorLimit}
+	if v := c.Query(paramLimit); v != "" && cursorReserved(api, paramLimit) {
+		limit, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || limit <= 0 || limit > maxCursorLimit {
+			return page, fmt.Errorf("%s must be an integer between 1 and %d", paramLimit, maxCursorLimit)
+		}
+		page.Limit = limit
+		page.Set = true
+	}
+	if v := c.Query(paramAfter); v != "" && cursorReserved(api, paramAfter) {
+		after, err := decodeCursor(v)
+		if err != nil || after.Field != page.Field {
+			return page, errInvalidCursor
+		}
+		page.After = after
+		page.Set = true
+	}
+	return page, nil
+}
+
+// encodeCursor turns a position into an opaque token. Extended JSON keeps the BSON
+// types of the values, so dates and ObjectIDs compare correctly on the next page.
+func encodeCursor(pos cursorPosition) (string, error) {
+	raw, err := bson.MarshalExtJSON(pos, true, false)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeCursor(token string) (*cursorPosition, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	var pos cursorPosition
+	if err := bson.UnmarshalExtJSON(raw, true, &pos); err != nil {
+		return nil, err
+	}
+	if pos.ID == nil {
+		return nil, errInvalidCursor
+	}
+	return &pos, nil
+}
+
+// filter restricts filter to the documents after the cursor position. Documents
+// missing the cursor field sort first (as null); the field should hold a single type,
+// since range queries do not compare values of different types.
+func (p cursorPage) filter(filter bson.M) bson.M {
+	if p.After == nil {
+		return filter
+	}
+	var keyset bson.M
+	switch {
+	case p.Field == "_id":
+		keyset = bson.M{"_id": bson.M{"$gt": p.After.ID}}
+	case p.After.Value == nil:
+		keyset = bson.M{"$or": []bson.M{
+			{p.Field: nil, "_id": bson.M{"$gt": p.After.ID}},
+			{p.Field: bson.M{"$ne": nil}},
+		}}
+	default:
+		keyset = bson.M{"$or": []bson.M{
+			{p.Field: bson.M{"$gt": p.After.Value}},
+			{p.Field: p.After.Value, "_id": bson.M{"$gt": p.After.ID}},
+		}}
+	}
+	if len(filter) == 0 {
+		return keyset
+	}
+	return bson.M{"$and": []bson.M{filter, keyset}}
+}
+
+// apply sorts by the cursor key and reads one document more than the page, to tell
+// whether a next page exists. The sort key is added to a projection that leaves it out;
+// strip reports whether it has to be removed from the results again.
+func (p cursorPage) apply(opts *database.FindOptions) (strip bool) {
+	opts.Sort = bson.D{{Key: p.Field, Value: 1}}
+	if p.Field != "_id" {
+		opts.Sort = append(opts.Sort, bson.E{Key: "_id", Value: 1})
+	}
+	opts.Limit = p.Limit + 1
+	if len(opts.Projection) > 0 && p.Field != "_id" {
+		if fieldRoots(projectedFields(opts.Projection))[fieldRoot(p.Field)] {
+			return false
+		}
+		opts.Projection[p.Field] = 1
+		return true
+	}
+	return false
+}
+
+// next cuts results to the page and returns the token of the following page, or ""
+// when results was the last page (or cursor pagination is not used). With strip, the
+// sort key added by apply is removed once the token is built.
+func (p cursorPage) next(results []bson.M, strip bool) ([]bson.M, string, error) {
+	if !p.Set {
+		return results, "", nil
+	}
+	var token string
+	var err error
+	if int64(len(results)) > p.Limit {
+		results = results[:p.Limit]
+		last := results[len(results)-1]
+		token, err = encodeCursor(cursorPosition{Field: p.Field, Value: lookupField(last, p.Field), ID: last["_id"]})
+	}
+	if strip {
+		root := fieldRoot(p.Field)
+		for _, doc := range results {
+			delete(doc, root)
+		}
+	}
+	return results, token, err
+}
+
+// setNextCursor announces the next page in the X-Next-Cursor and Link headers, which
+// leaves the response body the same in every format.
+func setNextCursor(c *fiber.Ctx, page cursorPage, token string) {
+	if token == "" {
+		return
+	}
+	c.Set(headerNextCursor, token)
+	u, err := url.Parse(c.OriginalURL())
+	if err != nil {
+		return
+	}
+	q := u.Query()
+	q.Set(paramAfter, token)
+	q.Set(paramLimit, strconv.FormatInt(page.Limit, 10))
+	u.RawQuery = q.Encode()
+	c.Append(fiber.HeaderLink, "<"+c.BaseURL()+u.String()+`>; rel="next"`)
+}
+
+// lookupField returns the value of a dotted path in a document, nil when missing.
+func lookupField(doc bson.M, path string) interface{} {
+	var value interface{} = doc
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(bson.M)
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+func projectedFields(projection bson.M) []string {
+	fields := make([]string, 0, len(projection))
+	for field := range projection {
+		fields = append(fields, field)
+	}
+	return fields
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// saveAttempts is how often a failing save is tried before it is dead-lettered.
+const saveAttempts = 3
+
+// withRetry runs fn up to attempts times with a short linear backoff.
+func withRetry(ctx context.Context, attempts int, fn func() error) (int, error) {
+	var err error
+	for i := 1; i <= attempts; i++ {
+		if err = fn(); err == nil {
+			return i, nil
+		}
+		if errors.Is(err, database.ErrCircuitOpen) {
+			return i, err // Retrying cannot succeed until the breaker closes
+		}
+		if database.IsDocumentInvalid(err) {
+			return i, err // Nor until the document or the collection schema changes
+		}
+		if i < attempts {
+			select {
+			case <-time.After(time.Duration(i) * 100 * time.Millisecond):
+			case <-ctx.Done():
+				return i, err
+			}
+		}
+	}
+	return attempts, err
+}
+
+// deadLetter persists documents that could not be saved. It uses its own context so the
+// record is written even when the request context has already expired.
+func deadLetter(store *database.Store, source string, api models.ApiDefinition, docs []map[string]interface{}, attempts int, cause error) *models.DeadLetter {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	entry := &models.DeadLetter{
+		Source:     source,
+		APIName:    api.Name,
+		Database:   api.Database,
+		Collection: api.Collection,
+		UniqueKey:  api.UniqueKey,
+		ScopeKeys:  core.ScopeKeys(api.DataScope),
+		Documents:  docs,
+		Error:      cause.Error(),
+		Attempts:   attempts,
+	}
+	if err := store.InsertDeadLetter(ctx, entry); err != nil {
+		log.Printf("ERROR: Payload for API '%s' is lost, dead letter could not be written: %v", api.Name, err)
+		return nil
+	}
+	return entry
+}
+
+// saveWithRetry saves one document (with its outbox events, if any) and dead-letters it
+// when every attempt fails. The returned entry is nil unless the document was dead-lettered.
+func (h *Handler) saveWithRetry(ctx context.Context, api models.ApiDefinition, doc map[string]interface{}) (*database.SaveResult, *models.DeadLetter, error) {
+	var result *database.SaveResult
+	attempts, err := withRetry(ctx, saveAttempts, func() (err error) {
+		if len(api.Events) > 0 {
+			// Data and its events are written together (outbox pattern)
+			result, err = h.store.SaveDataWithOutbox(ctx, api.Database, api.Collection, api.UniqueKey, doc, core.BuildOutboxMessages(ctx, api, doc), core.ScopeKeys(api.DataScope)...)
+			return err
+		}
+		result, err = h.store.SaveData(ctx, api.Database, api.Collection, api.UniqueKey, doc, core.ScopeKeys(api.DataScope)...)
+		return err
+	})
+	if errors.Is(err, database.ErrCircuitOpen) {
+		return nil, nil, err // Nowhere to dead-letter to; the client is told to retry
+	}
+	if err != nil {
+		return nil, deadLetter(h.store, "save", api, []map[string]interface{}{doc}, attempts, err), err
+	}
+	return result, nil, nil
+}
+
+// ListDeadLetters lists dead letters (?status=pending|replayed, ?limit=50)
+func (h *Handler) ListDeadLetters(c *fiber.Ctx) error {
+	limit, err := strconv.ParseInt(c.Query("limit", "50"), 10, 64)
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	entries, err := h.store.ListDeadLetters(ctx, c.Query("status"), limit)
+	if err != nil {
+		log.Printf("ERROR: Handler failed to list dead letters: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list dead letters"})
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   entries,
+	})
+}
+
+// GetDeadLetter returns a single dead letter with its payload
+func (h *Handler) GetDeadLetter(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	entry, err := h.store.GetDeadLetter(ctx, c.Params("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Dead letter not found"})
+		}
+		log.Printf("ERROR: Handler failed to get dead letter: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve dead letter"})
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   entry,
+	})
+}
+
+// ReplayDeadLetter writes the payload of a dead letter to its original target again
+func (h *Handler) ReplayDeadLetter(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 30*time.Second)
+	defer cancel()
+
+	entry, err := h.store.GetDeadLetter(ctx, c.Params("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Dead letter not found"})
+		}
+		log.Printf("ERROR: Handler failed to get dead letter for replay: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve dead letter"})
+	}
+	if entry.Status == models.DeadLetterReplayed {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Dead letter was already replayed"})
+	}
+
+	replayErr := h.store.SaveBatch(ctx, entry.Database, entry.Collection, entry.UniqueKey, entry.Documents, entry.ScopeKeys...)
+	if err := h.store.MarkDeadLetterReplayed(ctx, entry.ID, replayErr); err != nil {
+		log.Printf("ERROR: Failed to update dead letter %s after replay: %v", entry.ID.Hex(), err)
+	}
+	if replayErr != nil {
+		log.Printf("ERROR: Replay of dead letter %s failed: %v", entry.ID.Hex(), replayErr)
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{"error": "Replay failed: " + replayErr.Error()})
+	}
+
+	log.Printf("INFO: Replayed dead letter %s (%d documents) for API '%s'", entry.ID.Hex(), len(entry.Documents), entry.APIName)
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"code":    http.StatusOK,
+		"message": "Dead letter replayed",
+	})
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"api-genarator/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// defaultSoftField holds the deletion time of soft-deleted documents.
+const defaultSoftField = "deletedAt"
+
+// errDeleteWithoutFilter rejects a default DELETE that does not identify any document.
+var errDeleteWithoutFilter = errors.New("DELETE requires parameters to identify data to delete")
+
+// softDeleteField returns the field marking soft-deleted documents, "" when the
+// definition deletes for real.
+func softDeleteField(api models.ApiDefinition) string {
+	if api.Delete == nil || !api.Delete.Soft {
+		return ""
+	}
+	if api.Delete.SoftField != "" {
+		return api.Delete.SoftField
+	}
+	return defaultSoftField
+}
+
+// excludeSoftDeleted hides soft-deleted documents from a default GET, unless the
+// request filters on the deletion field itself.
+func excludeSoftDeleted(filter bson.M, api models.ApiDefinition) bson.M {
+	field := softDeleteField(api)
+	if field == "" {
+		return filter
+	}
+	if _, ok := filter[field]; !ok {
+		filter[field] = bson.M{"$exists": false}
+	}
+	return filter
+}
+
+// deleteFilter builds the filter of a default DELETE from the request data. Without
+// parameters it fails: the scope alone would delete all of the caller's data.
+func deleteFilter(api models.ApiDefinition, data map[string]interface{}) (bson.M, error) {
+	if api.Delete != nil && api.Delete.Match == models.DeleteMatchKey {
+		value, ok := data[api.UniqueKey]
+		if !ok || value == nil || value == "" {
+			return nil, fmt.Errorf("DELETE requires the unique key '%s'", api.UniqueKey)
+		}
+		return bson.M{api.UniqueKey: value}, nil
+	}
+	filter := bson.M{}
+	for k, v := range data {
+		filter[k] = v
+	}
+	if len(filter) == 0 {
+		return nil, errDeleteWithoutFilter
+	}
+	return filter, nil
+}
+
+// deleteData removes the documents a default DELETE selected: at most one unless the
+// definition allows deleting by an arbitrary filter (match "many"), archived or
+// soft-deleted when configured.
+func (h *Handler) deleteData(ctx context.Context, api models.ApiDefinition, filter bson.M) (int64, error) {
+	opts := models.DeleteOptions{}
+	if api.Delete != nil {
+		opts = *api.Delete
+	}
+	many := opts.Match == models.DeleteMatchMany
+	switch {
+	case opts.ArchiveCollection != "":
+		return h.store.ArchiveData(ctx, api.Database, api.Collection, opts.ArchiveCollection, filter, many)
+	case opts.Soft:
+		return h.store.SoftDeleteData(ctx, api.Database, api.Collection, filter, softDeleteField(api), many)
+	case many:
+		return h.store.DeleteData(ctx, api.Database, api.Collection, filter)
+	}
+	return h.store.DeleteOneData(ctx, api.Database, api.Collection, filter)
+}
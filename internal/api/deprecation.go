@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// setDeprecationHeaders tells the client that the API it called is being retired:
+// Deprecation (RFC 9745), Sunset (RFC 8594) and a Link to the successor.
+func setDeprecationHeaders(c *fiber.Ctx, d *models.Deprecation) {
+	if since, err := core.ParseDeprecationDate(d.Since); err == nil {
+		c.Set("Deprecation", "@"+strconv.FormatInt(since.Unix(), 10))
+	}
+	if sunset, err := core.ParseDeprecationDate(d.SunsetDate); err == nil {
+		c.Set("Sunset", sunset.Format(http.TimeFormat))
+	}
+	if d.Successor != "" {
+		successor := d.Successor
+		if strings.HasPrefix(successor, "/") {
+			successor = c.BaseURL() + successor
+		}
+		c.Append(fiber.HeaderLink, "<"+successor+`>; rel="successor-version"`)
+	}
+}
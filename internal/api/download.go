@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// downloadReadTimeout bounds how long a single file download may keep streaming chunks.
+const downloadReadTimeout = 5 * time.Minute
+
+// serveDownload streams a GridFS file for definitions with Download options.
+// The definition's Collection is used as the GridFS bucket name (e.g. "fs").
+func (h *Handler) serveDownload(c *fiber.Ctx, api models.ApiDefinition, reqData map[string]interface{}) error {
+	if api.Download.RequireSignature {
+		query, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+		if err == nil {
+			err = core.VerifyLocalSignature(c.Path(), query, time.Now())
+		}
+		if err != nil {
+			log.Printf("WARN: Rejected download for API '%s': %v", api.Name, err)
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Invalid or expired download link"})
+		}
+	}
+
+	filter, err := buildDownloadFilter(api.Download, reqData)
+	if err != nil {
+		log.Printf("WARN: Invalid download request for API '%s': %v", api.Name, err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	stream, err := h.store.OpenFile(ctx, api.Database, api.Collection, filter)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "File not found"})
+		}
+		if errors.Is(err, database.ErrCircuitOpen) {
+			return sendUnavailable(c)
+		}
+		log.Printf("ERROR: Failed to open file for API '%s': %v", api.Name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to open file"})
+	}
+	_ = stream.SetReadDeadline(time.Now().Add(downloadReadTimeout))
+
+	file := stream.GetFile()
+	size := file.Length
+
+	disposition := api.Download.Disposition
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	c.Set(fiber.HeaderContentType, fileContentType(file.Name, file.Metadata))
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType(disposition, map[string]string{"filename": file.Name}))
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	c.Set(fiber.HeaderLastModified, file.UploadDate.UTC().Format(http.TimeFormat))
+
+	// Single byte-range support (bytes=start-end, bytes=start-, bytes=-suffix)
+	rangeHeader := c.Get(fiber.HeaderRange)
+	if rangeHeader == "" {
+		return c.Status(http.StatusOK).SendStream(stream, int(size))
+	}
+
+	start, end, ok := parseByteRange(rangeHeader, size)
+	if !ok {
+		_ = stream.Close()
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", size))
+		return c.SendStatus(http.StatusRequestedRangeNotSatisfiable)
+	}
+	if _, err := stream.Skip(start); err != nil {
+		_ = stream.Close()
+		log.Printf("ERROR: Failed to seek file for API '%s': %v", api.Name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to read file"})
+	}
+
+	length := end - start + 1
+	c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+	return c.Status(http.StatusPartialContent).SendStream(limitedReadCloser{io.LimitReader(stream, length), stream}, int(length))
+}
+
+// buildDownloadFilter resolves the files filter either from the ID parameter or the filter template.
+func buildDownloadFilter(opts *models.DownloadOptions, reqData map[string]interface{}) (bson.M, error) {
+	if opts.IDParam != "" {
+		raw, ok := reqData[opts.IDParam]
+		if !ok || raw == nil || fmt.Sprintf("%v", raw) == "" {
+			return nil, fmt.Errorf("missing file ID parameter: %s", opts.IDParam)
+		}
+		idStr := fmt.Sprintf("%v", raw)
+		if oid, err := primitive.ObjectIDFromHex(idStr); err == nil {
+			return bson.M{"_id": oid}, nil
+		}
+		return bson.M{"_id": idStr}, nil
+	}
+
+	if len(opts.Filter) == 0 {
+		return nil, errors.New("download definition requires idParam or filter")
+	}
+	filter, ok := core.SubstituteVariables(opts.Filter, reqData).(map[string]interface{})
+	if !ok {
+		return nil, errors.New("download filter must be an object")
+	}
+	return bson.M(filter), nil
+}
+
+// fileContentType reads contentType from the file metadata, falling back to the file extension.
+func fileContentType(name string, metadata bson.Raw) string {
+	if len(metadata) > 0 {
+		if v, err := metadata.LookupErr("contentType"); err == nil {
+			if ct, ok := v.StringValueOK(); ok && ct != "" {
+				return ct
+			}
+		}
+	}
+	if ct := mime.TypeByExtension(filepath.Ext(name)); ct != "" {
+		return ct
+	}
+	return fiber.MIMEOctetStream
+}
+
+// parseByteRange parses a single-range Range header against the file size.
+func parseByteRange(header string, size int64) (int64, int64, bool) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") || size == 0 {
+		return 0, 0, false
+	}
+	startStr, endStr, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false
+	}
+
+	if startStr == "" { // Suffix range: last N bytes
+		n, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, true
+	}
+
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+	end := size - 1
+	if endStr != "" {
+		end, err = strconv.ParseInt(endStr, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end, true
+}
+
+// limitedReadCloser keeps the underlying stream closable after wrapping it in a LimitReader.
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
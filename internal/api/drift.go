@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// headerChecksum carries the content hash of a definition in GET /api-generator/detail/:name.
+const headerChecksum = "X-Definition-Checksum"
+
+// definitionChecksum hashes what a definition does, not when or where it was stored: the
+// ID and creation time are left out, so the same definition exported from one server
+// and imported into another has the same checksum.
+func definitionChecksum(api models.ApiDefinition) string {
+	api.ID = primitive.NilObjectID
+	api.CreatedAt = time.Time{}
+	raw, err := json.Marshal(api)
+	if err == nil {
+		// Round trip so numbers read from MongoDB (int32, int64) and from JSON (float64) match
+		var canonical interface{}
+		if err = json.Unmarshal(raw, &canonical); err == nil {
+			raw, err = json.Marshal(canonical)
+		}
+	}
+	if err != nil {
+		log.Printf("WARN: Cannot compute checksum of API '%s': %v", api.Name, err)
+		return ""
+	}
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// driftChange is a definition whose live checksum differs from the expected one.
+type driftChange struct {
+	Name     string `json:"name"`
+	Live     string `json:"live"`
+	Expected string `json:"expected"`
+}
+
+// driftReport compares the live catalog with an expected one (a bundle): added are live
+// definitions missing from the bundle, removed are bundle definitions missing live.
+type driftReport struct {
+	InSync    bool          `json:"inSync"`
+	Added     []string      `json:"added"`
+	Removed   []string      `json:"removed"`
+	Changed   []driftChange `json:"changed"`
+	Unchanged int           `json:"unchanged"`
+}
+
+// GetDrift returns the checksum of every live definition, or with ?backup=<name> the
+// drift of the live catalog from that backup bundle.
+func (h *Handler) GetDrift(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Minute)
+	defer cancel()
+
+	live, err := h.liveChecksums(ctx)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve API list"})
+	}
+	name := c.Query("backup")
+	if name == "" {
+		return c.JSON(fiber.Map{
+			"status": "success",
+			"code":   http.StatusOK,
+			"data":   live,
+		})
+	}
+	if !h.backupsEnabled(c) {
+		return nil
+	}
+	if !strings.HasPrefix(name, "definitions-") || strings.Contains(name, "/") {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "backup must be a backup name from GET /api-generator/backups, e.g. definitions-20260101T000000Z.json"})
+	}
+	body, err := core.GetObject(ctx, h.backups.cfg.Bucket, h.backups.cfg.Prefix+name, maxBackupBytes)
+	if err != nil {
+		log.Printf("ERROR: Could not read backup %s for drift detection: %v", name, err)
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{"error": "Failed to read backup: " + err.Error()})
+	}
+	return h.sendDrift(c, live, body)
+}
+
+// CompareDrift reports the drift of the live catalog from the bundle in the body: a
+// backup bundle, an array of definitions or {"checksums": {"name": "sha256:..."}}.
+func (h *Handler) CompareDrift(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Minute)
+	defer cancel()
+
+	live, err := h.liveChecksums(ctx)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve API list"})
+	}
+	return h.sendDrift(c, live, c.Body())
+}
+
+func (h *Handler) sendDrift(c *fiber.Ctx, live map[string]string, bundle []byte) error {
+	expected, err := bundleChecksums(bundle)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid bundle", "details": err.Error()})
+	}
+	report := compareChecksums(live, expected)
+	log.Printf("INFO: Drift check: %d added, %d removed, %d changed, %d unchanged", len(report.Added), len(report.Removed), len(report.Changed), report.Unchanged)
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   report,
+	})
+}
+
+func (h *Handler) liveChecksums(ctx context.Context) (map[string]string, error) {
+	apis, err := h.store.ListAPIDefinitions(ctx)
+	if err != nil {
+		log.Printf("ERROR: Handler failed to list APIs for drift detection: %v", err)
+		return nil, err
+	}
+	checksums := make(map[string]string, len(apis))
+	for _, api := range apis {
+		checksums[api.Name] = definitionChecksum(api)
+	}
+	return checksums, nil
+}
+
+// bundleChecksums reads the expected checksums from a bundle.
+func bundleChecksums(body []byte) (map[string]string, error) {
+	var definitions []models.ApiDefinition
+	if err := json.Unmarshal(body, &definitions); err != nil {
+		var bundle struct {
+			Definitions []models.ApiDefinition `json:"definitions"`
+			Checksums   map[string]string      `json:"checksums"`
+		}
+		if err := json.Unmarshal(body, &bundle); err != nil {
+			return nil, err
+		}
+		if bundle.Checksums != nil {
+			return bundle.Checksums, nil
+		}
+		if bundle.Definitions == nil {
+			return nil, errors.New("expected a definitions bundle, an array of definitions or {\"checksums\": {...}}")
+		}
+		definitions = bundle.Definitions
+	}
+	checksums := make(map[string]string, len(definitions))
+	for _, api := range definitions {
+		if api.Name == "" {
+			return nil, errors.New("every definition needs a name")
+		}
+		checksums[api.Name] = definitionChecksum(api)
+	}
+	return checksums, nil
+}
+
+func compareChecksums(live, expected map[string]string) driftReport {
+	report := driftReport{Added: []string{}, Removed: []string{}, Changed: []driftChange{}}
+	for name, sum := range live {
+		want, ok := expected[name]
+		switch {
+		case !ok:
+			report.Added = append(report.Added, name)
+		case want != sum:
+			report.Changed = append(report.Changed, driftChange{Name: name, Live: sum, Expected: want})
+		default:
+			report.Unchanged++
+		}
+	}
+	for name := range expected {
+		if _, ok := live[name]; !ok {
+			report.Removed = append(report.Removed, name)
+		}
+	}
+	sort.Strings(report.Added)
+	sort.Strings(report.Removed)
+	sort.Slice(report.Changed, func(i, j int) bool { return report.Changed[i].Name < report.Changed[j].Name })
+	report.InSync = len(report.Added) == 0 && len(report.Removed) == 0 && len(report.Changed) == 0
+	return report
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RefreshErrorCatalog loads the error codes used by returnError and reloads them every
+// interval, so changes made through other instances are picked up.
+func (h *Handler) RefreshErrorCatalog(ctx context.Context, interval time.Duration) {
+	refreshEvery(ctx, interval, "error catalog", func(loadCtx context.Context) error {
+		defs, err := h.store.ListErrorDefinitions(loadCtx)
+		if err == nil {
+			core.SetErrorDefinitions(defs)
+		}
+		return err
+	})
+}
+
+func (h *Handler) errorCatalog() namedResource[models.ErrorDefinition] {
+	return namedResource[models.ErrorDefinition]{
+		kind:    "error code",
+		list:    h.store.ListErrorDefinitions,
+		get:     h.store.GetErrorDefinition,
+		save:    h.store.SaveErrorDefinition,
+		delete:  h.store.DeleteErrorDefinition,
+		setName: func(def *models.ErrorDefinition, code string) { def.Code = code },
+		check:   core.CheckErrorDefinition,
+		saved:   core.PutErrorDefinition,
+		deleted: core.DropErrorDefinition,
+		detail:  func(def models.ErrorDefinition) string { return strconv.Itoa(def.Status) },
+	}
+}
+
+// ListErrorDefinitions returns the whole error catalog
+func (h *Handler) ListErrorDefinitions(c *fiber.Ctx) error {
+	return h.errorCatalog().handleList(c)
+}
+
+// GetErrorDefinition returns a single error code
+func (h *Handler) GetErrorDefinition(c *fiber.Ctx) error {
+	return h.errorCatalog().handleGet(c)
+}
+
+// PutErrorDefinition creates or replaces an error code. Consumers rely on codes staying
+// stable, so change the status or message rather than renaming a code.
+func (h *Handler) PutErrorDefinition(c *fiber.Ctx) error {
+	return h.errorCatalog().handlePut(c)
+}
+
+// DeleteErrorDefinition removes an error code. Flows that still return it fail with 500.
+func (h *Handler) DeleteErrorDefinition(c *fiber.Ctx) error {
+	return h.errorCatalog().handleDelete(c)
+}
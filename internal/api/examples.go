@@ -0,0 +1,219 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultCaptureTTL = 10 * time.Minute
+	maxCaptureCount   = 20
+)
+
+// exampleCapture records the next calls of a definition as documentation examples.
+type exampleCapture struct {
+	example   string // Example name (numbered when several calls are captured)
+	summary   string
+	count     int
+	taken     int
+	expiresAt time.Time
+}
+
+// captureState holds the armed captures of this instance, by definition name.
+type captureState struct {
+	mu    sync.Mutex
+	armed map[string]*exampleCapture
+}
+
+func (s *captureState) arm(apiName string, capture *exampleCapture) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.armed == nil {
+		s.armed = make(map[string]*exampleCapture)
+	}
+	s.armed[apiName] = capture
+}
+
+// take claims one capture slot for a call of apiName and returns the example name and
+// summary to record it under. ok is false when no capture is armed.
+func (s *captureState) take(apiName string) (name, summary string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	capture := s.armed[apiName]
+	if capture == nil {
+		return "", "", false
+	}
+	if time.Now().After(capture.expiresAt) {
+		delete(s.armed, apiName)
+		return "", "", false
+	}
+	capture.taken++
+	if capture.taken >= capture.count {
+		delete(s.armed, apiName)
+	}
+	name = capture.example
+	if capture.count > 1 {
+		name = fmt.Sprintf("%s-%d", capture.example, capture.taken)
+	}
+	return name, capture.summary, true
+}
+
+// changeableDefinition loads a definition for a change outside the full update (examples,
+// maintenance) and checks the operator may change it. It writes the error response itself when ok is false.
+func (h *Handler) changeableDefinition(ctx context.Context, c *fiber.Ctx, name string) (api *models.ApiDefinition, ok bool, err error) {
+	api, err = h.store.GetAPIDefinitionByName(ctx, name)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, false, c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API not found"})
+	}
+	if err != nil {
+		log.Printf("ERROR: Handler failed to find API '%s': %v", name, err)
+		return nil, false, c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve API definition"})
+	}
+	if op := operatorFrom(c); !op.canChange(api) {
+		return nil, false, sendNotOwner(c, name, errors.New("only the owner, its team or an admin may change it"))
+	}
+	return api, true, nil
+}
+
+// PutAPIExample attaches one example (replacing an example with the same name). Examples
+// are documentation only, so they are stored directly without the approval workflow.
+func (h *Handler) PutAPIExample(c *fiber.Ctx) error {
+	name := c.Params("name")
+	var example models.ApiExample
+	if err := c.BodyParser(&example); err != nil {
+		log.Printf("WARN: Cannot parse JSON for PutAPIExample (name: %s): %v", name, err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	if example.Name == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "example name is required"})
+	}
+	if example.Status != 0 && (example.Status < 100 || example.Status > 599) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid example status"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+	if _, ok, err := h.changeableDefinition(ctx, c, name); !ok {
+		return err
+	}
+
+	updatedAPI, err := h.storeExample(ctx, name, example)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API not found"})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save example"})
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   updatedAPI.Examples,
+	})
+}
+
+type captureRequest struct {
+	Example    string `json:"example"`
+	Summary    string `json:"summary"`
+	Count      int    `json:"count"`      // Calls to record (default 1)
+	TTLSeconds int    `json:"ttlSeconds"` // How long the capture stays armed (default 600)
+}
+
+// CaptureAPIExample arms this instance to record the next calls of a definition (their
+// query/body parameters and the response) as examples. Send the calls to the same instance.
+func (h *Handler) CaptureAPIExample(c *fiber.Ctx) error {
+	name := c.Params("name")
+	var body captureRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Printf("WARN: Cannot parse JSON for CaptureAPIExample (name: %s): %v", name, err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	if body.Example == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "example is required"})
+	}
+	if body.Count <= 0 {
+		body.Count = 1
+	}
+	if body.Count > maxCaptureCount {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("count may not exceed %d", maxCaptureCount)})
+	}
+	ttl := defaultCaptureTTL
+	if body.TTLSeconds > 0 {
+		ttl = time.Duration(body.TTLSeconds) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+	api, ok, err := h.changeableDefinition(ctx, c, name)
+	if !ok {
+		return err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	h.captures.arm(name, &exampleCapture{example: body.Example, summary: body.Summary, count: body.Count, expiresAt: expiresAt})
+	log.Printf("INFO: Capturing the next %d call(s) of API '%s' as example '%s' until %s", body.Count, name, body.Example, expiresAt.Format(time.RFC3339))
+	return c.Status(http.StatusAccepted).JSON(fiber.Map{
+		"status":  "accepted",
+		"code":    http.StatusAccepted,
+		"message": fmt.Sprintf("The next %d call(s) of %s %s on instance %s will be recorded", body.Count, api.Method, api.Endpoint, h.cluster.cfg.InstanceID),
+		"data":    fiber.Map{"example": body.Example, "count": body.Count, "expiresAt": expiresAt},
+	})
+}
+
+// recordExample stores the finished exchange as an example. It runs after the response
+// is written; the store write happens in the background so the caller is not delayed.
+func (h *Handler) recordExample(c *fiber.Ctx, api models.ApiDefinition, name, summary string) {
+	request := make(map[string]interface{})
+	c.Request().URI().QueryArgs().VisitAll(func(k, v []byte) {
+		request[string(k)] = string(v)
+	})
+	var body map[string]interface{}
+	if json.Unmarshal(c.BodyRaw(), &body) == nil {
+		for k, v := range body {
+			if _, exists := request[k]; !exists {
+				request[k] = v
+			}
+		}
+	}
+	example := models.ApiExample{Name: name, Summary: summary, Status: c.Response().StatusCode()}
+	if len(request) > 0 {
+		example.Request = request
+	}
+	respBody := c.Response().Body()
+	var response interface{}
+	if json.Unmarshal(respBody, &response) == nil {
+		example.Response = response
+	} else if len(respBody) > 0 {
+		example.Response = string(respBody)
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := h.storeExample(ctx, api.Name, example); err == nil {
+			log.Printf("INFO: Captured example '%s' (status %d) for API '%s'", name, example.Status, api.Name)
+		}
+	}()
+}
+
+// storeExample saves an example and refreshes the cached definition on every instance.
+func (h *Handler) storeExample(ctx context.Context, name string, example models.ApiExample) (*models.ApiDefinition, error) {
+	updatedAPI, err := h.store.SetAPIExample(ctx, name, example)
+	if err != nil {
+		log.Printf("ERROR: Handler failed to save example '%s' for API '%s': %v", example.Name, name, err)
+		return nil, err
+	}
+	h.swapRoute("", updatedAPI.Method+":"+updatedAPI.Endpoint, *updatedAPI)
+	h.notifyDefinitionChange(name)
+	return updatedAPI, nil
+}
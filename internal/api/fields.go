@@ -0,0 +1,132 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"api-genarator/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// paramFields selects the fields of a default GET response (?fields=name,email,address.city).
+// It is reserved unless the definition declares a parameter of that name, which keeps
+// filtering on a stored "fields" field working.
+const paramFields = "fields"
+
+// maxFieldsetSize bounds the number of fields one request may select.
+const maxFieldsetSize = 100
+
+// fieldsReserved reports whether ?fields= selects fields for this definition.
+func fieldsReserved(api models.ApiDefinition) bool {
+	for _, p := range api.Parameters {
+		if p.Name == paramFields {
+			return false
+		}
+	}
+	return true
+}
+
+// checkFieldset validates the requested field paths.
+func checkFieldset(fields []string) error {
+	if len(fields) > maxFieldsetSize {
+		return fmt.Errorf("at most %d fields may be selected", maxFieldsetSize)
+	}
+	for _, field := range fields {
+		if strings.HasPrefix(field, "$") || strings.HasPrefix(field, ".") || strings.HasSuffix(field, ".") ||
+			strings.Contains(field, "..") || strings.ContainsAny(field, "'\" ") {
+			return fmt.Errorf("invalid field '%s' in %s", field, paramFields)
+		}
+	}
+	return nil
+}
+
+// fieldsetProjection builds the Mongo projection of a fieldset, so unselected fields are
+// never read. Computed fields are not stored: when one is selected, the stored fields the
+// computed transformations read are projected too, and trim is set so they can be
+// removed again once the computed fields are added.
+func fieldsetProjection(fields []string, computed []models.Transformation) (projection bson.M, trim bool) {
+	paths := append([]string(nil), fields...)
+	requested := fieldRoots(fields)
+	for _, t := range computed {
+		if !requested[fieldRoot(t.Field)] {
+			continue
+		}
+		for _, t := range computed { // Computed fields may build on each other
+			for _, source := range computedSources(t) {
+				if !requested[source] {
+					paths = append(paths, source)
+					trim = true
+				}
+			}
+		}
+		break
+	}
+
+	// A path and one of its sub-paths ("address" and "address.city") collide in a projection
+	sort.Strings(paths)
+	projection = bson.M{}
+	kept := ""
+	for _, path := range paths {
+		if kept != "" && (path == kept || strings.HasPrefix(path, kept+".")) {
+			continue
+		}
+		projection[path] = 1
+		kept = path
+	}
+	return projection, trim
+}
+
+// trimToFieldset drops the top-level fields that were only read for computed fields.
+func trimToFieldset(docs []bson.M, fields []string) []bson.M {
+	keep := fieldRoots(fields)
+	keep["_id"] = true
+	for _, doc := range docs {
+		for key := range doc {
+			if !keep[key] {
+				delete(doc, key)
+			}
+		}
+	}
+	return docs
+}
+
+// computedSources returns the top-level stored fields a computed transformation reads:
+// "$field" references in its formula, value and path, and its own field for in-place
+// operations such as "round" or "age".
+func computedSources(t models.Transformation) []string {
+	sources := []string{fieldRoot(t.Field)}
+	if _, args, found := strings.Cut(t.Formula, ":"); found {
+		for _, arg := range strings.Split(args, ",") {
+			if ref, ok := strings.CutPrefix(strings.TrimPrefix(strings.TrimSpace(arg), "-"), "$"); ok {
+				sources = append(sources, fieldRoot(ref))
+			}
+		}
+	}
+	if s, ok := t.Value.(string); ok {
+		if ref, ok := strings.CutPrefix(s, "$"); ok {
+			sources = append(sources, fieldRoot(ref))
+		}
+	}
+	if path := strings.TrimLeft(strings.TrimPrefix(t.Path, "$"), "."); path != "" {
+		sources = append(sources, fieldRoot(path))
+	}
+	return sources
+}
+
+func fieldRoots(fields []string) map[string]bool {
+	roots := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		roots[fieldRoot(field)] = true
+	}
+	return roots
+}
+
+// fieldRoot returns the top-level field of a path ("address.city" -> "address", "items[0]" -> "items").
+func fieldRoot(path string) string {
+	if i := strings.IndexAny(path, ".["); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RefreshFeatureFlags loads the flags used by "featureFlag" conditions and reloads them
+// every interval, so changes made through other instances are picked up.
+func (h *Handler) RefreshFeatureFlags(ctx context.Context, interval time.Duration) {
+	refreshEvery(ctx, interval, "feature flags", func(loadCtx context.Context) error {
+		flags, err := h.store.ListFeatureFlags(loadCtx)
+		if err == nil {
+			core.SetFeatureFlags(flags)
+		}
+		return err
+	})
+}
+
+func (h *Handler) featureFlags() namedResource[models.FeatureFlag] {
+	return namedResource[models.FeatureFlag]{
+		kind:    "feature flag",
+		list:    h.store.ListFeatureFlags,
+		get:     h.store.GetFeatureFlag,
+		save:    h.store.SaveFeatureFlag,
+		delete:  h.store.DeleteFeatureFlag,
+		setName: func(flag *models.FeatureFlag, name string) { flag.Name = name },
+		saved:   core.PutFeatureFlag,
+		deleted: core.DropFeatureFlag,
+		detail:  func(flag models.FeatureFlag) string { return fmt.Sprintf("enabled=%t", flag.Enabled) },
+	}
+}
+
+// ListFeatureFlags returns all feature flags
+func (h *Handler) ListFeatureFlags(c *fiber.Ctx) error {
+	return h.featureFlags().handleList(c)
+}
+
+// GetFeatureFlag returns a single feature flag
+func (h *Handler) GetFeatureFlag(c *fiber.Ctx) error {
+	return h.featureFlags().handleGet(c)
+}
+
+// PutFeatureFlag creates or replaces a feature flag. It takes effect on this instance
+// immediately and on other instances at their next refresh.
+func (h *Handler) PutFeatureFlag(c *fiber.Ctx) error {
+	return h.featureFlags().handlePut(c)
+}
+
+// DeleteFeatureFlag removes a feature flag. Conditions on it then evaluate it as off.
+func (h *Handler) DeleteFeatureFlag(c *fiber.Ctx) error {
+	return h.featureFlags().handleDelete(c)
+}
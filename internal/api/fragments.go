@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *Handler) flowFragments() namedResource[models.FlowFragment] {
+	return namedResource[models.FlowFragment]{
+		kind:      "flow fragment",
+		list:      h.store.ListFlowFragments,
+		get:       h.store.GetFlowFragment,
+		save:      h.store.SaveFlowFragment,
+		delete:    h.store.DeleteFlowFragment,
+		setName:   func(fragment *models.FlowFragment, name string) { fragment.Name = name },
+		check:     core.CheckFlowFragment,
+		authorize: h.changeableFragment,
+		saved:     func(fragment models.FlowFragment) { core.ForgetFlowFragment(fragment.Name) },
+		deleted:   core.ForgetFlowFragment,
+	}
+}
+
+// ListFlowFragments returns all flow fragments
+func (h *Handler) ListFlowFragments(c *fiber.Ctx) error {
+	return h.flowFragments().handleList(c)
+}
+
+// GetFlowFragment returns a single flow fragment
+func (h *Handler) GetFlowFragment(c *fiber.Ctx) error {
+	return h.flowFragments().handleGet(c)
+}
+
+// changeableFragment checks the operator may change a fragment, which changes every
+// definition running it: they must be allowed to change each of those definitions. Revisions
+// carry definitions only, so with approval required fragment changes are left to admins,
+// who approve revisions anyway. It writes the error response itself when ok is false.
+func (h *Handler) changeableFragment(ctx context.Context, c *fiber.Ctx, name string) (ok bool, err error) {
+	op := operatorFrom(c)
+	if h.needsApproval(c) && op != nil && !op.hasRole(RoleAdmin) {
+		return false, sendNotOwner(c, name, errors.New("flow fragments can only be changed by an admin while changes need approval"))
+	}
+	apis, err := h.store.ListAPIDefinitions(ctx)
+	if err != nil {
+		log.Printf("ERROR: Handler failed to list APIs for flow fragment '%s': %v", name, err)
+		return false, c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to check flow fragment usage"})
+	}
+	for i := range apis {
+		if core.FlowCallsFragment(apis[i].ConditionalFlow, name) && !op.canChange(&apis[i]) {
+			return false, sendNotOwner(c, name, fmt.Errorf("it is used by API '%s', which you may not change", apis[i].Name))
+		}
+	}
+	return true, nil
+}
+
+// PutFlowFragment creates or replaces a flow fragment. Definitions using it pick up
+// the change on their next request (other instances within a short cache period).
+func (h *Handler) PutFlowFragment(c *fiber.Ctx) error {
+	return h.flowFragments().handlePut(c)
+}
+
+// DeleteFlowFragment removes a flow fragment. Definitions still referencing it fail
+// at the action that uses it.
+func (h *Handler) DeleteFlowFragment(c *fiber.Ctx) error {
+	return h.flowFragments().handleDelete(c)
+}
@@ -0,0 +1,558 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// gRPC service served by the gateway. Every definition is reachable through one generic
+// method; the payload is a google.protobuf.Struct and the response body a google.protobuf.Value.
+const (
+	grpcService      = "apigen.v1.DynamicAPI"
+	grpcInvokeMethod = "/" + grpcService + "/Invoke"
+	grpcMaxMessage   = 10 * 1024 * 1024 // Same as the HTTP body limit
+	grpcMaxNesting   = 64               // Struct/ListValue depth accepted in a payload
+)
+
+// gRPC status codes used by the gateway.
+const (
+	grpcOK                 = 0
+	grpcInvalidArgument    = 3
+	grpcDeadlineExceeded   = 4
+	grpcNotFound           = 5
+	grpcPermissionDenied   = 7
+	grpcResourceExhausted  = 8
+	grpcFailedPrecondition = 9
+	grpcAborted            = 10
+	grpcUnimplemented      = 12
+	grpcInternal           = 13
+	grpcUnavailable        = 14
+	grpcUnauthenticated    = 16
+)
+
+// grpcProto is the service definition clients compile against.
+const grpcProto = `syntax = "proto3";
+
+package apigen.v1;
+
+import "google/protobuf/struct.proto";
+
+// DynamicAPI invokes any served definition by name. Requests run through the same
+// pipeline as HTTP calls (access rules, validation, flow, permissions).
+service DynamicAPI {
+  rpc Invoke(InvokeRequest) returns (InvokeResponse);
+}
+
+message InvokeRequest {
+  string api = 1;                      // Definition name
+  google.protobuf.Struct payload = 2;  // Path, query or body parameters
+}
+
+message InvokeResponse {
+  int32 status = 1;                    // HTTP status the definition answered with
+  google.protobuf.Value body = 2;      // JSON response body
+  bytes message = 3;                   // Body encoded with the definition's protobuf schema, if it has one
+}
+`
+
+// NewGRPCServer returns an HTTP/2 (cleartext) server for the gRPC gateway. Calls are
+// transcoded to HTTP requests and served in-process by app, so they pass through the
+// same middleware and dynamic handler as HTTP/JSON clients.
+func (h *Handler) NewGRPCServer(addr string, app *fiber.App) *http.Server {
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true) // gRPC clients speak h2c with prior knowledge
+	return &http.Server{
+		Addr:              addr,
+		Handler:           &grpcGateway{h: h, app: app.Handler()},
+		Protocols:         &protocols,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+type grpcGateway struct {
+	h   *Handler
+	app fasthttp.RequestHandler
+}
+
+func (g *grpcGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	if r.URL.Path == grpcReflectionV1 || r.URL.Path == grpcReflectionV1Alpha {
+		g.serveReflection(w, r)
+		return
+	}
+	if r.URL.Path != grpcInvokeMethod {
+		writeGRPCStatus(w, grpcUnimplemented, "unknown method "+r.URL.Path)
+		return
+	}
+
+	msg, err := readGRPCMessage(r.Body)
+	if err != nil {
+		writeGRPCStatus(w, grpcInvalidArgument, err.Error())
+		return
+	}
+	name, payload, err := decodeInvokeRequest(msg)
+	if err != nil {
+		writeGRPCStatus(w, grpcInvalidArgument, "invalid InvokeRequest: "+err.Error())
+		return
+	}
+	api, ok := g.h.servedAPI(name)
+	if !ok {
+		writeGRPCStatus(w, grpcNotFound, fmt.Sprintf("API '%s' not found", name))
+		return
+	}
+
+	status, body := g.invoke(r, api, payload)
+	if status >= http.StatusBadRequest {
+		writeGRPCStatus(w, grpcCodeForStatus(status), grpcErrorMessage(status, body))
+		return
+	}
+	resp, err := encodeInvokeResponse(api, status, body)
+	if err != nil {
+		log.Printf("ERROR: gRPC gateway failed to encode response of API '%s': %v", api.Name, err)
+		writeGRPCStatus(w, grpcInternal, "response cannot be encoded")
+		return
+	}
+
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+	frame := make([]byte, 5, 5+len(resp))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(resp)))
+	if _, err := w.Write(append(frame, resp...)); err != nil {
+		return // Client went away
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(grpcOK))
+}
+
+// servedAPI finds a cached definition by name.
+func (h *Handler) servedAPI(name string) (models.ApiDefinition, bool) {
+	for _, api := range h.cachedAPIs() {
+		if api.Name == name {
+			return api, true
+		}
+	}
+	return models.ApiDefinition{}, false
+}
+
+// invoke replays the call against the fiber app with the caller's metadata as headers.
+func (g *grpcGateway) invoke(r *http.Request, api models.ApiDefinition, payload map[string]interface{}) (int, []byte) {
+	header := make(http.Header)
+	for k, vs := range r.Header {
+		if forwardGRPCMetadata(k) {
+			header[k] = vs
+		}
+	}
+	var remote net.Addr
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		remote = addr
+	}
+	return dispatchInProcess(g.app, api, payload, header, r.Host, remote)
+}
+
+// dispatchInProcess serves a call of a definition as an HTTP request handled in-process
+// by app. Path parameters are taken from the payload; the rest becomes the query string
+// (GET/DELETE) or a JSON body. Returns the response status and body.
+func dispatchInProcess(app fasthttp.RequestHandler, api models.ApiDefinition, payload map[string]interface{}, header http.Header, host string, remote net.Addr) (int, []byte) {
+	path, pathParams := openAPIPath(api.Endpoint)
+	for _, p := range pathParams {
+		path = strings.ReplaceAll(path, "{"+p+"}", url.PathEscape(paramString(payload[p])))
+		delete(payload, p)
+	}
+
+	var req fasthttp.Request
+	req.Header.SetMethod(api.Method)
+	req.Header.SetHost(host)
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set(fiber.HeaderAccept, fiber.MIMEApplicationJSON)
+
+	if api.Method == http.MethodGet || api.Method == http.MethodDelete {
+		query := url.Values{}
+		for k, v := range payload {
+			query.Set(k, paramString(v))
+		}
+		if len(query) > 0 {
+			path += "?" + query.Encode()
+		}
+	} else {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return http.StatusBadRequest, []byte(`{"error":"payload cannot be encoded as JSON"}`)
+		}
+		req.Header.SetContentType(fiber.MIMEApplicationJSON)
+		req.SetBody(body)
+	}
+	req.SetRequestURI(path)
+
+	var fctx fasthttp.RequestCtx
+	fctx.Init(&req, remote, nil)
+	app(&fctx)
+	return fctx.Response.StatusCode(), append([]byte(nil), fctx.Response.Body()...)
+}
+
+// forwardGRPCMetadata reports whether a request header is caller metadata (Authorization,
+// API keys, tenant headers) rather than part of the gRPC transport.
+func forwardGRPCMetadata(key string) bool {
+	k := strings.ToLower(key)
+	switch k {
+	case "content-type", "content-length", "te", "host", "connection", "accept", "accept-encoding":
+		return false
+	}
+	return !strings.HasPrefix(k, "grpc-") && !strings.HasSuffix(k, "-bin")
+}
+
+// paramString renders a payload value as a path or query parameter.
+func paramString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
+
+// grpcCodeForStatus maps the HTTP status of a failed call to a gRPC status code.
+func grpcCodeForStatus(status int) int {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusRequestEntityTooLarge:
+		return grpcInvalidArgument
+	case http.StatusUnauthorized:
+		return grpcUnauthenticated
+	case http.StatusForbidden:
+		return grpcPermissionDenied
+	case http.StatusNotFound:
+		return grpcNotFound
+	case http.StatusConflict:
+		return grpcAborted
+	case http.StatusPreconditionFailed:
+		return grpcFailedPrecondition
+	case http.StatusTooManyRequests:
+		return grpcResourceExhausted
+	case http.StatusServiceUnavailable, http.StatusBadGateway:
+		return grpcUnavailable
+	case http.StatusGatewayTimeout:
+		return grpcDeadlineExceeded
+	}
+	return grpcInternal
+}
+
+// grpcErrorMessage takes the "error" text of a JSON error body, else the status text.
+func grpcErrorMessage(status int, body []byte) string {
+	var errBody struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &errBody) == nil && errBody.Error != "" {
+		return errBody.Error
+	}
+	return http.StatusText(status)
+}
+
+// writeGRPCStatus ends the call with a trailers-only response.
+func writeGRPCStatus(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	w.Header().Set("Grpc-Message", grpcPercentEncode(message))
+	w.WriteHeader(http.StatusOK)
+}
+
+// grpcPercentEncode escapes a status message as the gRPC HTTP/2 mapping requires.
+func grpcPercentEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// readGRPCMessage reads the next length-prefixed message of a call; io.EOF when the
+// client sent no further message.
+func readGRPCMessage(body io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(body, prefix[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, errors.New("missing message")
+	}
+	if prefix[0] != 0 {
+		return nil, errors.New("compressed messages are not supported")
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > grpcMaxMessage {
+		return nil, fmt.Errorf("message larger than %d bytes", grpcMaxMessage)
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(body, msg); err != nil {
+		return nil, errors.New("truncated message")
+	}
+	return msg, nil
+}
+
+// --- InvokeRequest / InvokeResponse ---
+
+func decodeInvokeRequest(msg []byte) (string, map[string]interface{}, error) {
+	var name string
+	payload := make(map[string]interface{})
+	err := walkProtoFields(msg, func(num, wire int, varint uint64, data []byte) error {
+		switch {
+		case num == 1 && wire == wireBytes:
+			name = string(data)
+		case num == 2 && wire == wireBytes:
+			s, err := decodeProtoStruct(data, 0)
+			if err != nil {
+				return fmt.Errorf("payload: %w", err)
+			}
+			payload = s
+		}
+		return nil
+	})
+	if err == nil && name == "" {
+		err = errors.New("api is required")
+	}
+	return name, payload, err
+}
+
+func encodeInvokeResponse(api models.ApiDefinition, status int, body []byte) ([]byte, error) {
+	b := appendProtoTag(nil, 1, wireVarint)
+	b = binary.AppendUvarint(b, uint64(status))
+	if len(bytes.TrimSpace(body)) == 0 {
+		return b, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		// Not JSON (plain text, downloads): pass it through as a string value
+		value = string(body)
+	}
+	encoded, err := appendProtoValue(nil, value)
+	if err != nil {
+		return nil, err
+	}
+	b = appendProtoBytes(appendProtoTag(b, 2, wireBytes), encoded)
+
+	if api.Protobuf != nil {
+		msg, err := encodeProtobuf(api.Protobuf, value)
+		if err != nil {
+			return nil, fmt.Errorf("protobuf schema: %w", err)
+		}
+		b = appendProtoBytes(appendProtoTag(b, 3, wireBytes), msg)
+	}
+	return b, nil
+}
+
+// --- google.protobuf.Struct / Value / ListValue ---
+
+// walkProtoFields calls fn for each field of an encoded message. varint carries varint
+// and fixed-width values; data carries length-delimited ones.
+func walkProtoFields(b []byte, fn func(num, wire int, varint uint64, data []byte) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errors.New("malformed tag")
+		}
+		b = b[n:]
+		num, wire := int(tag>>3), int(tag&7)
+		var varint uint64
+		var data []byte
+		switch wire {
+		case wireVarint:
+			varint, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errors.New("malformed varint")
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errors.New("truncated fixed64")
+			}
+			varint, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return errors.New("truncated fixed32")
+			}
+			varint, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return errors.New("truncated length-delimited field")
+			}
+			data, b = b[n:n+int(size)], b[n+int(size):]
+		default:
+			return fmt.Errorf("unsupported wire type %d", wire)
+		}
+		if err := fn(num, wire, varint, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeProtoStruct decodes a google.protobuf.Struct nested depth levels deep. The depth
+// is limited so a hostile payload cannot exhaust the stack.
+func decodeProtoStruct(b []byte, depth int) (map[string]interface{}, error) {
+	if depth > grpcMaxNesting {
+		return nil, fmt.Errorf("payload nested deeper than %d levels", grpcMaxNesting)
+	}
+	out := make(map[string]interface{})
+	err := walkProtoFields(b, func(num, wire int, _ uint64, data []byte) error {
+		if num != 1 || wire != wireBytes {
+			return nil
+		}
+		var key string
+		var value interface{}
+		err := walkProtoFields(data, func(num, wire int, _ uint64, data []byte) error {
+			if wire != wireBytes {
+				return nil
+			}
+			var err error
+			switch num {
+			case 1:
+				key = string(data)
+			case 2:
+				value, err = decodeProtoValue(data, depth)
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		out[key] = value
+		return nil
+	})
+	return out, err
+}
+
+func decodeProtoValue(b []byte, depth int) (interface{}, error) {
+	var value interface{}
+	err := walkProtoFields(b, func(num, wire int, varint uint64, data []byte) error {
+		var err error
+		switch num {
+		case 1:
+			value = nil
+		case 2:
+			value = math.Float64frombits(varint)
+		case 3:
+			value = string(data)
+		case 4:
+			value = varint != 0
+		case 5:
+			value, err = decodeProtoStruct(data, depth+1)
+		case 6:
+			if depth+1 > grpcMaxNesting {
+				return fmt.Errorf("payload nested deeper than %d levels", grpcMaxNesting)
+			}
+			items := []interface{}{}
+			err = walkProtoFields(data, func(num, wire int, _ uint64, data []byte) error {
+				if num != 1 || wire != wireBytes {
+					return nil
+				}
+				item, err := decodeProtoValue(data, depth+1)
+				items = append(items, item)
+				return err
+			})
+			value = items
+		}
+		return err
+	})
+	return value, err
+}
+
+// appendProtoValue encodes a decoded JSON value (numbers as json.Number) as a
+// google.protobuf.Value. Numbers become doubles, as in the JSON mapping of Struct.
+func appendProtoValue(b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(appendProtoTag(b, 1, wireVarint), 0), nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return binary.LittleEndian.AppendUint64(appendProtoTag(b, 2, wireFixed64), math.Float64bits(f)), nil
+	case string:
+		return appendProtoBytes(appendProtoTag(b, 3, wireBytes), []byte(v)), nil
+	case bool:
+		flag := byte(0)
+		if v {
+			flag = 1
+		}
+		return append(appendProtoTag(b, 4, wireVarint), flag), nil
+	case map[string]interface{}:
+		var fields []byte
+		for k, item := range v {
+			encoded, err := appendProtoValue(nil, item)
+			if err != nil {
+				return nil, err
+			}
+			entry := appendProtoBytes(appendProtoTag(nil, 1, wireBytes), []byte(k))
+			entry = appendProtoBytes(appendProtoTag(entry, 2, wireBytes), encoded)
+			fields = appendProtoBytes(appendProtoTag(fields, 1, wireBytes), entry)
+		}
+		return appendProtoBytes(appendProtoTag(b, 5, wireBytes), fields), nil
+	case []interface{}:
+		var items []byte
+		for _, item := range v {
+			encoded, err := appendProtoValue(nil, item)
+			if err != nil {
+				return nil, err
+			}
+			items = appendProtoBytes(appendProtoTag(items, 1, wireBytes), encoded)
+		}
+		return appendProtoBytes(appendProtoTag(b, 6, wireBytes), items), nil
+	}
+	return nil, fmt.Errorf("unsupported value of type %T", v)
+}
+
+// --- .proto export ---
+
+// GetGRPCProto returns the gateway's service definition, followed by the message schemas
+// of definitions that have one (decode InvokeResponse.message with these).
+func (h *Handler) GetGRPCProto(c *fiber.Ctx) error {
+	var b strings.Builder
+	b.WriteString(grpcProto)
+	for _, api := range h.sortedCachedAPIs() {
+		if api.Protobuf == nil {
+			continue
+		}
+		fmt.Fprintf(&b, "\n// %s (%s %s)\n", api.Name, api.Method, api.Endpoint)
+		schema := renderProto(api.Protobuf)
+		b.WriteString(strings.TrimPrefix(schema, "syntax = \"proto3\";\n\n"))
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+	return c.SendString(b.String())
+}
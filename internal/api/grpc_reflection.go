@@ -0,0 +1,253 @@
+package api
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Server reflection (grpc.reflection.v1, and v1alpha for older clients) lets tools such as
+// grpcurl and Postman discover the gateway without the downloaded .proto. The descriptors
+// are encoded by hand, like the rest of the gateway, to avoid a protobuf runtime.
+const (
+	grpcReflectionV1      = "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"
+	grpcReflectionV1Alpha = "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
+
+	grpcProtoFile   = "apigen/v1/dynamic_api.proto"
+	structProtoFile = "google/protobuf/struct.proto"
+)
+
+// FieldDescriptorProto labels and types used by the descriptors below.
+const (
+	descLabelOptional = 1
+	descLabelRepeated = 3
+
+	descTypeDouble  = 1
+	descTypeInt32   = 5
+	descTypeBool    = 8
+	descTypeString  = 9
+	descTypeMessage = 11
+	descTypeBytes   = 12
+	descTypeEnum    = 14
+)
+
+// Encoded FileDescriptorProtos of the gateway service and of its dependency.
+var (
+	grpcFileDescriptor   = buildGRPCFileDescriptor()
+	structFileDescriptor = buildStructFileDescriptor()
+)
+
+// serveReflection answers the requests of a ServerReflectionInfo stream, one response each.
+func (g *grpcGateway) serveReflection(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeGRPCStatus(w, grpcInternal, "streaming not supported")
+		return
+	}
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		msg, err := readGRPCMessage(r.Body)
+		if errors.Is(err, io.EOF) {
+			break // Client closed its side of the stream
+		}
+		if err != nil {
+			w.Header().Set("Grpc-Status", strconv.Itoa(grpcInvalidArgument))
+			w.Header().Set("Grpc-Message", grpcPercentEncode(err.Error()))
+			return
+		}
+		resp := reflectionResponse(msg)
+		frame := make([]byte, 5, 5+len(resp))
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(resp)))
+		if _, err := w.Write(append(frame, resp...)); err != nil {
+			log.Printf("DEBUG: gRPC reflection client went away: %v", err)
+			return
+		}
+		flusher.Flush()
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(grpcOK))
+}
+
+// reflectionResponse builds the ServerReflectionResponse to one ServerReflectionRequest.
+func reflectionResponse(req []byte) []byte {
+	var host, filename, symbol string
+	var kind int
+	err := walkProtoFields(req, func(num, wire int, _ uint64, data []byte) error {
+		switch num {
+		case 1:
+			host = string(data)
+		case 3:
+			kind, filename = num, string(data)
+		case 4:
+			kind, symbol = num, string(data)
+		case 5, 6, 7:
+			kind = num
+		}
+		return nil
+	})
+
+	resp := appendProtoString(nil, 1, host)
+	resp = appendProtoBytes(appendProtoTag(resp, 2, wireBytes), req)
+	if err != nil {
+		return appendReflectionError(resp, grpcInvalidArgument, "invalid request: "+err.Error())
+	}
+	switch kind {
+	case 3: // file_by_filename
+		switch filename {
+		case grpcProtoFile:
+			return appendFileDescriptors(resp, grpcFileDescriptor, structFileDescriptor)
+		case structProtoFile:
+			return appendFileDescriptors(resp, structFileDescriptor)
+		}
+		return appendReflectionError(resp, grpcNotFound, "file '"+filename+"' not found")
+	case 4: // file_containing_symbol
+		switch {
+		case symbol == "apigen.v1" || strings.HasPrefix(symbol, "apigen.v1."):
+			return appendFileDescriptors(resp, grpcFileDescriptor, structFileDescriptor)
+		case strings.HasPrefix(symbol, "google.protobuf.Struct"), strings.HasPrefix(symbol, "google.protobuf.Value"),
+			symbol == "google.protobuf.ListValue", symbol == "google.protobuf.NullValue":
+			return appendFileDescriptors(resp, structFileDescriptor)
+		}
+		return appendReflectionError(resp, grpcNotFound, "symbol '"+symbol+"' not found")
+	case 7: // list_services
+		service := appendProtoString(nil, 1, grpcService)
+		list := appendProtoBytes(appendProtoTag(nil, 1, wireBytes), service)
+		return appendProtoBytes(appendProtoTag(resp, 6, wireBytes), list)
+	}
+	return appendReflectionError(resp, grpcNotFound, "extensions are not supported") // 5, 6: the gateway declares none
+}
+
+func appendFileDescriptors(resp []byte, files ...[]byte) []byte {
+	var body []byte
+	for _, file := range files {
+		body = appendProtoBytes(appendProtoTag(body, 1, wireBytes), file)
+	}
+	return appendProtoBytes(appendProtoTag(resp, 4, wireBytes), body)
+}
+
+func appendReflectionError(resp []byte, code int, message string) []byte {
+	body := binary.AppendUvarint(appendProtoTag(nil, 1, wireVarint), uint64(code))
+	body = appendProtoString(body, 2, message)
+	return appendProtoBytes(appendProtoTag(resp, 7, wireBytes), body)
+}
+
+func appendProtoString(b []byte, number int, s string) []byte {
+	return appendProtoBytes(appendProtoTag(b, number, wireBytes), []byte(s))
+}
+
+func appendProtoVarint(b []byte, number int, v uint64) []byte {
+	return binary.AppendUvarint(appendProtoTag(b, number, wireVarint), v)
+}
+
+// --- Descriptors ---
+
+// descField encodes a FieldDescriptorProto; oneof is the oneof index, -1 for none.
+func descField(name string, number, label, typ int, typeName string, oneof int) []byte {
+	b := appendProtoString(nil, 1, name)
+	b = appendProtoVarint(b, 3, uint64(number))
+	b = appendProtoVarint(b, 4, uint64(label))
+	b = appendProtoVarint(b, 5, uint64(typ))
+	if typeName != "" {
+		b = appendProtoString(b, 6, typeName)
+	}
+	if oneof >= 0 {
+		b = appendProtoVarint(b, 9, uint64(oneof))
+	}
+	return appendProtoString(b, 10, jsonFieldName(name))
+}
+
+// descMessage encodes a DescriptorProto with its fields; extra holds further encoded
+// DescriptorProto fields (nested types, oneofs, options).
+func descMessage(name string, fields [][]byte, extra []byte) []byte {
+	b := appendProtoString(nil, 1, name)
+	for _, field := range fields {
+		b = appendProtoBytes(appendProtoTag(b, 2, wireBytes), field)
+	}
+	return append(b, extra...)
+}
+
+func jsonFieldName(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func descFile(name, pkg string, deps []string, messages [][]byte, enums [][]byte, services [][]byte) []byte {
+	b := appendProtoString(nil, 1, name)
+	b = appendProtoString(b, 2, pkg)
+	for _, dep := range deps {
+		b = appendProtoString(b, 3, dep)
+	}
+	for _, m := range messages {
+		b = appendProtoBytes(appendProtoTag(b, 4, wireBytes), m)
+	}
+	for _, e := range enums {
+		b = appendProtoBytes(appendProtoTag(b, 5, wireBytes), e)
+	}
+	for _, s := range services {
+		b = appendProtoBytes(appendProtoTag(b, 6, wireBytes), s)
+	}
+	return appendProtoString(b, 12, "proto3")
+}
+
+// buildGRPCFileDescriptor describes grpcProto.
+func buildGRPCFileDescriptor() []byte {
+	request := descMessage("InvokeRequest", [][]byte{
+		descField("api", 1, descLabelOptional, descTypeString, "", -1),
+		descField("payload", 2, descLabelOptional, descTypeMessage, ".google.protobuf.Struct", -1),
+	}, nil)
+	response := descMessage("InvokeResponse", [][]byte{
+		descField("status", 1, descLabelOptional, descTypeInt32, "", -1),
+		descField("body", 2, descLabelOptional, descTypeMessage, ".google.protobuf.Value", -1),
+		descField("message", 3, descLabelOptional, descTypeBytes, "", -1),
+	}, nil)
+	method := appendProtoString(nil, 1, "Invoke")
+	method = appendProtoString(method, 2, ".apigen.v1.InvokeRequest")
+	method = appendProtoString(method, 3, ".apigen.v1.InvokeResponse")
+	service := appendProtoString(nil, 1, "DynamicAPI")
+	service = appendProtoBytes(appendProtoTag(service, 2, wireBytes), method)
+	return descFile(grpcProtoFile, "apigen.v1", []string{structProtoFile}, [][]byte{request, response}, nil, [][]byte{service})
+}
+
+// buildStructFileDescriptor describes google/protobuf/struct.proto, which clients may not
+// have locally.
+func buildStructFileDescriptor() []byte {
+	entryOptions := appendProtoVarint(nil, 7, 1) // MessageOptions.map_entry
+	entry := descMessage("FieldsEntry", [][]byte{
+		descField("key", 1, descLabelOptional, descTypeString, "", -1),
+		descField("value", 2, descLabelOptional, descTypeMessage, ".google.protobuf.Value", -1),
+	}, appendProtoBytes(appendProtoTag(nil, 7, wireBytes), entryOptions))
+	structMsg := descMessage("Struct", [][]byte{
+		descField("fields", 1, descLabelRepeated, descTypeMessage, ".google.protobuf.Struct.FieldsEntry", -1),
+	}, appendProtoBytes(appendProtoTag(nil, 3, wireBytes), entry))
+
+	kind := appendProtoBytes(appendProtoTag(nil, 8, wireBytes), appendProtoString(nil, 1, "kind"))
+	value := descMessage("Value", [][]byte{
+		descField("null_value", 1, descLabelOptional, descTypeEnum, ".google.protobuf.NullValue", 0),
+		descField("number_value", 2, descLabelOptional, descTypeDouble, "", 0),
+		descField("string_value", 3, descLabelOptional, descTypeString, "", 0),
+		descField("bool_value", 4, descLabelOptional, descTypeBool, "", 0),
+		descField("struct_value", 5, descLabelOptional, descTypeMessage, ".google.protobuf.Struct", 0),
+		descField("list_value", 6, descLabelOptional, descTypeMessage, ".google.protobuf.ListValue", 0),
+	}, kind)
+	list := descMessage("ListValue", [][]byte{
+		descField("values", 1, descLabelRepeated, descTypeMessage, ".google.protobuf.Value", -1),
+	}, nil)
+
+	nullValue := appendProtoString(nil, 1, "NULL_VALUE")
+	nullValue = appendProtoVarint(nullValue, 2, 0)
+	enum := appendProtoString(nil, 1, "NullValue")
+	enum = appendProtoBytes(appendProtoTag(enum, 2, wireBytes), nullValue)
+
+	return descFile(structProtoFile, "google.protobuf", nil, [][]byte{structMsg, value, list}, [][]byte{enum}, nil)
+}
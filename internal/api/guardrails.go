@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"api-genarator/internal/database"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/pprof"
+)
+
+// pprofPrefix is where the profiling endpoints are mounted (admin role required).
+const pprofPrefix = "/api-generator/debug/pprof"
+
+// GuardrailConfig bounds what one request, or a burst of them, may cost the process.
+// Zero values disable the corresponding limit.
+type GuardrailConfig struct {
+	MaxInFlight      int   // Requests served at the same time (all routes except /health and /readyz)
+	MaxResponseBytes int   // Largest response body; bigger ones are replaced by a 500
+	MaxFindDocuments int64 // Documents a single find may load
+	Pprof            bool  // Serve /api-generator/debug/pprof/ (requires operator login)
+}
+
+// guardrailState holds the limits and counts what they rejected, for GET /api-generator/stats.
+type guardrailState struct {
+	mu               sync.RWMutex
+	cfg              GuardrailConfig
+	inFlight         atomic.Int64
+	rejectedInFlight atomic.Int64
+	rejectedResponse atomic.Int64
+	rejectedFind     atomic.Int64
+}
+
+// ConfigureGuardrails sets the process limits. Call after ConfigureManagementAuth; the
+// limits may be changed again while serving (see ReloadRuntimeConfig).
+func (h *Handler) ConfigureGuardrails(cfg GuardrailConfig) error {
+	if cfg.MaxInFlight < 0 || cfg.MaxResponseBytes < 0 || cfg.MaxFindDocuments < 0 {
+		return errors.New("guardrail limits must not be negative")
+	}
+	if cfg.Pprof && h.mgmtAuth.cfg.Provider == nil {
+		return fmt.Errorf("pprof endpoints require operator login (OIDC_ISSUER), they would be public otherwise")
+	}
+	h.guardrails.mu.Lock()
+	h.guardrails.cfg = cfg
+	h.guardrails.mu.Unlock()
+	database.SetMaxFindDocuments(cfg.MaxFindDocuments)
+	return nil
+}
+
+func (g *guardrailState) config() GuardrailConfig {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.cfg
+}
+
+// guard rejects requests over the in-flight limit with 503 and replaces responses over
+// the size limit. It runs inside the access log, so rejections are logged like any request.
+func (h *Handler) guard(c *fiber.Ctx) error {
+	cfg := h.guardrails.config()
+	if cfg.MaxInFlight > 0 && c.Path() != "/health" && c.Path() != "/readyz" {
+		n := h.guardrails.inFlight.Add(1)
+		defer h.guardrails.inFlight.Add(-1)
+		if n > int64(cfg.MaxInFlight) {
+			h.guardrails.rejectedInFlight.Add(1)
+			log.Printf("WARN: %d requests in flight (limit %d), rejecting %s %s", n-1, cfg.MaxInFlight, c.Method(), c.Path())
+			c.Set(fiber.HeaderRetryAfter, "1")
+			return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"error": "Server is busy, retry later"})
+		}
+	}
+
+	err := c.Next()
+	if err != nil || cfg.MaxResponseBytes <= 0 || c.Response().IsBodyStream() {
+		return err
+	}
+	if size := len(c.Response().Body()); size > cfg.MaxResponseBytes {
+		h.guardrails.rejectedResponse.Add(1)
+		log.Printf("ERROR: Response of %s %s is %d bytes (limit %d), replaced by an error", c.Method(), c.Path(), size, cfg.MaxResponseBytes)
+		c.Response().ResetBody()
+		c.Response().Header.Del(fiber.HeaderContentDisposition)
+		c.Response().Header.Del(fiber.HeaderETag)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": fmt.Sprintf("Response exceeds the %d byte limit, narrow the query", cfg.MaxResponseBytes),
+		})
+	}
+	return nil
+}
+
+// tooManyDocuments counts a find rejected by MaxFindDocuments and reports whether err is one.
+func (h *Handler) tooManyDocuments(err error) bool {
+	if !errors.Is(err, database.ErrTooManyDocuments) {
+		return false
+	}
+	h.guardrails.rejectedFind.Add(1)
+	return true
+}
+
+// profiler serves the pprof endpoints when enabled; RequireOperator has already
+// checked the admin role (see requiredRole).
+func (h *Handler) profiler() fiber.Handler {
+	return pprof.New(pprof.Config{
+		Next:   func(*fiber.Ctx) bool { return !h.guardrails.config().Pprof },
+		Prefix: "/api-generator",
+	})
+}
+
+// stats reports the limits, the requests in flight and the rejections per guardrail.
+func (g *guardrailState) stats() fiber.Map {
+	cfg := g.config()
+	return fiber.Map{
+		"maxInFlight":      cfg.MaxInFlight,
+		"maxResponseBytes": cfg.MaxResponseBytes,
+		"maxFindDocuments": cfg.MaxFindDocuments,
+		"inFlight":         g.inFlight.Load(),
+		"rejected": fiber.Map{
+			"inFlight":     g.rejectedInFlight.Load(),
+			"responseSize": g.rejectedResponse.Load(),
+			"findSize":     g.rejectedFind.Load(),
+		},
+	}
+}
@@ -26,19 +26,41 @@ import (
 // Handler holds dependencies for API handlers
 type Handler struct {
 	store         *database.Store
-	dynamicRoutes map[string]models.ApiDefinition // In-memory cache
-	routesMutex   sync.RWMutex                    // Mutex for the cache
+	dynamicRoutes map[string]*routeHandle // In-memory cache of versioned route handles
+	routeVersion  uint64                  // Last handle version issued (guarded by routesMutex)
+	routesAt      time.Time               // Last change of the cache (guarded by routesMutex)
+	routesMutex   sync.RWMutex            // Mutex for the cache
+	proxies       proxyConfig             // Trusted proxies for resolving the real client IP
+	maintenance   maintenanceState        // Global maintenance switch
+	loadReport    loadReportState         // Result of the latest definition load
+	coalescer     requestGroup            // In-flight GETs shared between identical requests
+	ingest        ingestManager           // Write-behind buffers for ingestion endpoints
+	jobs          jobRunner               // Worker pool for async definitions
+	limiter       concurrencyLimiter      // Per-definition and global execution caps
+	readThrough   readThroughState        // Store fallback (with negative caching) on route cache misses
+	cluster       clusterState            // Instance identity and peer cache invalidation
+	tenantTargets sync.Map                // Resolved tenant db.collection targets already prepared
+	mgmtAuth      managementAuth          // OIDC operator login for the management API
+	approval      approvalState           // Two-step approval of definition changes
+	notifier      *lifecycleNotifier      // Lifecycle notifications (nil when not configured)
+	captures      captureState            // Calls to record as documentation examples
+	mqtt          mqttBridge              // MQTT topic subscriptions feeding definitions
+	accessLog     AccessLogConfig         // Request log format, fields and sampling
+	guardrails    guardrailState          // In-flight, response size and find size limits
+	runtime       runtimeConfigState      // Settings reloadable from the config file (SIGHUP)
+	compile       compileState            // Eager or lazy flow compilation
+	backups       backupState             // Scheduled definition backups to object storage
+	tails         tailState               // Open streaming GETs of Tail definitions
+	seeding       bool                    // POST /api-generator/seed/:name enabled (ConfigureSeeding)
+	build         BuildInfo               // Engine build reported by GET /version
+	consumers     consumerState           // Registered consumer keys and their counted calls
 }
 
 // NewHandler creates a new API handler
 func NewHandler(store *database.Store, initialRoutes map[string]models.ApiDefinition) *Handler {
-	if initialRoutes == nil {
-		initialRoutes = make(map[string]models.ApiDefinition)
-	}
-	return &Handler{
-		store:         store,
-		dynamicRoutes: initialRoutes,
-	}
+	h := &Handler{store: store}
+	h.dynamicRoutes = h.newRouteTable(initialRoutes)
+	return h
 }
 
 // --- API Definition CRUD Handlers ---
@@ -57,6 +79,20 @@ func (h *Handler) CreateAPI(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := assignOwnership(operatorFrom(c), &api); err != nil {
+		return sendNotOwner(c, api.Name, err)
+	}
+	if h.needsApproval(c) {
+		return h.submitRevision(c, models.RevisionCreate, api.Name, &api)
+	}
+	return h.createDefinition(c, &api)
+}
+
+// createDefinition stores a new definition and adds its route to the cache.
+// Shared by CreateAPI and the publishing of approved revisions.
+func (h *Handler) createDefinition(c *fiber.Ctx, def *models.ApiDefinition) error {
+	api := *def
+
 	// 2. Call database layer to create
 	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second) // Use Fiber context
 	defer cancel()
@@ -76,13 +112,14 @@ func (h *Handler) CreateAPI(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save API definition"})
 	}
 	api.ID = insertedID // Ensure ID is set from return value
+	h.prepareCollection(ctx, api)
 
 	// 3. Update cache (Write Lock)
 	key := api.Method + ":" + api.Endpoint
-	h.routesMutex.Lock()
-	h.dynamicRoutes[key] = api
-	h.routesMutex.Unlock()
+	h.swapRoute("", key, api)
 	log.Printf("INFO: Added/Updated route key '%s' in cache for API '%s'", key, api.Name)
+	h.notifyDefinitionChange(api.Name)
+	h.notifyLifecycle(c, EventDefinitionCreated, api, "")
 
 	// 4. Return response
 	return c.Status(http.StatusCreated).JSON(fiber.Map{
@@ -108,6 +145,15 @@ func (h *Handler) ListAPIs(c *fiber.Ctx) error {
 		})
 	}
 
+	owner := c.Query("owner")
+	if owner == "me" {
+		op := operatorFrom(c)
+		if op == nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "owner=me requires operator login"})
+		}
+		owner = op.Subject
+	}
+	apis = filterByOwnership(apis, owner, c.Query("team"))
 	if apis == nil {
 		apis = []models.ApiDefinition{}
 	}
@@ -137,6 +183,7 @@ func (h *Handler) GetAPIDetail(c *fiber.Ctx) error {
 		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API not found"})
 	}
 
+	c.Set(headerChecksum, definitionChecksum(*api))
 	return c.JSON(api)
 }
 
@@ -161,6 +208,29 @@ func (h *Handler) DeleteAPI(c *fiber.Ctx) error {
 		log.Printf("WARN: API not found for deletion in handler (name: %s)", name)
 		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API not found"})
 	}
+	if op := operatorFrom(c); !op.canChange(apiToDelete) {
+		return sendNotOwner(c, name, errors.New("only the owner, its team or an admin may delete it"))
+	}
+	if h.needsApproval(c) {
+		return h.submitRevision(c, models.RevisionDelete, name, nil)
+	}
+	return h.deleteDefinition(c, name)
+}
+
+// deleteDefinition removes a definition and its cached route.
+// Shared by DeleteAPI and the publishing of approved revisions.
+func (h *Handler) deleteDefinition(c *fiber.Ctx, name string) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 15*time.Second)
+	defer cancel()
+
+	apiToDelete, err := h.store.GetAPIDefinitionByName(ctx, name)
+	if errors.Is(err, database.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API not found"})
+	}
+	if err != nil {
+		log.Printf("ERROR: Handler failed find API for deletion (name: %s): %v", name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve API data before deletion"})
+	}
 	keyToDelete := apiToDelete.Method + ":" + apiToDelete.Endpoint
 
 	// 2. Call database layer to delete
@@ -179,10 +249,11 @@ func (h *Handler) DeleteAPI(c *fiber.Ctx) error {
 	log.Printf("INFO: API '%s' deleted successfully from database", name)
 
 	// 3. Remove from cache (Write Lock)
-	h.routesMutex.Lock()
-	delete(h.dynamicRoutes, keyToDelete)
-	h.routesMutex.Unlock()
+	h.removeRoute(keyToDelete)
 	log.Printf("INFO: Removed route key '%s' from cache for deleted API '%s'", keyToDelete, name)
+	h.releaseCollectionSchema(ctx, *apiToDelete)
+	h.notifyDefinitionChange(name)
+	h.notifyLifecycle(c, EventDefinitionDeleted, *apiToDelete, "")
 
 	// 4. Return response
 	return c.Status(http.StatusOK).JSON(fiber.Map{"message": "API deleted successfully"})
@@ -205,22 +276,35 @@ func (h *Handler) UpdateAPI(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(c.Context(), 15*time.Second)
 	defer cancel()
 
-	// 2. Get existing API to find the old cache key
-	// (ทำภายใน store.UpdateAPIDefinition หรือเรียก Get ก่อนก็ได้)
-	existingAPI, err := h.store.GetAPIDefinitionByName(ctx, name)
-	if err != nil {
-		log.Printf("ERROR: Handler failed find existing API for update (name: %s): %v", name, err)
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve existing API data for update"})
+	return h.applyDefinitionUpdate(ctx, c, name, &payloadToUpdate)
+}
+
+// applyDefinitionUpdate stores a full replacement definition and swaps it into the cache.
+// Shared by PUT (full payload) and PATCH (payload produced by merging into the stored definition).
+func (h *Handler) applyDefinitionUpdate(ctx context.Context, c *fiber.Ctx, name string, payloadToUpdate *models.ApiDefinition) error {
+	// 1.1 Ownership: only the owner, its team or an admin may change a definition
+	if op := operatorFrom(c); op != nil {
+		current, err := h.store.GetAPIDefinitionByName(ctx, name)
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API not found"})
+		}
+		if err != nil {
+			log.Printf("ERROR: Handler failed to find API for update (name: %s): %v", name, err)
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve existing API data for update"})
+		}
+		if !op.canChange(current) {
+			return sendNotOwner(c, name, errors.New("only the owner, its team or an admin may change it"))
+		}
+		if err := checkTeamChange(op, current, payloadToUpdate); err != nil {
+			return sendNotOwner(c, name, err)
+		}
 	}
-	if existingAPI == nil {
-		log.Printf("WARN: API not found for update in handler (name: %s)", name)
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API not found for update"})
+	if h.needsApproval(c) {
+		return h.submitRevision(c, models.RevisionUpdate, name, payloadToUpdate)
 	}
-	oldKey := existingAPI.Method + ":" + existingAPI.Endpoint
 
-	// 3. Call database layer to update
-	// สมมติว่า UpdateAPIDefinition คืน *models.ApiDefinition ที่อัปเดตแล้ว แะละ error
-	updatedAPI, err := h.store.UpdateAPIDefinition(ctx, name, &payloadToUpdate)
+	// 2. Call database layer to update (atomically; it also returns the previous version for the old cache key)
+	updatedAPI, existingAPI, err := h.store.UpdateAPIDefinition(ctx, name, payloadToUpdate)
 	if err != nil {
 		log.Printf("ERROR: Handler failed to update API (name: %s): %v", name, err)
 		if errors.Is(err, database.ErrMissingRequiredFields) { // สมมติมี error type นี้
@@ -230,7 +314,7 @@ func (h *Handler) UpdateAPI(c *fiber.Ctx) error {
 			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API not found during update"})
 		}
 		// Check for duplicate endpoint error if method/endpoint changed and conflicts
-		if errors.Is(err, database.ErrDuplicateEndpoint) {
+		if errors.Is(err, database.ErrDuplicateEndpoint) || errors.Is(err, database.ErrDuplicateName) || errors.Is(err, database.ErrDuplicateKey) {
 			return c.Status(http.StatusConflict).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update API definition"})
@@ -245,18 +329,27 @@ func (h *Handler) UpdateAPI(c *fiber.Ctx) error {
 		})
 	}
 
-	// 4. Update cache (Write Lock)
+	oldKey := existingAPI.Method + ":" + existingAPI.Endpoint
+	h.prepareCollection(ctx, *updatedAPI)
+	if updatedAPI.CollectionSchema == nil || updatedAPI.Database != existingAPI.Database || updatedAPI.Collection != existingAPI.Collection {
+		h.releaseCollectionSchema(ctx, *existingAPI)
+	}
+
+	// 3. Update cache (Write Lock)
 	newKey := updatedAPI.Method + ":" + updatedAPI.Endpoint
-	h.routesMutex.Lock()
-	if oldKey != newKey && oldKey != "" { // Remove old key if it changed
-		delete(h.dynamicRoutes, oldKey)
+	// In-flight requests keep running on the previous version; new requests get this one
+	version := h.swapRoute(oldKey, newKey, *updatedAPI)
+	if oldKey != newKey && oldKey != "" {
 		log.Printf("INFO: Removed old route key '%s' from cache for API '%s'", oldKey, name)
 	}
-	h.dynamicRoutes[newKey] = *updatedAPI // Add/Update with new key/data
-	h.routesMutex.Unlock()
-	log.Printf("INFO: API '%s' updated successfully in cache (New Key: '%s')", name, newKey)
+	log.Printf("INFO: API '%s' updated successfully in cache (New Key: '%s', version %d)", name, newKey, version)
+	h.notifyLifecycle(c, EventDefinitionPublished, *updatedAPI, "")
+	h.notifyDefinitionChange(name)
+	if updatedAPI.Name != name {
+		h.notifyDefinitionChange(updatedAPI.Name)
+	}
 
-	// 5. Return response
+	// 4. Return response
 	return c.JSON(fiber.Map{
 		"message": "API updated successfully",
 		"api":     updatedAPI,
@@ -265,6 +358,19 @@ func (h *Handler) UpdateAPI(c *fiber.Ctx) error {
 
 // --- Dynamic Route Handler ---
 
+// stripNamespaces returns a copy of data without the $path/$query/$body namespace maps,
+// so they are never used as query filters or persisted.
+func stripNamespaces(data map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		if k == core.NamespacePath || k == core.NamespaceQuery || k == core.NamespaceBody {
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
 // Helper function to convert array-style response to map
 func convertArrayToMap(data interface{}) interface{} {
 	log.Printf("DEBUG: convertArrayToMap input type: %T, value: %v", data, data)
@@ -381,31 +487,107 @@ func (h *Handler) DynamicAPIHandler(c *fiber.Ctx) error {
 	key := c.Method() + ":" + c.Path()
 
 	// 1. Find API Definition from Cache (Read Lock)
-	h.routesMutex.RLock()
-	api, exists := h.dynamicRoutes[key]
-	h.routesMutex.RUnlock()
-
+	handle, exists := h.acquireRoute(key)
 	if !exists {
-		// ถ้าไม่เจอใน cache ลองหาใน DB อีกครั้งเผื่อกรี cache ไม่ sync?
-		// หรือจะให้มี endpoint /reload APIs แทน? --> ใช้ /reload ดีกว่า
-		// ถ้าต้องกาม robust สูง อาจจะ fallback ไปหาใน DB ตรงนี้
-		// log.Printf("DEBUG: Route key '%s' not found in cache. Passing to next handler.", key)
-		return c.Next() // Not found, pass to next handler (or 404 if this is the last)
+		// ถ้าไม่เจอใน cache ลองหาใน DB อีกครั้งเผื่อกรี cache ไม่ sync (เช่น API ถูกสร้างจาก instance อื่น)
+		// Read-through is opt-in; unknown keys are negatively cached
+		handle, exists = h.readThroughRoute(c, key)
+		if !exists {
+			// log.Printf("DEBUG: Route key '%s' not found in cache. Passing to next handler.", key)
+			return c.Next() // Not found, pass to next handler (or 404 if this is the last)
+		}
+	}
+	defer handle.release()
+	api := core.ApplyEnvironment(handle.api)
+	c.Locals(apiNameLocal, api.Name) // For the access log
+	if h.compile.lazy && !handle.compiled.Load() {
+		if problems := h.compileOnFirstHit(key, handle); len(problems) > 0 {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": fmt.Sprintf("API definition '%s' is invalid, see GET /api-generator/load-report", api.Name),
+			})
+		}
+	}
+	defer func(start time.Time) { core.ObserveEndpoint(c.Context(), api.Name, time.Since(start)) }(time.Now())
+	// Deferred encoders run last-in first-out: the binary encoding sees the final JSON body
+	if encoding := negotiateEncoding(c, api); encoding != "" {
+		defer encodeResponse(c, api, encoding)
+	}
+	if api.Format == models.FormatJSONAPI {
+		defer jsonAPIErrors(c) // Error responses become JSON:API error objects
 	}
 
 	log.Printf("INFO: Matched dynamic route for API '%s': %s %s", api.Name, api.Method, api.Endpoint)
+	if api.Deprecated != nil {
+		setDeprecationHeaders(c, api.Deprecated)
+	}
+	h.trackConsumer(c, api)
 
+	// 1.1 Maintenance mode (global or per definition)
+	if cfg, active := h.activeMaintenance(api); active {
+		log.Printf("INFO: API '%s' is in maintenance mode, returning 503", api.Name)
+		return sendMaintenance(c, cfg)
+	}
+
+	// 1.2 Enforce per-definition IP allow/deny lists
+	if ip := h.clientIP(c); !ipAllowed(api.AccessControl, ip) {
+		log.Printf("WARN: Access denied for IP '%s' to API '%s'", ip, api.Name)
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	// 1.3 Verify the signature of inbound webhooks over the raw body (and reject replays)
+	if api.Webhook != nil {
+		header := func(name string) string { return c.Get(name) }
+		nonceKeys, err := core.VerifyWebhook(c.Context(), api.Webhook, header, c.BodyRaw(), time.Now())
+		if err != nil {
+			return sendWebhookError(c, api, err)
+		}
+		if len(nonceKeys) > 0 {
+			defer releaseFailedWebhook(c, api, nonceKeys)
+		}
+	}
+
+	// 1.4 Resolve per-tenant database/collection templates (from headers or token claims)
+	api, err := h.resolveTenant(c, api)
+	if err != nil {
+		return sendTenantError(c, api, err)
+	}
+	// 1.4.1 X-Environment: sandbox moves reads and writes to the definition's sandbox data
+	if api, err = h.resolveSandbox(c, api); err != nil {
+		return sendSandboxError(c, api, err)
+	}
+
+	// 1.5 Record this call as a documentation example when a capture is armed
+	if name, summary, ok := h.captures.take(api.Name); ok {
+		defer h.recordExample(c, api, name, summary)
+	}
+
+	// 1.6 Coalesce concurrent identical GETs into one flow execution
+	if api.CoalesceGets && c.Method() == fiber.MethodGet && api.Download == nil && !api.Tail {
+		return h.serveCoalesced(c, api)
+	}
+	// 1.7 Concurrency limits apply to each execution (a coalesced group counts once)
+	return h.processLimited(c, api)
+}
+
+// processAPI runs a matched definition: builds the request data, validates it,
+// executes the flow (or default logic), saves and writes the response.
+func (h *Handler) processAPI(c *fiber.Ctx, api models.ApiDefinition) error {
 	// 2. Prepare Request Data (รวม Query Params, Path Params, Body)
 	reqData := make(map[string]interface{})
+	pathData := make(map[string]interface{})
+	queryData := make(map[string]interface{})
+	bodyData := make(map[string]interface{})
 
 	// Path Params (มีความสำคัญสุด อาจะ overwrite ตัวอื่น)
 	for k, v := range c.AllParams() {
 		reqData[k] = v
+		pathData[k] = v
 	}
 
 	// Query Params (รองลงมา)
 	c.Request().URI().QueryArgs().VisitAll(func(k, v []byte) {
 		keyStr := string(k)
+		queryData[keyStr] = string(v)
 		if _, exists := reqData[keyStr]; !exists { // ใส่ถ้ายังไม่มี key ซ้ำกับ Path Param
 			reqData[keyStr] = string(v)
 		}
@@ -415,7 +597,6 @@ func (h *Handler) DynamicAPIHandler(c *fiber.Ctx) error {
 	if c.Method() == fiber.MethodPost || c.Method() == fiber.MethodPut || c.Method() == fiber.MethodPatch {
 		// ใช้ c.BodyRaw() เพื่ออ่าน body โดยไม่ consume แล้ว parse เอง หรือใช้ BodyParser ถ้าไม่ต้องการ raw body
 		// การใช้ BodyParser จะสะดวกกว่าสำหรับการแปลงเป็น map[string]interface{}
-		var bodyData map[string]interface{}
 		if err := c.BodyParser(&bodyData); err == nil {
 			for k, v := range bodyData {
 				if _, exists := reqData[k]; !exists { // ใส่ถ้ายังไม่มี key ซ้ำกับ Path/Query Param
@@ -426,32 +607,73 @@ func (h *Handler) DynamicAPIHandler(c *fiber.Ctx) error {
 			log.Printf("WARN: Cannot parse request body for API '%s' (Method: %s): %v. Body params might be ignored.", api.Name, c.Method(), err)
 		}
 	}
+
+	// Namespaced view ($path.*, $query.*, $body.*) alongside the merged flat view
+	if api.NamespacedParams {
+		reqData[core.NamespacePath] = pathData
+		reqData[core.NamespaceQuery] = queryData
+		reqData[core.NamespaceBody] = bodyData
+	}
 	log.Printf("DEBUG: Request data for API '%s': %v", api.Name, reqData)
 
-	// 3. Validate Required Parameters
-	for _, param := range api.Parameters {
-		if param.Required {
-			val, paramExists := reqData[param.Name]
-			// ตรวจสอบว่ามี key และค่าไม่เป็น nil หรือ string ว่าง (อาจจะต้องปรับตามความต้องการ)
-			if !paramExists || val == nil || fmt.Sprintf("%v", val) == "" {
-				log.Printf("WARN: Missing or empty required parameter '%s' for API '%s'", param.Name, api.Name)
-				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Missing or empty required parameter: " + param.Name})
-			}
-			// TODO: Add type validation based on param.Type
+	// 2.1 Row-level security: resolve the caller's dataScope before touching any data
+	scope, err := resolveDataScope(c, api)
+	if err != nil {
+		return sendScopeError(c, api, err)
+	}
+
+	// 2.2 Field-level permissions: reject writes to protected fields up front
+	access, err := resolveFieldAccess(c, api)
+	if err != nil {
+		return sendFieldAccessError(c, api, err)
+	}
+	if access != nil && (c.Method() == fiber.MethodPost || c.Method() == fiber.MethodPut || c.Method() == fiber.MethodPatch) {
+		if denied := unwritableFields(access, bodyData, queryData); len(denied) > 0 {
+			return sendProtectedFields(c, api, denied)
 		}
 	}
 
+	// 3. Validate parameters (required, type) and cross-field / conditional rules together,
+	// so the client gets every problem in one response
+	validationErrors := core.ValidateParameters(api.Parameters, reqData)
+	validationErrors = append(validationErrors, core.ValidateData(api.Validations, reqData)...)
+	if len(validationErrors) > 0 {
+		log.Printf("WARN: %d parameter/validation check(s) failed for API '%s'", len(validationErrors), api.Name)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"code":    http.StatusBadRequest,
+			"message": "Validation failed",
+			"errors":  validationErrors,
+		})
+	}
+
+	// 3.1 Mock definitions answer with generated data (consumers integrate before the backend exists)
+	if api.Mock {
+		return serveMock(c, api)
+	}
+
 	// 4. Check Target Database/Collection
 	if api.Database == "" || api.Collection == "" {
 		log.Printf("ERROR: API definition '%s' is missing database or collection name", api.Name)
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "API configuration error: missing target database or collection"})
 	}
 
+	// 4.1 File download definitions stream from GridFS instead of running a flow
+	if api.Download != nil {
+		return h.serveDownload(c, api, reqData)
+	}
+
+	// 4.2 Async definitions run the flow in a background worker and answer with a job ID
+	if api.Async && api.ConditionalFlow != nil {
+		return h.serveAsync(c, api, reqData, scope, access)
+	}
+
 	// 5. Process Logic (Conditional Flow or Default)
 	var response interface{}
 	var dataForSaving map[string]interface{} // ข้อมูลที่จะใช้บันทึก (อาจะต่างจาก response)
 	var saveData bool
 	var processingError error
+	var page jsonAPIPage
 	ctx, cancel := context.WithTimeout(c.Context(), 20*time.Second) // Use Fiber context
 	defer cancel()
 
@@ -460,28 +682,57 @@ func (h *Handler) DynamicAPIHandler(c *fiber.Ctx) error {
 	for k, v := range reqData {
 		currentDataState[k] = v
 	}
+	if api.NamespacedParams && api.ConditionalFlow == nil {
+		// Default logic uses the data as a filter / document, so only the merged view applies
+		currentDataState = stripNamespaces(currentDataState)
+	}
 
 	if api.ConditionalFlow != nil {
 		// --- Use Conditional Flow ---
 		log.Printf("DEBUG: Processing conditional flow for API '%s'", api.Name)
+		flowCtx := core.WithInboundRequest(core.WithAPIName(ctx, api.Name), h.clientIP(c), func(name string) string { return c.Get(name) }, c.BodyRaw())
+		flowCtx = core.WithFlowTarget(flowCtx, core.FlowTarget{UniqueKey: api.UniqueKey, Scope: scope})
+		flowCtx = core.WithBaseURLs(flowCtx, api)
+		if api.FlowBudgetMs > 0 {
+			var flowCancel context.CancelFunc
+			flowCtx, flowCancel = core.WithFlowBudget(flowCtx, time.Duration(api.FlowBudgetMs)*time.Millisecond)
+			defer flowCancel()
+		}
 		// ProcessConditionalFlow ควรคืน:
 		// 1. responseToSend: ข้อมูลที่จะส่งกลับให้กลอง client (อาจเป็น map, string, etc.)
 		// 2. finalDataState: สถานะล่าสุดของข้อมูลหลังผ่าน transform (เป็น map[string]interface{} เสมอ)
 		// 3. shouldSave: boolean บอกว่าควรบันทึก finalDataState หรือไม่
 		// 4. err: error ที่เกิดขึ้นระหว่างประมวลผล
-		responseToSend, finalDataState, shouldSave, err := core.ProcessConditionalFlow(api.ConditionalFlow, currentDataState, ctx, h.store, api.Database, api.Collection)
+		responseToSend, finalDataState, shouldSave, err := core.ProcessConditionalFlow(api.ConditionalFlow, currentDataState, flowCtx, h.store, api.Database, api.Collection)
 		if err != nil {
-			log.Printf("ERROR: Failed to process conditional flow for API '%s': %v", api.Name, err)
+			log.Printf("ERROR: Failed to process conditional flow for API '%s' (request %s): %v", api.Name, core.RequestID(ctx), err)
 			// TODO: Map specific error types from core to HTTP statuses
 			processingError = fmt.Errorf("failed to process request logic: %w", err) // เก็บ error ไว้ก่อน
 			response = fiber.Map{"error": processingError.Error()}                   // กำหนด response เป็น error message
+			var flowErr *core.FlowError
+			if errors.As(err, &flowErr) {
+				log.Printf("ERROR: Flow error in API '%s' (request %s): path=%s action=%s operator=%s field=%s: %s",
+					api.Name, core.RequestID(ctx), flowErr.Path, flowErr.Action, flowErr.Operator, flowErr.Field, flowErr.Message)
+				if core.DebugEnabled() {
+					response = fiber.Map{"error": processingError.Error(), "flowError": flowErr}
+				}
+			}
 			// พิจารณา status code ที่เหมาะสม
 			c.Status(http.StatusInternalServerError) // ตั้ง status ไว้ก่อน อาจะถูก override ถ้า error เฉพาะเจาะจงกว่า
+			if errors.Is(err, core.ErrFlowTimeout) {
+				c.Status(http.StatusGatewayTimeout)
+			}
 		} else {
 			response = responseToSend
+			if access != nil {
+				response = stripResponse(response, access)
+			}
 			saveData = shouldSave
 			if saveData {
 				dataForSaving = finalDataState // ใช้ finalDataState ในการบันทึก
+				if api.NamespacedParams {
+					dataForSaving = stripNamespaces(dataForSaving)
+				}
 			}
 		}
 		log.Printf("DEBUG: Conditional flow result for API '%s': saveData=%t, response=%v", api.Name, saveData, response)
@@ -492,44 +743,219 @@ func (h *Handler) DynamicAPIHandler(c *fiber.Ctx) error {
 		// Default logic ควรทำงานกับ currentDataState (ซึ่งเป็น copy ของ reqData)
 		switch c.Method() {
 		case fiber.MethodGet:
-			filter := bson.M{}
 			// ใช้ currentDataState (ที่มาจาก reqData) เป็น filter
-			for k, v := range currentDataState {
-				filter[k] = v
+			queryOpts := parseQueryOptions(c, api)
+			odata, err := parseODataQuery(c)
+			if err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			}
+			queryOpts.OData = odata
+			if err := checkFieldset(queryOpts.Fields); err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 			}
-			log.Printf("DEBUG: Default GET - Finding data in %s.%s with filter: %v", api.Database, api.Collection, filter)
-			results, err := h.store.FindData(ctx, api.Database, api.Collection, filter) // Assuming FindData exists
+			if len(queryOpts.Fields) > 0 && len(odata.Select) > 0 {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Use either fields or $select, not both"})
+			}
+			cursor, err := parseCursorPage(c, api)
 			if err != nil {
-				log.Printf("ERROR: Default GET - Failed to find data for API '%s': %v", api.Name, err)
-				processingError = fmt.Errorf("failed to retrieve data: %w", err)
-				response = fiber.Map{"error": processingError.Error()}
-				c.Status(http.StatusInternalServerError)
-			} else {
-				response = results
-				saveData = false // GET ไม่ควร save
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			}
+			if cursor.Set && (len(odata.OrderBy) > 0 || odata.Skip > 0 || odata.Top > 0) {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Cursor pagination (after, limit) cannot be combined with $orderby, $skip or $top"})
+			}
+			if max := h.guardrails.config().MaxFindDocuments; cursor.Set && max > 1 && cursor.Limit >= max {
+				cursor.Limit = max - 1 // The extra document read to detect a next page must stay under the guardrail
+			}
+			var projection bson.M
+			trimFields := false
+			if len(queryOpts.Fields) > 0 {
+				projection, trimFields = fieldsetProjection(queryOpts.Fields, api.Computed)
+			}
+			if api.Format == models.FormatJSONAPI {
+				if page, err = parseJSONAPIPage(c); err != nil {
+					return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+				}
+				if cursor.Set && page.Set {
+					return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Use either cursor pagination (after, limit) or page[limit]/page[offset], not both"})
+				}
+			}
+			filter := withODataFilter(buildFilter(currentDataState, queryOpts), odata.Filter)
+			if access != nil {
+				if denied := unreadableQueryFields(access, filter, queryOpts); len(denied) > 0 {
+					return sendProtectedFields(c, api, denied)
+				}
+			}
+			filter = excludeSoftDeleted(applyScope(filter, scope), api)
+			saveData = false // GET ไม่ควร save
+
+			switch {
+			case queryOpts.Bucket != "":
+				pipeline, err := buildBucketPipeline(filter, api.TimeSeries, queryOpts)
+				if err != nil {
+					log.Printf("WARN: Default GET - Invalid bucket query for API '%s': %v", api.Name, err)
+					processingError = err
+					response = fiber.Map{"error": err.Error()}
+					c.Status(http.StatusBadRequest)
+					break
+				}
+				log.Printf("DEBUG: Default GET - Bucketing by %s in %s.%s with pipeline: %v", queryOpts.Bucket, api.Database, api.Collection, pipeline)
+				results, err := h.store.AggregateData(ctx, api.Database, api.Collection, pipeline)
+				if err != nil {
+					log.Printf("ERROR: Default GET - Failed to aggregate time buckets for API '%s': %v", api.Name, err)
+					processingError = fmt.Errorf("failed to aggregate data: %w", err)
+					response = fiber.Map{"error": processingError.Error()}
+					c.Status(http.StatusInternalServerError)
+				} else {
+					response = results
+				}
+
+			case len(queryOpts.GroupBy) > 0:
+				pipeline, err := buildGroupPipeline(filter, queryOpts)
+				if err != nil {
+					log.Printf("WARN: Default GET - Invalid group-by query for API '%s': %v", api.Name, err)
+					processingError = err
+					response = fiber.Map{"error": err.Error()}
+					c.Status(http.StatusBadRequest)
+					break
+				}
+				log.Printf("DEBUG: Default GET - Group-by %v in %s.%s with pipeline: %v", queryOpts.GroupBy, api.Database, api.Collection, pipeline)
+				results, err := h.store.AggregateData(ctx, api.Database, api.Collection, pipeline)
+				if err != nil {
+					log.Printf("ERROR: Default GET - Failed to aggregate data for API '%s': %v", api.Name, err)
+					processingError = fmt.Errorf("failed to aggregate data: %w", err)
+					response = fiber.Map{"error": processingError.Error()}
+					c.Status(http.StatusInternalServerError)
+				} else {
+					response = results
+				}
+
+			case queryOpts.Search != "":
+				log.Printf("DEBUG: Default GET - Searching '%s' in %s.%s with filter: %v", queryOpts.Search, api.Database, api.Collection, filter)
+				results, err := h.store.SearchData(ctx, api.Database, api.Collection, api.SearchFields, api.SearchIndex, queryOpts.Search, filter, projection)
+				if err != nil {
+					log.Printf("ERROR: Default GET - Failed to search data for API '%s': %v", api.Name, err)
+					processingError = fmt.Errorf("failed to search data: %w", err)
+					response = fiber.Map{"error": processingError.Error()}
+					c.Status(http.StatusInternalServerError)
+				} else {
+					results = core.ComputeFields(ctx, api.Computed, results)
+					if trimFields {
+						results = trimToFieldset(results, queryOpts.Fields)
+					}
+					response = results
+					if access != nil {
+						response = stripResponse(results, access)
+					}
+				}
+
+			case queryOpts.Distinct != "":
+				log.Printf("DEBUG: Default GET - Distinct '%s' in %s.%s with filter: %v", queryOpts.Distinct, api.Database, api.Collection, filter)
+				values, err := h.store.DistinctData(ctx, api.Database, api.Collection, queryOpts.Distinct, filter)
+				if err != nil {
+					log.Printf("ERROR: Default GET - Failed to get distinct values for API '%s': %v", api.Name, err)
+					processingError = fmt.Errorf("failed to retrieve distinct values: %w", err)
+					response = fiber.Map{"error": processingError.Error()}
+					c.Status(http.StatusInternalServerError)
+				} else {
+					response = fiber.Map{"field": queryOpts.Distinct, "values": values}
+				}
+
+			case queryOpts.Count:
+				log.Printf("DEBUG: Default GET - Counting data in %s.%s with filter: %v", api.Database, api.Collection, filter)
+				count, err := h.store.CountData(ctx, api.Database, api.Collection, filter)
+				if err != nil {
+					log.Printf("ERROR: Default GET - Failed to count data for API '%s': %v", api.Name, err)
+					processingError = fmt.Errorf("failed to count data: %w", err)
+					response = fiber.Map{"error": processingError.Error()}
+					c.Status(http.StatusInternalServerError)
+				} else {
+					response = fiber.Map{"count": count}
+				}
+
+			default:
+				log.Printf("DEBUG: Default GET - Finding data in %s.%s with filter: %v", api.Database, api.Collection, filter)
+				findOpts := odata.findOptions()
+				if projection != nil {
+					findOpts.Projection = projection
+				}
+				page.apply(&findOpts)
+				if api.Tail && !cursor.Set && !odata.Present && !page.Set { // Paged reads get a regular response
+					return h.serveTail(ctx, c, api, filter, findOpts, func(ctx context.Context, docs []bson.M) []bson.M {
+						docs = core.ComputeFields(ctx, api.Computed, docs)
+						if trimFields {
+							docs = trimToFieldset(docs, queryOpts.Fields)
+						}
+						if access != nil {
+							docs = stripResponse(docs, access).([]bson.M)
+						}
+						return docs
+					})
+				}
+				stripCursor := false
+				if cursor.Set {
+					filter = cursor.filter(filter)
+					stripCursor = cursor.apply(&findOpts)
+				}
+				results, err := h.store.FindDataWith(ctx, api.Database, api.Collection, filter, findOpts)
+				if err != nil {
+					log.Printf("ERROR: Default GET - Failed to find data for API '%s': %v", api.Name, err)
+					processingError = fmt.Errorf("failed to retrieve data: %w", err)
+					response = fiber.Map{"error": processingError.Error()}
+					c.Status(http.StatusInternalServerError)
+					if h.tooManyDocuments(err) {
+						c.Status(http.StatusBadRequest) // The caller can page or filter; not a server fault
+					}
+				} else if next, token, err := cursor.next(results, stripCursor); err != nil {
+					log.Printf("ERROR: Default GET - Failed to encode the next cursor for API '%s': %v", api.Name, err)
+					processingError = fmt.Errorf("failed to encode cursor: %w", err)
+					response = fiber.Map{"error": processingError.Error()}
+					c.Status(http.StatusInternalServerError)
+				} else {
+					results = next
+					setNextCursor(c, cursor, token)
+					results = core.ComputeFields(ctx, api.Computed, results) // Before stripping, so protected fields can hide computed ones too
+					if trimFields {
+						results = trimToFieldset(results, queryOpts.Fields)
+					}
+					response = results
+					if access != nil {
+						response = stripResponse(results, access)
+					}
+					if odata.Present {
+						response, err = h.odataEnvelope(ctx, api, filter, odata, response)
+						if err != nil {
+							log.Printf("ERROR: Default GET - Failed to count data for API '%s': %v", api.Name, err)
+							processingError = fmt.Errorf("failed to count data: %w", err)
+							response = fiber.Map{"error": processingError.Error()}
+							c.Status(http.StatusInternalServerError)
+						}
+					}
+				}
 			}
 
 		case fiber.MethodPost, fiber.MethodPut:
 			// Default: บันทึกข้อมูลที่เข้ามา (currentDataState)
 			response = currentDataState // คืนข้อมูลที่รับมา (หรือที่จะบันทึก)
+			if access != nil {
+				response = stripResponse(currentDataState, access)
+			}
 			saveData = true
 			dataForSaving = currentDataState // ข้อมูลที่จะบันทึกคือข้อมูลที่เข้ามา
 			log.Printf("DEBUG: Default POST/PUT - Data to be saved: %v", dataForSaving)
 
 		case fiber.MethodDelete:
-			filter := bson.M{}
 			// ใช้ currentDataState เป็น filter
-			for k, v := range currentDataState {
-				filter[k] = v
-			}
-			if len(filter) == 0 {
-				log.Printf("WARN: Default DELETE for API '%s' called without parameters to filter.", api.Name)
-				processingError = errors.New("DELETE requires parameters to identify data to delete")
+			filter, err := deleteFilter(api, currentDataState)
+			if err != nil {
+				// Checked before the scope is applied: the scope alone would delete all of the caller's data
+				log.Printf("WARN: Default DELETE for API '%s' rejected: %v", api.Name, err)
+				processingError = err
 				response = fiber.Map{"error": processingError.Error()}
 				c.Status(http.StatusBadRequest)
 			} else {
+				filter = applyScope(filter, scope)
 				log.Printf("DEBUG: Default DELETE - Deleting data in %s.%s with filter: %v", api.Database, api.Collection, filter)
-				delCount, err := h.store.DeleteData(ctx, api.Database, api.Collection, filter) // Assuming DeleteData returns count
+				delCount, err := h.deleteData(ctx, api, filter)
 				if err != nil {
 					log.Printf("ERROR: Default DELETE - Failed to delete data for API '%s': %v", api.Name, err)
 					processingError = fmt.Errorf("failed to delete data: %w", err)
@@ -557,21 +983,51 @@ func (h *Handler) DynamicAPIHandler(c *fiber.Ctx) error {
 			c.Status(http.StatusInternalServerError)
 
 		} else {
+			if api.TimeSeries != nil {
+				// Time-series collections require a BSON date in the time field
+				dataForSaving = core.NormalizeTimeField(dataForSaving, api.TimeSeries.TimeField)
+			}
+			stampScope(dataForSaving, scope) // Saved rows always belong to the caller
 			log.Printf("DEBUG: Attempting to save data for API '%s' to %s.%s", api.Name, api.Database, api.Collection)
 			saveCtx, saveCancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer saveCancel()
 
-			err := h.store.SaveData(saveCtx, api.Database, api.Collection, api.UniqueKey, dataForSaving)
+			var err error
+			var saved *database.SaveResult
+			if api.Ingest != nil {
+				// Write-behind: queue for a bulk write instead of saving inline
+				err = h.ingest.enqueue(saveCtx, h.store, api, dataForSaving)
+				if err == nil && !api.Ingest.WaitForFlush {
+					c.Status(http.StatusAccepted)
+				}
+			} else {
+				var entry *models.DeadLetter
+				saved, entry, err = h.saveWithRetry(saveCtx, api, dataForSaving)
+				if entry != nil {
+					c.Set("X-Dead-Letter-Id", entry.ID.Hex())
+				}
+			}
+			if errors.Is(err, errIngestQueueFull) {
+				log.Printf("WARN: Ingestion queue full for API '%s', rejecting request", api.Name)
+				c.Set(fiber.HeaderRetryAfter, "1")
+				return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"error": "Server is busy, retry later"})
+			}
 			if err != nil {
-				log.Printf("ERROR: Handler failed to save data for API '%s': %v", api.Name, err)
+				log.Printf("ERROR: Handler failed to save data for API '%s' (request %s): %v", api.Name, core.RequestID(ctx), err)
 				processingError = fmt.Errorf("failed to save data to database: %w", err)
 				// ั้ง response เป็น error ถ้ายังไม่มี error ก่อนหน้า
-				if response == nil || (response.(fiber.Map)["error"] == nil) {
+				if database.IsDocumentInvalid(err) {
+					response = fiber.Map{"error": "Document does not match the collection schema", "details": err.Error()}
+					c.Status(http.StatusUnprocessableEntity)
+				} else if response == nil || (response.(fiber.Map)["error"] == nil) {
 					response = fiber.Map{"error": processingError.Error()}
 					c.Status(http.StatusInternalServerError)
 				}
 			} else {
 				log.Printf("INFO: Data saved successfully for API '%s'", api.Name)
+				if saved != nil {
+					response = echoSaveResult(c, api, response, saved)
+				}
 				// อาจะปรับ response เล็กน้อยเพื่อยืนยันว่า save สำเร็จ ถ้า response เดิมไม่มีข้อมูลนี้
 				if respMap, ok := response.(fiber.Map); ok && respMap["message"] == nil && respMap["data"] == nil {
 					respMap["message"] = "Data processed and saved successfully"
@@ -582,6 +1038,10 @@ func (h *Handler) DynamicAPIHandler(c *fiber.Ctx) error {
 	} // End if saveData
 
 	// 7. Return Final Response
+	if errors.Is(processingError, database.ErrCircuitOpen) {
+		log.Printf("WARN: Database circuit breaker is open, API '%s' returns 503", api.Name)
+		return sendUnavailable(c)
+	}
 	if processingError != nil {
 		if c.Response().StatusCode() == http.StatusOK {
 			c.Status(http.StatusInternalServerError)
@@ -589,7 +1049,7 @@ func (h *Handler) DynamicAPIHandler(c *fiber.Ctx) error {
 		if respMap, ok := response.(fiber.Map); !ok || respMap["error"] == nil {
 			response = fiber.Map{"error": processingError.Error()}
 		}
-		log.Printf("DEBUG: Returning error response for API '%s': Status=%d, Body=%v", api.Name, c.Response().StatusCode(), response)
+		log.Printf("DEBUG: Returning error response for API '%s' (request %s): Status=%d, Body=%v", api.Name, core.RequestID(ctx), c.Response().StatusCode(), response)
 		return c.JSON(response)
 	}
 
@@ -649,6 +1109,9 @@ func (h *Handler) DynamicAPIHandler(c *fiber.Ctx) error {
 	}
 
 	c.Status(statusCode)
+	if api.Format == models.FormatJSONAPI {
+		return sendJSONAPI(c, api, response, page)
+	}
 
 	// Ensure response is in fiber.Map format
 	if _, ok := response.(fiber.Map); !ok {
@@ -752,6 +1215,89 @@ func (h *Handler) DynamicAPIHandler(c *fiber.Ctx) error {
 
 // --- Helper Functions (อาจะมี ถ้าจำเป็น) ---
 
+// PrepareCollections makes sure the target collections of the cached definitions are set up
+// (time-series collections created, text indexes for search ensured).
+// Failures are logged and do not stop the server.
+func (h *Handler) PrepareCollections(ctx context.Context) {
+	for _, api := range h.cachedAPIs() {
+		h.prepareCollection(ctx, api)
+	}
+}
+
+// prepareCollection applies the collection-level settings of a single definition to
+// its target on this server's environment.
+func (h *Handler) prepareCollection(ctx context.Context, api models.ApiDefinition) {
+	h.prepareTarget(ctx, core.ApplyEnvironment(api))
+}
+
+// prepareTarget applies the collection-level settings to api.Database/api.Collection as
+// given (a resolved tenant or sandbox target). The time-series collection must exist
+// before any index is created, otherwise MongoDB implicitly creates a regular collection.
+func (h *Handler) prepareTarget(ctx context.Context, api models.ApiDefinition) {
+	if isTenantRouted(api) {
+		return // Prepared per resolved tenant target on first use
+	}
+	if api.TimeSeries != nil {
+		if err := h.store.EnsureTimeSeriesCollection(ctx, api.Database, api.Collection, api.TimeSeries); err != nil {
+			log.Printf("WARN: Could not ensure time-series collection for API '%s' on %s.%s: %v", api.Name, api.Database, api.Collection, err)
+		}
+	}
+	if api.CollectionSchema != nil {
+		if err := h.store.ApplyCollectionSchema(ctx, api.Database, api.Collection, api.CollectionSchema); err != nil {
+			log.Printf("WARN: Could not apply collection schema of API '%s' to %s.%s: %v", api.Name, api.Database, api.Collection, err)
+		}
+	}
+	h.ensureSearchIndex(ctx, api)
+	if api.CursorField != "" && api.CursorField != "_id" {
+		if err := h.store.EnsureCursorIndex(ctx, api.Database, api.Collection, api.CursorField); err != nil {
+			log.Printf("WARN: Could not ensure cursor index for API '%s' on %s.%s: %v", api.Name, api.Database, api.Collection, err)
+		}
+	}
+}
+
+// releaseCollectionSchema runs when a definition that declared a collection schema is
+// deleted, drops the schema or moves to another collection. Another definition's schema
+// for the same collection is applied instead; without one the validator is removed.
+func (h *Handler) releaseCollectionSchema(ctx context.Context, previous models.ApiDefinition) {
+	if previous.CollectionSchema == nil || isTenantRouted(previous) {
+		return
+	}
+	for _, api := range h.cachedAPIs() {
+		if api.Name != previous.Name && api.CollectionSchema != nil && api.Database == previous.Database && api.Collection == previous.Collection {
+			h.prepareCollection(ctx, api)
+			return
+		}
+	}
+	if err := h.store.RemoveCollectionSchema(ctx, previous.Database, previous.Collection); err != nil {
+		log.Printf("WARN: Could not remove collection schema of API '%s' from %s.%s: %v", previous.Name, previous.Database, previous.Collection, err)
+	}
+}
+
+// ensureSearchIndex creates the text index for a definition with SearchFields, and drops
+// the one left behind once they are removed, unless another definition on the same
+// collection searches it. Definitions backed by an Atlas Search index
+// (SearchIndex) manage their index in Atlas instead.
+func (h *Handler) ensureSearchIndex(ctx context.Context, api models.ApiDefinition) {
+	if api.SearchIndex != "" {
+		return
+	}
+	if len(api.SearchFields) == 0 {
+		for _, other := range h.cachedAPIs() {
+			target := core.ApplyEnvironment(other)
+			if other.Name != api.Name && len(other.SearchFields) > 0 && target.Database == api.Database && target.Collection == api.Collection {
+				return // The index is another definition's
+			}
+		}
+		if err := h.store.DropTextIndex(ctx, api.Database, api.Collection); err != nil {
+			log.Printf("WARN: Could not drop text index of API '%s' on %s.%s: %v", api.Name, api.Database, api.Collection, err)
+		}
+		return
+	}
+	if err := h.store.EnsureTextIndex(ctx, api.Database, api.Collection, api.SearchFields); err != nil {
+		log.Printf("WARN: Could not ensure text index for API '%s' on %s.%s: %v", api.Name, api.Database, api.Collection, err)
+	}
+}
+
 // ตัวอย่าง ReloadAPIs (ต้องเพิ่มใน Handler และ Routes)
 /*
 func (h *Handler) ReloadAPIs(c *fiber.Ctx) error {
@@ -759,15 +1305,13 @@ func (h *Handler) ReloadAPIs(c *fiber.Ctx) error {
 	loadCtx, loadCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer loadCancel()
 
-	newAPIs, err := h.store.LoadAPIs(loadCtx)
+	newAPIs, _, err := h.store.LoadAPIs(loadCtx)
 	if err != nil {
 		log.Printf("ERROR: Failed to reload APIs from database: %v", err)
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reload APIs"})
 	}
 
-	h.routesMutex.Lock()
-	h.dynamicRoutes = newAPIs // Replace the entire map
-	h.routesMutex.Unlock()
+	h.replaceRoutes(newAPIs) // Replace the entire map
 
 	count := len(newAPIs)
 	log.Printf("INFO: Successfully reloaded %d APIs into cache.", count)
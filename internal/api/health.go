@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+
+	"api-genarator/internal/database"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// unavailableRetryAfter is the Retry-After (seconds) sent while the database breaker is open;
+// it matches the breaker's cooldown before the next probe.
+const unavailableRetryAfter = "10"
+
+// sendUnavailable answers 503 for requests failed fast by the database circuit breaker.
+func sendUnavailable(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderRetryAfter, unavailableRetryAfter)
+	return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"error": database.ErrCircuitOpen.Error()})
+}
+
+// Readyz reports whether this instance can serve traffic: 503 while the database
+// circuit breaker is open, so load balancers stop routing requests here.
+func (h *Handler) Readyz(c *fiber.Ctx) error {
+	breaker := h.store.BreakerStats()
+	status, code := "ready", http.StatusOK
+	if breaker.State == database.BreakerOpen {
+		status, code = "unavailable", http.StatusServiceUnavailable
+		c.Set(fiber.HeaderRetryAfter, unavailableRetryAfter)
+	}
+	return c.Status(code).JSON(fiber.Map{
+		"status":   status,
+		"database": breaker,
+	})
+}
@@ -0,0 +1,276 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+)
+
+// Defaults for ingestion (write-behind) mode.
+const (
+	defaultIngestBatchSize     = 500
+	defaultIngestFlushInterval = time.Second
+	defaultIngestMaxQueue      = 10000
+)
+
+// errIngestQueueFull is returned when a buffer is full and the definition rejects instead of blocking.
+var errIngestQueueFull = errors.New("ingestion queue is full")
+
+// ingestItem is one document waiting to be written. done is set when the caller waits for the flush.
+type ingestItem struct {
+	doc  map[string]interface{}
+	done chan error
+}
+
+// ingestBuffer batches documents for one target collection and flushes them in bulk.
+type ingestBuffer struct {
+	name      string
+	dbName    string
+	collName  string
+	uniqueKey string
+	dataScope map[string]interface{}
+	batchSize int
+	interval  time.Duration
+	block     bool
+	maxQueue  int
+
+	queue  chan ingestItem
+	mu     sync.RWMutex // Guards closed against concurrent enqueue
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// ingestManager owns the write-behind buffers, one per definition and target.
+type ingestManager struct {
+	mu       sync.Mutex
+	buffers  map[string]*ingestBuffer
+	retiring sync.WaitGroup // Buffers replaced after a definition update, still flushing
+}
+
+// enqueue hands a document to the definition's buffer. With WaitForFlush it returns the
+// result of the bulk write containing the document; otherwise it returns once queued.
+func (m *ingestManager) enqueue(ctx context.Context, store *database.Store, api models.ApiDefinition, doc map[string]interface{}) error {
+	buf := m.buffer(store, api)
+	item := ingestItem{doc: doc}
+	if api.Ingest.WaitForFlush {
+		item.done = make(chan error, 1)
+	}
+
+	buf.mu.RLock()
+	if buf.closed {
+		buf.mu.RUnlock()
+		// A concurrent definition update may have just replaced the buffer
+		if next := m.buffer(store, api); next != buf {
+			return m.enqueue(ctx, store, api, doc)
+		}
+		return errors.New("ingestion buffer is shut down")
+	}
+	if buf.block {
+		select {
+		case buf.queue <- item:
+		case <-ctx.Done():
+			buf.mu.RUnlock()
+			return fmt.Errorf("%w: %w", errIngestQueueFull, ctx.Err())
+		}
+	} else {
+		select {
+		case buf.queue <- item:
+		default:
+			buf.mu.RUnlock()
+			return errIngestQueueFull
+		}
+	}
+	buf.mu.RUnlock()
+
+	if item.done == nil {
+		return nil
+	}
+	select {
+	case err := <-item.done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for ingestion flush: %w", ctx.Err())
+	}
+}
+
+// buffer returns the buffer for a definition, starting its flusher on first use. When the
+// definition's ingestion options or data scope changed since, the old buffer is closed
+// (it flushes what it holds in the background) and replaced.
+func (m *ingestManager) buffer(store *database.Store, api models.ApiDefinition) *ingestBuffer {
+	key := api.Name + "|" + api.Database + "." + api.Collection + "|" + api.UniqueKey
+	want := newIngestBuffer(api)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if buf, ok := m.buffers[key]; ok {
+		if buf.sameSettings(want) {
+			return buf
+		}
+		m.retire(buf)
+		log.Printf("INFO: Ingestion options of API '%s' changed, replacing its buffer", api.Name)
+	}
+	if m.buffers == nil {
+		m.buffers = make(map[string]*ingestBuffer)
+	}
+
+	buf := want
+	buf.queue = make(chan ingestItem, buf.maxQueue)
+	buf.wg.Add(1)
+	go buf.run(store)
+	m.buffers[key] = buf
+	log.Printf("INFO: Started ingestion buffer for API '%s' (%s.%s, batch %d, interval %s, queue %d)",
+		api.Name, api.Database, api.Collection, buf.batchSize, buf.interval, buf.maxQueue)
+	return buf
+}
+
+// newIngestBuffer builds an unstarted buffer, without its queue, from the definition's options.
+func newIngestBuffer(api models.ApiDefinition) *ingestBuffer {
+	opts := api.Ingest
+	buf := &ingestBuffer{
+		name:      api.Name,
+		dbName:    api.Database,
+		collName:  api.Collection,
+		uniqueKey: api.UniqueKey,
+		dataScope: api.DataScope,
+		batchSize: defaultIngestBatchSize,
+		interval:  defaultIngestFlushInterval,
+		block:     opts.OnFull == "block",
+	}
+	if opts.BatchSize > 0 {
+		buf.batchSize = opts.BatchSize
+	}
+	if opts.FlushIntervalMs > 0 {
+		buf.interval = time.Duration(opts.FlushIntervalMs) * time.Millisecond
+	}
+	buf.maxQueue = defaultIngestMaxQueue
+	if opts.MaxQueue > 0 {
+		buf.maxQueue = opts.MaxQueue
+	}
+	return buf
+}
+
+// sameSettings reports whether b was built from the same options and data scope as other.
+func (b *ingestBuffer) sameSettings(other *ingestBuffer) bool {
+	return b.batchSize == other.batchSize && b.interval == other.interval && b.block == other.block &&
+		b.maxQueue == other.maxQueue && reflect.DeepEqual(b.dataScope, other.dataScope)
+}
+
+// retire stops a replaced buffer from accepting documents; its flusher writes what is
+// queued and exits, and shutdown waits for it. Callers hold m.mu.
+func (m *ingestManager) retire(buf *ingestBuffer) {
+	buf.mu.Lock()
+	if !buf.closed {
+		buf.closed = true
+		close(buf.queue)
+	}
+	buf.mu.Unlock()
+	m.retiring.Add(1)
+	go func() {
+		buf.wg.Wait()
+		m.retiring.Done()
+	}()
+}
+
+// run collects queued documents and flushes them when the batch is full or the interval elapses.
+func (b *ingestBuffer) run(store *database.Store) {
+	defer b.wg.Done()
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	batch := make([]ingestItem, 0, b.batchSize)
+	for {
+		select {
+		case item, ok := <-b.queue:
+			if !ok {
+				b.flush(store, batch)
+				return
+			}
+			batch = append(batch, item)
+			if len(batch) >= b.batchSize {
+				b.flush(store, batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				b.flush(store, batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+func (b *ingestBuffer) flush(store *database.Store, batch []ingestItem) {
+	if len(batch) == 0 {
+		return
+	}
+	docs := make([]map[string]interface{}, len(batch))
+	for i, item := range batch {
+		docs[i] = item.doc
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	attempts, err := withRetry(ctx, saveAttempts, func() error {
+		return store.SaveBatch(ctx, b.dbName, b.collName, b.uniqueKey, docs, core.ScopeKeys(b.dataScope)...)
+	})
+	if err != nil {
+		log.Printf("ERROR: Ingestion flush of %d documents for API '%s' failed: %v", len(docs), b.name, err)
+		target := models.ApiDefinition{Name: b.name, Database: b.dbName, Collection: b.collName, UniqueKey: b.uniqueKey, DataScope: b.dataScope}
+		deadLetter(store, "ingest", target, docs, attempts, err)
+	} else {
+		log.Printf("DEBUG: Ingestion flushed %d documents for API '%s' to %s.%s", len(docs), b.name, b.dbName, b.collName)
+	}
+	for _, item := range batch {
+		if item.done != nil {
+			item.done <- err
+		}
+	}
+}
+
+// shutdown stops accepting documents and flushes everything still queued.
+func (m *ingestManager) shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	buffers := make([]*ingestBuffer, 0, len(m.buffers))
+	for _, buf := range m.buffers {
+		buffers = append(buffers, buf)
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		for _, buf := range buffers {
+			buf.mu.Lock()
+			if !buf.closed {
+				buf.closed = true
+				close(buf.queue)
+			}
+			buf.mu.Unlock()
+			buf.wg.Wait()
+		}
+		m.retiring.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("ingestion buffers not fully flushed: %w", ctx.Err())
+	}
+}
+
+// Shutdown finishes queued async jobs, flushes pending write-behind buffers and consumer
+// usage counts and delivers queued lifecycle notifications; call it after the server
+// stops accepting requests.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.tails.shutdown()
+	jobsErr := h.jobs.shutdown(ctx) // Jobs may still enqueue into the ingestion buffers
+	return errors.Join(jobsErr, h.ingest.shutdown(ctx), h.notifier.shutdown(ctx), h.flushConsumerUsage(ctx))
+}
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Defaults for the async job worker pool.
+const (
+	defaultJobWorkers   = 4
+	defaultJobQueueSize = 1000
+	jobTimeout          = 10 * time.Minute // Upper bound for one job when the definition has no flowBudgetMs
+	staleJobAge         = time.Hour        // Unfinished jobs older than this are treated as lost
+)
+
+// errJobQueueFull is returned when every worker is busy and the queue has no room.
+var errJobQueueFull = errors.New("job queue is full")
+
+// jobTask is one async flow execution waiting for a worker.
+type jobTask struct {
+	id     primitive.ObjectID
+	api    models.ApiDefinition
+	data   map[string]interface{}
+	scope  map[string]interface{} // Resolved dataScope of the caller
+	access *core.FieldAccess      // Caller's field permissions, nil when unrestricted
+	corr   core.Correlation       // Request ID and trace of the request that queued the job
+}
+
+// jobRunner is the worker pool for async definitions. Workers start on first use.
+type jobRunner struct {
+	mu        sync.Mutex
+	workers   int
+	queueSize int
+	queue     chan jobTask
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// ConfigureJobs sets the async worker count and queue size. Call it before serving requests.
+func (h *Handler) ConfigureJobs(workers, queueSize int) {
+	h.jobs.mu.Lock()
+	defer h.jobs.mu.Unlock()
+	h.jobs.workers = workers
+	h.jobs.queueSize = queueSize
+}
+
+// RecoverJobs fails jobs left unfinished by a previous process.
+func (h *Handler) RecoverJobs(ctx context.Context) {
+	count, err := h.store.FailStaleJobs(ctx, time.Now().UTC().Add(-staleJobAge))
+	if err != nil {
+		log.Printf("WARN: Could not mark stale async jobs as failed: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("WARN: Marked %d unfinished async jobs from a previous run as failed", count)
+	}
+}
+
+// submit queues a task without blocking, starting the workers on first use.
+func (r *jobRunner) submit(h *Handler, task jobTask) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return errors.New("job workers are shut down")
+	}
+	if r.queue == nil {
+		workers, queueSize := r.workers, r.queueSize
+		if workers <= 0 {
+			workers = defaultJobWorkers
+		}
+		if queueSize <= 0 {
+			queueSize = defaultJobQueueSize
+		}
+		r.queue = make(chan jobTask, queueSize)
+		for i := 0; i < workers; i++ {
+			r.wg.Add(1)
+			go r.work(h)
+		}
+		log.Printf("INFO: Started %d async job workers (queue %d)", workers, queueSize)
+	}
+	select {
+	case r.queue <- task:
+		return nil
+	default:
+		return errJobQueueFull
+	}
+}
+
+func (r *jobRunner) work(h *Handler) {
+	defer r.wg.Done()
+	for task := range r.queue {
+		h.runJob(task)
+	}
+}
+
+// shutdown stops accepting jobs and waits for the queued ones to finish.
+func (r *jobRunner) shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	if r.queue == nil || r.closed {
+		r.closed = true
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.queue)
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("async jobs not finished before shutdown: %w", ctx.Err())
+	}
+}
+
+// serveAsync records a job for the request, queues it and answers 202 with the job ID.
+func (h *Handler) serveAsync(c *fiber.Ctx, api models.ApiDefinition, reqData, scope map[string]interface{}, access *core.FieldAccess) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	job, err := h.store.CreateJob(ctx, api.Name)
+	if errors.Is(err, database.ErrCircuitOpen) {
+		return sendUnavailable(c)
+	}
+	if err != nil {
+		log.Printf("ERROR: Failed to create async job for API '%s': %v", api.Name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create job"})
+	}
+
+	data := make(map[string]interface{}, len(reqData))
+	for k, v := range reqData {
+		data[k] = v
+	}
+	corr, _ := core.CorrelationFrom(c.Context())
+	if err := h.jobs.submit(h, jobTask{id: job.ID, api: api, data: data, scope: scope, access: access, corr: corr}); err != nil {
+		log.Printf("WARN: Rejecting async job for API '%s': %v", api.Name, err)
+		if finishErr := h.store.FinishJob(ctx, job.ID, nil, err); finishErr != nil {
+			log.Printf("ERROR: Failed to mark rejected job %s: %v", job.ID.Hex(), finishErr)
+		}
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"error": "Server is busy, retry later"})
+	}
+
+	statusURL := "/api-generator/jobs/" + job.ID.Hex()
+	log.Printf("INFO: Queued async job %s for API '%s'", job.ID.Hex(), api.Name)
+	c.Set(fiber.HeaderLocation, statusURL)
+	return c.Status(http.StatusAccepted).JSON(fiber.Map{
+		"status": "accepted",
+		"code":   http.StatusAccepted,
+		"data": fiber.Map{
+			"jobId":     job.ID.Hex(),
+			"statusUrl": statusURL,
+		},
+	})
+}
+
+// runJob executes an async flow, saves its data like a synchronous request would and records the outcome.
+func (h *Handler) runJob(task jobTask) {
+	api := task.api
+	ctx, cancel := context.WithTimeout(core.WithAPIName(core.WithCorrelation(context.Background(), task.corr), api.Name), jobTimeout)
+	defer cancel()
+	ctx = core.WithFlowTarget(ctx, core.FlowTarget{UniqueKey: api.UniqueKey, Scope: task.scope})
+	ctx = core.WithBaseURLs(ctx, api)
+	if api.FlowBudgetMs > 0 {
+		var budgetCancel context.CancelFunc
+		ctx, budgetCancel = core.WithFlowBudget(ctx, time.Duration(api.FlowBudgetMs)*time.Millisecond)
+		defer budgetCancel()
+	}
+	if err := h.store.StartJob(ctx, task.id); err != nil {
+		log.Printf("WARN: Could not mark job %s as running: %v", task.id.Hex(), err)
+	}
+
+	result, finalState, shouldSave, err := core.ProcessConditionalFlow(api.ConditionalFlow, task.data, ctx, h.store, api.Database, api.Collection)
+	if task.access != nil {
+		result = stripResponse(result, task.access)
+	}
+	if err != nil {
+		log.Printf("ERROR: Async job %s for API '%s' (request %s) failed: %v", task.id.Hex(), api.Name, task.corr.RequestID, err)
+		err = fmt.Errorf("failed to process request logic: %w", err)
+	} else if shouldSave {
+		doc := finalState
+		if api.NamespacedParams {
+			doc = stripNamespaces(doc)
+		}
+		if api.TimeSeries != nil {
+			doc = core.NormalizeTimeField(doc, api.TimeSeries.TimeField)
+		}
+		stampScope(doc, task.scope)
+		saveCtx, saveCancel := context.WithTimeout(core.WithCorrelation(context.Background(), task.corr), 10*time.Second)
+		if api.Ingest != nil {
+			err = h.ingest.enqueue(saveCtx, h.store, api, doc)
+		} else {
+			_, _, err = h.saveWithRetry(saveCtx, api, doc)
+		}
+		saveCancel()
+		if err != nil {
+			log.Printf("ERROR: Async job %s failed to save data for API '%s': %v", task.id.Hex(), api.Name, err)
+			err = fmt.Errorf("failed to save data to database: %w", err)
+		}
+	}
+
+	// The job context may have run out; the outcome is still recorded
+	finishCtx, finishCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer finishCancel()
+	if finishErr := h.store.FinishJob(finishCtx, task.id, result, err); finishErr != nil {
+		log.Printf("ERROR: Failed to record outcome of job %s: %v", task.id.Hex(), finishErr)
+		return
+	}
+	if err == nil {
+		log.Printf("INFO: Async job %s for API '%s' succeeded", task.id.Hex(), api.Name)
+	}
+}
+
+// GetJob reports the status and result of an async job
+func (h *Handler) GetJob(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	job, err := h.store.GetJob(ctx, c.Params("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Job not found"})
+		}
+		log.Printf("ERROR: Handler failed to get job: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve job"})
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   job,
+	})
+}
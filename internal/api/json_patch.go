@@ -0,0 +1,287 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// patchOperation is a single RFC 6902 JSON Patch operation.
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+var errPatchTestFailed = errors.New("test operation failed")
+
+// applyJSONPatch applies the operations in order to a decoded JSON document.
+// The whole patch fails if any operation fails.
+func applyJSONPatch(doc interface{}, ops []patchOperation) (interface{}, error) {
+	var err error
+	for i, op := range ops {
+		doc, err = applyPatchOperation(doc, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+func applyPatchOperation(doc interface{}, op patchOperation) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add":
+		return pointerAdd(doc, path, op.Value)
+	case "remove":
+		doc, _, err = pointerRemove(doc, path)
+		return doc, err
+	case "replace":
+		if _, err := pointerGet(doc, path); err != nil {
+			return nil, err
+		}
+		if doc, _, err = pointerRemove(doc, path); err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, op.Value)
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := pointerGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if strings.HasPrefix(op.Path+"/", op.From+"/") && op.Path != op.From {
+				return nil, errors.New("cannot move a value into one of its children")
+			}
+			if doc, _, err = pointerRemove(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			value = deepCopyJSON(value)
+		}
+		return pointerAdd(doc, path, value)
+	case "test":
+		value, err := pointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, op.Value) {
+			return nil, errPatchTestFailed
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown op '%s'", op.Op)
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer '%s'", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func pointerGet(doc interface{}, path []string) (interface{}, error) {
+	node := doc
+	for _, token := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			value, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("path member '%s' does not exist", token)
+			}
+			node = value
+		case []interface{}:
+			idx, err := arrayIndex(token, len(n), false)
+			if err != nil {
+				return nil, err
+			}
+			node = n[idx]
+		default:
+			return nil, fmt.Errorf("cannot traverse into '%s'", token)
+		}
+	}
+	return node, nil
+}
+
+// pointerAdd adds value at path, inserting into arrays and creating or replacing object members.
+func pointerAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return updateParent(doc, path, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[key] = value
+			return p, nil
+		case []interface{}:
+			if key == "-" {
+				return append(p, value), nil
+			}
+			idx, err := arrayIndex(key, len(p), true)
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, nil)
+			copy(p[idx+1:], p[idx:])
+			p[idx] = value
+			return p, nil
+		}
+		return nil, fmt.Errorf("cannot add '%s' to a non-container value", key)
+	})
+}
+
+// pointerRemove removes the value at path and returns it.
+func pointerRemove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+	var removed interface{}
+	doc, err := updateParent(doc, path, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			value, ok := p[key]
+			if !ok {
+				return nil, fmt.Errorf("path member '%s' does not exist", key)
+			}
+			removed = value
+			delete(p, key)
+			return p, nil
+		case []interface{}:
+			idx, err := arrayIndex(key, len(p), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = p[idx]
+			return append(p[:idx], p[idx+1:]...), nil
+		}
+		return nil, fmt.Errorf("cannot remove '%s' from a non-container value", key)
+	})
+	return doc, removed, err
+}
+
+// updateParent walks to the parent of the last token, applies fn to it and writes the
+// (possibly reallocated) parent back, since growing or shrinking a slice returns a new slice.
+func updateParent(node interface{}, path []string, fn func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(node, path[0])
+	}
+	child, err := pointerGet(node, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	newChild, err := updateParent(child, path[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		n[path[0]] = newChild
+	case []interface{}:
+		idx, _ := strconv.Atoi(path[0]) // Validated by pointerGet above
+		n[idx] = newChild
+	}
+	return node, nil
+}
+
+// arrayIndex parses an array reference token; allowEnd permits len (insert at end).
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index '%s'", token)
+	}
+	if idx > length || (idx == length && !allowEnd) {
+		return 0, fmt.Errorf("array index %d out of range", idx)
+	}
+	return idx, nil
+}
+
+func deepCopyJSON(value interface{}) interface{} {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var copied interface{}
+	if err := json.Unmarshal(raw, &copied); err != nil {
+		return value
+	}
+	return copied
+}
+
+// PatchAPIFlow applies an RFC 6902 JSON Patch to the conditionalFlow of a definition.
+// Pointers are relative to the flow, e.g. "/then/conditionalFlow/conditions/0/value".
+func (h *Handler) PatchAPIFlow(c *fiber.Ctx) error {
+	name := c.Params("name")
+
+	var ops []patchOperation
+	if err := json.Unmarshal(c.Body(), &ops); err != nil {
+		log.Printf("WARN: Cannot parse JSON Patch for PatchAPIFlow (name: %s): %v", name, err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Body must be a JSON Patch array"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 15*time.Second)
+	defer cancel()
+
+	existingAPI, err := h.store.GetAPIDefinitionByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API not found"})
+		}
+		log.Printf("ERROR: Handler failed to find API for flow patch (name: %s): %v", name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve existing API data for update"})
+	}
+
+	var flow interface{}
+	if existingAPI.ConditionalFlow != nil {
+		flow = deepCopyJSON(existingAPI.ConditionalFlow)
+	}
+	patchedFlow, err := applyJSONPatch(flow, ops)
+	if err != nil {
+		status := http.StatusUnprocessableEntity
+		if errors.Is(err, errPatchTestFailed) {
+			status = http.StatusConflict
+		}
+		return c.Status(status).JSON(fiber.Map{"error": "JSON Patch failed: " + err.Error()})
+	}
+
+	var newFlow *models.ConditionalBlock
+	if patchedFlow != nil {
+		raw, _ := json.Marshal(patchedFlow)
+		if err := json.Unmarshal(raw, &newFlow); err != nil {
+			return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Patched conditionalFlow is invalid: " + err.Error()})
+		}
+	}
+
+	// Log the operations themselves, they are a compact diff of the change
+	log.Printf("INFO: Applying %d JSON Patch operations to conditionalFlow of API '%s': %s", len(ops), name, string(c.Body()))
+	existingAPI.ConditionalFlow = newFlow
+	return h.applyDefinitionUpdate(ctx, c, name, existingAPI)
+}
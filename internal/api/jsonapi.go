@@ -0,0 +1,272 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	jsonAPIMediaType = "application/vnd.api+json"
+	paramPageLimit   = "page[limit]"
+	paramPageOffset  = "page[offset]"
+)
+
+// jsonAPIPage is the offset pagination of a JSON:API collection request.
+type jsonAPIPage struct {
+	Set    bool
+	Limit  int64
+	Offset int64
+}
+
+// parseJSONAPIPage reads page[limit] and page[offset].
+func parseJSONAPIPage(c *fiber.Ctx) (jsonAPIPage, error) {
+	var page jsonAPIPage
+	var err error
+	if v := c.Query(paramPageLimit); v != "" {
+		if page.Limit, err = strconv.ParseInt(v, 10, 64); err != nil || page.Limit <= 0 {
+			return page, fmt.Errorf("%s must be a positive integer", paramPageLimit)
+		}
+		page.Set = true
+	}
+	if v := c.Query(paramPageOffset); v != "" {
+		if page.Offset, err = strconv.ParseInt(v, 10, 64); err != nil || page.Offset < 0 {
+			return page, fmt.Errorf("%s must be a non-negative integer", paramPageOffset)
+		}
+		page.Set = true
+	}
+	return page, nil
+}
+
+// apply limits a find to the page. An explicit OData $top/$skip wins.
+func (p jsonAPIPage) apply(opts *database.FindOptions) {
+	if p.Limit > 0 && opts.Limit == 0 {
+		opts.Limit = p.Limit
+	}
+	if p.Offset > 0 && opts.Skip == 0 {
+		opts.Skip = p.Offset
+	}
+}
+
+// sendJSONAPI writes a successful response as a JSON:API document. Documents with an ID
+// become resource objects; anything else (counts, flow results without IDs) goes to meta.
+func sendJSONAPI(c *fiber.Ctx, api models.ApiDefinition, response interface{}, page jsonAPIPage) error {
+	generic, err := toGenericJSON(response)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to encode response"})
+	}
+	if envelope, ok := generic.(map[string]interface{}); ok {
+		if data, ok := envelope["data"]; ok {
+			generic = data
+		}
+	}
+
+	doc := fiber.Map{
+		"jsonapi": fiber.Map{"version": "1.1"},
+		"links":   fiber.Map{"self": c.BaseURL() + c.OriginalURL()},
+	}
+	switch v := generic.(type) {
+	case nil:
+		doc["data"] = nil
+	case []interface{}:
+		resources := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				break
+			}
+			resource, ok := jsonAPIResource(m, api)
+			if !ok {
+				break
+			}
+			resources = append(resources, resource)
+		}
+		if len(resources) != len(v) {
+			doc["meta"] = fiber.Map{"results": v}
+			break
+		}
+		doc["data"] = resources
+		if page.Set {
+			addPageLinks(c, doc["links"].(fiber.Map), page, len(resources))
+		}
+	case map[string]interface{}:
+		if resource, ok := jsonAPIResource(v, api); ok {
+			doc["data"] = resource
+		} else {
+			doc["meta"] = v
+		}
+	default:
+		doc["meta"] = fiber.Map{"result": v}
+	}
+
+	c.Set(fiber.HeaderContentType, jsonAPIMediaType)
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to encode response"})
+	}
+	return c.Send(body)
+}
+
+// jsonAPIResource converts a document to a resource object; ok is false without an ID.
+func jsonAPIResource(doc map[string]interface{}, api models.ApiDefinition) (fiber.Map, bool) {
+	var opts models.JSONAPIOptions
+	if api.JSONAPI != nil {
+		opts = *api.JSONAPI
+	}
+	idField := opts.IDField
+	if idField == "" {
+		idField = "_id"
+	}
+	id, ok := doc[idField]
+	if !ok || id == nil {
+		return nil, false
+	}
+	resourceType := opts.Type
+	if resourceType == "" {
+		resourceType = api.Collection
+	}
+
+	attributes := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		if k == idField || k == "_id" {
+			continue
+		}
+		if _, isRel := opts.Relationships[k]; isRel {
+			continue
+		}
+		attributes[k] = v
+	}
+	resource := fiber.Map{"type": resourceType, "id": jsonAPIID(id), "attributes": attributes}
+
+	relationships := fiber.Map{}
+	for field, relType := range opts.Relationships {
+		value, ok := doc[field]
+		if !ok {
+			continue
+		}
+		switch ids := value.(type) {
+		case nil:
+			relationships[field] = fiber.Map{"data": nil}
+		case []interface{}:
+			linkage := make([]fiber.Map, 0, len(ids))
+			for _, relID := range ids {
+				linkage = append(linkage, fiber.Map{"type": relType, "id": jsonAPIID(relID)})
+			}
+			relationships[field] = fiber.Map{"data": linkage}
+		default:
+			relationships[field] = fiber.Map{"data": fiber.Map{"type": relType, "id": jsonAPIID(ids)}}
+		}
+	}
+	if len(relationships) > 0 {
+		resource["relationships"] = relationships
+	}
+	return resource, true
+}
+
+// jsonAPIID renders an ID as the string JSON:API requires.
+func jsonAPIID(id interface{}) string {
+	switch v := id.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(id)
+}
+
+// addPageLinks adds first/prev/next links for offset pagination. next is only
+// present when the page came back full.
+func addPageLinks(c *fiber.Ctx, links fiber.Map, page jsonAPIPage, count int) {
+	if page.Limit <= 0 {
+		return
+	}
+	links["first"] = pageLink(c, page.Limit, 0)
+	if page.Offset > 0 {
+		links["prev"] = pageLink(c, page.Limit, max(page.Offset-page.Limit, 0))
+	}
+	if int64(count) == page.Limit {
+		links["next"] = pageLink(c, page.Limit, page.Offset+page.Limit)
+	}
+}
+
+func pageLink(c *fiber.Ctx, limit, offset int64) string {
+	u, err := url.Parse(c.OriginalURL())
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	q.Set(paramPageLimit, strconv.FormatInt(limit, 10))
+	q.Set(paramPageOffset, strconv.FormatInt(offset, 10))
+	u.RawQuery = q.Encode()
+	return c.BaseURL() + u.String()
+}
+
+// jsonAPIErrors rewrites an error response ({"error": ...}) of a JSON:API definition
+// into a JSON:API error document. Deferred by the dynamic handler.
+func jsonAPIErrors(c *fiber.Ctx) {
+	status := c.Response().StatusCode()
+	if status < http.StatusBadRequest {
+		return
+	}
+	var body map[string]interface{}
+	if json.Unmarshal(c.Response().Body(), &body) != nil {
+		return
+	}
+	if _, done := body["errors"]; done {
+		return
+	}
+
+	errObj := fiber.Map{"status": strconv.Itoa(status), "title": http.StatusText(status)}
+	meta := fiber.Map{}
+	for k, v := range body {
+		switch k {
+		case "error", "message":
+			if detail, ok := v.(string); ok && errObj["detail"] == nil {
+				errObj["detail"] = detail
+			}
+		case "status", "code":
+		default:
+			meta[k] = v
+		}
+	}
+	if len(meta) > 0 {
+		errObj["meta"] = meta
+	}
+	out, err := json.Marshal(fiber.Map{"errors": []fiber.Map{errObj}})
+	if err != nil {
+		return
+	}
+	c.Response().SetBody(out)
+	c.Set(fiber.HeaderContentType, jsonAPIMediaType)
+}
+
+// toGenericJSON converts a response (bson.M, primitive.D, structs) to plain JSON values.
+func toGenericJSON(v interface{}) (interface{}, error) {
+	if d, ok := v.(primitive.D); ok {
+		var m bson.M
+		raw, err := bson.Marshal(d)
+		if err == nil {
+			err = bson.Unmarshal(raw, &m)
+		}
+		if err != nil {
+			return nil, err
+		}
+		v = m
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(raw, &out)
+	return out, err
+}
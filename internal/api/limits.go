@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// errConcurrencyLimit is returned when no execution slot became free in time.
+var errConcurrencyLimit = errors.New("concurrency limit reached")
+
+// slotPool is a counting semaphore; a nil pool never limits.
+type slotPool struct {
+	slots chan struct{}
+}
+
+func newSlotPool(max int) *slotPool {
+	if max <= 0 {
+		return nil
+	}
+	return &slotPool{slots: make(chan struct{}, max)}
+}
+
+// acquire takes a slot, waiting up to wait for one to free up.
+func (p *slotPool) acquire(ctx context.Context, wait time.Duration) error {
+	if p == nil {
+		return nil
+	}
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if wait <= 0 {
+		return errConcurrencyLimit
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return errConcurrencyLimit
+	case <-ctx.Done():
+		return errConcurrencyLimit
+	}
+}
+
+func (p *slotPool) release() {
+	if p != nil {
+		<-p.slots
+	}
+}
+
+func (p *slotPool) active() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.slots)
+}
+
+// concurrencyLimiter caps executions per definition and for the whole process.
+type concurrencyLimiter struct {
+	mu         sync.Mutex
+	global     *slotPool
+	globalMax  int
+	globalWait time.Duration
+	perAPI     map[string]*slotPool // Keyed by definition name
+	perAPIMax  map[string]int       // Limit each pool was built with, to rebuild on change
+}
+
+// ConfigureConcurrency sets the process-wide cap on dynamic API executions and how long an
+// excess request may queue for a slot. max <= 0 disables the global cap.
+func (h *Handler) ConfigureConcurrency(max int, queueTimeout time.Duration) {
+	h.limiter.mu.Lock()
+	defer h.limiter.mu.Unlock()
+	if h.limiter.global == nil || h.limiter.globalMax != max { // A reload with the same cap keeps the slots held
+		h.limiter.global = newSlotPool(max)
+		h.limiter.globalMax = max
+	}
+	h.limiter.globalWait = queueTimeout
+}
+
+// pool returns the definition's pool, rebuilding it when its limit was changed by an update.
+func (l *concurrencyLimiter) pool(api models.ApiDefinition) *slotPool {
+	if api.Concurrency == nil || api.Concurrency.MaxConcurrent <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.perAPI == nil {
+		l.perAPI = make(map[string]*slotPool)
+		l.perAPIMax = make(map[string]int)
+	}
+	if p, ok := l.perAPI[api.Name]; ok && l.perAPIMax[api.Name] == api.Concurrency.MaxConcurrent {
+		return p
+	}
+	// Requests holding slots of a replaced pool release them into that pool
+	p := newSlotPool(api.Concurrency.MaxConcurrent)
+	l.perAPI[api.Name] = p
+	l.perAPIMax[api.Name] = api.Concurrency.MaxConcurrent
+	return p
+}
+
+// acquire takes a definition slot and then a global slot. The returned func releases both.
+func (l *concurrencyLimiter) acquire(ctx context.Context, api models.ApiDefinition) (func(), error) {
+	apiPool := l.pool(api)
+	var apiWait time.Duration
+	if api.Concurrency != nil {
+		apiWait = time.Duration(api.Concurrency.QueueTimeoutMs) * time.Millisecond
+	}
+	if err := apiPool.acquire(ctx, apiWait); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	global, globalWait := l.global, l.globalWait
+	l.mu.Unlock()
+	if err := global.acquire(ctx, globalWait); err != nil {
+		apiPool.release()
+		return nil, err
+	}
+	return func() {
+		global.release()
+		apiPool.release()
+	}, nil
+}
+
+// stats reports the global cap and the executions currently holding slots.
+func (l *concurrencyLimiter) stats() fiber.Map {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	perAPI := make(map[string]int, len(l.perAPI))
+	for name, p := range l.perAPI {
+		if n := p.active(); n > 0 {
+			perAPI[name] = n
+		}
+	}
+	return fiber.Map{
+		"globalLimit":  l.globalMax,
+		"globalActive": l.global.active(),
+		"active":       perAPI,
+	}
+}
+
+// processLimited runs processAPI inside the definition's and the global concurrency limits.
+// Requests that cannot get a slot in time receive 429 with Retry-After.
+func (h *Handler) processLimited(c *fiber.Ctx, api models.ApiDefinition) error {
+	release, err := h.limiter.acquire(c.Context(), api)
+	if err != nil {
+		retryAfter := 1
+		if api.Concurrency != nil && api.Concurrency.RetryAfter > 0 {
+			retryAfter = api.Concurrency.RetryAfter
+		}
+		log.Printf("WARN: Concurrency limit reached for API '%s', returning 429", api.Name)
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+		return c.Status(http.StatusTooManyRequests).JSON(fiber.Map{"error": "Too many concurrent requests, retry later"})
+	}
+	defer release()
+	return h.processAPI(c, api)
+}
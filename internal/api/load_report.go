@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LoadReport summarizes the outcome of loading stored definitions into the route cache.
+type LoadReport struct {
+	LoadedAt time.Time                   `json:"loadedAt"`
+	Loaded   int                         `json:"loaded"`
+	Rejected []models.RejectedDefinition `json:"rejected"`
+}
+
+// loadReportState keeps the most recent load report for the report endpoint.
+type loadReportState struct {
+	mu     sync.RWMutex
+	report LoadReport
+}
+
+// CheckLoadedDefinitions runs the engine checks over loaded definitions and moves the
+// ones that would fail at request time into the rejected list.
+func CheckLoadedDefinitions(apis map[string]models.ApiDefinition, rejected []models.RejectedDefinition) (map[string]models.ApiDefinition, []models.RejectedDefinition) {
+	valid := make(map[string]models.ApiDefinition, len(apis))
+	keys := make([]string, 0, len(apis))
+	for key := range apis {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // Stable report order
+
+	for _, key := range keys {
+		api := apis[key]
+		problems := compileDefinition(key, api, false)
+		if len(problems) == 0 {
+			valid[key] = api
+			continue
+		}
+		reasons := make([]string, 0, len(problems))
+		for _, p := range problems {
+			reasons = append(reasons, p.Path+": "+p.Message)
+		}
+		log.Printf("WARN: Rejecting API definition '%s' (%s): %v", api.Name, key, reasons)
+		rejected = append(rejected, models.RejectedDefinition{ID: api.ID.Hex(), Name: api.Name, Key: key, Reasons: reasons})
+	}
+	return valid, rejected
+}
+
+// SetLoadReport records the result of the latest definition load.
+func (h *Handler) SetLoadReport(report LoadReport) {
+	if report.Rejected == nil {
+		report.Rejected = []models.RejectedDefinition{}
+	}
+	h.loadReport.mu.Lock()
+	h.loadReport.report = report
+	h.loadReport.mu.Unlock()
+	for _, rejected := range report.Rejected {
+		method, endpoint, _ := strings.Cut(rejected.Key, ":")
+		target := models.ApiDefinition{Name: rejected.Name, Method: method, Endpoint: endpoint}
+		h.notifyLifecycle(nil, EventDefinitionRejected, target, strings.Join(rejected.Reasons, "; "))
+	}
+}
+
+// routeConflicts returns the rejections caused by duplicate route keys.
+func routeConflicts(rejected []models.RejectedDefinition) []models.RejectedDefinition {
+	conflicts := []models.RejectedDefinition{}
+	for _, r := range rejected {
+		if r.ConflictsWith != "" {
+			conflicts = append(conflicts, r)
+		}
+	}
+	return conflicts
+}
+
+// GetLoadReport lists the definitions that were rejected at load time and why
+func (h *Handler) GetLoadReport(c *fiber.Ctx) error {
+	h.loadReport.mu.RLock()
+	report := h.loadReport.report
+	h.loadReport.mu.RUnlock()
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   report,
+	})
+}
+
+// GetStats returns counters for the route cache and the latest load
+func (h *Handler) GetStats(c *fiber.Ctx) error {
+	h.loadReport.mu.RLock()
+	report := h.loadReport.report
+	h.loadReport.mu.RUnlock()
+
+	h.routesMutex.RLock()
+	routeCount := len(h.dynamicRoutes)
+	h.routesMutex.RUnlock()
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data": fiber.Map{
+			"instance":    h.cluster.cfg.InstanceID,
+			"routes":      routeCount,
+			"loadedAt":    report.LoadedAt,
+			"loaded":      report.Loaded,
+			"rejected":    len(report.Rejected),
+			"conflicts":   routeConflicts(report.Rejected),
+			"maintenance": h.maintenance.get().Enabled,
+			"indexes":     h.store.IndexesReady(),
+			"concurrency": h.limiter.stats(),
+			"database":    h.store.BreakerStats(),
+			"guardrails":  h.guardrails.stats(),
+		},
+	})
+}
+
+// GetSlowReport lists the slowest endpoints, flow steps and database queries of this
+// instance (?limit=20&sort=avg|max|slow).
+func (h *Handler) GetSlowReport(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 20)
+	sortBy := c.Query("sort", "avg")
+	if sortBy != "avg" && sortBy != "max" && sortBy != "slow" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "sort must be avg, max or slow"})
+	}
+	report := core.SlowReport(limit, sortBy)
+	report["instance"] = h.cluster.cfg.InstanceID
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   report,
+	})
+}
+
+// ResetSlowReport clears the recorded timings, e.g. after fixing a definition.
+func (h *Handler) ResetSlowReport(c *fiber.Ctx) error {
+	core.ResetSlowReport()
+	log.Printf("INFO: Slow report reset by %s", actorName(c))
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"code":    http.StatusOK,
+		"message": "Slow report reset",
+	})
+}
+
+// ValidateAPIs re-reads all stored definitions and reports which would be rejected
+// (including duplicate route keys) without touching the route cache
+func (h *Handler) ValidateAPIs(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 30*time.Second)
+	defer cancel()
+
+	apis, rejected, err := h.store.LoadAPIs(ctx)
+	if err != nil {
+		log.Printf("ERROR: Handler failed to load API definitions for validation: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load API definitions"})
+	}
+	apis, rejected = CheckLoadedDefinitions(apis, rejected)
+	if rejected == nil {
+		rejected = []models.RejectedDefinition{}
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data": fiber.Map{
+			"valid":     len(rejected) == 0,
+			"loadable":  len(apis),
+			"rejected":  rejected,
+			"conflicts": routeConflicts(rejected),
+		},
+	})
+}
+
+// LintAPI reports problems that would reject a definition (errors) and
+// non-fatal issues that are likely logic bugs (warnings)
+func (h *Handler) LintAPI(c *fiber.Ctx) error {
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	api, err := h.store.GetAPIDefinitionByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API not found"})
+		}
+		log.Printf("ERROR: Handler failed to find API for lint (name: %s): %v", name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve API definition"})
+	}
+
+	problems := core.CheckDefinition(*api)
+	if problems == nil {
+		problems = []core.DefinitionProblem{}
+	}
+	warnings := core.LintDefinition(*api)
+	if warnings == nil {
+		warnings = []core.LintWarning{}
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data": fiber.Map{
+			"name":     api.Name,
+			"errors":   problems,
+			"warnings": warnings,
+		},
+	})
+}
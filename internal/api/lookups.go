@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RefreshLookupTables loads the lookup tables used by "$lookup" references and reloads
+// them every interval, so changes made through other instances are picked up.
+func (h *Handler) RefreshLookupTables(ctx context.Context, interval time.Duration) {
+	refreshEvery(ctx, interval, "lookup tables", func(loadCtx context.Context) error {
+		tables, err := h.store.ListLookupTables(loadCtx)
+		if err == nil {
+			core.SetLookupTables(tables)
+		}
+		return err
+	})
+}
+
+// refreshEvery runs load now and then every interval until ctx ends. A failed load
+// keeps what was loaded before.
+func refreshEvery(ctx context.Context, interval time.Duration, what string, load func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		loadCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		err := load(loadCtx)
+		cancel()
+		if err != nil {
+			log.Printf("WARN: Could not refresh %s, keeping the loaded ones: %v", what, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Handler) lookupTables() namedResource[models.LookupTable] {
+	return namedResource[models.LookupTable]{
+		kind:    "lookup table",
+		list:    h.store.ListLookupTables,
+		get:     h.store.GetLookupTable,
+		save:    h.store.SaveLookupTable,
+		delete:  h.store.DeleteLookupTable,
+		setName: func(table *models.LookupTable, name string) { table.Name = name },
+		check: func(table models.LookupTable) []core.DefinitionProblem {
+			if table.Values == nil {
+				return []core.DefinitionProblem{{Path: "values", Message: "lookup table requires values"}}
+			}
+			return nil
+		},
+		saved:   core.PutLookupTable,
+		deleted: core.DropLookupTable,
+		detail:  func(table models.LookupTable) string { return fmt.Sprintf("%d entries", len(table.Values)) },
+	}
+}
+
+// ListLookupTables returns all lookup tables
+func (h *Handler) ListLookupTables(c *fiber.Ctx) error {
+	return h.lookupTables().handleList(c)
+}
+
+// GetLookupTable returns a single lookup table
+func (h *Handler) GetLookupTable(c *fiber.Ctx) error {
+	return h.lookupTables().handleGet(c)
+}
+
+// PutLookupTable creates or replaces a lookup table. It takes effect on this instance
+// immediately and on other instances at their next refresh.
+func (h *Handler) PutLookupTable(c *fiber.Ctx) error {
+	return h.lookupTables().handlePut(c)
+}
+
+// DeleteLookupTable removes a lookup table. References to it then resolve like
+// ordinary fields (usually to nothing).
+func (h *Handler) DeleteLookupTable(c *fiber.Ctx) error {
+	return h.lookupTables().handleDelete(c)
+}
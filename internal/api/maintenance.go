@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maintenanceState holds the global (instance-wide) maintenance switch for dynamic APIs.
+type maintenanceState struct {
+	mu     sync.RWMutex
+	config models.MaintenanceConfig
+}
+
+func (m *maintenanceState) get() models.MaintenanceConfig {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.config
+}
+
+func (m *maintenanceState) set(cfg models.MaintenanceConfig) {
+	m.mu.Lock()
+	m.config = cfg
+	m.mu.Unlock()
+}
+
+// SetMaintenance switches global maintenance mode on or off (e.g. from configuration at startup).
+func (h *Handler) SetMaintenance(cfg models.MaintenanceConfig) {
+	h.maintenance.set(cfg)
+}
+
+// activeMaintenance returns the maintenance configuration that applies to a definition, if any.
+// A definition-level flag takes precedence so it can carry its own message.
+func (h *Handler) activeMaintenance(api models.ApiDefinition) (models.MaintenanceConfig, bool) {
+	if api.Maintenance != nil && api.Maintenance.Enabled {
+		return *api.Maintenance, true
+	}
+	if global := h.maintenance.get(); global.Enabled {
+		return global, true
+	}
+	return models.MaintenanceConfig{}, false
+}
+
+// sendMaintenance writes the 503 downtime response with Retry-After.
+func sendMaintenance(c *fiber.Ctx, cfg models.MaintenanceConfig) error {
+	if cfg.RetryAfter > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(cfg.RetryAfter))
+	}
+	if cfg.Payload != nil {
+		return c.Status(http.StatusServiceUnavailable).JSON(cfg.Payload)
+	}
+	message := cfg.Message
+	if message == "" {
+		message = "Service is temporarily unavailable due to maintenance"
+	}
+	return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{
+		"status":  "error",
+		"code":    http.StatusServiceUnavailable,
+		"message": message,
+	})
+}
+
+// GetMaintenance reports the global maintenance state
+func (h *Handler) GetMaintenance(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   h.maintenance.get(),
+	})
+}
+
+// UpdateMaintenance toggles global maintenance mode for all dynamic APIs
+func (h *Handler) UpdateMaintenance(c *fiber.Ctx) error {
+	var cfg models.MaintenanceConfig
+	if err := c.BodyParser(&cfg); err != nil {
+		log.Printf("WARN: Cannot parse JSON for UpdateMaintenance: %v", err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	h.maintenance.set(cfg)
+	log.Printf("INFO: Global maintenance mode set to %t", cfg.Enabled)
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"code":    http.StatusOK,
+		"message": "Maintenance mode updated",
+		"data":    cfg,
+	})
+}
+
+// UpdateAPIMaintenance toggles maintenance mode for a single API definition. Only those who
+// may change the definition may toggle it; with approval required, the toggle becomes an
+// update revision like any other definition change.
+func (h *Handler) UpdateAPIMaintenance(c *fiber.Ctx) error {
+	name := c.Params("name")
+	var cfg models.MaintenanceConfig
+	if err := c.BodyParser(&cfg); err != nil {
+		log.Printf("WARN: Cannot parse JSON for UpdateAPIMaintenance (name: %s): %v", name, err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	existing, ok, err := h.changeableDefinition(ctx, c, name)
+	if !ok {
+		return err
+	}
+	if h.needsApproval(c) {
+		existing.Maintenance = &cfg
+		return h.submitRevision(c, models.RevisionUpdate, name, existing)
+	}
+
+	updatedAPI, err := h.store.SetAPIMaintenance(ctx, name, &cfg)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API not found"})
+		}
+		log.Printf("ERROR: Handler failed to update maintenance for API '%s': %v", name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update maintenance mode"})
+	}
+
+	key := updatedAPI.Method + ":" + updatedAPI.Endpoint
+	h.swapRoute("", key, *updatedAPI)
+	log.Printf("INFO: Maintenance mode for API '%s' set to %t", name, cfg.Enabled)
+	h.notifyDefinitionChange(name)
+	if cfg.Enabled {
+		h.notifyLifecycle(c, EventDefinitionDisabled, *updatedAPI, cfg.Message)
+	} else {
+		h.notifyLifecycle(c, EventDefinitionEnabled, *updatedAPI, "")
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"code":    http.StatusOK,
+		"message": "API maintenance mode updated",
+		"data":    updatedAPI,
+	})
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"api-genarator/internal/core"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *Handler) mappingProfiles() namedResource[models.MappingProfile] {
+	return namedResource[models.MappingProfile]{
+		kind:    "mapping profile",
+		list:    h.store.ListMappingProfiles,
+		get:     h.store.GetMappingProfile,
+		save:    h.store.SaveMappingProfile,
+		delete:  h.store.DeleteMappingProfile,
+		setName: func(profile *models.MappingProfile, name string) { profile.Name = name },
+		check:   core.CheckMappingProfile,
+		saved:   func(profile models.MappingProfile) { core.ForgetMappingProfile(profile.Name) },
+		deleted: core.ForgetMappingProfile,
+	}
+}
+
+// ListMappingProfiles returns all mapping profiles
+func (h *Handler) ListMappingProfiles(c *fiber.Ctx) error {
+	return h.mappingProfiles().handleList(c)
+}
+
+// GetMappingProfile returns a single mapping profile
+func (h *Handler) GetMappingProfile(c *fiber.Ctx) error {
+	return h.mappingProfiles().handleGet(c)
+}
+
+// PutMappingProfile creates or replaces a mapping profile. Definitions using it pick up
+// the change on their next request (other instances within a short cache period).
+func (h *Handler) PutMappingProfile(c *fiber.Ctx) error {
+	return h.mappingProfiles().handlePut(c)
+}
+
+// DeleteMappingProfile removes a mapping profile. Definitions still referencing it fail
+// at the action that uses it.
+func (h *Handler) DeleteMappingProfile(c *fiber.Ctx) error {
+	return h.mappingProfiles().handleDelete(c)
+}
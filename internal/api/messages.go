@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RefreshMessageCatalogs loads the catalogs used by $t(...) and reloads them every
+// interval, so changes made through other instances are picked up.
+func (h *Handler) RefreshMessageCatalogs(ctx context.Context, interval time.Duration) {
+	refreshEvery(ctx, interval, "message catalogs", func(loadCtx context.Context) error {
+		catalogs, err := h.store.ListMessageCatalogs(loadCtx)
+		if err == nil {
+			core.SetMessageCatalogs(catalogs)
+		}
+		return err
+	})
+}
+
+func (h *Handler) messageCatalogs() namedResource[models.MessageCatalog] {
+	return namedResource[models.MessageCatalog]{
+		kind:    "message catalog",
+		list:    h.store.ListMessageCatalogs,
+		get:     h.store.GetMessageCatalog,
+		save:    h.store.SaveMessageCatalog,
+		delete:  h.store.DeleteMessageCatalog,
+		setName: func(catalog *models.MessageCatalog, locale string) { catalog.Locale = locale },
+		check: func(catalog models.MessageCatalog) []core.DefinitionProblem {
+			if catalog.Messages == nil {
+				return []core.DefinitionProblem{{Path: "messages", Message: "message catalog requires messages"}}
+			}
+			return nil
+		},
+		saved:   core.PutMessageCatalog,
+		deleted: core.DropMessageCatalog,
+		detail:  func(catalog models.MessageCatalog) string { return fmt.Sprintf("%d messages", len(catalog.Messages)) },
+	}
+}
+
+// ListMessageCatalogs returns the catalogs of all locales
+func (h *Handler) ListMessageCatalogs(c *fiber.Ctx) error {
+	return h.messageCatalogs().handleList(c)
+}
+
+// GetMessageCatalog returns the catalog of one locale
+func (h *Handler) GetMessageCatalog(c *fiber.Ctx) error {
+	return h.messageCatalogs().handleGet(c)
+}
+
+// PutMessageCatalog creates or replaces the catalog of a locale. It takes effect on this
+// instance immediately and on other instances at their next refresh.
+func (h *Handler) PutMessageCatalog(c *fiber.Ctx) error {
+	return h.messageCatalogs().handlePut(c)
+}
+
+// DeleteMessageCatalog removes the catalog of a locale. Requests for it then fall back
+// to the next requested locale or the default one.
+func (h *Handler) DeleteMessageCatalog(c *fiber.Ctx) error {
+	return h.messageCatalogs().handleDelete(c)
+}
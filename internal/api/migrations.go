@@ -0,0 +1,225 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// migrationTimeout bounds a migration started through the API; a run cut off by it is
+// recorded as failed and resumes at the interrupted step.
+const migrationTimeout = 30 * time.Minute
+
+// ListMigrations returns the migrations in the order they are applied (?definition=orders)
+func (h *Handler) ListMigrations(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	migrations, err := h.store.ListMigrations(ctx, c.Query("definition"))
+	if err != nil {
+		log.Printf("ERROR: Handler failed to list migrations: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list migrations"})
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   migrations,
+	})
+}
+
+// GetMigration returns a single migration with its run record
+func (h *Handler) GetMigration(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	migration, err := h.store.GetMigration(ctx, nameParam(c))
+	if err != nil {
+		return sendMigrationError(c, nameParam(c), err)
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   migration,
+	})
+}
+
+// PutMigration creates or replaces a migration that has not been applied yet. Only
+// operators allowed to change the definition may write its migrations.
+func (h *Handler) PutMigration(c *fiber.Ctx) error {
+	var migration models.Migration
+	if err := c.BodyParser(&migration); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body", "details": err.Error()})
+	}
+	migration.Name = nameParam(c)
+	if problems := core.CheckMigration(migration); len(problems) > 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Migration is invalid", "errors": problems})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+	if _, ok := h.migrationDefinition(ctx, c, migration.Definition); !ok {
+		return nil
+	}
+	if err := h.store.SaveMigration(ctx, &migration); err != nil {
+		return sendMigrationError(c, migration.Name, err)
+	}
+	log.Printf("INFO: Migration '%s' of API '%s' saved by %s", migration.Name, migration.Definition, actorName(c))
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   migration,
+	})
+}
+
+// DeleteMigration removes a migration that has not been applied
+func (h *Handler) DeleteMigration(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	name := nameParam(c)
+	migration, err := h.store.GetMigration(ctx, name)
+	if err != nil {
+		return sendMigrationError(c, name, err)
+	}
+	if _, ok := h.migrationDefinition(ctx, c, migration.Definition); !ok {
+		return nil
+	}
+	if err := h.store.DeleteMigration(ctx, name); err != nil {
+		return sendMigrationError(c, name, err)
+	}
+	log.Printf("INFO: Migration '%s' deleted by %s", name, actorName(c))
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"code":    http.StatusOK,
+		"message": "Migration deleted",
+	})
+}
+
+// ApplyMigration handles POST /api-generator/migrations/:name/apply. With ?dryRun=true
+// it only counts the documents each step would touch.
+func (h *Handler) ApplyMigration(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), migrationTimeout)
+	defer cancel()
+
+	name := nameParam(c)
+	migration, err := h.store.GetMigration(ctx, name)
+	if err != nil {
+		return sendMigrationError(c, name, err)
+	}
+	api, ok := h.migrationDefinition(ctx, c, migration.Definition)
+	if !ok {
+		return nil
+	}
+	if c.QueryBool("dryRun") {
+		counts, err := core.PreviewMigration(ctx, h.store, migration, api)
+		if err != nil {
+			return sendMigrationError(c, name, err)
+		}
+		return c.JSON(fiber.Map{
+			"status": "success",
+			"code":   http.StatusOK,
+			"data":   fiber.Map{"migration": migration, "matching": counts},
+		})
+	}
+
+	run, err := core.ApplyMigration(ctx, h.store, migration, api, actorName(c))
+	if err != nil {
+		return sendMigrationRunError(c, name, run, err)
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   run,
+	})
+}
+
+// MigrateDefinition handles POST /api-generator/migrate/:name: applies the pending
+// migrations of a definition in name order, stopping at the first failure.
+func (h *Handler) MigrateDefinition(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), migrationTimeout)
+	defer cancel()
+
+	name := nameParam(c)
+	api, ok := h.migrationDefinition(ctx, c, name)
+	if !ok {
+		return nil
+	}
+	migrations, err := h.store.ListMigrations(ctx, name)
+	if err != nil {
+		log.Printf("ERROR: Handler failed to list migrations of API '%s': %v", name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list migrations"})
+	}
+
+	runs := []*models.MigrationRun{}
+	for i := range migrations {
+		migration := &migrations[i]
+		if migration.Run != nil && migration.Run.Status == models.MigrationApplied {
+			continue
+		}
+		run, err := core.ApplyMigration(ctx, h.store, migration, api, actorName(c))
+		if err != nil {
+			return sendMigrationRunError(c, migration.Name, run, err)
+		}
+		runs = append(runs, run)
+	}
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"code":    http.StatusOK,
+		"message": "Migrations applied",
+		"data":    runs,
+	})
+}
+
+// migrationDefinition loads the definition a migration belongs to and checks that the
+// operator may change it. On failure the response is already sent.
+func (h *Handler) migrationDefinition(ctx context.Context, c *fiber.Ctx, name string) (*models.ApiDefinition, bool) {
+	api, err := h.store.GetAPIDefinitionByName(ctx, name)
+	if errors.Is(err, database.ErrNotFound) {
+		c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API '" + name + "' not found"})
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("ERROR: Handler failed to find API '%s' for a migration: %v", name, err)
+		c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve API data"})
+		return nil, false
+	}
+	if op := operatorFrom(c); !op.canChange(api) {
+		sendNotOwner(c, name, errors.New("only the owner, its team or an admin may migrate its data"))
+		return nil, false
+	}
+	return api, true
+}
+
+// sendMigrationRunError answers a failed run with its record, so the caller sees which step failed.
+func sendMigrationRunError(c *fiber.Ctx, name string, run *models.MigrationRun, err error) error {
+	if run == nil {
+		return sendMigrationError(c, name, err)
+	}
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Migration '" + name + "' failed: " + err.Error(),
+		"data":  run,
+	})
+}
+
+func sendMigrationError(c *fiber.Ctx, name string, err error) error {
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Migration not found"})
+	case errors.Is(err, database.ErrMigrationApplied), errors.Is(err, database.ErrMigrationRunning):
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Migration '" + name + "': " + err.Error()})
+	case errors.Is(err, core.ErrTenantCollection):
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, database.ErrCircuitOpen):
+		return sendUnavailable(c)
+	}
+	log.Printf("ERROR: Migration '%s': %v", name, err)
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Migration operation failed: " + err.Error()})
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// headerMock marks responses generated by a Mock definition.
+const headerMock = "X-Mock"
+
+// serveMock answers a request to a Mock definition with data generated from its
+// ResponseSchema. Nothing is read or written.
+func serveMock(c *fiber.Ctx, api models.ApiDefinition) error {
+	if len(api.ResponseSchema) == 0 {
+		return c.Status(http.StatusNotImplemented).JSON(fiber.Map{"error": "Mock definition has no responseSchema to generate data from"})
+	}
+	data, err := core.FakeFromSchema(api.ResponseSchema)
+	if err != nil {
+		log.Printf("ERROR: Failed to generate mock response of API '%s': %v", api.Name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate mock data"})
+	}
+	c.Set(headerMock, "true")
+	return c.Status(http.StatusOK).JSON(data)
+}
@@ -0,0 +1,434 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	mqttDefaultKeepAlive = 60 * time.Second
+	mqttRetryMaxInterval = time.Minute
+	mqttDialTimeout      = 10 * time.Second
+	mqttMaxPacket        = 10 * 1024 * 1024 // Same as the HTTP body limit
+)
+
+// MQTT 3.1.1 control packet types (high nibble of the fixed header).
+const (
+	mqttConnect    = 1
+	mqttConnack    = 2
+	mqttPublish    = 3
+	mqttPuback     = 4
+	mqttSubscribe  = 8
+	mqttSuback     = 9
+	mqttPingreq    = 12
+	mqttPingresp   = 13
+	mqttDisconnect = 14
+)
+
+// MQTTConfig configures the MQTT ingestion bridge.
+type MQTTConfig struct {
+	Broker        string // tcp://host:1883 (or mqtt://); ssl://, tls:// or mqtts:// for TLS
+	ClientID      string // Stable ID, so QoS 1 messages are queued by the broker while disconnected
+	Username      string
+	Password      string
+	KeepAlive     time.Duration // Default 60s
+	Subscriptions []MQTTSubscription
+}
+
+// MQTTSubscription feeds the messages of a topic filter (+ and # wildcards) into a definition.
+type MQTTSubscription struct {
+	Topic string
+	API   string // Definition name
+	QoS   byte   // 0 or 1; QoS 1 messages are acknowledged after the flow ran
+}
+
+// mqttBridge is the configured bridge; nil config means the bridge is off.
+type mqttBridge struct {
+	cfg  *MQTTConfig
+	addr string
+	tls  bool
+}
+
+// ConfigureMQTT validates the bridge configuration. The connection is made by RunMQTTBridge.
+func (h *Handler) ConfigureMQTT(cfg MQTTConfig) error {
+	u, err := url.Parse(cfg.Broker)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid broker url '%s'", cfg.Broker)
+	}
+	bridge := mqttBridge{addr: u.Host}
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		bridge.tls = true
+	default:
+		return fmt.Errorf("unsupported broker scheme '%s' (use tcp, mqtt, ssl, tls or mqtts)", u.Scheme)
+	}
+	if u.Port() == "" {
+		port := "1883"
+		if bridge.tls {
+			port = "8883"
+		}
+		bridge.addr = net.JoinHostPort(u.Hostname(), port)
+	}
+	if u.User != nil && cfg.Username == "" {
+		cfg.Username = u.User.Username()
+		cfg.Password, _ = u.User.Password()
+	}
+	if cfg.ClientID == "" {
+		return errors.New("client id is required")
+	}
+	if cfg.KeepAlive <= 0 {
+		cfg.KeepAlive = mqttDefaultKeepAlive
+	}
+	if len(cfg.Subscriptions) == 0 {
+		return errors.New("at least one topic subscription is required")
+	}
+	for _, sub := range cfg.Subscriptions {
+		if err := checkTopicFilter(sub.Topic); err != nil {
+			return fmt.Errorf("topic '%s': %w", sub.Topic, err)
+		}
+		if sub.API == "" {
+			return fmt.Errorf("topic '%s': definition name is required", sub.Topic)
+		}
+		if sub.QoS > 1 {
+			return fmt.Errorf("topic '%s': qos must be 0 or 1", sub.Topic)
+		}
+	}
+	bridge.cfg = &cfg
+	h.mqtt = bridge
+	return nil
+}
+
+// checkTopicFilter enforces the MQTT wildcard rules: + fills a whole level, # only ends a filter.
+func checkTopicFilter(filter string) error {
+	if filter == "" {
+		return errors.New("empty topic filter")
+	}
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		if strings.Contains(level, "#") && (level != "#" || i != len(levels)-1) {
+			return errors.New("'#' must be the last level on its own")
+		}
+		if strings.Contains(level, "+") && level != "+" {
+			return errors.New("'+' must fill a whole level")
+		}
+	}
+	return nil
+}
+
+// topicMatches reports whether a topic name matches a topic filter.
+func topicMatches(filter, topic string) bool {
+	f := strings.Split(filter, "/")
+	t := strings.Split(topic, "/")
+	for i, level := range f {
+		if level == "#" {
+			return !strings.HasPrefix(topic, "$") || i > 0
+		}
+		if i >= len(t) {
+			return false
+		}
+		if level == "+" {
+			if i == 0 && strings.HasPrefix(t[0], "$") {
+				return false // Wildcards never match $SYS-style topics
+			}
+			continue
+		}
+		if level != t[i] {
+			return false
+		}
+	}
+	return len(f) == len(t)
+}
+
+// RunMQTTBridge keeps a connection to the broker, reconnecting with backoff, and serves
+// each message through app as a call of the mapped definition. No-op when not configured.
+func (h *Handler) RunMQTTBridge(ctx context.Context, app *fiber.App) {
+	if h.mqtt.cfg == nil {
+		return
+	}
+	handler := app.Handler()
+	retry := time.Second
+	for ctx.Err() == nil {
+		connected, err := h.mqtt.session(ctx, func(topic string, payload []byte) error {
+			return h.ingestMQTTMessage(handler, topic, payload)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if connected && !errors.Is(err, errMQTTRedeliver) { // Keep backing off while the definition fails
+			retry = time.Second
+		}
+		log.Printf("WARN: MQTT bridge disconnected from %s, retrying in %s: %v", h.mqtt.addr, retry, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		retry = min(retry*2, mqttRetryMaxInterval)
+	}
+}
+
+// errMQTTRedeliver ends a session without acknowledging a QoS 1 message, so the broker
+// delivers it again after the reconnect.
+var errMQTTRedeliver = errors.New("message left unacknowledged for redelivery")
+
+// ingestMQTTMessage runs one message through the definition its topic is mapped to. A JSON
+// object is the payload as it is; anything else is wrapped as {"payload": ...}. Server
+// errors (5xx, e.g. the database is down) are returned so the message is not acknowledged;
+// rejected messages are dropped.
+func (h *Handler) ingestMQTTMessage(app fasthttp.RequestHandler, topic string, raw []byte) error {
+	var sub *MQTTSubscription
+	for i := range h.mqtt.cfg.Subscriptions {
+		if topicMatches(h.mqtt.cfg.Subscriptions[i].Topic, topic) {
+			sub = &h.mqtt.cfg.Subscriptions[i]
+			break
+		}
+	}
+	if sub == nil {
+		return nil // Overlapping broker-side subscriptions can deliver topics we do not map
+	}
+	api, ok := h.servedAPI(sub.API)
+	if !ok {
+		log.Printf("WARN: MQTT message on '%s' dropped, API '%s' is not served", topic, sub.API)
+		return nil
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(raw, &payload); err != nil || payload == nil {
+		var value interface{}
+		if json.Unmarshal(raw, &value) != nil {
+			value = string(raw)
+		}
+		payload = map[string]interface{}{"payload": value}
+	}
+	header := http.Header{"X-Mqtt-Topic": {topic}}
+	status, body := dispatchInProcess(app, api, payload, header, "mqtt", nil)
+	if status >= http.StatusInternalServerError {
+		return fmt.Errorf("API '%s' failed (%d): %s", api.Name, status, grpcErrorMessage(status, body))
+	}
+	if status >= http.StatusBadRequest {
+		log.Printf("WARN: MQTT message on '%s' rejected by API '%s' (%d): %s", topic, api.Name, status, grpcErrorMessage(status, body))
+	}
+	return nil
+}
+
+// --- MQTT 3.1.1 client ---
+
+// session connects, subscribes and delivers messages until the connection fails or ctx
+// ends. connected reports whether the broker accepted the connection. A QoS 1 message
+// that deliver fails is not acknowledged: the session ends with errMQTTRedeliver and the
+// broker sends the message again on the next connection (persistent session).
+func (b mqttBridge) session(ctx context.Context, deliver func(topic string, payload []byte) error) (connected bool, err error) {
+	dialer := &net.Dialer{Timeout: mqttDialTimeout}
+	var conn net.Conn
+	if b.tls {
+		host, _, _ := net.SplitHostPort(b.addr)
+		conn, err = tls.DialWithDialer(dialer, "tcp", b.addr, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", b.addr)
+	}
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() {
+		_ = writeMQTTPacket(conn, mqttDisconnect<<4, nil)
+		_ = conn.Close()
+	})
+	defer stop()
+
+	r := bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(mqttDialTimeout))
+	if err := writeMQTTPacket(conn, mqttConnect<<4, b.connectPacket()); err != nil {
+		return false, err
+	}
+	kind, body, err := readMQTTPacket(r)
+	if err != nil {
+		return false, err
+	}
+	if kind>>4 != mqttConnack || len(body) != 2 {
+		return false, errors.New("expected CONNACK")
+	}
+	if body[1] != 0 {
+		return false, fmt.Errorf("connection refused (return code %d)", body[1])
+	}
+	conn.SetDeadline(time.Time{}) // Reads get a keep-alive deadline below
+
+	if err := writeMQTTPacket(conn, mqttSubscribe<<4|0x02, b.subscribePacket(1)); err != nil {
+		return true, err
+	}
+	log.Printf("INFO: MQTT bridge connected to %s, subscribed to %d topics", b.addr, len(b.cfg.Subscriptions))
+
+	// Writes come from the reader loop (acks) and the keep-alive pinger
+	var writeMu sync.Mutex
+	write := func(kind byte, body []byte) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return writeMQTTPacket(conn, kind, body)
+	}
+	pingDone := make(chan struct{})
+	defer close(pingDone)
+	go func() {
+		ticker := time.NewTicker(b.cfg.KeepAlive / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-pingDone:
+				return
+			case <-ticker.C:
+				if write(mqttPingreq<<4, nil) != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(b.cfg.KeepAlive * 3 / 2))
+		kind, body, err := readMQTTPacket(r)
+		if err != nil {
+			return true, err
+		}
+		switch kind >> 4 {
+		case mqttSuback:
+			for i, code := range body[min(2, len(body)):] {
+				if code == 0x80 && i < len(b.cfg.Subscriptions) {
+					log.Printf("ERROR: MQTT broker refused subscription to '%s'", b.cfg.Subscriptions[i].Topic)
+				}
+			}
+		case mqttPublish:
+			topic, packetID, payload, err := parseMQTTPublish(kind, body)
+			if err != nil {
+				return true, err
+			}
+			if err := deliver(topic, payload); err != nil {
+				if packetID != 0 {
+					return true, fmt.Errorf("%w (topic '%s'): %w", errMQTTRedeliver, topic, err)
+				}
+				log.Printf("ERROR: MQTT message on '%s' lost (QoS 0): %v", topic, err)
+			}
+			if packetID != 0 {
+				if err := write(mqttPuback<<4, binary.BigEndian.AppendUint16(nil, packetID)); err != nil {
+					return true, err
+				}
+			}
+		case mqttPingresp:
+		default:
+			log.Printf("DEBUG: MQTT bridge ignored packet type %d", kind>>4)
+		}
+	}
+}
+
+func (b mqttBridge) connectPacket() []byte {
+	p := appendMQTTString(nil, "MQTT")
+	p = append(p, 4) // Protocol level 3.1.1
+	flags := byte(0) // Persistent session: the broker keeps QoS 1 messages for our client ID
+	if b.cfg.Username != "" {
+		flags |= 0x80
+		if b.cfg.Password != "" {
+			flags |= 0x40
+		}
+	}
+	p = append(p, flags)
+	p = binary.BigEndian.AppendUint16(p, uint16(b.cfg.KeepAlive/time.Second))
+	p = appendMQTTString(p, b.cfg.ClientID)
+	if b.cfg.Username != "" {
+		p = appendMQTTString(p, b.cfg.Username)
+		if b.cfg.Password != "" {
+			p = appendMQTTString(p, b.cfg.Password)
+		}
+	}
+	return p
+}
+
+func (b mqttBridge) subscribePacket(packetID uint16) []byte {
+	p := binary.BigEndian.AppendUint16(nil, packetID)
+	for _, sub := range b.cfg.Subscriptions {
+		p = append(appendMQTTString(p, sub.Topic), sub.QoS)
+	}
+	return p
+}
+
+// parseMQTTPublish splits a PUBLISH packet. packetID is 0 for QoS 0 messages.
+func parseMQTTPublish(kind byte, body []byte) (topic string, packetID uint16, payload []byte, err error) {
+	if len(body) < 2 {
+		return "", 0, nil, errors.New("malformed PUBLISH")
+	}
+	n := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+n {
+		return "", 0, nil, errors.New("malformed PUBLISH topic")
+	}
+	topic, body = string(body[2:2+n]), body[2+n:]
+	if qos := (kind >> 1) & 0x03; qos > 0 {
+		if len(body) < 2 {
+			return "", 0, nil, errors.New("malformed PUBLISH packet id")
+		}
+		packetID, body = binary.BigEndian.Uint16(body), body[2:]
+	}
+	return topic, packetID, body, nil
+}
+
+func appendMQTTString(b []byte, s string) []byte {
+	return append(binary.BigEndian.AppendUint16(b, uint16(len(s))), s...)
+}
+
+func writeMQTTPacket(w io.Writer, kind byte, body []byte) error {
+	p := []byte{kind}
+	for n := len(body); ; {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		p = append(p, digit)
+		if n == 0 {
+			break
+		}
+	}
+	_, err := w.Write(append(p, body...))
+	return err
+}
+
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	kind, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	size, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		size += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	if size > mqttMaxPacket {
+		return 0, nil, fmt.Errorf("packet larger than %d bytes", mqttMaxPacket)
+	}
+	body := make([]byte, size)
+	_, err = io.ReadFull(r, body)
+	return kind, body, err
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/database"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// nameParam returns the decoded :name of the route (names like
+// "externalOrder→internal" arrive percent-encoded).
+func nameParam(c *fiber.Ctx) string {
+	if name, err := url.PathUnescape(c.Params("name")); err == nil {
+		return name
+	}
+	return c.Params("name")
+}
+
+// namedResource serves the list, get, put and delete routes of a set of named documents
+// (mapping profiles, lookup tables, ...); the exported handlers are thin wrappers.
+type namedResource[T any] struct {
+	kind string // Document kind in messages and logs (e.g. "mapping profile")
+
+	list   func(ctx context.Context) ([]T, error)
+	get    func(ctx context.Context, name string) (*T, error)
+	save   func(ctx context.Context, doc *T) error
+	delete func(ctx context.Context, name string) error
+
+	setName func(doc *T, name string)            // Takes the name from the route
+	check   func(doc T) []core.DefinitionProblem // (Optional) Problems that make the document invalid
+	// (Optional) Checks the operator may change the named document; it writes the error
+	// response itself when ok is false
+	authorize func(ctx context.Context, c *fiber.Ctx, name string) (ok bool, err error)
+	saved     func(doc T)        // Updates this instance's cache after a save
+	deleted   func(name string)  // Updates this instance's cache after a delete
+	detail    func(doc T) string // (Optional) Extra detail for the save log line
+}
+
+func (r namedResource[T]) title() string {
+	return strings.ToUpper(r.kind[:1]) + r.kind[1:]
+}
+
+func (r namedResource[T]) handleList(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	docs, err := r.list(ctx)
+	if err != nil {
+		log.Printf("ERROR: Handler failed to list %ss: %v", r.kind, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list " + r.kind + "s"})
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   docs,
+	})
+}
+
+func (r namedResource[T]) handleGet(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	doc, err := r.get(ctx, nameParam(c))
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": r.title() + " not found"})
+		}
+		log.Printf("ERROR: Handler failed to get %s: %v", r.kind, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve " + r.kind})
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   doc,
+	})
+}
+
+func (r namedResource[T]) handlePut(c *fiber.Ctx) error {
+	var doc T
+	if err := c.BodyParser(&doc); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body", "details": err.Error()})
+	}
+	name := nameParam(c)
+	r.setName(&doc, name)
+	if r.check != nil {
+		if problems := r.check(doc); len(problems) > 0 {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": r.title() + " is invalid", "errors": problems})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+	if r.authorize != nil {
+		if ok, err := r.authorize(ctx, c, name); !ok {
+			return err
+		}
+	}
+	if err := r.save(ctx, &doc); err != nil {
+		log.Printf("ERROR: Handler failed to save %s '%s': %v", r.kind, name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save " + r.kind})
+	}
+	r.saved(doc)
+	detail := ""
+	if r.detail != nil {
+		detail = " (" + r.detail(doc) + ")"
+	}
+	log.Printf("INFO: %s '%s' saved by %s%s", r.title(), name, actorName(c), detail)
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   doc,
+	})
+}
+
+func (r namedResource[T]) handleDelete(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	name := nameParam(c)
+	if r.authorize != nil {
+		if ok, err := r.authorize(ctx, c, name); !ok {
+			return err
+		}
+	}
+	if err := r.delete(ctx, name); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": r.title() + " not found"})
+		}
+		log.Printf("ERROR: Handler failed to delete %s '%s': %v", r.kind, name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete " + r.kind})
+	}
+	r.deleted(name)
+	log.Printf("INFO: %s '%s' deleted by %s", r.title(), name, actorName(c))
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"code":    http.StatusOK,
+		"message": r.title() + " deleted",
+	})
+}
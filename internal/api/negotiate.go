@@ -0,0 +1,189 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Binary response media types offered through content negotiation.
+const (
+	mimeMsgpack  = "application/msgpack"
+	mimeXMsgpack = "application/x-msgpack"
+	mimeProtobuf = "application/x-protobuf"
+)
+
+// negotiateEncoding picks a binary encoding from the Accept header. It returns "" when
+// the client prefers JSON (the default). Protobuf is only offered with a schema.
+func negotiateEncoding(c *fiber.Ctx, api models.ApiDefinition) string {
+	accept := c.Get(fiber.HeaderAccept)
+	if !strings.Contains(accept, "msgpack") && !strings.Contains(accept, "protobuf") {
+		return "" // Fast path for the usual JSON clients
+	}
+	offers := []string{fiber.MIMEApplicationJSON, jsonAPIMediaType, mimeMsgpack, mimeXMsgpack}
+	if api.Protobuf != nil {
+		offers = append(offers, mimeProtobuf, "application/protobuf")
+	}
+	switch c.Accepts(offers...) {
+	case mimeMsgpack, mimeXMsgpack:
+		return mimeMsgpack
+	case mimeProtobuf, "application/protobuf":
+		return mimeProtobuf
+	}
+	return ""
+}
+
+// encodeResponse re-encodes the JSON response of a dynamic API in the negotiated
+// encoding. Deferred by the dynamic handler so every response path is covered.
+// Protobuf only applies to successful responses; errors stay JSON.
+func encodeResponse(c *fiber.Ctx, api models.ApiDefinition, encoding string) {
+	c.Vary(fiber.HeaderAccept)
+	contentType := string(c.Response().Header.ContentType())
+	if !strings.HasPrefix(contentType, fiber.MIMEApplicationJSON) && !strings.HasPrefix(contentType, jsonAPIMediaType) {
+		return // Downloads, plain text and other non-JSON bodies are sent as they are
+	}
+	dec := json.NewDecoder(bytes.NewReader(c.Response().Body()))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return
+	}
+
+	var out []byte
+	var err error
+	switch encoding {
+	case mimeMsgpack:
+		out, err = appendMsgpack(nil, value)
+	case mimeProtobuf:
+		if c.Response().StatusCode() >= http.StatusBadRequest {
+			return
+		}
+		out, err = encodeProtobuf(api.Protobuf, value)
+	}
+	if err != nil {
+		log.Printf("ERROR: Failed to encode response of API '%s' as %s: %v", api.Name, encoding, err)
+		c.Status(http.StatusInternalServerError)
+		c.Response().SetBodyString(fmt.Sprintf(`{"error":%q}`, "Response cannot be encoded as "+encoding))
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return
+	}
+	c.Response().SetBody(out)
+	c.Set(fiber.HeaderContentType, encoding)
+}
+
+// --- MessagePack ---
+
+// appendMsgpack encodes a decoded JSON value (numbers as json.Number) as MessagePack.
+// Map keys are written in sorted order so equal values encode identically.
+func appendMsgpack(b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return appendMsgpackInt(b, n), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, 0xcb)
+		return binary.BigEndian.AppendUint64(b, math.Float64bits(f)), nil
+	case string:
+		n := len(v)
+		switch {
+		case n < 32:
+			b = append(b, 0xa0|byte(n))
+		case n <= math.MaxUint8:
+			b = append(b, 0xd9, byte(n))
+		case n <= math.MaxUint16:
+			b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+		default:
+			b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+		}
+		return append(b, v...), nil
+	case []interface{}:
+		n := len(v)
+		switch {
+		case n < 16:
+			b = append(b, 0x90|byte(n))
+		case n <= math.MaxUint16:
+			b = binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+		default:
+			b = binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+		}
+		var err error
+		for _, item := range v {
+			if b, err = appendMsgpack(b, item); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]interface{}:
+		n := len(v)
+		switch {
+		case n < 16:
+			b = append(b, 0x80|byte(n))
+		case n <= math.MaxUint16:
+			b = binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+		default:
+			b = binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+		}
+		keys := make([]string, 0, n)
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var err error
+		for _, k := range keys {
+			if b, err = appendMsgpack(b, k); err != nil {
+				return nil, err
+			}
+			if b, err = appendMsgpack(b, v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("unsupported value of type %T", v)
+}
+
+// appendMsgpackInt writes an integer in the smallest MessagePack representation.
+func appendMsgpackInt(b []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n < 128:
+		return append(b, byte(n))
+	case n < 0 && n >= -32:
+		return append(b, byte(int8(n)))
+	case n >= 0 && n <= math.MaxUint8:
+		return append(b, 0xcc, byte(n))
+	case n >= 0 && n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(n))
+	case n >= 0 && n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(n))
+	case n >= 0:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), uint64(n))
+	case n >= math.MinInt8:
+		return append(b, 0xd0, byte(int8(n)))
+	case n >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(int16(n)))
+	case n >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(int32(n)))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(n))
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Definition lifecycle events sent to the notification channels.
+const (
+	EventDefinitionCreated   = "definition.created"
+	EventDefinitionPublished = "definition.published" // An update was stored and is being served
+	EventDefinitionDeleted   = "definition.deleted"
+	EventDefinitionDisabled  = "definition.disabled" // Maintenance mode switched on
+	EventDefinitionEnabled   = "definition.enabled"  // Maintenance mode switched off
+	EventDefinitionRejected  = "definition.rejected" // Failed validation at load
+	EventRevisionSubmitted   = "revision.submitted"
+)
+
+const (
+	notificationQueueSize = 256
+	notificationTimeout   = 10 * time.Second
+)
+
+// NotificationConfig lists where lifecycle events are sent. Empty channels are skipped.
+type NotificationConfig struct {
+	WebhookURL      string // Receives the event as JSON
+	SlackWebhookURL string // Slack incoming webhook, receives a one-line summary
+	Email           EmailConfig
+	Events          []string // Events to send (default all)
+}
+
+// EmailConfig sends lifecycle events by SMTP.
+type EmailConfig struct {
+	SMTPAddr string // host:port
+	Username string // (Optional) PLAIN auth
+	Password string
+	From     string
+	To       []string
+}
+
+// LifecycleEvent is one definition lifecycle notification.
+type LifecycleEvent struct {
+	Event    string    `json:"event"`
+	API      string    `json:"api"`
+	Method   string    `json:"method,omitempty"`
+	Endpoint string    `json:"endpoint,omitempty"`
+	Actor    string    `json:"actor,omitempty"`
+	Instance string    `json:"instance"`
+	Details  string    `json:"details,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// lifecycleNotifier delivers events in the background; a full queue drops events
+// rather than slowing down the management API.
+type lifecycleNotifier struct {
+	cfg    NotificationConfig
+	events map[string]bool
+	queue  chan LifecycleEvent
+	client *http.Client
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	closed bool
+}
+
+// ConfigureNotifications starts delivering lifecycle events to the configured channels.
+func (h *Handler) ConfigureNotifications(cfg NotificationConfig) {
+	n := &lifecycleNotifier{
+		cfg:    cfg,
+		queue:  make(chan LifecycleEvent, notificationQueueSize),
+		client: &http.Client{Timeout: notificationTimeout},
+	}
+	if len(cfg.Events) > 0 {
+		n.events = make(map[string]bool, len(cfg.Events))
+		for _, e := range cfg.Events {
+			n.events[e] = true
+		}
+	}
+	n.wg.Add(1)
+	go n.run()
+	h.notifier = n
+}
+
+// notifyLifecycle queues an event about api. Safe to call when notifications are off.
+func (h *Handler) notifyLifecycle(c *fiber.Ctx, event string, api models.ApiDefinition, details string) {
+	n := h.notifier
+	if n == nil || (n.events != nil && !n.events[event]) {
+		return
+	}
+	e := LifecycleEvent{
+		Event:    event,
+		API:      api.Name,
+		Method:   api.Method,
+		Endpoint: api.Endpoint,
+		Instance: h.cluster.cfg.InstanceID,
+		Details:  details,
+		At:       time.Now().UTC(),
+	}
+	if c != nil {
+		e.Actor = actorName(c)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	select {
+	case n.queue <- e:
+	default:
+		log.Printf("WARN: Notification queue full, dropped '%s' event for API '%s'", event, api.Name)
+	}
+}
+
+func (n *lifecycleNotifier) run() {
+	defer n.wg.Done()
+	for e := range n.queue {
+		if n.cfg.WebhookURL != "" {
+			if err := n.postJSON(n.cfg.WebhookURL, e); err != nil {
+				log.Printf("WARN: Lifecycle webhook for '%s' (%s) failed: %v", e.API, e.Event, err)
+			}
+		}
+		if n.cfg.SlackWebhookURL != "" {
+			if err := n.postJSON(n.cfg.SlackWebhookURL, map[string]string{"text": e.summary()}); err != nil {
+				log.Printf("WARN: Slack notification for '%s' (%s) failed: %v", e.API, e.Event, err)
+			}
+		}
+		if n.cfg.Email.SMTPAddr != "" && len(n.cfg.Email.To) > 0 {
+			if err := n.sendEmail(e); err != nil {
+				log.Printf("WARN: Email notification for '%s' (%s) failed: %v", e.API, e.Event, err)
+			}
+		}
+	}
+}
+
+// shutdown stops accepting events and waits for the queued ones to be delivered.
+func (n *lifecycleNotifier) shutdown(ctx context.Context) error {
+	if n == nil {
+		return nil
+	}
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("lifecycle notifications not delivered before shutdown: %w", ctx.Err())
+	}
+}
+
+func (n *lifecycleNotifier) postJSON(url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (n *lifecycleNotifier) sendEmail(e LifecycleEvent) error {
+	cfg := n.cfg.Email
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		host, _, _ := strings.Cut(cfg.SMTPAddr, ":")
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	// The API name is user input: encoding it keeps CR/LF from injecting further headers.
+	subject := fmt.Sprintf("[api-generator] %s: %s", e.Event, e.API)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(e.summary() + "\r\n")
+	if e.Details != "" {
+		msg.WriteString("\r\n" + e.Details + "\r\n")
+	}
+	return smtp.SendMail(cfg.SMTPAddr, auth, cfg.From, cfg.To, []byte(msg.String()))
+}
+
+// summary renders the event as one human-readable line.
+func (e LifecycleEvent) summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "API '%s'", e.API)
+	if e.Method != "" {
+		fmt.Fprintf(&b, " (%s %s)", e.Method, e.Endpoint)
+	}
+	fmt.Fprintf(&b, ": %s", e.Event)
+	if e.Actor != "" {
+		fmt.Fprintf(&b, " by %s", e.Actor)
+	}
+	fmt.Fprintf(&b, " on %s", e.Instance)
+	if e.Details != "" {
+		fmt.Fprintf(&b, " - %s", e.Details)
+	}
+	return b.String()
+}
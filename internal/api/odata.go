@@ -0,0 +1,465 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// OData system query options supported on default GETs. Other "$" options are rejected.
+const (
+	odataFilter  = "$filter"
+	odataSelect  = "$select"
+	odataOrderBy = "$orderby"
+	odataTop     = "$top"
+	odataSkip    = "$skip"
+	odataCount   = "$count"
+)
+
+var odataOptions = map[string]bool{
+	odataFilter:  true,
+	odataSelect:  true,
+	odataOrderBy: true,
+	odataTop:     true,
+	odataSkip:    true,
+	odataCount:   true,
+}
+
+const (
+	maxODataFilterLength = 4096
+	maxODataDepth        = 32
+)
+
+// odataQuery is the parsed set of OData query options of a request.
+type odataQuery struct {
+	Present bool   // Any OData option was sent; the response uses the OData {"value": [...]} envelope
+	Filter  bson.M // Translated $filter (nil when absent)
+	Select  []string
+	OrderBy bson.D
+	Top     int64
+	Skip    int64
+	Count   bool // $count=true adds "@odata.count" to the response
+
+	fields []string // Fields referenced by $filter and $orderby
+}
+
+// findOptions turns $select, $orderby, $top and $skip into store find options.
+func (q odataQuery) findOptions() database.FindOptions {
+	opts := database.FindOptions{Sort: q.OrderBy, Skip: q.Skip, Limit: q.Top}
+	if len(q.Select) > 0 {
+		opts.Projection = bson.M{}
+		for _, field := range q.Select {
+			opts.Projection[field] = 1
+		}
+	}
+	return opts
+}
+
+// parseODataQuery reads the OData query options of a request.
+func parseODataQuery(c *fiber.Ctx) (odataQuery, error) {
+	var q odataQuery
+	var err error
+	c.Request().URI().QueryArgs().VisitAll(func(k, v []byte) {
+		key := string(k)
+		if err != nil || !strings.HasPrefix(key, "$") {
+			return
+		}
+		if !odataOptions[key] {
+			err = fmt.Errorf("unsupported query option '%s'", key)
+			return
+		}
+		q.Present = true
+		value := strings.TrimSpace(string(v))
+		switch key {
+		case odataFilter:
+			q.Filter, q.fields, err = parseODataFilter(value)
+		case odataSelect:
+			for _, field := range splitList(value) {
+				if field, err = odataField(field); err != nil {
+					return
+				}
+				q.Select = append(q.Select, field)
+			}
+		case odataOrderBy:
+			q.OrderBy, err = parseODataOrderBy(value)
+			for _, e := range q.OrderBy {
+				q.fields = append(q.fields, e.Key)
+			}
+		case odataTop:
+			q.Top, err = parseODataInt(key, value)
+		case odataSkip:
+			q.Skip, err = parseODataInt(key, value)
+		case odataCount:
+			q.Count = strings.EqualFold(value, "true")
+		}
+	})
+	return q, err
+}
+
+func parseODataInt(option, value string) (int64, error) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", option)
+	}
+	return n, nil
+}
+
+// odataField converts an OData property path ("address/city") to a Mongo field path.
+func odataField(path string) (string, error) {
+	field := strings.ReplaceAll(strings.TrimSpace(path), "/", ".")
+	if field == "" || strings.HasPrefix(field, "$") || strings.ContainsAny(field, "'\"") || strings.Contains(field, "..") {
+		return "", fmt.Errorf("invalid property '%s'", path)
+	}
+	return field, nil
+}
+
+func parseODataOrderBy(value string) (bson.D, error) {
+	var sort bson.D
+	for _, item := range splitList(value) {
+		parts := strings.Fields(item)
+		if len(parts) == 0 || len(parts) > 2 {
+			return nil, fmt.Errorf("invalid $orderby item '%s'", item)
+		}
+		field, err := odataField(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		direction := 1
+		if len(parts) == 2 {
+			switch strings.ToLower(parts[1]) {
+			case "asc":
+			case "desc":
+				direction = -1
+			default:
+				return nil, fmt.Errorf("invalid $orderby direction '%s'", parts[1])
+			}
+		}
+		sort = append(sort, bson.E{Key: field, Value: direction})
+	}
+	return sort, nil
+}
+
+// --- $filter ---
+
+// odataComparisons maps OData comparison operators to Mongo operators.
+var odataComparisons = map[string]string{
+	"eq": "$eq",
+	"ne": "$ne",
+	"gt": "$gt",
+	"ge": "$gte",
+	"lt": "$lt",
+	"le": "$lte",
+}
+
+// odataParser is a recursive-descent parser for the $filter subset: comparisons,
+// "in", and/or/not, parentheses and the contains/startswith/endswith functions.
+type odataParser struct {
+	tokens []string
+	pos    int
+	depth  int
+	fields []string
+}
+
+// parseODataFilter translates a $filter expression into a Mongo filter and lists the
+// fields it references.
+func parseODataFilter(expr string) (bson.M, []string, error) {
+	if len(expr) > maxODataFilterLength {
+		return nil, nil, fmt.Errorf("$filter is longer than %d characters", maxODataFilterLength)
+	}
+	tokens, err := tokenizeOData(expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil, fmt.Errorf("$filter is empty")
+	}
+	p := &odataParser{tokens: tokens}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, nil, fmt.Errorf("unexpected '%s' in $filter", p.tokens[p.pos])
+	}
+	return filter, p.fields, nil
+}
+
+// tokenizeOData splits an expression into parentheses, commas, quoted strings
+// (kept with their quotes) and words.
+func tokenizeOData(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		switch ch := expr[i]; {
+		case ch == ' ' || ch == '\t':
+			i++
+		case ch == '(' || ch == ')' || ch == ',':
+			tokens = append(tokens, string(ch))
+			i++
+		case ch == '\'':
+			j := i + 1
+			for {
+				if j >= len(expr) {
+					return nil, fmt.Errorf("unterminated string in $filter")
+				}
+				if expr[j] == '\'' {
+					if j+1 < len(expr) && expr[j+1] == '\'' { // '' escapes a quote
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			tokens = append(tokens, expr[i:j+1])
+			i = j + 1
+		default:
+			j := i
+			for j < len(expr) && !strings.ContainsRune(" \t(),'", rune(expr[j])) {
+				j++
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+func (p *odataParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *odataParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *odataParser) expect(token string) error {
+	if t := p.next(); t != token {
+		if t == "" {
+			return fmt.Errorf("expected '%s' at end of $filter", token)
+		}
+		return fmt.Errorf("expected '%s' but found '%s' in $filter", token, t)
+	}
+	return nil
+}
+
+func (p *odataParser) keyword(word string) bool {
+	if strings.EqualFold(p.peek(), word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *odataParser) parseOr() (bson.M, error) {
+	return p.parseLogical("or", "$or", p.parseAnd)
+}
+
+func (p *odataParser) parseAnd() (bson.M, error) {
+	return p.parseLogical("and", "$and", p.parseUnary)
+}
+
+func (p *odataParser) parseLogical(word, op string, operand func() (bson.M, error)) (bson.M, error) {
+	first, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	clauses := []interface{}{first}
+	for p.keyword(word) {
+		clause, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+	if len(clauses) == 1 {
+		return first, nil
+	}
+	return bson.M{op: clauses}, nil
+}
+
+func (p *odataParser) parseUnary() (bson.M, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxODataDepth {
+		return nil, fmt.Errorf("$filter is nested too deeply")
+	}
+
+	if p.keyword("not") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$nor": []interface{}{inner}}, nil
+	}
+	if p.peek() == "(" {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	}
+
+	name := p.next()
+	if name == "" {
+		return nil, fmt.Errorf("unexpected end of $filter")
+	}
+	if p.peek() == "(" {
+		return p.parseFunction(strings.ToLower(name))
+	}
+	field, err := p.field(name)
+	if err != nil {
+		return nil, err
+	}
+
+	op := strings.ToLower(p.next())
+	if op == "in" {
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{field: bson.M{"$in": values}}, nil
+	}
+	mongoOp, ok := odataComparisons[op]
+	if !ok {
+		return nil, fmt.Errorf("unsupported operator '%s' in $filter", op)
+	}
+	value, err := parseODataLiteral(p.next())
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{field: bson.M{mongoOp: value}}, nil
+}
+
+// parseFunction handles contains(field,'text'), startswith(...) and endswith(...).
+func (p *odataParser) parseFunction(name string) (bson.M, error) {
+	if name != "contains" && name != "startswith" && name != "endswith" {
+		return nil, fmt.Errorf("unsupported function '%s' in $filter", name)
+	}
+	p.next() // "("
+	field, err := p.field(p.next())
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(","); err != nil {
+		return nil, err
+	}
+	literal := p.next()
+	value, err := parseODataLiteral(literal)
+	text, isString := value.(string)
+	if err != nil || !isString {
+		return nil, fmt.Errorf("%s expects a string, got '%s'", name, literal)
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	pattern := regexp.QuoteMeta(text)
+	switch name {
+	case "startswith":
+		pattern = "^" + pattern
+	case "endswith":
+		pattern += "$"
+	}
+	return bson.M{field: bson.M{"$regex": pattern}}, nil
+}
+
+// parseList reads "(v1, v2, ...)" for the in operator.
+func (p *odataParser) parseList() ([]interface{}, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var values []interface{}
+	for {
+		value, err := parseODataLiteral(p.next())
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if p.peek() == ")" {
+			p.next()
+			return values, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *odataParser) field(token string) (string, error) {
+	field, err := odataField(token)
+	if err != nil {
+		return "", err
+	}
+	p.fields = append(p.fields, field)
+	return field, nil
+}
+
+// parseODataLiteral converts a literal token: 'string', number, true/false, null or a
+// date/date-time (ISO 8601).
+func parseODataLiteral(token string) (interface{}, error) {
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("missing value in $filter")
+	case strings.HasPrefix(token, "'"):
+		return strings.ReplaceAll(token[1:len(token)-1], "''", "'"), nil
+	case token == "true":
+		return true, nil
+	case token == "false":
+		return false, nil
+	case token == "null":
+		return nil, nil
+	}
+	if n, err := strconv.ParseInt(token, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(token, 64); err == nil {
+		return f, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, token); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, token); err == nil {
+		return t, nil
+	}
+	return nil, fmt.Errorf("invalid value '%s' in $filter", token)
+}
+
+// withODataFilter combines the parameter filter with the OData $filter.
+func withODataFilter(filter bson.M, odata bson.M) bson.M {
+	if odata == nil {
+		return filter
+	}
+	if len(filter) == 0 {
+		return odata
+	}
+	return bson.M{"$and": []interface{}{filter, odata}}
+}
+
+// odataEnvelope wraps find results as an OData collection, with "@odata.count" (the
+// total ignoring $top/$skip) when $count=true.
+func (h *Handler) odataEnvelope(ctx context.Context, api models.ApiDefinition, filter bson.M, q odataQuery, results interface{}) (interface{}, error) {
+	envelope := fiber.Map{"value": results}
+	if q.Count {
+		count, err := h.store.CountData(ctx, api.Database, api.Collection, filter)
+		if err != nil {
+			return nil, err
+		}
+		envelope["@odata.count"] = count
+	}
+	return envelope, nil
+}
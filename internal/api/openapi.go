@@ -0,0 +1,192 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"api-genarator/internal/models"
+)
+
+// buildOpenAPI renders the definitions as an OpenAPI 3.0 document. Path parameters
+// (":id") become "{id}"; the other declared parameters are query parameters for
+// GET/DELETE and request body properties otherwise.
+func buildOpenAPI(apis []models.ApiDefinition, serverURL string) map[string]interface{} {
+	paths := make(map[string]interface{})
+	for _, api := range apis {
+		path, pathParams := openAPIPath(api.Endpoint)
+		item, _ := paths[path].(map[string]interface{})
+		if item == nil {
+			item = make(map[string]interface{})
+			paths[path] = item
+		}
+		item[strings.ToLower(api.Method)] = openAPIOperation(api, pathParams)
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Generated APIs",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+	}
+	if serverURL != "" {
+		doc["servers"] = []interface{}{map[string]interface{}{"url": serverURL}}
+	}
+	return doc
+}
+
+// openAPIPath converts a fiber route ("/users/:id") to an OpenAPI path and lists its parameters.
+func openAPIPath(endpoint string) (string, []string) {
+	segments := strings.Split(endpoint, "/")
+	var params []string
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") {
+			name := strings.TrimSuffix(strings.TrimPrefix(seg, ":"), "?")
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func openAPIOperation(api models.ApiDefinition, pathParams []string) map[string]interface{} {
+	op := map[string]interface{}{
+		"operationId": api.Name,
+	}
+	if api.Summary != "" {
+		op["summary"] = api.Summary
+	}
+	if api.Description != "" {
+		op["description"] = api.Description
+	}
+	if api.Contact != nil {
+		op["x-contact"] = api.Contact // OpenAPI only has a document-level contact
+	}
+	if d := api.Deprecated; d != nil {
+		op["deprecated"] = true
+		if d.SunsetDate != "" {
+			op["x-sunset"] = d.SunsetDate
+		}
+		if d.Successor != "" {
+			op["x-successor"] = d.Successor
+		}
+	}
+
+	inPath := make(map[string]bool, len(pathParams))
+	var parameters []interface{}
+	for _, name := range pathParams {
+		inPath[name] = true
+		parameters = append(parameters, map[string]interface{}{
+			"name": name, "in": "path", "required": true, "schema": map[string]interface{}{"type": openAPIType(paramType(api.Parameters, name))},
+		})
+	}
+
+	hasBody := api.Method == http.MethodPost || api.Method == http.MethodPut || api.Method == http.MethodPatch
+	properties := make(map[string]interface{})
+	var required []string
+	for _, p := range api.Parameters {
+		if inPath[p.Name] {
+			continue
+		}
+		schema := map[string]interface{}{"type": openAPIType(p.Type)}
+		if hasBody {
+			properties[p.Name] = schema
+			if p.Required {
+				required = append(required, p.Name)
+			}
+			continue
+		}
+		parameters = append(parameters, map[string]interface{}{
+			"name": p.Name, "in": "query", "required": p.Required, "schema": schema,
+		})
+	}
+	if len(parameters) > 0 {
+		op["parameters"] = parameters
+	}
+	if hasBody {
+		bodySchema := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			bodySchema["required"] = required
+		}
+		op["requestBody"] = map[string]interface{}{
+			"content": map[string]interface{}{"application/json": map[string]interface{}{"schema": bodySchema}},
+		}
+	}
+	op["responses"] = openAPIResponses(api)
+	return op
+}
+
+// openAPIResponses documents the success response plus one response per example status.
+func openAPIResponses(api models.ApiDefinition) map[string]interface{} {
+	successStatus := http.StatusOK
+	if api.Async {
+		successStatus = http.StatusAccepted
+	}
+	responses := make(map[string]interface{})
+	examplesByStatus := make(map[int]map[string]interface{})
+	for _, ex := range api.Examples {
+		status := ex.Status
+		if status == 0 {
+			status = successStatus
+		}
+		if examplesByStatus[status] == nil {
+			examplesByStatus[status] = make(map[string]interface{})
+		}
+		entry := map[string]interface{}{"value": ex.Response}
+		if ex.Summary != "" {
+			entry["summary"] = ex.Summary
+		}
+		examplesByStatus[status][ex.Name] = entry
+	}
+	if _, ok := examplesByStatus[successStatus]; !ok {
+		examplesByStatus[successStatus] = nil
+	}
+
+	for status, examples := range examplesByStatus {
+		resp := map[string]interface{}{"description": http.StatusText(status)}
+		media := map[string]interface{}{}
+		if api.ResponseSchema != nil && status == successStatus {
+			media["schema"] = api.ResponseSchema
+		}
+		if len(examples) > 0 {
+			media["examples"] = examples
+		}
+		if len(media) > 0 {
+			resp["content"] = map[string]interface{}{"application/json": media}
+		}
+		responses[strconv.Itoa(status)] = resp
+	}
+	return responses
+}
+
+// paramType returns the declared type of a parameter ("" when undeclared).
+func paramType(params []models.Parameter, name string) string {
+	for _, p := range params {
+		if p.Name == name {
+			return p.Type
+		}
+	}
+	return ""
+}
+
+// openAPIType maps a parameter type to a JSON Schema type (string when unknown).
+func openAPIType(t string) string {
+	switch strings.ToLower(t) {
+	case "number", "float", "double":
+		return "number"
+	case "int", "integer":
+		return "integer"
+	case "bool", "boolean":
+		return "boolean"
+	case "object", "array":
+		return strings.ToLower(t)
+	}
+	return "string"
+}
+
+// containsFold reports whether substr is in s, ignoring case.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,348 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"api-genarator/internal/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Operator roles for the management API, from least to most privileged.
+const (
+	RoleViewer = "viewer" // Read definitions, reports and stats
+	RoleEditor = "editor" // Also create, change and delete definitions
+	RoleAdmin  = "admin"  // Also maintenance mode, dead-letter replay and approving revisions
+)
+
+var roleRank = map[string]int{RoleViewer: 1, RoleEditor: 2, RoleAdmin: 3}
+
+const (
+	sessionCookie     = "apigen_session"
+	loginCookie       = "apigen_login"
+	loginTTL          = 10 * time.Minute
+	defaultSessionTTL = 8 * time.Hour
+	operatorLocal     = "operator" // fiber Locals key of the authenticated *Operator
+)
+
+// ManagementAuthConfig enables OIDC operator login for /api-generator/*.
+type ManagementAuthConfig struct {
+	Provider    *auth.OIDCProvider
+	GroupsClaim string            // Claim holding the IdP groups, dotted for nested claims (default "groups")
+	RoleMapping map[string]string // IdP group -> RoleViewer, RoleEditor or RoleAdmin
+	SessionKey  []byte            // Signs the session and login cookies
+	SessionTTL  time.Duration     // Session lifetime (default 8h)
+	Secure      bool              // Send cookies with the Secure flag (HTTPS deployments)
+}
+
+// managementAuth is the operator authentication state; a nil provider leaves the management API open.
+type managementAuth struct {
+	cfg ManagementAuthConfig
+}
+
+// Operator is the authenticated caller of the management API.
+type Operator struct {
+	Subject string   `json:"sub"`
+	Email   string   `json:"email,omitempty"`
+	Name    string   `json:"name,omitempty"`
+	Roles   []string `json:"roles"`
+	Groups  []string `json:"groups,omitempty"` // IdP groups, used as team names for definition ownership
+}
+
+// hasRole reports whether the operator holds role or a more privileged one.
+func (o *Operator) hasRole(role string) bool {
+	for _, r := range o.Roles {
+		if roleRank[r] >= roleRank[role] {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigureManagementAuth turns on operator authentication for the management API.
+func (h *Handler) ConfigureManagementAuth(cfg ManagementAuthConfig) error {
+	if cfg.Provider == nil {
+		return errors.New("an OIDC provider is required")
+	}
+	if len(cfg.SessionKey) < 32 {
+		return errors.New("session key must be at least 32 bytes")
+	}
+	for group, role := range cfg.RoleMapping {
+		if roleRank[role] == 0 {
+			return fmt.Errorf("group '%s' maps to unknown role '%s' (use viewer, editor or admin)", group, role)
+		}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if cfg.SessionTTL <= 0 {
+		cfg.SessionTTL = defaultSessionTTL
+	}
+	h.mgmtAuth.cfg = cfg
+	return nil
+}
+
+// RequireOperator authenticates management API requests by session cookie or by a bearer
+// ID token from the provider, and checks the role the request needs.
+func (h *Handler) RequireOperator(c *fiber.Ctx) error {
+	if h.mgmtAuth.cfg.Provider == nil || strings.HasPrefix(c.Path(), "/api-generator/auth/") {
+		return c.Next()
+	}
+	op, err := h.authenticateOperator(c)
+	if err != nil {
+		log.Printf("WARN: Management API request to %s rejected: %v", c.Path(), err)
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Authentication required", "login": "/api-generator/auth/login"})
+	}
+	if role := requiredRole(c); !op.hasRole(role) {
+		log.Printf("WARN: Operator '%s' lacks role '%s' for %s %s", op.Subject, role, c.Method(), c.Path())
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+	c.Locals(operatorLocal, op)
+	return c.Next()
+}
+
+// operatorFrom returns the authenticated operator, or nil when operator login is off.
+func operatorFrom(c *fiber.Ctx) *Operator {
+	op, _ := c.Locals(operatorLocal).(*Operator)
+	return op
+}
+
+func requiredRole(c *fiber.Ctx) string {
+	// Match the path the way the router does (case-insensitive, trailing slash optional),
+	// or "/Maintenance" or "/approve/" would reach admin handlers with the editor role.
+	path := strings.TrimSuffix(strings.ToLower(c.Path()), "/")
+	if strings.HasPrefix(path, pprofPrefix) {
+		return RoleAdmin // Profiles expose memory contents and cost CPU
+	}
+	if c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead {
+		return RoleViewer
+	}
+	path = strings.TrimPrefix(path, "/api-generator")
+	if path == "/drift" {
+		return RoleViewer // Compares a bundle, changes nothing
+	}
+	if strings.HasPrefix(path, "/maintenance") || strings.HasPrefix(path, "/dead-letters") || strings.HasPrefix(path, "/config") {
+		return RoleAdmin
+	}
+	if strings.HasPrefix(path, "/revisions/") && (strings.HasSuffix(path, "/approve") || strings.HasSuffix(path, "/reject")) {
+		return RoleAdmin
+	}
+	if strings.HasPrefix(path, "/restore/") || strings.HasPrefix(path, "/snapshots/") || strings.HasPrefix(path, "/backups/") {
+		return RoleAdmin // Replaces (or drops the way back to) the whole catalog
+	}
+	if strings.HasPrefix(path, "/migrate/") || (strings.HasPrefix(path, "/migrations/") && strings.HasSuffix(path, "/apply")) {
+		return RoleAdmin // Rewrites stored data
+	}
+	if strings.HasPrefix(path, "/seed/") {
+		return RoleAdmin // Writes straight into the collections behind definitions
+	}
+	return RoleEditor
+}
+
+func (h *Handler) authenticateOperator(c *fiber.Ctx) (*Operator, error) {
+	cfg := h.mgmtAuth.cfg
+	if token, found := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "); found {
+		ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+		defer cancel()
+		claims, err := cfg.Provider.VerifyIDToken(ctx, strings.TrimSpace(token))
+		if err != nil {
+			return nil, err
+		}
+		op := h.operatorFromClaims(claims)
+		if len(op.Roles) == 0 {
+			return nil, fmt.Errorf("no operator role for '%s'", op.Subject)
+		}
+		return op, nil
+	}
+
+	cookie := c.Cookies(sessionCookie)
+	if cookie == "" {
+		return nil, errors.New("no session")
+	}
+	claims, err := auth.VerifyHMAC(cookie, cfg.SessionKey, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	op := &Operator{}
+	op.Subject, _ = claims["sub"].(string)
+	op.Email, _ = claims["email"].(string)
+	op.Name, _ = claims["name"].(string)
+	op.Roles = stringList(claims["roles"])
+	op.Groups = stringList(claims["groups"])
+	return op, nil
+}
+
+// operatorFromClaims maps the ID token's groups to operator roles.
+func (h *Handler) operatorFromClaims(claims map[string]interface{}) *Operator {
+	op := &Operator{}
+	op.Subject, _ = claims["sub"].(string)
+	op.Email, _ = claims["email"].(string)
+	op.Name, _ = claims["name"].(string)
+
+	switch v := claimPath(claims, h.mgmtAuth.cfg.GroupsClaim).(type) {
+	case string:
+		op.Groups = strings.Fields(v)
+	default:
+		op.Groups = stringList(v)
+	}
+	seen := make(map[string]bool)
+	for _, g := range op.Groups {
+		if role, ok := h.mgmtAuth.cfg.RoleMapping[g]; ok && !seen[role] {
+			seen[role] = true
+			op.Roles = append(op.Roles, role)
+		}
+	}
+	return op
+}
+
+// stringList returns the string items of a JSON array claim.
+func stringList(v interface{}) []string {
+	items, _ := v.([]interface{})
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// claimPath reads a possibly nested claim such as "realm_access.roles".
+func claimPath(claims map[string]interface{}, path string) interface{} {
+	var current interface{} = claims
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[part]
+	}
+	return current
+}
+
+// Login redirects the operator to the OIDC provider
+func (h *Handler) Login(c *fiber.Ctx) error {
+	cfg := h.mgmtAuth.cfg
+	if cfg.Provider == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Operator login is not configured"})
+	}
+	state, nonce := randomToken(), randomToken()
+	returnTo := c.Query("returnTo")
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") {
+		returnTo = "" // Only same-site paths, never an open redirect
+	}
+	pending, err := auth.SignHMAC(map[string]interface{}{
+		"state":    state,
+		"nonce":    nonce,
+		"returnTo": returnTo,
+		"exp":      time.Now().Add(loginTTL).Unix(),
+	}, cfg.SessionKey, "")
+	if err != nil {
+		log.Printf("ERROR: Could not start operator login: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start login"})
+	}
+	h.setCookie(c, loginCookie, pending, loginTTL)
+	return c.Redirect(cfg.Provider.AuthCodeURL(state, nonce), http.StatusFound)
+}
+
+// LoginCallback completes the authorization code flow and starts an operator session
+func (h *Handler) LoginCallback(c *fiber.Ctx) error {
+	cfg := h.mgmtAuth.cfg
+	if cfg.Provider == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Operator login is not configured"})
+	}
+	if errCode := c.Query("error"); errCode != "" {
+		log.Printf("WARN: OIDC provider returned error '%s': %s", errCode, c.Query("error_description"))
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Login failed"})
+	}
+	pending, err := auth.VerifyHMAC(c.Cookies(loginCookie), cfg.SessionKey, time.Now())
+	h.setCookie(c, loginCookie, "", -time.Hour)
+	if err != nil || pending["state"] != c.Query("state") {
+		log.Printf("WARN: OIDC callback with missing or mismatched state")
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Login expired or invalid, start again"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 15*time.Second)
+	defer cancel()
+	claims, err := cfg.Provider.Exchange(ctx, c.Query("code"))
+	if err != nil {
+		log.Printf("WARN: OIDC code exchange failed: %v", err)
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Login failed"})
+	}
+	if claims["nonce"] != pending["nonce"] {
+		log.Printf("WARN: OIDC ID token nonce mismatch")
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Login failed"})
+	}
+	op := h.operatorFromClaims(claims)
+	if len(op.Roles) == 0 {
+		log.Printf("WARN: Operator '%s' (%s) has no group mapped to a role", op.Subject, op.Email)
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Your account has no access to the management API"})
+	}
+
+	session, err := auth.SignHMAC(map[string]interface{}{
+		"sub":    op.Subject,
+		"email":  op.Email,
+		"name":   op.Name,
+		"roles":  op.Roles,
+		"groups": op.Groups,
+		"iat":    time.Now().Unix(),
+		"exp":    time.Now().Add(cfg.SessionTTL).Unix(),
+	}, cfg.SessionKey, "")
+	if err != nil {
+		log.Printf("ERROR: Could not create operator session: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create session"})
+	}
+	h.setCookie(c, sessionCookie, session, cfg.SessionTTL)
+	log.Printf("INFO: Operator '%s' (%s) logged in with roles %v", op.Subject, op.Email, op.Roles)
+
+	if returnTo, _ := pending["returnTo"].(string); returnTo != "" {
+		return c.Redirect(returnTo, http.StatusFound)
+	}
+	return c.JSON(fiber.Map{"status": "success", "code": http.StatusOK, "data": op})
+}
+
+// Logout ends the operator session
+func (h *Handler) Logout(c *fiber.Ctx) error {
+	h.setCookie(c, sessionCookie, "", -time.Hour)
+	return c.JSON(fiber.Map{"status": "success", "code": http.StatusOK})
+}
+
+// GetOperator returns the logged-in operator and their roles
+func (h *Handler) GetOperator(c *fiber.Ctx) error {
+	if h.mgmtAuth.cfg.Provider == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Operator login is not configured"})
+	}
+	op, err := h.authenticateOperator(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Authentication required", "login": "/api-generator/auth/login"})
+	}
+	return c.JSON(fiber.Map{"status": "success", "code": http.StatusOK, "data": op})
+}
+
+func (h *Handler) setCookie(c *fiber.Ctx, name, value string, ttl time.Duration) {
+	c.Cookie(&fiber.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/api-generator",
+		Expires:  time.Now().Add(ttl),
+		HTTPOnly: true,
+		Secure:   h.mgmtAuth.cfg.Secure,
+		SameSite: fiber.CookieSameSiteLaxMode, // Lax: the provider redirects back with a top-level GET
+	})
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand does not fail on supported platforms
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// mergePatch applies an RFC 7396 JSON Merge Patch: objects are merged recursively,
+// null removes a member, and any other value (including arrays) replaces the target.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
+
+// applyDefinitionPatch merges a patch document into a definition via its JSON form.
+func applyDefinitionPatch(existing *models.ApiDefinition, patch map[string]interface{}) (*models.ApiDefinition, error) {
+	raw, err := json.Marshal(existing)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	merged, err := json.Marshal(mergePatch(doc, patch))
+	if err != nil {
+		return nil, err
+	}
+	var patched models.ApiDefinition
+	if err := json.Unmarshal(merged, &patched); err != nil {
+		return nil, err
+	}
+	return &patched, nil
+}
+
+// PatchAPI handles partial updates of an API definition using JSON Merge Patch (RFC 7396).
+// Omitted fields are kept; fields set to null are cleared.
+func (h *Handler) PatchAPI(c *fiber.Ctx) error {
+	name := c.Params("name")
+
+	var patch map[string]interface{}
+	if err := json.Unmarshal(c.Body(), &patch); err != nil {
+		log.Printf("WARN: Cannot parse merge patch for PatchAPI (name: %s): %v", name, err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Body must be a JSON Merge Patch object"})
+	}
+	if newName, ok := patch["name"]; ok && newName != name {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "API name cannot be changed"})
+	}
+	delete(patch, "id")
+	delete(patch, "createdAt")
+
+	ctx, cancel := context.WithTimeout(c.Context(), 15*time.Second)
+	defer cancel()
+
+	existingAPI, err := h.store.GetAPIDefinitionByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API not found"})
+		}
+		log.Printf("ERROR: Handler failed to find API for patch (name: %s): %v", name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve existing API data for update"})
+	}
+
+	patched, err := applyDefinitionPatch(existingAPI, patch)
+	if err != nil {
+		log.Printf("WARN: Merge patch for API '%s' produced an invalid definition: %v", name, err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Patched definition is invalid: " + err.Error()})
+	}
+
+	return h.applyDefinitionUpdate(ctx, c, name, patched)
+}
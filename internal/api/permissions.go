@@ -0,0 +1,154 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errNoFieldAccess is returned when none of a definition's fieldPermissions apply to the caller.
+var errNoFieldAccess = errors.New("no field permissions for this caller")
+
+// resolveFieldAccess returns the caller's field access, or nil for definitions without fieldPermissions.
+func resolveFieldAccess(c *fiber.Ctx, api models.ApiDefinition) (*core.FieldAccess, error) {
+	if len(api.FieldPermissions) == 0 {
+		return nil, nil
+	}
+	claims, err := callerClaims(c)
+	if err != nil {
+		return nil, err
+	}
+	access, ok := core.ResolveFieldAccess(api.FieldPermissions, core.CallerRoles(claims))
+	if !ok {
+		return nil, errNoFieldAccess
+	}
+	return access, nil
+}
+
+// sendFieldAccessError answers 401 for an invalid token and 403 for a caller without access.
+func sendFieldAccessError(c *fiber.Ctx, api models.ApiDefinition, err error) error {
+	log.Printf("WARN: Field access denied for API '%s': %v", api.Name, err)
+	if errors.Is(err, errNoFieldAccess) {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+	return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Authentication required"})
+}
+
+// sendProtectedFields rejects a request that touches fields the caller may not use.
+func sendProtectedFields(c *fiber.Ctx, api models.ApiDefinition, fields []string) error {
+	log.Printf("WARN: Request to API '%s' touches protected fields %v", api.Name, fields)
+	return c.Status(http.StatusForbidden).JSON(fiber.Map{
+		"error":  "Request touches fields you are not allowed to use",
+		"fields": fields,
+	})
+}
+
+// unwritableFields lists the fields in the given request sources the caller may not set.
+func unwritableFields(access *core.FieldAccess, sources ...map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	for _, source := range sources {
+		for field := range source {
+			if !access.CanWrite(field) {
+				seen[field] = true
+			}
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// unreadableQueryFields lists the fields a default GET filters, groups or aggregates on that
+// the caller may not read; querying them would reveal the values stripped from responses.
+func unreadableQueryFields(access *core.FieldAccess, filter bson.M, opts queryOptions) []string {
+	fields := make([]string, 0, len(filter)+len(opts.GroupBy)+1)
+	for field := range filter {
+		fields = append(fields, field)
+	}
+	fields = append(fields, opts.GroupBy...)
+	for _, accFields := range opts.Accumulators {
+		fields = append(fields, accFields...)
+	}
+	for _, agg := range opts.Agg {
+		if _, field, found := strings.Cut(agg, ":"); found {
+			fields = append(fields, field)
+		}
+	}
+	if opts.Distinct != "" {
+		fields = append(fields, opts.Distinct)
+	}
+	fields = append(fields, opts.OData.fields...)
+
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		root, _, _ := strings.Cut(field, ".")
+		if root != "" && !strings.HasPrefix(root, "$") && !access.CanRead(root) {
+			seen[root] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// stripResponse removes unreadable fields from documents in a response. A map with a
+// "data" key is treated as an envelope and only its data is stripped.
+func stripResponse(response interface{}, access *core.FieldAccess) interface{} {
+	switch v := response.(type) {
+	case fiber.Map:
+		if data, ok := v["data"]; ok {
+			v["data"] = stripResponse(data, access)
+			return v
+		}
+		return fiber.Map(access.StripDocument(v))
+	case bson.M:
+		return bson.M(access.StripDocument(v))
+	case map[string]interface{}:
+		if data, ok := v["data"]; ok {
+			v["data"] = stripResponse(data, access)
+			return v
+		}
+		return access.StripDocument(v)
+	case primitive.D:
+		stripped := make(primitive.D, 0, len(v))
+		for _, elem := range v {
+			if access.CanRead(elem.Key) {
+				stripped = append(stripped, elem)
+			}
+		}
+		return stripped
+	case []bson.M:
+		stripped := make([]bson.M, len(v))
+		for i, doc := range v {
+			stripped[i] = access.StripDocument(doc)
+		}
+		return stripped
+	case []map[string]interface{}:
+		stripped := make([]map[string]interface{}, len(v))
+		for i, doc := range v {
+			stripped[i] = access.StripDocument(doc)
+		}
+		return stripped
+	case []interface{}:
+		stripped := make([]interface{}, len(v))
+		for i, item := range v {
+			stripped[i] = stripResponse(item, access)
+		}
+		return stripped
+	}
+	return response
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
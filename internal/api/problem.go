@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// problemMediaType is the content type of RFC 7807 problem details.
+const problemMediaType = "application/problem+json"
+
+// ProblemDetails rewrites JSON error responses (status >= 400) as RFC 7807 problem
+// details. "error" (or "message") becomes detail, a string "code" (see the error
+// catalog) is appended to typeBase to form type, and other fields are kept as
+// extension members. Errors returned by handlers are left to the ErrorHandler.
+func ProblemDetails(typeBase string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+		status := c.Response().StatusCode()
+		if status < http.StatusBadRequest || !strings.HasPrefix(string(c.Response().Header.ContentType()), fiber.MIMEApplicationJSON) {
+			return nil // JSON:API, msgpack and non-JSON bodies keep their own formats
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(c.Response().Body(), &body); err != nil {
+			return nil
+		}
+		problem := newProblem(c, status, typeBase, body)
+		out, err := json.Marshal(problem)
+		if err != nil {
+			log.Printf("ERROR: Failed to encode problem details for %s: %v", c.Path(), err)
+			return nil
+		}
+		c.Response().SetBody(out)
+		c.Set(fiber.HeaderContentType, problemMediaType)
+		return nil
+	}
+}
+
+// newProblem maps an error body to problem details.
+func newProblem(c *fiber.Ctx, status int, typeBase string, body map[string]interface{}) map[string]interface{} {
+	problem := make(map[string]interface{}, len(body)+5)
+	for k, v := range body {
+		switch k {
+		case "statusCode", "status", "type", "title", "instance":
+			// Replaced by the standard members
+		default:
+			problem[k] = v
+		}
+	}
+	detail, _ := body["error"].(string)
+	if detail == "" {
+		detail, _ = body["message"].(string)
+	}
+	if detail != "" {
+		problem["detail"] = detail
+		delete(problem, "error")
+		if detail == body["message"] {
+			delete(problem, "message")
+		}
+	}
+
+	problem["type"] = "about:blank"
+	if code, ok := body["code"].(string); ok && code != "" && typeBase != "" {
+		problem["type"] = strings.TrimRight(typeBase, "/") + "/" + code
+	}
+	problem["title"] = http.StatusText(status)
+	problem["status"] = status
+	problem["instance"] = c.Path()
+	return problem
+}
+
+// SendProblem answers with a problem details document. The server's ErrorHandler uses
+// it for errors returned by handlers (unknown routes, body limits, panics).
+func SendProblem(c *fiber.Ctx, status int, detail string) error {
+	problem := map[string]interface{}{
+		"type":     "about:blank",
+		"title":    http.StatusText(status),
+		"status":   status,
+		"instance": c.Path(),
+	}
+	if detail != "" && detail != http.StatusText(status) {
+		problem["detail"] = detail
+	}
+	c.Status(status)
+	c.Set(fiber.HeaderContentType, problemMediaType)
+	out, err := json.Marshal(problem)
+	if err != nil {
+		return err
+	}
+	return c.Send(out)
+}
@@ -0,0 +1,276 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// encodeProtobuf encodes a decoded JSON response with the definition's schema. An array
+// response becomes the "<Message>List" wrapper (items in field 1). Fields missing from
+// the schema are dropped; zero values are omitted as in proto3.
+func encodeProtobuf(schema *models.ProtobufSchema, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return appendProtoMessage(nil, schema.Fields, v)
+	case []interface{}:
+		var b []byte
+		for i, item := range v {
+			doc, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("item %d is not an object", i)
+			}
+			msg, err := appendProtoMessage(nil, schema.Fields, doc)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			b = appendProtoTag(b, 1, wireBytes)
+			b = appendProtoBytes(b, msg)
+		}
+		return b, nil
+	}
+	return nil, errors.New("response is neither an object nor an array")
+}
+
+func appendProtoMessage(b []byte, fields []models.ProtoField, doc map[string]interface{}) ([]byte, error) {
+	ordered := make([]models.ProtoField, len(fields))
+	copy(ordered, fields)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Number < ordered[j].Number })
+
+	var err error
+	for _, f := range ordered {
+		v, ok := doc[f.Name]
+		if !ok || v == nil {
+			continue
+		}
+		if !f.Repeated {
+			if b, err = appendProtoField(b, f, v); err != nil {
+				return nil, fmt.Errorf("field '%s': %w", f.Name, err)
+			}
+			continue
+		}
+		items, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("field '%s': repeated field is not an array", f.Name)
+		}
+		if packedType(f.Type) {
+			var packed []byte
+			for _, item := range items {
+				if packed, err = appendProtoScalar(packed, f.Type, item); err != nil {
+					return nil, fmt.Errorf("field '%s': %w", f.Name, err)
+				}
+			}
+			if len(packed) > 0 {
+				b = appendProtoBytes(appendProtoTag(b, f.Number, wireBytes), packed)
+			}
+			continue
+		}
+		for _, item := range items {
+			if b, err = appendProtoField(b, f, item); err != nil {
+				return nil, fmt.Errorf("field '%s': %w", f.Name, err)
+			}
+		}
+	}
+	return b, nil
+}
+
+// packedType reports whether repeated values of the type are packed (proto3 numeric scalars).
+func packedType(t string) bool {
+	return t != "string" && t != "bytes" && t != "message"
+}
+
+// appendProtoField writes one tagged value, skipping proto3 default values.
+func appendProtoField(b []byte, f models.ProtoField, v interface{}) ([]byte, error) {
+	switch f.Type {
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %T", v)
+		}
+		if s == "" {
+			return b, nil
+		}
+		return appendProtoBytes(appendProtoTag(b, f.Number, wireBytes), []byte(s)), nil
+	case "bytes":
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a base64 string, got %T", v)
+		}
+		raw, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64: %w", err)
+		}
+		if len(raw) == 0 {
+			return b, nil
+		}
+		return appendProtoBytes(appendProtoTag(b, f.Number, wireBytes), raw), nil
+	case "message":
+		doc, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an object, got %T", v)
+		}
+		msg, err := appendProtoMessage(nil, f.Fields, doc)
+		if err != nil {
+			return nil, err
+		}
+		return appendProtoBytes(appendProtoTag(b, f.Number, wireBytes), msg), nil
+	}
+
+	scalar, err := appendProtoScalar(nil, f.Type, v)
+	if err != nil {
+		return nil, err
+	}
+	if isZeroScalar(scalar) {
+		return b, nil
+	}
+	wire := wireVarint
+	switch f.Type {
+	case "double":
+		wire = wireFixed64
+	case "float":
+		wire = wireFixed32
+	}
+	return append(appendProtoTag(b, f.Number, wire), scalar...), nil
+}
+
+// appendProtoScalar writes a numeric or bool value without its tag.
+func appendProtoScalar(b []byte, t string, v interface{}) ([]byte, error) {
+	if t == "bool" {
+		flag, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected a bool, got %T", v)
+		}
+		if flag {
+			return append(b, 1), nil
+		}
+		return append(b, 0), nil
+	}
+	num, ok := v.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("expected a number, got %T", v)
+	}
+	switch t {
+	case "double":
+		f, err := num.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(f)), nil
+	case "float":
+		f, err := num.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(f))), nil
+	}
+	n, err := num.Int64()
+	if err != nil {
+		return nil, fmt.Errorf("expected an integer, got %s", num)
+	}
+	switch t {
+	case "int32", "int64":
+		return binary.AppendUvarint(b, uint64(n)), nil
+	case "uint32", "uint64":
+		if n < 0 {
+			return nil, fmt.Errorf("negative value %d for %s", n, t)
+		}
+		return binary.AppendUvarint(b, uint64(n)), nil
+	case "sint32", "sint64":
+		return binary.AppendUvarint(b, uint64((n<<1)^(n>>63))), nil
+	}
+	return nil, fmt.Errorf("unsupported type '%s'", t)
+}
+
+// isZeroScalar reports whether an encoded scalar is the proto3 default (all zero bytes).
+func isZeroScalar(encoded []byte) bool {
+	for _, c := range encoded {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func appendProtoTag(b []byte, number, wire int) []byte {
+	return binary.AppendUvarint(b, uint64(number)<<3|uint64(wire))
+}
+
+func appendProtoBytes(b []byte, data []byte) []byte {
+	return append(binary.AppendUvarint(b, uint64(len(data))), data...)
+}
+
+// --- .proto export ---
+
+// GetProtoSchema returns the .proto file of a definition's protobuf response schema
+func (h *Handler) GetProtoSchema(c *fiber.Ctx) error {
+	name := c.Params("name")
+	for _, api := range h.cachedAPIs() {
+		if api.Name != name {
+			continue
+		}
+		if api.Protobuf == nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API has no protobuf schema"})
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.SendString(renderProto(api.Protobuf))
+	}
+	return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API not found"})
+}
+
+func renderProto(schema *models.ProtobufSchema) string {
+	var b strings.Builder
+	b.WriteString("syntax = \"proto3\";\n\n")
+	writeProtoMessage(&b, schema.Message, schema.Fields, "")
+	fmt.Fprintf(&b, "\nmessage %sList {\n  repeated %s items = 1;\n}\n", schema.Message, schema.Message)
+	return b.String()
+}
+
+func writeProtoMessage(b *strings.Builder, name string, fields []models.ProtoField, indent string) {
+	fmt.Fprintf(b, "%smessage %s {\n", indent, name)
+	for _, f := range fields {
+		if f.Type == "message" {
+			writeProtoMessage(b, nestedMessageName(f), f.Fields, indent+"  ")
+		}
+	}
+	for _, f := range fields {
+		typ := f.Type
+		if typ == "message" {
+			typ = nestedMessageName(f)
+		}
+		label := ""
+		if f.Repeated {
+			label = "repeated "
+		}
+		fmt.Fprintf(b, "%s  %s%s %s = %d;\n", indent, label, typ, f.Name, f.Number)
+	}
+	fmt.Fprintf(b, "%s}\n", indent)
+}
+
+// nestedMessageName names a nested message after its field unless the schema names it.
+func nestedMessageName(f models.ProtoField) string {
+	if f.Message != "" {
+		return f.Message
+	}
+	r := []rune(f.Name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
@@ -0,0 +1,253 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Reserved query parameters that control how a default GET is executed.
+// They are never used as part of the Mongo filter.
+const (
+	paramCount    = "_count"
+	paramDistinct = "_distinct"
+	paramGroupBy  = "_groupBy"
+	paramSearch   = "q"      // Only reserved for definitions with SearchFields
+	paramBucket   = "bucket" // Only reserved for time-series definitions
+	paramAgg      = "agg"    // Only reserved for time-series definitions
+)
+
+// bucketUnits maps the bucket size suffix to a $dateTrunc unit
+var bucketUnits = map[string]string{
+	"s": "second",
+	"m": "minute",
+	"h": "hour",
+	"d": "day",
+	"w": "week",
+	"M": "month",
+	"y": "year",
+}
+
+// groupAccumulators maps the shortcut query parameter to its $group accumulator
+var groupAccumulators = map[string]string{
+	"_sum": "$sum",
+	"_avg": "$avg",
+	"_min": "$min",
+	"_max": "$max",
+}
+
+var controlParams = map[string]bool{
+	paramCount:    true,
+	paramDistinct: true,
+	paramGroupBy:  true,
+	"_sum":        true,
+	"_avg":        true,
+	"_min":        true,
+	"_max":        true,
+
+	paramPageLimit:  true, // JSON:API pagination
+	paramPageOffset: true,
+}
+
+// queryOptions describes the query mode for a default GET request
+type queryOptions struct {
+	Count    bool   // Return only the number of matching documents
+	Distinct string // Return only the distinct values of this field
+
+	GroupBy      []string            // Fields to group by (translated to an aggregation pipeline)
+	Accumulators map[string][]string // Accumulator param (e.g. "_sum") -> fields
+
+	Search         string // Full-text search terms (?q=), only when the definition has SearchFields
+	searchReserved bool   // ?q= is a control parameter for this definition, even when blank
+
+	Bucket string   // Time bucket size (e.g. "1h"), only for time-series definitions
+	Agg    []string // Bucket accumulators as "op:field" (e.g. "avg:value")
+
+	OData odataQuery // $filter, $select, $orderby, $top, $skip, $count
+
+	Fields         []string // Sparse fieldset (?fields=), projected in the query
+	fieldsReserved bool     // ?fields= is a control parameter for this definition
+
+	afterReserved bool // ?after= and ?limit= are cursor pagination parameters for this definition
+	limitReserved bool
+}
+
+// parseQueryOptions reads the query mode from the definition and lets query parameters override it.
+func parseQueryOptions(c *fiber.Ctx, api models.ApiDefinition) queryOptions {
+	var opts queryOptions
+	switch api.QueryMode {
+	case "count":
+		opts.Count = true
+	case "distinct":
+		opts.Distinct = api.DistinctField
+	}
+
+	if v := c.Query(paramCount); v != "" {
+		opts.Count = strings.EqualFold(v, "true") || v == "1"
+	}
+	if v := c.Query(paramDistinct); v != "" {
+		opts.Distinct = v
+		opts.Count = false // distinct takes precedence when both are requested
+	}
+
+	if len(api.SearchFields) > 0 {
+		opts.searchReserved = true
+		opts.Search = strings.TrimSpace(c.Query(paramSearch))
+	}
+
+	if api.TimeSeries != nil {
+		opts.Bucket = strings.TrimSpace(c.Query(paramBucket))
+		opts.Agg = splitList(c.Query(paramAgg))
+	}
+
+	if fieldsReserved(api) {
+		opts.fieldsReserved = true
+		opts.Fields = splitList(c.Query(paramFields))
+	}
+	opts.afterReserved = cursorReserved(api, paramAfter)
+	opts.limitReserved = cursorReserved(api, paramLimit)
+
+	if v := c.Query(paramGroupBy); v != "" {
+		opts.GroupBy = splitList(v)
+		opts.Accumulators = make(map[string][]string)
+		for param := range groupAccumulators {
+			if fields := splitList(c.Query(param)); len(fields) > 0 {
+				opts.Accumulators[param] = fields
+			}
+		}
+	}
+	return opts
+}
+
+// buildGroupPipeline translates the group-by shortcuts into an aggregation pipeline.
+// Each result row carries the grouped values under "group", a "count" and one
+// "<field>_<op>" entry per requested accumulator. Fields whose output names collide
+// (e.g. "a.b" and "a_b") are rejected rather than overwriting each other.
+func buildGroupPipeline(filter bson.M, opts queryOptions) ([]bson.M, error) {
+	groupID := bson.M{}
+	for _, field := range opts.GroupBy {
+		key := groupKey(field)
+		if _, taken := groupID[key]; taken {
+			return nil, fmt.Errorf("group-by fields collide on '%s'", key)
+		}
+		groupID[key] = "$" + field
+	}
+
+	group := bson.M{"_id": groupID, "count": bson.M{"$sum": 1}}
+	for param, fields := range opts.Accumulators {
+		op := groupAccumulators[param]
+		for _, field := range fields {
+			key := groupKey(field) + "_" + strings.TrimPrefix(op, "$")
+			if _, taken := group[key]; taken || key == "group" {
+				return nil, fmt.Errorf("accumulator fields collide on '%s'", key)
+			}
+			group[key] = bson.M{op: "$" + field}
+		}
+	}
+
+	return []bson.M{
+		{"$match": filter},
+		{"$group": group},
+		{"$sort": bson.M{"count": -1}},
+		{"$set": bson.M{"group": "$_id"}},
+		{"$unset": "_id"},
+	}, nil
+}
+
+// buildBucketPipeline groups a time-series collection into fixed time windows using $dateTrunc.
+// Each window carries "time", "count", and one "<field>_<op>" entry per requested accumulator.
+func buildBucketPipeline(filter bson.M, ts *models.TimeSeriesOptions, opts queryOptions) ([]bson.M, error) {
+	if ts == nil || ts.TimeField == "" {
+		return nil, fmt.Errorf("bucket queries require a time-series definition with a timeField")
+	}
+
+	size, unit, err := parseBucket(opts.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	group := bson.M{
+		"_id": bson.M{"$dateTrunc": bson.M{
+			"date":    "$" + ts.TimeField,
+			"unit":    unit,
+			"binSize": size,
+		}},
+		"count": bson.M{"$sum": 1},
+	}
+	for _, spec := range opts.Agg {
+		op, field, found := strings.Cut(spec, ":")
+		if !found || field == "" {
+			return nil, fmt.Errorf("invalid agg '%s', expected 'op:field'", spec)
+		}
+		accumulator, ok := groupAccumulators["_"+op]
+		if !ok {
+			return nil, fmt.Errorf("unsupported agg operation '%s'", op)
+		}
+		group[groupKey(field)+"_"+op] = bson.M{accumulator: "$" + field}
+	}
+
+	return []bson.M{
+		{"$match": filter},
+		{"$group": group},
+		{"$sort": bson.M{"_id": 1}},
+		{"$set": bson.M{"time": "$_id"}},
+		{"$unset": "_id"},
+	}, nil
+}
+
+// parseBucket splits a bucket size like "15m" into its bin size and $dateTrunc unit.
+func parseBucket(bucket string) (int, string, error) {
+	if len(bucket) < 2 {
+		return 0, "", fmt.Errorf("invalid bucket '%s', expected e.g. '1h' or '15m'", bucket)
+	}
+	unit, ok := bucketUnits[bucket[len(bucket)-1:]]
+	if !ok {
+		return 0, "", fmt.Errorf("invalid bucket unit in '%s', expected one of s, m, h, d, w, M, y", bucket)
+	}
+	size, err := strconv.Atoi(bucket[:len(bucket)-1])
+	if err != nil || size <= 0 {
+		return 0, "", fmt.Errorf("invalid bucket size in '%s'", bucket)
+	}
+	return size, unit, nil
+}
+
+// groupKey makes a dotted path safe to use as an output field name
+func groupKey(field string) string {
+	return strings.ReplaceAll(field, ".", "_")
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// buildFilter converts request data into a Mongo filter, skipping reserved control parameters.
+func buildFilter(data map[string]interface{}, opts queryOptions) bson.M {
+	filter := bson.M{}
+	for k, v := range data {
+		if controlParams[k] || odataOptions[k] || (opts.searchReserved && k == paramSearch) {
+			continue
+		}
+		if opts.Bucket != "" && (k == paramBucket || k == paramAgg) {
+			continue
+		}
+		if opts.fieldsReserved && k == paramFields {
+			continue
+		}
+		if (opts.afterReserved && k == paramAfter) || (opts.limitReserved && k == paramLimit) {
+			continue
+		}
+		filter[k] = v
+	}
+	return filter
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/database"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Defaults for the read-through definition fallback.
+const (
+	defaultNegativeTTL = 30 * time.Second
+	maxNegativeEntries = 10000 // Bounds memory when clients probe many unknown paths
+)
+
+// readThroughState configures the store lookup on a route cache miss and remembers
+// route keys that were not found, so unknown paths do not query MongoDB on every request.
+type readThroughState struct {
+	mu          sync.Mutex
+	enabled     bool
+	negativeTTL time.Duration
+	misses      map[string]time.Time // Route key -> time the negative result expires
+}
+
+// ConfigureReadThrough enables the store fallback for route keys missing from the cache
+// (e.g. definitions created on another instance). negativeTTL <= 0 uses the default.
+func (h *Handler) ConfigureReadThrough(enabled bool, negativeTTL time.Duration) {
+	if negativeTTL <= 0 {
+		negativeTTL = defaultNegativeTTL
+	}
+	h.readThrough.mu.Lock()
+	defer h.readThrough.mu.Unlock()
+	h.readThrough.enabled = enabled
+	h.readThrough.negativeTTL = negativeTTL
+	h.readThrough.misses = make(map[string]time.Time)
+}
+
+// lookupAllowed reports whether a miss for key should go to the store.
+func (r *readThroughState) lookupAllowed(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.enabled {
+		return false
+	}
+	expires, cached := r.misses[key]
+	if !cached {
+		return true
+	}
+	if time.Now().Before(expires) {
+		return false
+	}
+	delete(r.misses, key)
+	return true
+}
+
+// remember caches a negative result for key.
+func (r *readThroughState) remember(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.misses == nil {
+		return
+	}
+	now := time.Now()
+	if len(r.misses) >= maxNegativeEntries {
+		for k, expires := range r.misses {
+			if now.After(expires) {
+				delete(r.misses, k)
+			}
+		}
+		if len(r.misses) >= maxNegativeEntries {
+			r.misses = make(map[string]time.Time)
+		}
+	}
+	r.misses[key] = now.Add(r.negativeTTL)
+}
+
+// forget drops a negative result, e.g. once the route is added to the cache.
+func (r *readThroughState) forget(key string) {
+	r.mu.Lock()
+	delete(r.misses, key)
+	r.mu.Unlock()
+}
+
+// readThroughRoute loads a definition missing from the cache directly from the store,
+// installs it and acquires its handle. It returns false when the route does not exist.
+func (h *Handler) readThroughRoute(c *fiber.Ctx, key string) (*routeHandle, bool) {
+	if !h.readThrough.lookupAllowed(key) {
+		return nil, false
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	api, err := h.store.GetAPIDefinitionByRoute(ctx, c.Method(), c.Path())
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			h.readThrough.remember(key)
+		} else {
+			// Not cached: the lookup is retried once the store is healthy
+			log.Printf("WARN: Read-through lookup for route key '%s' failed: %v", key, err)
+		}
+		return nil, false
+	}
+	if problems := core.CheckDefinition(*api); len(problems) > 0 {
+		log.Printf("WARN: Read-through definition '%s' for route key '%s' is invalid, not serving it: %v", api.Name, key, problems)
+		h.readThrough.remember(key)
+		return nil, false
+	}
+
+	h.prepareCollection(ctx, *api)
+	version := h.swapRoute("", key, *api)
+	log.Printf("INFO: Loaded API '%s' (v%d) into the route cache on first request (read-through)", api.Name, version)
+	return h.acquireRoute(key)
+}
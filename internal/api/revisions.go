@@ -0,0 +1,220 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// revisionLocal marks a request that is publishing an approved revision (fiber Locals key).
+const revisionLocal = "revision"
+
+// approvalState switches definition changes to the two-step approval workflow.
+type approvalState struct {
+	required bool
+}
+
+// ConfigureApproval makes create, update and delete of definitions produce pending
+// revisions that must be approved (by an admin other than the author) before publishing.
+func (h *Handler) ConfigureApproval(required bool) {
+	h.approval.required = required
+}
+
+// needsApproval reports whether a definition change in this request must become a revision.
+func (h *Handler) needsApproval(c *fiber.Ctx) bool {
+	return h.approval.required && c.Locals(revisionLocal) == nil
+}
+
+// actorName identifies the operator in the audit trail.
+func actorName(c *fiber.Ctx) string {
+	op := operatorFrom(c)
+	if op == nil {
+		return "anonymous"
+	}
+	if op.Email != "" {
+		return op.Email
+	}
+	return op.Subject
+}
+
+// submitRevision records a proposed change and answers 202 with the pending revision.
+func (h *Handler) submitRevision(c *fiber.Ctx, action, name string, def *models.ApiDefinition) error {
+	if def != nil {
+		if problems := core.CheckDefinition(*def); len(problems) > 0 {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Definition is invalid", "errors": problems})
+		}
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	rev := &models.Revision{APIName: name, Action: action, Definition: def, Author: actorName(c)}
+	if err := h.store.CreateRevision(ctx, rev); err != nil {
+		log.Printf("ERROR: Handler failed to create revision for API '%s': %v", name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create revision"})
+	}
+	log.Printf("INFO: Revision %s (%s of API '%s') by '%s' is waiting for approval", rev.ID.Hex(), action, name, rev.Author)
+	target := models.ApiDefinition{Name: name}
+	if def != nil {
+		target = *def
+	}
+	h.notifyLifecycle(c, EventRevisionSubmitted, target, action+" revision "+rev.ID.Hex())
+	c.Set(fiber.HeaderLocation, "/api-generator/revisions/"+rev.ID.Hex())
+	return c.Status(http.StatusAccepted).JSON(fiber.Map{
+		"status":  "accepted",
+		"code":    http.StatusAccepted,
+		"message": "Change submitted for approval",
+		"data":    rev,
+	})
+}
+
+// ListRevisions lists revisions, newest first (?status=pending&api=name&limit=50)
+func (h *Handler) ListRevisions(c *fiber.Ctx) error {
+	limit, err := strconv.ParseInt(c.Query("limit", "50"), 10, 64)
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	revisions, err := h.store.ListRevisions(ctx, c.Query("status"), c.Query("api"), limit)
+	if err != nil {
+		log.Printf("ERROR: Handler failed to list revisions: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list revisions"})
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   revisions,
+	})
+}
+
+// GetRevision returns a revision with its proposed definition and audit trail
+func (h *Handler) GetRevision(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	rev, err := h.store.GetRevision(ctx, c.Params("id"))
+	if err != nil {
+		return sendRevisionError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   rev,
+	})
+}
+
+type revisionComment struct {
+	Comment string `json:"comment"`
+}
+
+// CommentRevision adds a comment to a revision's audit trail
+func (h *Handler) CommentRevision(c *fiber.Ctx) error {
+	var body revisionComment
+	if err := c.BodyParser(&body); err != nil || body.Comment == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "comment is required"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	rev, err := h.store.GetRevision(ctx, c.Params("id"))
+	if err != nil {
+		return sendRevisionError(c, err)
+	}
+	event := models.RevisionEvent{Type: "commented", Actor: actorName(c), Comment: body.Comment, At: time.Now().UTC()}
+	if err := h.store.AddRevisionEvent(ctx, rev.ID, event); err != nil {
+		return sendRevisionError(c, err)
+	}
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusCreated,
+		"data":   event,
+	})
+}
+
+// RejectRevision closes a pending revision without publishing it
+func (h *Handler) RejectRevision(c *fiber.Ctx) error {
+	var body revisionComment
+	_ = c.BodyParser(&body) // The comment is optional
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	rev, err := h.store.GetRevision(ctx, c.Params("id"))
+	if err != nil {
+		return sendRevisionError(c, err)
+	}
+	event := models.RevisionEvent{Type: "rejected", Actor: actorName(c), Comment: body.Comment, At: time.Now().UTC()}
+	rev, err = h.store.DecideRevision(ctx, rev.ID, models.RevisionRejected, event)
+	if err != nil {
+		return sendRevisionError(c, err)
+	}
+	log.Printf("INFO: Revision %s of API '%s' rejected by '%s'", rev.ID.Hex(), rev.APIName, event.Actor)
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   rev,
+	})
+}
+
+// ApproveRevision approves a pending revision and publishes it to the store and route cache.
+// The author cannot approve their own revision. If publishing fails the revision is reopened.
+func (h *Handler) ApproveRevision(c *fiber.Ctx) error {
+	var body revisionComment
+	_ = c.BodyParser(&body) // The comment is optional
+	ctx, cancel := context.WithTimeout(c.Context(), 15*time.Second)
+	defer cancel()
+
+	rev, err := h.store.GetRevision(ctx, c.Params("id"))
+	if err != nil {
+		return sendRevisionError(c, err)
+	}
+	actor := actorName(c)
+	if operatorFrom(c) != nil && actor == rev.Author {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "A revision must be approved by someone other than its author"})
+	}
+	event := models.RevisionEvent{Type: "approved", Actor: actor, Comment: body.Comment, At: time.Now().UTC()}
+	rev, err = h.store.DecideRevision(ctx, rev.ID, models.RevisionPublished, event)
+	if err != nil {
+		return sendRevisionError(c, err)
+	}
+
+	c.Locals(revisionLocal, rev)
+	switch rev.Action {
+	case models.RevisionCreate:
+		err = h.createDefinition(c, rev.Definition)
+	case models.RevisionUpdate:
+		err = h.applyDefinitionUpdate(ctx, c, rev.APIName, rev.Definition)
+	case models.RevisionDelete:
+		err = h.deleteDefinition(c, rev.APIName)
+	}
+	if status := c.Response().StatusCode(); err != nil || status >= http.StatusBadRequest {
+		failed := models.RevisionEvent{Type: "publishFailed", Actor: actor, Comment: string(c.Response().Body()), At: time.Now().UTC()}
+		if reopenErr := h.store.ReopenRevision(context.Background(), rev.ID, failed); reopenErr != nil {
+			log.Printf("ERROR: Failed to reopen revision %s after publishing failed: %v", rev.ID.Hex(), reopenErr)
+		}
+		log.Printf("WARN: Approved revision %s of API '%s' could not be published (status %d)", rev.ID.Hex(), rev.APIName, status)
+		return err
+	}
+	log.Printf("INFO: Revision %s (%s of API '%s') approved by '%s' and published", rev.ID.Hex(), rev.Action, rev.APIName, actor)
+	return nil
+}
+
+func sendRevisionError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Revision not found"})
+	case errors.Is(err, database.ErrRevisionDecided):
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Printf("ERROR: Handler failed on revision: %v", err)
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to process revision"})
+}
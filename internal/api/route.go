@@ -2,7 +2,6 @@ package api
 
 import (
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/logger" // สามารถเพิ่ม middleware อื่นๆ ที่นี่ได้
 	// "github.com/gofiber/fiber/v2/middleware/cors" // ตัวอย่าง middleware เพิ่มเติม
 )
 
@@ -12,25 +11,145 @@ func RegisterRoutes(app *fiber.App, h *Handler) {
 	// --- Middleware ---
 	// คุณสามารถเพิ่ม Middleware ที่ต้องการให้ทำงานกับทุก Route ที่ลงทะเบียนในไฟล์นี้ได้ที่นี่
 	// หรือจะไปเพิ่มใน main.go ก่อนเรียก RegisterRoutes ก็ได้
-	app.Use(logger.New(logger.Config{
-		// สามารถปรับแต่ง Format ของ Logger ได้ตามต้องการ
-		Format: "[${ip}]:${port} ${status} - ${method} ${path}\n",
-	}))
+	// Access log: format, fields and sampling come from ConfigureAccessLog (IP ผ่าน trusted proxies แล้ว)
+	app.Use(correlate) // X-Request-Id / traceparent, before the access log so it can include them
+	app.Use(h.logAccess)
+	app.Use(h.guard) // In-flight and response size limits (ConfigureGuardrails)
 	// app.Use(cors.New()) // ตัวอย่างการเปิดใช้งาน CORS
 
 	// --- Routes for managing API Definitions ---
 	// จัดกลุ่ม route สำหรับจัดการ API definitions เพื่อความชัดเจน
 	apiGenGroup := app.Group("/api-generator")
+	apiGenGroup.Use(h.RequireOperator) // No-op unless OIDC operator login is configured
+	apiGenGroup.Use(h.profiler())      // GET /api-generator/debug/pprof/ (admin, only with PPROF=true)
 
-	apiGenGroup.Post("/create", h.CreateAPI)       // POST /api-generator/create
-	apiGenGroup.Get("/list", h.ListAPIs)           // GET /api-generator/list
+	// Operator login (OIDC)
+	apiGenGroup.Get("/auth/login", h.Login)            // GET /api-generator/auth/login?returnTo=/path
+	apiGenGroup.Get("/auth/callback", h.LoginCallback) // GET /api-generator/auth/callback (redirect URL registered at the provider)
+	apiGenGroup.Post("/auth/logout", h.Logout)         // POST /api-generator/auth/logout
+	apiGenGroup.Get("/auth/me", h.GetOperator)         // GET /api-generator/auth/me
+
+	apiGenGroup.Post("/create", h.CreateAPI)         // POST /api-generator/create
+	apiGenGroup.Get("/list", h.ListAPIs)             // GET /api-generator/list
 	apiGenGroup.Get("/detail/:name", h.GetAPIDetail) // GET /api-generator/detail/some-api-name
 	apiGenGroup.Delete("/delete/:name", h.DeleteAPI) // DELETE /api-generator/delete/some-api-name
-	apiGenGroup.Put("/update/:name", h.UpdateAPI)     // PUT /api-generator/update/some-api-name
+	apiGenGroup.Put("/update/:name", h.UpdateAPI)    // PUT /api-generator/update/some-api-name
+	apiGenGroup.Patch("/update/:name", h.PatchAPI)   // PATCH /api-generator/update/some-api-name (JSON Merge Patch)
+	apiGenGroup.Patch("/flow/:name", h.PatchAPIFlow) // PATCH /api-generator/flow/some-api-name (JSON Patch on conditionalFlow)
+
+	// Maintenance mode (global and per API)
+	apiGenGroup.Get("/maintenance", h.GetMaintenance)             // GET /api-generator/maintenance
+	apiGenGroup.Put("/maintenance", h.UpdateMaintenance)          // PUT /api-generator/maintenance
+	apiGenGroup.Put("/maintenance/:name", h.UpdateAPIMaintenance) // PUT /api-generator/maintenance/some-api-name
+
+	// Definitions rejected at load time
+	apiGenGroup.Get("/load-report", h.GetLoadReport)       // GET /api-generator/load-report
+	apiGenGroup.Get("/stats", h.GetStats)                  // GET /api-generator/stats
+	apiGenGroup.Get("/slow-report", h.GetSlowReport)       // GET /api-generator/slow-report?limit=20&sort=avg|max|slow
+	apiGenGroup.Get("/compile-report", h.GetCompileReport) // GET /api-generator/compile-report?limit=50
+	apiGenGroup.Delete("/slow-report", h.ResetSlowReport)  // DELETE /api-generator/slow-report
+	apiGenGroup.Get("/validate", h.ValidateAPIs)           // GET /api-generator/validate
+	apiGenGroup.Get("/lint/:name", h.LintAPI)              // GET /api-generator/lint/some-api-name
+	apiGenGroup.Get("/cluster", h.GetCluster)              // GET /api-generator/cluster
+	apiGenGroup.Get("/config", h.GetRuntimeConfig)         // GET /api-generator/config (reloadable settings in effect)
+	apiGenGroup.Post("/config/reload", h.ReloadConfig)     // POST /api-generator/config/reload (same as SIGHUP)
+
+	// Dead letters (failed saves kept for inspection and replay)
+	apiGenGroup.Get("/dead-letters", h.ListDeadLetters)              // GET /api-generator/dead-letters?status=pending
+	apiGenGroup.Get("/dead-letters/:id", h.GetDeadLetter)            // GET /api-generator/dead-letters/<id>
+	apiGenGroup.Post("/dead-letters/:id/replay", h.ReplayDeadLetter) // POST /api-generator/dead-letters/<id>/replay
+
+	// Revisions (approval workflow for definition changes)
+	apiGenGroup.Get("/revisions", h.ListRevisions)                 // GET /api-generator/revisions?status=pending
+	apiGenGroup.Get("/revisions/:id", h.GetRevision)               // GET /api-generator/revisions/<id>
+	apiGenGroup.Post("/revisions/:id/comments", h.CommentRevision) // POST /api-generator/revisions/<id>/comments
+	apiGenGroup.Post("/revisions/:id/approve", h.ApproveRevision)  // POST /api-generator/revisions/<id>/approve
+	apiGenGroup.Post("/revisions/:id/reject", h.RejectRevision)    // POST /api-generator/revisions/<id>/reject
+
+	// Snapshots of the whole catalog (blue/green rollback after a bad bulk import)
+	apiGenGroup.Post("/snapshot", h.CreateSnapshot)                // POST /api-generator/snapshot {"note": "..."}
+	apiGenGroup.Get("/snapshots", h.ListSnapshots)                 // GET /api-generator/snapshots?limit=50
+	apiGenGroup.Delete("/snapshots/:snapshotId", h.DeleteSnapshot) // DELETE /api-generator/snapshots/<id>
+	apiGenGroup.Post("/restore/:snapshotId", h.RestoreSnapshot)    // POST /api-generator/restore/<id>
+	apiGenGroup.Get("/backups", h.ListBackups)                     // GET /api-generator/backups (object storage, newest first)
+	apiGenGroup.Post("/backups", h.CreateBackup)                   // POST /api-generator/backups (back up now)
+	apiGenGroup.Post("/backups/:name/restore", h.RestoreBackup)    // POST /api-generator/backups/<name>/restore
+	apiGenGroup.Get("/drift", h.GetDrift)                          // GET /api-generator/drift (checksums) or ?backup=<name> (drift from a backup)
+	apiGenGroup.Post("/drift", h.CompareDrift)                     // POST /api-generator/drift (drift from the bundle in the body)
+
+	// Mapping profiles (shared transformations referenced by useMappings)
+	apiGenGroup.Get("/mappings", h.ListMappingProfiles)           // GET /api-generator/mappings
+	apiGenGroup.Get("/mappings/:name", h.GetMappingProfile)       // GET /api-generator/mappings/externalOrder→internal
+	apiGenGroup.Put("/mappings/:name", h.PutMappingProfile)       // PUT /api-generator/mappings/externalOrder→internal
+	apiGenGroup.Delete("/mappings/:name", h.DeleteMappingProfile) // DELETE /api-generator/mappings/externalOrder→internal
+
+	// Data migrations of the collections behind definitions
+	apiGenGroup.Get("/migrations", h.ListMigrations)              // GET /api-generator/migrations?definition=some-api-name
+	apiGenGroup.Get("/migrations/:name", h.GetMigration)          // GET /api-generator/migrations/0002-split-full-name
+	apiGenGroup.Put("/migrations/:name", h.PutMigration)          // PUT /api-generator/migrations/0002-split-full-name
+	apiGenGroup.Delete("/migrations/:name", h.DeleteMigration)    // DELETE /api-generator/migrations/0002-split-full-name
+	apiGenGroup.Post("/migrations/:name/apply", h.ApplyMigration) // POST /api-generator/migrations/0002-split-full-name/apply?dryRun=true
+	apiGenGroup.Post("/migrate/:name", h.MigrateDefinition)       // POST /api-generator/migrate/some-api-name (all pending, in order)
+	apiGenGroup.Post("/seed/:name", h.SeedData)                   // POST /api-generator/seed/some-api-name (admin, only with SEED_ENABLED=true outside production)
+
+	// Lookup tables (constants read in flows as $lookup.<name>[<key>])
+	apiGenGroup.Get("/lookups", h.ListLookupTables)           // GET /api-generator/lookups
+	apiGenGroup.Get("/lookups/:name", h.GetLookupTable)       // GET /api-generator/lookups/taxRates
+	apiGenGroup.Put("/lookups/:name", h.PutLookupTable)       // PUT /api-generator/lookups/taxRates
+	apiGenGroup.Delete("/lookups/:name", h.DeleteLookupTable) // DELETE /api-generator/lookups/taxRates
+
+	// Feature flags ("featureFlag" condition operator)
+	apiGenGroup.Get("/flags", h.ListFeatureFlags)           // GET /api-generator/flags
+	apiGenGroup.Get("/flags/:name", h.GetFeatureFlag)       // GET /api-generator/flags/newCheckout
+	apiGenGroup.Put("/flags/:name", h.PutFeatureFlag)       // PUT /api-generator/flags/newCheckout
+	apiGenGroup.Delete("/flags/:name", h.DeleteFeatureFlag) // DELETE /api-generator/flags/newCheckout
+
+	// Message catalogs (localized messages returned through $t("key", {...}))
+	apiGenGroup.Get("/messages", h.ListMessageCatalogs)           // GET /api-generator/messages
+	apiGenGroup.Get("/messages/:name", h.GetMessageCatalog)       // GET /api-generator/messages/th
+	apiGenGroup.Put("/messages/:name", h.PutMessageCatalog)       // PUT /api-generator/messages/th
+	apiGenGroup.Delete("/messages/:name", h.DeleteMessageCatalog) // DELETE /api-generator/messages/th
+
+	// Error catalog (stable codes returned through returnError)
+	apiGenGroup.Get("/errors", h.ListErrorDefinitions)           // GET /api-generator/errors
+	apiGenGroup.Get("/errors/:name", h.GetErrorDefinition)       // GET /api-generator/errors/ORDER_NOT_FOUND
+	apiGenGroup.Put("/errors/:name", h.PutErrorDefinition)       // PUT /api-generator/errors/ORDER_NOT_FOUND
+	apiGenGroup.Delete("/errors/:name", h.DeleteErrorDefinition) // DELETE /api-generator/errors/ORDER_NOT_FOUND
+
+	// Flow fragments (shared sub-flows run by callFlow actions)
+	apiGenGroup.Get("/fragments", h.ListFlowFragments)           // GET /api-generator/fragments
+	apiGenGroup.Get("/fragments/:name", h.GetFlowFragment)       // GET /api-generator/fragments/validate-customer
+	apiGenGroup.Put("/fragments/:name", h.PutFlowFragment)       // PUT /api-generator/fragments/validate-customer
+	apiGenGroup.Delete("/fragments/:name", h.DeleteFlowFragment) // DELETE /api-generator/fragments/validate-customer
+
+	// Registered API consumers (X-Consumer-Key) and who calls which definition
+	apiGenGroup.Get("/consumers", h.ListConsumers)                // GET /api-generator/consumers
+	apiGenGroup.Post("/consumers", h.RegisterConsumer)            // POST /api-generator/consumers {"name": "mobile-app", "contact": {"email": "..."}}
+	apiGenGroup.Get("/consumers/:name", h.GetConsumer)            // GET /api-generator/consumers/mobile-app (with the definitions it calls)
+	apiGenGroup.Post("/consumers/:name/key", h.RotateConsumerKey) // POST /api-generator/consumers/mobile-app/key (new key)
+	apiGenGroup.Delete("/consumers/:name", h.DeleteConsumer)      // DELETE /api-generator/consumers/mobile-app
+	apiGenGroup.Get("/consumer-report", h.GetConsumerReport)      // GET /api-generator/consumer-report?api=some-api-name&deprecated=true&since=2026-01-01
+
+	// Async jobs (definitions with async: true)
+	apiGenGroup.Get("/jobs/:id", h.GetJob) // GET /api-generator/jobs/<id>
+
+	// Documentation of the served APIs
+	apiGenGroup.Get("/openapi.json", h.GetOpenAPI)                   // GET /api-generator/openapi.json
+	apiGenGroup.Put("/examples/:name", h.PutAPIExample)              // PUT /api-generator/examples/some-api-name
+	apiGenGroup.Post("/examples/:name/capture", h.CaptureAPIExample) // POST /api-generator/examples/some-api-name/capture
+	apiGenGroup.Get("/sdk", h.GetSDK)                                // GET /api-generator/sdk?lang=typescript|go|python
+	apiGenGroup.Get("/snippet/:name", h.GetSnippet)                  // GET /api-generator/snippet/some-api-name?lang=curl|js|go|har
+	apiGenGroup.Get("/proto/:name", h.GetProtoSchema)                // GET /api-generator/proto/some-api-name (.proto of the protobuf response)
+	apiGenGroup.Get("/grpc.proto", h.GetGRPCProto)                   // GET /api-generator/grpc.proto (service of the gRPC gateway, GRPC_ADDR)
 
 	// Endpoint สำหรับ Reload API Definitions (ถ้าต้องการ implement)
 	// apiGenGroup.Post("/reload", h.ReloadAPIs) // POST /api-generator/reload
 
+	// Public API catalog for consumers (registered before the dynamic handler so it takes precedence)
+	app.Get("/catalog", h.GetCatalog)      // GET /catalog?q=orders
+	app.Get("/openapi.json", h.GetOpenAPI) // GET /openapi.json
+	app.Get("/docs", h.GetDocs)            // GET /docs (browsable catalog with examples)
+
 	// --- Dynamic API Handler ---
 	// Middleware/Handler นี้ควรลงทะเบียน **หลังสุด** สำหรับ path ที่ต้องการให้ dynamic API ทำงาน
 	// การใช้ app.Use("/") จะทำให้ handler นี้ทำงานกับทุก request ที่ไม่ตรงกับ route ที่ลงทะเบียนไว้ก่อนหน้า
@@ -41,5 +160,7 @@ func RegisterRoutes(app *fiber.App, h *Handler) {
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "ok"})
 	})
+	app.Get("/readyz", h.Readyz)      // 503 while the database circuit breaker is open
+	app.Get("/version", h.GetVersion) // Engine build and hash of the served definitions
 
-}
\ No newline at end of file
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/models"
+)
+
+// drainWarnAfter is how long a replaced route handle may stay busy before a warning is logged.
+const drainWarnAfter = 30 * time.Second
+
+// routeHandle is an immutable, versioned snapshot of one API definition in the cache.
+// Requests acquire a handle and run entirely against it, so an update swaps in a new
+// handle while in-flight requests finish on the old one (connection draining).
+type routeHandle struct {
+	api      models.ApiDefinition
+	version  uint64
+	inflight sync.WaitGroup
+
+	// Lazy compilation (see compileOnFirstHit); handles from writes are compiled already
+	compiled    atomic.Bool
+	compileOnce sync.Once
+	problems    []core.DefinitionProblem
+}
+
+// release marks a request acquired via acquireRoute as finished.
+func (r *routeHandle) release() {
+	r.inflight.Done()
+}
+
+// newRouteTable wraps loaded definitions into versioned handles.
+func (h *Handler) newRouteTable(apis map[string]models.ApiDefinition) map[string]*routeHandle {
+	table := make(map[string]*routeHandle, len(apis))
+	h.routesAt = time.Now().UTC()
+	for key, api := range apis {
+		h.routeVersion++
+		table[key] = &routeHandle{api: api, version: h.routeVersion}
+	}
+	return table
+}
+
+// acquireRoute returns the current handle for a route key and registers an in-flight request on it.
+// The caller must call release when the request is done.
+func (h *Handler) acquireRoute(key string) (*routeHandle, bool) {
+	h.routesMutex.RLock()
+	defer h.routesMutex.RUnlock()
+	handle, exists := h.dynamicRoutes[key]
+	if !exists {
+		return nil, false
+	}
+	// Add under the read lock so a concurrent swap cannot start draining before we are counted
+	handle.inflight.Add(1)
+	return handle, true
+}
+
+// swapRoute installs a new version of a definition under key (and removes oldKey if the route moved).
+// Replaced handles are drained in the background.
+func (h *Handler) swapRoute(oldKey, key string, api models.ApiDefinition) uint64 {
+	h.routesMutex.Lock()
+	var replaced []*routeHandle
+	if oldKey != "" && oldKey != key {
+		if old, ok := h.dynamicRoutes[oldKey]; ok {
+			replaced = append(replaced, old)
+			delete(h.dynamicRoutes, oldKey)
+		}
+	}
+	if old, ok := h.dynamicRoutes[key]; ok {
+		replaced = append(replaced, old)
+	}
+	h.routeVersion++
+	h.routesAt = time.Now().UTC()
+	version := h.routeVersion
+	handle := &routeHandle{api: api, version: version}
+	handle.compiled.Store(true) // Every caller has run CheckDefinition on it
+	h.dynamicRoutes[key] = handle
+	h.routesMutex.Unlock()
+	h.readThrough.forget(key)
+
+	for _, old := range replaced {
+		go drainRoute(old)
+	}
+	return version
+}
+
+// removeRoute drops a route from the cache, letting in-flight requests finish on it.
+func (h *Handler) removeRoute(key string) {
+	h.routesMutex.Lock()
+	old, ok := h.dynamicRoutes[key]
+	delete(h.dynamicRoutes, key)
+	if ok {
+		h.routesAt = time.Now().UTC()
+	}
+	h.routesMutex.Unlock()
+	if ok {
+		go drainRoute(old)
+	}
+}
+
+// replaceRoutes swaps the whole cache (e.g. on reload), draining every previous handle.
+func (h *Handler) replaceRoutes(apis map[string]models.ApiDefinition) {
+	h.routesMutex.Lock()
+	previous := h.dynamicRoutes
+	h.dynamicRoutes = h.newRouteTable(apis)
+	h.routesMutex.Unlock()
+	for _, old := range previous {
+		go drainRoute(old)
+	}
+}
+
+// cachedAPIs returns a snapshot of the cached definitions.
+func (h *Handler) cachedAPIs() []models.ApiDefinition {
+	h.routesMutex.RLock()
+	defer h.routesMutex.RUnlock()
+	apis := make([]models.ApiDefinition, 0, len(h.dynamicRoutes))
+	for _, handle := range h.dynamicRoutes {
+		apis = append(apis, handle.api)
+	}
+	return apis
+}
+
+// drainRoute waits for the in-flight requests of a replaced handle to finish.
+func drainRoute(old *routeHandle) {
+	done := make(chan struct{})
+	go func() {
+		old.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(drainWarnAfter):
+		log.Printf("WARN: Route '%s %s' v%d still has in-flight requests after %s", old.api.Method, old.api.Endpoint, old.version, drainWarnAfter)
+		<-done
+	}
+	log.Printf("DEBUG: Drained route '%s %s' v%d", old.api.Method, old.api.Endpoint, old.version)
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"api-genarator/internal/core"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+)
+
+// RuntimeConfig holds the settings that can change without a restart. The startup values
+// come from the environment; the config file overrides only the keys it contains.
+type RuntimeConfig struct {
+	LogLevel              string     `json:"logLevel"` // debug (default), info, warn or error
+	MaxConcurrentRequests int        `json:"maxConcurrentRequests"`
+	ConcurrencyQueueMs    int        `json:"concurrencyQueueMs"`
+	MaxInFlightRequests   int        `json:"maxInFlightRequests"`
+	MaxResponseBytes      int        `json:"maxResponseBytes"`
+	MaxFindDocuments      int64      `json:"maxFindDocuments"`
+	CacheMaxEntries       int        `json:"cacheMaxEntries"` // In-memory flow cache (cacheGet/cacheSet)
+	CORS                  CORSConfig `json:"cors"`
+}
+
+// CORSConfig mirrors the fiber CORS settings.
+type CORSConfig struct {
+	AllowOrigins     string `json:"allowOrigins"`
+	AllowMethods     string `json:"allowMethods"`
+	AllowHeaders     string `json:"allowHeaders"`
+	AllowCredentials bool   `json:"allowCredentials"`
+	ExposeHeaders    string `json:"exposeHeaders"`
+	MaxAge           int    `json:"maxAge"`
+}
+
+// runtimeConfigState remembers the environment values and the file to overlay on reload.
+type runtimeConfigState struct {
+	mu       sync.Mutex // Serializes reloads
+	base     RuntimeConfig
+	path     string
+	applied  RuntimeConfig
+	loadedAt time.Time
+	cors     atomic.Value // fiber.Handler built from applied.CORS
+}
+
+// ConfigureRuntime applies base overlaid with the config file at path (optional) and
+// remembers both for ReloadRuntimeConfig. Call after ConfigureGuardrails.
+func (h *Handler) ConfigureRuntime(base RuntimeConfig, path string) error {
+	h.runtime.mu.Lock()
+	h.runtime.base = base
+	h.runtime.path = path
+	h.runtime.mu.Unlock()
+	_, err := h.ReloadRuntimeConfig()
+	return err
+}
+
+// ReloadRuntimeConfig re-reads the config file and applies it. An invalid file leaves
+// the current settings untouched; dynamic routes are never reloaded here.
+func (h *Handler) ReloadRuntimeConfig() (RuntimeConfig, error) {
+	h.runtime.mu.Lock()
+	defer h.runtime.mu.Unlock()
+
+	cfg := h.runtime.base
+	if h.runtime.path != "" {
+		raw, err := os.ReadFile(h.runtime.path)
+		if err != nil {
+			return RuntimeConfig{}, fmt.Errorf("read config file: %w", err)
+		}
+		if err := json.Unmarshal(raw, &cfg); err != nil { // Keys missing from the file keep their base value
+			return RuntimeConfig{}, fmt.Errorf("parse config file %s: %w", h.runtime.path, err)
+		}
+	}
+	if cfg.MaxConcurrentRequests < 0 || cfg.ConcurrencyQueueMs < 0 || cfg.CacheMaxEntries < 0 {
+		return RuntimeConfig{}, errors.New("limits must not be negative")
+	}
+	corsHandler, err := newCORSHandler(cfg.CORS)
+	if err != nil {
+		return RuntimeConfig{}, err
+	}
+	guard := h.guardrails.config()
+	guard.MaxInFlight = cfg.MaxInFlightRequests
+	guard.MaxResponseBytes = cfg.MaxResponseBytes
+	guard.MaxFindDocuments = cfg.MaxFindDocuments
+	if guard.MaxInFlight < 0 || guard.MaxResponseBytes < 0 || guard.MaxFindDocuments < 0 {
+		return RuntimeConfig{}, errors.New("guardrail limits must not be negative")
+	}
+	if err := core.SetLogLevel(cfg.LogLevel); err != nil {
+		return RuntimeConfig{}, err
+	}
+
+	// Validated: from here on nothing fails
+	_ = h.ConfigureGuardrails(guard)
+	h.ConfigureConcurrency(cfg.MaxConcurrentRequests, time.Duration(cfg.ConcurrencyQueueMs)*time.Millisecond)
+	core.SetMemoryCacheLimit(cfg.CacheMaxEntries)
+	h.runtime.cors.Store(corsHandler)
+	h.runtime.applied = cfg
+	h.runtime.loadedAt = time.Now().UTC()
+	log.Printf("INFO: Runtime configuration applied (file: '%s', log level: '%s', max concurrent: %d, max in flight: %d, cache entries: %d)",
+		h.runtime.path, cfg.LogLevel, cfg.MaxConcurrentRequests, cfg.MaxInFlightRequests, cfg.CacheMaxEntries)
+	return cfg, nil
+}
+
+// newCORSHandler builds the fiber CORS middleware, turning its setup panics into errors.
+func newCORSHandler(cfg CORSConfig) (handler fiber.Handler, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid CORS configuration: %v", r)
+		}
+	}()
+	return cors.New(cors.Config{
+		AllowOrigins:     cfg.AllowOrigins,
+		AllowMethods:     cfg.AllowMethods,
+		AllowHeaders:     cfg.AllowHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		ExposeHeaders:    cfg.ExposeHeaders,
+		MaxAge:           cfg.MaxAge,
+	}), nil
+}
+
+// CORS is the CORS middleware of the current runtime configuration.
+func (h *Handler) CORS() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if handler, ok := h.runtime.cors.Load().(fiber.Handler); ok {
+			return handler(c)
+		}
+		return c.Next()
+	}
+}
+
+// ReloadConfig handles POST /api-generator/config/reload, like sending SIGHUP.
+func (h *Handler) ReloadConfig(c *fiber.Ctx) error {
+	cfg, err := h.ReloadRuntimeConfig()
+	if err != nil {
+		log.Printf("ERROR: Config reload requested by %s failed: %v", actorName(c), err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"code":    http.StatusBadRequest,
+			"message": err.Error(),
+		})
+	}
+	log.Printf("INFO: Config reloaded by %s", actorName(c))
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   cfg,
+	})
+}
+
+// GetRuntimeConfig handles GET /api-generator/config: the settings currently applied.
+func (h *Handler) GetRuntimeConfig(c *fiber.Ctx) error {
+	h.runtime.mu.Lock()
+	cfg, path, loadedAt := h.runtime.applied, h.runtime.path, h.runtime.loadedAt
+	h.runtime.mu.Unlock()
+	return c.JSON(fiber.Map{
+		"status":   "success",
+		"code":     http.StatusOK,
+		"data":     cfg,
+		"file":     path,
+		"loadedAt": loadedAt,
+	})
+}
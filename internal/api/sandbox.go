@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// headerEnvironment selects the data a request works on: "sandbox" for the parallel
+// sandbox data of a definition, "production" (or no header) for the real data.
+const (
+	headerEnvironment  = "X-Environment"
+	environmentSandbox = "sandbox"
+	sandboxSuffix      = "_sandbox"
+)
+
+// errNoSandbox rejects sandbox requests to definitions without sandbox data.
+var errNoSandbox = errors.New("this API has no sandbox")
+
+// resolveSandbox returns a copy of the definition pointing at its sandbox data when the
+// request asks for it. Sandbox writes publish no events, so consumers of the real data
+// never see test traffic; flow actions calling other systems still run as configured.
+func (h *Handler) resolveSandbox(c *fiber.Ctx, api models.ApiDefinition) (models.ApiDefinition, error) {
+	switch env := strings.ToLower(strings.TrimSpace(c.Get(headerEnvironment))); env {
+	case "", "production":
+		return api, nil
+	case environmentSandbox:
+	default:
+		return api, fmt.Errorf("unknown environment '%s', use 'sandbox' or 'production'", env)
+	}
+	if api.Sandbox == nil {
+		return api, errNoSandbox
+	}
+	if api.Sandbox.Database != "" {
+		api.Database = api.Sandbox.Database
+	}
+	if api.Sandbox.Collection != "" {
+		api.Collection = api.Sandbox.Collection
+	} else {
+		api.Collection += sandboxSuffix
+	}
+	api.Events = nil
+	c.Set(headerEnvironment, environmentSandbox)
+
+	// Same collection settings as the real data, applied once per target
+	target := api.Database + "." + api.Collection
+	if _, prepared := h.tenantTargets.LoadOrStore(target, true); !prepared {
+		ctx, cancel := context.WithTimeout(c.Context(), 30*time.Second)
+		h.prepareTarget(ctx, api)
+		cancel()
+		log.Printf("INFO: Prepared sandbox target %s for API '%s'", target, api.Name)
+	}
+	return api, nil
+}
+
+// sendSandboxError answers a request for an environment the definition does not have.
+func sendSandboxError(c *fiber.Ctx, api models.ApiDefinition, err error) error {
+	log.Printf("WARN: Cannot route request to API '%s' by %s: %v", api.Name, headerEnvironment, err)
+	return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+}
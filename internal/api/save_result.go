@@ -0,0 +1,48 @@
+package api
+
+import (
+	"strings"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Headers reporting what a save did: "created", "updated" or "unchanged", and the
+// fields an update changed.
+const (
+	headerSaveResult     = "X-Save-Result"
+	headerModifiedFields = "X-Modified-Fields"
+)
+
+// echoSaveResult tells the client what saving the request did. The default response
+// (the saved document) gets its _id; a flow response gets its $saveResult references
+// resolved.
+func echoSaveResult(c *fiber.Ctx, api models.ApiDefinition, response interface{}, saved *database.SaveResult) interface{} {
+	c.Set(headerSaveResult, saved.Outcome())
+	if len(saved.Modified) > 0 {
+		c.Set(headerModifiedFields, strings.Join(saved.Modified, ","))
+	}
+	if api.ConditionalFlow != nil {
+		return core.SubstituteSaveResult(response, saveResultVars(saved))
+	}
+	if doc, ok := response.(map[string]interface{}); ok && doc["_id"] == nil && saved.ID != nil {
+		doc["_id"] = saved.ID
+	}
+	return response
+}
+
+func saveResultVars(saved *database.SaveResult) map[string]interface{} {
+	modified := make([]interface{}, len(saved.Modified))
+	for i, field := range saved.Modified {
+		modified[i] = field
+	}
+	return map[string]interface{}{
+		"created":  saved.Created,
+		"id":       saved.ID,
+		"modified": modified,
+		"outcome":  saved.Outcome(),
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// resolveDataScope resolves the definition's row-level scope for the caller.
+// Definitions without a dataScope return nil.
+func resolveDataScope(c *fiber.Ctx, api models.ApiDefinition) (map[string]interface{}, error) {
+	if len(api.DataScope) == 0 {
+		return nil, nil
+	}
+	claims, err := callerClaims(c)
+	if err != nil {
+		return nil, err
+	}
+	return core.ResolveDataScope(api.DataScope, claims)
+}
+
+// callerClaims verifies the request's bearer token, if any. Anonymous requests return nil claims.
+func callerClaims(c *fiber.Ctx) (map[string]interface{}, error) {
+	authHeader := c.Get(fiber.HeaderAuthorization)
+	if authHeader == "" {
+		return nil, nil
+	}
+	return core.VerifyBearer(authHeader)
+}
+
+// applyScope forces the scope fields into a query filter, overriding any value the client sent.
+func applyScope(filter bson.M, scope map[string]interface{}) bson.M {
+	for field, value := range scope {
+		filter[field] = value
+	}
+	return filter
+}
+
+// stampScope writes the scope fields onto a document about to be saved.
+func stampScope(doc map[string]interface{}, scope map[string]interface{}) {
+	for field, value := range scope {
+		doc[field] = value
+	}
+}
+
+// sendScopeError answers a request to a scoped definition without a usable identity.
+func sendScopeError(c *fiber.Ctx, api models.ApiDefinition, err error) error {
+	log.Printf("WARN: Data scope for API '%s' not resolved: %v", api.Name, err)
+	return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Authentication required"})
+}
@@ -0,0 +1,573 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode"
+
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sdkGenerators produce the client source files per language.
+var sdkGenerators = map[string]func(ops []sdkOperation) map[string]string{
+	"typescript": typeScriptSDK,
+	"go":         goSDK,
+	"python":     pythonSDK,
+}
+
+// sdkOperation is one definition as the client sees it, with the same parameter
+// placement as the OpenAPI export.
+type sdkOperation struct {
+	Name    string
+	Method  string
+	Path    string // OpenAPI form ("/users/{id}")
+	Summary string
+	Params  []sdkParam
+	HasBody bool
+}
+
+type sdkParam struct {
+	Name     string
+	Type     string // JSON Schema type
+	In       string // "path", "query" or "body"
+	Required bool
+}
+
+func sdkOperations(apis []models.ApiDefinition) []sdkOperation {
+	ops := make([]sdkOperation, 0, len(apis))
+	for _, api := range apis {
+		path, pathParams := openAPIPath(api.Endpoint)
+		op := sdkOperation{
+			Name:    api.Name,
+			Method:  api.Method,
+			Path:    path,
+			Summary: api.Summary,
+			HasBody: api.Method == http.MethodPost || api.Method == http.MethodPut || api.Method == http.MethodPatch,
+		}
+		inPath := make(map[string]bool, len(pathParams))
+		for _, name := range pathParams {
+			inPath[name] = true
+			op.Params = append(op.Params, sdkParam{Name: name, Type: openAPIType(paramType(api.Parameters, name)), In: "path", Required: true})
+		}
+		for _, p := range api.Parameters {
+			if inPath[p.Name] {
+				continue
+			}
+			in := "query"
+			if op.HasBody {
+				in = "body"
+			}
+			op.Params = append(op.Params, sdkParam{Name: p.Name, Type: openAPIType(p.Type), In: in, Required: p.Required})
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// GetSDK generates a typed client for all served definitions and returns it as a zip
+// (?lang=typescript|go|python). The zip also contains the OpenAPI export it matches.
+func (h *Handler) GetSDK(c *fiber.Ctx) error {
+	lang := c.Query("lang", "typescript")
+	generate, ok := sdkGenerators[lang]
+	if !ok {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "lang must be one of typescript, go, python"})
+	}
+	apis := h.sortedCachedAPIs()
+	files := generate(sdkOperations(apis))
+	spec, err := json.MarshalIndent(buildOpenAPI(apis, c.BaseURL()), "", "  ")
+	if err != nil {
+		log.Printf("ERROR: Failed to encode OpenAPI export for SDK: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate SDK"})
+	}
+	files["openapi.json"] = string(spec)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		w, err := zw.Create(name)
+		if err == nil {
+			_, err = w.Write([]byte(files[name]))
+		}
+		if err != nil {
+			log.Printf("ERROR: Failed to write SDK file '%s': %v", name, err)
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate SDK"})
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("ERROR: Failed to finish SDK zip: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate SDK"})
+	}
+
+	log.Printf("INFO: Generated %s SDK for %d API(s)", lang, len(apis))
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="api-client-%s.zip"`, lang))
+	return c.Send(buf.Bytes())
+}
+
+// --- Identifiers ---
+
+// identWords splits a definition or parameter name ("get-user_by id") into words.
+func identWords(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func pascalIdent(name string) string {
+	var b strings.Builder
+	for _, w := range identWords(name) {
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	s := b.String()
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		s = "Op" + s // Keeps Go identifiers exported
+	}
+	return s
+}
+
+func camelIdent(name string) string {
+	p := pascalIdent(name)
+	return strings.ToLower(p[:1]) + p[1:]
+}
+
+func snakeIdent(name string) string {
+	words := identWords(name)
+	for i, w := range words {
+		words[i] = strings.ToLower(w)
+	}
+	id := safeIdent(strings.Join(words, "_"))
+	if pythonKeywords[id] {
+		id += "_"
+	}
+	return id
+}
+
+var pythonKeywords = map[string]bool{
+	"and": true, "as": true, "assert": true, "async": true, "await": true, "break": true, "class": true,
+	"continue": true, "def": true, "del": true, "elif": true, "else": true, "except": true, "finally": true,
+	"for": true, "from": true, "global": true, "if": true, "import": true, "in": true, "is": true,
+	"lambda": true, "nonlocal": true, "not": true, "or": true, "pass": true, "raise": true, "return": true,
+	"try": true, "while": true, "with": true, "yield": true, "self": true,
+}
+
+// safeIdent makes sure a snake_case identifier is non-empty and does not start with a digit.
+func safeIdent(s string) string {
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		return "_" + s
+	}
+	return s
+}
+
+// uniqueIdents maps each operation to an identifier, numbering collisions.
+func uniqueIdents(ops []sdkOperation, ident func(string) string) []string {
+	seen := make(map[string]int, len(ops))
+	idents := make([]string, len(ops))
+	for i, op := range ops {
+		id := ident(op.Name)
+		if n := seen[id]; n > 0 {
+			idents[i] = fmt.Sprintf("%s%d", id, n+1)
+		} else {
+			idents[i] = id
+		}
+		seen[id]++
+	}
+	return idents
+}
+
+// --- TypeScript ---
+
+func typeScriptType(t string) string {
+	switch t {
+	case "number", "integer":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "object":
+		return "Record<string, unknown>"
+	case "array":
+		return "unknown[]"
+	}
+	return "string"
+}
+
+func typeScriptSDK(ops []sdkOperation) map[string]string {
+	var b strings.Builder
+	b.WriteString(`// Generated by api-generator. Do not edit.
+
+export interface ClientOptions {
+  headers?: Record<string, string>;
+  fetch?: typeof fetch;
+}
+
+export class ApiError extends Error {
+  constructor(public status: number, public body: unknown) {
+    super("Request failed with status " + status);
+  }
+}
+
+`)
+	idents := uniqueIdents(ops, camelIdent)
+	for i, op := range ops {
+		fmt.Fprintf(&b, "export interface %sParams {\n", pascalIdent(idents[i]))
+		for _, p := range op.Params {
+			optional := "?"
+			if p.Required {
+				optional = ""
+			}
+			fmt.Fprintf(&b, "  %q%s: %s;\n", p.Name, optional, typeScriptType(p.Type))
+		}
+		b.WriteString("}\n\n")
+	}
+
+	b.WriteString(`export class ApiClient {
+  constructor(private baseUrl: string, private options: ClientOptions = {}) {}
+
+  private async request(method: string, path: string, query: Record<string, unknown>, body?: Record<string, unknown>): Promise<unknown> {
+    const url = new URL(this.baseUrl.replace(/\/$/, "") + path);
+    for (const [k, v] of Object.entries(query)) {
+      if (v !== undefined) url.searchParams.set(k, String(v));
+    }
+    const headers: Record<string, string> = { ...this.options.headers };
+    if (body !== undefined) headers["Content-Type"] = "application/json";
+    const res = await (this.options.fetch ?? fetch)(url.toString(), {
+      method,
+      headers,
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    const text = await res.text();
+    const data = text ? JSON.parse(text) : undefined;
+    if (!res.ok) throw new ApiError(res.status, data);
+    return data;
+  }
+`)
+	for i, op := range ops {
+		b.WriteString("\n")
+		if op.Summary != "" {
+			fmt.Fprintf(&b, "  /** %s */\n", strings.ReplaceAll(op.Summary, "*/", "* /"))
+		}
+		paramsType := pascalIdent(idents[i]) + "Params"
+		defaultParams := ""
+		if !hasRequired(op.Params) {
+			defaultParams = " = {}"
+		}
+		fmt.Fprintf(&b, "  async %s(params: %s%s): Promise<unknown> {\n", idents[i], paramsType, defaultParams)
+		path := op.Path
+		for _, p := range op.Params {
+			if p.In == "path" {
+				path = strings.ReplaceAll(path, "{"+p.Name+"}", "${encodeURIComponent(String(params["+fmt.Sprintf("%q", p.Name)+"]))}")
+			}
+		}
+		b.WriteString("    const query: Record<string, unknown> = {};\n")
+		if op.HasBody {
+			b.WriteString("    const body: Record<string, unknown> = {};\n")
+		}
+		for _, p := range op.Params {
+			switch p.In {
+			case "query":
+				fmt.Fprintf(&b, "    query[%q] = params[%q];\n", p.Name, p.Name)
+			case "body":
+				fmt.Fprintf(&b, "    body[%q] = params[%q];\n", p.Name, p.Name)
+			}
+		}
+		body := ""
+		if op.HasBody {
+			body = ", body"
+		}
+		fmt.Fprintf(&b, "    return this.request(%q, `%s`, query%s);\n  }\n", op.Method, path, body)
+	}
+	b.WriteString("}\n")
+
+	return map[string]string{
+		"client.ts": b.String(),
+		"README.md": "# API client (TypeScript)\n\n```ts\nimport { ApiClient } from \"./client\";\n\nconst api = new ApiClient(\"https://api.example.com\", { headers: { Authorization: \"Bearer ...\" } });\n```\n",
+	}
+}
+
+// --- Go ---
+
+func goType(t string, required bool) string {
+	var s string
+	switch t {
+	case "number":
+		s = "float64"
+	case "integer":
+		s = "int64"
+	case "boolean":
+		s = "bool"
+	case "object":
+		return "map[string]interface{}"
+	case "array":
+		return "[]interface{}"
+	default:
+		s = "string"
+	}
+	if !required {
+		return "*" + s
+	}
+	return s
+}
+
+func goSDK(ops []sdkOperation) map[string]string {
+	var b strings.Builder
+	b.WriteString(`// Package client is generated by api-generator. Do not edit.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls the generated APIs.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Header     http.Header // Sent with every request (e.g. Authorization)
+}
+
+// New returns a client for the server at baseURL.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient, Header: http.Header{}}
+}
+
+// APIError is returned for responses with a status of 400 or above.
+type APIError struct {
+	Status int
+	Body   json.RawMessage
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("request failed with status %d: %s", e.Status, e.Body)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body map[string]interface{}) (json.RawMessage, error) {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, &APIError{Status: resp.StatusCode, Body: data}
+	}
+	return data, nil
+}
+`)
+	idents := uniqueIdents(ops, pascalIdent)
+	for i, op := range ops {
+		paramsType := idents[i] + "Params"
+		fmt.Fprintf(&b, "\n// %s holds the parameters of %s %s.\ntype %s struct {\n", paramsType, op.Method, op.Path, paramsType)
+		for _, p := range op.Params {
+			tag := p.Name
+			if !p.Required {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(&b, "\t%s %s `json:%q`\n", pascalIdent(p.Name), goType(p.Type, p.Required), tag)
+		}
+		b.WriteString("}\n\n")
+
+		if op.Summary != "" {
+			fmt.Fprintf(&b, "// %s calls %s %s: %s\n", idents[i], op.Method, op.Path, strings.ReplaceAll(op.Summary, "\n", " "))
+		} else {
+			fmt.Fprintf(&b, "// %s calls %s %s.\n", idents[i], op.Method, op.Path)
+		}
+		fmt.Fprintf(&b, "func (c *Client) %s(ctx context.Context, params %s) (json.RawMessage, error) {\n", idents[i], paramsType)
+		b.WriteString("\tquery := url.Values{}\n")
+		if op.HasBody {
+			b.WriteString("\tbody := map[string]interface{}{}\n")
+		}
+		path := fmt.Sprintf("%q", op.Path)
+		for _, p := range op.Params {
+			field := "params." + pascalIdent(p.Name)
+			switch p.In {
+			case "path":
+				path = fmt.Sprintf("strings.ReplaceAll(%s, %q, url.PathEscape(fmt.Sprint(%s)))", path, "{"+p.Name+"}", field)
+			case "query":
+				if p.Required || strings.HasPrefix(goType(p.Type, false), "[") || strings.HasPrefix(goType(p.Type, false), "map") {
+					fmt.Fprintf(&b, "\tquery.Set(%q, fmt.Sprint(%s))\n", p.Name, field)
+				} else {
+					fmt.Fprintf(&b, "\tif %s != nil {\n\t\tquery.Set(%q, fmt.Sprint(*%s))\n\t}\n", field, p.Name, field)
+				}
+			case "body":
+				if p.Required {
+					fmt.Fprintf(&b, "\tbody[%q] = %s\n", p.Name, field)
+				} else {
+					fmt.Fprintf(&b, "\tif %s != nil {\n\t\tbody[%q] = %s\n\t}\n", field, p.Name, field)
+				}
+			}
+		}
+		body := "nil"
+		if op.HasBody {
+			body = "body"
+		}
+		fmt.Fprintf(&b, "\treturn c.do(ctx, %q, %s, query, %s)\n}\n", op.Method, path, body)
+	}
+
+	source, err := format.Source([]byte(b.String()))
+	if err != nil {
+		log.Printf("WARN: Generated Go SDK could not be formatted: %v", err)
+		source = []byte(b.String())
+	}
+	return map[string]string{
+		"client/client.go": string(source),
+		"go.mod":           "module apiclient\n\ngo 1.21\n",
+		"README.md":        "# API client (Go)\n\n```go\nc := client.New(\"https://api.example.com\")\nc.Header.Set(\"Authorization\", \"Bearer ...\")\n```\n",
+	}
+}
+
+// --- Python ---
+
+func pythonType(t string) string {
+	switch t {
+	case "number":
+		return "float"
+	case "integer":
+		return "int"
+	case "boolean":
+		return "bool"
+	case "object":
+		return "Dict[str, Any]"
+	case "array":
+		return "List[Any]"
+	}
+	return "str"
+}
+
+func pythonSDK(ops []sdkOperation) map[string]string {
+	var b strings.Builder
+	b.WriteString(`"""Generated by api-generator. Do not edit."""
+import json
+import urllib.error
+import urllib.parse
+import urllib.request
+from typing import Any, Dict, List, Optional
+
+
+class ApiError(Exception):
+    def __init__(self, status: int, body: Any):
+        super().__init__(f"Request failed with status {status}")
+        self.status = status
+        self.body = body
+
+
+class ApiClient:
+    def __init__(self, base_url: str, headers: Optional[Dict[str, str]] = None, timeout: float = 30):
+        self.base_url = base_url.rstrip("/")
+        self.headers = dict(headers or {})
+        self.timeout = timeout
+
+    def _request(self, method: str, path: str, query: Dict[str, Any], body: Optional[Dict[str, Any]] = None) -> Any:
+        url = self.base_url + path
+        query = {k: v for k, v in query.items() if v is not None}
+        if query:
+            url += "?" + urllib.parse.urlencode(query)
+        headers = dict(self.headers)
+        data = None
+        if body is not None:
+            data = json.dumps({k: v for k, v in body.items() if v is not None}).encode()
+            headers["Content-Type"] = "application/json"
+        req = urllib.request.Request(url, data=data, headers=headers, method=method)
+        try:
+            with urllib.request.urlopen(req, timeout=self.timeout) as resp:
+                text = resp.read().decode()
+        except urllib.error.HTTPError as e:
+            text = e.read().decode()
+            raise ApiError(e.code, json.loads(text) if text else None) from None
+        return json.loads(text) if text else None
+`)
+	idents := uniqueIdents(ops, snakeIdent)
+	for i, op := range ops {
+		var args []string
+		for _, p := range op.Params {
+			if p.Required {
+				args = append(args, fmt.Sprintf("%s: %s", snakeIdent(p.Name), pythonType(p.Type)))
+			}
+		}
+		for _, p := range op.Params {
+			if !p.Required {
+				args = append(args, fmt.Sprintf("%s: Optional[%s] = None", snakeIdent(p.Name), pythonType(p.Type)))
+			}
+		}
+		signature := "self"
+		if len(args) > 0 {
+			signature += ", *, " + strings.Join(args, ", ")
+		}
+		fmt.Fprintf(&b, "\n    def %s(%s) -> Any:\n", idents[i], signature)
+		if op.Summary != "" {
+			fmt.Fprintf(&b, "        %q\n", strings.ReplaceAll(op.Summary, "\n", " "))
+		}
+		path := fmt.Sprintf("%q", op.Path)
+		var query, body []string
+		for _, p := range op.Params {
+			arg := snakeIdent(p.Name)
+			switch p.In {
+			case "path":
+				path += fmt.Sprintf(".replace(%q, urllib.parse.quote(str(%s), safe=\"\"))", "{"+p.Name+"}", arg)
+			case "query":
+				query = append(query, fmt.Sprintf("%q: %s", p.Name, arg))
+			case "body":
+				body = append(body, fmt.Sprintf("%q: %s", p.Name, arg))
+			}
+		}
+		bodyArg := ""
+		if op.HasBody {
+			bodyArg = ", {" + strings.Join(body, ", ") + "}"
+		}
+		fmt.Fprintf(&b, "        return self._request(%q, %s, {%s}%s)\n", op.Method, path, strings.Join(query, ", "), bodyArg)
+	}
+
+	return map[string]string{
+		"apiclient.py": b.String(),
+		"README.md":    "# API client (Python)\n\n```python\nfrom apiclient import ApiClient\n\napi = ApiClient(\"https://api.example.com\", headers={\"Authorization\": \"Bearer ...\"})\n```\n",
+	}
+}
+
+// hasRequired reports whether any parameter is required.
+func hasRequired(params []sdkParam) bool {
+	for _, p := range params {
+		if p.Required {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/database"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxSeedDocuments bounds the documents one seed request may insert.
+const maxSeedDocuments = 10000
+
+// seedRequest is the generated form of POST /api-generator/seed/:name: count documents
+// from template, whose "$fake.<kind>" references generate values and "$index" resolves
+// to 1..count. Without a template the documents follow the definition's responseSchema.
+type seedRequest struct {
+	Template map[string]interface{} `json:"template"`
+	Count    int                    `json:"count"`
+}
+
+// ConfigureSeeding enables POST /api-generator/seed/:name. Seeding writes straight into
+// the collections behind definitions, so it needs operator login and is refused in
+// production.
+func (h *Handler) ConfigureSeeding(enabled bool, environment string) error {
+	if enabled && environment == "production" {
+		return errors.New("seeding cannot be enabled in production")
+	}
+	if enabled && h.mgmtAuth.cfg.Provider == nil {
+		return errors.New("seeding requires operator login (OIDC_ISSUER), it would be public otherwise")
+	}
+	h.seeding = enabled
+	return nil
+}
+
+// SeedData populates the collection of a definition for demos and tests. The body is an
+// array of documents, or {"template": {...}, "count": 20}, or {"count": 20} to generate
+// documents from the definition's responseSchema.
+func (h *Handler) SeedData(c *fiber.Ctx) error {
+	if !h.seeding {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Seeding is not enabled on this server"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), time.Minute)
+	defer cancel()
+
+	name := nameParam(c)
+	api, err := h.store.GetAPIDefinitionByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API '" + name + "' not found"})
+		}
+		log.Printf("ERROR: Handler failed to find API '%s' for seeding: %v", name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve API data"})
+	}
+	target := core.ApplyEnvironment(*api)
+	api = &target
+	if core.IsTargetTemplate(api.Database) || core.IsTargetTemplate(api.Collection) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Definition targets a tenant template, there is no single collection to seed"})
+	}
+
+	docs, err := seedDocuments(c.Body(), api.ResponseSchema)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid seed request", "details": err.Error()})
+	}
+	h.prepareCollection(ctx, *api)
+	inserted, err := h.store.InsertDocuments(ctx, api.Database, api.Collection, docs)
+	if err != nil {
+		if errors.Is(err, database.ErrCircuitOpen) {
+			return sendUnavailable(c)
+		}
+		log.Printf("ERROR: Seeding API '%s' failed after %d documents: %v", name, inserted, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": fmt.Sprintf("Seeding failed after %d documents: %v", inserted, err),
+			"data":  fiber.Map{"inserted": inserted},
+		})
+	}
+	log.Printf("INFO: Operator '%s' seeded %d documents into %s.%s for API '%s'", actorName(c), inserted, api.Database, api.Collection, name)
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"status":  "success",
+		"code":    http.StatusCreated,
+		"message": "Data seeded",
+		"data":    fiber.Map{"inserted": inserted},
+	})
+}
+
+// seedDocuments parses the body of a seed request into the documents to insert.
+func seedDocuments(body []byte, schema map[string]interface{}) ([]interface{}, error) {
+	var list []map[string]interface{}
+	if err := json.Unmarshal(body, &list); err == nil {
+		if len(list) == 0 || len(list) > maxSeedDocuments {
+			return nil, fmt.Errorf("between 1 and %d documents are required", maxSeedDocuments)
+		}
+		docs := make([]interface{}, len(list))
+		for i, doc := range list {
+			docs[i] = doc
+		}
+		return docs, nil
+	}
+
+	var req seedRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, errors.New("body must be an array of documents or {\"template\": {...}, \"count\": n}")
+	}
+	if req.Count <= 0 || req.Count > maxSeedDocuments {
+		return nil, fmt.Errorf("count must be between 1 and %d", maxSeedDocuments)
+	}
+	if len(req.Template) == 0 {
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			schema = items // A list response describes one document in items
+		}
+		if len(schema) == 0 {
+			return nil, errors.New("template is required, the definition has no responseSchema to generate documents from")
+		}
+	}
+	docs := make([]interface{}, req.Count)
+	for i := range docs {
+		var doc interface{}
+		var err error
+		if len(req.Template) > 0 {
+			doc, err = core.FakeTemplate(req.Template, i+1)
+		} else {
+			doc, err = core.FakeFromSchema(schema)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := doc.(map[string]interface{}); !ok {
+			return nil, errors.New("generated documents must be objects")
+		}
+		docs[i] = doc
+	}
+	return docs, nil
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"api-genarator/internal/database"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type snapshotRequest struct {
+	Note string `json:"note"`
+}
+
+// CreateSnapshot handles POST /api-generator/snapshot: saves the whole definitions
+// collection so it can be restored later ({"note": "before bulk import"}).
+func (h *Handler) CreateSnapshot(c *fiber.Ctx) error {
+	var body snapshotRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 60*time.Second)
+	defer cancel()
+
+	snapshot, err := h.store.CreateSnapshot(ctx, body.Note, actorName(c))
+	if err != nil {
+		log.Printf("ERROR: Handler failed to create definition snapshot: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create snapshot"})
+	}
+	log.Printf("INFO: Snapshot %s of %d API definitions created by %s", snapshot.ID.Hex(), snapshot.Count, snapshot.Author)
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusCreated,
+		"data":   snapshot,
+	})
+}
+
+// ListSnapshots lists the snapshots, newest first (?limit=50)
+func (h *Handler) ListSnapshots(c *fiber.Ctx) error {
+	limit, err := strconv.ParseInt(c.Query("limit", "50"), 10, 64)
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	snapshots, err := h.store.ListSnapshots(ctx, limit)
+	if err != nil {
+		log.Printf("ERROR: Handler failed to list definition snapshots: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list snapshots"})
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"code":   http.StatusOK,
+		"data":   snapshots,
+	})
+}
+
+// RestoreSnapshot handles POST /api-generator/restore/:snapshotId: replaces every
+// definition with the snapshot's and swaps the route cache. The current catalog is
+// snapshotted first, so the restore itself can be undone.
+func (h *Handler) RestoreSnapshot(c *fiber.Ctx) error {
+	id := c.Params("snapshotId")
+	ctx, cancel := context.WithTimeout(c.Context(), 60*time.Second)
+	defer cancel()
+
+	if _, err := h.store.GetSnapshot(ctx, id); err != nil {
+		return sendSnapshotError(c, id, err)
+	}
+	return h.restoreSnapshot(ctx, c, id)
+}
+
+// restoreSnapshot snapshots the current catalog, restores snapshot id and reloads the routes.
+func (h *Handler) restoreSnapshot(ctx context.Context, c *fiber.Ctx, id string) error {
+	backup, err := h.store.CreateSnapshot(ctx, "automatic, before restoring "+id, actorName(c))
+	if err != nil {
+		log.Printf("ERROR: Could not snapshot the current definitions before restoring %s: %v", id, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to snapshot the current definitions, nothing was restored"})
+	}
+	snapshot, err := h.store.RestoreSnapshot(ctx, id)
+	if err != nil {
+		return sendSnapshotError(c, id, err)
+	}
+
+	h.reloadRoutes(context.Background())
+	for _, name := range append(backup.Names, snapshot.Names...) { // Peers refresh removed and restored definitions
+		h.notifyDefinitionChange(name)
+	}
+	log.Printf("INFO: Snapshot %s (%d API definitions) restored by %s, previous catalog saved as snapshot %s",
+		id, snapshot.Count, actorName(c), backup.ID.Hex())
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"code":    http.StatusOK,
+		"message": "Definitions restored, undo with POST /api-generator/restore/" + backup.ID.Hex(),
+		"data": fiber.Map{
+			"restored": snapshot,
+			"backup":   backup,
+		},
+	})
+}
+
+// DeleteSnapshot removes a snapshot that is no longer needed
+func (h *Handler) DeleteSnapshot(c *fiber.Ctx) error {
+	id := c.Params("snapshotId")
+	ctx, cancel := context.WithTimeout(c.Context(), 30*time.Second)
+	defer cancel()
+
+	if err := h.store.DeleteSnapshot(ctx, id); err != nil {
+		return sendSnapshotError(c, id, err)
+	}
+	log.Printf("INFO: Snapshot %s deleted by %s", id, actorName(c))
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"code":    http.StatusOK,
+		"message": "Snapshot deleted",
+	})
+}
+
+func sendSnapshotError(c *fiber.Ctx, id string, err error) error {
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Snapshot not found"})
+	case errors.Is(err, database.ErrCircuitOpen):
+		return sendUnavailable(c)
+	}
+	log.Printf("ERROR: Snapshot %s: %v", id, err)
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Snapshot operation failed: " + err.Error()})
+}
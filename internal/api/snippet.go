@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// snippetRequest is a concrete call of a definition with placeholder values filled in.
+type snippetRequest struct {
+	Method string
+	URL    string
+	Body   map[string]interface{} // nil for methods without a body
+}
+
+// GetSnippet renders a ready-to-run call of a definition (?lang=curl|js|go|har).
+// Parameter values come from the definition's first example, or placeholders.
+func (h *Handler) GetSnippet(c *fiber.Ctx) error {
+	name := c.Params("name")
+	lang := c.Query("lang", "curl")
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	api, err := h.store.GetAPIDefinitionByName(ctx, name)
+	if errors.Is(err, database.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API not found"})
+	}
+	if err != nil {
+		log.Printf("ERROR: Handler failed to find API '%s' for snippet: %v", name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve API definition"})
+	}
+
+	req := buildSnippetRequest(*api, c.BaseURL())
+	var snippet string
+	switch lang {
+	case "curl":
+		snippet = curlSnippet(req)
+	case "js":
+		snippet = jsSnippet(req)
+	case "go":
+		snippet = goSnippet(req)
+	case "har":
+		return c.JSON(harRequest(req))
+	default:
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "lang must be one of curl, js, go, har"})
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+	return c.SendString(snippet)
+}
+
+func buildSnippetRequest(api models.ApiDefinition, baseURL string) snippetRequest {
+	var values map[string]interface{}
+	if len(api.Examples) > 0 {
+		values = api.Examples[0].Request
+	}
+	valueOf := func(p sdkParam) interface{} {
+		if v, ok := values[p.Name]; ok {
+			return v
+		}
+		return placeholder(p)
+	}
+
+	op := sdkOperations([]models.ApiDefinition{api})[0]
+	path := op.Path
+	query := url.Values{}
+	req := snippetRequest{Method: op.Method}
+	if op.HasBody {
+		req.Body = make(map[string]interface{})
+	}
+	for _, p := range op.Params {
+		switch p.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", url.PathEscape(fmt.Sprint(valueOf(p))))
+		case "query":
+			query.Set(p.Name, fmt.Sprint(valueOf(p)))
+		case "body":
+			req.Body[p.Name] = valueOf(p)
+		}
+	}
+	req.URL = strings.TrimSuffix(baseURL, "/") + path
+	if len(query) > 0 {
+		req.URL += "?" + query.Encode()
+	}
+	return req
+}
+
+// placeholder is an obviously fake value of the parameter's type.
+func placeholder(p sdkParam) interface{} {
+	switch p.Type {
+	case "number", "integer":
+		return 0
+	case "boolean":
+		return true
+	case "object":
+		return map[string]interface{}{}
+	case "array":
+		return []interface{}{}
+	}
+	return "<" + p.Name + ">"
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func snippetBody(req snippetRequest, indent string) string {
+	body, _ := json.MarshalIndent(req.Body, indent, "  ")
+	return string(body)
+}
+
+func curlSnippet(req snippetRequest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "curl -X %s %s", req.Method, shellQuote(req.URL))
+	if req.Body != nil {
+		b.WriteString(" \\\n  -H 'Content-Type: application/json'")
+		fmt.Fprintf(&b, " \\\n  -d %s", shellQuote(snippetBody(req, "")))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func jsSnippet(req snippetRequest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "const res = await fetch(%q, {\n  method: %q,\n", req.URL, req.Method)
+	if req.Body != nil {
+		b.WriteString("  headers: { \"Content-Type\": \"application/json\" },\n")
+		fmt.Fprintf(&b, "  body: JSON.stringify(%s),\n", snippetBody(req, "  "))
+	}
+	b.WriteString("});\nconsole.log(res.status, await res.json());\n")
+	return b.String()
+}
+
+func goSnippet(req snippetRequest) string {
+	var b strings.Builder
+	b.WriteString("package main\n\nimport (\n\t\"fmt\"\n\t\"io\"\n\t\"net/http\"\n")
+	if req.Body != nil {
+		b.WriteString("\t\"strings\"\n")
+	}
+	b.WriteString(")\n\nfunc main() {\n")
+	body := "nil"
+	if req.Body != nil {
+		fmt.Fprintf(&b, "\tbody := strings.NewReader(%s)\n", "`"+strings.ReplaceAll(snippetBody(req, "\t"), "`", "`+\"`\"+`")+"`")
+		body = "body"
+	}
+	fmt.Fprintf(&b, "\treq, err := http.NewRequest(%q, %q, %s)\n", req.Method, req.URL, body)
+	b.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+	if req.Body != nil {
+		b.WriteString("\treq.Header.Set(\"Content-Type\", \"application/json\")\n")
+	}
+	b.WriteString("\tresp, err := http.DefaultClient.Do(req)\n\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+	b.WriteString("\tdefer resp.Body.Close()\n\tdata, _ := io.ReadAll(resp.Body)\n\tfmt.Println(resp.Status, string(data))\n}\n")
+	return b.String()
+}
+
+// harRequest renders the call as a HAR 1.2 request object.
+func harRequest(req snippetRequest) fiber.Map {
+	queryString := []fiber.Map{}
+	if u, err := url.Parse(req.URL); err == nil {
+		for k, vs := range u.Query() {
+			for _, v := range vs {
+				queryString = append(queryString, fiber.Map{"name": k, "value": v})
+			}
+		}
+	}
+	har := fiber.Map{
+		"method":      req.Method,
+		"url":         req.URL,
+		"httpVersion": "HTTP/1.1",
+		"headers":     []fiber.Map{},
+		"queryString": queryString,
+		"cookies":     []fiber.Map{},
+		"headersSize": -1,
+		"bodySize":    -1,
+	}
+	if req.Body != nil {
+		har["headers"] = []fiber.Map{{"name": "Content-Type", "value": "application/json"}}
+		har["postData"] = fiber.Map{"mimeType": "application/json", "text": snippetBody(req, "")}
+	}
+	return har
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Defaults for tails (GETs of definitions with Tail that keep streaming inserts).
+const (
+	defaultTailsPerClient = 4
+	defaultTailDuration   = time.Hour
+	tailHeartbeat         = 30 * time.Second // Blank line written to an idle tail to detect a gone client
+)
+
+// TailConfig bounds the tails open on this instance. Zero values use the defaults
+// (MaxTotal 0 leaves the total unlimited).
+type TailConfig struct {
+	MaxPerClient int           // Open tails per client IP
+	MaxTotal     int           // Open tails on this instance
+	MaxDuration  time.Duration // Lifetime of one tail; clients reconnect when it ends
+}
+
+// tailState counts the open tails and ends them on shutdown.
+type tailState struct {
+	mu     sync.Mutex
+	cfg    TailConfig
+	open   map[string]int // Client IP -> open tails
+	total  int
+	ctx    context.Context // Cancelled by Shutdown
+	cancel context.CancelFunc
+	closed bool
+}
+
+// ConfigureTails sets the limits of open tails.
+func (h *Handler) ConfigureTails(cfg TailConfig) error {
+	if cfg.MaxPerClient < 0 || cfg.MaxTotal < 0 || cfg.MaxDuration < 0 {
+		return errors.New("tail limits must not be negative")
+	}
+	h.tails.mu.Lock()
+	defer h.tails.mu.Unlock()
+	h.tails.cfg = cfg
+	return nil
+}
+
+// acquire reserves a tail for client; release must be called once it ends.
+func (t *tailState) acquire(client string) (ctx context.Context, release func(), err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, nil, errors.New("server is shutting down")
+	}
+	perClient := t.cfg.MaxPerClient
+	if perClient == 0 {
+		perClient = defaultTailsPerClient
+	}
+	if t.open[client] >= perClient {
+		return nil, nil, fmt.Errorf("at most %d tails may be open per client", perClient)
+	}
+	if t.cfg.MaxTotal > 0 && t.total >= t.cfg.MaxTotal {
+		return nil, nil, fmt.Errorf("at most %d tails may be open", t.cfg.MaxTotal)
+	}
+	if t.ctx == nil {
+		t.ctx, t.cancel = context.WithCancel(context.Background())
+		t.open = make(map[string]int)
+	}
+	duration := t.cfg.MaxDuration
+	if duration == 0 {
+		duration = defaultTailDuration
+	}
+	t.open[client]++
+	t.total++
+
+	ctx, cancel := context.WithTimeout(t.ctx, duration)
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			cancel()
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.open[client]--; t.open[client] <= 0 {
+				delete(t.open, client)
+			}
+			t.total--
+		})
+	}
+	return ctx, release, nil
+}
+
+// CloseTails ends the open tails and refuses new ones. Call it before shutting the
+// server down, which otherwise waits for the tails' connections.
+func (h *Handler) CloseTails() {
+	h.tails.shutdown()
+}
+
+func (t *tailState) shutdown() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	if t.cancel != nil {
+		t.cancel()
+	}
+}
+
+// tailShape prepares documents for the client (computed fields, fieldset, permissions).
+type tailShape func(ctx context.Context, docs []bson.M) []bson.M
+
+// serveTail answers a default GET of a Tail definition: the current matches, then every
+// matching insert, as NDJSON over a chunked response. A document is only read from the
+// change stream once the previous one was written to the client, so a slow client
+// slows its own stream instead of buffering in memory.
+func (h *Handler) serveTail(ctx context.Context, c *fiber.Ctx, api models.ApiDefinition, filter bson.M, findOpts database.FindOptions, shape tailShape) error {
+	client := h.clientIP(c)
+	tailCtx, release, err := h.tails.acquire(client)
+	if err != nil {
+		log.Printf("WARN: Tail of API '%s' rejected for %s: %v", api.Name, client, err)
+		c.Set(fiber.HeaderRetryAfter, "5")
+		return c.Status(http.StatusTooManyRequests).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// The stream is opened before the current matches are read, so no insert is lost in between
+	stream, err := h.store.WatchInserts(tailCtx, api.Database, api.Collection, filter, findOpts.Projection)
+	if err != nil {
+		release()
+		switch {
+		case errors.Is(err, database.ErrTailFilter):
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, database.ErrConfigError):
+			return c.Status(http.StatusNotImplemented).JSON(fiber.Map{"error": "Tailing requires MongoDB change streams (replica set or sharded cluster)"})
+		case errors.Is(err, database.ErrCircuitOpen):
+			return sendUnavailable(c)
+		}
+		log.Printf("ERROR: Failed to open tail of API '%s': %v", api.Name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to open change stream"})
+	}
+	current, err := h.store.FindDataWith(ctx, api.Database, api.Collection, filter, findOpts)
+	if err != nil {
+		stream.Close()
+		release()
+		if h.tooManyDocuments(err) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if errors.Is(err, database.ErrCircuitOpen) {
+			return sendUnavailable(c)
+		}
+		log.Printf("ERROR: Failed to read current matches of tail of API '%s': %v", api.Name, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve data"})
+	}
+	// Documents inserted after the stream opened but before the read would be sent twice
+	seen := make(map[interface{}]bool, len(current))
+	for _, doc := range current {
+		if key, ok := tailKey(doc["_id"]); ok {
+			seen[key] = true
+		}
+	}
+
+	log.Printf("INFO: Tail of API '%s' opened for %s with %d current matches", api.Name, client, len(current))
+	c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set("X-Accel-Buffering", "no") // Keep reverse proxies from buffering the stream
+	c.Status(http.StatusOK).Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer release()
+		defer stream.Close()
+		start := time.Now()
+		sent, err := writeTail(tailCtx, w, stream, shape(tailCtx, current), seen, shape)
+		log.Printf("INFO: Tail of API '%s' for %s closed after %s, %d documents sent: %v", api.Name, client, time.Since(start).Round(time.Second), sent, err)
+	})
+	return nil
+}
+
+// writeTail writes the current matches, then the inserts until ctx ends, the stream
+// fails or the client goes away.
+func writeTail(ctx context.Context, w *bufio.Writer, stream *database.InsertStream, current []bson.M, seen map[interface{}]bool, shape tailShape) (int, error) {
+	enc := json.NewEncoder(w) // One document per line
+	sent := 0
+	for _, doc := range current {
+		if err := enc.Encode(doc); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	if err := w.Flush(); err != nil {
+		return sent, err
+	}
+
+	lastWrite := time.Now()
+	for {
+		doc, err := stream.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return sent, nil // Duration limit or shutdown
+			}
+			return sent, err
+		}
+		if doc == nil {
+			if time.Since(lastWrite) >= tailHeartbeat {
+				if err := w.WriteByte('\n'); err != nil {
+					return sent, err
+				}
+				if err := w.Flush(); err != nil {
+					return sent, err
+				}
+				lastWrite = time.Now()
+			}
+			continue
+		}
+		if key, ok := tailKey(doc["_id"]); ok && seen[key] {
+			delete(seen, key)
+			continue
+		}
+		for _, out := range shape(ctx, []bson.M{doc}) {
+			if err := enc.Encode(out); err != nil {
+				return sent, err
+			}
+			sent++
+		}
+		if err := w.Flush(); err != nil {
+			return sent, err
+		}
+		lastWrite = time.Now()
+	}
+}
+
+// tailKey returns a comparable form of a document ID.
+func tailKey(id interface{}) (interface{}, bool) {
+	switch id.(type) {
+	case nil, bson.M, bson.D, bson.A, []interface{}, map[string]interface{}:
+		return nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// isTenantRouted reports whether a definition resolves its database or collection per request.
+func isTenantRouted(api models.ApiDefinition) bool {
+	return core.IsTargetTemplate(api.Database) || core.IsTargetTemplate(api.Collection)
+}
+
+// tenantScope collects the trusted request values templates may use: request headers
+// and the claims of a valid bearer token. tokenErr is set when a token was sent but rejected.
+func tenantScope(c *fiber.Ctx) (scope core.TenantScope, tokenErr error) {
+	scope.Headers = make(map[string]string)
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		scope.Headers[strings.ToLower(string(key))] = string(value)
+	})
+	if authHeader := c.Get(fiber.HeaderAuthorization); authHeader != "" {
+		scope.Claims, tokenErr = core.VerifyBearer(authHeader)
+	}
+	return scope, tokenErr
+}
+
+// resolveTenant returns a copy of the definition with its database/collection templates
+// rendered for this request. Definitions without templates are returned unchanged.
+func (h *Handler) resolveTenant(c *fiber.Ctx, api models.ApiDefinition) (models.ApiDefinition, error) {
+	if !isTenantRouted(api) {
+		return api, nil
+	}
+	scope, tokenErr := tenantScope(c)
+	dbName, err := core.ResolveTargetName(api.Database, scope)
+	if err == nil {
+		api.Collection, err = core.ResolveTargetName(api.Collection, scope)
+	}
+	if err != nil {
+		if tokenErr != nil {
+			return api, tokenErr
+		}
+		return api, err
+	}
+	api.Database = dbName
+
+	// Collection-level settings (time-series, text index) are applied once per resolved target
+	target := api.Database + "." + api.Collection
+	if _, prepared := h.tenantTargets.LoadOrStore(target, true); !prepared {
+		ctx, cancel := context.WithTimeout(c.Context(), 30*time.Second)
+		h.prepareTarget(ctx, api)
+		cancel()
+		log.Printf("INFO: Prepared tenant target %s for API '%s'", target, api.Name)
+	}
+	return api, nil
+}
+
+// sendTenantError answers a request whose tenant could not be resolved.
+func sendTenantError(c *fiber.Ctx, api models.ApiDefinition, err error) error {
+	log.Printf("WARN: Cannot resolve tenant target for API '%s': %v", api.Name, err)
+	if errors.Is(err, core.ErrTenantUnresolved) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired token"})
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"runtime/debug"
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BuildInfo identifies the engine build serving traffic.
+type BuildInfo struct {
+	Version   string `json:"version"`             // Release version (set at build time, "dev" otherwise)
+	Commit    string `json:"commit,omitempty"`    // VCS revision the binary was built from
+	BuiltAt   string `json:"builtAt,omitempty"`   // Commit time of that revision
+	Modified  bool   `json:"modified,omitempty"`  // Built from a working tree with uncommitted changes
+	GoVersion string `json:"goVersion,omitempty"` // Go toolchain
+}
+
+// NewBuildInfo completes version with what the Go toolchain recorded in the binary.
+func NewBuildInfo(version string) BuildInfo {
+	info := BuildInfo{Version: version}
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.GoVersion = build.GoVersion
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Commit = setting.Value
+		case "vcs.time":
+			info.BuiltAt = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
+
+// SetBuildInfo sets the build reported by GET /version.
+func (h *Handler) SetBuildInfo(info BuildInfo) {
+	h.build = info
+}
+
+// GetVersion reports the engine build and the definition set it serves: a hash over the
+// checksums of the served definitions (equal on instances serving the same catalog),
+// when the catalog was loaded and when it last changed.
+func (h *Handler) GetVersion(c *fiber.Ctx) error {
+	h.routesMutex.RLock()
+	checksums := make([]string, 0, len(h.dynamicRoutes))
+	for _, handle := range h.dynamicRoutes {
+		checksums = append(checksums, handle.api.Name+"\x00"+definitionChecksum(handle.api)+"\n")
+	}
+	updatedAt := h.routesAt
+	h.routesMutex.RUnlock()
+	sort.Strings(checksums)
+	hash := sha256.New()
+	for _, line := range checksums {
+		hash.Write([]byte(line))
+	}
+
+	h.loadReport.mu.RLock()
+	loadedAt := h.loadReport.report.LoadedAt
+	h.loadReport.mu.RUnlock()
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"build":    h.build,
+		"instance": h.cluster.cfg.InstanceID,
+		"catalog": fiber.Map{
+			"hash":        "sha256:" + hex.EncodeToString(hash.Sum(nil)),
+			"definitions": len(checksums),
+			"loadedAt":    loadedAt,
+			"updatedAt":   updatedAt,
+		},
+	})
+}
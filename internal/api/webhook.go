@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"api-genarator/internal/core"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sendWebhookError rejects a webhook call that failed signature verification. A missing
+// secret is our misconfiguration, so the sender gets a 500 and may retry later.
+func sendWebhookError(c *fiber.Ctx, api models.ApiDefinition, err error) error {
+	switch {
+	case errors.Is(err, core.ErrWebhookSignature):
+		log.Printf("WARN: Webhook call to API '%s' rejected: %v", api.Name, err)
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid webhook signature"})
+	case errors.Is(err, core.ErrWebhookReplay):
+		log.Printf("WARN: Replayed webhook call to API '%s' rejected", api.Name)
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Webhook call was already received"})
+	}
+	log.Printf("ERROR: Cannot verify webhook call to API '%s': %v", api.Name, err)
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Webhook verification is not configured"})
+}
+
+// releaseFailedWebhook forgets the nonces of a call that failed on our side, so the
+// sender's retry of the same delivery is not rejected as a replay. Deferred.
+func releaseFailedWebhook(c *fiber.Ctx, api models.ApiDefinition, nonceKeys []string) {
+	if c.Response().StatusCode() < http.StatusInternalServerError {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := core.ForgetWebhookNonce(ctx, nonceKeys...); err != nil {
+		log.Printf("WARN: Could not release webhook nonce of failed call to API '%s': %v", api.Name, err)
+	}
+}
@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Errors returned when a token cannot be verified
+var (
+	ErrInvalidToken     = errors.New("invalid token")
+	ErrTokenExpired     = errors.New("token expired")
+	ErrUnsupportedAlg   = errors.New("unsupported signing algorithm")
+	ErrInvalidSignature = errors.New("invalid token signature")
+)
+
+var hmacAlgorithms = map[string]func() hash.Hash{
+	"HS256": sha256.New,
+	"HS384": sha512.New384,
+	"HS512": sha512.New,
+}
+
+// SignHMAC creates a compact JWT signed with an HMAC algorithm (HS256 by default).
+func SignHMAC(claims map[string]interface{}, secret []byte, alg string) (string, error) {
+	if alg == "" {
+		alg = "HS256"
+	}
+	newHash, ok := hmacAlgorithms[alg]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedAlg, alg)
+	}
+	if len(secret) == 0 {
+		return "", errors.New("signing key is empty")
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %w", err)
+	}
+
+	signingInput := encodeSegment(header) + "." + encodeSegment(payload)
+	mac := hmac.New(newHash, secret)
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + encodeSegment(mac.Sum(nil)), nil
+}
+
+// SignKey creates a compact JWT signed with a private key: RS256 for RSA keys, ES256 for
+// P-256 keys. kid is added to the header when set.
+func SignKey(claims map[string]interface{}, key crypto.Signer, kid string) (string, error) {
+	var alg string
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		alg = "RS256"
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return "", fmt.Errorf("%w: ECDSA curve %s", ErrUnsupportedAlg, k.Curve.Params().Name)
+		}
+		alg = "ES256"
+	default:
+		return "", fmt.Errorf("%w: key type %T", ErrUnsupportedAlg, key)
+	}
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %w", err)
+	}
+
+	signingInput := encodeSegment(headerJSON) + "." + encodeSegment(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		if r, s, err = ecdsa.Sign(rand.Reader, k, digest[:]); err == nil {
+			// JWS uses the fixed-size r || s form, not ASN.1
+			signature = make([]byte, 64)
+			r.FillBytes(signature[:32])
+			s.FillBytes(signature[32:])
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + encodeSegment(signature), nil
+}
+
+// VerifyHMAC verifies an HMAC-signed JWT and its time-based claims, returning the claims.
+func VerifyHMAC(token string, secret []byte, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegmentJSON(parts[0], &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+	newHash, ok := hmacAlgorithms[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlg, header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	mac := hmac.New(newHash, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegmentJSON(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err := ValidateTimeClaims(claims, now); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ValidateTimeClaims checks exp and nbf (with a small clock skew allowance).
+func ValidateTimeClaims(claims map[string]interface{}, now time.Time) error {
+	const leeway = 30 * time.Second
+	if exp, ok := claims["exp"].(float64); ok && now.Add(-leeway).Unix() > int64(exp) {
+		return ErrTokenExpired
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(leeway).Unix() < int64(nbf) {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSegmentJSON(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+)
+
+// ErrFlowTimeout is wrapped by FlowErrors raised when an action timeout or the flow budget is exceeded.
+var ErrFlowTimeout = errors.New("flow time limit exceeded")
+
+type budgetKey struct{}
+
+// flowBudget tracks the time a single flow execution may spend across all of its actions.
+type flowBudget struct {
+	limit time.Duration
+	start time.Time
+}
+
+// WithFlowBudget bounds a whole flow execution. Actions see the remaining budget as their
+// context deadline, and the step that runs past it is reported by name.
+func WithFlowBudget(ctx context.Context, limit time.Duration) (context.Context, context.CancelFunc) {
+	ctx = context.WithValue(ctx, budgetKey{}, &flowBudget{limit: limit, start: time.Now()})
+	return context.WithTimeout(ctx, limit)
+}
+
+func budgetFrom(ctx context.Context) *flowBudget {
+	b, _ := ctx.Value(budgetKey{}).(*flowBudget)
+	return b
+}
+
+func (b *flowBudget) spent() time.Duration {
+	return time.Since(b.start)
+}
+
+func (b *flowBudget) exhausted() bool {
+	return b.spent() >= b.limit
+}
+
+// processAction runs an action within its own timeout (TimeoutMs) and the flow budget.
+// A step that exceeds either limit fails with an ErrFlowTimeout FlowError naming the action.
+func processAction(action *models.ActionDefinition,
+	dataBeforeAction map[string]interface{},
+	ctx context.Context,
+	store *database.Store,
+	dbName, collName string) (interface{}, map[string]interface{}, bool, error) {
+
+	if action == nil {
+		return executeAction(action, dataBeforeAction, ctx, store, dbName, collName)
+	}
+
+	budget := budgetFrom(ctx)
+	if budget != nil && budget.exhausted() {
+		err := &FlowError{Action: action.Type, Err: ErrFlowTimeout,
+			Message: fmt.Sprintf("flow budget of %s exhausted before '%s' action (spent %s)", budget.limit, action.Type, budget.spent().Round(time.Millisecond))}
+		return nil, dataBeforeAction, false, err
+	}
+
+	actionCtx := ctx
+	if action.TimeoutMs > 0 {
+		var cancel context.CancelFunc
+		actionCtx, cancel = context.WithTimeout(ctx, time.Duration(action.TimeoutMs)*time.Millisecond)
+		defer cancel()
+	}
+
+	start := time.Now()
+	response, finalState, save, err := executeAction(action, dataBeforeAction, actionCtx, store, dbName, collName)
+	elapsed := time.Since(start)
+	log.Printf("DEBUG: Action '%s' took %s", action.Type, elapsed)
+	if action.Type != "conditionalBlock" {
+		observeStep(ctx, action, elapsed) // Includes any block the action continues into; bare blocks only nest steps
+	}
+
+	// A nested step already reported the limit it ran into
+	if errors.Is(err, ErrFlowTimeout) {
+		return response, finalState, save, err
+	}
+	if action.TimeoutMs > 0 && errors.Is(actionCtx.Err(), context.DeadlineExceeded) && (budget == nil || !budget.exhausted()) {
+		return nil, dataBeforeAction, false, &FlowError{Action: action.Type, Err: ErrFlowTimeout,
+			Message: fmt.Sprintf("'%s' action exceeded its timeout of %dms (took %s)", action.Type, action.TimeoutMs, elapsed.Round(time.Millisecond))}
+	}
+	if budget != nil && budget.exhausted() {
+		return nil, dataBeforeAction, false, &FlowError{Action: action.Type, Err: ErrFlowTimeout,
+			Message: fmt.Sprintf("flow budget of %s exceeded during '%s' action (took %s)", budget.limit, action.Type, elapsed.Round(time.Millisecond))}
+	}
+	return response, finalState, save, err
+}
@@ -0,0 +1,202 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultCacheTTL applies when a cacheSet action does not specify a TTL.
+const defaultCacheTTL = 5 * time.Minute
+
+// Cache stores values shared across requests for the cacheGet/cacheSet actions.
+type Cache interface {
+	Get(ctx context.Context, key string) (interface{}, bool, error)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	Add(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) // Set only when the key is absent; reports whether it was stored
+	Delete(ctx context.Context, key string) error
+}
+
+var (
+	flowCache   Cache = NewMemoryCache()
+	flowCacheMu sync.RWMutex
+)
+
+// SetCache replaces the cache backend used by flows (in-memory by default).
+func SetCache(c Cache) {
+	flowCacheMu.Lock()
+	flowCache = c
+	flowCacheMu.Unlock()
+}
+
+func currentCache() Cache {
+	flowCacheMu.RLock()
+	defer flowCacheMu.RUnlock()
+	return flowCache
+}
+
+// memoryCache is a process-local cache with per-entry expiry. Values are stored JSON-encoded,
+// like in Redis, so flows never share mutable maps through the cache.
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	raw       []byte
+	expiresAt time.Time
+}
+
+// NewMemoryCache creates an in-process cache. Entries are not shared between instances.
+func NewMemoryCache() Cache {
+	return &memoryCache{entries: make(map[string]memoryEntry)}
+}
+
+func (m *memoryCache) Get(_ context.Context, key string) (interface{}, bool, error) {
+	m.mu.Lock()
+	entry, ok := m.entries[key]
+	if ok && time.Now().After(entry.expiresAt) {
+		delete(m.entries, key)
+		ok = false
+	}
+	m.mu.Unlock()
+	if !ok {
+		return nil, false, nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(entry.raw, &value); err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (m *memoryCache) Set(_ context.Context, key string, value interface{}, ttl time.Duration) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now, key)
+	m.entries[key] = memoryEntry{raw: raw, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+// memoryCacheLimit caps the entries of in-memory caches (0 = unlimited), see SetMemoryCacheLimit.
+var memoryCacheLimit atomic.Int64
+
+// SetMemoryCacheLimit sets the maximum number of entries an in-memory cache keeps.
+// Over the limit, the entries closest to expiry are evicted first. n <= 0 removes the limit.
+func SetMemoryCacheLimit(n int) {
+	memoryCacheLimit.Store(int64(max(n, 0)))
+}
+
+// sweep drops expired entries once the map grows, so unused keys don't accumulate
+// forever, and makes room for key under the entry limit. Callers hold m.mu.
+func (m *memoryCache) sweep(now time.Time, key string) {
+	limit := int(memoryCacheLimit.Load())
+	if len(m.entries) < 10000 && (limit <= 0 || len(m.entries) < limit) {
+		return
+	}
+	for k, e := range m.entries {
+		if now.After(e.expiresAt) {
+			delete(m.entries, k)
+		}
+	}
+	if _, replacing := m.entries[key]; limit <= 0 || replacing || len(m.entries) < limit {
+		return
+	}
+	keys := make([]string, 0, len(m.entries))
+	for k := range m.entries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return m.entries[keys[i]].expiresAt.Before(m.entries[keys[j]].expiresAt) })
+	for _, k := range keys[:len(keys)-limit+1] {
+		delete(m.entries, k)
+	}
+}
+
+func (m *memoryCache) Add(_ context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if entry, ok := m.entries[key]; ok && !now.After(entry.expiresAt) {
+		return false, nil
+	}
+	m.sweep(now, key)
+	m.entries[key] = memoryEntry{raw: raw, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (m *memoryCache) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	delete(m.entries, key)
+	m.mu.Unlock()
+	return nil
+}
+
+// redisCache stores JSON-encoded values in Redis so entries are shared between instances.
+type redisCache struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisCache connects to Redis using a redis:// URL.
+func NewRedisCache(ctx context.Context, url string) (Cache, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to ping redis: %w", err)
+	}
+	return &redisCache{client: client, prefix: "apigen:cache:"}, nil
+}
+
+func (r *redisCache) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	raw, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r *redisCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.prefix+key, raw, ttl).Err()
+}
+
+func (r *redisCache) Add(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+	return r.client.SetNX(ctx, r.prefix+key, raw, ttl).Result()
+}
+
+func (r *redisCache) Delete(ctx context.Context, key string) error {
+	return r.client.Del(ctx, r.prefix+key).Err()
+}
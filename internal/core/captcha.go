@@ -0,0 +1,93 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"api-genarator/internal/models"
+)
+
+// captchaProviders holds the verification endpoint and default token field per provider.
+var captchaProviders = map[string]struct {
+	verifyURL  string
+	tokenField string
+}{
+	"recaptcha": {"https://www.google.com/recaptcha/api/siteverify", "g-recaptcha-response"},
+	"hcaptcha":  {"https://api.hcaptcha.com/siteverify", "h-captcha-response"},
+	"turnstile": {"https://challenges.cloudflare.com/turnstile/v0/siteverify", "cf-turnstile-response"},
+}
+
+// captchaResult is the common subset of the siteverify responses.
+type captchaResult struct {
+	Success    bool     `json:"success"`
+	Score      *float64 `json:"score,omitempty"` // reCAPTCHA v3 only
+	Action     string   `json:"action,omitempty"`
+	Hostname   string   `json:"hostname,omitempty"`
+	ErrorCodes []string `json:"error-codes,omitempty"`
+}
+
+// verifyCaptcha checks the token in the data state with the configured provider.
+// It returns whether the check passed and the provider's result for logging/branching.
+func verifyCaptcha(ctx context.Context, cfg *models.CaptchaConfig, data map[string]interface{}) (bool, *captchaResult, error) {
+	provider, ok := captchaProviders[cfg.Provider]
+	if !ok {
+		return false, nil, fmt.Errorf("unsupported captcha provider: %s", cfg.Provider)
+	}
+	secret := currentSettings().CaptchaSecrets[cfg.Provider]
+	if secret == "" {
+		return false, nil, fmt.Errorf("captcha secret for '%s' is not configured", cfg.Provider)
+	}
+
+	tokenField := cfg.TokenField
+	if tokenField == "" {
+		tokenField = provider.tokenField
+	}
+	tokenValue, _ := lookupField(data, tokenField)
+	token, _ := tokenValue.(string)
+	if token == "" {
+		log.Printf("WARN: Captcha token field '%s' is missing or empty", tokenField)
+		return false, &captchaResult{ErrorCodes: []string{"missing-input-response"}}, nil
+	}
+
+	form := url.Values{}
+	form.Set("secret", secret)
+	form.Set("response", token)
+	if cfg.RemoteIPField != "" {
+		if ip, ok := lookupField(data, cfg.RemoteIPField); ok && ip != nil {
+			form.Set("remoteip", fmt.Sprintf("%v", ip))
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := outboundClient.Do(req)
+	if err != nil {
+		return false, nil, fmt.Errorf("captcha verification request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result captchaResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, nil, fmt.Errorf("failed to decode captcha response: %w", err)
+	}
+
+	passed := result.Success
+	if passed && cfg.MinScore > 0 && result.Score != nil && *result.Score < cfg.MinScore {
+		log.Printf("WARN: Captcha score %.2f below minimum %.2f", *result.Score, cfg.MinScore)
+		passed = false
+	}
+	if passed && cfg.ExpectedAction != "" && result.Action != cfg.ExpectedAction {
+		log.Printf("WARN: Captcha action '%s' does not match expected '%s'", result.Action, cfg.ExpectedAction)
+		passed = false
+	}
+	return passed, &result, nil
+}
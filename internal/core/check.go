@@ -0,0 +1,610 @@
+package core
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"api-genarator/internal/models"
+)
+
+// DefinitionProblem describes one reason a stored definition cannot be served.
+// Path points at the offending element, e.g. "conditionalFlow.then.apiCall".
+type DefinitionProblem struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// knownActions, knownOperators and knownTransforms list what the engine can execute.
+// Keep them in sync with processAction, evaluateCondition and ApplyTransformations.
+var (
+	knownActions = map[string]bool{
+		"return": true, "continue": true, "conditionalBlock": true, "apiCall": true,
+		"presignUrl": true, "signJwt": true, "verifyCaptcha": true, "cacheGet": true, "cacheSet": true,
+		"graphqlCall": true, "soapCall": true, "fileDrop": true, "ldapLookup": true, "payment": true, "push": true, "sendSms": true, "generateOtp": true, "verifyOtp": true, "callFlow": true, "dbUpdate": true, "findAndModify": true,
+	}
+	knownOperators = map[string]bool{
+		"eq": true, "neq": true, "gt": true, "lt": true, "gte": true, "lte": true,
+		"contains": true, "in": true, "exists": true, "bcryptVerify": true, "featureFlag": true,
+	}
+	knownTransforms = map[string]bool{
+		"set": true, "remove": true, "append": true, "calculate": true, "bcryptHash": true, "geoip": true, "userAgent": true,
+		"round": true, "floor": true, "ceil": true, "toFixed": true, "formatCurrency": true, "extract": true,
+		"flatten": true, "unflatten": true, "rename": true, "age": true,
+	}
+	knownProtoTypes = map[string]bool{
+		"double": true, "float": true, "int32": true, "int64": true, "uint32": true, "uint64": true,
+		"sint32": true, "sint64": true, "bool": true, "string": true, "bytes": true, "message": true,
+	}
+	knownMethods = map[string]bool{
+		"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "HEAD": true, "OPTIONS": true,
+	}
+)
+
+// CheckDefinition validates a definition the same way the engine would interpret it,
+// so broken definitions can be rejected up front instead of failing per request.
+func CheckDefinition(api models.ApiDefinition) []DefinitionProblem {
+	var problems []DefinitionProblem
+	add := func(path, format string, args ...interface{}) {
+		problems = append(problems, DefinitionProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if api.Name == "" {
+		add("name", "name is required")
+	}
+	if !knownMethods[api.Method] {
+		add("method", "unsupported HTTP method '%s'", api.Method)
+	}
+	if !strings.HasPrefix(api.Endpoint, "/") {
+		add("endpoint", "endpoint must start with '/'")
+	}
+	if api.Database == "" || api.Collection == "" {
+		add("collection", "database and collection are required")
+	}
+
+	for i, rule := range api.Validations {
+		for j, cond := range rule.When {
+			checkCondition(cond, fmt.Sprintf("validations[%d].when[%d]", i, j), add)
+		}
+	}
+	if api.AccessControl != nil {
+		checkCidrs(api.AccessControl.AllowCidrs, "accessControl.allowCidrs", add)
+		checkCidrs(api.AccessControl.DenyCidrs, "accessControl.denyCidrs", add)
+	}
+	if api.QueryMode == "distinct" && api.DistinctField == "" {
+		add("distinctField", "distinctField is required when queryMode is 'distinct'")
+	}
+	if api.TimeSeries != nil && api.TimeSeries.TimeField == "" {
+		add("timeSeries.timeField", "timeField is required for time-series collections")
+	}
+	checkTransforms(api.Computed, "computed", add)
+	if strings.HasPrefix(api.CursorField, "$") || strings.Contains(api.CursorField, "..") {
+		add("cursorField", "'%s' is not a field path", api.CursorField)
+	}
+	if api.CollectionSchema != nil {
+		checkCollectionSchema(api.CollectionSchema, add)
+	}
+
+	for i, event := range api.Events {
+		if event.WebhookURL == "" {
+			add(fmt.Sprintf("events[%d].webhookUrl", i), "webhookUrl is required")
+		}
+	}
+	if len(api.Events) > 0 && api.Ingest != nil {
+		add("events", "events are not published for ingest (write-behind) definitions")
+	}
+	if api.Concurrency != nil {
+		if api.Concurrency.MaxConcurrent <= 0 {
+			add("concurrency.maxConcurrent", "maxConcurrent must be greater than 0")
+		}
+		if api.Concurrency.QueueTimeoutMs < 0 || api.Concurrency.RetryAfter < 0 {
+			add("concurrency", "queueTimeoutMs and retryAfter must not be negative")
+		}
+	}
+	if api.Async && api.ConditionalFlow == nil {
+		add("async", "async requires a conditionalFlow")
+	}
+	if d := api.Delete; d != nil {
+		switch d.Match {
+		case "", models.DeleteMatchOne, models.DeleteMatchMany:
+		case models.DeleteMatchKey:
+			if api.UniqueKey == "" {
+				add("delete.match", "match 'key' requires a uniqueKey")
+			}
+		default:
+			add("delete.match", "unknown delete match '%s' (use one, key or many)", d.Match)
+		}
+		if d.Soft && d.ArchiveCollection != "" {
+			add("delete", "use either soft or archiveCollection, not both")
+		}
+		if strings.HasPrefix(d.SoftField, "$") {
+			add("delete.softField", "'%s' is not a field name", d.SoftField)
+		}
+		if d.ArchiveCollection != "" && d.ArchiveCollection == api.Collection {
+			add("delete.archiveCollection", "the archive must be another collection")
+		}
+	}
+	if api.Tail && (api.ConditionalFlow != nil || api.Download != nil || api.TimeSeries != nil) {
+		add("tail", "tail streams the default GET of a regular collection; it cannot be combined with conditionalFlow, download or timeSeries")
+	}
+	for env, override := range api.Environments {
+		path := "environments." + env
+		if env == "" {
+			add("environments", "environment names must not be empty")
+		}
+		if override.FlowBudgetMs < 0 {
+			add(path+".flowBudgetMs", "flowBudgetMs must not be negative")
+		}
+		if override.Concurrency != nil && override.Concurrency.MaxConcurrent <= 0 {
+			add(path+".concurrency.maxConcurrent", "maxConcurrent must be positive")
+		}
+		for from, to := range override.BaseURLs {
+			for _, u := range []string{from, to} {
+				if !strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "http://") {
+					add(path+".baseUrls", "'%s' is not an http(s) URL", u)
+				}
+			}
+		}
+	}
+	if d := api.Deprecated; d != nil {
+		since, err := ParseDeprecationDate(d.Since)
+		if err != nil {
+			add("deprecated.since", "%v", err)
+		}
+		if d.SunsetDate != "" {
+			sunset, err := ParseDeprecationDate(d.SunsetDate)
+			if err != nil {
+				add("deprecated.sunsetDate", "%v", err)
+			} else if !since.IsZero() && sunset.Before(since) {
+				add("deprecated.sunsetDate", "the sunset date must not be before since")
+			}
+		}
+		if s := d.Successor; s != "" && !strings.HasPrefix(s, "/") && !strings.HasPrefix(s, "https://") && !strings.HasPrefix(s, "http://") {
+			add("deprecated.successor", "successor must be a path or an http(s) URL")
+		}
+	}
+	if sb := api.Sandbox; sb != nil {
+		if IsTargetTemplate(sb.Database) || IsTargetTemplate(sb.Collection) {
+			add("sandbox", "sandbox database and collection must be plain names")
+		}
+		if (sb.Database == "" || sb.Database == api.Database) && sb.Collection == api.Collection {
+			add("sandbox.collection", "the sandbox must be another collection")
+		}
+	}
+	if api.Mock && len(api.ResponseSchema) == 0 {
+		add("mock", "mock responses are generated from responseSchema, which is missing")
+	}
+	checkFakerAnnotations(api.ResponseSchema, "responseSchema", add)
+	for i, perm := range api.FieldPermissions {
+		if len(perm.Roles) == 0 {
+			add(fmt.Sprintf("fieldPermissions[%d].roles", i), "at least one role is required (use \"*\" for every caller)")
+		}
+	}
+	for field := range api.DataScope {
+		if field == "" || strings.HasPrefix(field, "$") {
+			add("dataScope", "invalid dataScope field '%s'", field)
+		}
+	}
+	switch api.Format {
+	case models.FormatJSON:
+		if api.JSONAPI != nil {
+			add("jsonApi", "jsonApi requires format \"jsonapi\"")
+		}
+	case models.FormatJSONAPI:
+		if api.JSONAPI != nil {
+			for field, relType := range api.JSONAPI.Relationships {
+				if field == "" || relType == "" {
+					add("jsonApi.relationships", "relationship '%s' needs a field and a resource type", field)
+				}
+			}
+		}
+	default:
+		add("format", "unsupported format '%s' (expected \"jsonapi\" or empty)", api.Format)
+	}
+	if api.Protobuf != nil {
+		if !protoIdent.MatchString(api.Protobuf.Message) {
+			add("protobuf.message", "invalid message name '%s'", api.Protobuf.Message)
+		}
+		checkProtoFields(api.Protobuf.Fields, "protobuf.fields", add)
+	}
+	if api.Webhook != nil {
+		checkWebhook(api.Webhook, add)
+	}
+	exampleNames := make(map[string]bool, len(api.Examples))
+	for i, ex := range api.Examples {
+		switch {
+		case ex.Name == "":
+			add(fmt.Sprintf("examples[%d].name", i), "example name is required")
+		case exampleNames[ex.Name]:
+			add(fmt.Sprintf("examples[%d].name", i), "duplicate example name '%s'", ex.Name)
+		}
+		exampleNames[ex.Name] = true
+		if ex.Status != 0 && (ex.Status < 100 || ex.Status > 599) {
+			add(fmt.Sprintf("examples[%d].status", i), "invalid status %d", ex.Status)
+		}
+	}
+
+	checkBlock(api.ConditionalFlow, "conditionalFlow", add)
+	return problems
+}
+
+// unsupportedSchemaKeywords are JSON Schema keywords MongoDB's $jsonSchema rejects.
+var unsupportedSchemaKeywords = map[string]bool{"$ref": true, "$schema": true, "default": true, "definitions": true, "format": true, "id": true}
+
+func checkCollectionSchema(cs *models.CollectionSchema, add func(path, format string, args ...interface{})) {
+	if len(cs.Schema) == 0 {
+		add("collectionSchema.schema", "schema is required")
+	}
+	switch cs.ValidationLevel {
+	case "", "strict", "moderate", "off":
+	default:
+		add("collectionSchema.validationLevel", "unsupported validationLevel '%s' (expected strict, moderate or off)", cs.ValidationLevel)
+	}
+	switch cs.ValidationAction {
+	case "", "error", "warn":
+	default:
+		add("collectionSchema.validationAction", "unsupported validationAction '%s' (expected error or warn)", cs.ValidationAction)
+	}
+	checkSchemaKeywords(cs.Schema, "collectionSchema.schema", add)
+}
+
+// checkSchemaKeywords walks schema objects; "properties" and "patternProperties" hold
+// field names, which are not keywords themselves.
+func checkSchemaKeywords(node interface{}, path string, add func(path, format string, args ...interface{})) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for key, value := range n {
+			if unsupportedSchemaKeywords[key] {
+				add(path+"."+key, "'%s' is not supported by MongoDB $jsonSchema", key)
+				continue
+			}
+			if props, ok := value.(map[string]interface{}); ok && (key == "properties" || key == "patternProperties") {
+				for name, sub := range props {
+					checkSchemaKeywords(sub, path+"."+key+"."+name, add)
+				}
+				continue
+			}
+			checkSchemaKeywords(value, path+"."+key, add)
+		}
+	case []interface{}:
+		for i, v := range n {
+			checkSchemaKeywords(v, fmt.Sprintf("%s[%d]", path, i), add)
+		}
+	}
+}
+
+// checkFakerAnnotations reports x-faker annotations naming unknown generators.
+func checkFakerAnnotations(node interface{}, path string, add func(path, format string, args ...interface{})) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for key, value := range n {
+			if ref, ok := value.(string); ok && key == fakerKey {
+				if _, err := Fake(ref); err != nil {
+					add(path+"."+key, "%v", err)
+				}
+				continue
+			}
+			checkFakerAnnotations(value, path+"."+key, add)
+		}
+	case []interface{}:
+		for i, value := range n {
+			checkFakerAnnotations(value, fmt.Sprintf("%s[%d]", path, i), add)
+		}
+	}
+}
+
+func checkBlock(block *models.ConditionalBlock, path string, add func(path, format string, args ...interface{})) {
+	if block == nil {
+		return
+	}
+	for i, cond := range block.Conditions {
+		checkCondition(cond, fmt.Sprintf("%s.conditions[%d]", path, i), add)
+	}
+	checkAction(block.Then, path+".then", add)
+	checkAction(block.Else, path+".else", add)
+}
+
+func checkCondition(cond models.Condition, path string, add func(path, format string, args ...interface{})) {
+	if cond.Field == "" {
+		add(path+".field", "condition field is required")
+	}
+	if !knownOperators[cond.Operator] {
+		add(path+".operator", "unknown operator '%s'", cond.Operator)
+	}
+}
+
+// checkTranslateCalls reports $t(...) references whose arguments do not parse.
+func checkTranslateCalls(template interface{}, path string, add func(path, format string, args ...interface{})) {
+	switch t := template.(type) {
+	case string:
+		if strings.HasPrefix(t, translatePrefix) {
+			if _, _, err := parseTranslateCall(t); err != nil {
+				add(path, "%v", err)
+			}
+		}
+	case map[string]interface{}:
+		for k, v := range t {
+			checkTranslateCalls(v, path+"."+k, add)
+		}
+	case []interface{}:
+		for i, v := range t {
+			checkTranslateCalls(v, fmt.Sprintf("%s[%d]", path, i), add)
+		}
+	}
+}
+
+func checkTransforms(transform []models.Transformation, path string, add func(path, format string, args ...interface{})) {
+	for i, t := range transform {
+		if !knownTransforms[t.Operation] {
+			add(fmt.Sprintf("%s[%d].operation", path, i), "unknown transform operation '%s'", t.Operation)
+		}
+		if t.Operation == "extract" {
+			if _, err := compileJSONPath(t.Path); err != nil {
+				add(fmt.Sprintf("%s[%d].path", path, i), "%v", err)
+			}
+		}
+	}
+}
+
+// CheckMappingProfile validates a mapping profile before it is stored.
+func CheckMappingProfile(profile models.MappingProfile) []DefinitionProblem {
+	var problems []DefinitionProblem
+	add := func(path, format string, args ...interface{}) {
+		problems = append(problems, DefinitionProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	if profile.Name == "" {
+		add("name", "name is required")
+	}
+	if len(profile.Transform) == 0 {
+		add("transform", "a mapping profile needs at least one transformation")
+	}
+	checkTransforms(profile.Transform, "transform", add)
+	return problems
+}
+
+// CheckFlowFragment validates a flow fragment before it is stored.
+func CheckFlowFragment(fragment models.FlowFragment) []DefinitionProblem {
+	var problems []DefinitionProblem
+	add := func(path, format string, args ...interface{}) {
+		problems = append(problems, DefinitionProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	if fragment.Name == "" {
+		add("name", "name is required")
+	}
+	if fragment.Flow == nil {
+		add("flow", "flow is required")
+	}
+	checkBlock(fragment.Flow, "flow", add)
+	return problems
+}
+
+func checkAction(action *models.ActionDefinition, path string, add func(path, format string, args ...interface{})) {
+	if action == nil {
+		return
+	}
+	if !knownActions[action.Type] {
+		add(path+".type", "unknown action type '%s'", action.Type)
+	}
+	for i, name := range action.UseMappings {
+		if name == "" {
+			add(fmt.Sprintf("%s.useMappings[%d]", path, i), "mapping profile name is empty")
+		}
+	}
+	checkTransforms(action.Transform, path+".transform", add)
+
+	switch action.Type {
+	case "return":
+		checkTranslateCalls(action.ReturnData, path+".returnData", add)
+		if action.ReturnError != "" && !errorCodePattern.MatchString(action.ReturnError) {
+			add(path+".returnError", "'%s' is not a valid error code", action.ReturnError)
+		}
+	case "conditionalBlock":
+		if action.ConditionalFlow == nil {
+			add(path+".conditionalFlow", "conditionalBlock action requires conditionalFlow")
+		}
+	case "apiCall":
+		if action.ApiCall == nil || action.ApiCall.ApiName == "" {
+			add(path+".apiCall", "apiCall action requires apiCall.apiName")
+		}
+	case "presignUrl":
+		if action.Presign == nil || action.Presign.Key == "" {
+			add(path+".presign", "presignUrl action requires presign.key")
+		}
+	case "signJwt":
+		if action.Jwt == nil {
+			add(path+".jwt", "signJwt action requires jwt")
+		}
+	case "cacheGet", "cacheSet":
+		if action.Cache == nil || action.Cache.Key == "" {
+			add(path+".cache.key", "%s action requires cache.key", action.Type)
+		}
+	case "graphqlCall":
+		if action.GraphQL == nil || action.GraphQL.Endpoint == "" || action.GraphQL.Query == "" {
+			add(path+".graphql", "graphqlCall action requires graphql.endpoint and graphql.query")
+		}
+	case "soapCall":
+		if action.SOAP == nil || action.SOAP.Endpoint == "" || action.SOAP.Envelope == "" {
+			add(path+".soap", "soapCall action requires soap.endpoint and soap.envelope")
+		} else if action.SOAP.Version != "" && action.SOAP.Version != "1.1" && action.SOAP.Version != "1.2" {
+			add(path+".soap.version", "unsupported SOAP version '%s' (expected 1.1 or 1.2)", action.SOAP.Version)
+		}
+	case "fileDrop":
+		if action.FileDrop == nil || action.FileDrop.Target == "" || action.FileDrop.Path == "" {
+			add(path+".fileDrop", "fileDrop action requires fileDrop.target and fileDrop.path")
+		} else if f := action.FileDrop.Format; f != "" && f != "json" && f != "csv" && f != "xml" {
+			add(path+".fileDrop.format", "unsupported file format '%s' (expected json, csv or xml)", f)
+		}
+	case "ldapLookup":
+		if action.LDAP == nil || action.LDAP.Filter == "" || len(action.LDAP.Attributes) == 0 {
+			add(path+".ldap", "ldapLookup action requires ldap.filter and ldap.attributes")
+		} else {
+			if _, ok := ldapScopes[defaultString(action.LDAP.Scope, "sub")]; !ok {
+				add(path+".ldap.scope", "unsupported LDAP scope '%s' (expected sub, one or base)", action.LDAP.Scope)
+			}
+			if _, err := compileLDAPFilter(templatePattern.ReplaceAllString(action.LDAP.Filter, "x")); err != nil {
+				add(path+".ldap.filter", "%v", err)
+			}
+		}
+	case "payment":
+		if action.Payment == nil {
+			add(path+".payment", "payment action requires payment")
+		} else {
+			p := action.Payment
+			switch {
+			case !paymentOperations[p.Operation]:
+				add(path+".payment.operation", "unsupported payment operation '%s' (expected createIntent, charge, refund or verifyWebhook)", p.Operation)
+			case (p.Operation == "createIntent" || p.Operation == "charge") && (p.Amount == nil || p.Currency == ""):
+				add(path+".payment", "%s requires payment.amount and payment.currency", p.Operation)
+			case p.Operation == "charge" && p.PaymentMethod == "":
+				add(path+".payment.paymentMethod", "charge requires payment.paymentMethod")
+			case p.Operation == "refund" && p.PaymentIntent == "":
+				add(path+".payment.paymentIntent", "refund requires payment.paymentIntent")
+			}
+			checkAction(p.OnFailure, path+".payment.onFailure", add)
+		}
+	case "push":
+		switch p := action.Push; {
+		case p == nil:
+			add(path+".push", "push action requires push")
+		case p.Provider != "fcm" && p.Provider != "apns":
+			add(path+".push.provider", "unsupported push provider '%s' (expected fcm or apns)", p.Provider)
+		case p.Tokens == "" && p.Lookup == nil:
+			add(path+".push", "push action requires push.tokens or push.lookup")
+		case p.Lookup != nil && (p.Lookup.Collection == "" || p.Lookup.Field == ""):
+			add(path+".push.lookup", "push.lookup requires collection and field")
+		case p.Title == "" && p.Body == "" && len(p.Data) == 0:
+			add(path+".push", "push action requires a title, body or data")
+		}
+	case "sendSms":
+		if action.SMS == nil || action.SMS.To == "" || action.SMS.Body == "" {
+			add(path+".sms", "sendSms action requires sms.to and sms.body")
+		} else {
+			if rl := action.SMS.RateLimit; rl != nil && (rl.Max < 1 || rl.WindowSeconds < 0) {
+				add(path+".sms.rateLimit", "rateLimit.max must be at least 1 and windowSeconds must not be negative")
+			}
+			checkAction(action.SMS.OnRateLimit, path+".sms.onRateLimit", add)
+		}
+	case "generateOtp", "verifyOtp":
+		if action.OTP == nil || action.OTP.Purpose == "" || action.OTP.Subject == "" {
+			add(path+".otp", "%s action requires otp.purpose and otp.subject", action.Type)
+		} else {
+			o := action.OTP
+			if _, ok := otpAlphabets[defaultString(o.Alphabet, "numeric")]; !ok {
+				add(path+".otp.alphabet", "unsupported OTP alphabet '%s' (expected numeric or alphanumeric)", o.Alphabet)
+			}
+			if o.Length != 0 && (o.Length < 4 || o.Length > 12) {
+				add(path+".otp.length", "OTP length must be between 4 and 12")
+			}
+			if o.TTLSeconds < 0 || o.MaxAttempts < 0 {
+				add(path+".otp", "ttlSeconds and maxAttempts must not be negative")
+			}
+			if action.Type == "generateOtp" && o.OnFailure != nil {
+				add(path+".otp.onFailure", "onFailure only applies to verifyOtp")
+			}
+			checkAction(o.OnFailure, path+".otp.onFailure", add)
+		}
+	case "callFlow":
+		if action.CallFlow == nil || action.CallFlow.Fragment == "" {
+			add(path+".callFlow", "callFlow action requires callFlow.fragment")
+		}
+	case "dbUpdate":
+		if action.DbUpdate == nil {
+			add(path+".dbUpdate", "dbUpdate action requires dbUpdate")
+		} else {
+			d := action.DbUpdate
+			if _, err := updateOperators(d.Update, nil, nil); err != nil {
+				add(path+".dbUpdate.update", "%v", err)
+			}
+			if len(d.Expect) > 0 {
+				if _, err := expectFilter(d.Expect, nil, ""); err != nil {
+					add(path+".dbUpdate.expect", "%v", err)
+				}
+				if d.Upsert {
+					add(path+".dbUpdate.upsert", "upsert cannot be combined with expect")
+				}
+			} else if d.OnConflict != nil {
+				add(path+".dbUpdate.onConflict", "onConflict requires expect")
+			}
+			checkAction(d.OnConflict, path+".dbUpdate.onConflict", add)
+		}
+	case "findAndModify":
+		m := action.FindAndModify
+		if m == nil {
+			add(path+".findAndModify", "findAndModify action requires findAndModify")
+			break
+		}
+		if _, err := modifyFilter(m.Filter, nil); err != nil {
+			add(path+".findAndModify.filter", "%v", err)
+		}
+		if m.Delete {
+			if len(m.Update) > 0 || m.Upsert || m.ReturnBefore {
+				add(path+".findAndModify", "delete cannot be combined with update, upsert or returnBefore")
+			}
+		} else if _, err := updateOperators(m.Update, nil, nil); err != nil {
+			add(path+".findAndModify.update", "%v", err)
+		}
+		for _, field := range m.Sort {
+			if name := strings.TrimLeft(field, "+-"); name == "" || strings.HasPrefix(name, "$") {
+				add(path+".findAndModify.sort", "invalid sort field '%s'", field)
+			}
+		}
+	case "verifyCaptcha":
+		if action.Captcha == nil {
+			add(path+".captcha", "verifyCaptcha action requires captcha")
+		} else {
+			if _, ok := captchaProviders[action.Captcha.Provider]; !ok {
+				add(path+".captcha.provider", "unknown captcha provider '%s'", action.Captcha.Provider)
+			}
+			checkAction(action.Captcha.OnFailure, path+".captcha.onFailure", add)
+		}
+	}
+	checkBlock(action.ConditionalFlow, path+".conditionalFlow", add)
+}
+
+func checkCidrs(cidrs []string, path string, add func(path, format string, args ...interface{})) {
+	for i, entry := range cidrs {
+		entry = strings.TrimSpace(entry)
+		var err error
+		if strings.Contains(entry, "/") {
+			_, err = netip.ParsePrefix(entry)
+		} else {
+			_, err = netip.ParseAddr(entry)
+		}
+		if err != nil {
+			add(fmt.Sprintf("%s[%d]", path, i), "invalid IP or CIDR '%s'", entry)
+		}
+	}
+}
+
+var protoIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// checkProtoFields validates a protobuf message's fields (names, unique legal numbers, types).
+func checkProtoFields(fields []models.ProtoField, path string, add func(path, format string, args ...interface{})) {
+	if len(fields) == 0 {
+		add(path, "a message needs at least one field")
+	}
+	numbers := make(map[int]bool, len(fields))
+	for i, f := range fields {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		if !protoIdent.MatchString(f.Name) {
+			add(p+".name", "invalid field name '%s'", f.Name)
+		}
+		switch {
+		case f.Number < 1 || f.Number > 536870911 || (f.Number >= 19000 && f.Number <= 19999):
+			add(p+".number", "invalid field number %d", f.Number)
+		case numbers[f.Number]:
+			add(p+".number", "duplicate field number %d", f.Number)
+		}
+		numbers[f.Number] = true
+		if !knownProtoTypes[f.Type] {
+			add(p+".type", "unsupported type '%s'", f.Type)
+		}
+		if f.Type == "message" {
+			if f.Message != "" && !protoIdent.MatchString(f.Message) {
+				add(p+".message", "invalid message name '%s'", f.Message)
+			}
+			checkProtoFields(f.Fields, p+".fields", add)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"api-genarator/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ComputeFields adds computed (virtual) fields to documents read for a GET. The
+// transformations run on each document in turn; nothing is written back.
+func ComputeFields(ctx context.Context, computed []models.Transformation, docs []bson.M) []bson.M {
+	if len(computed) == 0 {
+		return docs
+	}
+	result := make([]bson.M, len(docs))
+	for i, doc := range docs {
+		result[i] = bson.M(ApplyTransformations(ctx, computed, doc))
+	}
+	return result
+}
+
+// ageInYears returns the whole years between a date and now, e.g. an age from a birthdate.
+func ageInYears(v interface{}, now time.Time) (int, bool) {
+	date, ok := toTime(v)
+	if !ok || date.After(now) {
+		return 0, false
+	}
+	date, now = date.UTC(), now.UTC()
+	years := now.Year() - date.Year()
+	if now.Month() < date.Month() || (now.Month() == date.Month() && now.Day() < date.Day()) {
+		years-- // Birthday not reached yet this year
+	}
+	return years, true
+}
@@ -2,13 +2,14 @@ package core
 
 import (
 	"context"
-	// "net/http"
-	// "errors"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"reflect"
 	"strconv" // ใช้สำหรับแปลง string เป็น float
 	"strings"
+	"time"
 
 	// --- เปลี่ยน your_module_name เป็นชื่อ Module Go ของคุณ ---
 	"api-genarator/internal/database"
@@ -17,6 +18,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
 	// --- ---------------------------------------------------
 	// "go.mongodb.org/mongo-driver/mongo" // อาจจะไม่จำเป็น ถ้า Action ไม่เรียก DB โดยตรง
 )
@@ -52,12 +54,14 @@ func ProcessConditionalFlow(flow *models.ConditionalBlock,
 	conditionsMet := evaluateConditions(flow.Conditions, currentDataState)
 
 	var actionToProcess *models.ActionDefinition
+	branch := "then"
 	if conditionsMet {
 		log.Printf("DEBUG: Conditions MET. Processing 'Then' action.")
 		actionToProcess = flow.Then
 	} else {
 		log.Printf("DEBUG: Conditions NOT MET. Processing 'Else' action.")
 		actionToProcess = flow.Else
+		branch = "else"
 	}
 
 	// If there's an action to process (either Then or Else)
@@ -65,6 +69,7 @@ func ProcessConditionalFlow(flow *models.ConditionalBlock,
 		// Process the chosen action
 		responseFromAction, dataAfterAction, saveFromAction, actionErr := processAction(actionToProcess, currentDataState, ctx, store, dbName, collName)
 		if actionErr != nil {
+			actionErr = withFlowPath(actionErr, branch)
 			log.Printf("ERROR: Error processing action: %v", actionErr)
 			// Return the error, potentially setting a default error response
 			return fiber.Map{"error": actionErr.Error()}, dataAfterAction, false, actionErr // Return error response, last known data state, don't save
@@ -103,8 +108,25 @@ func evaluateConditions(conditions []models.Condition, data map[string]interface
 
 // evaluateCondition checks a single condition against the data.
 func evaluateCondition(condition models.Condition, data map[string]interface{}) bool {
+	// Compare against a lookup table entry (e.g. "$lookup.limits[$plan]") instead of a literal
+	if ref, ok := condition.Value.(string); ok && strings.HasPrefix(ref, lookupPrefix) {
+		condition.Value = SubstituteVariables(ref, data)
+	}
+
+	// 'featureFlag' reads a flag named by Field, not a data field
+	if condition.Operator == "featureFlag" {
+		return featureFlagMatches(condition, data)
+	}
+
+	// 'exists' checks presence only; Value false inverts it (field must be absent)
+	if condition.Operator == "exists" {
+		_, present := lookupField(data, condition.Field)
+		return present == (condition.Value != false)
+	}
+
 	// Support nested field access (e.g., "opdResult.statusCode")
 	fieldParts := strings.Split(condition.Field, ".")
+	fieldParts[0] = namespaceRoot(data, fieldParts[0])
 	fieldValue := interface{}(data)
 
 	for _, part := range fieldParts {
@@ -181,6 +203,15 @@ func evaluateCondition(condition models.Condition, data map[string]interface{})
 		}
 		return false // No match found
 
+	case "bcryptVerify": // Field holds the plaintext, Value the bcrypt hash (literal or "$storedHash")
+		plain, ok1 := fieldValue.(string)
+		hashed, ok2 := SubstituteVariables(condition.Value, data).(string)
+		if !ok1 || !ok2 || hashed == "" {
+			log.Printf("WARN: 'bcryptVerify' requires string plaintext and hash. Got field type %T. Evaluating as false.", fieldValue)
+			return false
+		}
+		return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(plain)) == nil
+
 	// Numeric Comparisons (gt, lt, gte, lte)
 	case "gt", "lt", "gte", "lte":
 		fvFloat, okFv := convertToFloat64(fieldValue)
@@ -211,14 +242,15 @@ func evaluateCondition(condition models.Condition, data map[string]interface{})
 	return false
 }
 
-// processAction handles the execution of a specific action (return, continue, conditionalBlock).
-// It first applies transformations, then executes the action logic.
+// executeAction handles the execution of a specific action (return, continue, conditionalBlock).
+// It first applies transformations, then executes the action logic. Callers go through
+// processAction, which enforces time limits.
 // It returns:
 // - responseToSend: The data determined by the action (e.g., return data, data to continue with).
 // - dataAfterAction: The state of the data map *after* transformations and action execution.
 // - shouldSave: The boolean save flag from the action definition.
 // - err: Any error encountered.
-func processAction(action *models.ActionDefinition,
+func executeAction(action *models.ActionDefinition,
 	dataBeforeAction map[string]interface{},
 	ctx context.Context,
 	store *database.Store,
@@ -235,7 +267,20 @@ func processAction(action *models.ActionDefinition,
 	// --- 1. Apply Transformations ---
 	// Transformations modify the data state *before* the action type logic is executed.
 	// ApplyTransformations returns a *new* map, preserving the original dataBeforeAction if needed.
-	dataAfterTransform := ApplyTransformations(action.Transform, dataBeforeAction) // Calls func in transform.go
+	transform := action.Transform
+	if len(action.UseMappings) > 0 {
+		mappings, err := resolveMappings(ctx, store, action.UseMappings)
+		if err != nil {
+			log.Printf("ERROR: Failed to load mapping profiles %v: %v", action.UseMappings, err)
+			return fiber.Map{"error": "Mapping profile failed"}, dataBeforeAction, false, newActionError(action.Type, "useMappings", err)
+		}
+		transform = append(mappings, transform...)
+	}
+	dataAfterTransform, err := transformData(ctx, transform, dataBeforeAction) // Calls func in transform.go
+	if err != nil {
+		log.Printf("ERROR: Transformations failed: %v", err)
+		return fiber.Map{"error": "Transformation failed"}, dataBeforeAction, false, newActionError(action.Type, "transform", err)
+	}
 	log.Printf("DEBUG: Data state after transformations: %v", dataAfterTransform)
 
 	// Initialize return values based on the state after transformation
@@ -246,6 +291,10 @@ func processAction(action *models.ActionDefinition,
 	// --- 2. Execute Action Logic ---
 	switch action.Type {
 	case "return":
+		if action.ReturnError != "" {
+			response, err := catalogError(ctx, action, dataAfterTransform)
+			return response, dataAfterTransform, false, err
+		}
 		// Substitute variables in the defined ReturnData using the state *after* transformations
 		var finalReturnData interface{}
 
@@ -260,10 +309,10 @@ func processAction(action *models.ActionDefinition,
 						returnMap[key] = kvPair["Value"]
 					}
 				}
-				finalReturnData = SubstituteVariables(returnMap, dataAfterTransform)
+				finalReturnData = SubstituteVariables(translateMessages(ctx, returnMap, dataAfterTransform), dataAfterTransform)
 			}
 		default: // ถ้าเป็น Object ปกติ
-			finalReturnData = SubstituteVariables(action.ReturnData, dataAfterTransform)
+			finalReturnData = SubstituteVariables(translateMessages(ctx, action.ReturnData, dataAfterTransform), dataAfterTransform)
 		}
 
 		log.Printf("DEBUG: Action 'return'. Returning data: %v", finalReturnData)
@@ -281,7 +330,8 @@ func processAction(action *models.ActionDefinition,
 		log.Printf("DEBUG: Action 'conditionalBlock'. Processing nested flow...")
 		// Recursively call ProcessConditionalFlow with the *transformed* data state
 		// The results of the nested flow become the results of this action
-		return ProcessConditionalFlow(action.ConditionalFlow, dataAfterTransform, ctx, store, dbName, collName)
+		response, finalState, save, err := ProcessConditionalFlow(action.ConditionalFlow, dataAfterTransform, ctx, store, dbName, collName)
+		return response, finalState, save, withFlowPath(err, "conditionalFlow")
 
 	case "continue":
 		log.Printf("DEBUG: Action 'continue'. Proceeding with current data state.")
@@ -303,8 +353,9 @@ func processAction(action *models.ActionDefinition,
 		if err != nil {
 			log.Printf("ERROR: Failed to get target API '%s': %v", action.ApiCall.ApiName, err)
 			return fiber.Map{"error": fmt.Sprintf("Failed to process API call to %s", action.ApiCall.ApiName)},
-				dataAfterTransform, false, err
+				dataAfterTransform, false, newActionError(action.Type, "apiCall.apiName", err)
 		}
+		target := ApplyEnvironment(*targetAPI) // The target's own per-environment database and base URLs
 
 		// Prepare parameters for the target API
 		callParams := make(map[string]interface{})
@@ -313,6 +364,7 @@ func processAction(action *models.ActionDefinition,
 			if strVal, ok := v.(string); ok && strings.HasPrefix(strVal, "$") {
 				paramName := strings.TrimPrefix(strVal, "$")
 				fieldParts := strings.Split(paramName, ".")
+				fieldParts[0] = namespaceRoot(dataAfterTransform, fieldParts[0])
 
 				// Traverse nested structure
 				value := interface{}(dataAfterTransform)
@@ -353,17 +405,18 @@ func processAction(action *models.ActionDefinition,
 
 		// Process the target API using its conditional flow
 		apiResponse, _, _, callErr := ProcessConditionalFlow(
-			targetAPI.ConditionalFlow,
+			target.ConditionalFlow,
 			callParams,
-			ctx,
+			WithBaseURLs(ctx, target),
 			store,
-			targetAPI.Database,
-			targetAPI.Collection,
+			target.Database,
+			target.Collection,
 		)
 		if callErr != nil {
 			log.Printf("ERROR: Failed to process API call to '%s': %v", action.ApiCall.ApiName, callErr)
+			// The target API's own flow path is kept inside the message
 			return fiber.Map{"error": fmt.Sprintf("API call to %s failed: %v", action.ApiCall.ApiName, callErr)},
-				dataAfterTransform, false, callErr
+				dataAfterTransform, false, newActionError(action.Type, "apiCall.apiName", fmt.Errorf("API call to %s failed: %w", action.ApiCall.ApiName, callErr))
 		}
 
 		// Extract the actual response data we want
@@ -429,8 +482,11 @@ func processAction(action *models.ActionDefinition,
 			finalState[k] = v
 		}
 
-		// Apply transformations AFTER storing API call result
-		finalState = ApplyTransformations(action.Transform, finalState)
+		// Apply transformations (and mapping profiles) AFTER storing API call result
+		if finalState, err = transformData(ctx, transform, finalState); err != nil {
+			log.Printf("ERROR: Transformations failed: %v", err)
+			return fiber.Map{"error": "Transformation failed"}, dataAfterTransform, false, newActionError(action.Type, "transform", err)
+		}
 
 		// Apply variable substitution on the final state
 		if returnMap, ok := action.ReturnData.(map[string]interface{}); ok {
@@ -443,13 +499,387 @@ func processAction(action *models.ActionDefinition,
 		// If type assertions fail, return the transformed state directly
 		return finalState, finalState, action.SaveData, nil
 
+	case "presignUrl":
+		if action.Presign == nil {
+			log.Printf("WARN: Action type is 'presignUrl' but Presign configuration is nil")
+			return fiber.Map{"status": "error", "message": "Invalid presign configuration"}, dataAfterTransform, false, nil
+		}
+		signedURL, err := presignURL(action.Presign, dataAfterTransform, time.Now())
+		if err != nil {
+			log.Printf("ERROR: Failed to presign URL: %v", err)
+			return fiber.Map{"error": fmt.Sprintf("Failed to generate signed URL: %v", err)}, dataAfterTransform, false, newActionError(action.Type, "presign.key", err)
+		}
+		resultField := action.Presign.ResultField
+		if resultField == "" {
+			resultField = "url"
+		}
+		state := copyData(dataAfterTransform)
+		setField(state, resultField, signedURL)
+		log.Printf("DEBUG: Action 'presignUrl'. Stored signed URL in '%s'", resultField)
+		return completeAction(action, state, ctx, store, dbName, collName)
+
+	case "signJwt":
+		if action.Jwt == nil {
+			log.Printf("WARN: Action type is 'signJwt' but Jwt configuration is nil")
+			return fiber.Map{"status": "error", "message": "Invalid JWT configuration"}, dataAfterTransform, false, nil
+		}
+		token, err := signJwt(action.Jwt, dataAfterTransform, time.Now())
+		if err != nil {
+			log.Printf("ERROR: Failed to sign JWT: %v", err)
+			return fiber.Map{"error": "Failed to issue token"}, dataAfterTransform, false, newActionError(action.Type, "jwt", err)
+		}
+		resultField := action.Jwt.ResultField
+		if resultField == "" {
+			resultField = "token"
+		}
+		state := copyData(dataAfterTransform)
+		setField(state, resultField, token)
+		log.Printf("DEBUG: Action 'signJwt'. Stored token in '%s'", resultField)
+		return completeAction(action, state, ctx, store, dbName, collName)
+
+	case "verifyCaptcha":
+		if action.Captcha == nil {
+			log.Printf("WARN: Action type is 'verifyCaptcha' but Captcha configuration is nil")
+			return fiber.Map{"status": "error", "message": "Invalid captcha configuration"}, dataAfterTransform, false, nil
+		}
+		passed, result, err := verifyCaptcha(ctx, action.Captcha, dataAfterTransform)
+		if err != nil {
+			log.Printf("ERROR: Captcha verification error: %v", err)
+			return fiber.Map{"error": "Captcha verification unavailable"}, dataAfterTransform, false, newActionError(action.Type, action.Captcha.TokenField, err)
+		}
+		if !passed {
+			log.Printf("INFO: Captcha verification failed (errors: %v)", result.ErrorCodes)
+			if action.Captcha.OnFailure != nil {
+				response, finalState, save, err := processAction(action.Captcha.OnFailure, dataAfterTransform, ctx, store, dbName, collName)
+				return response, finalState, save, withFlowPath(err, "captcha.onFailure")
+			}
+			return fiber.Map{
+				"statusCode": http.StatusBadRequest,
+				"status":     "error",
+				"message":    "Captcha verification failed",
+			}, dataAfterTransform, false, nil
+		}
+		log.Printf("DEBUG: Action 'verifyCaptcha'. Verification passed.")
+		return completeAction(action, dataAfterTransform, ctx, store, dbName, collName)
+
+	case "cacheGet":
+		if action.Cache == nil || action.Cache.Key == "" {
+			log.Printf("WARN: Action type is 'cacheGet' but Cache configuration is missing")
+			return fiber.Map{"status": "error", "message": "Invalid cache configuration"}, dataAfterTransform, false, nil
+		}
+		key := InterpolateString(action.Cache.Key, dataAfterTransform)
+		value, hit, err := currentCache().Get(ctx, key)
+		if err != nil {
+			// A broken cache should not break the flow; treat it as a miss
+			log.Printf("WARN: Cache lookup for key '%s' failed, treating as miss: %v", key, err)
+		}
+		state := copyData(dataAfterTransform)
+		if hit {
+			resultField := action.Cache.ResultField
+			if resultField == "" {
+				resultField = "cached"
+			}
+			setField(state, resultField, value)
+		}
+		log.Printf("DEBUG: Action 'cacheGet'. Key '%s' hit=%t", key, hit)
+		return completeAction(action, state, ctx, store, dbName, collName)
+
+	case "cacheSet":
+		if action.Cache == nil || action.Cache.Key == "" {
+			log.Printf("WARN: Action type is 'cacheSet' but Cache configuration is missing")
+			return fiber.Map{"status": "error", "message": "Invalid cache configuration"}, dataAfterTransform, false, nil
+		}
+		key := InterpolateString(action.Cache.Key, dataAfterTransform)
+		ttl := defaultCacheTTL
+		if action.Cache.TTL > 0 {
+			ttl = time.Duration(action.Cache.TTL) * time.Second
+		}
+		value := SubstituteVariables(action.Cache.Value, dataAfterTransform)
+		if err := currentCache().Set(ctx, key, value, ttl); err != nil {
+			log.Printf("WARN: Cache write for key '%s' failed: %v", key, err)
+		} else {
+			log.Printf("DEBUG: Action 'cacheSet'. Stored key '%s' for %s", key, ttl)
+		}
+		return completeAction(action, dataAfterTransform, ctx, store, dbName, collName)
+
+	case "graphqlCall":
+		if action.GraphQL == nil || action.GraphQL.Endpoint == "" {
+			log.Printf("WARN: Action type is 'graphqlCall' but GraphQL configuration is missing")
+			return fiber.Map{"status": "error", "message": "Invalid GraphQL configuration"}, dataAfterTransform, false, nil
+		}
+		result, err := graphqlCall(ctx, action.GraphQL, dataAfterTransform)
+		if err != nil {
+			log.Printf("ERROR: GraphQL call to '%s' failed: %v", action.GraphQL.Endpoint, err)
+			return fiber.Map{"error": "GraphQL call failed"}, dataAfterTransform, false, newActionError(action.Type, "graphql.endpoint", err)
+		}
+		resultField := defaultString(action.GraphQL.ResultField, "graphql")
+		state := copyData(dataAfterTransform)
+		setField(state, resultField, result)
+		log.Printf("DEBUG: Action 'graphqlCall'. Stored result in '%s'", resultField)
+		return completeAction(action, state, ctx, store, dbName, collName)
+
+	case "soapCall":
+		if action.SOAP == nil || action.SOAP.Endpoint == "" {
+			log.Printf("WARN: Action type is 'soapCall' but SOAP configuration is missing")
+			return fiber.Map{"status": "error", "message": "Invalid SOAP configuration"}, dataAfterTransform, false, nil
+		}
+		result, err := soapCall(ctx, action.SOAP, dataAfterTransform)
+		if err != nil {
+			log.Printf("ERROR: SOAP call to '%s' failed: %v", action.SOAP.Endpoint, err)
+			return fiber.Map{"error": "SOAP call failed"}, dataAfterTransform, false, newActionError(action.Type, "soap.endpoint", err)
+		}
+		resultField := defaultString(action.SOAP.ResultField, "soap")
+		state := copyData(dataAfterTransform)
+		setField(state, resultField, result)
+		log.Printf("DEBUG: Action 'soapCall'. Stored result in '%s'", resultField)
+		return completeAction(action, state, ctx, store, dbName, collName)
+
+	case "fileDrop":
+		if action.FileDrop == nil || action.FileDrop.Target == "" || action.FileDrop.Path == "" {
+			log.Printf("WARN: Action type is 'fileDrop' but FileDrop configuration is missing")
+			return fiber.Map{"status": "error", "message": "Invalid file drop configuration"}, dataAfterTransform, false, nil
+		}
+		result, err := fileDrop(ctx, action.FileDrop, dataAfterTransform)
+		if err != nil {
+			log.Printf("ERROR: File drop to target '%s' failed: %v", action.FileDrop.Target, err)
+			return fiber.Map{"error": "File drop failed"}, dataAfterTransform, false, newActionError(action.Type, "fileDrop.target", err)
+		}
+		resultField := defaultString(action.FileDrop.ResultField, "file")
+		state := copyData(dataAfterTransform)
+		setField(state, resultField, result)
+		log.Printf("INFO: Action 'fileDrop'. Wrote %v bytes to %s:%v", result["bytes"], action.FileDrop.Target, result["path"])
+		return completeAction(action, state, ctx, store, dbName, collName)
+
+	case "ldapLookup":
+		if action.LDAP == nil || action.LDAP.Filter == "" {
+			log.Printf("WARN: Action type is 'ldapLookup' but LDAP configuration is missing")
+			return fiber.Map{"status": "error", "message": "Invalid LDAP lookup configuration"}, dataAfterTransform, false, nil
+		}
+		entry, err := ldapLookup(ctx, action.LDAP, dataAfterTransform)
+		if err == nil && entry == nil && action.LDAP.Required {
+			err = ErrLDAPNotFound
+		}
+		if err != nil {
+			log.Printf("ERROR: LDAP lookup in directory '%s' failed: %v", defaultString(action.LDAP.Directory, "default"), err)
+			return fiber.Map{"error": "LDAP lookup failed"}, dataAfterTransform, false, newActionError(action.Type, "ldap.filter", err)
+		}
+		state := copyData(dataAfterTransform)
+		if entry == nil {
+			log.Printf("INFO: Action 'ldapLookup'. No entry matched, fields left unset")
+			return completeAction(action, state, ctx, store, dbName, collName)
+		}
+		for attr, field := range action.LDAP.Attributes {
+			if value, ok := entry[strings.ToLower(attr)]; ok {
+				setField(state, field, value)
+			}
+		}
+		log.Printf("INFO: Action 'ldapLookup'. Merged attributes of '%v'", entry["dn"])
+		return completeAction(action, state, ctx, store, dbName, collName)
+
+	case "payment":
+		if action.Payment == nil || action.Payment.Operation == "" {
+			log.Printf("WARN: Action type is 'payment' but Payment configuration is missing")
+			return fiber.Map{"status": "error", "message": "Invalid payment configuration"}, dataAfterTransform, false, nil
+		}
+		result, declined, err := payment(ctx, action.Payment, dataAfterTransform)
+		if err != nil {
+			log.Printf("ERROR: Payment operation '%s' failed: %v", action.Payment.Operation, err)
+			return fiber.Map{"error": "Payment operation failed"}, dataAfterTransform, false, newActionError(action.Type, "payment.operation", err)
+		}
+		resultField := defaultString(action.Payment.ResultField, "payment")
+		state := copyData(dataAfterTransform)
+		setField(state, resultField, result)
+		if declined {
+			log.Printf("INFO: Payment operation '%s' declined: %v", action.Payment.Operation, result["message"])
+			if action.Payment.OnFailure != nil {
+				response, finalState, save, err := processAction(action.Payment.OnFailure, state, ctx, store, dbName, collName)
+				return response, finalState, save, withFlowPath(err, "payment.onFailure")
+			}
+			if action.Payment.Operation == "verifyWebhook" {
+				return fiber.Map{"statusCode": http.StatusBadRequest, "status": "error", "message": "Invalid webhook signature"}, dataAfterTransform, false, nil
+			}
+			return fiber.Map{"statusCode": http.StatusPaymentRequired, "status": "error", "message": "Payment declined", "data": result}, dataAfterTransform, false, nil
+		}
+		log.Printf("INFO: Action 'payment'. Operation '%s' stored %v in '%s'", action.Payment.Operation, result["id"], resultField)
+		return completeAction(action, state, ctx, store, dbName, collName)
+
+	case "push":
+		if action.Push == nil || action.Push.Provider == "" {
+			log.Printf("WARN: Action type is 'push' but Push configuration is missing")
+			return fiber.Map{"status": "error", "message": "Invalid push configuration"}, dataAfterTransform, false, nil
+		}
+		result, err := sendPush(ctx, action.Push, dataAfterTransform, store, dbName)
+		if err != nil {
+			log.Printf("ERROR: Push notification via '%s' failed: %v", action.Push.Provider, err)
+			return fiber.Map{"error": "Push notification failed"}, dataAfterTransform, false, newActionError(action.Type, "push.provider", err)
+		}
+		resultField := defaultString(action.Push.ResultField, "push")
+		state := copyData(dataAfterTransform)
+		setField(state, resultField, result)
+		log.Printf("INFO: Action 'push'. Sent %v, failed %v via '%s'", result["sent"], result["failed"], action.Push.Provider)
+		return completeAction(action, state, ctx, store, dbName, collName)
+
+	case "sendSms":
+		if action.SMS == nil || action.SMS.To == "" || action.SMS.Body == "" {
+			log.Printf("WARN: Action type is 'sendSms' but SMS configuration is missing")
+			return fiber.Map{"status": "error", "message": "Invalid SMS configuration"}, dataAfterTransform, false, nil
+		}
+		result, err := sendSms(ctx, action.SMS, dataAfterTransform)
+		if errors.Is(err, ErrSMSRateLimited) {
+			log.Printf("INFO: Action 'sendSms'. Recipient rate limit reached")
+			if action.SMS.OnRateLimit != nil {
+				response, finalState, save, err := processAction(action.SMS.OnRateLimit, dataAfterTransform, ctx, store, dbName, collName)
+				return response, finalState, save, withFlowPath(err, "sms.onRateLimit")
+			}
+			return fiber.Map{"statusCode": http.StatusTooManyRequests, "status": "error", "message": "Too many messages to this number, try again later"}, dataAfterTransform, false, nil
+		}
+		if err != nil {
+			log.Printf("ERROR: Sending SMS via provider '%s' failed: %v", defaultString(action.SMS.Provider, "default"), err)
+			return fiber.Map{"error": "SMS sending failed"}, dataAfterTransform, false, newActionError(action.Type, "sms.to", err)
+		}
+		resultField := defaultString(action.SMS.ResultField, "sms")
+		state := copyData(dataAfterTransform)
+		setField(state, resultField, result)
+		log.Printf("INFO: Action 'sendSms'. Message %v is '%v'", result["id"], result["status"])
+		return completeAction(action, state, ctx, store, dbName, collName)
+
+	case "generateOtp":
+		if action.OTP == nil || action.OTP.Subject == "" {
+			log.Printf("WARN: Action type is 'generateOtp' but OTP configuration is missing")
+			return fiber.Map{"status": "error", "message": "Invalid OTP configuration"}, dataAfterTransform, false, nil
+		}
+		code, expiresAt, err := generateOtp(ctx, action.OTP, dataAfterTransform, store, time.Now())
+		if err != nil {
+			log.Printf("ERROR: Generating OTP for purpose '%s' failed: %v", action.OTP.Purpose, err)
+			return fiber.Map{"error": "OTP generation failed"}, dataAfterTransform, false, newActionError(action.Type, "otp.subject", err)
+		}
+		resultField := defaultString(action.OTP.ResultField, "otp")
+		state := copyData(dataAfterTransform)
+		setField(state, resultField, map[string]interface{}{"code": code, "expiresAt": expiresAt.Format(time.RFC3339)})
+		log.Printf("INFO: Action 'generateOtp'. Issued code for purpose '%s' valid until %s", action.OTP.Purpose, expiresAt.Format(time.RFC3339))
+		return completeAction(action, state, ctx, store, dbName, collName)
+
+	case "verifyOtp":
+		if action.OTP == nil || action.OTP.Subject == "" {
+			log.Printf("WARN: Action type is 'verifyOtp' but OTP configuration is missing")
+			return fiber.Map{"status": "error", "message": "Invalid OTP configuration"}, dataAfterTransform, false, nil
+		}
+		outcome, err := verifyOtp(ctx, action.OTP, dataAfterTransform, store, time.Now())
+		if err != nil {
+			log.Printf("ERROR: Verifying OTP for purpose '%s' failed: %v", action.OTP.Purpose, err)
+			return fiber.Map{"error": "OTP verification unavailable"}, dataAfterTransform, false, newActionError(action.Type, "otp.subject", err)
+		}
+		state := copyData(dataAfterTransform)
+		setField(state, defaultString(action.OTP.ResultField, "otp"), map[string]interface{}{"status": outcome})
+		if outcome != OTPVerified {
+			log.Printf("INFO: OTP verification for purpose '%s' failed: %s", action.OTP.Purpose, outcome)
+			if action.OTP.OnFailure != nil {
+				response, finalState, save, err := processAction(action.OTP.OnFailure, state, ctx, store, dbName, collName)
+				return response, finalState, save, withFlowPath(err, "otp.onFailure")
+			}
+			return fiber.Map{"statusCode": http.StatusBadRequest, "status": "error", "message": "Invalid or expired code"}, dataAfterTransform, false, nil
+		}
+		log.Printf("DEBUG: Action 'verifyOtp'. Code verified for purpose '%s'", action.OTP.Purpose)
+		return completeAction(action, state, ctx, store, dbName, collName)
+
+	case "callFlow":
+		if action.CallFlow == nil {
+			log.Printf("WARN: Action type is 'callFlow' but callFlow configuration is missing")
+			return fiber.Map{"status": "error", "message": "Invalid callFlow configuration"}, dataAfterTransform, false, nil
+		}
+		response, failed, err := callFlow(ctx, action.CallFlow, dataAfterTransform, store, dbName, collName)
+		if err != nil {
+			log.Printf("ERROR: Flow fragment '%s' failed: %v", action.CallFlow.Fragment, err)
+			return fiber.Map{"error": "Flow fragment failed"}, dataAfterTransform, false, err
+		}
+		if failed {
+			log.Printf("INFO: Flow fragment '%s' ended the flow with an error response", action.CallFlow.Fragment)
+			return response, dataAfterTransform, false, nil
+		}
+		state := copyData(dataAfterTransform)
+		setField(state, defaultString(action.CallFlow.ResultField, "result"), response)
+		return completeAction(action, state, ctx, store, dbName, collName)
+
+	case "dbUpdate":
+		if action.DbUpdate == nil {
+			log.Printf("WARN: Action type is 'dbUpdate' but dbUpdate configuration is missing")
+			return fiber.Map{"status": "error", "message": "Invalid dbUpdate configuration"}, dataAfterTransform, false, nil
+		}
+		doc, current, err := dbUpdate(ctx, action.DbUpdate, dataAfterTransform, store, dbName, collName)
+		if err != nil {
+			log.Printf("ERROR: dbUpdate on %s.%s failed: %v", dbName, collName, err)
+			return fiber.Map{"error": "Database update failed"}, dataAfterTransform, false, newActionError(action.Type, "dbUpdate.update", err)
+		}
+		state := copyData(dataAfterTransform)
+		if current != nil {
+			log.Printf("INFO: dbUpdate on %s.%s skipped: document does not have the expected values", dbName, collName)
+			setField(state, defaultString(action.DbUpdate.ConflictField, "conflict"), current)
+			if action.DbUpdate.OnConflict != nil {
+				response, finalState, save, err := processAction(action.DbUpdate.OnConflict, state, ctx, store, dbName, collName)
+				return response, finalState, save, withFlowPath(err, "dbUpdate.onConflict")
+			}
+			return fiber.Map{"statusCode": http.StatusConflict, "status": "error", "message": "Document was changed by another request"}, dataAfterTransform, false, nil
+		}
+		resultField := defaultString(action.DbUpdate.ResultField, "updated")
+		if doc != nil {
+			setField(state, resultField, doc)
+		}
+		log.Printf("DEBUG: Action 'dbUpdate'. Matched=%t, stored in '%s'", doc != nil, resultField)
+		return completeAction(action, state, ctx, store, dbName, collName)
+
+	case "findAndModify":
+		if action.FindAndModify == nil {
+			log.Printf("WARN: Action type is 'findAndModify' but findAndModify configuration is missing")
+			return fiber.Map{"status": "error", "message": "Invalid findAndModify configuration"}, dataAfterTransform, false, nil
+		}
+		doc, err := findAndModify(ctx, action.FindAndModify, dataAfterTransform, store, dbName, collName)
+		if err != nil {
+			log.Printf("ERROR: findAndModify on %s.%s failed: %v", dbName, collName, err)
+			return fiber.Map{"error": "Database update failed"}, dataAfterTransform, false, newActionError(action.Type, "findAndModify.filter", err)
+		}
+		state := copyData(dataAfterTransform)
+		resultField := defaultString(action.FindAndModify.ResultField, "document")
+		if doc != nil {
+			setField(state, resultField, doc)
+		}
+		log.Printf("DEBUG: Action 'findAndModify'. Matched=%t, stored in '%s'", doc != nil, resultField)
+		return completeAction(action, state, ctx, store, dbName, collName)
+
 	default:
 		log.Printf("ERROR: Unknown action type '%s' in action definition.", action.Type)
-		err = fmt.Errorf("unknown action type: %s", action.Type)
+		err = &FlowError{Path: "type", Action: action.Type, Message: fmt.Sprintf("unknown action type: %s", action.Type)}
 		return fiber.Map{"error": err.Error()}, dataAfterTransform, false, err
 	}
 }
 
+// completeAction finishes an action that stored its result in the data state (e.g. presignUrl):
+// a nested ConditionalFlow continues processing with the new state, ReturnData is rendered
+// as the response, otherwise the state itself is returned.
+func completeAction(action *models.ActionDefinition,
+	state map[string]interface{},
+	ctx context.Context,
+	store *database.Store,
+	dbName, collName string) (interface{}, map[string]interface{}, bool, error) {
+
+	if action.ConditionalFlow != nil {
+		response, finalState, save, err := ProcessConditionalFlow(action.ConditionalFlow, state, ctx, store, dbName, collName)
+		return response, finalState, save || action.SaveData, withFlowPath(err, "conditionalFlow")
+	}
+	if action.ReturnData != nil {
+		return SubstituteVariables(action.ReturnData, state), state, action.SaveData, nil
+	}
+	return state, state, action.SaveData, nil
+}
+
+// copyData returns a shallow copy of a data map.
+func copyData(data map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		result[k] = v
+	}
+	return result
+}
+
 // convertToFloat64 attempts to convert various numeric types (and strings) to float64.
 func convertToFloat64(val interface{}) (float64, bool) {
 	if val == nil {
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Correlation identifies a request across the services a flow calls.
+type Correlation struct {
+	RequestID   string // X-Request-Id, accepted from the client or generated
+	TraceParent string // W3C traceparent sent downstream: the caller's trace with this request as parent
+}
+
+// correlationKey is stored as a fasthttp user value, so every context derived from the
+// request context (fasthttp's Value reads user values) carries the correlation.
+type correlationKey struct{}
+
+var (
+	requestIDPattern   = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+	traceParentPattern = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})`)
+)
+
+// NewCorrelation accepts the inbound X-Request-Id and traceparent headers when they are
+// well-formed and generates what is missing. Without a request ID the trace ID is used,
+// so both headers point at the same request in downstream logs.
+func NewCorrelation(requestID, traceParent string) Correlation {
+	traceID, flags := "", "00"
+	if m := traceParentPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(traceParent))); m != nil &&
+		m[1] != "ff" && m[2] != strings.Repeat("0", 32) && m[3] != strings.Repeat("0", 16) {
+		traceID, flags = m[2], m[4]
+	}
+	if traceID == "" {
+		traceID = randomHex(16)
+	}
+	if !requestIDPattern.MatchString(requestID) {
+		requestID = traceID
+	}
+	return Correlation{RequestID: requestID, TraceParent: "00-" + traceID + "-" + randomHex(8) + "-" + flags}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// StoreCorrelation attaches a correlation to a request context (*fasthttp.RequestCtx).
+func StoreCorrelation(requestCtx interface{ SetUserValue(key, value interface{}) }, corr Correlation) {
+	requestCtx.SetUserValue(correlationKey{}, corr)
+}
+
+// WithCorrelation attaches a correlation to a context that is not derived from a request.
+func WithCorrelation(ctx context.Context, corr Correlation) context.Context {
+	return context.WithValue(ctx, correlationKey{}, corr)
+}
+
+// CorrelationFrom returns the correlation of the request ctx belongs to.
+func CorrelationFrom(ctx context.Context) (Correlation, bool) {
+	corr, ok := ctx.Value(correlationKey{}).(Correlation)
+	return corr, ok
+}
+
+// RequestID returns the request ID carried by ctx, or "" outside a request.
+func RequestID(ctx context.Context) string {
+	corr, _ := CorrelationFrom(ctx)
+	return corr.RequestID
+}
+
+// correlationTransport adds X-Request-Id and traceparent to outbound calls made within a
+// request, unless the action set them itself.
+type correlationTransport struct {
+	base http.RoundTripper
+}
+
+func (t correlationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	corr, ok := CorrelationFrom(req.Context())
+	if !ok || (req.Header.Get("X-Request-Id") != "" && req.Header.Get("traceparent") != "") {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context()) // A RoundTripper must not modify the caller's request
+	if req.Header.Get("X-Request-Id") == "" {
+		req.Header.Set("X-Request-Id", corr.RequestID)
+	}
+	if req.Header.Get("traceparent") == "" {
+		req.Header.Set("traceparent", corr.TraceParent)
+	}
+	return t.base.RoundTrip(req)
+}
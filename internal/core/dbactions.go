@@ -0,0 +1,292 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// dbUpdateOperators are the update operators a "dbUpdate" action may use.
+var dbUpdateOperators = map[string]bool{
+	"$inc": true, "$mul": true, "$min": true, "$max": true,
+	"$push": true, "$addToSet": true, "$pull": true,
+	"$set": true, "$unset": true,
+}
+
+// FlowTarget describes the documents of the definition a flow runs for: the field
+// identifying one document, and the dataScope every document it touches must match.
+type FlowTarget struct {
+	UniqueKey string
+	Scope     map[string]interface{}
+}
+
+// flowTargetKey carries the FlowTarget of the running flow.
+type flowTargetKey struct{}
+
+// WithFlowTarget tells database actions of a flow which documents they may touch.
+func WithFlowTarget(ctx context.Context, target FlowTarget) context.Context {
+	return context.WithValue(ctx, flowTargetKey{}, target)
+}
+
+func flowTargetFrom(ctx context.Context) FlowTarget {
+	target, _ := ctx.Value(flowTargetKey{}).(FlowTarget)
+	return target
+}
+
+// scopedFilter restricts filter to the caller's dataScope.
+func (t FlowTarget) scopedFilter(filter bson.M) bson.M {
+	for field, value := range t.Scope {
+		filter[field] = value
+	}
+	return filter
+}
+
+// dbUpdate applies the action's update operators to the document whose unique key has
+// the configured value. It returns the updated document, nil when none has the key. When
+// the document exists but does not have the expected values, it returns the document as
+// current instead.
+func dbUpdate(ctx context.Context, cfg *models.DbUpdateConfig, data map[string]interface{}, store *database.Store, dbName, collName string) (updated, current interface{}, err error) {
+	target := flowTargetFrom(ctx)
+	if target.UniqueKey == "" {
+		return nil, nil, errors.New("dbUpdate requires a uniqueKey on the definition")
+	}
+	template := cfg.Key
+	if template == nil {
+		template = "$" + target.UniqueKey
+	}
+	key := SubstituteVariables(template, data)
+	if key == nil || key == "" {
+		return nil, nil, fmt.Errorf("no value for unique key '%s'", target.UniqueKey)
+	}
+	if structuredValue(key) {
+		// {"$ne": null} would update whichever document of the scope matches first
+		return nil, nil, fmt.Errorf("unique key '%s' must be a single value", target.UniqueKey)
+	}
+	update, err := updateOperators(cfg.Update, data, ScopeKeys(target.Scope))
+	if err != nil {
+		return nil, nil, err
+	}
+	keyFilter := target.scopedFilter(bson.M{target.UniqueKey: key})
+	filter := keyFilter
+	if len(cfg.Expect) > 0 {
+		expect, err := expectFilter(cfg.Expect, data, target.UniqueKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		filter = bson.M{"$and": bson.A{keyFilter, expect}}
+	}
+	doc, err := store.FindOneAndUpdateData(ctx, dbName, collName, filter, update, database.ModifyOptions{Upsert: cfg.Upsert})
+	if err != nil {
+		return nil, nil, err
+	}
+	if doc != nil {
+		return plainValue(doc), nil, nil
+	}
+	if len(cfg.Expect) == 0 {
+		return nil, nil, nil
+	}
+	// Nothing matched: either no document has the key, or it does not have the expected values
+	docs, err := store.FindDataWith(ctx, dbName, collName, keyFilter, database.FindOptions{Limit: 1})
+	if err != nil || len(docs) == 0 {
+		return nil, nil, err
+	}
+	return nil, plainValue(docs[0]), nil
+}
+
+// expectFilter resolves the expected values of a compare-and-set. Fields are matched
+// like a query, so operators such as $gte may be used.
+func expectFilter(expect map[string]interface{}, data map[string]interface{}, uniqueKey string) (bson.M, error) {
+	for field := range expect {
+		if field == "" || strings.HasPrefix(field, "$") {
+			return nil, fmt.Errorf("invalid expected field '%s'", field)
+		}
+		if field == uniqueKey {
+			return nil, fmt.Errorf("expected field '%s' is the unique key", field)
+		}
+	}
+	return modifyFilter(expect, data)
+}
+
+// updateOperators resolves the "$field" references in an update document. Only the
+// operators of dbUpdateOperators are accepted, and the scope fields cannot be changed:
+// that would move the document out of its owner's data.
+func updateOperators(update map[string]interface{}, data map[string]interface{}, scopeFields []string) (bson.M, error) {
+	if len(update) == 0 {
+		return nil, errors.New("update must not be empty")
+	}
+	resolved := bson.M{}
+	for op, fields := range update {
+		if !dbUpdateOperators[op] {
+			return nil, fmt.Errorf("update operator '%s' is not supported", op)
+		}
+		values, ok := fields.(map[string]interface{})
+		if !ok || len(values) == 0 {
+			return nil, fmt.Errorf("update operator '%s' requires an object of fields", op)
+		}
+		for field := range values {
+			if field == "" || strings.HasPrefix(field, "$") {
+				return nil, fmt.Errorf("invalid field '%s' in update operator '%s'", field, op)
+			}
+			for _, scoped := range scopeFields {
+				if field == scoped || strings.HasPrefix(field, scoped+".") {
+					return nil, fmt.Errorf("scope field '%s' cannot be updated", field)
+				}
+			}
+		}
+		resolved[op] = SubstituteVariables(values, data)
+	}
+	return resolved, nil
+}
+
+// findAndModify updates or deletes the first document matching the action's filter
+// within the caller's scope and returns it, nil when none matched.
+func findAndModify(ctx context.Context, cfg *models.ModifyConfig, data map[string]interface{}, store *database.Store, dbName, collName string) (interface{}, error) {
+	target := flowTargetFrom(ctx)
+	filter, err := modifyFilter(cfg.Filter, data)
+	if err != nil {
+		return nil, err
+	}
+	filter = target.scopedFilter(filter)
+	sort := modifySort(cfg.Sort)
+
+	var doc bson.M
+	if cfg.Delete {
+		doc, err = store.FindOneAndDeleteData(ctx, dbName, collName, filter, sort)
+	} else {
+		var update bson.M
+		if update, err = updateOperators(cfg.Update, data, ScopeKeys(target.Scope)); err != nil {
+			return nil, err
+		}
+		doc, err = store.FindOneAndUpdateData(ctx, dbName, collName, filter, update, database.ModifyOptions{
+			Sort:         sort,
+			Upsert:       cfg.Upsert,
+			ReturnBefore: cfg.ReturnBefore,
+		})
+	}
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	return plainValue(doc), nil
+}
+
+// modifyFilter resolves the "$field" references in a findAndModify filter. A reference
+// must resolve to a plain value, except directly under $in, $nin and $all, which take a
+// list of plain values; otherwise a request could send {"$ne": null} and widen the
+// filter. Operators that run code ($where, $expr, $function) are rejected at any depth
+// of the resolved filter.
+func modifyFilter(filter map[string]interface{}, data map[string]interface{}) (bson.M, error) {
+	if len(filter) == 0 {
+		return nil, errors.New("filter must not be empty")
+	}
+	resolved := bson.M{}
+	for field, value := range filter {
+		v, err := resolveFilterValue(field, value, data)
+		if err != nil {
+			return nil, err
+		}
+		resolved[field] = v
+	}
+	if err := checkFilterOperators(map[string]interface{}(resolved)); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// listOperators take an array of values.
+var listOperators = map[string]bool{"$in": true, "$nin": true, "$all": true}
+
+// resolveFilterValue substitutes the references in the filter value under key.
+func resolveFilterValue(key string, template interface{}, data map[string]interface{}) (interface{}, error) {
+	switch t := template.(type) {
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			value, err := resolveFilterValue(k, v, data)
+			if err != nil {
+				return nil, err
+			}
+			resolved[k] = value
+		}
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(t))
+		for i, v := range t {
+			value, err := resolveFilterValue("", v, data)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = value
+		}
+		return resolved, nil
+	case string:
+		value := SubstituteVariables(t, data)
+		if !structuredValue(value) {
+			return value, nil
+		}
+		if list, ok := value.([]interface{}); ok && listOperators[key] {
+			for _, item := range list {
+				if structuredValue(item) {
+					return nil, fmt.Errorf("'%s' under %s must be a list of plain values", t, key)
+				}
+			}
+			return list, nil
+		}
+		return nil, fmt.Errorf("'%s' must resolve to a plain value, not an object or array", t)
+	}
+	return template, nil
+}
+
+func checkFilterOperators(value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			switch key {
+			case "$where", "$expr", "$function", "$accumulator":
+				return fmt.Errorf("filter operator '%s' is not allowed", key)
+			}
+			if err := checkFilterOperators(nested); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, nested := range v {
+			if err := checkFilterOperators(nested); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// structuredValue reports whether a resolved value is an object or an array. Taken from
+// a request, such a value could turn an equality match into a query operator.
+func structuredValue(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Map, reflect.Slice:
+		return true
+	}
+	return false
+}
+
+// modifySort turns ["-priority", "createdAt"] into a sort document.
+func modifySort(fields []string) bson.D {
+	var sort bson.D
+	for _, field := range fields {
+		if name, ok := strings.CutPrefix(field, "-"); ok {
+			sort = append(sort, bson.E{Key: name, Value: -1})
+		} else {
+			sort = append(sort, bson.E{Key: strings.TrimPrefix(field, "+"), Value: 1})
+		}
+	}
+	return sort
+}
@@ -0,0 +1,22 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// ParseDeprecationDate reads the dates of a deprecation notice: YYYY-MM-DD (midnight
+// UTC) or RFC 3339.
+func ParseDeprecationDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("a date is required")
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' is not a date (YYYY-MM-DD or RFC 3339)", value)
+	}
+	return t.UTC(), nil
+}
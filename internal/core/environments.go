@@ -0,0 +1,75 @@
+package core
+
+import (
+	"context"
+	"strings"
+
+	"api-genarator/internal/models"
+)
+
+// environmentOverride returns the overrides of a definition for this server's
+// environment, nil when there are none.
+func environmentOverride(api models.ApiDefinition) *models.EnvOverride {
+	if len(api.Environments) == 0 {
+		return nil
+	}
+	env := currentSettings().Environment
+	if env == "" {
+		return nil
+	}
+	override, ok := api.Environments[env]
+	if !ok {
+		return nil
+	}
+	return &override
+}
+
+// ApplyEnvironment returns a copy of the definition with the overrides of this server's
+// environment applied. Applying it twice gives the same result.
+func ApplyEnvironment(api models.ApiDefinition) models.ApiDefinition {
+	override := environmentOverride(api)
+	if override == nil {
+		return api
+	}
+	if override.Database != "" {
+		api.Database = override.Database
+	}
+	if override.Collection != "" {
+		api.Collection = override.Collection
+	}
+	if override.FlowBudgetMs > 0 {
+		api.FlowBudgetMs = override.FlowBudgetMs
+	}
+	if override.Concurrency != nil {
+		api.Concurrency = override.Concurrency
+	}
+	return api
+}
+
+// baseURLsKey carries the endpoint prefixes to replace in the running flow.
+type baseURLsKey struct{}
+
+// WithBaseURLs hands the base URL overrides of the definition (for this server's
+// environment) to the endpoints its flow calls.
+func WithBaseURLs(ctx context.Context, api models.ApiDefinition) context.Context {
+	override := environmentOverride(api)
+	if override == nil || len(override.BaseURLs) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, baseURLsKey{}, override.BaseURLs)
+}
+
+// resolveEndpoint replaces the longest matching base URL prefix of endpoint.
+func resolveEndpoint(ctx context.Context, endpoint string) string {
+	baseURLs, _ := ctx.Value(baseURLsKey{}).(map[string]string)
+	match := ""
+	for prefix := range baseURLs {
+		if len(prefix) > len(match) && strings.HasPrefix(endpoint, prefix) {
+			match = prefix
+		}
+	}
+	if match == "" {
+		return endpoint
+	}
+	return baseURLs[match] + endpoint[len(match):]
+}
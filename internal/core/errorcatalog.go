@@ -0,0 +1,100 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrUnknownErrorCode is returned when a flow returns a code missing from the error catalog.
+var ErrUnknownErrorCode = errors.New("unknown error code")
+
+// errorCodePattern keeps codes machine-friendly: "ORDER_NOT_FOUND".
+var errorCodePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,63}$`)
+
+var (
+	errorCatalogMu sync.RWMutex
+	errorCatalog   = map[string]models.ErrorDefinition{}
+)
+
+// SetErrorDefinitions replaces the whole error catalog (at startup and on each refresh).
+func SetErrorDefinitions(defs []models.ErrorDefinition) {
+	loaded := make(map[string]models.ErrorDefinition, len(defs))
+	for _, d := range defs {
+		loaded[d.Code] = d
+	}
+	errorCatalogMu.Lock()
+	errorCatalog = loaded
+	errorCatalogMu.Unlock()
+}
+
+// PutErrorDefinition installs or replaces one error code.
+func PutErrorDefinition(def models.ErrorDefinition) {
+	errorCatalogMu.Lock()
+	errorCatalog[def.Code] = def
+	errorCatalogMu.Unlock()
+}
+
+// DropErrorDefinition removes one error code.
+func DropErrorDefinition(code string) {
+	errorCatalogMu.Lock()
+	delete(errorCatalog, code)
+	errorCatalogMu.Unlock()
+}
+
+// CheckErrorDefinition validates an error code before it is stored.
+func CheckErrorDefinition(def models.ErrorDefinition) []DefinitionProblem {
+	var problems []DefinitionProblem
+	add := func(path, format string, args ...interface{}) {
+		problems = append(problems, DefinitionProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	if !errorCodePattern.MatchString(def.Code) {
+		add("code", "code must be upper-case letters, digits and underscores (e.g. ORDER_NOT_FOUND)")
+	}
+	if def.Status < 400 || def.Status > 599 {
+		add("status", "status must be an HTTP error status (400-599)")
+	}
+	if def.Message == "" {
+		add("message", "message is required")
+	}
+	checkTranslateCalls(def.Message, "message", add)
+	return problems
+}
+
+// catalogError builds the response of a "return" action with returnError. The message
+// template may use {{field}} placeholders or be a $t(...) reference; returnData, if
+// any, is added as "details".
+func catalogError(ctx context.Context, action *models.ActionDefinition, data map[string]interface{}) (fiber.Map, error) {
+	errorCatalogMu.RLock()
+	def, ok := errorCatalog[action.ReturnError]
+	errorCatalogMu.RUnlock()
+	if !ok {
+		return fiber.Map{"error": "Internal error"}, newActionError("return", "returnError", fmt.Errorf("%w: %s", ErrUnknownErrorCode, action.ReturnError))
+	}
+
+	message := def.Message
+	if strings.HasPrefix(message, translatePrefix) {
+		message = translate(ctx, message, data)
+	} else {
+		message = InterpolateString(message, data)
+	}
+	response := fiber.Map{
+		"statusCode": def.Status,
+		"status":     "error",
+		"code":       def.Code,
+		"message":    message,
+	}
+	if action.ReturnData != nil {
+		response["details"] = SubstituteVariables(translateMessages(ctx, action.ReturnData, data), data)
+	}
+	log.Printf("DEBUG: Action 'return'. Returning catalog error %s (%d)", def.Code, def.Status)
+	return response, nil
+}
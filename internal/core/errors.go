@@ -0,0 +1,52 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FlowError is a runtime failure inside a conditional flow, annotated with where it happened.
+// Path uses the definition's JSON names, e.g. "then.conditionalFlow.else.transform[2]".
+type FlowError struct {
+	Path     string `json:"path"`
+	Action   string `json:"action,omitempty"`   // Action type that failed
+	Operator string `json:"operator,omitempty"` // Condition operator or transform operation involved
+	Field    string `json:"field,omitempty"`    // Field involved
+	Message  string `json:"message"`
+	Err      error  `json:"-"`
+}
+
+func (e *FlowError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s (at %s)", e.Message, e.Path)
+}
+
+func (e *FlowError) Unwrap() error {
+	return e.Err
+}
+
+// newActionError creates a FlowError for a failing action; the path is filled in
+// by withFlowPath as the error travels up the flow.
+func newActionError(action, field string, err error) *FlowError {
+	return &FlowError{Action: action, Field: field, Message: err.Error(), Err: err}
+}
+
+// withFlowPath prefixes the path of a FlowError with the segment of the enclosing element.
+// Plain errors are converted to a FlowError at that segment.
+func withFlowPath(err error, segment string) error {
+	if err == nil {
+		return nil
+	}
+	var fe *FlowError
+	if !errors.As(err, &fe) {
+		return &FlowError{Path: segment, Message: err.Error(), Err: err}
+	}
+	if fe.Path == "" {
+		fe.Path = segment
+	} else {
+		fe.Path = segment + "." + fe.Path
+	}
+	return fe
+}
@@ -0,0 +1,303 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fakePrefix starts a generated value in seed templates: "$fake.email", "$fake.int(1,100)".
+const fakePrefix = "$fake."
+
+// fakerKey is the schema annotation naming the generator of a property, e.g.
+// {"type": "string", "x-faker": "company"}.
+const fakerKey = "x-faker"
+
+var (
+	fakeFirstNames = []string{"Somchai", "Suda", "Anan", "Malee", "James", "Mary", "Robert", "Linda", "Wei", "Yuki", "Carlos", "Amara", "Nattapong", "Pimchanok", "Olivia", "Noah"}
+	fakeLastNames  = []string{"Srisuk", "Wongsawat", "Chaiyaporn", "Smith", "Johnson", "Brown", "Garcia", "Tanaka", "Chen", "Muller", "Rossi", "Silva", "Kowalski", "Okafor"}
+	fakeCompanies  = []string{"Acme", "Globex", "Initech", "Umbrella", "Stark Industries", "Wayne Enterprises", "Siam Trading", "Northwind", "Contoso", "Tyrell"}
+	fakeCities     = []string{"Bangkok", "Chiang Mai", "Phuket", "Khon Kaen", "Singapore", "Tokyo", "London", "Berlin", "New York", "Sydney", "Toronto", "Paris"}
+	fakeCountries  = []string{"Thailand", "Singapore", "Japan", "United Kingdom", "Germany", "United States", "Australia", "Canada", "France"}
+	fakeStreets    = []string{"Sukhumvit Road", "Silom Road", "Main Street", "High Street", "Park Avenue", "Station Road", "Rama IV Road", "Oak Lane"}
+	fakeWords      = []string{"alpha", "bright", "cloud", "delta", "ember", "field", "garden", "harbor", "island", "jade", "kite", "lotus", "meadow", "north", "ocean", "river", "stone", "tiger"}
+)
+
+// fakeValue runs the generator of kind; the kinds are available as "$fake.<kind>" and
+// as x-faker annotations.
+func fakeValue(kind string, args []string) (interface{}, error) {
+	switch kind {
+	case "name":
+		return pick(fakeFirstNames) + " " + pick(fakeLastNames), nil
+	case "firstName":
+		return pick(fakeFirstNames), nil
+	case "lastName":
+		return pick(fakeLastNames), nil
+	case "email":
+		return fmt.Sprintf("%s.%s%d@example.com", strings.ToLower(pick(fakeFirstNames)), strings.ToLower(pick(fakeLastNames)), rand.IntN(100)), nil
+	case "username":
+		return strings.ToLower(pick(fakeFirstNames)) + strconv.Itoa(rand.IntN(1000)), nil
+	case "phone":
+		return fmt.Sprintf("+66 8%d %03d %04d", rand.IntN(10), rand.IntN(1000), rand.IntN(10000)), nil
+	case "company":
+		return pick(fakeCompanies), nil
+	case "city":
+		return pick(fakeCities), nil
+	case "country":
+		return pick(fakeCountries), nil
+	case "address":
+		return fmt.Sprintf("%d %s, %s", 1+rand.IntN(999), pick(fakeStreets), pick(fakeCities)), nil
+	case "word":
+		return pick(fakeWords), nil
+	case "sentence":
+		return fakeSentence(), nil
+	case "uuid":
+		return fakeUUID(), nil
+	case "bool":
+		return rand.IntN(2) == 1, nil
+	case "date":
+		return fakeTime(-365, 0).Format("2006-01-02"), nil
+	case "dateTime", "pastDate":
+		return fakeTime(-365, 0).Format(time.RFC3339), nil
+	case "futureDate":
+		return fakeTime(1, 365).Format(time.RFC3339), nil
+	case "int":
+		lo, hi, err := fakeRange(args, 0, 1000)
+		if err != nil {
+			return nil, err
+		}
+		return int64(lo) + rand.Int64N(int64(hi-lo)+1), nil
+	case "float":
+		lo, hi, err := fakeRange(args, 0, 1000)
+		if err != nil {
+			return nil, err
+		}
+		return math.Round((lo+rand.Float64()*(hi-lo))*100) / 100, nil
+	case "oneOf":
+		if len(args) == 0 {
+			return nil, errors.New("oneOf requires values")
+		}
+		return pick(args), nil
+	}
+	return nil, fmt.Errorf("unknown fake value '%s'", kind)
+}
+
+// Fake generates a value from a "$fake.<kind>" reference or an x-faker annotation
+// ("email", "int(1,100)", "oneOf(pending,paid)").
+func Fake(ref string) (interface{}, error) {
+	spec := strings.TrimPrefix(ref, fakePrefix)
+	kind, args := spec, []string(nil)
+	if open := strings.IndexByte(spec, '('); open >= 0 && strings.HasSuffix(spec, ")") {
+		kind = spec[:open]
+		for _, arg := range strings.Split(spec[open+1:len(spec)-1], ",") {
+			if arg = strings.TrimSpace(arg); arg != "" {
+				args = append(args, arg)
+			}
+		}
+	}
+	return fakeValue(kind, args)
+}
+
+// FakeTemplate resolves the "$fake." references of a seed template, and "$index" to index.
+func FakeTemplate(template interface{}, index int) (interface{}, error) {
+	switch t := template.(type) {
+	case string:
+		if strings.HasPrefix(t, fakePrefix) {
+			return Fake(t)
+		}
+		if t == "$index" {
+			return index, nil
+		}
+		return t, nil
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			value, err := FakeTemplate(v, index)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			result[k] = value
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(t))
+		for i, v := range t {
+			value, err := FakeTemplate(v, index)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = value
+		}
+		return result, nil
+	}
+	return template, nil
+}
+
+// FakeFromSchema generates a value matching a JSON schema: x-faker annotations first,
+// then enum/const, format and the property name (e.g. "email", "city") pick realistic
+// values for the declared type.
+func FakeFromSchema(schema map[string]interface{}) (interface{}, error) {
+	return fakeSchemaValue(schema, "")
+}
+
+func fakeSchemaValue(schema map[string]interface{}, name string) (interface{}, error) {
+	if ref, ok := schema[fakerKey].(string); ok {
+		return Fake(ref)
+	}
+	if value, ok := schema["const"]; ok {
+		return value, nil
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[rand.IntN(len(enum))], nil
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		props, _ := schema["properties"].(map[string]interface{})
+		result := make(map[string]interface{}, len(props))
+		for prop, sub := range props {
+			subSchema, _ := sub.(map[string]interface{})
+			value, err := fakeSchemaValue(subSchema, prop)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", prop, err)
+			}
+			result[prop] = value
+		}
+		return result, nil
+	case "array":
+		items, _ := schema["items"].(map[string]interface{})
+		lo, hi := schemaInt(schema, "minItems", 1), schemaInt(schema, "maxItems", 3)
+		if hi < lo {
+			hi = lo
+		}
+		result := make([]interface{}, lo+rand.IntN(hi-lo+1))
+		for i := range result {
+			value, err := fakeSchemaValue(items, name)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = value
+		}
+		return result, nil
+	case "integer", "int", "long":
+		lo, hi := schemaInt(schema, "minimum", 0), schemaInt(schema, "maximum", 1000)
+		if hi < lo {
+			hi = lo
+		}
+		return int64(lo + rand.IntN(hi-lo+1)), nil
+	case "number", "double", "decimal":
+		return Fake(fmt.Sprintf("float(%d,%d)", schemaInt(schema, "minimum", 0), schemaInt(schema, "maximum", 1000)))
+	case "boolean", "bool":
+		return rand.IntN(2) == 1, nil
+	case "null":
+		return nil, nil
+	}
+	return fakeString(schema, name)
+}
+
+// fakeString picks a string generator from the format, then from the property name.
+func fakeString(schema map[string]interface{}, name string) (interface{}, error) {
+	switch format, _ := schema["format"].(string); format {
+	case "email":
+		return Fake("email")
+	case "date":
+		return Fake("date")
+	case "date-time":
+		return Fake("dateTime")
+	case "uuid":
+		return Fake("uuid")
+	case "uri", "url":
+		return "https://example.com/" + pick(fakeWords), nil
+	}
+	lower := strings.ToLower(name)
+	for _, hint := range []struct{ contains, kind string }{
+		{"email", "email"}, {"firstname", "firstName"}, {"lastname", "lastName"}, {"username", "username"},
+		{"company", "company"}, {"name", "name"}, {"phone", "phone"}, {"mobile", "phone"},
+		{"city", "city"}, {"country", "country"}, {"address", "address"}, {"description", "sentence"},
+	} {
+		if strings.Contains(lower, hint.contains) {
+			return Fake(hint.kind)
+		}
+	}
+	return Fake("word")
+}
+
+// schemaType returns the declared type (JSON schema "type" or MongoDB "bsonType"),
+// the first non-null one of a list.
+func schemaType(schema map[string]interface{}) string {
+	declared := schema["type"]
+	if declared == nil {
+		declared = schema["bsonType"]
+	}
+	switch t := declared.(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, v := range t {
+			if s, ok := v.(string); ok && s != "null" {
+				return s
+			}
+		}
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	return "string"
+}
+
+func schemaInt(schema map[string]interface{}, key string, fallback int) int {
+	if f, ok := convertToFloat64(schema[key]); ok {
+		return int(f)
+	}
+	return fallback
+}
+
+func pick(values []string) string {
+	return values[rand.IntN(len(values))]
+}
+
+// fakeUUID returns a random version 4 UUID.
+func fakeUUID() string {
+	var b [16]byte
+	for i := range b {
+		b[i] = byte(rand.IntN(256))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func fakeSentence() string {
+	words := make([]string, 4+rand.IntN(6))
+	for i := range words {
+		words[i] = pick(fakeWords)
+	}
+	sentence := strings.Join(words, " ")
+	return strings.ToUpper(sentence[:1]) + sentence[1:] + "."
+}
+
+// fakeTime returns a time between fromDays and toDays from now.
+func fakeTime(fromDays, toDays int) time.Time {
+	span := time.Duration(toDays-fromDays) * 24 * time.Hour
+	return time.Now().UTC().Add(time.Duration(fromDays)*24*time.Hour + time.Duration(rand.Int64N(int64(span)))).Truncate(time.Second)
+}
+
+// fakeRange reads the (min,max) arguments of int and float.
+func fakeRange(args []string, lo, hi float64) (float64, float64, error) {
+	if len(args) == 0 {
+		return lo, hi, nil
+	}
+	if len(args) != 2 {
+		return 0, 0, fmt.Errorf("expected (min,max), got %d arguments", len(args))
+	}
+	min, err1 := strconv.ParseFloat(args[0], 64)
+	max, err2 := strconv.ParseFloat(args[1], 64)
+	if err1 != nil || err2 != nil || max < min {
+		return 0, 0, fmt.Errorf("invalid range (%s,%s)", args[0], args[1])
+	}
+	return min, max, nil
+}
@@ -0,0 +1,159 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"api-genarator/internal/models"
+)
+
+// fileDrop renders data from the state as a file and uploads it to the configured SFTP
+// target. It returns what was written ({"target", "path", "bytes"}).
+func fileDrop(ctx context.Context, cfg *models.FileDropConfig, data map[string]interface{}) (map[string]interface{}, error) {
+	target, ok := currentSettings().SFTPTargets[cfg.Target]
+	if !ok {
+		return nil, fmt.Errorf("sftp target '%s' is not configured", cfg.Target)
+	}
+	var source interface{} = data
+	if cfg.Source != "" {
+		if source, ok = lookupField(data, strings.TrimPrefix(cfg.Source, "$")); !ok {
+			return nil, fmt.Errorf("source field '%s' not found", cfg.Source)
+		}
+	}
+	content, err := renderFile(cfg, source)
+	if err != nil {
+		return nil, err
+	}
+	name := InterpolateString(cfg.Path, data)
+	written, err := sftpUpload(ctx, target, name, content)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"target": cfg.Target, "path": written, "bytes": len(content)}, nil
+}
+
+// renderFile encodes the source value in the configured format.
+func renderFile(cfg *models.FileDropConfig, source interface{}) ([]byte, error) {
+	switch cfg.Format {
+	case "", "json":
+		return json.MarshalIndent(source, "", "  ")
+	case "csv":
+		return renderCSV(source, cfg.Fields)
+	case "xml":
+		root := defaultString(cfg.Root, "records")
+		var b bytes.Buffer
+		b.WriteString(xml.Header)
+		if err := writeXMLValue(&b, root, source); err != nil {
+			return nil, err
+		}
+		b.WriteString("\n")
+		return b.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported file format '%s'", cfg.Format)
+}
+
+// renderCSV writes one row per object. Columns default to the sorted keys of all rows.
+func renderCSV(source interface{}, fields []string) ([]byte, error) {
+	var rows []map[string]interface{}
+	switch v := source.(type) {
+	case []interface{}:
+		for i, item := range v {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("csv row %d is not an object", i)
+			}
+			rows = append(rows, row)
+		}
+	case map[string]interface{}:
+		rows = []map[string]interface{}{v}
+	default:
+		return nil, fmt.Errorf("csv needs an object or an array of objects, got %T", source)
+	}
+	if len(fields) == 0 {
+		seen := make(map[string]bool)
+		for _, row := range rows {
+			for k := range row {
+				if !seen[k] {
+					seen[k] = true
+					fields = append(fields, k)
+				}
+			}
+		}
+		sort.Strings(fields)
+	}
+
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	if err := w.Write(fields); err != nil {
+		return nil, err
+	}
+	record := make([]string, len(fields))
+	for _, row := range rows {
+		for i, f := range fields {
+			record[i] = csvCell(row[f])
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return b.Bytes(), w.Error()
+}
+
+func csvCell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		raw, _ := json.Marshal(v)
+		return string(raw)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// xmlName is the subset of XML names accepted as element names for data fields.
+var xmlName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// writeXMLValue writes value as element name: objects become child elements (sorted),
+// arrays repeat a "record" element, scalars become escaped text.
+func writeXMLValue(b *bytes.Buffer, name string, value interface{}) error {
+	fmt.Fprintf(b, "<%s>", name)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if !xmlName.MatchString(k) {
+				return fmt.Errorf("field '%s' is not a valid XML element name", k)
+			}
+			if err := writeXMLValue(b, k, v[k]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := writeXMLValue(b, "record", item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := xml.EscapeText(b, []byte(fmt.Sprintf("%v", v))); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(b, "</%s>", name)
+	return nil
+}
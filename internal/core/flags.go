@@ -0,0 +1,161 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"api-genarator/internal/models"
+)
+
+// FeatureFlagSettings selects the provider behind the "featureFlag" condition operator.
+// Without a ProviderURL the flags stored through /api-generator/flags are used.
+type FeatureFlagSettings struct {
+	ProviderURL   string // (Optional) Base URL of an OpenFeature Remote Evaluation Protocol (OFREP) service
+	ProviderToken string // (Optional) Bearer token for the provider
+	TenantField   string // Flow field holding the tenant (default "tenantId")
+}
+
+var (
+	flagMu       sync.RWMutex
+	featureFlags = map[string]models.FeatureFlag{}
+
+	// remoteFlags caches provider answers briefly so a busy flow does not call out per request.
+	remoteFlags sync.Map // "key|tenant" -> remoteFlag
+)
+
+const remoteFlagTTL = 10 * time.Second
+
+type remoteFlag struct {
+	value   interface{}
+	expires time.Time
+}
+
+// SetFeatureFlags replaces all internal flags (at startup and on each refresh).
+func SetFeatureFlags(flags []models.FeatureFlag) {
+	loaded := make(map[string]models.FeatureFlag, len(flags))
+	for _, f := range flags {
+		loaded[f.Name] = f
+	}
+	flagMu.Lock()
+	featureFlags = loaded
+	flagMu.Unlock()
+}
+
+// PutFeatureFlag installs or replaces one internal flag.
+func PutFeatureFlag(flag models.FeatureFlag) {
+	flagMu.Lock()
+	featureFlags[flag.Name] = flag
+	flagMu.Unlock()
+}
+
+// DropFeatureFlag removes one internal flag.
+func DropFeatureFlag(name string) {
+	flagMu.Lock()
+	delete(featureFlags, name)
+	flagMu.Unlock()
+}
+
+// RemoteFeatureFlags reports whether flags come from an external provider.
+func RemoteFeatureFlags() bool {
+	return currentSettings().FeatureFlags.ProviderURL != ""
+}
+
+// evaluateFeatureFlag returns the value of a flag for the current environment and the
+// tenant found in the flow data. Unknown flags are off.
+func evaluateFeatureFlag(key string, data map[string]interface{}) (interface{}, error) {
+	s := currentSettings()
+	tenant := ""
+	if v, ok := lookupField(data, defaultString(s.FeatureFlags.TenantField, "tenantId")); ok && v != nil {
+		tenant = fmt.Sprintf("%v", v)
+	}
+	if s.FeatureFlags.ProviderURL != "" {
+		return remoteFeatureFlag(s, key, tenant)
+	}
+
+	flagMu.RLock()
+	flag, exists := featureFlags[key]
+	flagMu.RUnlock()
+	if !exists {
+		log.Printf("WARN: Feature flag '%s' is not defined, treating it as off", key)
+		return false, nil
+	}
+	if on, ok := flag.Tenants[tenant]; ok && tenant != "" {
+		return on, nil
+	}
+	if on, ok := flag.Environments[s.Environment]; ok && s.Environment != "" {
+		return on, nil
+	}
+	return flag.Enabled, nil
+}
+
+// remoteFeatureFlag evaluates a flag through OFREP (POST /ofrep/v1/evaluate/flags/{key}).
+func remoteFeatureFlag(s Settings, key, tenant string) (interface{}, error) {
+	cacheKey := key + "|" + tenant
+	if cached, ok := remoteFlags.Load(cacheKey); ok && time.Now().Before(cached.(remoteFlag).expires) {
+		return cached.(remoteFlag).value, nil
+	}
+
+	evalContext := map[string]interface{}{"environment": s.Environment}
+	if tenant != "" {
+		evalContext["targetingKey"] = tenant
+		evalContext["tenant"] = tenant
+	}
+	body, _ := json.Marshal(map[string]interface{}{"context": evalContext})
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	endpoint := strings.TrimRight(s.FeatureFlags.ProviderURL, "/") + "/ofrep/v1/evaluate/flags/" + url.PathEscape(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.FeatureFlags.ProviderToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.FeatureFlags.ProviderToken)
+	}
+	resp, err := outboundClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("flag provider request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Value        interface{} `json:"value"`
+		ErrorCode    string      `json:"errorCode"`
+		ErrorDetails string      `json:"errorDetails"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("invalid flag provider response: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound || result.ErrorCode == "FLAG_NOT_FOUND":
+		log.Printf("WARN: Feature flag '%s' is not defined at the provider, treating it as off", key)
+		result.Value = false
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("flag provider returned %d: %s %s", resp.StatusCode, result.ErrorCode, result.ErrorDetails)
+	}
+	remoteFlags.Store(cacheKey, remoteFlag{value: result.Value, expires: time.Now().Add(remoteFlagTTL)})
+	return result.Value, nil
+}
+
+// featureFlagMatches implements the "featureFlag" operator: Field names the flag and
+// Value the expected state (true when omitted). Provider errors count as "off".
+func featureFlagMatches(condition models.Condition, data map[string]interface{}) bool {
+	value, err := evaluateFeatureFlag(condition.Field, data)
+	if err != nil {
+		log.Printf("ERROR: Feature flag '%s' could not be evaluated, treating it as off: %v", condition.Field, err)
+		value = false
+	}
+	expected := condition.Value
+	if expected == nil {
+		expected = true
+	}
+	return fmt.Sprintf("%v", value) == fmt.Sprintf("%v", expected)
+}
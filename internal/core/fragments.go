@@ -0,0 +1,153 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxFragmentDepth bounds fragments calling fragments, which also stops accidental recursion.
+const maxFragmentDepth = 8
+
+// fragmentTTL is how long a loaded fragment is reused (see mappingProfileTTL).
+const fragmentTTL = 30 * time.Second
+
+type fragmentDepthKey struct{}
+
+type cachedFragment struct {
+	fragment *models.FlowFragment
+	loadedAt time.Time
+}
+
+var (
+	fragmentMu    sync.Mutex
+	flowFragments = map[string]cachedFragment{}
+)
+
+func loadFragment(ctx context.Context, store *database.Store, name string) (*models.FlowFragment, error) {
+	fragmentMu.Lock()
+	cached, ok := flowFragments[name]
+	fragmentMu.Unlock()
+	if ok && time.Since(cached.loadedAt) <= fragmentTTL {
+		return cached.fragment, nil
+	}
+	if store == nil {
+		return nil, fmt.Errorf("flow fragment '%s' cannot be loaded without a database", name)
+	}
+	fragment, err := store.GetFlowFragment(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("flow fragment '%s': %w", name, err)
+	}
+	fragmentMu.Lock()
+	flowFragments[name] = cachedFragment{fragment: fragment, loadedAt: time.Now()}
+	fragmentMu.Unlock()
+	return fragment, nil
+}
+
+// ForgetFlowFragment drops a cached fragment after it was changed or deleted.
+func ForgetFlowFragment(name string) {
+	fragmentMu.Lock()
+	delete(flowFragments, name)
+	fragmentMu.Unlock()
+}
+
+// FlowCallsFragment reports whether a callFlow action anywhere in the flow, including
+// the onFailure-style branches, runs the named fragment.
+func FlowCallsFragment(block *models.ConditionalBlock, name string) bool {
+	if block == nil {
+		return false
+	}
+	for _, action := range []*models.ActionDefinition{block.Then, block.Else} {
+		if actionCallsFragment(action, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func actionCallsFragment(action *models.ActionDefinition, name string) bool {
+	if action == nil {
+		return false
+	}
+	if action.CallFlow != nil && action.CallFlow.Fragment == name {
+		return true
+	}
+	branches := []*models.ActionDefinition{}
+	if action.Captcha != nil {
+		branches = append(branches, action.Captcha.OnFailure)
+	}
+	if action.Payment != nil {
+		branches = append(branches, action.Payment.OnFailure)
+	}
+	if action.SMS != nil {
+		branches = append(branches, action.SMS.OnRateLimit)
+	}
+	if action.OTP != nil {
+		branches = append(branches, action.OTP.OnFailure)
+	}
+	if action.DbUpdate != nil {
+		branches = append(branches, action.DbUpdate.OnConflict)
+	}
+	for _, branch := range branches {
+		if actionCallsFragment(branch, name) {
+			return true
+		}
+	}
+	return FlowCallsFragment(action.ConditionalFlow, name)
+}
+
+// callFlow runs a fragment with the rendered parameters as its whole data state and
+// returns its response. failed is set when that response carries an error statusCode,
+// which the caller passes on instead of continuing. A saveData inside the fragment is
+// ignored: only the calling definition saves.
+func callFlow(ctx context.Context, cfg *models.CallFlowConfig, data map[string]interface{}, store *database.Store, dbName, collName string) (response interface{}, failed bool, err error) {
+	depth, _ := ctx.Value(fragmentDepthKey{}).(int)
+	if depth >= maxFragmentDepth {
+		return nil, false, newActionError("callFlow", "callFlow.fragment",
+			fmt.Errorf("flow fragments nested deeper than %d (recursive callFlow?)", maxFragmentDepth))
+	}
+	fragment, err := loadFragment(ctx, store, cfg.Fragment)
+	if err != nil {
+		return nil, false, newActionError("callFlow", "callFlow.fragment", err)
+	}
+
+	input := make(map[string]interface{}, len(cfg.Parameters))
+	for name, value := range cfg.Parameters {
+		input[name] = SubstituteVariables(value, data)
+	}
+	for _, name := range fragment.Parameters {
+		if input[name] == nil {
+			return nil, false, newActionError("callFlow", "callFlow.parameters."+name,
+				fmt.Errorf("flow fragment '%s' requires parameter '%s'", fragment.Name, name))
+		}
+	}
+
+	log.Printf("DEBUG: Calling flow fragment '%s' (depth %d)", fragment.Name, depth+1)
+	response, _, _, err = ProcessConditionalFlow(fragment.Flow, input, context.WithValue(ctx, fragmentDepthKey{}, depth+1), store, dbName, collName)
+	if err != nil {
+		return nil, false, withFlowPath(err, fmt.Sprintf("callFlow(%s)", fragment.Name))
+	}
+	return response, responseStatus(response) >= 400, nil
+}
+
+// responseStatus reads the statusCode a flow response asks for (0 if none).
+func responseStatus(response interface{}) int {
+	var m map[string]interface{}
+	switch r := response.(type) {
+	case fiber.Map:
+		m = r
+	case map[string]interface{}:
+		m = r
+	default:
+		return 0
+	}
+	code, _ := convertToFloat64(m["statusCode"])
+	return int(code)
+}
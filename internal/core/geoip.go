@@ -0,0 +1,390 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+
+	"api-genarator/internal/models"
+)
+
+// geoIPResult flattens the commonly used fields of GeoIP2/GeoLite2 City, Country and
+// ASN records. Fields missing from the databases are left out.
+func geoIPResult(ip netip.Addr, records []map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{"ip": ip.String(), "found": false}
+	for _, r := range records {
+		if r == nil {
+			continue
+		}
+		out["found"] = true
+		for field, path := range map[string]string{
+			"country":     "country.iso_code",
+			"countryName": "country.names.en",
+			"continent":   "continent.code",
+			"city":        "city.names.en",
+			"postalCode":  "postal.code",
+			"latitude":    "location.latitude",
+			"longitude":   "location.longitude",
+			"timeZone":    "location.time_zone",
+			"asn":         "autonomous_system_number",
+			"asOrg":       "autonomous_system_organization",
+		} {
+			if v, ok := lookupField(r, path); ok {
+				out[field] = v
+			}
+		}
+		if subdivisions, ok := r["subdivisions"].([]interface{}); ok && len(subdivisions) > 0 {
+			if first, ok := subdivisions[0].(map[string]interface{}); ok {
+				if v, ok := lookupField(first, "names.en"); ok {
+					out["region"] = v
+				}
+				if v, ok := first["iso_code"]; ok {
+					out["regionCode"] = v
+				}
+			}
+		}
+	}
+	return out
+}
+
+// geoIPLookup resolves an address in every configured database (e.g. City and ASN).
+func geoIPLookup(ip string) (map[string]interface{}, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return nil, fmt.Errorf("'%s' is not an IP address", ip)
+	}
+	addr = addr.Unmap()
+	paths := currentSettings().GeoIPDatabases
+	if len(paths) == 0 {
+		return nil, errors.New("no GeoIP database is configured")
+	}
+	records := make([]map[string]interface{}, 0, len(paths))
+	for _, path := range paths {
+		db, err := openGeoIPDatabase(path)
+		if err != nil {
+			return nil, err
+		}
+		record, err := db.lookup(addr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		records = append(records, record)
+	}
+	return geoIPResult(addr, records), nil
+}
+
+var (
+	geoIPMu        sync.Mutex
+	geoIPDatabases = map[string]*mmdbReader{}
+)
+
+// openGeoIPDatabase loads a database file once and keeps it in memory.
+func openGeoIPDatabase(path string) (*mmdbReader, error) {
+	geoIPMu.Lock()
+	defer geoIPMu.Unlock()
+	if db, ok := geoIPDatabases[path]; ok {
+		return db, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("open GeoIP database: %w", err)
+	}
+	db, err := newMMDBReader(raw)
+	if err != nil {
+		return nil, fmt.Errorf("GeoIP database %s: %w", path, err)
+	}
+	log.Printf("INFO: Loaded GeoIP database %s (%s, %d nodes)", path, db.databaseType, db.nodeCount)
+	geoIPDatabases[path] = db
+	return db, nil
+}
+
+// --- MaxMind DB format (https://maxmind.github.io/MaxMind-DB/) ---
+
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+type mmdbReader struct {
+	buf          []byte
+	data         []byte // Data section
+	nodeCount    uint
+	recordSize   uint
+	ipVersion    uint
+	databaseType string
+	ipv4Start    uint // Node reached after the 96 zero bits of ::/96
+}
+
+func newMMDBReader(buf []byte) (*mmdbReader, error) {
+	start := bytes.LastIndex(buf, mmdbMetadataMarker)
+	if start < 0 {
+		return nil, errors.New("not a MaxMind DB file (metadata marker missing)")
+	}
+	meta, _, err := (&mmdbReader{data: buf[start+len(mmdbMetadataMarker):]}).decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	metaMap, _ := meta.(map[string]interface{})
+	r := &mmdbReader{buf: buf}
+	r.nodeCount = metaUint(metaMap["node_count"])
+	r.recordSize = metaUint(metaMap["record_size"])
+	r.ipVersion = metaUint(metaMap["ip_version"])
+	r.databaseType, _ = metaMap["database_type"].(string)
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("unsupported record size %d", r.recordSize)
+	}
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+16 > uint(start) {
+		return nil, errors.New("search tree exceeds the file")
+	}
+	r.data = buf[treeSize+16 : start]
+
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.readNode(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+func metaUint(v interface{}) uint {
+	switch n := v.(type) {
+	case uint64:
+		return uint(n)
+	case *big.Int:
+		return uint(n.Uint64())
+	}
+	return 0
+}
+
+// lookup walks the search tree and returns the record of the address, nil if there is none.
+func (r *mmdbReader) lookup(addr netip.Addr) (map[string]interface{}, error) {
+	var bits []byte
+	node := uint(0)
+	if addr.Is4() {
+		a := addr.As4()
+		bits = a[:]
+		if r.ipVersion == 6 {
+			node = r.ipv4Start
+		}
+	} else {
+		if r.ipVersion == 4 {
+			return nil, nil // IPv6 address in an IPv4-only database
+		}
+		a := addr.As16()
+		bits = a[:]
+	}
+	for i := 0; i < len(bits)*8 && node < r.nodeCount; i++ {
+		bit := uint(bits[i/8]>>(7-i%8)) & 1
+		node = r.readNode(node, bit)
+	}
+	if node == r.nodeCount {
+		return nil, nil // Not in the database
+	}
+	if node < r.nodeCount {
+		return nil, errors.New("search tree ended inside a node")
+	}
+	offset := node - r.nodeCount - 16
+	value, _, err := r.decode(offset)
+	if err != nil {
+		return nil, err
+	}
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("record is not a map")
+	}
+	return record, nil
+}
+
+// readNode returns the left (bit 0) or right (bit 1) record of a node.
+func (r *mmdbReader) readNode(node, bit uint) uint {
+	b := r.buf[node*r.recordSize/4:]
+	switch r.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// Data section field types.
+const (
+	mmdbExtended = 0
+	mmdbPointer  = 1
+	mmdbString   = 2
+	mmdbDouble   = 3
+	mmdbBytes    = 4
+	mmdbUint16   = 5
+	mmdbUint32   = 6
+	mmdbMap      = 7
+	mmdbInt32    = 8
+	mmdbUint64   = 9
+	mmdbUint128  = 10
+	mmdbArray    = 11
+	mmdbBool     = 14
+	mmdbFloat    = 15
+)
+
+// decode reads the value at offset in the data section and returns it with the offset
+// of the next field. Maps and arrays are decoded recursively; pointers are followed.
+func (r *mmdbReader) decode(offset uint) (interface{}, uint, error) {
+	if offset >= uint(len(r.data)) {
+		return nil, 0, errors.New("offset outside the data section")
+	}
+	ctrl := r.data[offset]
+	offset++
+	kind := uint(ctrl >> 5)
+
+	if kind == mmdbPointer {
+		size := uint(ctrl>>3) & 0x3
+		if offset+size+1 > uint(len(r.data)) {
+			return nil, 0, errors.New("truncated pointer")
+		}
+		b := r.data[offset : offset+size+1]
+		var target uint
+		switch size {
+		case 0:
+			target = uint(ctrl&0x7)<<8 | uint(b[0])
+		case 1:
+			target = (uint(ctrl&0x7)<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+		case 2:
+			target = (uint(ctrl&0x7)<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+		case 3:
+			target = uint(binary.BigEndian.Uint32(b))
+		}
+		value, _, err := r.decode(target)
+		return value, offset + size + 1, err
+	}
+
+	if kind == mmdbExtended {
+		if offset >= uint(len(r.data)) {
+			return nil, 0, errors.New("truncated extended type")
+		}
+		kind = 7 + uint(r.data[offset])
+		offset++
+	}
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(r.data)) {
+			return nil, 0, errors.New("truncated size")
+		}
+		var extra uint
+		for _, c := range r.data[offset : offset+n] {
+			extra = extra<<8 | uint(c)
+		}
+		offset += n
+		switch size {
+		case 29:
+			size = 29 + extra
+		case 30:
+			size = 285 + extra
+		default:
+			size = 65821 + extra
+		}
+	}
+
+	switch kind {
+	case mmdbMap:
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := r.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			value, after, err := r.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			k, _ := key.(string)
+			m[k] = value
+			offset = after
+		}
+		return m, offset, nil
+	case mmdbArray:
+		list := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := r.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			list = append(list, value)
+			offset = next
+		}
+		return list, offset, nil
+	case mmdbBool:
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(r.data)) {
+		return nil, 0, errors.New("field exceeds the data section")
+	}
+	b := r.data[offset : offset+size]
+	next := offset + size
+	switch kind {
+	case mmdbString:
+		return string(b), next, nil
+	case mmdbBytes:
+		return append([]byte(nil), b...), next, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, errors.New("invalid double size")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case mmdbFloat:
+		if size != 4 {
+			return nil, 0, errors.New("invalid float size")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64:
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, next, nil
+	case mmdbInt32:
+		var n uint32
+		for _, c := range b {
+			n = n<<8 | uint32(c)
+		}
+		return int64(int32(n)), next, nil
+	case mmdbUint128:
+		return new(big.Int).SetBytes(b), next, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported data type %d", kind)
+}
+
+// geoIPTransform implements the "geoip" transformation: the address comes from the
+// Value ("$field") or, by default, the client of the inbound request.
+func geoIPTransform(ctx context.Context, t models.Transformation, data map[string]interface{}) (map[string]interface{}, bool) {
+	var ip string
+	if t.Value != nil {
+		ip, _ = SubstituteVariables(t.Value, data).(string)
+	} else if inbound := inboundFrom(ctx); inbound != nil {
+		ip = inbound.clientIP
+	}
+	if ip == "" {
+		log.Printf("WARN: geoip transform for '%s' has no IP address (no client request or empty source field)", t.Field)
+		return nil, false
+	}
+	result, err := geoIPLookup(ip)
+	if err != nil {
+		log.Printf("WARN: geoip transform for '%s' failed: %v", t.Field, err)
+		return nil, false
+	}
+	return result, true
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"api-genarator/internal/models"
+)
+
+// graphqlResponse is the standard GraphQL response envelope.
+type graphqlResponse struct {
+	Data   interface{} `json:"data"`
+	Errors []struct {
+		Message string        `json:"message"`
+		Path    []interface{} `json:"path,omitempty"`
+	} `json:"errors,omitempty"`
+}
+
+// graphqlCall posts the configured query with variables from the data state and returns
+// the response data, narrowed to cfg.Extract. Errors alongside data (partial results)
+// are logged; errors without data fail the action.
+func graphqlCall(ctx context.Context, cfg *models.GraphQLConfig, data map[string]interface{}) (interface{}, error) {
+	payload := map[string]interface{}{"query": InterpolateString(cfg.Query, data)}
+	if cfg.OperationName != "" {
+		payload["operationName"] = cfg.OperationName
+	}
+	if cfg.Variables != nil {
+		payload["variables"] = SubstituteVariables(cfg.Variables, data)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode GraphQL request: %w", err)
+	}
+
+	endpoint := resolveEndpoint(ctx, InterpolateString(cfg.Endpoint, data))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/graphql-response+json, application/json")
+	for name, value := range cfg.Headers {
+		req.Header.Set(name, InterpolateString(value, data))
+	}
+
+	resp, err := outboundClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GraphQL request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result graphqlResponse
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, 10<<20)).Decode(&result)
+	if resp.StatusCode >= http.StatusBadRequest && (decodeErr != nil || len(result.Errors) == 0) {
+		return nil, fmt.Errorf("GraphQL endpoint returned %s", resp.Status)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to decode GraphQL response: %w", decodeErr)
+	}
+	if len(result.Errors) > 0 {
+		messages := make([]string, len(result.Errors))
+		for i, e := range result.Errors {
+			messages[i] = e.Message
+		}
+		if result.Data == nil {
+			return nil, fmt.Errorf("GraphQL errors: %s", strings.Join(messages, "; "))
+		}
+		log.Printf("WARN: GraphQL call returned partial data with errors: %s", strings.Join(messages, "; "))
+	}
+
+	if cfg.Extract == "" {
+		return result.Data, nil
+	}
+	dataMap, _ := result.Data.(map[string]interface{})
+	value, ok := lookupField(dataMap, cfg.Extract)
+	if !ok {
+		return nil, fmt.Errorf("GraphQL response has no field '%s'", cfg.Extract)
+	}
+	return value, nil
+}
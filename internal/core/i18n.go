@@ -0,0 +1,191 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"api-genarator/internal/models"
+)
+
+// I18nSettings controls how "$t(...)" picks a locale.
+type I18nSettings struct {
+	DefaultLocale string // Used when the request asks for no available locale (default "en")
+	LocaleField   string // Flow field that overrides Accept-Language (default "locale")
+}
+
+// translatePrefix starts a message reference: $t("order.created", {"id": "$orderId"}).
+const translatePrefix = "$t("
+
+var (
+	catalogMu       sync.RWMutex
+	messageCatalogs = map[string]map[string]string{} // locale -> key -> message
+)
+
+// SetMessageCatalogs replaces all message catalogs (at startup and on each refresh).
+func SetMessageCatalogs(catalogs []models.MessageCatalog) {
+	loaded := make(map[string]map[string]string, len(catalogs))
+	for _, c := range catalogs {
+		loaded[strings.ToLower(c.Locale)] = c.Messages
+	}
+	catalogMu.Lock()
+	messageCatalogs = loaded
+	catalogMu.Unlock()
+}
+
+// PutMessageCatalog installs or replaces the catalog of one locale.
+func PutMessageCatalog(catalog models.MessageCatalog) {
+	catalogMu.Lock()
+	messageCatalogs[strings.ToLower(catalog.Locale)] = catalog.Messages
+	catalogMu.Unlock()
+}
+
+// DropMessageCatalog removes the catalog of one locale.
+func DropMessageCatalog(locale string) {
+	catalogMu.Lock()
+	delete(messageCatalogs, strings.ToLower(locale))
+	catalogMu.Unlock()
+}
+
+// parseTranslateCall splits `$t("key", {...})` into the key and the parameters.
+// The arguments are read as a JSON array, so keys and parameters use JSON syntax.
+func parseTranslateCall(ref string) (key string, params map[string]interface{}, err error) {
+	if !strings.HasPrefix(ref, translatePrefix) || !strings.HasSuffix(ref, ")") {
+		return "", nil, fmt.Errorf("'%s' is not a $t(...) reference", ref)
+	}
+	var args []interface{}
+	if err := json.Unmarshal([]byte("["+ref[len(translatePrefix):len(ref)-1]+"]"), &args); err != nil {
+		return "", nil, fmt.Errorf("invalid $t arguments in '%s': %w", ref, err)
+	}
+	if len(args) == 0 || len(args) > 2 {
+		return "", nil, fmt.Errorf("$t takes a key and optional parameters: '%s'", ref)
+	}
+	if key, _ = args[0].(string); key == "" {
+		return "", nil, fmt.Errorf("$t key must be a non-empty string: '%s'", ref)
+	}
+	if len(args) == 2 {
+		if params, _ = args[1].(map[string]interface{}); params == nil {
+			return "", nil, fmt.Errorf("$t parameters must be an object: '%s'", ref)
+		}
+	}
+	return key, params, nil
+}
+
+// requestLocales lists the locales a request asks for, most preferred first: the
+// locale field of the flow data, then Accept-Language by quality.
+func requestLocales(ctx context.Context, data map[string]interface{}, s I18nSettings) []string {
+	var locales []string
+	if v, ok := lookupField(data, defaultString(s.LocaleField, "locale")); ok {
+		if locale, _ := v.(string); locale != "" {
+			locales = append(locales, locale)
+		}
+	}
+	if inbound := inboundFrom(ctx); inbound != nil {
+		locales = append(locales, parseAcceptLanguage(inbound.header("Accept-Language"))...)
+	}
+	return append(locales, defaultString(s.DefaultLocale, "en"))
+}
+
+// parseAcceptLanguage returns the tags of an Accept-Language header sorted by q-value.
+func parseAcceptLanguage(header string) []string {
+	type tag struct {
+		name string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if name == "" || name == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tag{name, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.name
+	}
+	return names
+}
+
+// lookupMessage finds key in the first locale that has it; "th-TH" falls back to "th".
+func lookupMessage(locales []string, key string) (string, bool) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	for _, locale := range locales {
+		locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+		for {
+			if message, ok := messageCatalogs[locale][key]; ok {
+				return message, true
+			}
+			i := strings.LastIndex(locale, "-")
+			if i < 0 {
+				break
+			}
+			locale = locale[:i]
+		}
+	}
+	return "", false
+}
+
+// translate resolves one $t(...) reference. {{name}} placeholders in the message are
+// filled from the parameters (whose "$field" values are substituted) and then the flow data.
+// A missing message renders as its key so the gap is visible instead of an empty string.
+func translate(ctx context.Context, ref string, data map[string]interface{}) string {
+	key, params, err := parseTranslateCall(ref)
+	if err != nil {
+		log.Printf("WARN: %v", err)
+		return ref
+	}
+	message, ok := lookupMessage(requestLocales(ctx, data, currentSettings().I18n), key)
+	if !ok {
+		log.Printf("WARN: No message '%s' in any requested locale, returning the key", key)
+		return key
+	}
+	scope := data
+	if len(params) > 0 {
+		scope = copyData(data)
+		for name, value := range params {
+			scope[name] = SubstituteVariables(value, data)
+		}
+	}
+	return InterpolateString(message, scope)
+}
+
+// translateMessages replaces every $t(...) string in a template (e.g. return data) with
+// the localized message. It runs before SubstituteVariables, which would otherwise read
+// "$t(...)" as a field reference.
+func translateMessages(ctx context.Context, template interface{}, data map[string]interface{}) interface{} {
+	switch t := template.(type) {
+	case string:
+		if strings.HasPrefix(t, translatePrefix) {
+			return translate(ctx, t, data)
+		}
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			out[k] = translateMessages(ctx, v, data)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, v := range t {
+			out[i] = translateMessages(ctx, v, data)
+		}
+		return out
+	}
+	return template
+}
@@ -0,0 +1,671 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// jsonPath is a compiled JSONPath expression. Supported syntax:
+//
+//	$.a.b  $['a']  $.items[0]  $.items[-1]  $.items[*]  $.items[1:3]  $.items[0,2]
+//	$..name  $.items[?(@.price < 10 && @.tags[0] == 'new')]  $.items[?(@.sku =~ '^A')]
+//
+// jq-style paths (".items[].name") and bare dotted paths ("items[0].name") are accepted too.
+type jsonPath struct {
+	segments []jpSegment
+	definite bool // Only names and indices: the result is one value, not a list
+}
+
+type jpSegment struct {
+	recursive bool // ".." descends into every nested value first
+	selectors []jpSelector
+}
+
+type jpSelectorKind int
+
+const (
+	jpName jpSelectorKind = iota
+	jpIndex
+	jpWildcard
+	jpSlice
+	jpFilter
+)
+
+type jpSelector struct {
+	kind             jpSelectorKind
+	name             string
+	index            int
+	start, end, step *int
+	filter           jpExpr
+}
+
+var jsonPathCache sync.Map // expression -> *jsonPath
+
+// compileJSONPath parses an expression, caching the result.
+func compileJSONPath(expr string) (*jsonPath, error) {
+	if cached, ok := jsonPathCache.Load(expr); ok {
+		return cached.(*jsonPath), nil
+	}
+	p := &jpParser{s: strings.TrimSpace(expr)}
+	switch {
+	case strings.HasPrefix(p.s, "$"):
+		p.pos = 1
+	case strings.HasPrefix(p.s, "."), strings.HasPrefix(p.s, "["):
+		// jq style, relative to the root
+	default:
+		p.s = "." + p.s
+	}
+	path, err := p.parsePath(false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path '%s': %w", expr, err)
+	}
+	if p.pos < len(p.s) {
+		return nil, fmt.Errorf("invalid path '%s': unexpected '%s' at %d", expr, p.s[p.pos:], p.pos)
+	}
+	jsonPathCache.Store(expr, path)
+	return path, nil
+}
+
+// evaluate returns every value the path selects, in document order (map keys sorted).
+func (p *jsonPath) evaluate(root interface{}) []interface{} {
+	nodes := []interface{}{root}
+	for _, seg := range p.segments {
+		var next []interface{}
+		for _, node := range nodes {
+			if seg.recursive {
+				jpDescend(node, func(n interface{}) { next = append(next, seg.apply(n, root)...) })
+			} else {
+				next = append(next, seg.apply(node, root)...)
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// first returns the first selected value.
+func (p *jsonPath) first(root interface{}) (interface{}, bool) {
+	if matches := p.evaluate(root); len(matches) > 0 {
+		return matches[0], true
+	}
+	return nil, false
+}
+
+func (seg jpSegment) apply(node, root interface{}) []interface{} {
+	var out []interface{}
+	for _, sel := range seg.selectors {
+		switch sel.kind {
+		case jpName:
+			if m, ok := jpMap(node); ok {
+				if v, found := m[sel.name]; found {
+					out = append(out, v)
+				}
+			}
+		case jpIndex:
+			if list, ok := jpList(node); ok {
+				i := sel.index
+				if i < 0 {
+					i += len(list)
+				}
+				if i >= 0 && i < len(list) {
+					out = append(out, list[i])
+				}
+			}
+		case jpWildcard:
+			out = append(out, jpChildren(node)...)
+		case jpSlice:
+			if list, ok := jpList(node); ok {
+				out = append(out, jpSliceOf(list, sel)...)
+			}
+		case jpFilter:
+			for _, child := range jpChildren(node) {
+				if jpTest(sel.filter, child, root) {
+					out = append(out, child)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func jpMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case primitive.M:
+		return m, true
+	}
+	return nil, false
+}
+
+func jpList(v interface{}) ([]interface{}, bool) {
+	switch l := v.(type) {
+	case []interface{}:
+		return l, true
+	case primitive.A:
+		return l, true
+	}
+	return nil, false
+}
+
+func jpChildren(node interface{}) []interface{} {
+	if list, ok := jpList(node); ok {
+		return list
+	}
+	m, ok := jpMap(node)
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, m[k])
+	}
+	return out
+}
+
+// jpDescend visits node and everything nested in it.
+func jpDescend(node interface{}, visit func(interface{})) {
+	visit(node)
+	for _, child := range jpChildren(node) {
+		jpDescend(child, visit)
+	}
+}
+
+// jpSliceOf applies [start:end:step] with Python semantics.
+func jpSliceOf(list []interface{}, sel jpSelector) []interface{} {
+	n := len(list)
+	step := 1
+	if sel.step != nil {
+		step = *sel.step
+	}
+	if step == 0 {
+		return nil
+	}
+	bound := func(p *int, def int) int {
+		if p == nil {
+			return def
+		}
+		i := *p
+		if i < 0 {
+			i += n
+		}
+		if step > 0 {
+			return min(max(i, 0), n)
+		}
+		return min(max(i, -1), n-1)
+	}
+	var out []interface{}
+	if step > 0 {
+		for i := bound(sel.start, 0); i < bound(sel.end, n); i += step {
+			out = append(out, list[i])
+		}
+	} else {
+		for i := bound(sel.start, n-1); i > bound(sel.end, -1); i += step {
+			out = append(out, list[i])
+		}
+	}
+	return out
+}
+
+// --- Filter expressions ---
+
+// jpExpr is a node of a filter expression. eval returns false as its second value
+// when a path selects nothing.
+type jpExpr interface {
+	eval(current, root interface{}) (interface{}, bool)
+}
+
+type jpPathExpr struct {
+	relative bool // "@" rather than "$"
+	path     *jsonPath
+}
+
+func (e jpPathExpr) eval(current, root interface{}) (interface{}, bool) {
+	if e.relative {
+		return e.path.first(current)
+	}
+	return e.path.first(root)
+}
+
+type jpLiteral struct{ value interface{} }
+
+func (e jpLiteral) eval(_, _ interface{}) (interface{}, bool) { return e.value, true }
+
+type jpNot struct{ x jpExpr }
+
+func (e jpNot) eval(current, root interface{}) (interface{}, bool) {
+	return !jpTest(e.x, current, root), true
+}
+
+type jpLogical struct {
+	and         bool
+	left, right jpExpr
+}
+
+func (e jpLogical) eval(current, root interface{}) (interface{}, bool) {
+	left := jpTest(e.left, current, root)
+	if e.and {
+		return left && jpTest(e.right, current, root), true
+	}
+	return left || jpTest(e.right, current, root), true
+}
+
+type jpCompare struct {
+	op          string
+	left, right jpExpr
+	re          *regexp.Regexp // For "=~"
+}
+
+func (e jpCompare) eval(current, root interface{}) (interface{}, bool) {
+	l, lok := e.left.eval(current, root)
+	r, rok := e.right.eval(current, root)
+	if !lok || !rok {
+		return e.op == "!=" && lok != rok, true
+	}
+	switch e.op {
+	case "==":
+		return jpEqual(l, r), true
+	case "!=":
+		return !jpEqual(l, r), true
+	case "=~":
+		s, ok := l.(string)
+		return ok && e.re.MatchString(s), true
+	}
+	c, ok := jpOrder(l, r)
+	if !ok {
+		return false, true
+	}
+	switch e.op {
+	case "<":
+		return c < 0, true
+	case "<=":
+		return c <= 0, true
+	case ">":
+		return c > 0, true
+	default:
+		return c >= 0, true
+	}
+}
+
+// jpTest is the truth of a filter: paths test for existence, everything else must be true.
+func jpTest(e jpExpr, current, root interface{}) bool {
+	v, ok := e.eval(current, root)
+	if _, isPath := e.(jpPathExpr); isPath {
+		return ok
+	}
+	return ok && v == true
+}
+
+func jpNumber(v interface{}) (float64, bool) {
+	if _, isString := v.(string); isString {
+		return 0, false
+	}
+	return convertToFloat64(v)
+}
+
+func jpEqual(a, b interface{}) bool {
+	if x, ok := jpNumber(a); ok {
+		y, ok := jpNumber(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func jpOrder(a, b interface{}) (int, bool) {
+	if x, ok := jpNumber(a); ok {
+		if y, ok := jpNumber(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+		return 0, false
+	}
+	x, ok1 := a.(string)
+	y, ok2 := b.(string)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
+
+// --- Parser ---
+
+type jpParser struct {
+	s   string
+	pos int
+}
+
+func (p *jpParser) peek(prefix string) bool { return strings.HasPrefix(p.s[p.pos:], prefix) }
+
+func (p *jpParser) skipSpaces() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// parsePath reads segments until a character that cannot continue a path. Inside
+// filters (nested) it stops at operators and closing brackets.
+func (p *jpParser) parsePath(nested bool) (*jsonPath, error) {
+	path := &jsonPath{definite: true}
+	for p.pos < len(p.s) {
+		var seg jpSegment
+		switch {
+		case p.peek(".."):
+			p.pos += 2
+			seg.recursive = true
+			path.definite = false
+			if !p.peek("[") {
+				sel, err := p.parseDotSelector()
+				if err != nil {
+					return nil, err
+				}
+				seg.selectors = []jpSelector{sel}
+				break
+			}
+			fallthrough
+		case p.peek("["):
+			sels, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			seg.selectors = sels
+		case p.peek("."):
+			p.pos++
+			if p.peek("[") {
+				continue // jq ".[0]"
+			}
+			if p.pos == len(p.s) || (nested && strings.ContainsRune(" )]=!<>&|,", rune(p.s[p.pos]))) {
+				continue // jq "." is the root itself
+			}
+			sel, err := p.parseDotSelector()
+			if err != nil {
+				return nil, err
+			}
+			seg.selectors = []jpSelector{sel}
+		default:
+			if nested {
+				return path, nil
+			}
+			return nil, fmt.Errorf("unexpected '%c' at %d", p.s[p.pos], p.pos)
+		}
+		if len(seg.selectors) != 1 || (seg.selectors[0].kind != jpName && seg.selectors[0].kind != jpIndex) {
+			path.definite = false
+		}
+		path.segments = append(path.segments, seg)
+	}
+	return path, nil
+}
+
+func (p *jpParser) parseDotSelector() (jpSelector, error) {
+	if p.peek("*") {
+		p.pos++
+		return jpSelector{kind: jpWildcard}, nil
+	}
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(".[]()=!<>&|, '\"", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	if start == p.pos {
+		return jpSelector{}, fmt.Errorf("missing name at %d", start)
+	}
+	return jpSelector{kind: jpName, name: p.s[start:p.pos]}, nil
+}
+
+// parseBracket reads "[...]": names, indices, slices, "*", a filter, or jq's "[]".
+func (p *jpParser) parseBracket() ([]jpSelector, error) {
+	p.pos++ // "["
+	p.skipSpaces()
+	if p.peek("]") {
+		p.pos++
+		return []jpSelector{{kind: jpWildcard}}, nil
+	}
+	if p.peek("?") {
+		p.pos++
+		p.skipSpaces()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if !p.peek("]") {
+			return nil, fmt.Errorf("missing ']' after filter at %d", p.pos)
+		}
+		p.pos++
+		return []jpSelector{{kind: jpFilter, filter: expr}}, nil
+	}
+
+	var sels []jpSelector
+	for {
+		p.skipSpaces()
+		switch {
+		case p.peek("'") || p.peek(`"`):
+			name, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			sels = append(sels, jpSelector{kind: jpName, name: name})
+		case p.peek("*"):
+			p.pos++
+			sels = append(sels, jpSelector{kind: jpWildcard})
+		default:
+			sel, err := p.parseIndexOrSlice()
+			if err != nil {
+				return nil, err
+			}
+			sels = append(sels, sel)
+		}
+		p.skipSpaces()
+		if p.peek(",") {
+			p.pos++
+			continue
+		}
+		if p.peek("]") {
+			p.pos++
+			return sels, nil
+		}
+		return nil, fmt.Errorf("expected ',' or ']' at %d", p.pos)
+	}
+}
+
+func (p *jpParser) parseIndexOrSlice() (jpSelector, error) {
+	var parts [3]*int
+	n := 0
+	for {
+		p.skipSpaces()
+		start := p.pos
+		if p.peek("-") {
+			p.pos++
+		}
+		for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+			p.pos++
+		}
+		if start != p.pos {
+			i, err := strconv.Atoi(p.s[start:p.pos])
+			if err != nil {
+				return jpSelector{}, fmt.Errorf("invalid index at %d", start)
+			}
+			parts[n] = &i
+		}
+		p.skipSpaces()
+		if !p.peek(":") || n == 2 {
+			break
+		}
+		p.pos++
+		n++
+	}
+	if n == 0 {
+		if parts[0] == nil {
+			return jpSelector{}, fmt.Errorf("invalid selector at %d", p.pos)
+		}
+		return jpSelector{kind: jpIndex, index: *parts[0]}, nil
+	}
+	return jpSelector{kind: jpSlice, start: parts[0], end: parts[1], step: parts[2]}, nil
+}
+
+func (p *jpParser) parseString() (string, error) {
+	quote := p.s[p.pos]
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == '\\' && p.pos < len(p.s):
+			b.WriteByte(p.s[p.pos])
+			p.pos++
+		case c == quote:
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+func (p *jpParser) parseOr() (jpExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.skipSpaces(); p.peek("||"); p.skipSpaces() {
+		p.pos += 2
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = jpLogical{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *jpParser) parseAnd() (jpExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.skipSpaces(); p.peek("&&"); p.skipSpaces() {
+		p.pos += 2
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = jpLogical{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *jpParser) parseUnary() (jpExpr, error) {
+	p.skipSpaces()
+	if p.peek("!") && !p.peek("!=") {
+		p.pos++
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return jpNot{x}, nil
+	}
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	for _, op := range []string{"==", "!=", "<=", ">=", "=~", "<", ">"} {
+		if !p.peek(op) {
+			continue
+		}
+		p.pos += len(op)
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		cmp := jpCompare{op: op, left: left, right: right}
+		if op == "=~" {
+			lit, ok := right.(jpLiteral)
+			pattern, isString := lit.value.(string)
+			if !ok || !isString {
+				return nil, fmt.Errorf("'=~' needs a quoted regular expression")
+			}
+			if cmp.re, err = regexp.Compile(pattern); err != nil {
+				return nil, err
+			}
+		}
+		return cmp, nil
+	}
+	return left, nil
+}
+
+func (p *jpParser) parsePrimary() (jpExpr, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.s) {
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+	switch c := p.s[p.pos]; {
+	case c == '(':
+		p.pos++
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if !p.peek(")") {
+			return nil, fmt.Errorf("missing ')' at %d", p.pos)
+		}
+		p.pos++
+		return x, nil
+	case c == '@' || c == '$':
+		p.pos++
+		path, err := p.parsePath(true)
+		if err != nil {
+			return nil, err
+		}
+		return jpPathExpr{relative: c == '@', path: path}, nil
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		return jpLiteral{s}, err
+	}
+	for word, value := range map[string]interface{}{"true": true, "false": false, "null": nil} {
+		if p.peek(word) {
+			p.pos += len(word)
+			return jpLiteral{value}, nil
+		}
+	}
+	start := p.pos
+	for p.pos < len(p.s) && strings.ContainsRune("+-.0123456789eE", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	f, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected '%s' in filter at %d", p.s[start:], start)
+	}
+	return jpLiteral{f}, nil
+}
+
+// jsonPathRoot returns the top-level field a path starts with, for lint ("" when it
+// starts with a wildcard or descends recursively).
+func jsonPathRoot(expr string) string {
+	path, err := compileJSONPath(expr)
+	if err != nil || len(path.segments) == 0 {
+		return ""
+	}
+	seg := path.segments[0]
+	if seg.recursive || len(seg.selectors) != 1 || seg.selectors[0].kind != jpName {
+		return ""
+	}
+	return seg.selectors[0].name
+}
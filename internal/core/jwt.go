@@ -0,0 +1,80 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"api-genarator/internal/auth"
+	"api-genarator/internal/models"
+)
+
+const defaultJwtTTL = time.Hour
+
+// signJwt mints a token whose claims are rendered from the data state.
+// Registered claims iat/exp (and iss when configured) are added automatically.
+func signJwt(cfg *models.JwtConfig, data map[string]interface{}, now time.Time) (string, error) {
+	s := currentSettings()
+	keyName := cfg.Key
+	if keyName == "" {
+		keyName = "default"
+	}
+	secret, ok := s.JWTKeys[keyName]
+	if !ok || secret == "" {
+		return "", fmt.Errorf("JWT signing key '%s' is not configured", keyName)
+	}
+
+	claims := make(map[string]interface{})
+	if cfg.Claims != nil {
+		rendered, ok := SubstituteVariables(cfg.Claims, data).(map[string]interface{})
+		if !ok {
+			return "", errors.New("JWT claims must be an object")
+		}
+		for k, v := range rendered {
+			if v != nil { // Drop claims whose variables could not be resolved
+				claims[k] = v
+			}
+		}
+	}
+
+	ttl := defaultJwtTTL
+	if cfg.TTL > 0 {
+		ttl = time.Duration(cfg.TTL) * time.Second
+	}
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+	if _, exists := claims["iss"]; !exists && s.JWTIssuer != "" {
+		claims["iss"] = s.JWTIssuer
+	}
+
+	return auth.SignHMAC(claims, []byte(secret), cfg.Algorithm)
+}
+
+// VerifyBearer verifies an "Authorization: Bearer <jwt>" value against the configured
+// JWT keys (and issuer, when set) and returns the token's claims.
+func VerifyBearer(header string) (map[string]interface{}, error) {
+	token, found := strings.CutPrefix(header, "Bearer ")
+	if !found || strings.TrimSpace(token) == "" {
+		return nil, auth.ErrInvalidToken
+	}
+	s := currentSettings()
+	if len(s.JWTKeys) == 0 {
+		return nil, errors.New("no JWT keys configured")
+	}
+	var lastErr error = auth.ErrInvalidSignature
+	for _, secret := range s.JWTKeys {
+		claims, err := auth.VerifyHMAC(strings.TrimSpace(token), []byte(secret), time.Now())
+		if err != nil {
+			if !errors.Is(err, auth.ErrInvalidSignature) {
+				lastErr = err // Signature matched but the token is expired or malformed
+			}
+			continue
+		}
+		if s.JWTIssuer != "" && claims["iss"] != s.JWTIssuer {
+			return nil, fmt.Errorf("%w: unexpected issuer", auth.ErrInvalidToken)
+		}
+		return claims, nil
+	}
+	return nil, lastErr
+}
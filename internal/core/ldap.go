@@ -0,0 +1,621 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"api-genarator/internal/models"
+)
+
+// LDAPDirectory is a server-configured LDAP / Active Directory server for the
+// "ldapLookup" action. Bind credentials live in the server settings, definitions only
+// name the directory.
+type LDAPDirectory struct {
+	URL          string `json:"url"`                    // "ldap://host:389" or "ldaps://host:636"
+	BindDN       string `json:"bindDn,omitempty"`       // Service account DN (or "user@domain" for AD); empty binds anonymously
+	BindPassword string `json:"bindPassword,omitempty"` // Service account password
+	BaseDN       string `json:"baseDn"`                 // Default search base
+	StartTLS     bool   `json:"startTls,omitempty"`     // Upgrade an ldap:// connection with StartTLS
+}
+
+// ErrLDAPNotFound is returned by a required lookup that matched no entry.
+var ErrLDAPNotFound = errors.New("no LDAP entry matched the filter")
+
+// BER tags of the LDAPv3 messages used here (RFC 4511).
+const (
+	berInteger    = 0x02
+	berOctets     = 0x04
+	berBoolean    = 0x01
+	berEnumerated = 0x0a
+	berSequence   = 0x30
+
+	ldapBindRequest      = 0x60
+	ldapBindResponse     = 0x61
+	ldapUnbindRequest    = 0x42
+	ldapSearchRequest    = 0x63
+	ldapSearchEntry      = 0x64
+	ldapSearchDone       = 0x65
+	ldapSearchReference  = 0x73
+	ldapExtendedRequest  = 0x77
+	ldapExtendedResponse = 0x78
+
+	ldapStartTLSOID = "1.3.6.1.4.1.1466.20037"
+	ldapMaxMessage  = 10 << 20
+)
+
+var ldapScopes = map[string]int{"base": 0, "one": 1, "sub": 2}
+
+// ldapLookup searches the directory with the interpolated filter and returns the first
+// matching entry as {"dn": ..., attribute: value}. Single values are strings, multiple
+// values (and attributes listed in cfg.MultiValued) are arrays. A nil entry means no match.
+func ldapLookup(ctx context.Context, cfg *models.LDAPLookupConfig, data map[string]interface{}) (map[string]interface{}, error) {
+	name := defaultString(cfg.Directory, "default")
+	dir, ok := currentSettings().LDAPDirectories[name]
+	if !ok {
+		return nil, fmt.Errorf("LDAP directory '%s' is not configured", name)
+	}
+	filter, err := compileLDAPFilter(interpolateLDAP(cfg.Filter, data, escapeLDAPValue))
+	if err != nil {
+		return nil, err
+	}
+	base := dir.BaseDN
+	if cfg.BaseDN != "" {
+		base = interpolateLDAP(cfg.BaseDN, data, escapeLDAPDN)
+	}
+	attributes := make([]string, 0, len(cfg.Attributes))
+	for attr := range cfg.Attributes {
+		if !strings.EqualFold(attr, "dn") {
+			attributes = append(attributes, attr)
+		}
+	}
+	if len(attributes) == 0 {
+		attributes = append(attributes, "1.1") // RFC 4511: no attributes, DN only
+	}
+
+	conn, err := dialLDAP(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	if err := conn.bind(dir.BindDN, dir.BindPassword); err != nil {
+		return nil, err
+	}
+	entries, err := conn.search(base, ldapScopes[defaultString(cfg.Scope, "sub")], filter, attributes)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	if len(entries) > 1 {
+		log.Printf("WARN: LDAP filter '%s' matched %d entries, using '%s'", cfg.Filter, len(entries), entries[0].dn)
+	}
+	return entries[0].toMap(cfg.MultiValued), nil
+}
+
+// interpolateLDAP is InterpolateString with every value escaped: escapeLDAPValue for search
+// filters, so data cannot widen the filter with "*" or ")(", and escapeLDAPDN for base DNs,
+// so it cannot add RDNs with "," and move the search to another subtree.
+func interpolateLDAP(template string, data map[string]interface{}, escape func(string) string) string {
+	return templatePattern.ReplaceAllStringFunc(template, func(match string) string {
+		path := templatePattern.FindStringSubmatch(match)[1]
+		value, ok := lookupField(data, strings.TrimPrefix(path, "$"))
+		if !ok || value == nil {
+			log.Printf("WARN: Template field '%s' not found, rendering as empty string", path)
+			return ""
+		}
+		return escape(fmt.Sprintf("%v", value))
+	})
+}
+
+// escapeLDAPValue escapes a filter assertion value as in RFC 4515.
+func escapeLDAPValue(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// escapeLDAPDN escapes an attribute value of a distinguished name as in RFC 4514.
+func escapeLDAPDN(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == 0:
+			b.WriteString("\\00")
+		case strings.IndexByte(",+\"\\<>;=", c) >= 0,
+			(c == ' ' || c == '#') && i == 0,
+			c == ' ' && i == len(s)-1:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// --- Filters (RFC 4515) ---
+
+// compileLDAPFilter parses a string filter such as "(&(objectClass=user)(mail=a@b.c))"
+// into its BER encoding. The outer parentheses may be omitted.
+func compileLDAPFilter(filter string) ([]byte, error) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return nil, errors.New("empty LDAP filter")
+	}
+	if filter[0] != '(' {
+		filter = "(" + filter + ")"
+	}
+	encoded, rest, err := parseLDAPFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LDAP filter: %w", err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("invalid LDAP filter: unexpected '%s'", rest)
+	}
+	return encoded, nil
+}
+
+func parseLDAPFilter(s string) ([]byte, string, error) {
+	if len(s) < 2 || s[0] != '(' {
+		return nil, "", errors.New("expected '('")
+	}
+	s = s[1:]
+	switch s[0] {
+	case '&', '|':
+		tag := byte(0xa0)
+		if s[0] == '|' {
+			tag = 0xa1
+		}
+		s = s[1:]
+		var items []byte
+		for len(s) > 0 && s[0] == '(' {
+			item, rest, err := parseLDAPFilter(s)
+			if err != nil {
+				return nil, "", err
+			}
+			items, s = append(items, item...), rest
+		}
+		if len(items) == 0 || len(s) == 0 || s[0] != ')' {
+			return nil, "", errors.New("malformed '&' or '|' filter")
+		}
+		return berTLV(tag, items), s[1:], nil
+	case '!':
+		item, rest, err := parseLDAPFilter(s[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		if len(rest) == 0 || rest[0] != ')' {
+			return nil, "", errors.New("malformed '!' filter")
+		}
+		return berTLV(0xa2, item), rest[1:], nil
+	}
+
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, "", errors.New("missing ')'")
+	}
+	item, rest := s[:end], s[end+1:]
+	eq := strings.IndexByte(item, '=')
+	if eq < 1 {
+		return nil, "", fmt.Errorf("'%s' is not an attribute assertion", item)
+	}
+	attr, value := item[:eq], item[eq+1:]
+	tag := byte(0xa3) // equalityMatch
+	switch attr[len(attr)-1] {
+	case '>':
+		tag, attr = 0xa5, attr[:len(attr)-1]
+	case '<':
+		tag, attr = 0xa6, attr[:len(attr)-1]
+	case '~':
+		tag, attr = 0xa8, attr[:len(attr)-1]
+	case ':':
+		return nil, "", errors.New("extensible match filters are not supported")
+	}
+	if attr == "" {
+		return nil, "", fmt.Errorf("'%s' has no attribute", item)
+	}
+	if tag == 0xa3 && value == "*" {
+		return berTLV(0x87, []byte(attr)), rest, nil // present
+	}
+	if tag == 0xa3 && strings.Contains(value, "*") {
+		parts := strings.Split(value, "*")
+		var subs []byte
+		for i, part := range parts {
+			if part == "" {
+				continue
+			}
+			raw, err := unescapeLDAPValue(part)
+			if err != nil {
+				return nil, "", err
+			}
+			kind := byte(0x81) // any
+			switch i {
+			case 0:
+				kind = 0x80 // initial
+			case len(parts) - 1:
+				kind = 0x82 // final
+			}
+			subs = append(subs, berTLV(kind, raw)...)
+		}
+		body := append(berTLV(berOctets, []byte(attr)), berTLV(berSequence, subs)...)
+		return berTLV(0xa4, body), rest, nil
+	}
+	raw, err := unescapeLDAPValue(value)
+	if err != nil {
+		return nil, "", err
+	}
+	body := append(berTLV(berOctets, []byte(attr)), berTLV(berOctets, raw)...)
+	return berTLV(tag, body), rest, nil
+}
+
+// unescapeLDAPValue decodes the "\XX" hex escapes of a filter value.
+func unescapeLDAPValue(s string) ([]byte, error) {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			out = append(out, s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return nil, fmt.Errorf("truncated escape in '%s'", s)
+		}
+		c, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid escape in '%s'", s)
+		}
+		out = append(out, c...)
+		i += 2
+	}
+	return out, nil
+}
+
+// --- Connection ---
+
+type ldapConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	nextID int64
+}
+
+type ldapEntry struct {
+	dn         string
+	attributes map[string][]string
+}
+
+func dialLDAP(ctx context.Context, dir LDAPDirectory) (*ldapConn, error) {
+	u, err := url.Parse(dir.URL)
+	if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid LDAP url '%s'", dir.URL)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		port := "389"
+		if u.Scheme == "ldaps" {
+			port = "636"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(30 * time.Second)
+	}
+	conn.SetDeadline(deadline)
+
+	tlsConfig := &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	if u.Scheme == "ldaps" {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	c := &ldapConn{conn: conn, r: bufio.NewReader(conn)}
+	if u.Scheme == "ldap" && dir.StartTLS {
+		req := berTLV(ldapExtendedRequest, berTLV(0x80, []byte(ldapStartTLSOID)))
+		if _, err := c.roundTrip(req, ldapExtendedResponse); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("StartTLS failed: %w", err)
+		}
+		c.conn = tls.Client(conn, tlsConfig)
+		c.r = bufio.NewReader(c.conn)
+	}
+	return c, nil
+}
+
+func (c *ldapConn) close() {
+	c.nextID++
+	msg := append(berInt(berInteger, c.nextID), ldapUnbindRequest, 0)
+	c.conn.Write(berTLV(berSequence, msg))
+	c.conn.Close()
+}
+
+func (c *ldapConn) bind(dn, password string) error {
+	body := berInt(berInteger, 3)
+	body = append(body, berTLV(berOctets, []byte(dn))...)
+	body = append(body, berTLV(0x80, []byte(password))...) // simple authentication
+	if _, err := c.roundTrip(berTLV(ldapBindRequest, body), ldapBindResponse); err != nil {
+		return fmt.Errorf("LDAP bind failed: %w", err)
+	}
+	return nil
+}
+
+func (c *ldapConn) search(base string, scope int, filter []byte, attributes []string) ([]ldapEntry, error) {
+	var attrs []byte
+	for _, a := range attributes {
+		attrs = append(attrs, berTLV(berOctets, []byte(a))...)
+	}
+	body := berTLV(berOctets, []byte(base))
+	body = append(body, berInt(berEnumerated, int64(scope))...)
+	body = append(body, berInt(berEnumerated, 0)...) // neverDerefAliases
+	body = append(body, berInt(berInteger, 0)...)    // sizeLimit (server default)
+	body = append(body, berInt(berInteger, 0)...)    // timeLimit (server default)
+	body = append(body, berTLV(berBoolean, []byte{0})...)
+	body = append(body, filter...)
+	body = append(body, berTLV(berSequence, attrs)...)
+
+	id, err := c.send(berTLV(ldapSearchRequest, body))
+	if err != nil {
+		return nil, err
+	}
+	var entries []ldapEntry
+	for {
+		tag, op, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+		switch tag {
+		case ldapSearchEntry:
+			entry, err := parseLDAPEntry(op)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case ldapSearchReference:
+			// Referrals to other servers are not followed
+		case ldapSearchDone:
+			if err := ldapResultError(op); err != nil {
+				return nil, fmt.Errorf("LDAP search failed: %w", err)
+			}
+			return entries, nil
+		default:
+			return nil, fmt.Errorf("unexpected LDAP response 0x%02x", tag)
+		}
+	}
+}
+
+// roundTrip sends a request and checks the LDAPResult of its single response.
+func (c *ldapConn) roundTrip(op []byte, responseTag byte) ([]byte, error) {
+	id, err := c.send(op)
+	if err != nil {
+		return nil, err
+	}
+	tag, body, err := c.receive(id)
+	if err != nil {
+		return nil, err
+	}
+	if tag != responseTag {
+		return nil, fmt.Errorf("unexpected LDAP response 0x%02x", tag)
+	}
+	return body, ldapResultError(body)
+}
+
+func (c *ldapConn) send(op []byte) (int64, error) {
+	c.nextID++
+	msg := append(berInt(berInteger, c.nextID), op...)
+	_, err := c.conn.Write(berTLV(berSequence, msg))
+	return c.nextID, err
+}
+
+// receive reads the next LDAPMessage for the given ID and returns its protocolOp.
+func (c *ldapConn) receive(id int64) (byte, []byte, error) {
+	for {
+		tag, msg, err := readBER(c.r)
+		if err != nil {
+			return 0, nil, err
+		}
+		if tag != berSequence {
+			return 0, nil, errors.New("malformed LDAP message")
+		}
+		_, idBytes, rest, err := splitBER(msg)
+		if err != nil {
+			return 0, nil, err
+		}
+		opTag, op, _, err := splitBER(rest)
+		if err != nil {
+			return 0, nil, err
+		}
+		if berToInt(idBytes) == 0 {
+			return 0, nil, fmt.Errorf("LDAP server notice: %v", ldapResultError(op))
+		}
+		if berToInt(idBytes) == id {
+			return opTag, op, nil
+		}
+	}
+}
+
+// ldapResultError converts an LDAPResult (resultCode, matchedDN, diagnosticMessage) to an error.
+func ldapResultError(result []byte) error {
+	_, code, rest, err := splitBER(result)
+	if err != nil {
+		return err
+	}
+	if berToInt(code) == 0 {
+		return nil
+	}
+	_, _, rest, _ = splitBER(rest) // matchedDN
+	_, message, _, _ := splitBER(rest)
+	return fmt.Errorf("result code %d: %s", berToInt(code), message)
+}
+
+func parseLDAPEntry(op []byte) (ldapEntry, error) {
+	_, dn, rest, err := splitBER(op)
+	if err != nil {
+		return ldapEntry{}, err
+	}
+	_, list, _, err := splitBER(rest)
+	if err != nil {
+		return ldapEntry{}, err
+	}
+	entry := ldapEntry{dn: string(dn), attributes: make(map[string][]string)}
+	for len(list) > 0 {
+		var attr []byte
+		if _, attr, list, err = splitBER(list); err != nil {
+			return ldapEntry{}, err
+		}
+		_, name, vals, err := splitBER(attr)
+		if err != nil {
+			return ldapEntry{}, err
+		}
+		_, set, _, err := splitBER(vals)
+		if err != nil {
+			return ldapEntry{}, err
+		}
+		var values []string
+		for len(set) > 0 {
+			var value []byte
+			if _, value, set, err = splitBER(set); err != nil {
+				return ldapEntry{}, err
+			}
+			values = append(values, string(value))
+		}
+		entry.attributes[strings.ToLower(string(name))] = values
+	}
+	return entry, nil
+}
+
+// toMap keys attributes by their lower-case names (LDAP attribute names are case-insensitive).
+func (e ldapEntry) toMap(multiValued []string) map[string]interface{} {
+	out := map[string]interface{}{"dn": e.dn}
+	for name, values := range e.attributes {
+		multi := len(values) > 1
+		for _, m := range multiValued {
+			multi = multi || strings.EqualFold(m, name)
+		}
+		if !multi {
+			out[name] = values[0]
+			continue
+		}
+		list := make([]interface{}, len(values))
+		for i, v := range values {
+			list[i] = v
+		}
+		out[name] = list
+	}
+	return out
+}
+
+// --- BER ---
+
+func berTLV(tag byte, content []byte) []byte {
+	b := []byte{tag}
+	switch n := len(content); {
+	case n < 0x80:
+		b = append(b, byte(n))
+	case n <= 0xff:
+		b = append(b, 0x81, byte(n))
+	case n <= 0xffff:
+		b = append(b, 0x82, byte(n>>8), byte(n))
+	default:
+		b = append(b, 0x84, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(b, content...)
+}
+
+func berInt(tag byte, n int64) []byte {
+	var b []byte
+	for {
+		b = append([]byte{byte(n)}, b...)
+		if (n < 0x80 && n >= -0x80) || len(b) == 8 {
+			break
+		}
+		n >>= 8
+	}
+	return berTLV(tag, b)
+}
+
+func berToInt(b []byte) int64 {
+	var n int64
+	for i, c := range b {
+		if i == 0 && c&0x80 != 0 {
+			n = -1
+		}
+		n = n<<8 | int64(c)
+	}
+	return n
+}
+
+// readBER reads one TLV from the stream.
+func readBER(r *bufio.Reader) (byte, []byte, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length := int(first)
+	if first&0x80 != 0 {
+		count := int(first & 0x7f)
+		if count == 0 || count > 4 {
+			return 0, nil, errors.New("unsupported BER length")
+		}
+		length = 0
+		for i := 0; i < count; i++ {
+			c, err := r.ReadByte()
+			if err != nil {
+				return 0, nil, err
+			}
+			length = length<<8 | int(c)
+		}
+	}
+	if length > ldapMaxMessage {
+		return 0, nil, fmt.Errorf("LDAP message of %d bytes exceeds the limit", length)
+	}
+	content := make([]byte, length)
+	_, err = io.ReadFull(r, content)
+	return tag, content, err
+}
+
+// splitBER splits the first TLV off b and returns its tag, content and the remainder.
+func splitBER(b []byte) (byte, []byte, []byte, error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errors.New("truncated BER element")
+	}
+	tag, length, offset := b[0], int(b[1]), 2
+	if b[1]&0x80 != 0 {
+		count := int(b[1] & 0x7f)
+		if count == 0 || count > 4 || len(b) < 2+count {
+			return 0, nil, nil, errors.New("malformed BER length")
+		}
+		length = 0
+		for _, c := range b[2 : 2+count] {
+			length = length<<8 | int(c)
+		}
+		offset += count
+	}
+	if length < 0 || len(b)-offset < length {
+		return 0, nil, nil, errors.New("truncated BER element")
+	}
+	return tag, b[offset : offset+length], b[offset+length:], nil
+}
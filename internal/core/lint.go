@@ -0,0 +1,403 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"api-genarator/internal/models"
+)
+
+// LintWarning is a non-fatal finding about a definition that is likely a logic bug.
+type LintWarning struct {
+	Code    string `json:"code"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// flowUsage collects which fields a definition reads and writes.
+type flowUsage struct {
+	reads      map[string]bool // Root field names read anywhere
+	writes     map[string]string
+	available  map[string]bool // Fields that can exist in the data state
+	variables  map[string]string
+	wholeState bool // Some path saves or returns the whole data state
+}
+
+// LintDefinition reports suspicious but valid constructs: unused parameters, fields written
+// but never read, $variables that cannot exist, PUT saves without a UniqueKey, dead branches.
+func LintDefinition(api models.ApiDefinition) []LintWarning {
+	var warnings []LintWarning
+	add := func(code, path, format string, args ...interface{}) {
+		warnings = append(warnings, LintWarning{Code: code, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	u := &flowUsage{
+		reads:     map[string]bool{},
+		writes:    map[string]string{},
+		available: map[string]bool{},
+		variables: map[string]string{},
+	}
+	for _, p := range api.Parameters {
+		u.available[p.Name] = true
+	}
+	if api.NamespacedParams {
+		u.available[namespacePathRoot] = true
+		u.available[namespaceQueryRoot] = true
+		u.available[namespaceBodyRoot] = true
+	}
+	for _, rule := range api.Validations {
+		u.read(rule.Field)
+		u.read(rule.Other)
+	}
+	u.read(api.UniqueKey)
+
+	if api.ConditionalFlow == nil {
+		u.wholeState = true // Default logic filters or saves with all parameters
+	}
+	u.walkBlock(api.ConditionalFlow, "conditionalFlow", add)
+
+	// Reading a namespace root reads every parameter through it
+	namespaceRead := u.reads[namespacePathRoot] || u.reads[namespaceQueryRoot] || u.reads[namespaceBodyRoot]
+	if !u.wholeState && !namespaceRead {
+		for i, p := range api.Parameters {
+			if !u.reads[p.Name] {
+				add("unused-parameter", fmt.Sprintf("parameters[%d]", i), "parameter '%s' is never read by the flow and is neither saved nor returned", p.Name)
+			}
+		}
+		for _, field := range sortedKeys(u.writes) {
+			if !u.reads[field] {
+				add("unread-field", u.writes[field], "field '%s' is written but never read, saved or returned", field)
+			}
+		}
+	}
+
+	// Undeclared variables can only be flagged when the inputs are declared
+	if len(api.Parameters) > 0 {
+		for _, name := range sortedKeys(u.variables) {
+			if !u.available[name] {
+				add("unknown-variable", u.variables[name], "'$%s' does not match a parameter or a field set by the flow and may not exist", name)
+			}
+		}
+	}
+
+	if api.Method == "PUT" && api.UniqueKey == "" && (api.ConditionalFlow == nil || flowSaves(api.ConditionalFlow)) {
+		add("put-without-unique-key", "uniqueKey", "PUT saves data without a uniqueKey, so every request inserts a new document instead of updating")
+	}
+	return warnings
+}
+
+// Root names of the request namespaces (see NamespacedParams).
+const (
+	namespacePathRoot  = "path"
+	namespaceQueryRoot = "query"
+	namespaceBodyRoot  = "body"
+)
+
+func (u *flowUsage) read(path string) {
+	if path != "" {
+		u.reads[rootField(path)] = true
+	}
+}
+
+func (u *flowUsage) write(path, at string) {
+	if path == "" {
+		return
+	}
+	root := rootField(path)
+	u.available[root] = true
+	if _, seen := u.writes[root]; !seen {
+		u.writes[root] = at
+	}
+}
+
+// readTemplate records $variables and {{placeholders}} used inside a template value.
+func (u *flowUsage) readTemplate(template interface{}, at string) {
+	switch t := template.(type) {
+	case string:
+		if strings.HasPrefix(t, lookupPrefix) {
+			// Lookup tables are not flow fields; only the $field keys in brackets are read
+			for _, m := range lookupKeyPattern.FindAllStringSubmatch(t, -1) {
+				u.readTemplate(m[1], at)
+			}
+		} else if strings.HasPrefix(t, translatePrefix) {
+			// Only the parameters of a message reference are flow values
+			if _, params, err := parseTranslateCall(t); err == nil {
+				u.readTemplate(params, at)
+			}
+		} else if strings.HasPrefix(t, "$") && len(t) > 1 {
+			root := rootField(strings.TrimPrefix(t, "$"))
+			u.reads[root] = true
+			if _, seen := u.variables[root]; !seen {
+				u.variables[root] = at
+			}
+		}
+		for _, m := range templatePattern.FindAllStringSubmatch(t, -1) {
+			u.read(m[1])
+		}
+	case map[string]interface{}:
+		for _, v := range t {
+			u.readTemplate(v, at)
+		}
+	case []interface{}:
+		for _, v := range t {
+			u.readTemplate(v, at)
+		}
+	}
+}
+
+func (u *flowUsage) walkBlock(block *models.ConditionalBlock, path string, add func(code, path, format string, args ...interface{})) {
+	if block == nil {
+		return
+	}
+	for _, cond := range block.Conditions {
+		if cond.Operator == "featureFlag" {
+			continue // Field is a flag name
+		}
+		u.read(cond.Field)
+		u.readTemplate(cond.Value, path+".conditions")
+	}
+	if block.Then == nil && block.Else == nil {
+		add("empty-block", path, "block has neither 'then' nor 'else', so it returns the data unchanged")
+	}
+	if len(block.Conditions) == 0 && block.Else != nil {
+		add("unreachable-else", path+".else", "block has no conditions, so 'else' can never run")
+	}
+	u.walkAction(block.Then, path+".then", add)
+	u.walkAction(block.Else, path+".else", add)
+}
+
+func (u *flowUsage) walkAction(action *models.ActionDefinition, path string, add func(code, path, format string, args ...interface{})) {
+	if action == nil {
+		return
+	}
+	for i, t := range action.Transform {
+		at := fmt.Sprintf("%s.transform[%d]", path, i)
+		u.readTemplate(t.Value, at)
+		u.readTemplate(t.Currency, at)
+		u.readTemplate(t.Locale, at)
+		if t.Operation == "calculate" {
+			if _, fields, ok := strings.Cut(t.Formula, ":"); ok {
+				for _, f := range strings.Split(fields, ",") {
+					u.read(strings.TrimSpace(f))
+				}
+			}
+		}
+		if t.Operation == "extract" {
+			if root := jsonPathRoot(t.Path); root != "" {
+				u.read(root)
+			}
+		}
+		reshape := t.Operation == "flatten" || t.Operation == "unflatten" || t.Operation == "rename"
+		if t.Operation == "append" || t.Operation == "bcryptHash" || reshape || (numberFormatOps[t.Operation] && t.Value == nil) {
+			u.read(t.Field) // Reads the current value before writing it back
+		}
+		if target, ok := t.Value.(string); reshape && ok && target != "" {
+			u.write(target, at)
+		} else if t.Operation != "remove" && t.Operation != "rename" {
+			u.write(t.Field, at)
+		}
+	}
+
+	if action.SaveData || action.Type == "continue" || len(action.UseMappings) > 0 {
+		u.wholeState = true // Mapping profiles are resolved at run time and may read any field
+	}
+	u.readTemplate(action.ReturnData, path+".returnData")
+
+	switch action.Type {
+	case "apiCall":
+		if action.ApiCall != nil {
+			u.readTemplate(action.ApiCall.Parameters, path+".apiCall.parameters")
+			u.write(action.ApiCall.ResultField, path+".apiCall.resultField")
+		}
+	case "presignUrl":
+		if action.Presign != nil {
+			u.readTemplate(action.Presign.Key, path+".presign.key")
+			u.readTemplate(action.Presign.Bucket, path+".presign.bucket")
+			u.write(defaultString(action.Presign.ResultField, "url"), path+".presign.resultField")
+		}
+	case "signJwt":
+		if action.Jwt != nil {
+			u.readTemplate(action.Jwt.Claims, path+".jwt.claims")
+			u.write(defaultString(action.Jwt.ResultField, "token"), path+".jwt.resultField")
+		}
+	case "cacheGet", "cacheSet":
+		if action.Cache != nil {
+			u.readTemplate(action.Cache.Key, path+".cache.key")
+			u.readTemplate(action.Cache.Value, path+".cache.value")
+			if action.Type == "cacheGet" {
+				u.write(defaultString(action.Cache.ResultField, "cached"), path+".cache.resultField")
+			}
+		}
+	case "graphqlCall":
+		if action.GraphQL != nil {
+			u.readTemplate(action.GraphQL.Endpoint, path+".graphql.endpoint")
+			u.readTemplate(action.GraphQL.Query, path+".graphql.query")
+			u.readTemplate(action.GraphQL.Variables, path+".graphql.variables")
+			for name, value := range action.GraphQL.Headers {
+				u.readTemplate(value, path+".graphql.headers."+name)
+			}
+			u.write(defaultString(action.GraphQL.ResultField, "graphql"), path+".graphql.resultField")
+		}
+	case "soapCall":
+		if action.SOAP != nil {
+			u.readTemplate(action.SOAP.Endpoint, path+".soap.endpoint")
+			u.readTemplate(action.SOAP.Envelope, path+".soap.envelope")
+			for name, value := range action.SOAP.Headers {
+				u.readTemplate(value, path+".soap.headers."+name)
+			}
+			u.write(defaultString(action.SOAP.ResultField, "soap"), path+".soap.resultField")
+		}
+	case "fileDrop":
+		if action.FileDrop != nil {
+			u.readTemplate(action.FileDrop.Path, path+".fileDrop.path")
+			if action.FileDrop.Source == "" {
+				u.wholeState = true
+			} else {
+				u.read(strings.TrimPrefix(action.FileDrop.Source, "$"))
+			}
+			u.write(defaultString(action.FileDrop.ResultField, "file"), path+".fileDrop.resultField")
+		}
+	case "ldapLookup":
+		if action.LDAP != nil {
+			u.readTemplate(action.LDAP.Filter, path+".ldap.filter")
+			u.readTemplate(action.LDAP.BaseDN, path+".ldap.baseDn")
+			for attr, field := range action.LDAP.Attributes {
+				u.write(field, path+".ldap.attributes."+attr)
+			}
+		}
+	case "payment":
+		if p := action.Payment; p != nil {
+			if amount, ok := p.Amount.(string); ok {
+				u.readTemplate(amount, path+".payment.amount")
+			}
+			for field, template := range map[string]string{"currency": p.Currency, "customer": p.Customer, "paymentMethod": p.PaymentMethod,
+				"paymentIntent": p.PaymentIntent, "description": p.Description, "idempotencyKey": p.IdempotencyKey} {
+				u.readTemplate(template, path+".payment."+field)
+			}
+			for key, value := range p.Metadata {
+				u.readTemplate(value, path+".payment.metadata."+key)
+			}
+			u.write(defaultString(p.ResultField, "payment"), path+".payment.resultField")
+			u.walkAction(p.OnFailure, path+".payment.onFailure", add)
+		}
+	case "push":
+		if p := action.Push; p != nil {
+			u.readTemplate(p.Tokens, path+".push.tokens")
+			u.readTemplate(p.Title, path+".push.title")
+			u.readTemplate(p.Body, path+".push.body")
+			for key, value := range p.Data {
+				u.readTemplate(value, path+".push.data."+key)
+			}
+			if p.Lookup != nil {
+				u.readTemplate(p.Lookup.Filter, path+".push.lookup.filter")
+			}
+			u.write(defaultString(p.ResultField, "push"), path+".push.resultField")
+		}
+	case "sendSms":
+		if s := action.SMS; s != nil {
+			u.readTemplate(s.To, path+".sms.to")
+			u.readTemplate(s.Body, path+".sms.body")
+			u.readTemplate(s.From, path+".sms.from")
+			u.readTemplate(s.StatusCallback, path+".sms.statusCallback")
+			u.write(defaultString(s.ResultField, "sms"), path+".sms.resultField")
+			u.walkAction(s.OnRateLimit, path+".sms.onRateLimit", add)
+		}
+	case "generateOtp", "verifyOtp":
+		if o := action.OTP; o != nil {
+			u.readTemplate(o.Subject, path+".otp.subject")
+			if action.Type == "verifyOtp" {
+				u.read(defaultString(o.CodeField, "code"))
+			}
+			u.write(defaultString(o.ResultField, "otp"), path+".otp.resultField")
+			u.walkAction(o.OnFailure, path+".otp.onFailure", add)
+		}
+	case "callFlow":
+		if cf := action.CallFlow; cf != nil {
+			u.readTemplate(cf.Parameters, path+".callFlow.parameters")
+			u.write(defaultString(cf.ResultField, "result"), path+".callFlow.resultField")
+		}
+	case "dbUpdate":
+		if d := action.DbUpdate; d != nil {
+			u.readTemplate(d.Key, path+".dbUpdate.key")
+			u.readTemplate(d.Update, path+".dbUpdate.update")
+			u.readTemplate(d.Expect, path+".dbUpdate.expect")
+			u.write(defaultString(d.ResultField, "updated"), path+".dbUpdate.resultField")
+			if d.OnConflict != nil {
+				u.write(defaultString(d.ConflictField, "conflict"), path+".dbUpdate.conflictField")
+				u.walkAction(d.OnConflict, path+".dbUpdate.onConflict", add)
+			}
+		}
+	case "findAndModify":
+		if m := action.FindAndModify; m != nil {
+			u.readTemplate(m.Filter, path+".findAndModify.filter")
+			u.readTemplate(m.Update, path+".findAndModify.update")
+			u.write(defaultString(m.ResultField, "document"), path+".findAndModify.resultField")
+		}
+	case "verifyCaptcha":
+		if action.Captcha != nil {
+			u.read(action.Captcha.TokenField)
+			u.read(action.Captcha.RemoteIPField)
+			u.walkAction(action.Captcha.OnFailure, path+".captcha.onFailure", add)
+		}
+	}
+
+	// Side-effect actions without a follow-up return the whole state
+	if action.ConditionalFlow == nil && action.ReturnData == nil && action.Type != "return" && action.Type != "conditionalBlock" {
+		u.wholeState = true
+	}
+	u.walkBlock(action.ConditionalFlow, path+".conditionalFlow", add)
+}
+
+// flowSaves reports whether any action in the flow has SaveData set.
+func flowSaves(block *models.ConditionalBlock) bool {
+	if block == nil {
+		return false
+	}
+	for _, action := range []*models.ActionDefinition{block.Then, block.Else} {
+		if action == nil {
+			continue
+		}
+		if action.SaveData || flowSaves(action.ConditionalFlow) {
+			return true
+		}
+		if action.Captcha != nil && action.Captcha.OnFailure != nil &&
+			(action.Captcha.OnFailure.SaveData || flowSaves(action.Captcha.OnFailure.ConditionalFlow)) {
+			return true
+		}
+		if action.Payment != nil && action.Payment.OnFailure != nil &&
+			(action.Payment.OnFailure.SaveData || flowSaves(action.Payment.OnFailure.ConditionalFlow)) {
+			return true
+		}
+		if action.OTP != nil && action.OTP.OnFailure != nil &&
+			(action.OTP.OnFailure.SaveData || flowSaves(action.OTP.OnFailure.ConditionalFlow)) {
+			return true
+		}
+		if action.SMS != nil && action.SMS.OnRateLimit != nil &&
+			(action.SMS.OnRateLimit.SaveData || flowSaves(action.SMS.OnRateLimit.ConditionalFlow)) {
+			return true
+		}
+	}
+	return false
+}
+
+func rootField(path string) string {
+	root, _, _ := strings.Cut(path, ".")
+	return root
+}
+
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
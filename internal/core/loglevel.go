@@ -0,0 +1,75 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// logLevels ranks the prefixes used in log lines ("DEBUG: ...", "WARN: ...").
+var logLevels = map[string]int32{"debug": 0, "info": 1, "warn": 2, "error": 3}
+
+var (
+	minLogLevel    atomic.Int32 // Lines below this level are dropped; debug (0) writes everything
+	logFilterSetup sync.Once
+)
+
+// SetLogLevel drops log lines below level ("debug", "info", "warn" or "error"). Lines
+// without a level prefix, and FATAL lines, are always written. "" means debug.
+func SetLogLevel(level string) error {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if level == "" {
+		level = "debug"
+	}
+	rank, ok := logLevels[level]
+	if !ok {
+		return fmt.Errorf("unknown log level '%s' (use debug, info, warn or error)", level)
+	}
+	logFilterSetup.Do(func() { log.SetOutput(levelWriter{out: os.Stderr}) })
+	minLogLevel.Store(rank)
+	return nil
+}
+
+// levelWriter sits between the standard logger and stderr and filters by level.
+type levelWriter struct {
+	out io.Writer
+}
+
+func (w levelWriter) Write(p []byte) (int, error) {
+	if rank, ok := lineLevel(p); ok && rank < minLogLevel.Load() {
+		return len(p), nil
+	}
+	return w.out.Write(p)
+}
+
+// lineLevel reads the level word after the log prefix, date and time of a line.
+func lineLevel(line []byte) (int32, bool) {
+	rest := bytes.TrimPrefix(line, []byte(log.Prefix()))
+	skip := 0
+	if log.Flags()&log.Ldate != 0 {
+		skip++
+	}
+	if log.Flags()&(log.Ltime|log.Lmicroseconds) != 0 {
+		skip++
+	}
+	for ; skip > 0; skip-- {
+		if i := bytes.IndexByte(rest, ' '); i >= 0 {
+			rest = rest[i+1:]
+		}
+	}
+	end := bytes.IndexAny(rest, ": ")
+	if end < 0 {
+		return 0, false
+	}
+	word := strings.ToLower(string(rest[:end]))
+	if word == "warning" {
+		word = "warn"
+	}
+	rank, ok := logLevels[word]
+	return rank, ok
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"api-genarator/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// lookupPrefix starts a reference into a lookup table, e.g. "$lookup.taxRates[$region]".
+const lookupPrefix = "$lookup."
+
+// lookupKeyPattern finds the bracketed keys of a reference (used by lint).
+var lookupKeyPattern = regexp.MustCompile(`\[([^\]]*)\]`)
+
+var (
+	lookupMu     sync.RWMutex
+	lookupTables = map[string]map[string]interface{}{}
+)
+
+// SetLookupTables replaces all lookup tables (at startup and on each refresh).
+func SetLookupTables(tables []models.LookupTable) {
+	loaded := make(map[string]map[string]interface{}, len(tables))
+	for _, t := range tables {
+		values, _ := plainValue(t.Values).(map[string]interface{})
+		loaded[t.Name] = values
+	}
+	lookupMu.Lock()
+	lookupTables = loaded
+	lookupMu.Unlock()
+}
+
+// PutLookupTable installs or replaces one table.
+func PutLookupTable(table models.LookupTable) {
+	values, _ := plainValue(table.Values).(map[string]interface{})
+	lookupMu.Lock()
+	lookupTables[table.Name] = values
+	lookupMu.Unlock()
+}
+
+// DropLookupTable removes one table.
+func DropLookupTable(name string) {
+	lookupMu.Lock()
+	delete(lookupTables, name)
+	lookupMu.Unlock()
+}
+
+// resolveLookup evaluates "$lookup.<table>[<key>]...": keys in brackets may be "$field"
+// references, quoted strings or literals; ".key" works for fixed keys. ok is false when
+// the reference does not name a loaded table, so a data field called "lookup" still works.
+func resolveLookup(ref string, data map[string]interface{}) (value interface{}, ok bool) {
+	rest := strings.TrimPrefix(ref, lookupPrefix)
+	end := strings.IndexAny(rest, ".[")
+	if end < 0 {
+		end = len(rest)
+	}
+	lookupMu.RLock()
+	table, exists := lookupTables[rest[:end]]
+	lookupMu.RUnlock()
+	if !exists {
+		return nil, false
+	}
+
+	value = table
+	rest = rest[end:]
+	for rest != "" {
+		var key string
+		if rest[0] == '.' {
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key, rest = rest[:end], rest[end:]
+		} else {
+			closing := strings.IndexByte(rest, ']')
+			if closing < 0 {
+				log.Printf("WARN: Unterminated '[' in lookup reference '%s'", ref)
+				return nil, true
+			}
+			key, rest = lookupKey(strings.TrimSpace(rest[1:closing]), data), rest[closing+1:]
+		}
+		m, isMap := value.(map[string]interface{})
+		if !isMap {
+			log.Printf("WARN: Lookup reference '%s' goes below a non-object value", ref)
+			return nil, true
+		}
+		if value, exists = m[key]; !exists {
+			log.Printf("DEBUG: Lookup reference '%s' has no entry for key '%s'", ref, key)
+			return nil, true
+		}
+	}
+	return value, true
+}
+
+func lookupKey(expr string, data map[string]interface{}) string {
+	switch {
+	case strings.HasPrefix(expr, "$"):
+		if v := SubstituteVariables(expr, data); v != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return ""
+	case len(expr) >= 2 && (expr[0] == '\'' || expr[0] == '"') && expr[len(expr)-1] == expr[0]:
+		if unquoted, err := strconv.Unquote(`"` + expr[1:len(expr)-1] + `"`); err == nil {
+			return unquoted
+		}
+		return expr[1 : len(expr)-1]
+	}
+	return expr
+}
+
+// plainValue converts BSON documents and arrays into plain maps and slices.
+func plainValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case primitive.D:
+		m := make(map[string]interface{}, len(t))
+		for _, e := range t {
+			m[e.Key] = plainValue(e.Value)
+		}
+		return m
+	case primitive.M:
+		return plainValue(map[string]interface{}(t))
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[k] = plainValue(e)
+		}
+		return m
+	case primitive.A:
+		return plainValue([]interface{}(t))
+	case []interface{}:
+		list := make([]interface{}, len(t))
+		for i, e := range t {
+			list[i] = plainValue(e)
+		}
+		return list
+	}
+	return v
+}
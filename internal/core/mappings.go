@@ -0,0 +1,57 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+)
+
+// mappingProfileTTL is how long a profile is reused before it is read again, so an
+// update made through another instance is picked up without a restart.
+const mappingProfileTTL = 30 * time.Second
+
+type cachedProfile struct {
+	transform []models.Transformation
+	loadedAt  time.Time
+}
+
+var (
+	mappingMu       sync.Mutex
+	mappingProfiles = map[string]cachedProfile{}
+)
+
+// resolveMappings returns the transformations of the named profiles, in order.
+func resolveMappings(ctx context.Context, store *database.Store, names []string) ([]models.Transformation, error) {
+	var transform []models.Transformation
+	for _, name := range names {
+		mappingMu.Lock()
+		cached, ok := mappingProfiles[name]
+		mappingMu.Unlock()
+		if !ok || time.Since(cached.loadedAt) > mappingProfileTTL {
+			if store == nil {
+				return nil, fmt.Errorf("mapping profile '%s' cannot be loaded without a database", name)
+			}
+			profile, err := store.GetMappingProfile(ctx, name)
+			if err != nil {
+				return nil, fmt.Errorf("mapping profile '%s': %w", name, err)
+			}
+			cached = cachedProfile{transform: profile.Transform, loadedAt: time.Now()}
+			mappingMu.Lock()
+			mappingProfiles[name] = cached
+			mappingMu.Unlock()
+		}
+		transform = append(transform, cached.transform...)
+	}
+	return transform, nil
+}
+
+// ForgetMappingProfile drops a cached profile after it was changed or deleted.
+func ForgetMappingProfile(name string) {
+	mappingMu.Lock()
+	delete(mappingProfiles, name)
+	mappingMu.Unlock()
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrTenantCollection is returned for migrations of definitions whose database or
+// collection is a tenant template: there is no single collection to migrate.
+var ErrTenantCollection = errors.New("definition targets a tenant template, migrate each tenant's collection separately")
+
+// CheckMigration validates a migration before it is stored.
+func CheckMigration(migration models.Migration) []DefinitionProblem {
+	var problems []DefinitionProblem
+	add := func(path, format string, args ...interface{}) {
+		problems = append(problems, DefinitionProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	if migration.Name == "" {
+		add("name", "name is required")
+	}
+	if migration.Definition == "" {
+		add("definition", "definition is required")
+	}
+	if len(migration.Steps) == 0 {
+		add("steps", "a migration needs at least one step")
+	}
+	for i, step := range migration.Steps {
+		path := fmt.Sprintf("steps[%d]", i)
+		switch step.Type {
+		case models.MigrationTransform:
+			if len(step.Transform) == 0 {
+				add(path+".transform", "a transform step needs at least one transformation")
+			}
+			checkTransforms(step.Transform, path+".transform", add)
+		case models.MigrationUpdate:
+			if len(step.Update) == 0 {
+				add(path+".update", "an update step needs update operators")
+			}
+			for op := range step.Update {
+				if !strings.HasPrefix(op, "$") {
+					add(path+".update", "'%s' is not an update operator (e.g. $set, $unset, $rename)", op)
+				}
+			}
+		case models.MigrationDelete:
+			if len(step.Filter) == 0 {
+				add(path+".filter", "a delete step needs a filter")
+			}
+		case models.MigrationCreateIndex:
+			if step.Index == nil || step.Index.Name == "" || len(step.Index.Fields) == 0 {
+				add(path+".index", "a createIndex step needs an index name and fields")
+			}
+		case models.MigrationDropIndex:
+			if step.Index == nil || step.Index.Name == "" {
+				add(path+".index", "a dropIndex step needs an index name")
+			}
+		default:
+			add(path+".type", "unknown migration step '%s' (use transform, update, delete, createIndex or dropIndex)", step.Type)
+		}
+	}
+	return problems
+}
+
+// migrationTarget returns the fixed database and collection of a definition.
+func migrationTarget(api *models.ApiDefinition) (string, string, error) {
+	target := ApplyEnvironment(*api)
+	if IsTargetTemplate(target.Database) || IsTargetTemplate(target.Collection) {
+		return "", "", ErrTenantCollection
+	}
+	return target.Database, target.Collection, nil
+}
+
+// PreviewMigration counts the documents each step's filter matches, without changing
+// anything (index steps count 0).
+func PreviewMigration(ctx context.Context, store *database.Store, migration *models.Migration, api *models.ApiDefinition) ([]int64, error) {
+	dbName, collName, err := migrationTarget(api)
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]int64, len(migration.Steps))
+	for i, step := range migration.Steps {
+		if step.Type == models.MigrationCreateIndex || step.Type == models.MigrationDropIndex {
+			continue
+		}
+		if counts[i], err = store.CountData(ctx, dbName, collName, bson.M(step.Filter)); err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return counts, nil
+}
+
+// ApplyMigration runs the steps of a migration that are not completed yet against the
+// definition's collection. Progress is recorded after each step, so a failed migration
+// resumes where it stopped once the cause is fixed.
+func ApplyMigration(ctx context.Context, store *database.Store, migration *models.Migration, api *models.ApiDefinition, by string) (*models.MigrationRun, error) {
+	dbName, collName, err := migrationTarget(api)
+	if err != nil {
+		return nil, err
+	}
+	run, err := store.StartMigrationRun(ctx, migration, by)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	log.Printf("INFO: Applying migration '%s' to %s.%s (steps %d-%d)", migration.Name, dbName, collName, run.CompletedSteps+1, len(migration.Steps))
+
+	for i := run.CompletedSteps; i < len(migration.Steps); i++ {
+		affected, stepErr := applyMigrationStep(ctx, store, dbName, collName, migration.Steps[i])
+		if stepErr != nil {
+			stepErr = fmt.Errorf("step %d (%s): %w", i+1, migration.Steps[i].Type, stepErr)
+			return finishMigrationRun(store, run, stepErr)
+		}
+		run.CompletedSteps = i + 1
+		run.Affected = append(run.Affected, affected)
+		if err := store.SaveMigrationRun(context.WithoutCancel(ctx), run); err != nil {
+			log.Printf("WARN: Could not record progress of migration '%s': %v", migration.Name, err)
+		}
+	}
+	log.Printf("INFO: Migration '%s' applied by %s in %s, documents changed per step: %v", migration.Name, by, time.Since(start).Round(time.Millisecond), run.Affected)
+	return finishMigrationRun(store, run, nil)
+}
+
+// finishMigrationRun records the outcome of a run; the history must be written even
+// when the request that started it was cancelled.
+func finishMigrationRun(store *database.Store, run *models.MigrationRun, runErr error) (*models.MigrationRun, error) {
+	now := time.Now().UTC()
+	run.FinishedAt = &now
+	run.Status = models.MigrationApplied
+	if runErr != nil {
+		run.Status = models.MigrationFailed
+		run.Error = runErr.Error()
+		log.Printf("ERROR: Migration '%s' failed after %d completed steps: %v", run.Migration, run.CompletedSteps, runErr)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := store.SaveMigrationRun(ctx, run); err != nil {
+		log.Printf("ERROR: Could not record the outcome of migration '%s': %v", run.Migration, err)
+		if runErr == nil {
+			runErr = err
+		}
+	}
+	return run, runErr
+}
+
+func applyMigrationStep(ctx context.Context, store *database.Store, dbName, collName string, step models.MigrationStep) (int64, error) {
+	filter := bson.M(step.Filter)
+	if filter == nil {
+		filter = bson.M{}
+	}
+	switch step.Type {
+	case models.MigrationTransform:
+		return store.TransformData(ctx, dbName, collName, filter, func(doc bson.M) (bson.M, error) {
+			transformed, err := transformData(ctx, step.Transform, doc)
+			return bson.M(transformed), err
+		})
+	case models.MigrationUpdate:
+		return store.UpdateData(ctx, dbName, collName, filter, bson.M(step.Update))
+	case models.MigrationDelete:
+		return store.DeleteData(ctx, dbName, collName, filter)
+	case models.MigrationCreateIndex:
+		return 0, store.CreateDataIndex(ctx, dbName, collName, *step.Index)
+	case models.MigrationDropIndex:
+		return 0, store.DropDataIndex(ctx, dbName, collName, step.Index.Name)
+	}
+	return 0, fmt.Errorf("unknown migration step '%s'", step.Type)
+}
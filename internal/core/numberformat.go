@@ -0,0 +1,184 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"api-genarator/internal/models"
+)
+
+// numberFormatOps are the transformations that read a number from Value or, without a
+// Value, from Field itself (like "bcryptHash").
+var numberFormatOps = map[string]bool{"round": true, "floor": true, "ceil": true, "toFixed": true, "formatCurrency": true}
+
+// numberLocale describes how a locale writes numbers and where the currency symbol goes.
+type numberLocale struct {
+	decimal, group string
+	symbolAfter    bool // "1.234,56 €" instead of "€1,234.56"
+	symbolSpace    bool // Space between symbol and amount
+	indianGrouping bool // 12,34,567.00
+}
+
+var numberLocales = map[string]numberLocale{
+	"en-US": {decimal: ".", group: ","},
+	"en-GB": {decimal: ".", group: ","},
+	"th-TH": {decimal: ".", group: ","},
+	"ja-JP": {decimal: ".", group: ","},
+	"zh-CN": {decimal: ".", group: ","},
+	"ko-KR": {decimal: ".", group: ","},
+	"en-IN": {decimal: ".", group: ",", indianGrouping: true},
+	"id-ID": {decimal: ",", group: "."},
+	"pt-BR": {decimal: ",", group: ".", symbolSpace: true},
+	"de-DE": {decimal: ",", group: ".", symbolAfter: true, symbolSpace: true},
+	"es-ES": {decimal: ",", group: ".", symbolAfter: true, symbolSpace: true},
+	"it-IT": {decimal: ",", group: ".", symbolAfter: true, symbolSpace: true},
+	"vi-VN": {decimal: ",", group: ".", symbolAfter: true, symbolSpace: true},
+	"fr-FR": {decimal: ",", group: " ", symbolAfter: true, symbolSpace: true},
+}
+
+// currencyInfo holds the symbol and the number of minor-unit digits (ISO 4217).
+type currencyInfo struct {
+	symbol string
+	digits int
+}
+
+var currencies = map[string]currencyInfo{
+	"USD": {"$", 2}, "EUR": {"€", 2}, "GBP": {"£", 2}, "THB": {"฿", 2}, "JPY": {"¥", 0},
+	"CNY": {"¥", 2}, "KRW": {"₩", 0}, "INR": {"₹", 2}, "IDR": {"Rp", 2}, "VND": {"₫", 0},
+	"BRL": {"R$", 2}, "SGD": {"S$", 2}, "AUD": {"A$", 2}, "CAD": {"CA$", 2}, "CHF": {"CHF", 2},
+	"MYR": {"RM", 2}, "PHP": {"₱", 2}, "HKD": {"HK$", 2},
+}
+
+// applyNumberFormat implements "round", "floor", "ceil", "toFixed" and "formatCurrency".
+// Rounding works on the shortest decimal form of the number, so 1.005 rounds to 1.01
+// rather than to the 1.00 a binary float multiplication would give.
+func applyNumberFormat(t models.Transformation, data map[string]interface{}) (interface{}, error) {
+	source := data[t.Field]
+	if t.Value != nil {
+		source = SubstituteVariables(t.Value, data)
+	}
+	number, ok := convertToFloat64(source)
+	if !ok {
+		return nil, fmt.Errorf("value %v is not a number", source)
+	}
+	precision := 0
+	if t.Precision != nil {
+		precision = *t.Precision
+	}
+
+	switch t.Operation {
+	case "round", "floor", "ceil":
+		rounded, _ := roundDecimal(number, precision, t.Operation).Float64()
+		return rounded, nil
+	case "toFixed":
+		return roundDecimal(number, precision, "round").FloatString(max(precision, 0)), nil
+	}
+
+	// formatCurrency
+	code := strings.ToUpper(InterpolateString(t.Currency, data))
+	currency, known := currencies[code]
+	if !known {
+		if code == "" {
+			return nil, fmt.Errorf("formatCurrency requires a currency")
+		}
+		currency = currencyInfo{symbol: code, digits: 2}
+	}
+	if t.Precision != nil {
+		currency.digits = precision
+	}
+	localeName := defaultString(InterpolateString(t.Locale, data), "en-US")
+	locale, known := numberLocales[localeName]
+	if !known {
+		log.Printf("WARN: Unknown locale '%s' for formatCurrency, using en-US", localeName)
+		locale = numberLocales["en-US"]
+	}
+	if currency.symbol == code {
+		locale.symbolSpace = true // "CHF 10.00", not "CHF10.00"
+	}
+	return formatMoney(roundDecimal(number, currency.digits, "round"), currency, locale), nil
+}
+
+// roundDecimal rounds x to precision decimal places: "round" (half away from zero),
+// "floor" or "ceil". A negative precision rounds to tens, hundreds, ...
+func roundDecimal(x float64, precision int, mode string) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(x, 'f', -1, 64))
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(precision))), nil))
+	if precision >= 0 {
+		r.Mul(r, scale)
+	} else {
+		r.Quo(r, scale)
+	}
+
+	quo, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int)) // Truncated toward zero
+	if rem.Sign() != 0 {
+		switch mode {
+		case "floor":
+			if r.Sign() < 0 {
+				quo.Sub(quo, big.NewInt(1))
+			}
+		case "ceil":
+			if r.Sign() > 0 {
+				quo.Add(quo, big.NewInt(1))
+			}
+		default:
+			twice := new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2))
+			if twice.Cmp(r.Denom()) >= 0 {
+				quo.Add(quo, big.NewInt(int64(r.Sign())))
+			}
+		}
+	}
+
+	result := new(big.Rat).SetInt(quo)
+	if precision >= 0 {
+		return result.Quo(result, scale)
+	}
+	return result.Mul(result, scale)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// formatMoney writes an already rounded amount with the locale's separators and symbol.
+func formatMoney(amount *big.Rat, currency currencyInfo, locale numberLocale) string {
+	digits := currency.digits
+	if digits < 0 {
+		digits = 0
+	}
+	text := new(big.Rat).Abs(amount).FloatString(digits)
+	whole, fraction, _ := strings.Cut(text, ".")
+
+	var b strings.Builder
+	for i, c := range whole {
+		remaining := len(whole) - i
+		if i > 0 && remaining%3 == 0 && (!locale.indianGrouping || remaining == 3) {
+			b.WriteString(locale.group)
+		} else if i > 0 && locale.indianGrouping && remaining > 3 && (remaining-3)%2 == 0 {
+			b.WriteString(locale.group)
+		}
+		b.WriteRune(c)
+	}
+	number := b.String()
+	if fraction != "" {
+		number += locale.decimal + fraction
+	}
+
+	space := ""
+	if locale.symbolSpace {
+		space = " "
+	}
+	sign := ""
+	if amount.Sign() < 0 {
+		sign = "-"
+	}
+	if locale.symbolAfter {
+		return sign + number + space + currency.symbol
+	}
+	return sign + currency.symbol + space + number
+}
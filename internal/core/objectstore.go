@@ -0,0 +1,117 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// objectURLExpiry is how long the presigned URLs used for server-side requests stay valid.
+const objectURLExpiry = 5 * time.Minute
+
+// ObjectInfo describes an object returned by ListObjects.
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// PutObject uploads body to the configured S3-compatible storage (S3, MinIO, or GCS
+// through its interoperability endpoint with HMAC keys).
+func PutObject(ctx context.Context, bucket, key string, body []byte, contentType string) error {
+	resp, err := objectRequest(ctx, http.MethodPut, bucket, key, nil, body, contentType)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetObject downloads an object, up to limit bytes.
+func GetObject(ctx context.Context, bucket, key string, limit int64) ([]byte, error) {
+	resp, err := objectRequest(ctx, http.MethodGet, bucket, key, nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("read object %s: %w", key, err)
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("object %s is larger than %d bytes", key, limit)
+	}
+	return body, nil
+}
+
+// DeleteObject removes an object; deleting a missing object is not an error.
+func DeleteObject(ctx context.Context, bucket, key string) error {
+	resp, err := objectRequest(ctx, http.MethodDelete, bucket, key, nil, nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ListObjects returns every object under prefix, in key order.
+func ListObjects(ctx context.Context, bucket, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	params := map[string]string{"list-type": "2", "prefix": prefix}
+	for {
+		resp, err := objectRequest(ctx, http.MethodGet, bucket, "", params, nil, "")
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(io.LimitReader(resp.Body, 10<<20)).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode object list: %w", err)
+		}
+		for _, c := range page.Contents {
+			objects = append(objects, ObjectInfo{Key: c.Key, Size: c.Size, LastModified: c.LastModified})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		params["continuation-token"] = page.NextContinuationToken
+	}
+}
+
+// objectRequest sends a request through a presigned URL and fails on non-2xx answers.
+func objectRequest(ctx context.Context, method, bucket, key string, params map[string]string, body []byte, contentType string) (*http.Response, error) {
+	signed, err := presignS3With(currentSettings().S3, method, bucket, key, params, objectURLExpiry, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, signed, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := outboundClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s/%s: %w", method, bucket, key, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s/%s: status %d: %s", method, bucket, key, resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return resp, nil
+}
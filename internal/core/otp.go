@@ -0,0 +1,163 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+)
+
+const (
+	defaultOTPLength      = 6
+	defaultOTPTTL         = 5 * time.Minute
+	defaultOTPMaxAttempts = 5
+)
+
+// Outcomes of verifyOtp.
+const (
+	OTPVerified = "verified"
+	OTPInvalid  = "invalid"
+	OTPExpired  = "expired"
+	OTPLocked   = "locked"
+)
+
+// otpAlphabets leave out look-alike characters (0/O, 1/I) from alphanumeric codes.
+var otpAlphabets = map[string]string{
+	"numeric":      "0123456789",
+	"alphanumeric": "23456789ABCDEFGHJKLMNPQRSTUVWXYZ",
+}
+
+// generateOtp issues a new code for the subject, replacing any earlier one, and returns
+// the plain code and its expiry.
+func generateOtp(ctx context.Context, cfg *models.OTPConfig, data map[string]interface{}, store *database.Store, now time.Time) (string, time.Time, error) {
+	secret := currentSettings().OTPSecret
+	if secret == "" {
+		return "", time.Time{}, errors.New("OTP secret is not configured")
+	}
+	id, err := otpID(cfg, data)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	alphabet, ok := otpAlphabets[defaultString(cfg.Alphabet, "numeric")]
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("unsupported OTP alphabet '%s'", cfg.Alphabet)
+	}
+	length := defaultOTPLength
+	if cfg.Length > 0 {
+		length = cfg.Length
+	}
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		code[i] = alphabet[n.Int64()]
+	}
+	saltBytes := make([]byte, 16)
+	if _, err := rand.Read(saltBytes); err != nil {
+		return "", time.Time{}, err
+	}
+
+	ttl := defaultOTPTTL
+	if cfg.TTLSeconds > 0 {
+		ttl = time.Duration(cfg.TTLSeconds) * time.Second
+	}
+	maxAttempts := defaultOTPMaxAttempts
+	if cfg.MaxAttempts > 0 {
+		maxAttempts = cfg.MaxAttempts
+	}
+	salt := hex.EncodeToString(saltBytes)
+	record := &models.OTPCode{
+		ID:          id,
+		Purpose:     cfg.Purpose,
+		Salt:        salt,
+		Hash:        hashOTP(secret, salt, string(code)),
+		MaxAttempts: maxAttempts,
+		CreatedAt:   now.UTC(),
+		ExpiresAt:   now.Add(ttl).UTC(),
+	}
+	if err := store.SaveOTP(ctx, record); err != nil {
+		return "", time.Time{}, err
+	}
+	return string(code), record.ExpiresAt, nil
+}
+
+// verifyOtp checks the submitted code. Every guess counts against MaxAttempts; a
+// verified code is consumed.
+func verifyOtp(ctx context.Context, cfg *models.OTPConfig, data map[string]interface{}, store *database.Store, now time.Time) (string, error) {
+	secret := currentSettings().OTPSecret
+	if secret == "" {
+		return "", errors.New("OTP secret is not configured")
+	}
+	id, err := otpID(cfg, data)
+	if err != nil {
+		return "", err
+	}
+	var code string
+	switch v, _ := lookupField(data, defaultString(cfg.CodeField, "code")); v := v.(type) {
+	case string:
+		code = strings.ToUpper(strings.TrimSpace(v))
+	case float64: // Numeric codes sent as JSON numbers
+		code = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	if code == "" {
+		return OTPInvalid, nil
+	}
+
+	// The guess is counted before the comparison: checking the count first and adding
+	// to it after would let parallel guesses bypass MaxAttempts
+	record, claimed, err := store.ClaimOTPAttempt(ctx, id)
+	if errors.Is(err, database.ErrNotFound) {
+		return OTPInvalid, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case now.After(record.ExpiresAt):
+		return OTPExpired, nil // The TTL monitor only runs once a minute
+	case !claimed:
+		return OTPLocked, nil
+	}
+	if !hmac.Equal([]byte(hashOTP(secret, record.Salt, code)), []byte(record.Hash)) {
+		return OTPInvalid, nil
+	}
+	consumed, err := store.ConsumeOTP(ctx, id, record.Hash)
+	if err != nil {
+		return "", err
+	}
+	if !consumed {
+		return OTPInvalid, nil
+	}
+	return OTPVerified, nil
+}
+
+// otpID keys a code by purpose and subject without storing the subject itself.
+func otpID(cfg *models.OTPConfig, data map[string]interface{}) (string, error) {
+	subject := InterpolateString(cfg.Subject, data)
+	if subject == "" {
+		return "", errors.New("OTP subject is empty")
+	}
+	sum := sha256.Sum256([]byte(cfg.Purpose + "\x00" + subject))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// hashOTP is HMAC-SHA256 over salt and code, keyed with the server's OTP secret so
+// stored hashes cannot be brute-forced without it.
+func hashOTP(secret, salt, code string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(salt))
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,11 @@
+package core
+
+import (
+	"net/http"
+	"time"
+)
+
+// outboundClient is shared by actions that call external services.
+// Per-request deadlines come from the flow context, which also supplies the
+// X-Request-Id and traceparent headers (see correlationTransport).
+var outboundClient = &http.Client{Timeout: 15 * time.Second, Transport: correlationTransport{base: http.DefaultTransport}}
@@ -0,0 +1,141 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+)
+
+// Outbox delivery tuning.
+const (
+	outboxMaxAttempts = 10
+	outboxLease       = time.Minute
+	outboxMaxBackoff  = 5 * time.Minute
+)
+
+// BuildOutboxMessages renders the events of a definition for a saved document.
+// An event without a Payload template sends the saved document itself. The request's
+// correlation is kept with each message, since delivery happens after the request.
+func BuildOutboxMessages(ctx context.Context, api models.ApiDefinition, saved map[string]interface{}) []models.OutboxMessage {
+	corr, _ := CorrelationFrom(ctx)
+	messages := make([]models.OutboxMessage, 0, len(api.Events))
+	for _, event := range api.Events {
+		payload := interface{}(saved)
+		if event.Payload != nil {
+			payload = SubstituteVariables(event.Payload, saved)
+		}
+		messages = append(messages, models.OutboxMessage{
+			APIName:     api.Name,
+			Event:       event.Name,
+			URL:         InterpolateString(event.WebhookURL, saved),
+			Headers:     event.Headers,
+			Payload:     payload,
+			RequestID:   corr.RequestID,
+			TraceParent: corr.TraceParent,
+		})
+	}
+	return messages
+}
+
+// RunOutboxDispatcher relays outbox messages until ctx is cancelled. Delivery is
+// at-least-once: receivers should deduplicate on the X-Outbox-Id header.
+func RunOutboxDispatcher(ctx context.Context, store *database.Store, interval time.Duration) {
+	log.Printf("INFO: Outbox dispatcher started (poll interval %s)", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// Drain everything that is due before sleeping again
+		for ctx.Err() == nil {
+			msg, err := store.ClaimOutboxMessage(ctx, outboxLease)
+			if err != nil {
+				if !errors.Is(err, database.ErrNotFound) && ctx.Err() == nil {
+					log.Printf("ERROR: Outbox dispatcher failed to claim message: %v", err)
+				}
+				break
+			}
+			dispatchOutboxMessage(ctx, store, msg)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("INFO: Outbox dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func dispatchOutboxMessage(ctx context.Context, store *database.Store, msg *models.OutboxMessage) {
+	err := deliverWebhook(ctx, msg)
+	if err == nil {
+		if err := store.CompleteOutboxMessage(ctx, msg.ID); err != nil {
+			log.Printf("ERROR: Outbox message %s delivered but not marked: %v", msg.ID.Hex(), err)
+		}
+		log.Printf("INFO: Delivered outbox event '%s' for API '%s' (ID: %s, request %s)", msg.Event, msg.APIName, msg.ID.Hex(), msg.RequestID)
+		return
+	}
+
+	attempts := msg.Attempts + 1
+	giveUp := attempts >= outboxMaxAttempts
+	backoff := time.Duration(1<<min(attempts, 16)) * time.Second
+	if backoff > outboxMaxBackoff {
+		backoff = outboxMaxBackoff
+	}
+	log.Printf("WARN: Delivery of outbox event '%s' (ID: %s, request %s) failed on attempt %d: %v", msg.Event, msg.ID.Hex(), msg.RequestID, attempts, err)
+	if markErr := store.FailOutboxMessage(ctx, msg.ID, err, time.Now().UTC().Add(backoff), giveUp); markErr != nil {
+		log.Printf("ERROR: Failed to record outbox delivery failure for %s: %v", msg.ID.Hex(), markErr)
+	}
+	if giveUp {
+		entry := &models.DeadLetter{
+			Source:    "webhook",
+			APIName:   msg.APIName,
+			Documents: []map[string]interface{}{{"outboxId": msg.ID, "event": msg.Event, "url": msg.URL, "payload": msg.Payload}},
+			Error:     err.Error(),
+			Attempts:  attempts,
+		}
+		if dlErr := store.InsertDeadLetter(ctx, entry); dlErr != nil {
+			log.Printf("ERROR: Outbox event %s failed permanently and could not be dead-lettered: %v", msg.ID.Hex(), dlErr)
+		}
+	}
+}
+
+func deliverWebhook(ctx context.Context, msg *models.OutboxMessage) error {
+	body, err := json.Marshal(msg.Payload)
+	if err != nil {
+		return fmt.Errorf("cannot encode payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, msg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Name", msg.Event)
+	req.Header.Set("X-Outbox-Id", msg.ID.Hex())
+	if msg.RequestID != "" {
+		req.Header.Set("X-Request-Id", msg.RequestID)
+		req.Header.Set("traceparent", msg.TraceParent)
+	}
+	for name, value := range msg.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := outboundClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-genarator/internal/models"
+)
+
+// PaymentProvider is a server-configured account at a Stripe-compatible payment API for
+// the "payment" action. Keys live in the server settings, definitions only name the provider.
+type PaymentProvider struct {
+	APIKey        string `json:"apiKey"`                  // Secret API key ("sk_live_...")
+	BaseURL       string `json:"baseUrl,omitempty"`       // (Optional) API base URL (default "https://api.stripe.com")
+	WebhookSecret string `json:"webhookSecret,omitempty"` // Endpoint signing secret ("whsec_...") for "verifyWebhook"
+}
+
+var paymentOperations = map[string]bool{"createIntent": true, "charge": true, "refund": true, "verifyWebhook": true}
+
+// payment runs one payment operation and returns the provider's object (PaymentIntent,
+// Refund or webhook Event). declined is set for card declines and invalid webhook
+// signatures; the result then describes the failure instead.
+func payment(ctx context.Context, cfg *models.PaymentConfig, data map[string]interface{}) (result map[string]interface{}, declined bool, err error) {
+	name := defaultString(cfg.Provider, "default")
+	provider, ok := currentSettings().PaymentProviders[name]
+	if !ok {
+		return nil, false, fmt.Errorf("payment provider '%s' is not configured", name)
+	}
+
+	if cfg.Operation == "verifyWebhook" {
+		inbound := inboundFrom(ctx)
+		if inbound == nil {
+			return nil, false, errors.New("verifyWebhook needs the inbound request (not available in background jobs)")
+		}
+		if provider.WebhookSecret == "" {
+			return nil, false, fmt.Errorf("payment provider '%s' has no webhookSecret", name)
+		}
+		if _, err := verifyStripeSignature(inbound.header("Stripe-Signature"), provider.WebhookSecret, inbound.body, time.Now(), defaultWebhookTolerance); err != nil {
+			if errors.Is(err, ErrWebhookSignature) {
+				return map[string]interface{}{"status": "invalid", "message": err.Error()}, true, nil
+			}
+			return nil, false, err
+		}
+		var event map[string]interface{}
+		if err := json.Unmarshal(inbound.body, &event); err != nil {
+			return nil, false, fmt.Errorf("webhook body is not a JSON event: %w", err)
+		}
+		return event, false, nil
+	}
+
+	form := url.Values{}
+	var endpoint string
+	switch cfg.Operation {
+	case "createIntent", "charge":
+		amount, err := paymentAmount(cfg.Amount, data)
+		if err != nil {
+			return nil, false, err
+		}
+		if amount == 0 {
+			return nil, false, errors.New("payment amount is required")
+		}
+		currency := strings.ToLower(InterpolateString(cfg.Currency, data))
+		if currency == "" {
+			return nil, false, errors.New("payment currency is required")
+		}
+		endpoint = "/v1/payment_intents"
+		form.Set("amount", strconv.FormatInt(amount, 10))
+		form.Set("currency", currency)
+		setFormTemplate(form, "customer", cfg.Customer, data)
+		setFormTemplate(form, "payment_method", cfg.PaymentMethod, data)
+		setFormTemplate(form, "description", cfg.Description, data)
+		if cfg.Operation == "charge" {
+			if form.Get("payment_method") == "" {
+				return nil, false, errors.New("charge requires a payment method")
+			}
+			// Confirm server-side; methods that need a redirect cannot complete here
+			form.Set("confirm", "true")
+			form.Set("automatic_payment_methods[enabled]", "true")
+			form.Set("automatic_payment_methods[allow_redirects]", "never")
+		}
+	case "refund":
+		intent := InterpolateString(cfg.PaymentIntent, data)
+		if intent == "" {
+			return nil, false, errors.New("refund requires a payment intent")
+		}
+		endpoint = "/v1/refunds"
+		form.Set("payment_intent", intent)
+		amount, err := paymentAmount(cfg.Amount, data)
+		if err != nil {
+			return nil, false, err
+		}
+		if amount > 0 {
+			form.Set("amount", strconv.FormatInt(amount, 10)) // Partial refund
+		}
+		setFormTemplate(form, "reason", cfg.Reason, data)
+	default:
+		return nil, false, fmt.Errorf("unsupported payment operation '%s'", cfg.Operation)
+	}
+	for key, value := range cfg.Metadata {
+		form.Set("metadata["+key+"]", InterpolateString(value, data))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimRight(defaultString(provider.BaseURL, "https://api.stripe.com"), "/")+endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+provider.APIKey)
+	if key := InterpolateString(cfg.IdempotencyKey, data); key != "" {
+		req.Header.Set("Idempotency-Key", key) // Makes a retried flow safe to charge once
+	}
+
+	resp, err := outboundClient.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("payment request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return nil, false, fmt.Errorf("payment provider returned %s with an unreadable body", resp.Status)
+	}
+	if resp.StatusCode < http.StatusBadRequest {
+		return body, false, nil
+	}
+	providerErr, _ := body["error"].(map[string]interface{})
+	message, _ := providerErr["message"].(string)
+	if providerErr["type"] == "card_error" {
+		declinedResult := map[string]interface{}{"status": "declined", "message": message}
+		for from, to := range map[string]string{"code": "code", "decline_code": "declineCode"} {
+			if v, ok := providerErr[from]; ok {
+				declinedResult[to] = v
+			}
+		}
+		if intent, ok := providerErr["payment_intent"].(map[string]interface{}); ok {
+			declinedResult["paymentIntent"] = intent["id"]
+		}
+		return declinedResult, true, nil
+	}
+	return nil, false, fmt.Errorf("payment provider returned %s: %s", resp.Status, message)
+}
+
+// paymentAmount resolves an amount in the currency's minor unit (e.g. cents) from a
+// number or a "$field" reference. A missing amount is 0.
+func paymentAmount(template interface{}, data map[string]interface{}) (int64, error) {
+	value := SubstituteVariables(template, data)
+	if value == nil {
+		return 0, nil
+	}
+	f, ok := convertToFloat64(value)
+	if !ok || f < 0 || f != math.Trunc(f) {
+		return 0, fmt.Errorf("payment amount %v must be a non-negative integer in the minor unit", value)
+	}
+	return int64(f), nil
+}
+
+func setFormTemplate(form url.Values, key, template string, data map[string]interface{}) {
+	if value := InterpolateString(template, data); value != "" {
+		form.Set(key, value)
+	}
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"strings"
+
+	"api-genarator/internal/models"
+)
+
+// FieldAccess is the set of fields one caller may read and write on a definition.
+type FieldAccess struct {
+	readAll, writeAll bool
+	read, write       map[string]bool
+}
+
+// CallerRoles collects the roles and scopes carried by token claims: "roles" and "scp"
+// (array or string), "role" and "scope" (space-separated string).
+func CallerRoles(claims map[string]interface{}) []string {
+	var roles []string
+	for _, claim := range []string{"roles", "role", "scope", "scp"} {
+		switch v := claims[claim].(type) {
+		case string:
+			roles = append(roles, strings.Fields(v)...)
+		case []interface{}:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					roles = append(roles, s)
+				}
+			}
+		}
+	}
+	return roles
+}
+
+// ResolveFieldAccess merges the permission entries matching the caller's roles.
+// It returns false when no entry applies to the caller at all.
+func ResolveFieldAccess(perms []models.FieldPermission, roles []string) (*FieldAccess, bool) {
+	held := make(map[string]bool, len(roles)+1)
+	held["*"] = true
+	for _, r := range roles {
+		held[r] = true
+	}
+
+	access := &FieldAccess{read: map[string]bool{"_id": true}, write: make(map[string]bool)}
+	matched := false
+	for _, perm := range perms {
+		if !holdsAny(held, perm.Roles) {
+			continue
+		}
+		matched = true
+		for _, f := range perm.Read {
+			access.readAll = access.readAll || f == "*"
+			access.read[f] = true
+		}
+		for _, f := range perm.Write {
+			access.writeAll = access.writeAll || f == "*"
+			access.write[f] = true
+		}
+	}
+	return access, matched
+}
+
+func holdsAny(held map[string]bool, roles []string) bool {
+	for _, r := range roles {
+		if held[r] {
+			return true
+		}
+	}
+	return false
+}
+
+// CanRead reports whether the top-level field may be returned to the caller.
+func (a *FieldAccess) CanRead(field string) bool {
+	return a.readAll || a.read[field]
+}
+
+// CanWrite reports whether the caller may set the top-level field.
+func (a *FieldAccess) CanWrite(field string) bool {
+	return a.writeAll || a.write[field]
+}
+
+// StripDocument returns a copy of doc without the fields the caller may not read.
+func (a *FieldAccess) StripDocument(doc map[string]interface{}) map[string]interface{} {
+	if a.readAll {
+		return doc
+	}
+	stripped := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		if a.read[k] {
+			stripped[k] = v
+		}
+	}
+	return stripped
+}
@@ -0,0 +1,213 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-genarator/internal/models"
+)
+
+const defaultPresignExpiry = 15 * time.Minute
+
+// Query parameters carried by URLs signed for this server's own endpoints.
+const (
+	SignatureExpiresParam = "expires"
+	SignatureParam        = "signature"
+)
+
+// presignURL builds a time-limited URL for the action's configuration using the current data state.
+func presignURL(cfg *models.PresignConfig, data map[string]interface{}, now time.Time) (string, error) {
+	key := InterpolateString(cfg.Key, data)
+	if key == "" {
+		return "", errors.New("presign key resolved to an empty string")
+	}
+	expiry := defaultPresignExpiry
+	if cfg.ExpiresIn > 0 {
+		expiry = time.Duration(cfg.ExpiresIn) * time.Second
+	}
+
+	s := currentSettings()
+	switch cfg.Target {
+	case "s3":
+		method := strings.ToUpper(cfg.Method)
+		if method == "" {
+			method = "GET"
+		}
+		return presignS3(s.S3, method, InterpolateString(cfg.Bucket, data), key, expiry, now)
+	case "local", "":
+		return SignLocalURL(s, key, now.Add(expiry))
+	default:
+		return "", fmt.Errorf("unsupported presign target: %s", cfg.Target)
+	}
+}
+
+// SignLocalURL signs a path on this server, including its query string, so it can be
+// verified with VerifyLocalSignature.
+func SignLocalURL(s Settings, target string, expiresAt time.Time) (string, error) {
+	if s.URLSigningKey == "" {
+		return "", errors.New("URL signing key is not configured")
+	}
+	path, rawQuery, _ := strings.Cut(target, "?")
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", fmt.Errorf("invalid query in signed path: %w", err)
+	}
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	query.Del(SignatureParam)
+	query.Set(SignatureExpiresParam, expires)
+	query.Set(SignatureParam, localSignature(s.URLSigningKey, path, query))
+	return strings.TrimSuffix(s.PublicBaseURL, "/") + path + "?" + query.Encode(), nil
+}
+
+// VerifyLocalSignature checks a signature produced by SignLocalURL for the given path and
+// query parameters; a parameter added, removed or changed after signing invalidates it.
+func VerifyLocalSignature(path string, query url.Values, now time.Time) error {
+	s := currentSettings()
+	if s.URLSigningKey == "" {
+		return errors.New("URL signing key is not configured")
+	}
+	expires, signature := query.Get(SignatureExpiresParam), query.Get(SignatureParam)
+	if expires == "" || signature == "" {
+		return errors.New("missing signature")
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return errors.New("invalid signature expiry")
+	}
+	if now.Unix() > expiresAt {
+		return errors.New("signature expired")
+	}
+	expected := localSignature(s.URLSigningKey, path, query)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// localSignature signs the path, the sorted query parameters and the expiry, so parameters
+// a handler reads (e.g. a file ID) are bound to the link.
+func localSignature(key, path string, query url.Values) string {
+	params := url.Values{}
+	for k, v := range query {
+		if k != SignatureParam && k != SignatureExpiresParam {
+			params[k] = v
+		}
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(path + "\n" + params.Encode() + "\n" + query.Get(SignatureExpiresParam)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// presignS3 creates an AWS Signature Version 4 query-string presigned URL.
+func presignS3(s3 S3Settings, method, bucket, key string, expiry time.Duration, now time.Time) (string, error) {
+	return presignS3With(s3, method, bucket, key, nil, expiry, now)
+}
+
+// presignS3With is presignS3 with extra query parameters signed into the URL (e.g.
+// "list-type" and "prefix" for ListObjectsV2 on the bucket, where key is empty).
+func presignS3With(s3 S3Settings, method, bucket, key string, params map[string]string, expiry time.Duration, now time.Time) (string, error) {
+	if s3.AccessKeyID == "" || s3.SecretAccessKey == "" || s3.Region == "" {
+		return "", errors.New("S3 credentials/region are not configured")
+	}
+	if bucket == "" {
+		return "", errors.New("S3 bucket is required")
+	}
+	if expiry > 7*24*time.Hour {
+		expiry = 7 * 24 * time.Hour // SigV4 maximum
+	}
+
+	// Virtual-hosted style for AWS, path style for custom endpoints (MinIO etc.)
+	scheme, host := "https", fmt.Sprintf("%s.s3.%s.amazonaws.com", bucket, s3.Region)
+	canonicalURI := "/" + awsURIEncode(strings.TrimPrefix(key, "/"), false)
+	if s3.Endpoint != "" {
+		endpoint, err := url.Parse(s3.Endpoint)
+		if err != nil || endpoint.Host == "" {
+			return "", fmt.Errorf("invalid S3 endpoint: %s", s3.Endpoint)
+		}
+		scheme, host = endpoint.Scheme, endpoint.Host
+		canonicalURI = "/" + awsURIEncode(bucket, true) + canonicalURI
+	}
+
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+	scope := dateStamp + "/" + s3.Region + "/s3/aws4_request"
+
+	query := map[string]string{
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    s3.AccessKeyID + "/" + scope,
+		"X-Amz-Date":          amzDate,
+		"X-Amz-Expires":       strconv.Itoa(int(expiry.Seconds())),
+		"X-Amz-SignedHeaders": "host",
+	}
+	if s3.SessionToken != "" {
+		query["X-Amz-Security-Token"] = s3.SessionToken
+	}
+	for k, v := range params {
+		query[k] = v
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, awsURIEncode(k, true)+"="+awsURIEncode(query[k], true))
+	}
+	canonicalQuery := strings.Join(parts, "&")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		canonicalURI,
+		canonicalQuery,
+		"host:" + host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+s3.SecretAccessKey), dateStamp)
+	signingKey = hmacSHA256(signingKey, s3.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	return fmt.Sprintf("%s://%s%s?%s&X-Amz-Signature=%s", scheme, host, canonicalURI, canonicalQuery, signature), nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsURIEncode encodes per the SigV4 rules: unreserved characters are kept,
+// '/' is kept in object keys unless encodeSlash is set.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9'),
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,488 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"api-genarator/internal/auth"
+	"api-genarator/internal/database"
+	"api-genarator/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// PushSettings holds the push provider credentials of the "push" action.
+type PushSettings struct {
+	FCMCredentialsFile string // Firebase service account JSON (project, client email, private key)
+	APNsKeyFile        string // APNs auth key (.p8)
+	APNsKeyID          string // Key ID of the .p8 key
+	APNsTeamID         string // Apple developer team ID
+	APNsTopic          string // App bundle ID
+	APNsSandbox        bool   // Use the development APNs environment
+}
+
+const (
+	pushConcurrency = 10   // Notifications in flight per action
+	pushMaxTokens   = 5000 // Upper bound on recipients of a single action
+	fcmScope        = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+// pushTarget sends one notification to one device token. invalid reports a token the
+// provider says is unregistered or malformed, so the flow can remove it. authorize
+// obtains the provider credential once before a batch is sent.
+type pushTarget interface {
+	authorize(ctx context.Context) error
+	send(ctx context.Context, token string, msg pushMessage) (invalid bool, err error)
+}
+
+type pushMessage struct {
+	title, body, sound string
+	badge              int
+	data               map[string]string
+}
+
+// sendPush resolves the recipient tokens, sends the rendered notification to each of
+// them (pushConcurrency at a time) and reports the per-token outcome:
+// {"sent", "failed", "invalidTokens", "errors": [{"token", "error"}]}.
+// Only configuration and credential problems fail the action.
+func sendPush(ctx context.Context, cfg *models.PushConfig, data map[string]interface{}, store *database.Store, dbName string) (map[string]interface{}, error) {
+	tokens, err := pushTokens(ctx, cfg, data, store, dbName)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) > pushMaxTokens {
+		return nil, fmt.Errorf("%d push tokens exceed the limit of %d per action", len(tokens), pushMaxTokens)
+	}
+	target, err := pushTargetFor(cfg.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return map[string]interface{}{"sent": 0, "failed": 0, "invalidTokens": []interface{}{}, "errors": []interface{}{}}, nil
+	}
+	if err := target.authorize(ctx); err != nil {
+		return nil, err
+	}
+	msg := pushMessage{
+		title: InterpolateString(cfg.Title, data),
+		body:  InterpolateString(cfg.Body, data),
+		sound: cfg.Sound,
+		badge: cfg.Badge,
+		data:  make(map[string]string, len(cfg.Data)),
+	}
+	for k, v := range cfg.Data {
+		msg.data[k] = InterpolateString(v, data)
+	}
+
+	type outcome struct {
+		invalid bool
+		err     error
+	}
+	outcomes := make([]outcome, len(tokens))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, pushConcurrency)
+	for i, token := range tokens {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer func() { <-slots; wg.Done() }()
+			invalid, err := target.send(ctx, token, msg)
+			outcomes[i] = outcome{invalid, err}
+		}()
+	}
+	wg.Wait()
+
+	sent := 0
+	invalidTokens := []interface{}{}
+	failures := []interface{}{}
+	for i, o := range outcomes {
+		if o.err == nil {
+			sent++
+			continue
+		}
+		if o.invalid {
+			invalidTokens = append(invalidTokens, tokens[i])
+		}
+		failures = append(failures, map[string]interface{}{"token": tokens[i], "error": o.err.Error()})
+	}
+	return map[string]interface{}{
+		"sent":          sent,
+		"failed":        len(failures),
+		"invalidTokens": invalidTokens,
+		"errors":        failures,
+	}, nil
+}
+
+// pushTokens collects the unique tokens from cfg.Tokens (a "$field" holding a token or
+// an array of tokens) and from the cfg.Lookup collection query.
+func pushTokens(ctx context.Context, cfg *models.PushConfig, data map[string]interface{}, store *database.Store, dbName string) ([]string, error) {
+	var values []interface{}
+	if cfg.Tokens != "" {
+		switch v := SubstituteVariables(cfg.Tokens, data).(type) {
+		case string:
+			values = append(values, v)
+		case []interface{}:
+			values = append(values, v...)
+		case nil:
+		default:
+			return nil, fmt.Errorf("push tokens must be a string or an array, got %T", v)
+		}
+	}
+	if l := cfg.Lookup; l != nil {
+		filter := bson.M{}
+		if l.Filter != nil {
+			rendered, ok := SubstituteVariables(l.Filter, data).(map[string]interface{})
+			if !ok {
+				return nil, errors.New("push lookup filter must be an object")
+			}
+			filter = bson.M(rendered)
+		}
+		found, err := store.DistinctData(ctx, defaultString(l.Database, dbName), l.Collection, l.Field, filter)
+		if err != nil {
+			return nil, fmt.Errorf("push token lookup failed: %w", err)
+		}
+		values = append(values, found...)
+	}
+
+	seen := make(map[string]bool, len(values))
+	tokens := make([]string, 0, len(values))
+	for _, v := range values {
+		if token, ok := v.(string); ok && token != "" && !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+var (
+	pushTargetsMu sync.Mutex
+	pushTargets   = map[string]pushTarget{}
+)
+
+// pushTargetFor returns the (cached) provider client; it keeps the access token between actions.
+func pushTargetFor(provider string) (pushTarget, error) {
+	s := currentSettings().Push
+	var key string
+	switch provider {
+	case "fcm":
+		key = "fcm:" + s.FCMCredentialsFile
+	case "apns":
+		key = fmt.Sprintf("apns:%s:%s:%s:%s:%t", s.APNsKeyFile, s.APNsKeyID, s.APNsTeamID, s.APNsTopic, s.APNsSandbox)
+	default:
+		return nil, fmt.Errorf("unsupported push provider '%s'", provider)
+	}
+
+	pushTargetsMu.Lock()
+	defer pushTargetsMu.Unlock()
+	if t, ok := pushTargets[key]; ok {
+		return t, nil
+	}
+	var t pushTarget
+	var err error
+	if provider == "fcm" {
+		t, err = newFCMTarget(s.FCMCredentialsFile)
+	} else {
+		t, err = newAPNsTarget(s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	pushTargets[key] = t
+	return t, nil
+}
+
+// --- FCM (HTTP v1) ---
+
+type fcmTarget struct {
+	projectID   string
+	clientEmail string
+	tokenURI    string
+	key         crypto.Signer
+
+	mu          sync.Mutex
+	accessToken string
+	expires     time.Time
+}
+
+func newFCMTarget(file string) (*fcmTarget, error) {
+	if file == "" {
+		return nil, errors.New("FCM credentials are not configured")
+	}
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read FCM credentials: %w", err)
+	}
+	var account struct {
+		ProjectID   string `json:"project_id"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(raw, &account); err != nil {
+		return nil, fmt.Errorf("invalid FCM credentials: %w", err)
+	}
+	key, err := parsePrivateKeyPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid FCM credentials: %w", err)
+	}
+	return &fcmTarget{
+		projectID:   account.ProjectID,
+		clientEmail: account.ClientEmail,
+		tokenURI:    defaultString(account.TokenURI, "https://oauth2.googleapis.com/token"),
+		key:         key,
+	}, nil
+}
+
+// token exchanges a signed service account assertion for an OAuth access token, reusing
+// it until shortly before it expires.
+func (f *fcmTarget) token(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.accessToken != "" && time.Now().Before(f.expires) {
+		return f.accessToken, nil
+	}
+	now := time.Now()
+	assertion, err := auth.SignKey(map[string]interface{}{
+		"iss": f.clientEmail, "scope": fcmScope, "aud": f.tokenURI,
+		"iat": now.Unix(), "exp": now.Add(time.Hour).Unix(),
+	}, f.key, "")
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := outboundClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("FCM token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil || resp.StatusCode != http.StatusOK || result.AccessToken == "" {
+		return "", fmt.Errorf("FCM token request returned %s", resp.Status)
+	}
+	f.accessToken = result.AccessToken
+	f.expires = now.Add(time.Duration(result.ExpiresIn)*time.Second - time.Minute)
+	return f.accessToken, nil
+}
+
+func (f *fcmTarget) authorize(ctx context.Context) error {
+	_, err := f.token(ctx)
+	return err
+}
+
+func (f *fcmTarget) send(ctx context.Context, token string, msg pushMessage) (bool, error) {
+	accessToken, err := f.token(ctx)
+	if err != nil {
+		return false, err
+	}
+	message := map[string]interface{}{"token": token}
+	if msg.title != "" || msg.body != "" {
+		message["notification"] = map[string]string{"title": msg.title, "body": msg.body}
+	}
+	if len(msg.data) > 0 {
+		message["data"] = msg.data
+	}
+	// Sound and badge are platform-specific overrides in FCM
+	aps := map[string]interface{}{}
+	if msg.sound != "" {
+		message["android"] = map[string]interface{}{"notification": map[string]string{"sound": msg.sound}}
+		aps["sound"] = msg.sound
+	}
+	if msg.badge > 0 {
+		aps["badge"] = msg.badge
+	}
+	if len(aps) > 0 {
+		message["apns"] = map[string]interface{}{"payload": map[string]interface{}{"aps": aps}}
+	}
+	body, err := json.Marshal(map[string]interface{}{"message": message})
+	if err != nil {
+		return false, err
+	}
+	endpoint := "https://fcm.googleapis.com/v1/projects/" + url.PathEscape(f.projectID) + "/messages:send"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := outboundClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		return false, nil
+	}
+	var result struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&result)
+	code := result.Error.Status
+	for _, d := range result.Error.Details {
+		if d.ErrorCode != "" {
+			code = d.ErrorCode
+		}
+	}
+	invalid := code == "UNREGISTERED" || (code == "INVALID_ARGUMENT" && strings.Contains(result.Error.Message, "token"))
+	return invalid, fmt.Errorf("%s: %s", defaultString(code, resp.Status), result.Error.Message)
+}
+
+// --- APNs (HTTP/2 provider API) ---
+
+type apnsTarget struct {
+	host   string
+	topic  string
+	teamID string
+	keyID  string
+	key    crypto.Signer
+
+	mu     sync.Mutex
+	jwt    string
+	issued time.Time
+}
+
+func newAPNsTarget(s PushSettings) (*apnsTarget, error) {
+	if s.APNsKeyFile == "" || s.APNsKeyID == "" || s.APNsTeamID == "" || s.APNsTopic == "" {
+		return nil, errors.New("APNs key file, key ID, team ID and topic must be configured")
+	}
+	raw, err := os.ReadFile(s.APNsKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read APNs key: %w", err)
+	}
+	key, err := parsePrivateKeyPEM(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid APNs key: %w", err)
+	}
+	host := "https://api.push.apple.com"
+	if s.APNsSandbox {
+		host = "https://api.sandbox.push.apple.com"
+	}
+	return &apnsTarget{host: host, topic: s.APNsTopic, teamID: s.APNsTeamID, keyID: s.APNsKeyID, key: key}, nil
+}
+
+// providerToken returns the ES256 provider token. Apple rejects tokens older than an
+// hour and throttles refreshing more often than every 20 minutes, so it is renewed after 40.
+func (a *apnsTarget) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.jwt != "" && time.Since(a.issued) < 40*time.Minute {
+		return a.jwt, nil
+	}
+	now := time.Now()
+	token, err := auth.SignKey(map[string]interface{}{"iss": a.teamID, "iat": now.Unix()}, a.key, a.keyID)
+	if err != nil {
+		return "", err
+	}
+	a.jwt, a.issued = token, now
+	return token, nil
+}
+
+func (a *apnsTarget) authorize(context.Context) error {
+	_, err := a.providerToken()
+	return err
+}
+
+func (a *apnsTarget) send(ctx context.Context, token string, msg pushMessage) (bool, error) {
+	providerToken, err := a.providerToken()
+	if err != nil {
+		return false, err
+	}
+	aps := map[string]interface{}{}
+	if msg.title != "" || msg.body != "" {
+		aps["alert"] = map[string]string{"title": msg.title, "body": msg.body}
+	}
+	if msg.sound != "" {
+		aps["sound"] = msg.sound
+	}
+	if msg.badge > 0 {
+		aps["badge"] = msg.badge
+	}
+	pushType := "alert"
+	if len(aps) == 0 {
+		aps["content-available"] = 1
+		pushType = "background"
+	}
+	payload := map[string]interface{}{"aps": aps}
+	for k, v := range msg.data {
+		if k != "aps" {
+			payload[k] = v
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.host+"/3/device/"+url.PathEscape(token), bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "bearer "+providerToken)
+	req.Header.Set("apns-topic", a.topic)
+	req.Header.Set("apns-push-type", pushType)
+	if pushType == "background" {
+		req.Header.Set("apns-priority", "5") // Required for background pushes
+	}
+	resp, err := outboundClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return false, nil
+	}
+	var result struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&result)
+	invalid := resp.StatusCode == http.StatusGone || result.Reason == "BadDeviceToken" || result.Reason == "DeviceTokenNotForTopic"
+	return invalid, fmt.Errorf("%s: %s", resp.Status, result.Reason)
+}
+
+// parsePrivateKeyPEM reads a PKCS#8 (or PKCS#1 RSA / SEC 1 EC) private key.
+func parsePrivateKeyPEM(raw []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM private key found")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported private key format in PEM block '%s'", block.Type)
+}
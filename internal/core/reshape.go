@@ -0,0 +1,131 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"api-genarator/internal/models"
+)
+
+// flattenMap turns nested objects into dotted keys: {"a":{"b":1}} -> {"a.b":1}.
+// Arrays and empty objects are kept as values.
+func flattenMap(nested map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{})
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for k, v := range m {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			if child, ok := jpMap(v); ok && len(child) > 0 {
+				walk(key, child)
+			} else {
+				flat[key] = v
+			}
+		}
+	}
+	walk("", nested)
+	return flat
+}
+
+// unflattenMap is the reverse of flattenMap. Keys are applied shortest first, so
+// {"a":{...}} and {"a.b":1} merge instead of one replacing the other.
+func unflattenMap(flat map[string]interface{}) (map[string]interface{}, error) {
+	nested := make(map[string]interface{})
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		di, dj := strings.Count(keys[i], "."), strings.Count(keys[j], ".")
+		return di < dj || di == dj && keys[i] < keys[j]
+	})
+	for _, k := range keys {
+		v := flat[k]
+		if m, ok := jpMap(v); ok {
+			v = copyData(m) // Keys below it are merged into this map, not into the input
+		}
+		if !setField(nested, k, v) {
+			return nil, fmt.Errorf("key '%s' conflicts with a non-object value", k)
+		}
+	}
+	return nested, nil
+}
+
+// detachPath copies the maps along a dotted path so a change below them does not
+// show through in the (shallow-copied) state of earlier actions.
+func detachPath(data map[string]interface{}, path string) {
+	parts := strings.Split(path, ".")
+	current := data
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := jpMap(current[part])
+		if !ok {
+			return
+		}
+		copied := copyData(child)
+		current[part] = copied
+		current = copied
+	}
+}
+
+// removeField deletes a dotted field path and returns the removed value.
+func removeField(data map[string]interface{}, path string) (interface{}, bool) {
+	value, ok := lookupField(data, path)
+	if !ok {
+		return nil, false
+	}
+	detachPath(data, path)
+	parent := interface{}(data)
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		parent, _ = lookupField(data, path[:i])
+		path = path[i+1:]
+	}
+	delete(parent.(map[string]interface{}), path) // lookupField found it, so the parent is a map
+	return value, true
+}
+
+// applyReshape implements "flatten", "unflatten" and "rename". Field is the source;
+// Value, if set, is the destination field (the new name for "rename").
+func applyReshape(t models.Transformation, data map[string]interface{}) error {
+	target, _ := t.Value.(string)
+	if t.Operation == "rename" {
+		if target == "" {
+			return fmt.Errorf("rename needs the new field name in value")
+		}
+		value, ok := removeField(data, t.Field)
+		if !ok {
+			return fmt.Errorf("field not found")
+		}
+		detachPath(data, target)
+		if !setField(data, target, value) {
+			return fmt.Errorf("cannot set '%s'", target)
+		}
+		return nil
+	}
+
+	source, ok := lookupField(data, t.Field)
+	if !ok {
+		return fmt.Errorf("field not found")
+	}
+	m, ok := jpMap(source)
+	if !ok {
+		return fmt.Errorf("field is %T, not an object", source)
+	}
+	var result map[string]interface{}
+	if t.Operation == "flatten" {
+		result = flattenMap(m)
+	} else {
+		var err error
+		if result, err = unflattenMap(m); err != nil {
+			return err
+		}
+	}
+	target = defaultString(target, t.Field)
+	detachPath(data, target)
+	if !setField(data, target, result) {
+		return fmt.Errorf("cannot set '%s'", target)
+	}
+	return nil
+}
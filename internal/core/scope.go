@@ -0,0 +1,43 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrScopeUnresolved is returned when a dataScope value cannot be resolved for the caller
+// (typically because the request carries no valid token).
+var ErrScopeUnresolved = errors.New("data scope cannot be resolved for this request")
+
+// ResolveDataScope substitutes a definition's dataScope (e.g. {"ownerId": "$auth.sub"})
+// with the verified token claims. Every field must resolve to a value; a scope that
+// silently became {"ownerId": null} would match documents without an owner.
+func ResolveDataScope(scope map[string]interface{}, claims map[string]interface{}) (map[string]interface{}, error) {
+	if len(scope) == 0 {
+		return nil, nil
+	}
+	data := map[string]interface{}{"auth": claims}
+	resolved := make(map[string]interface{}, len(scope))
+	for field, template := range scope {
+		value := SubstituteVariables(template, data)
+		if value == nil {
+			return nil, fmt.Errorf("%w: '%s' has no value", ErrScopeUnresolved, field)
+		}
+		resolved[field] = value
+	}
+	return resolved, nil
+}
+
+// ScopeKeys returns the fields of a dataScope in a stable order.
+func ScopeKeys(scope map[string]interface{}) []string {
+	if len(scope) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(scope))
+	for k := range scope {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,62 @@
+package core
+
+import "sync"
+
+// Settings holds server-level configuration used by flow actions
+// (secrets, provider endpoints). It is populated once at startup via Configure.
+type Settings struct {
+	PublicBaseURL string // Externally reachable base URL of this server (used for self-signed URLs)
+	URLSigningKey string // HMAC key for signing URLs to this server's own endpoints
+	S3            S3Settings
+	JWTKeys       map[string]string // Named HMAC keys for "signJwt" ("default" is used when the action names none)
+	JWTIssuer     string            // (Optional) iss claim added to minted tokens
+
+	CaptchaSecrets   map[string]string          // Provider ("recaptcha", "hcaptcha", "turnstile") -> secret key
+	WebhookSecrets   map[string]string          // Named shared secrets for webhook signature verification
+	SFTPTargets      map[string]SFTPTarget      // Named SFTP locations for the "fileDrop" action
+	LDAPDirectories  map[string]LDAPDirectory   // Named directory servers for the "ldapLookup" action
+	PaymentProviders map[string]PaymentProvider // Named payment provider accounts for the "payment" action
+	Push             PushSettings
+	SMSProviders     map[string]SMSProvider // Named SMS gateway accounts for the "sendSms" action
+	OTPSecret        string                 // HMAC key for the stored hashes of one-time codes
+	GeoIPDatabases   []string               // MaxMind DB files (e.g. GeoLite2-City, GeoLite2-ASN) for the "geoip" transform
+	Environment      string                 // Deployment environment (e.g. "production", "staging") for feature flags
+	FeatureFlags     FeatureFlagSettings
+	I18n             I18nSettings
+	Slow             SlowThresholds
+
+	Debug bool // Include structured flow error details in API responses
+}
+
+// S3Settings configures S3-compatible object storage access.
+type S3Settings struct {
+	Region          string
+	Endpoint        string // (Optional) Custom endpoint for S3-compatible storage (path-style URLs)
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // (Optional) For temporary credentials
+}
+
+var (
+	settings   Settings
+	settingsMu sync.RWMutex
+)
+
+// Configure replaces the server-level settings used by flow actions.
+func Configure(s Settings) {
+	settingsMu.Lock()
+	settings = s
+	settingsMu.Unlock()
+}
+
+// currentSettings returns a copy of the active settings.
+func currentSettings() Settings {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return settings
+}
+
+// DebugEnabled reports whether debug details may be included in API responses.
+func DebugEnabled() bool {
+	return currentSettings().Debug
+}
@@ -0,0 +1,291 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SFTPTarget is a server-configured SFTP location for the "fileDrop" action. Credentials
+// live in the server settings, definitions only name the target.
+type SFTPTarget struct {
+	Addr           string `json:"addr"`                     // host:port (port defaults to 22)
+	User           string `json:"user"`                     // Login name
+	Password       string `json:"password,omitempty"`       // Password or private key authentication
+	PrivateKey     string `json:"privateKey,omitempty"`     // PEM private key
+	PrivateKeyFile string `json:"privateKeyFile,omitempty"` // Path to a PEM private key
+	HostKey        string `json:"hostKey"`                  // Expected host key in authorized_keys format ("ssh-ed25519 AAAA...")
+	Dir            string `json:"dir,omitempty"`            // Base directory files are written under
+}
+
+// SFTP protocol version 3 packet types and open flags (draft-ietf-secsh-filexfer-02).
+const (
+	sftpInit     = 1
+	sftpVersion  = 2
+	sftpOpen     = 3
+	sftpClose    = 4
+	sftpWrite    = 6
+	sftpRemove   = 13
+	sftpRename   = 18
+	sftpStatus   = 101
+	sftpHandle   = 102
+	sftpExtended = 200
+
+	sftpFlagWrite  = 0x02
+	sftpFlagCreate = 0x08
+	sftpFlagTrunc  = 0x10
+
+	sftpChunkSize = 32 * 1024
+)
+
+// sftpUpload writes content to name (relative to the target's Dir). The file is written
+// as "<name>.part" and renamed when complete, so pollers never pick up a partial file.
+func sftpUpload(ctx context.Context, target SFTPTarget, name string, content []byte) (string, error) {
+	config, err := target.clientConfig()
+	if err != nil {
+		return "", err
+	}
+	addr := target.Addr
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+	dialer := net.Dialer{Timeout: config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return "", err
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+	w, err := session.StdinPipe()
+	if err != nil {
+		return "", err
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		return "", fmt.Errorf("sftp subsystem unavailable: %w", err)
+	}
+
+	s := &sftpSession{w: w, r: bufio.NewReader(r)}
+	extensions, err := s.init()
+	if err != nil {
+		return "", err
+	}
+	full := path.Join(target.Dir, path.Clean("/"+name))
+	partial := full + ".part"
+	if err := s.writeFile(partial, content); err != nil {
+		return "", err
+	}
+	if extensions["posix-rename@openssh.com"] {
+		err = s.call(sftpExtended, sftpString("posix-rename@openssh.com"), sftpString(partial), sftpString(full))
+	} else {
+		_ = s.call(sftpRemove, sftpString(full)) // Plain v3 RENAME refuses to overwrite
+		err = s.call(sftpRename, sftpString(partial), sftpString(full))
+	}
+	if err != nil {
+		return "", fmt.Errorf("rename to '%s': %w", full, err)
+	}
+	return full, nil
+}
+
+func (t SFTPTarget) clientConfig() (*ssh.ClientConfig, error) {
+	if t.HostKey == "" {
+		return nil, errors.New("sftp target has no hostKey; refusing to connect to an unverified host")
+	}
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(t.HostKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hostKey: %w", err)
+	}
+	var auth []ssh.AuthMethod
+	pemKey := []byte(t.PrivateKey)
+	if t.PrivateKeyFile != "" {
+		if pemKey, err = os.ReadFile(t.PrivateKeyFile); err != nil {
+			return nil, fmt.Errorf("read private key: %w", err)
+		}
+	}
+	if len(pemKey) > 0 {
+		signer, err := ssh.ParsePrivateKey(pemKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if t.Password != "" {
+		auth = append(auth, ssh.Password(t.Password))
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("sftp target needs a password or private key")
+	}
+	return &ssh.ClientConfig{
+		User:            t.User,
+		Auth:            auth,
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+		Timeout:         10 * time.Second,
+	}, nil
+}
+
+// sftpSession speaks the request/response side of SFTP v3 one request at a time.
+type sftpSession struct {
+	w      io.Writer
+	r      *bufio.Reader
+	nextID uint32
+}
+
+func (s *sftpSession) init() (map[string]bool, error) {
+	if err := s.send(sftpInit, binary.BigEndian.AppendUint32(nil, 3)); err != nil {
+		return nil, err
+	}
+	kind, payload, err := s.receive()
+	if err != nil {
+		return nil, err
+	}
+	if kind != sftpVersion || len(payload) < 4 {
+		return nil, errors.New("sftp: unexpected reply to INIT")
+	}
+	extensions := make(map[string]bool)
+	for rest := payload[4:]; len(rest) > 0; {
+		var name string
+		if name, rest, err = readSFTPString(rest); err != nil {
+			break
+		}
+		if _, rest, err = readSFTPString(rest); err != nil {
+			break
+		}
+		extensions[name] = true
+	}
+	return extensions, nil
+}
+
+func (s *sftpSession) writeFile(name string, content []byte) error {
+	id := s.id()
+	req := binary.BigEndian.AppendUint32(nil, id)
+	req = append(req, sftpString(name)...)
+	req = binary.BigEndian.AppendUint32(req, sftpFlagWrite|sftpFlagCreate|sftpFlagTrunc)
+	req = binary.BigEndian.AppendUint32(req, 0) // No attributes
+	if err := s.send(sftpOpen, req); err != nil {
+		return err
+	}
+	kind, payload, err := s.receive()
+	if err != nil {
+		return err
+	}
+	if kind == sftpStatus {
+		return fmt.Errorf("open '%s': %w", name, sftpStatusError(payload))
+	}
+	if kind != sftpHandle || len(payload) < 4 {
+		return errors.New("sftp: unexpected reply to OPEN")
+	}
+	handle, _, err := readSFTPString(payload[4:])
+	if err != nil {
+		return err
+	}
+
+	var writeErr error
+	for offset := 0; offset < len(content) && writeErr == nil; offset += sftpChunkSize {
+		chunk := content[offset:min(offset+sftpChunkSize, len(content))]
+		writeErr = s.call(sftpWrite, sftpString(handle), binary.BigEndian.AppendUint64(nil, uint64(offset)), sftpString(string(chunk)))
+	}
+	closeErr := s.call(sftpClose, sftpString(handle))
+	if writeErr != nil {
+		return fmt.Errorf("write '%s': %w", name, writeErr)
+	}
+	return closeErr
+}
+
+// call sends a request made of the given fields and waits for its STATUS reply.
+func (s *sftpSession) call(kind byte, fields ...[]byte) error {
+	req := binary.BigEndian.AppendUint32(nil, s.id())
+	for _, f := range fields {
+		req = append(req, f...)
+	}
+	if err := s.send(kind, req); err != nil {
+		return err
+	}
+	reply, payload, err := s.receive()
+	if err != nil {
+		return err
+	}
+	if reply != sftpStatus {
+		return fmt.Errorf("sftp: unexpected reply type %d", reply)
+	}
+	return sftpStatusError(payload)
+}
+
+func (s *sftpSession) id() uint32 {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *sftpSession) send(kind byte, payload []byte) error {
+	packet := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	packet = append(packet, kind)
+	_, err := s.w.Write(append(packet, payload...))
+	return err
+}
+
+func (s *sftpSession) receive() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(s.r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:4])
+	if size < 1 || size > 256*1024 {
+		return 0, nil, fmt.Errorf("sftp: invalid packet length %d", size)
+	}
+	payload := make([]byte, size-1)
+	_, err := io.ReadFull(s.r, payload)
+	return header[4], payload, err
+}
+
+// sftpStatusError converts a STATUS payload (id, code, message) to an error; nil for SSH_FX_OK.
+func sftpStatusError(payload []byte) error {
+	if len(payload) < 8 {
+		return errors.New("sftp: malformed STATUS")
+	}
+	code := binary.BigEndian.Uint32(payload[4:8])
+	if code == 0 {
+		return nil
+	}
+	msg, _, _ := readSFTPString(payload[8:])
+	return fmt.Errorf("sftp status %d: %s", code, msg)
+}
+
+func sftpString(s string) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(s))), s...)
+}
+
+func readSFTPString(b []byte) (string, []byte, error) {
+	if len(b) < 4 {
+		return "", nil, errors.New("sftp: truncated string")
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint32(len(b)-4) < n {
+		return "", nil, errors.New("sftp: truncated string")
+	}
+	return string(b[4 : 4+n]), b[4+n:], nil
+}
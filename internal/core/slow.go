@@ -0,0 +1,192 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"api-genarator/internal/models"
+)
+
+// SlowThresholds are the durations above which a request, flow step or database query
+// is logged as slow and counted in the slow report.
+type SlowThresholds struct {
+	Request time.Duration // Whole dynamic API request (default 2s)
+	Step    time.Duration // One flow action (default 1s)
+	Query   time.Duration // One database command (default 500ms)
+}
+
+// maxTimingEntries bounds each table; tenant-routed collections could otherwise grow it without limit.
+const maxTimingEntries = 2000
+
+// TimingStat aggregates the durations of one endpoint, step or query shape.
+type TimingStat struct {
+	Name              string     `json:"name"`
+	Count             int64      `json:"count"`
+	AvgMs             float64    `json:"avgMs"`
+	MaxMs             float64    `json:"maxMs"`
+	SlowCount         int64      `json:"slowCount"`
+	LastSlowAt        *time.Time `json:"lastSlowAt,omitempty"`
+	LastSlowRequestID string     `json:"lastSlowRequestId,omitempty"`
+	total             time.Duration
+}
+
+type timingTable struct {
+	mu    sync.Mutex
+	stats map[string]*TimingStat
+}
+
+var (
+	endpointTimings = &timingTable{stats: map[string]*TimingStat{}}
+	stepTimings     = &timingTable{stats: map[string]*TimingStat{}}
+	queryTimings    = &timingTable{stats: map[string]*TimingStat{}}
+)
+
+// observe records one duration and reports whether it exceeded threshold.
+func (t *timingTable) observe(name string, d, threshold time.Duration, requestID string) bool {
+	slow := d > threshold
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stat, ok := t.stats[name]
+	if !ok {
+		if len(t.stats) >= maxTimingEntries {
+			return slow
+		}
+		stat = &TimingStat{Name: name}
+		t.stats[name] = stat
+	}
+	stat.Count++
+	stat.total += d
+	ms := float64(d.Microseconds()) / 1000
+	stat.MaxMs = max(stat.MaxMs, ms)
+	if slow {
+		now := time.Now().UTC()
+		stat.SlowCount++
+		stat.LastSlowAt = &now
+		stat.LastSlowRequestID = requestID
+	}
+	return slow
+}
+
+// top returns copies of the entries ordered by sortBy ("avg", "max" or "slow", the
+// number of slow occurrences), slowest first.
+func (t *timingTable) top(limit int, sortBy string) []TimingStat {
+	t.mu.Lock()
+	list := make([]TimingStat, 0, len(t.stats))
+	for _, s := range t.stats {
+		copied := *s
+		copied.AvgMs = float64(s.total.Microseconds()) / 1000 / float64(s.Count)
+		list = append(list, copied)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		switch sortBy {
+		case "max":
+			return list[i].MaxMs > list[j].MaxMs
+		case "slow":
+			return list[i].SlowCount > list[j].SlowCount || list[i].SlowCount == list[j].SlowCount && list[i].AvgMs > list[j].AvgMs
+		}
+		return list[i].AvgMs > list[j].AvgMs
+	})
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
+
+func (t *timingTable) reset() {
+	t.mu.Lock()
+	t.stats = map[string]*TimingStat{}
+	t.mu.Unlock()
+}
+
+// slowThresholds returns the configured thresholds with defaults filled in.
+func slowThresholds() SlowThresholds {
+	th := currentSettings().Slow
+	if th.Request <= 0 {
+		th.Request = 2 * time.Second
+	}
+	if th.Step <= 0 {
+		th.Step = time.Second
+	}
+	if th.Query <= 0 {
+		th.Query = 500 * time.Millisecond
+	}
+	return th
+}
+
+// ObserveEndpoint records the duration of a dynamic API request.
+func ObserveEndpoint(ctx context.Context, apiName string, d time.Duration) {
+	if endpointTimings.observe(apiName, d, slowThresholds().Request, RequestID(ctx)) {
+		log.Printf("WARN: Slow request to API '%s' took %s (request %s)", apiName, d.Round(time.Millisecond), RequestID(ctx))
+	}
+}
+
+// observeStep records the duration of one flow action, keyed by API and step.
+func observeStep(ctx context.Context, action *models.ActionDefinition, d time.Duration) {
+	name := stepLabel(action)
+	if apiName := apiNameFrom(ctx); apiName != "" {
+		name = apiName + " " + name
+	}
+	if stepTimings.observe(name, d, slowThresholds().Step, RequestID(ctx)) {
+		log.Printf("WARN: Slow flow step %s took %s (request %s)", name, d.Round(time.Millisecond), RequestID(ctx))
+	}
+}
+
+// ObserveQuery records the duration of a database command (see database.SetQueryObserver).
+func ObserveQuery(ctx context.Context, database, collection, command string, d time.Duration, err error) {
+	name := fmt.Sprintf("%s %s.%s", command, database, collection)
+	if queryTimings.observe(name, d, slowThresholds().Query, RequestID(ctx)) {
+		log.Printf("WARN: Slow query %s took %s (request %s, error: %v)", name, d.Round(time.Millisecond), RequestID(ctx), err)
+	}
+}
+
+// stepLabel names a step by its action type and, where it has one, its target.
+func stepLabel(action *models.ActionDefinition) string {
+	switch {
+	case action.ApiCall != nil && action.Type == "apiCall":
+		return "apiCall(" + action.ApiCall.ApiName + ")"
+	case action.CallFlow != nil && action.Type == "callFlow":
+		return "callFlow(" + action.CallFlow.Fragment + ")"
+	}
+	return action.Type
+}
+
+// apiNameKey carries the name of the definition whose flow is running.
+type apiNameKey struct{}
+
+// WithAPIName labels a flow context with its definition, for step timings.
+func WithAPIName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, apiNameKey{}, name)
+}
+
+func apiNameFrom(ctx context.Context) string {
+	name, _ := ctx.Value(apiNameKey{}).(string)
+	return name
+}
+
+// SlowReport lists the slowest endpoints, flow steps and queries since the last reset.
+func SlowReport(limit int, sortBy string) map[string]interface{} {
+	th := slowThresholds()
+	return map[string]interface{}{
+		"thresholdsMs": map[string]int64{
+			"request": th.Request.Milliseconds(),
+			"step":    th.Step.Milliseconds(),
+			"query":   th.Query.Milliseconds(),
+		},
+		"endpoints": endpointTimings.top(limit, sortBy),
+		"steps":     stepTimings.top(limit, sortBy),
+		"queries":   queryTimings.top(limit, sortBy),
+	}
+}
+
+// ResetSlowReport clears all recorded timings.
+func ResetSlowReport() {
+	endpointTimings.reset()
+	stepTimings.reset()
+	queryTimings.reset()
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-genarator/internal/models"
+)
+
+// SMSProvider is a server-configured SMS gateway account for the "sendSms" action.
+type SMSProvider struct {
+	Kind                string `json:"kind,omitempty"`                // Gateway API: "twilio" (default; also Twilio-compatible gateways)
+	BaseURL             string `json:"baseUrl,omitempty"`             // (Optional) API base URL (default "https://api.twilio.com")
+	AccountSID          string `json:"accountSid"`                    // Account identifier (basic auth user)
+	AuthToken           string `json:"authToken"`                     // Account secret (basic auth password)
+	From                string `json:"from,omitempty"`                // Default sender number or alphanumeric ID
+	MessagingServiceSID string `json:"messagingServiceSid,omitempty"` // (Optional) Sender pool used instead of From
+}
+
+// ErrSMSRateLimited is returned when a recipient has reached the configured message limit.
+var ErrSMSRateLimited = errors.New("SMS rate limit reached for recipient")
+
+// smsSender submits one message and returns the gateway's message ID and status.
+type smsSender interface {
+	send(ctx context.Context, msg smsMessage) (id, status string, err error)
+}
+
+type smsMessage struct {
+	to, from, body, statusCallback string
+}
+
+// smsProviderKinds maps SMSProvider.Kind to its gateway implementation.
+var smsProviderKinds = map[string]func(SMSProvider) smsSender{
+	"twilio": func(p SMSProvider) smsSender { return twilioSender{p} },
+}
+
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// sendSms renders and submits a message, enforcing the per-recipient rate limit, and
+// returns {"id", "status", "to", "provider"}.
+func sendSms(ctx context.Context, cfg *models.SMSConfig, data map[string]interface{}) (map[string]interface{}, error) {
+	name := defaultString(cfg.Provider, "default")
+	provider, ok := currentSettings().SMSProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("SMS provider '%s' is not configured", name)
+	}
+	newSender, ok := smsProviderKinds[defaultString(provider.Kind, "twilio")]
+	if !ok {
+		return nil, fmt.Errorf("unsupported SMS provider kind '%s'", provider.Kind)
+	}
+
+	to := normalizePhone(InterpolateString(cfg.To, data))
+	if !e164Pattern.MatchString(to) {
+		return nil, fmt.Errorf("recipient '%s' is not an E.164 phone number", to)
+	}
+	body := InterpolateString(cfg.Body, data)
+	if strings.TrimSpace(body) == "" {
+		return nil, errors.New("SMS body is empty")
+	}
+
+	var slot string
+	if cfg.RateLimit != nil && cfg.RateLimit.Max > 0 {
+		var err error
+		if slot, err = takeSMSSlot(ctx, name, to, cfg.RateLimit, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	id, status, err := newSender(provider).send(ctx, smsMessage{
+		to:             to,
+		from:           defaultString(InterpolateString(cfg.From, data), provider.From),
+		body:           body,
+		statusCallback: InterpolateString(cfg.StatusCallback, data),
+	})
+	if err != nil {
+		if slot != "" {
+			_ = currentCache().Delete(ctx, slot) // A message that was never sent does not count
+		}
+		return nil, err
+	}
+	return map[string]interface{}{"id": id, "status": status, "to": to, "provider": name}, nil
+}
+
+// takeSMSSlot claims one of the recipient's Max slots in the current fixed window. The
+// slots are separate cache keys claimed with Add, so the limit holds across instances
+// sharing a Redis cache. It returns the claimed key.
+func takeSMSSlot(ctx context.Context, provider, to string, limit *models.SMSRateLimit, now time.Time) (string, error) {
+	window := time.Duration(limit.WindowSeconds) * time.Second
+	if window <= 0 {
+		window = time.Hour
+	}
+	sum := sha256.Sum256([]byte(to)) // Keep phone numbers out of the cache keys
+	prefix := fmt.Sprintf("sms:rate:%s:%s:%d", provider, hex.EncodeToString(sum[:8]), now.Truncate(window).Unix())
+	for i := 0; i < limit.Max; i++ {
+		key := prefix + ":" + strconv.Itoa(i)
+		stored, err := currentCache().Add(ctx, key, now.Unix(), window)
+		if err != nil {
+			return "", fmt.Errorf("SMS rate limit check failed: %w", err)
+		}
+		if stored {
+			return key, nil
+		}
+	}
+	return "", ErrSMSRateLimited
+}
+
+// normalizePhone drops the spaces, dashes, dots and parentheses people type in numbers.
+func normalizePhone(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+}
+
+// twilioSender talks to the Twilio Messages API (and gateways that mimic it).
+type twilioSender struct {
+	p SMSProvider
+}
+
+func (t twilioSender) send(ctx context.Context, msg smsMessage) (string, string, error) {
+	form := url.Values{"To": {msg.to}, "Body": {msg.body}}
+	switch {
+	case t.p.MessagingServiceSID != "":
+		form.Set("MessagingServiceSid", t.p.MessagingServiceSID)
+	case msg.from != "":
+		form.Set("From", msg.from)
+	default:
+		return "", "", errors.New("SMS provider has no sender (from or messagingServiceSid)")
+	}
+	if msg.statusCallback != "" {
+		form.Set("StatusCallback", msg.statusCallback)
+	}
+	endpoint := strings.TrimRight(defaultString(t.p.BaseURL, "https://api.twilio.com"), "/") +
+		"/2010-04-01/Accounts/" + url.PathEscape(t.p.AccountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(t.p.AccountSID, t.p.AuthToken)
+
+	resp, err := outboundClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("SMS request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		SID     string `json:"sid"`
+		Status  string `json:"status"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return "", "", fmt.Errorf("SMS provider returned %s with an unreadable body", resp.Status)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return "", "", fmt.Errorf("SMS provider returned %s: %d %s", resp.Status, result.Code, result.Message)
+	}
+	return result.SID, result.Status, nil
+}
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"api-genarator/internal/models"
+)
+
+// soapCall renders the envelope template, posts it and returns the content of the
+// response Body as a map (see xmlToMap), narrowed to cfg.Extract. A SOAP Fault fails
+// the action with the fault string.
+func soapCall(ctx context.Context, cfg *models.SOAPConfig, data map[string]interface{}) (interface{}, error) {
+	envelope := interpolateXML(cfg.Envelope, data)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, resolveEndpoint(ctx, InterpolateString(cfg.Endpoint, data)), strings.NewReader(envelope))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Version == "1.2" {
+		contentType := "application/soap+xml; charset=utf-8"
+		if cfg.Action != "" {
+			contentType = mime.FormatMediaType("application/soap+xml", map[string]string{"charset": "utf-8", "action": cfg.Action})
+		}
+		req.Header.Set("Content-Type", contentType)
+	} else {
+		req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+		req.Header.Set("SOAPAction", `"`+cfg.Action+`"`)
+	}
+	for name, value := range cfg.Headers {
+		req.Header.Set(name, InterpolateString(value, data))
+	}
+
+	resp, err := outboundClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("SOAP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SOAP response: %w", err)
+	}
+
+	doc, parseErr := xmlToMap(raw)
+	if parseErr != nil {
+		if resp.StatusCode >= http.StatusBadRequest {
+			return nil, fmt.Errorf("SOAP endpoint returned %s", resp.Status)
+		}
+		return nil, fmt.Errorf("failed to parse SOAP response: %w", parseErr)
+	}
+	body, ok := lookupField(doc, "Envelope.Body")
+	if !ok {
+		return nil, errors.New("SOAP response has no Envelope/Body")
+	}
+	bodyMap, _ := body.(map[string]interface{})
+	if fault, isFault := bodyMap["Fault"]; isFault {
+		return nil, fmt.Errorf("SOAP fault: %s", soapFaultString(fault))
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("SOAP endpoint returned %s", resp.Status)
+	}
+
+	if cfg.Extract == "" {
+		return bodyMap, nil
+	}
+	value, ok := lookupField(bodyMap, cfg.Extract)
+	if !ok {
+		return nil, fmt.Errorf("SOAP response has no element '%s'", cfg.Extract)
+	}
+	return value, nil
+}
+
+// soapFaultString reads faultstring (SOAP 1.1) or Reason/Text (SOAP 1.2) from a Fault.
+func soapFaultString(fault interface{}) string {
+	m, _ := fault.(map[string]interface{})
+	for _, path := range []string{"faultstring", "Reason.Text", "Reason.Text.#text"} {
+		if v, ok := lookupField(m, path); ok {
+			if s, ok := v.(string); ok {
+				return s
+			}
+		}
+	}
+	return fmt.Sprintf("%v", fault)
+}
+
+// interpolateXML is InterpolateString for XML templates: values are escaped, so data
+// cannot inject elements into the envelope.
+func interpolateXML(template string, data map[string]interface{}) string {
+	return templatePattern.ReplaceAllStringFunc(template, func(match string) string {
+		path := templatePattern.FindStringSubmatch(match)[1]
+		value, ok := lookupField(data, strings.TrimPrefix(path, "$"))
+		if !ok || value == nil {
+			log.Printf("WARN: Template field '%s' not found, rendering as empty string", path)
+			return ""
+		}
+		var b bytes.Buffer
+		_ = xml.EscapeText(&b, []byte(fmt.Sprintf("%v", value)))
+		return b.String()
+	})
+}
+
+// xmlToMap converts an XML document into nested maps keyed by local element names
+// (namespace prefixes dropped). Attributes become "@name", repeated elements become
+// arrays, text-only elements become strings and text next to children is "#text".
+func xmlToMap(raw []byte) (map[string]interface{}, error) {
+	dec := xml.NewDecoder(bytes.NewReader(raw))
+	type frame struct {
+		name     string
+		children map[string]interface{}
+		text     strings.Builder
+	}
+	root := &frame{children: make(map[string]interface{})}
+	stack := []*frame{root}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			f := &frame{name: t.Name.Local, children: make(map[string]interface{})}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				f.children["@"+attr.Name.Local] = attr.Value
+			}
+			stack = append(stack, f)
+		case xml.CharData:
+			stack[len(stack)-1].text.Write(t)
+		case xml.EndElement:
+			f := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			var value interface{} = f.children
+			text := strings.TrimSpace(f.text.String())
+			if len(f.children) == 0 {
+				value = text
+			} else if text != "" {
+				f.children["#text"] = text
+			}
+			parent := stack[len(stack)-1].children
+			switch existing := parent[f.name].(type) {
+			case nil:
+				parent[f.name] = value
+			case []interface{}:
+				parent[f.name] = append(existing, value)
+			default:
+				parent[f.name] = []interface{}{existing, value}
+			}
+		}
+	}
+	if len(root.children) == 0 {
+		return nil, errors.New("empty XML document")
+	}
+	return root.children, nil
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrTenantUnresolved is returned when a database/collection template cannot be resolved for a request.
+var ErrTenantUnresolved = errors.New("cannot resolve tenant")
+
+// tenantValuePattern restricts what a placeholder may render to inside a database or
+// collection name, so a header or claim cannot point a request at another namespace.
+var tenantValuePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// TenantScope holds the trusted per-request values database/collection templates may use.
+type TenantScope struct {
+	Claims  map[string]interface{} // Verified JWT claims (nil without a valid bearer token)
+	Headers map[string]string      // Request headers, keyed by lower-case name
+}
+
+// IsTargetTemplate reports whether a database or collection name contains {{placeholders}}.
+func IsTargetTemplate(name string) bool {
+	return templatePattern.MatchString(name)
+}
+
+// ResolveTargetName renders a database/collection template such as "tenant_{{tenantId}}".
+// Placeholders are "claims.<path>", "header.<name>" or a bare name, which is looked up
+// in the claims first and then in the headers. Request data is never used, so callers
+// cannot choose another tenant through the body or query string.
+func ResolveTargetName(template string, scope TenantScope) (string, error) {
+	var resolveErr error
+	name := templatePattern.ReplaceAllStringFunc(template, func(match string) string {
+		path := templatePattern.FindStringSubmatch(match)[1]
+		value, ok := scope.lookup(path)
+		if !ok {
+			resolveErr = fmt.Errorf("%w: '%s' is not set", ErrTenantUnresolved, path)
+			return ""
+		}
+		if !tenantValuePattern.MatchString(value) {
+			resolveErr = fmt.Errorf("%w: invalid value for '%s'", ErrTenantUnresolved, path)
+			return ""
+		}
+		return value
+	})
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+	return name, nil
+}
+
+func (s TenantScope) lookup(path string) (string, bool) {
+	if name, found := strings.CutPrefix(path, "header."); found {
+		value, ok := s.Headers[strings.ToLower(name)]
+		return value, ok && value != ""
+	}
+	if claim, found := strings.CutPrefix(path, "claims."); found {
+		return s.claim(claim)
+	}
+	if value, ok := s.claim(path); ok {
+		return value, true
+	}
+	value, ok := s.Headers[strings.ToLower(path)]
+	return value, ok && value != ""
+}
+
+func (s TenantScope) claim(path string) (string, bool) {
+	if s.Claims == nil {
+		return "", false
+	}
+	value, ok := lookupField(s.Claims, path)
+	if !ok || value == nil {
+		return "", false
+	}
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return "", false
+	}
+	return fmt.Sprintf("%v", value), true
+}
@@ -1,9 +1,14 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"regexp"
 	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
 	// "strconv" // อาจจะจำเป็นถ้า calculate มีการแปลง type ซับซ้อน
 
 	// --- เปลี่ยน your_module_name เป็นชื่อ Module Go ของคุณ ---
@@ -13,9 +18,21 @@ import (
 
 // ApplyTransformations applies a series of transformations to a data map.
 // It returns a *new* map with the transformations applied, leaving the original map unchanged.
-func ApplyTransformations(transformations []models.Transformation, data map[string]interface{}) map[string]interface{} {
+// ctx carries the inbound request for operations that read it ("geoip", "userAgent").
+// A failed "bcryptHash" is logged and stops the transformations, with the field removed.
+func ApplyTransformations(ctx context.Context, transformations []models.Transformation, data map[string]interface{}) map[string]interface{} {
+	result, err := transformData(ctx, transformations, data)
+	if err != nil {
+		log.Printf("ERROR: Transformations stopped: %v", err)
+	}
+	return result
+}
+
+// transformData is ApplyTransformations returning the failure of a "bcryptHash", which
+// must stop the flow: the field to hash is removed rather than left in plain text.
+func transformData(ctx context.Context, transformations []models.Transformation, data map[string]interface{}) (map[string]interface{}, error) {
 	if len(transformations) == 0 {
-		return data // ถ้าไม่มี transform ก็คืน map เดิมไปเลย (ไม่ต้อง copy)
+		return data, nil // ถ้าไม่มี transform ก็คืน map เดิมไปเลย (ไม่ต้อง copy)
 	}
 
 	log.Printf("DEBUG: Applying %d transformations...", len(transformations))
@@ -207,11 +224,127 @@ func ApplyTransformations(transformations []models.Transformation, data map[stri
 				continue
 			}
 
+		case "bcryptHash":
+			// Hash Value (e.g. "$password") into Field, removing the source field; without
+			// Value, hash Field in place. An absent source leaves Field unset.
+			source := result[t.Field]
+			if t.Value != nil {
+				source = SubstituteVariables(t.Value, result)
+			}
+			if source == nil {
+				continue
+			}
+			if ref, ok := t.Value.(string); ok && strings.HasPrefix(ref, "$") && !strings.Contains(ref, "{{") {
+				removeField(result, strings.TrimPrefix(ref, "$"))
+			}
+			plain, ok := source.(string)
+			if !ok || plain == "" {
+				delete(result, t.Field) // Never keep the plain text where the hash belongs
+				return result, fmt.Errorf("bcryptHash of field '%s' requires a non-empty string", t.Field)
+			}
+			hashed, err := bcrypt.GenerateFromPassword([]byte(plain), bcrypt.DefaultCost)
+			if err != nil {
+				delete(result, t.Field)
+				return result, fmt.Errorf("bcryptHash of field '%s' failed: %w", t.Field, err)
+			}
+			result[t.Field] = string(hashed)
+
+		case "geoip":
+			// Resolve Value ("$ip") or the client address to {country, city, ...} in Field
+			if geo, ok := geoIPTransform(ctx, t, result); ok {
+				result[t.Field] = geo
+			}
+
+		case "userAgent":
+			// Parse Value ("$ua") or the User-Agent header into {browser, os, device, ...} in Field
+			if ua, ok := userAgentTransform(ctx, t, result); ok {
+				result[t.Field] = ua
+			}
+
+		case "round", "floor", "ceil", "toFixed", "formatCurrency":
+			// Number from Value ("$price") or Field itself, rounded to Precision decimals
+			formatted, err := applyNumberFormat(t, result)
+			if err != nil {
+				log.Printf("WARN: '%s' failed for field '%s': %v. Field not updated.", t.Operation, t.Field, err)
+				continue
+			}
+			result[t.Field] = formatted
+
+		case "extract":
+			// Evaluate Path ("$.items[?(@.qty > 0)].sku") against the state into Field
+			path, err := compileJSONPath(t.Path)
+			if err != nil {
+				log.Printf("WARN: 'extract' for field '%s': %v. Skipping.", t.Field, err)
+				continue
+			}
+			if !path.definite {
+				matches := path.evaluate(result)
+				if matches == nil {
+					matches = []interface{}{}
+				}
+				result[t.Field] = matches // Wildcards, slices and filters always give a list
+				continue
+			}
+			if value, ok := path.first(result); ok {
+				result[t.Field] = value
+			} else {
+				log.Printf("DEBUG: 'extract' path '%s' matched nothing, field '%s' not set", t.Path, t.Field)
+			}
+
+		case "flatten", "unflatten", "rename":
+			// Reshape Field in place or into Value ({"operation":"rename","field":"a.b","value":"c"})
+			if err := applyReshape(t, result); err != nil {
+				log.Printf("WARN: '%s' of field '%s' skipped: %v", t.Operation, t.Field, err)
+			}
+
+		case "age":
+			// Whole years from the date in Value ("$birthdate") or Field itself until today
+			source := result[t.Field]
+			if t.Value != nil {
+				source = SubstituteVariables(t.Value, result)
+			}
+			if years, ok := ageInYears(source, time.Now()); ok {
+				result[t.Field] = years
+			} else {
+				log.Printf("DEBUG: 'age' found no date for field '%s', field not set", t.Field)
+			}
+
 		default:
 			log.Printf("WARN: Unknown transformation operation '%s'. Skipping.", t.Operation)
 		}
 	}
-	return result // คืน map ที่มีการเปลี่ยนแปลงแล้ว
+	return result, nil // คืน map ที่มีการเปลี่ยนแปลงแล้ว
+}
+
+// SaveResultVariable holds the outcome of saving the request data ($saveResult.created,
+// $saveResult.id, $saveResult.modified, $saveResult.outcome). The save runs after the
+// flow, so flow responses keep these references until SubstituteSaveResult.
+const SaveResultVariable = "saveResult"
+
+// SubstituteSaveResult resolves the $saveResult references left in a flow response.
+// Other strings are not touched: the response is already substituted, so any other
+// "$" value is data.
+func SubstituteSaveResult(response interface{}, result map[string]interface{}) interface{} {
+	switch t := response.(type) {
+	case string:
+		if t == "$"+SaveResultVariable || strings.HasPrefix(t, "$"+SaveResultVariable+".") {
+			return SubstituteVariables(t, map[string]interface{}{SaveResultVariable: result})
+		}
+		return t
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			out[k] = SubstituteSaveResult(v, result)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, v := range t {
+			out[i] = SubstituteSaveResult(v, result)
+		}
+		return out
+	}
+	return response
 }
 
 // SubstituteVariables recursively replaces placeholders like $variableName in a template
@@ -223,10 +356,19 @@ func SubstituteVariables(template interface{}, data map[string]interface{}) inte
 
 	switch t := template.(type) {
 	case string:
+		if strings.HasPrefix(t, lookupPrefix) {
+			if value, ok := resolveLookup(t, data); ok {
+				return value
+			}
+		}
 		// ตรวจสอบว่าเป็น variable reference หรือไม่ (ขึ้นต้นด้วย $)
 		if strings.HasPrefix(t, "$") {
 			fieldPath := strings.TrimPrefix(t, "$")
 			fieldParts := strings.Split(fieldPath, ".")
+			if _, saved := data[SaveResultVariable]; fieldParts[0] == SaveResultVariable && !saved {
+				return t // Resolved by SubstituteSaveResult once the data is saved
+			}
+			fieldParts[0] = namespaceRoot(data, fieldParts[0])
 
 			// Traverse nested structure
 			value := interface{}(data)
@@ -278,10 +420,16 @@ func SubstituteVariables(template interface{}, data map[string]interface{}) inte
 // Tries to get a value from the data map if arg starts with '$',
 // otherwise tries to parse arg as a literal float64.
 func getValueAsFloat(arg string, data map[string]interface{}) (float64, bool) {
+	if strings.HasPrefix(arg, lookupPrefix) {
+		if value, ok := resolveLookup(arg, data); ok {
+			return convertToFloat64(value)
+		}
+	}
 	if strings.HasPrefix(arg, "$") {
 		// Support nested field access (e.g., $user.total.amount)
 		fieldPath := strings.TrimPrefix(arg, "$")
 		fieldParts := strings.Split(fieldPath, ".")
+		fieldParts[0] = namespaceRoot(data, fieldParts[0])
 
 		// Traverse the nested structure
 		value := interface{}(data)
@@ -317,3 +465,85 @@ func getValueAsFloat(arg string, data map[string]interface{}) (float64, bool) {
 	log.Printf("TRACE: Argument '%s' could not be interpreted as a field reference or a float literal.", arg)
 	return 0, false
 }
+
+// Request namespaces (ApiDefinition.NamespacedParams) are kept under reserved keys, so
+// they cannot overwrite request fields named path, query or body.
+const (
+	NamespacePath  = "$path"
+	NamespaceQuery = "$query"
+	NamespaceBody  = "$body"
+)
+
+// namespaceRoot maps the first part of a field path to its namespace key: "path.id"
+// reads the $path namespace when the request has one, the field "path" otherwise.
+func namespaceRoot(data map[string]interface{}, part string) string {
+	switch part {
+	case "path", "query", "body":
+		if _, ok := data["$"+part]; ok {
+			return "$" + part
+		}
+	}
+	return part
+}
+
+// lookupField resolves a dotted field path (e.g. "user.profile.id") against the data map.
+// It returns the value and whether every part of the path was present.
+func lookupField(data map[string]interface{}, path string) (interface{}, bool) {
+	parts := strings.Split(path, ".")
+	parts[0] = namespaceRoot(data, parts[0])
+	value := interface{}(data)
+	for _, part := range parts {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// templatePattern matches inline {{field.path}} placeholders inside a string.
+var templatePattern = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// InterpolateString replaces inline {{field.path}} placeholders with values from data.
+// Unlike SubstituteVariables, which replaces a whole "$field" value, this works inside
+// larger strings (e.g. "invoices/{{orderId}}.pdf"). Missing fields render as an empty string.
+func InterpolateString(template string, data map[string]interface{}) string {
+	if !strings.Contains(template, "{{") {
+		return template
+	}
+	return templatePattern.ReplaceAllStringFunc(template, func(match string) string {
+		path := templatePattern.FindStringSubmatch(match)[1]
+		value, ok := lookupField(data, strings.TrimPrefix(path, "$"))
+		if !ok || value == nil {
+			log.Printf("WARN: Template field '%s' not found, rendering as empty string", path)
+			return ""
+		}
+		return fmt.Sprintf("%v", value)
+	})
+}
+
+// setField stores value at a dotted field path, creating intermediate maps as needed.
+// It returns false if an intermediate path element exists but is not a map.
+func setField(data map[string]interface{}, path string, value interface{}) bool {
+	parts := strings.Split(path, ".")
+	current := data
+	for _, part := range parts[:len(parts)-1] {
+		next, exists := current[part]
+		if !exists || next == nil {
+			m := make(map[string]interface{})
+			current[part] = m
+			current = m
+			continue
+		}
+		m, ok := next.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		current = m
+	}
+	current[parts[len(parts)-1]] = value
+	return true
+}
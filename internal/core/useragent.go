@@ -0,0 +1,118 @@
+package core
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+
+	"api-genarator/internal/models"
+)
+
+// uaPattern matches one product in a User-Agent string. The first capture group, when
+// present, is the version.
+type uaPattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// Order matters: browsers built on Chromium or WebKit also carry the "Chrome/" and
+// "Safari/" tokens, so the more specific products come first.
+var uaBrowsers = []uaPattern{
+	{"Edge", regexp.MustCompile(`\b(?:Edg|Edge|EdgA|EdgiOS)/([\d.]+)`)},
+	{"Opera", regexp.MustCompile(`\b(?:OPR|Opera)/([\d.]+)`)},
+	{"Samsung Internet", regexp.MustCompile(`\bSamsungBrowser/([\d.]+)`)},
+	{"LINE", regexp.MustCompile(`\bLine/([\d.]+)`)},
+	{"Facebook", regexp.MustCompile(`\bFBAV/([\d.]+)`)},
+	{"Instagram", regexp.MustCompile(`\bInstagram ([\d.]+)`)},
+	{"Firefox", regexp.MustCompile(`\b(?:Firefox|FxiOS)/([\d.]+)`)},
+	{"Chrome", regexp.MustCompile(`\b(?:CriOS|Chrome)/([\d.]+)`)},
+	{"Safari", regexp.MustCompile(`\bVersion/([\d.]+).*\bSafari/`)},
+	{"Internet Explorer", regexp.MustCompile(`\bMSIE ([\d.]+)|\bTrident/.*\brv:([\d.]+)`)},
+}
+
+var uaOperatingSystems = []uaPattern{
+	{"Windows Phone", regexp.MustCompile(`\bWindows Phone(?: OS)? ([\d.]+)`)},
+	{"Windows", regexp.MustCompile(`\bWindows NT ([\d.]+)`)},
+	{"iOS", regexp.MustCompile(`\b(?:iPhone|CPU) OS ([\d_]+)`)},
+	{"Android", regexp.MustCompile(`\bAndroid ?([\d.]*)`)},
+	{"ChromeOS", regexp.MustCompile(`\bCrOS \S+ ([\d.]+)`)},
+	{"macOS", regexp.MustCompile(`\bMac OS X ?([\d_.]*)`)},
+	{"Linux", regexp.MustCompile(`\bLinux\b()`)},
+}
+
+// uaWindowsVersions maps NT kernel versions to marketing names (Windows 11 still reports 10.0).
+var uaWindowsVersions = map[string]string{
+	"10.0": "10", "6.3": "8.1", "6.2": "8", "6.1": "7", "6.0": "Vista", "5.1": "XP",
+}
+
+var (
+	uaBotPattern    = regexp.MustCompile(`(?i)bot\b|crawl|spider|slurp|headless|curl/|wget/|python-requests|go-http-client|okhttp|axios/|postman`)
+	uaTabletPattern = regexp.MustCompile(`(?i)ipad|tablet|kindle|silk/|playbook`)
+	uaMobilePattern = regexp.MustCompile(`(?i)mobi|iphone|ipod|android|windows phone`)
+)
+
+// parseUserAgent returns {"browser", "browserVersion", "os", "osVersion", "device", "bot"}.
+// device is "desktop", "mobile", "tablet" or "bot"; unknown products are left out.
+func parseUserAgent(ua string) map[string]interface{} {
+	out := map[string]interface{}{"device": "desktop", "bot": false}
+	if name, version, ok := matchUA(uaBrowsers, ua); ok {
+		out["browser"] = name
+		out["browserVersion"] = version
+	}
+	if name, version, ok := matchUA(uaOperatingSystems, ua); ok {
+		version = strings.ReplaceAll(version, "_", ".")
+		if name == "Windows" {
+			if marketing, known := uaWindowsVersions[version]; known {
+				version = marketing
+			}
+		}
+		if name == "iOS" && strings.Contains(ua, "iPad") {
+			name = "iPadOS"
+		}
+		out["os"] = name
+		out["osVersion"] = version
+	}
+
+	switch {
+	case strings.TrimSpace(ua) == "" || uaBotPattern.MatchString(ua):
+		out["device"] = "bot"
+		out["bot"] = true
+	case uaTabletPattern.MatchString(ua),
+		strings.Contains(ua, "Android") && !strings.Contains(ua, "Mobile"): // Android tablets drop "Mobile"
+		out["device"] = "tablet"
+	case uaMobilePattern.MatchString(ua):
+		out["device"] = "mobile"
+	}
+	return out
+}
+
+func matchUA(patterns []uaPattern, ua string) (name, version string, ok bool) {
+	for _, p := range patterns {
+		if m := p.re.FindStringSubmatch(ua); m != nil {
+			for _, v := range m[1:] {
+				if v != "" {
+					version = v
+					break
+				}
+			}
+			return p.name, version, true
+		}
+	}
+	return "", "", false
+}
+
+// userAgentTransform implements the "userAgent" transformation: the string comes from
+// the Value ("$field") or, by default, the User-Agent header of the inbound request.
+func userAgentTransform(ctx context.Context, t models.Transformation, data map[string]interface{}) (map[string]interface{}, bool) {
+	var ua string
+	if t.Value != nil {
+		ua, _ = SubstituteVariables(t.Value, data).(string)
+	} else if inbound := inboundFrom(ctx); inbound != nil {
+		ua = inbound.header("User-Agent")
+	} else {
+		log.Printf("WARN: userAgent transform for '%s' has no inbound request (background job?). Skipping.", t.Field)
+		return nil, false
+	}
+	return parseUserAgent(ua), true
+}
@@ -0,0 +1,264 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"api-genarator/internal/models"
+)
+
+// ValidationError describes a single failed validation rule in a structured form
+// that can be returned to the client as part of an `errors` array.
+type ValidationError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// dateLayouts are the formats tried when comparing string values as dates.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ValidateParameters checks the declared parameters: required ones must be present and
+// non-empty, and values of a "number", "integer", "boolean", "object" or "array"
+// parameter must have that type. Numbers and booleans may arrive as strings (query and
+// path values always do). Every failure is returned, not only the first.
+func ValidateParameters(params []models.Parameter, data map[string]interface{}) []ValidationError {
+	var failures []ValidationError
+	for _, param := range params {
+		value, exists := data[param.Name]
+		if !exists || value == nil || fmt.Sprintf("%v", value) == "" {
+			if param.Required {
+				failures = append(failures, ValidationError{Field: param.Name, Rule: "required", Message: fmt.Sprintf("Missing or empty required parameter: %s", param.Name)})
+			}
+			continue
+		}
+		if expected, ok := parameterTypeMatches(param.Type, value); !ok {
+			failures = append(failures, ValidationError{Field: param.Name, Rule: "type", Message: fmt.Sprintf("%s must be %s", param.Name, expected)})
+		}
+	}
+	return failures
+}
+
+// parameterTypeMatches reports whether value fits a parameter type, with the expected
+// type phrased for the error message. "string" and unknown types accept anything.
+func parameterTypeMatches(paramType string, value interface{}) (string, bool) {
+	switch strings.ToLower(paramType) {
+	case "number", "float", "double":
+		_, ok := convertToFloat64(value)
+		return "a number", ok
+	case "int", "integer":
+		n, ok := convertToFloat64(value)
+		return "an integer", ok && n == float64(int64(n))
+	case "bool", "boolean":
+		switch v := value.(type) {
+		case bool:
+			return "", true
+		case string:
+			return "true or false", v == "true" || v == "false"
+		}
+		return "true or false", false
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return "an object", ok
+	case "array":
+		_, ok := value.([]interface{})
+		return "an array", ok
+	}
+	return "", true
+}
+
+// ValidateData evaluates the validation rules against the combined request data.
+// All failing rules are collected so the client can fix everything in one round trip.
+func ValidateData(rules []models.ValidationRule, data map[string]interface{}) []ValidationError {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var failures []ValidationError
+	for i, rule := range rules {
+		// Conditional rules only apply when their 'when' conditions hold
+		if len(rule.When) > 0 && !evaluateConditions(rule.When, data) {
+			log.Printf("DEBUG: Validation rule #%d (%s %s) skipped, 'when' conditions not met", i+1, rule.Field, rule.Rule)
+			continue
+		}
+
+		if ok, msg := evaluateRule(rule, data); !ok {
+			if rule.Message != "" {
+				msg = rule.Message
+			}
+			failures = append(failures, ValidationError{Field: rule.Field, Rule: rule.Rule, Message: msg})
+		}
+	}
+	return failures
+}
+
+// evaluateRule checks one rule and returns false plus a default message when it fails.
+func evaluateRule(rule models.ValidationRule, data map[string]interface{}) (bool, string) {
+	value, exists := lookupField(data, rule.Field)
+
+	if rule.Rule == "required" {
+		if !exists || value == nil || fmt.Sprintf("%v", value) == "" {
+			return false, fmt.Sprintf("%s is required", rule.Field)
+		}
+		return true, ""
+	}
+
+	// Comparison rules are skipped when the field itself is absent;
+	// combine with a 'required' rule if the field must be present.
+	if !exists || value == nil {
+		return true, ""
+	}
+
+	// Resolve what we are comparing against: another field, or a literal value
+	target := rule.Value
+	targetName := fmt.Sprintf("%v", rule.Value)
+	if rule.Other != "" {
+		otherValue, otherExists := lookupField(data, rule.Other)
+		if !otherExists || otherValue == nil {
+			return true, "" // Nothing to compare against
+		}
+		target = otherValue
+		targetName = rule.Other
+	}
+
+	switch rule.Rule {
+	case "eq":
+		if !reflect.DeepEqual(value, target) {
+			return false, fmt.Sprintf("%s must equal %s", rule.Field, targetName)
+		}
+		return true, ""
+	case "neq":
+		if reflect.DeepEqual(value, target) {
+			return false, fmt.Sprintf("%s must not equal %s", rule.Field, targetName)
+		}
+		return true, ""
+	}
+
+	cmp, comparable := compareValues(value, target)
+	if !comparable {
+		return false, fmt.Sprintf("%s cannot be compared with %s", rule.Field, targetName)
+	}
+
+	switch rule.Rule {
+	case "after", "gt":
+		if cmp <= 0 {
+			return false, fmt.Sprintf("%s must be %s %s", rule.Field, comparisonWord(rule.Rule), targetName)
+		}
+	case "before", "lt":
+		if cmp >= 0 {
+			return false, fmt.Sprintf("%s must be %s %s", rule.Field, comparisonWord(rule.Rule), targetName)
+		}
+	case "gte":
+		if cmp < 0 {
+			return false, fmt.Sprintf("%s must be greater than or equal to %s", rule.Field, targetName)
+		}
+	case "lte":
+		if cmp > 0 {
+			return false, fmt.Sprintf("%s must be less than or equal to %s", rule.Field, targetName)
+		}
+	default:
+		log.Printf("WARN: Unknown validation rule '%s' for field '%s'. Treating as passed.", rule.Rule, rule.Field)
+	}
+	return true, ""
+}
+
+func comparisonWord(rule string) string {
+	switch rule {
+	case "after":
+		return "after"
+	case "before":
+		return "before"
+	case "gt":
+		return "greater than"
+	default:
+		return "less than"
+	}
+}
+
+// compareValues compares two values as dates, numerically, or as strings (in that order).
+// It returns -1, 0, 1 and whether the values could be compared at all.
+func compareValues(a, b interface{}) (int, bool) {
+	// Dates first, so "2024-01-02" is not half-parsed as a number
+	if ta, ok := toTime(a); ok {
+		if tb, ok := toTime(b); ok {
+			switch {
+			case ta.Before(tb):
+				return -1, true
+			case ta.After(tb):
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	if fa, ok := convertToFloat64(a); ok {
+		if fb, ok := convertToFloat64(b); ok {
+			switch {
+			case fa < fb:
+				return -1, true
+			case fa > fb:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	sa, okA := a.(string)
+	sb, okB := b.(string)
+	if okA && okB {
+		return strings.Compare(sa, sb), true
+	}
+	return 0, false
+}
+
+// toTime converts time.Time, primitive dates, and date strings to time.Time.
+func toTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case interface{ Time() time.Time }: // primitive.DateTime
+		return t.Time(), true
+	case string:
+		for _, layout := range dateLayouts {
+			if parsed, err := time.Parse(layout, t); err == nil {
+				return parsed, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// NormalizeTimeField returns a copy of data with the given field converted to time.Time,
+// as required by time-series collections. A missing field is set to the current time.
+func NormalizeTimeField(data map[string]interface{}, field string) map[string]interface{} {
+	if field == "" {
+		return data
+	}
+	result := make(map[string]interface{}, len(data)+1)
+	for k, v := range data {
+		result[k] = v
+	}
+
+	value, exists := result[field]
+	if !exists || value == nil {
+		result[field] = time.Now().UTC()
+		return result
+	}
+	if t, ok := toTime(value); ok {
+		result[field] = t
+	} else if f, ok := convertToFloat64(value); ok {
+		result[field] = time.UnixMilli(int64(f)).UTC() // Numeric values are treated as epoch milliseconds
+	} else {
+		log.Printf("WARN: Could not convert time field '%s' value '%v' to a date", field, value)
+	}
+	return result
+}
@@ -0,0 +1,252 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-genarator/internal/models"
+)
+
+// ErrWebhookSignature is returned when an inbound webhook is unsigned or its signature does not match.
+var ErrWebhookSignature = errors.New("invalid webhook signature")
+
+// ErrWebhookReplay is returned when a webhook call with the same nonce was already accepted.
+var ErrWebhookReplay = errors.New("webhook call was already received")
+
+// defaultWebhookTolerance is how far a signed timestamp may be off (Stripe's own default).
+const defaultWebhookTolerance = 5 * time.Minute
+
+var webhookAlgorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// VerifyWebhook checks the signature of an inbound webhook call over its raw body, the
+// skew of its signed timestamp and, with RejectReplays, that it was not seen before.
+// header returns a request header by name. nonceKeys is set when nonces were recorded;
+// pass them to ForgetWebhookNonce if the call fails, so the sender's retry is accepted.
+// A missing secret or an unavailable nonce store is a configuration error, not
+// ErrWebhookSignature, so it is not reported to the sender as their fault.
+func VerifyWebhook(ctx context.Context, cfg *models.WebhookVerification, header func(string) string, body []byte, now time.Time) (nonceKeys []string, err error) {
+	secret := currentSettings().WebhookSecrets[cfg.Secret]
+	if secret == "" {
+		return nil, fmt.Errorf("webhook secret '%s' is not configured", cfg.Secret)
+	}
+	tolerance := defaultWebhookTolerance
+	if cfg.ToleranceSeconds > 0 {
+		tolerance = time.Duration(cfg.ToleranceSeconds) * time.Second
+	}
+
+	var signature string
+	switch cfg.Scheme {
+	case models.WebhookHMAC, "":
+		newHash := webhookAlgorithms[cfg.Algorithm]
+		if cfg.Algorithm == "" {
+			newHash = sha256.New
+		}
+		name := cfg.Header
+		if name == "" {
+			name = "X-Signature"
+		}
+		sig, found := strings.CutPrefix(strings.TrimSpace(header(name)), cfg.Prefix)
+		if sig == "" || !found {
+			return nil, fmt.Errorf("%w: %s header is missing", ErrWebhookSignature, name)
+		}
+		signed := body
+		if cfg.TimestampHeader != "" {
+			timestamp := strings.TrimSpace(header(cfg.TimestampHeader))
+			if err := checkWebhookTimestamp(timestamp, now, tolerance); err != nil {
+				return nil, err
+			}
+			signed = append([]byte(timestamp+"."), body...)
+		}
+		var got []byte
+		if cfg.Encoding == "base64" {
+			got, err = base64.StdEncoding.DecodeString(sig)
+		} else {
+			got, err = hex.DecodeString(sig)
+		}
+		if err != nil || !hmac.Equal(got, webhookMAC(newHash, secret, signed)) {
+			return nil, ErrWebhookSignature
+		}
+		signature = sig
+
+	case models.WebhookGitHub:
+		sig, found := strings.CutPrefix(header("X-Hub-Signature-256"), "sha256=")
+		if !found {
+			return nil, fmt.Errorf("%w: X-Hub-Signature-256 header is missing", ErrWebhookSignature)
+		}
+		got, err := hex.DecodeString(sig)
+		if err != nil || !hmac.Equal(got, webhookMAC(sha256.New, secret, body)) {
+			return nil, ErrWebhookSignature
+		}
+		signature = sig
+
+	case models.WebhookStripe:
+		if signature, err = verifyStripeSignature(header("Stripe-Signature"), secret, body, now, tolerance); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported webhook scheme '%s'", cfg.Scheme)
+	}
+
+	if !cfg.RejectReplays {
+		return nil, nil
+	}
+	// The signature is always remembered: the nonce header is not signed (hmac), so a
+	// replay with a fresh nonce must still be caught. The nonce additionally catches a
+	// delivery the sender signed again for its retry.
+	nonces := []string{"sig\x00" + signature}
+	if cfg.NonceHeader != "" {
+		nonce := header(cfg.NonceHeader)
+		if nonce == "" {
+			return nil, fmt.Errorf("%w: %s header is missing", ErrWebhookSignature, cfg.NonceHeader)
+		}
+		nonces = append(nonces, "id\x00"+nonce)
+	}
+	// Nonces outlive the timestamp window on both sides, so a replay is either too old or remembered
+	for _, nonce := range nonces {
+		sum := sha256.Sum256([]byte(nonce))
+		nonceKey := "webhook:nonce:" + cfg.Secret + ":" + hex.EncodeToString(sum[:])
+		stored, err := currentCache().Add(ctx, nonceKey, now.Unix(), 2*tolerance)
+		if err == nil && !stored {
+			err = ErrWebhookReplay
+		}
+		if err != nil {
+			if forgetErr := ForgetWebhookNonce(ctx, nonceKeys...); forgetErr != nil {
+				log.Printf("WARN: Could not release webhook nonces of a rejected call: %v", forgetErr)
+			}
+			if errors.Is(err, ErrWebhookReplay) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("webhook replay check failed: %w", err)
+		}
+		nonceKeys = append(nonceKeys, nonceKey)
+	}
+	return nonceKeys, nil
+}
+
+type inboundKey struct{}
+
+// inboundRequest is the raw HTTP request a flow was started by.
+type inboundRequest struct {
+	clientIP string
+	header   func(string) string
+	body     []byte
+}
+
+// WithInboundRequest makes the raw request available to actions that verify signatures
+// over the exact bytes received (the parsed data state cannot reproduce them), and the
+// resolved client address to the "geoip" transform.
+func WithInboundRequest(ctx context.Context, clientIP string, header func(string) string, body []byte) context.Context {
+	return context.WithValue(ctx, inboundKey{}, &inboundRequest{clientIP: clientIP, header: header, body: body})
+}
+
+func inboundFrom(ctx context.Context) *inboundRequest {
+	r, _ := ctx.Value(inboundKey{}).(*inboundRequest)
+	return r
+}
+
+// ForgetWebhookNonce releases the nonces recorded by VerifyWebhook.
+func ForgetWebhookNonce(ctx context.Context, nonceKeys ...string) error {
+	var errs []error
+	for _, nonceKey := range nonceKeys {
+		errs = append(errs, currentCache().Delete(ctx, nonceKey))
+	}
+	return errors.Join(errs...)
+}
+
+// checkWebhookTimestamp checks a unix-seconds timestamp against the allowed skew.
+func checkWebhookTimestamp(value string, now time.Time, tolerance time.Duration) error {
+	unix, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: missing or malformed timestamp", ErrWebhookSignature)
+	}
+	if skew := now.Sub(time.Unix(unix, 0)); skew > tolerance || skew < -tolerance {
+		return fmt.Errorf("%w: timestamp outside the tolerance", ErrWebhookSignature)
+	}
+	return nil
+}
+
+// verifyStripeSignature checks "t=<unix>,v1=<hex>[,v1=<hex>...]": any v1 signature of
+// "<t>.<body>" may match (Stripe sends several while a secret is being rolled).
+// It returns the matching signature.
+func verifyStripeSignature(value, secret string, body []byte, now time.Time, tolerance time.Duration) (string, error) {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(value, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			timestamp = v
+		case "v1":
+			signatures = append(signatures, v)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return "", fmt.Errorf("%w: malformed Stripe-Signature header", ErrWebhookSignature)
+	}
+	if err := checkWebhookTimestamp(timestamp, now, tolerance); err != nil {
+		return "", err
+	}
+	expected := webhookMAC(sha256.New, secret, append([]byte(timestamp+"."), body...))
+	for _, sig := range signatures {
+		if got, err := hex.DecodeString(sig); err == nil && hmac.Equal(got, expected) {
+			return sig, nil
+		}
+	}
+	return "", ErrWebhookSignature
+}
+
+func webhookMAC(newHash func() hash.Hash, secret string, data []byte) []byte {
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// checkWebhook validates a definition's webhook verification settings.
+func checkWebhook(cfg *models.WebhookVerification, add func(path, format string, args ...interface{})) {
+	if cfg.Secret == "" {
+		add("webhook.secret", "webhook verification requires a secret name")
+	}
+	switch cfg.Scheme {
+	case models.WebhookHMAC, "":
+		if _, ok := webhookAlgorithms[cfg.Algorithm]; cfg.Algorithm != "" && !ok {
+			add("webhook.algorithm", "unsupported algorithm '%s' (expected sha1, sha256 or sha512)", cfg.Algorithm)
+		}
+		if cfg.Encoding != "" && cfg.Encoding != "hex" && cfg.Encoding != "base64" {
+			add("webhook.encoding", "unsupported encoding '%s' (expected hex or base64)", cfg.Encoding)
+		}
+	case models.WebhookGitHub, models.WebhookStripe:
+		if cfg.Header != "" || cfg.Algorithm != "" || cfg.Encoding != "" || cfg.Prefix != "" || cfg.TimestampHeader != "" {
+			add("webhook", "header, algorithm, encoding, prefix and timestampHeader only apply to the hmac scheme")
+		}
+	default:
+		add("webhook.scheme", "unsupported webhook scheme '%s' (expected hmac, github or stripe)", cfg.Scheme)
+	}
+	if cfg.ToleranceSeconds < 0 {
+		add("webhook.toleranceSeconds", "toleranceSeconds must not be negative")
+	}
+	if cfg.NonceHeader != "" && !cfg.RejectReplays {
+		add("webhook.nonceHeader", "nonceHeader requires rejectReplays")
+	}
+	// Nonces are only remembered for the timestamp window, so a signature without a
+	// timestamp could be replayed once its nonce expires
+	if untimed := cfg.Scheme == models.WebhookGitHub || (cfg.Scheme != models.WebhookStripe && cfg.TimestampHeader == ""); cfg.RejectReplays && untimed {
+		add("webhook.rejectReplays", "rejectReplays requires a signed timestamp (the stripe scheme, or hmac with timestampHeader)")
+	}
+}
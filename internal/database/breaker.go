@@ -0,0 +1,201 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/description"
+)
+
+// ErrCircuitOpen is returned without touching MongoDB while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("database unavailable (circuit breaker open)")
+
+// Circuit breaker states.
+const (
+	BreakerClosed   = "closed"    // Operations run normally
+	BreakerOpen     = "open"      // Operations fail fast with ErrCircuitOpen
+	BreakerHalfOpen = "half-open" // Cooldown elapsed, a probe operation may run
+)
+
+// Defaults for the circuit breaker.
+const (
+	defaultBreakerThreshold = 5                // Consecutive infrastructure failures that open the breaker
+	defaultBreakerCooldown  = 10 * time.Second // Time open before a probe is allowed
+)
+
+// BreakerStats is a snapshot of the circuit breaker for readiness checks and stats.
+type BreakerStats struct {
+	State     string     `json:"state"`
+	Failures  int        `json:"consecutiveFailures"`
+	Opened    int64      `json:"timesOpened"`
+	Rejected  int64      `json:"rejected"` // Operations failed fast while open
+	LastError string     `json:"lastError,omitempty"`
+	OpenedAt  *time.Time `json:"openedAt,omitempty"`
+}
+
+// circuitBreaker stops store operations from waiting on an unreachable MongoDB.
+// It opens after consecutive connection/timeout failures or when the driver's
+// monitoring sees no reachable server, and closes again once MongoDB responds.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+
+	state     string
+	failures  int
+	openedAt  time.Time
+	probing   bool // A half-open probe is in flight
+	reachable bool // Monitoring has seen a reachable server since the last outage
+	opened    int64
+	rejected  int64
+	lastError string
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		threshold = defaultBreakerThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, state: BreakerClosed}
+}
+
+// allow reports whether an operation may run. After the cooldown one probe is let through.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			b.rejected++
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		log.Println("INFO: Database circuit breaker half-open, probing MongoDB")
+		return nil
+	case BreakerHalfOpen:
+		if b.probing {
+			b.rejected++
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record feeds an operation result into the breaker. Only connection and timeout
+// errors count as failures; any other result means MongoDB answered.
+func (b *circuitBreaker) record(err error) {
+	if errors.Is(err, ErrCircuitOpen) {
+		// A nested operation was refused; release the probe slot this one may hold,
+		// or the breaker stays half-open rejecting everything
+		b.mu.Lock()
+		b.probing = false
+		b.mu.Unlock()
+		return
+	}
+	if err != nil && !isUnavailable(err) {
+		err = nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if err == nil {
+		b.closeLocked()
+		return
+	}
+	b.failures++
+	b.lastError = err.Error()
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.openLocked()
+	}
+}
+
+// serverAvailable is driven by the driver's topology monitoring, so the breaker
+// reacts to an outage (and recovers) even without request traffic.
+func (b *circuitBreaker) serverAvailable(available bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasReachable := b.reachable
+	b.reachable = available
+	if available {
+		b.closeLocked()
+		return
+	}
+	if wasReachable { // Servers start out unknown while the client connects
+		b.lastError = "no reachable MongoDB server"
+		b.openLocked()
+	}
+}
+
+func (b *circuitBreaker) openLocked() {
+	if b.state != BreakerOpen {
+		b.opened++
+		log.Printf("WARN: Database circuit breaker opened after %d failures: %s", b.failures, b.lastError)
+	}
+	b.state = BreakerOpen
+	b.openedAt = time.Now().UTC()
+}
+
+func (b *circuitBreaker) closeLocked() {
+	if b.state != BreakerClosed {
+		log.Println("INFO: Database circuit breaker closed, MongoDB is reachable again")
+	}
+	b.state = BreakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *circuitBreaker) stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := BreakerStats{
+		State:     b.state,
+		Failures:  b.failures,
+		Opened:    b.opened,
+		Rejected:  b.rejected,
+		LastError: b.lastError,
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		stats.OpenedAt = &openedAt
+	}
+	return stats
+}
+
+// serverMonitor reports topology changes to the breaker: the deployment is available
+// while at least one server is reachable.
+func (b *circuitBreaker) serverMonitor() *event.ServerMonitor {
+	return &event.ServerMonitor{
+		TopologyDescriptionChanged: func(e *event.TopologyDescriptionChangedEvent) {
+			available := false
+			for _, server := range e.NewDescription.Servers {
+				if server.Kind != description.Unknown {
+					available = true
+					break
+				}
+			}
+			b.serverAvailable(available)
+		},
+	}
+}
+
+// isUnavailable reports whether err means MongoDB could not be reached in time.
+func isUnavailable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false // The caller went away; says nothing about the database
+	}
+	return mongo.IsNetworkError(err) || mongo.IsTimeout(err)
+}
+
+// BreakerStats returns the current state of the database circuit breaker.
+func (s *Store) BreakerStats() BreakerStats {
+	return s.breaker.stats()
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"api-genarator/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// instanceCollection holds the heartbeats of the server processes sharing this database.
+const instanceCollection = "instances"
+
+// Definition change operations reported by WatchAPIDefinitions.
+const (
+	DefinitionUpserted = "upsert"
+	DefinitionDeleted  = "delete"
+)
+
+// DefinitionChange is one change to the api-definitions collection.
+// API is nil for deletes, which only carry the document ID.
+type DefinitionChange struct {
+	Op  string
+	ID  primitive.ObjectID
+	API *models.ApiDefinition
+}
+
+// HeartbeatInstance upserts this instance's heartbeat record
+func (s *Store) HeartbeatInstance(ctx context.Context, instance models.Instance) error {
+	instance.LastSeen = time.Now().UTC()
+	opts := options.Replace().SetUpsert(true)
+	if _, err := s.db.Collection(instanceCollection).ReplaceOne(ctx, bson.M{"_id": instance.ID}, instance, opts); err != nil {
+		return fmt.Errorf("%w: instance heartbeat failed: %w", ErrUpdateFailed, err)
+	}
+	return nil
+}
+
+// ListInstances returns the instances seen since the given time, and prunes long-dead ones
+func (s *Store) ListInstances(ctx context.Context, since time.Time) ([]models.Instance, error) {
+	coll := s.db.Collection(instanceCollection)
+	// Records of crashed instances are never removed by their owner
+	_, _ = coll.DeleteMany(ctx, bson.M{"lastSeen": bson.M{"$lt": time.Now().UTC().Add(-24 * time.Hour)}})
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := coll.Find(ctx, bson.M{"lastSeen": bson.M{"$gte": since}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	instances := []models.Instance{}
+	if err := cursor.All(ctx, &instances); err != nil {
+		return nil, fmt.Errorf("database decode failed: %w", err)
+	}
+	return instances, nil
+}
+
+// RemoveInstance deletes this instance's heartbeat record on shutdown
+func (s *Store) RemoveInstance(ctx context.Context, id string) error {
+	if _, err := s.db.Collection(instanceCollection).DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return fmt.Errorf("%w: %w", ErrDeleteFailed, err)
+	}
+	return nil
+}
+
+// WatchAPIDefinitions streams changes to the api-definitions collection to fn until ctx
+// is done or the stream fails. Change streams need a replica set or sharded cluster.
+func (s *Store) WatchAPIDefinitions(ctx context.Context, fn func(DefinitionChange)) error {
+	if !s.supportsTransactions {
+		return fmt.Errorf("%w: change streams require a replica set or sharded cluster", ErrConfigError)
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}}}}},
+	}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	stream, err := s.apiDefCollection.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return fmt.Errorf("failed to open change stream: %w", err)
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var event struct {
+			OperationType string `bson:"operationType"`
+			DocumentKey   struct {
+				ID primitive.ObjectID `bson:"_id"`
+			} `bson:"documentKey"`
+			FullDocument *models.ApiDefinition `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&event); err != nil {
+			return fmt.Errorf("failed to decode change event: %w", err)
+		}
+		change := DefinitionChange{Op: DefinitionUpserted, ID: event.DocumentKey.ID, API: event.FullDocument}
+		if event.OperationType == "delete" || event.FullDocument == nil {
+			// An update whose document was deleted before the lookup is a delete as well
+			change = DefinitionChange{Op: DefinitionDeleted, ID: event.DocumentKey.ID}
+		}
+		fn(change)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("change stream closed: %w", stream.Err())
+}
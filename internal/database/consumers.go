@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"api-genarator/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	consumerCollection      = "consumers"      // Registered API consumers
+	consumerUsageCollection = "consumer-usage" // Calls per consumer and definition
+)
+
+// ErrConsumerExists is returned when registering a consumer name that is taken.
+var ErrConsumerExists = errors.New("consumer already registered")
+
+// CreateConsumer registers a new consumer.
+func (s *Store) CreateConsumer(ctx context.Context, consumer *models.Consumer) (err error) {
+	if err := s.breaker.allow(); err != nil {
+		return err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	now := time.Now().UTC()
+	consumer.CreatedAt, consumer.UpdatedAt = now, now
+	if _, err := s.db.Collection(consumerCollection).InsertOne(ctx, consumer); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrConsumerExists
+		}
+		return fmt.Errorf("%w: consumer save failed: %w", ErrSaveFailed, err)
+	}
+	return nil
+}
+
+// GetConsumer finds a consumer by name.
+func (s *Store) GetConsumer(ctx context.Context, name string) (_ *models.Consumer, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	var consumer models.Consumer
+	if err := s.db.Collection(consumerCollection).FindOne(ctx, bson.M{"_id": name}).Decode(&consumer); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return &consumer, nil
+}
+
+// ListConsumers returns all consumers sorted by name.
+func (s *Store) ListConsumers(ctx context.Context) ([]models.Consumer, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetComment("List consumers")
+	cursor, err := s.db.Collection(consumerCollection).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	consumers := []models.Consumer{}
+	if err := cursor.All(ctx, &consumers); err != nil {
+		return nil, fmt.Errorf("database decode failed: %w", err)
+	}
+	return consumers, nil
+}
+
+// SetConsumerKey replaces the key of a consumer (key rotation).
+func (s *Store) SetConsumerKey(ctx context.Context, name, keyHash, keyPrefix string) (err error) {
+	if err := s.breaker.allow(); err != nil {
+		return err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	update := bson.M{"$set": bson.M{"keyHash": keyHash, "keyPrefix": keyPrefix, "updatedAt": time.Now().UTC()}}
+	result, err := s.db.Collection(consumerCollection).UpdateOne(ctx, bson.M{"_id": name}, update)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUpdateFailed, err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteConsumer removes a consumer and its usage counts.
+func (s *Store) DeleteConsumer(ctx context.Context, name string) error {
+	result, err := s.db.Collection(consumerCollection).DeleteOne(ctx, bson.M{"_id": name})
+	if err != nil {
+		return fmt.Errorf("database delete failed: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	if _, err := s.db.Collection(consumerUsageCollection).DeleteMany(ctx, bson.M{"consumer": name}); err != nil {
+		return fmt.Errorf("database delete failed: %w", err)
+	}
+	return nil
+}
+
+// RecordConsumerUsage adds counted calls to the stored usage, one upsert per consumer
+// and definition.
+func (s *Store) RecordConsumerUsage(ctx context.Context, usage []models.ConsumerUsage) (err error) {
+	if len(usage) == 0 {
+		return nil
+	}
+	if err := s.breaker.allow(); err != nil {
+		return err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	s.usageIndexOnce.Do(func() {
+		model := mongo.IndexModel{
+			Keys:    bson.D{{Key: "consumer", Value: 1}, {Key: "api", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("consumer_api_unique"),
+		}
+		if _, err := s.db.Collection(consumerUsageCollection).Indexes().CreateOne(ctx, model); err != nil {
+			log.Printf("WARN: Failed to create index on %s (usage reports are slower): %v", consumerUsageCollection, err)
+		}
+	})
+
+	writes := make([]mongo.WriteModel, 0, len(usage))
+	for _, u := range usage {
+		update := bson.M{
+			"$inc": bson.M{"calls": u.Calls},
+			"$min": bson.M{"firstCalledAt": u.FirstCalledAt},
+			"$max": bson.M{"lastCalledAt": u.LastCalledAt},
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"consumer": u.Consumer, "api": u.API}).
+			SetUpdate(update).
+			SetUpsert(true))
+	}
+	opts := options.BulkWrite().SetOrdered(false).SetComment("Record consumer usage")
+	if _, err := s.db.Collection(consumerUsageCollection).BulkWrite(ctx, writes, opts); err != nil {
+		return fmt.Errorf("%w: consumer usage: %w", ErrSaveFailed, err)
+	}
+	return nil
+}
+
+// ListConsumerUsage returns the usage counts matching filter ("consumer" and/or "api"),
+// most recently called first.
+func (s *Store) ListConsumerUsage(ctx context.Context, filter bson.M) ([]models.ConsumerUsage, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "lastCalledAt", Value: -1}}).SetProjection(bson.M{"_id": 0}).SetComment("List consumer usage")
+	cursor, err := s.db.Collection(consumerUsageCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	usage := []models.ConsumerUsage{}
+	if err := cursor.All(ctx, &usage); err != nil {
+		return nil, fmt.Errorf("database decode failed: %w", err)
+	}
+	return usage, nil
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"api-genarator/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// deadLetterCollection stores payloads whose writes or deliveries failed after retries.
+const deadLetterCollection = "dead-letters"
+
+// InsertDeadLetter records a failed payload so it can be inspected and replayed later
+func (s *Store) InsertDeadLetter(ctx context.Context, entry *models.DeadLetter) (err error) {
+	if err := s.breaker.allow(); err != nil {
+		return err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	entry.ID = primitive.NewObjectID()
+	entry.Status = models.DeadLetterPending
+	entry.CreatedAt = time.Now().UTC()
+	if _, err := s.db.Collection(deadLetterCollection).InsertOne(ctx, entry); err != nil {
+		log.Printf("ERROR: Failed to write dead letter for API '%s' (%s): %v", entry.APIName, entry.Source, err)
+		return fmt.Errorf("%w: dead letter insert failed: %w", ErrSaveFailed, err)
+	}
+	log.Printf("WARN: Dead-lettered %d documents for API '%s' (%s, ID: %s)", len(entry.Documents), entry.APIName, entry.Source, entry.ID.Hex())
+	return nil
+}
+
+// ListDeadLetters returns dead letters, newest first, optionally filtered by status
+func (s *Store) ListDeadLetters(ctx context.Context, status string, limit int64) ([]models.DeadLetter, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(limit).SetComment("List dead letters")
+	cursor, err := s.db.Collection(deadLetterCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.DeadLetter{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("database decode failed: %w", err)
+	}
+	return entries, nil
+}
+
+// GetDeadLetter finds a dead letter by its hex ID
+func (s *Store) GetDeadLetter(ctx context.Context, id string) (*models.DeadLetter, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	var entry models.DeadLetter
+	err = s.db.Collection(deadLetterCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(&entry)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return &entry, nil
+}
+
+// MarkDeadLetterReplayed records the outcome of a replay attempt
+func (s *Store) MarkDeadLetterReplayed(ctx context.Context, id primitive.ObjectID, replayErr error) error {
+	set := bson.M{"lastReplayAt": time.Now().UTC()}
+	if replayErr != nil {
+		set["error"] = replayErr.Error()
+	} else {
+		set["status"] = models.DeadLetterReplayed
+	}
+	update := bson.M{"$set": set, "$inc": bson.M{"attempts": 1}}
+	if _, err := s.db.Collection(deadLetterCollection).UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		return fmt.Errorf("%w: %w", ErrUpdateFailed, err)
+	}
+	return nil
+}
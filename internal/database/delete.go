@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// archiveBatchSize is the number of documents moved to an archive collection per transaction.
+const archiveBatchSize = 500
+
+// DeleteOneData removes at most one document matching the filter.
+func (s *Store) DeleteOneData(ctx context.Context, dbName, collName string, filter bson.M) (_ int64, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return 0, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return 0, err
+	}
+	if len(filter) == 0 {
+		return 0, fmt.Errorf("%w: empty filter provided for delete operation", ErrDeleteFailed)
+	}
+	result, err := collection.DeleteOne(ctx, filter, options.Delete().SetComment("Delete one dynamic document"))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrDeleteFailed, err)
+	}
+	log.Printf("INFO: Deleted %d document from %s.%s matching filter.", result.DeletedCount, dbName, collName)
+	return result.DeletedCount, nil
+}
+
+// SoftDeleteData stamps field with the current time on the matching documents (at most
+// one unless many) that are not soft-deleted yet.
+func (s *Store) SoftDeleteData(ctx context.Context, dbName, collName string, filter bson.M, field string, many bool) (_ int64, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return 0, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return 0, err
+	}
+	if len(filter) == 0 {
+		return 0, fmt.Errorf("%w: empty filter provided for delete operation", ErrDeleteFailed)
+	}
+	filter = bson.M{"$and": []bson.M{filter, {field: bson.M{"$exists": false}}}}
+	update := bson.M{"$set": bson.M{field: time.Now().UTC()}}
+	opts := options.Update().SetComment("Soft delete dynamic data")
+
+	var result *mongo.UpdateResult
+	if many {
+		result, err = collection.UpdateMany(ctx, filter, update, opts)
+	} else {
+		result, err = collection.UpdateOne(ctx, filter, update, opts)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrDeleteFailed, err)
+	}
+	log.Printf("INFO: Soft-deleted %d documents in %s.%s matching filter.", result.ModifiedCount, dbName, collName)
+	return result.ModifiedCount, nil
+}
+
+// ArchiveData moves the matching documents (at most one unless many) to the archive
+// collection of the same database, stamped with archivedAt. Each batch is moved in a
+// transaction when the deployment supports them; without, documents are copied before
+// they are deleted, so an interruption can only leave a copy behind, never lose one.
+func (s *Store) ArchiveData(ctx context.Context, dbName, collName, archiveName string, filter bson.M, many bool) (_ int64, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return 0, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return 0, err
+	}
+	archive, err := s.getDynamicCollection(dbName, archiveName)
+	if err != nil {
+		return 0, err
+	}
+	if len(filter) == 0 {
+		return 0, fmt.Errorf("%w: empty filter provided for delete operation", ErrDeleteFailed)
+	}
+
+	limit := int64(archiveBatchSize)
+	if !many {
+		limit = 1
+	}
+	var moved int64
+	for {
+		var batch int64
+		err = s.runInTransaction(ctx, func(ctx context.Context) error {
+			batch = 0
+			cursor, err := collection.Find(ctx, filter, options.Find().SetLimit(limit))
+			if err != nil {
+				return err
+			}
+			var docs []bson.M
+			if err := cursor.All(ctx, &docs); err != nil {
+				return err
+			}
+			if len(docs) == 0 {
+				return nil
+			}
+			now := time.Now().UTC()
+			writes := make([]mongo.WriteModel, 0, len(docs))
+			ids := make(bson.A, 0, len(docs))
+			for _, doc := range docs {
+				doc["archivedAt"] = now
+				writes = append(writes, mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": doc["_id"]}).SetReplacement(doc).SetUpsert(true))
+				ids = append(ids, doc["_id"])
+			}
+			if _, err := archive.BulkWrite(ctx, writes); err != nil {
+				return fmt.Errorf("failed to copy to archive: %w", err)
+			}
+			result, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+			if err != nil {
+				return err
+			}
+			batch = result.DeletedCount
+			return nil
+		})
+		if err != nil {
+			return moved, fmt.Errorf("%w: %w", ErrDeleteFailed, err)
+		}
+		moved += batch
+		if !many || batch < limit {
+			break
+		}
+	}
+	log.Printf("INFO: Archived %d documents from %s.%s to %s.%s.", moved, dbName, collName, dbName, archiveName)
+	return moved, nil
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"api-genarator/internal/models"
+)
+
+// errorCatalog stores the error codes flows return through returnError.
+var errorCatalog = namedCollection[models.ErrorDefinition]{collection: "error-catalog", kind: "error definition"}
+
+// SaveErrorDefinition creates or replaces an error code.
+func (s *Store) SaveErrorDefinition(ctx context.Context, def *models.ErrorDefinition) error {
+	def.UpdatedAt = time.Now().UTC()
+	return errorCatalog.save(ctx, s, def.Code, def)
+}
+
+// GetErrorDefinition finds an error code.
+func (s *Store) GetErrorDefinition(ctx context.Context, code string) (*models.ErrorDefinition, error) {
+	return errorCatalog.get(ctx, s, code)
+}
+
+// ListErrorDefinitions returns all error codes sorted by code.
+func (s *Store) ListErrorDefinitions(ctx context.Context) ([]models.ErrorDefinition, error) {
+	return errorCatalog.list(ctx, s)
+}
+
+// DeleteErrorDefinition removes an error code.
+func (s *Store) DeleteErrorDefinition(ctx context.Context, code string) error {
+	return errorCatalog.delete(ctx, s, code)
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"api-genarator/internal/models"
+)
+
+// featureFlags stores the feature flags evaluated by the "featureFlag" condition operator.
+var featureFlags = namedCollection[models.FeatureFlag]{collection: "feature-flags", kind: "feature flag"}
+
+// SaveFeatureFlag creates or replaces a flag by name.
+func (s *Store) SaveFeatureFlag(ctx context.Context, flag *models.FeatureFlag) error {
+	flag.UpdatedAt = time.Now().UTC()
+	return featureFlags.save(ctx, s, flag.Name, flag)
+}
+
+// GetFeatureFlag finds a flag by name.
+func (s *Store) GetFeatureFlag(ctx context.Context, name string) (*models.FeatureFlag, error) {
+	return featureFlags.get(ctx, s, name)
+}
+
+// ListFeatureFlags returns all flags sorted by name.
+func (s *Store) ListFeatureFlags(ctx context.Context) ([]models.FeatureFlag, error) {
+	return featureFlags.list(ctx, s)
+}
+
+// DeleteFeatureFlag removes a flag by name.
+func (s *Store) DeleteFeatureFlag(ctx context.Context, name string) error {
+	return featureFlags.delete(ctx, s, name)
+}
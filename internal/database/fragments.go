@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"api-genarator/internal/models"
+)
+
+// flowFragments stores the named flow fragments run by callFlow actions.
+var flowFragments = namedCollection[models.FlowFragment]{collection: "flow-fragments", kind: "flow fragment"}
+
+// SaveFlowFragment creates or replaces a fragment by name.
+func (s *Store) SaveFlowFragment(ctx context.Context, fragment *models.FlowFragment) error {
+	fragment.UpdatedAt = time.Now().UTC()
+	return flowFragments.save(ctx, s, fragment.Name, fragment)
+}
+
+// GetFlowFragment finds a fragment by name.
+func (s *Store) GetFlowFragment(ctx context.Context, name string) (*models.FlowFragment, error) {
+	return flowFragments.get(ctx, s, name)
+}
+
+// ListFlowFragments returns all fragments sorted by name.
+func (s *Store) ListFlowFragments(ctx context.Context) ([]models.FlowFragment, error) {
+	return flowFragments.list(ctx, s)
+}
+
+// DeleteFlowFragment removes a fragment by name.
+func (s *Store) DeleteFlowFragment(ctx context.Context, name string) error {
+	return flowFragments.delete(ctx, s, name)
+}
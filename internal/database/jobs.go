@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"api-genarator/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// jobCollection stores the status and result of async flow executions.
+const jobCollection = "jobs"
+
+// CreateJob records a new queued job for an async definition
+func (s *Store) CreateJob(ctx context.Context, apiName string) (_ *models.Job, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	job := &models.Job{
+		ID:        primitive.NewObjectID(),
+		APIName:   apiName,
+		Status:    models.JobQueued,
+		CreatedAt: time.Now().UTC(),
+	}
+	if _, err := s.db.Collection(jobCollection).InsertOne(ctx, job); err != nil {
+		return nil, fmt.Errorf("%w: job insert failed: %w", ErrSaveFailed, err)
+	}
+	return job, nil
+}
+
+// StartJob marks a job as running
+func (s *Store) StartJob(ctx context.Context, id primitive.ObjectID) error {
+	update := bson.M{"$set": bson.M{"status": models.JobRunning, "startedAt": time.Now().UTC()}}
+	if _, err := s.db.Collection(jobCollection).UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		return fmt.Errorf("%w: %w", ErrUpdateFailed, err)
+	}
+	return nil
+}
+
+// FinishJob stores the outcome of a job. A non-nil jobErr marks it failed.
+func (s *Store) FinishJob(ctx context.Context, id primitive.ObjectID, result interface{}, jobErr error) error {
+	set := bson.M{"finishedAt": time.Now().UTC()}
+	if jobErr != nil {
+		set["status"] = models.JobFailed
+		set["error"] = jobErr.Error()
+	} else {
+		set["status"] = models.JobSucceeded
+		set["result"] = result
+	}
+	if _, err := s.db.Collection(jobCollection).UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set}); err != nil {
+		return fmt.Errorf("%w: %w", ErrUpdateFailed, err)
+	}
+	return nil
+}
+
+// GetJob finds a job by its hex ID
+func (s *Store) GetJob(ctx context.Context, id string) (_ *models.Job, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	var job models.Job
+	err = s.db.Collection(jobCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(&job)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return &job, nil
+}
+
+// FailStaleJobs marks queued or running jobs created before cutoff as failed.
+// Jobs live in worker memory, so anything that old was lost with a previous process.
+func (s *Store) FailStaleJobs(ctx context.Context, cutoff time.Time) (int64, error) {
+	filter := bson.M{
+		"status":    bson.M{"$in": bson.A{models.JobQueued, models.JobRunning}},
+		"createdAt": bson.M{"$lt": cutoff},
+	}
+	update := bson.M{"$set": bson.M{
+		"status":     models.JobFailed,
+		"error":      "job was interrupted (server restarted before it finished)",
+		"finishedAt": time.Now().UTC(),
+	}}
+	res, err := s.db.Collection(jobCollection).UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrUpdateFailed, err)
+	}
+	return res.ModifiedCount, nil
+}
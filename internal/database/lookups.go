@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"api-genarator/internal/models"
+)
+
+// lookupTables stores the lookup tables flows read through "$lookup.<name>[<key>]".
+var lookupTables = namedCollection[models.LookupTable]{collection: "lookup-tables", kind: "lookup table"}
+
+// SaveLookupTable creates or replaces a table by name.
+func (s *Store) SaveLookupTable(ctx context.Context, table *models.LookupTable) error {
+	table.UpdatedAt = time.Now().UTC()
+	return lookupTables.save(ctx, s, table.Name, table)
+}
+
+// GetLookupTable finds a table by name.
+func (s *Store) GetLookupTable(ctx context.Context, name string) (*models.LookupTable, error) {
+	return lookupTables.get(ctx, s, name)
+}
+
+// ListLookupTables returns all tables sorted by name.
+func (s *Store) ListLookupTables(ctx context.Context) ([]models.LookupTable, error) {
+	return lookupTables.list(ctx, s)
+}
+
+// DeleteLookupTable removes a table by name.
+func (s *Store) DeleteLookupTable(ctx context.Context, name string) error {
+	return lookupTables.delete(ctx, s, name)
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"api-genarator/internal/models"
+)
+
+// mappingProfiles stores the named mapping profiles referenced by useMappings.
+var mappingProfiles = namedCollection[models.MappingProfile]{collection: "mapping-profiles", kind: "mapping profile"}
+
+// SaveMappingProfile creates or replaces a profile by name.
+func (s *Store) SaveMappingProfile(ctx context.Context, profile *models.MappingProfile) error {
+	profile.UpdatedAt = time.Now().UTC()
+	return mappingProfiles.save(ctx, s, profile.Name, profile)
+}
+
+// GetMappingProfile finds a profile by name.
+func (s *Store) GetMappingProfile(ctx context.Context, name string) (*models.MappingProfile, error) {
+	return mappingProfiles.get(ctx, s, name)
+}
+
+// ListMappingProfiles returns all profiles sorted by name.
+func (s *Store) ListMappingProfiles(ctx context.Context) ([]models.MappingProfile, error) {
+	return mappingProfiles.list(ctx, s)
+}
+
+// DeleteMappingProfile removes a profile by name.
+func (s *Store) DeleteMappingProfile(ctx context.Context, name string) error {
+	return mappingProfiles.delete(ctx, s, name)
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"api-genarator/internal/models"
+)
+
+// messageCatalogs stores the per-locale messages that return data reads through $t("key").
+var messageCatalogs = namedCollection[models.MessageCatalog]{collection: "message-catalogs", kind: "message catalog"}
+
+// SaveMessageCatalog creates or replaces the catalog of a locale.
+func (s *Store) SaveMessageCatalog(ctx context.Context, catalog *models.MessageCatalog) error {
+	catalog.UpdatedAt = time.Now().UTC()
+	return messageCatalogs.save(ctx, s, catalog.Locale, catalog)
+}
+
+// GetMessageCatalog finds the catalog of a locale.
+func (s *Store) GetMessageCatalog(ctx context.Context, locale string) (*models.MessageCatalog, error) {
+	return messageCatalogs.get(ctx, s, locale)
+}
+
+// ListMessageCatalogs returns all catalogs sorted by locale.
+func (s *Store) ListMessageCatalogs(ctx context.Context) ([]models.MessageCatalog, error) {
+	return messageCatalogs.list(ctx, s)
+}
+
+// DeleteMessageCatalog removes the catalog of a locale.
+func (s *Store) DeleteMessageCatalog(ctx context.Context, locale string) error {
+	return messageCatalogs.delete(ctx, s, locale)
+}
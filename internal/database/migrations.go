@@ -0,0 +1,337 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"api-genarator/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migrations and their history are kept apart, so editing a pending migration never
+// touches the record of what was applied.
+const (
+	migrationCollection        = "migrations"
+	migrationHistoryCollection = "migration-history"
+)
+
+// staleMigrationRun is how long a "running" record blocks other runs; after that the
+// process that started it is assumed dead and the run can be resumed.
+const staleMigrationRun = time.Hour
+
+var (
+	ErrMigrationApplied = errors.New("migration was already applied")
+	ErrMigrationRunning = errors.New("migration is being applied")
+)
+
+// SaveMigration creates or replaces a migration that has not been applied.
+func (s *Store) SaveMigration(ctx context.Context, migration *models.Migration) (err error) {
+	if err := s.breaker.allow(); err != nil {
+		return err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	if err := s.checkMigrationPending(ctx, migration.Name); err != nil {
+		return err
+	}
+	migration.UpdatedAt = time.Now().UTC()
+	opts := options.Replace().SetUpsert(true)
+	if _, err := s.db.Collection(migrationCollection).ReplaceOne(ctx, bson.M{"_id": migration.Name}, migration, opts); err != nil {
+		return fmt.Errorf("%w: migration save failed: %w", ErrSaveFailed, err)
+	}
+	return nil
+}
+
+// GetMigration finds a migration by name, with its history record.
+func (s *Store) GetMigration(ctx context.Context, name string) (_ *models.Migration, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	var migration models.Migration
+	if err := s.db.Collection(migrationCollection).FindOne(ctx, bson.M{"_id": name}).Decode(&migration); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	run, err := s.GetMigrationRun(ctx, name)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	migration.Run = run
+	return &migration, nil
+}
+
+// ListMigrations returns the migrations in name order (the order they are applied in),
+// optionally of one definition, with their history records.
+func (s *Store) ListMigrations(ctx context.Context, definition string) ([]models.Migration, error) {
+	filter := bson.M{}
+	if definition != "" {
+		filter["definition"] = definition
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetComment("List migrations")
+	cursor, err := s.db.Collection(migrationCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	migrations := []models.Migration{}
+	if err := cursor.All(ctx, &migrations); err != nil {
+		return nil, fmt.Errorf("database decode failed: %w", err)
+	}
+	if len(migrations) == 0 {
+		return migrations, nil
+	}
+
+	names := make([]string, 0, len(migrations))
+	for _, m := range migrations {
+		names = append(names, m.Name)
+	}
+	historyCursor, err := s.db.Collection(migrationHistoryCollection).Find(ctx, bson.M{"_id": bson.M{"$in": names}})
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	var runs []models.MigrationRun
+	if err := historyCursor.All(ctx, &runs); err != nil {
+		return nil, fmt.Errorf("database decode failed: %w", err)
+	}
+	byName := make(map[string]*models.MigrationRun, len(runs))
+	for i := range runs {
+		byName[runs[i].Migration] = &runs[i]
+	}
+	for i := range migrations {
+		migrations[i].Run = byName[migrations[i].Name]
+	}
+	return migrations, nil
+}
+
+// DeleteMigration removes a migration that has not been applied, with the record of a failed run.
+func (s *Store) DeleteMigration(ctx context.Context, name string) error {
+	if err := s.checkMigrationPending(ctx, name); err != nil {
+		return err
+	}
+	result, err := s.db.Collection(migrationCollection).DeleteOne(ctx, bson.M{"_id": name})
+	if err != nil {
+		return fmt.Errorf("database delete failed: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	if _, err := s.db.Collection(migrationHistoryCollection).DeleteOne(ctx, bson.M{"_id": name}); err != nil {
+		return fmt.Errorf("database delete failed: %w", err)
+	}
+	return nil
+}
+
+// checkMigrationPending fails when the migration was applied or is being applied.
+func (s *Store) checkMigrationPending(ctx context.Context, name string) error {
+	run, err := s.GetMigrationRun(ctx, name)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return migrationRunError(run)
+}
+
+func migrationRunError(run *models.MigrationRun) error {
+	switch run.Status {
+	case models.MigrationApplied:
+		return ErrMigrationApplied
+	case models.MigrationRunning:
+		return ErrMigrationRunning
+	}
+	return nil
+}
+
+// GetMigrationRun finds the history record of a migration.
+func (s *Store) GetMigrationRun(ctx context.Context, name string) (*models.MigrationRun, error) {
+	var run models.MigrationRun
+	if err := s.db.Collection(migrationHistoryCollection).FindOne(ctx, bson.M{"_id": name}).Decode(&run); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return &run, nil
+}
+
+// StartMigrationRun claims a migration for this run. A failed run, or one left running for
+// longer than staleMigrationRun, is resumed from its completed steps; otherwise the
+// claim fails with ErrMigrationApplied or ErrMigrationRunning, so instances never apply
+// the same migration twice.
+func (s *Store) StartMigrationRun(ctx context.Context, migration *models.Migration, by string) (_ *models.MigrationRun, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	now := time.Now().UTC()
+	run := &models.MigrationRun{
+		Migration:  migration.Name,
+		Definition: migration.Definition,
+		Status:     models.MigrationRunning,
+		Affected:   []int64{},
+		AppliedBy:  by,
+		StartedAt:  now,
+	}
+	history := s.db.Collection(migrationHistoryCollection)
+	_, err = history.InsertOne(ctx, run)
+	if err == nil {
+		return run, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, fmt.Errorf("%w: migration history insert failed: %w", ErrSaveFailed, err)
+	}
+
+	filter := bson.M{"_id": migration.Name, "$or": bson.A{
+		bson.M{"status": models.MigrationFailed},
+		bson.M{"status": models.MigrationRunning, "startedAt": bson.M{"$lt": now.Add(-staleMigrationRun)}},
+	}}
+	update := bson.M{
+		"$set":   bson.M{"status": models.MigrationRunning, "appliedBy": by, "startedAt": now},
+		"$unset": bson.M{"error": "", "finishedAt": ""},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = history.FindOneAndUpdate(ctx, filter, update, opts).Decode(run)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if current, err := s.GetMigrationRun(ctx, migration.Name); err == nil {
+			if err := migrationRunError(current); err != nil {
+				return nil, err
+			}
+		}
+		return nil, ErrMigrationRunning // Claimed by another instance in the meantime
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: migration history update failed: %w", ErrUpdateFailed, err)
+	}
+	log.Printf("INFO: Resuming migration '%s' at step %d", migration.Name, run.CompletedSteps+1)
+	return run, nil
+}
+
+// SaveMigrationRun records the progress or outcome of a run.
+func (s *Store) SaveMigrationRun(ctx context.Context, run *models.MigrationRun) (err error) {
+	if err := s.breaker.allow(); err != nil {
+		return err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	if _, err := s.db.Collection(migrationHistoryCollection).ReplaceOne(ctx, bson.M{"_id": run.Migration}, run); err != nil {
+		return fmt.Errorf("%w: migration history save failed: %w", ErrUpdateFailed, err)
+	}
+	return nil
+}
+
+// TransformData passes every document matching filter through fn and replaces the ones
+// it returns. Documents are read in _id order, which replacements never change, so no
+// document is visited twice.
+func (s *Store) TransformData(ctx context.Context, dbName, collName string, filter bson.M, fn func(doc bson.M) (bson.M, error)) (_ int64, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return 0, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return 0, err
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetComment("Migrate dynamic data")
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, fmt.Errorf("database query failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var modified int64
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return modified, fmt.Errorf("database decode failed: %w", err)
+		}
+		replacement, err := fn(doc)
+		if err != nil {
+			return modified, fmt.Errorf("document %v: %w", doc["_id"], err)
+		}
+		if replacement == nil {
+			continue
+		}
+		replacement["_id"] = doc["_id"]
+		result, err := collection.ReplaceOne(ctx, bson.M{"_id": doc["_id"]}, replacement)
+		if err != nil {
+			return modified, fmt.Errorf("%w: document %v: %w", ErrUpdateFailed, doc["_id"], err)
+		}
+		modified += result.ModifiedCount
+	}
+	if err := cursor.Err(); err != nil {
+		return modified, fmt.Errorf("database cursor failed: %w", err)
+	}
+	return modified, nil
+}
+
+// UpdateData applies update operators to every document matching filter.
+func (s *Store) UpdateData(ctx context.Context, dbName, collName string, filter, update bson.M) (_ int64, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return 0, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return 0, err
+	}
+	result, err := collection.UpdateMany(ctx, filter, update, options.Update().SetComment("Migrate dynamic data"))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrUpdateFailed, err)
+	}
+	log.Printf("INFO: Updated %d of %d matching documents in %s.%s", result.ModifiedCount, result.MatchedCount, dbName, collName)
+	return result.ModifiedCount, nil
+}
+
+// CreateDataIndex creates an index on a dynamic collection ("-field" sorts descending).
+func (s *Store) CreateDataIndex(ctx context.Context, dbName, collName string, index models.MigrationIndex) error {
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return err
+	}
+	keys := bson.D{}
+	for _, field := range index.Fields {
+		if name, desc := strings.CutPrefix(field, "-"); desc {
+			keys = append(keys, bson.E{Key: name, Value: -1})
+		} else {
+			keys = append(keys, bson.E{Key: field, Value: 1})
+		}
+	}
+	opts := options.Index().SetName(index.Name)
+	if index.Unique {
+		opts.SetUnique(true)
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: keys, Options: opts}); err != nil {
+		return fmt.Errorf("failed to create index '%s': %w", index.Name, err)
+	}
+	log.Printf("INFO: Index '%s' on %v created for %s.%s", index.Name, index.Fields, dbName, collName)
+	return nil
+}
+
+// DropDataIndex drops an index of a dynamic collection by name.
+func (s *Store) DropDataIndex(ctx context.Context, dbName, collName, name string) error {
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return err
+	}
+	if _, err := collection.Indexes().DropOne(ctx, name); err != nil {
+		return fmt.Errorf("failed to drop index '%s': %w", name, err)
+	}
+	log.Printf("INFO: Index '%s' dropped from %s.%s", name, dbName, collName)
+	return nil
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ModifyOptions configures FindOneAndUpdateData.
+type ModifyOptions struct {
+	Sort         bson.D // Which document to pick when several match
+	Upsert       bool   // Insert a document when none matches
+	ReturnBefore bool   // Return the document as it was before the update
+}
+
+// FindOneAndUpdateData atomically applies update (operators such as $inc or $push) to
+// one document matching the filter and returns it after the update, or before when
+// opts.ReturnBefore. It returns nil without error when no document matched.
+func (s *Store) FindOneAndUpdateData(ctx context.Context, dbName, collName string, filter, update bson.M, opts ModifyOptions) (_ bson.M, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return nil, err
+	}
+	if len(filter) == 0 {
+		return nil, fmt.Errorf("%w: empty filter provided for update operation", ErrUpdateFailed)
+	}
+	returnDocument := options.After
+	if opts.ReturnBefore {
+		returnDocument = options.Before
+	}
+	findOpts := options.FindOneAndUpdate().
+		SetUpsert(opts.Upsert).
+		SetReturnDocument(returnDocument).
+		SetComment("Flow update of dynamic document")
+	if len(opts.Sort) > 0 {
+		findOpts.SetSort(opts.Sort)
+	}
+
+	var doc bson.M
+	err = collection.FindOneAndUpdate(ctx, filter, update, findOpts).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUpdateFailed, err)
+	}
+	log.Printf("DEBUG: Updated one document in %s.%s matching filter.", dbName, collName)
+	return doc, nil
+}
+
+// FindOneAndDeleteData atomically removes one document matching the filter (the first
+// in sort order) and returns it. It returns nil without error when no document matched.
+func (s *Store) FindOneAndDeleteData(ctx context.Context, dbName, collName string, filter bson.M, sort bson.D) (_ bson.M, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return nil, err
+	}
+	if len(filter) == 0 {
+		return nil, fmt.Errorf("%w: empty filter provided for delete operation", ErrDeleteFailed)
+	}
+	findOpts := options.FindOneAndDelete().SetComment("Flow delete of dynamic document")
+	if len(sort) > 0 {
+		findOpts.SetSort(sort)
+	}
+
+	var doc bson.M
+	err = collection.FindOneAndDelete(ctx, filter, findOpts).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDeleteFailed, err)
+	}
+	log.Printf("INFO: Deleted one document from %s.%s matching filter.", dbName, collName)
+	return doc, nil
+}
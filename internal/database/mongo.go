@@ -1,11 +1,15 @@
 package database
 
 import (
+	"bytes"
 	"context"
 	"errors" // สำหรับสร้าง custom errors
 	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	// --- เปลี่ยน your_module_name เป็นชื่อ Module Go ของคุณ ---
@@ -15,6 +19,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -30,6 +35,7 @@ var (
 	ErrSaveFailed            = errors.New("failed to save data")
 	ErrDeleteFailed          = errors.New("failed to delete data")
 	ErrConfigError           = errors.New("configuration error (e.g., missing db/collection name)")
+	ErrTooManyDocuments      = errors.New("query matches too many documents") // Over SetMaxFindDocuments
 )
 
 // Store holds the database connection and collections handles
@@ -38,6 +44,12 @@ type Store struct {
 	dbName           string // เก็บชื่อ DB หลักไว้เผื่อใช้
 	db               *mongo.Database
 	apiDefCollection *mongo.Collection
+	indexesReady     atomic.Bool     // Unique indexes on api-definitions are in place
+	breaker          *circuitBreaker // Fails operations fast while MongoDB is unreachable
+	otpIndexOnce     sync.Once       // TTL index on otp-codes is created on first use
+	usageIndexOnce   sync.Once       // Unique index on consumer-usage is created on first use
+	// supportsTransactions is true when connected to a replica set or mongos
+	supportsTransactions bool
 }
 
 // NewStore creates a new database store instance
@@ -46,8 +58,11 @@ func NewStore(ctx context.Context, uri, dbName string, apiDefCollectionName stri
 		return nil, fmt.Errorf("%w: MongoDB URI and Database Name cannot be empty", ErrConfigError)
 	}
 
+	breaker := newCircuitBreaker(0, 0)
 	clientOptions := options.Client().ApplyURI(uri).
-		SetTimeout(10 * time.Second) // ตั้งค่า timeout สำหรับการเชื่อมต่อ
+		SetTimeout(10 * time.Second). // ตั้งค่า timeout สำหรับการเชื่อมต่อ
+		SetServerMonitor(breaker.serverMonitor()).
+		SetMonitor(commandMonitor()) // Query timings for slow-query reporting
 
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
@@ -69,14 +84,21 @@ func NewStore(ctx context.Context, uri, dbName string, apiDefCollectionName stri
 	// TODO: ทำให้ชื่อ collection สามารถ config ได้
 	apiDefCollection := db.Collection("api-definitions")
 
-	// อาจจะสร้าง Index ที่จำเป็นตรงนี้ (ทำครั้งเดียวตอนเริ่ม หรือใช้เครื่องมือแยก)
-	// createIndexes(ctx, apiDefCollection)
+	// Transactions need a replica set or sharded cluster
+	var hello bson.M
+	supportsTransactions := false
+	if err := client.Database("admin").RunCommand(pingCtx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err == nil {
+		supportsTransactions = hello["setName"] != nil || hello["msg"] == "isdbgrid"
+	}
+	log.Printf("INFO: MongoDB transactions supported: %t", supportsTransactions)
 
 	return &Store{
-		client:           client,
-		dbName:           dbName,
-		db:               db,
-		apiDefCollection: apiDefCollection,
+		client:               client,
+		dbName:               dbName,
+		db:                   db,
+		apiDefCollection:     apiDefCollection,
+		supportsTransactions: supportsTransactions,
+		breaker:              breaker,
 	}, nil
 }
 
@@ -103,15 +125,17 @@ func (s *Store) GetCollection(name string) *mongo.Collection {
 
 // --- API Definition Methods ---
 
-// LoadAPIs loads all API definitions from the database into a map
-func (s *Store) LoadAPIs(ctx context.Context) (map[string]models.ApiDefinition, error) {
+// LoadAPIs loads all API definitions from the database into a map.
+// Definitions that cannot be decoded or lack a route are skipped and reported as rejected.
+func (s *Store) LoadAPIs(ctx context.Context) (map[string]models.ApiDefinition, []models.RejectedDefinition, error) {
 	loadedRoutes := make(map[string]models.ApiDefinition)
+	var rejected []models.RejectedDefinition
 	log.Println("INFO: Loading API definitions from database...")
 
 	cursor, err := s.apiDefCollection.Find(ctx, bson.M{}, options.Find().SetComment("Load all API definitions"))
 	if err != nil {
 		log.Printf("ERROR: Error finding API definitions during load: %v", err)
-		return nil, fmt.Errorf("failed to query API definitions: %w", err)
+		return nil, nil, fmt.Errorf("failed to query API definitions: %w", err)
 	}
 	defer cursor.Close(ctx)
 
@@ -120,12 +144,14 @@ func (s *Store) LoadAPIs(ctx context.Context) (map[string]models.ApiDefinition,
 		var api models.ApiDefinition
 		if err := cursor.Decode(&api); err != nil {
 			log.Printf("WARN: Error decoding API definition during load (ID: %s): %v", api.ID.Hex(), err) // Log ID if available
-			continue                                                                                      // Skip invalid entries
+			rejected = append(rejected, models.RejectedDefinition{ID: api.ID.Hex(), Name: api.Name, Reasons: []string{"decode failed: " + err.Error()}})
+			continue // Skip invalid entries
 		}
 
 		// Basic validation
 		if api.Method == "" || api.Endpoint == "" {
 			log.Printf("WARN: Skipping API definition with empty method or endpoint (ID: %s, Name: %s)", api.ID.Hex(), api.Name)
+			rejected = append(rejected, models.RejectedDefinition{ID: api.ID.Hex(), Name: api.Name, Reasons: []string{"method and endpoint are required"}})
 			continue
 		}
 
@@ -133,6 +159,13 @@ func (s *Store) LoadAPIs(ctx context.Context) (map[string]models.ApiDefinition,
 		if existing, exists := loadedRoutes[key]; exists {
 			log.Printf("WARN: Duplicate route key '%s' detected during load. API Name '%s' (ID: %s) is overwriting API Name '%s' (ID: %s).",
 				key, api.Name, api.ID.Hex(), existing.Name, existing.ID.Hex())
+			rejected = append(rejected, models.RejectedDefinition{
+				ID:            existing.ID.Hex(),
+				Name:          existing.Name,
+				Key:           key,
+				Reasons:       []string{fmt.Sprintf("duplicate route key, overwritten by '%s'", api.Name)},
+				ConflictsWith: api.Name,
+			})
 		}
 		loadedRoutes[key] = api
 		loadedCount++
@@ -144,58 +177,51 @@ func (s *Store) LoadAPIs(ctx context.Context) (map[string]models.ApiDefinition,
 	}
 
 	log.Printf("INFO: Finished loading %d API definitions.", loadedCount)
-	return loadedRoutes, nil
+	return loadedRoutes, rejected, nil
 }
 
 // CreateAPIDefinition inserts a new API definition after validation checks
-func (s *Store) CreateAPIDefinition(ctx context.Context, api *models.ApiDefinition) (primitive.ObjectID, error) {
+func (s *Store) CreateAPIDefinition(ctx context.Context, api *models.ApiDefinition) (_ primitive.ObjectID, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return primitive.NilObjectID, err
+	}
+	defer func() { s.breaker.record(err) }()
+
 	// 1. Validate required fields
 	if api.Name == "" || api.Endpoint == "" || api.Method == "" || api.Database == "" || api.Collection == "" {
 		return primitive.NilObjectID, ErrMissingRequiredFields
 	}
 	// TODO: Add more validation (method format, endpoint format?)
 
-	// 2. Check for duplicate Name (atomic check if possible, otherwise best effort)
-	countName, err := s.apiDefCollection.CountDocuments(ctx, bson.M{"name": api.Name}, options.Count().SetLimit(1))
-	if err != nil {
-		log.Printf("ERROR: Failed to check existing API name '%s': %v", api.Name, err)
-		return primitive.NilObjectID, fmt.Errorf("failed to check existing API name: %w", err)
-	}
-	if countName > 0 {
-		return primitive.NilObjectID, fmt.Errorf("%w: %s", ErrDuplicateName, api.Name)
-	}
-
-	// 3. Check for duplicate Method + Endpoint
-	countEndpoint, err := s.apiDefCollection.CountDocuments(ctx, bson.M{"method": api.Method, "endpoint": api.Endpoint}, options.Count().SetLimit(1))
-	if err != nil {
-		log.Printf("ERROR: Failed to check existing API endpoint '%s %s': %v", api.Method, api.Endpoint, err)
-		return primitive.NilObjectID, fmt.Errorf("failed to check existing API endpoint: %w", err)
-	}
-	if countEndpoint > 0 {
-		return primitive.NilObjectID, fmt.Errorf("%w: %s %s", ErrDuplicateEndpoint, api.Method, api.Endpoint)
-	}
+	// 2. Check-then-insert inside a transaction (when supported). Once the unique indexes
+	// are in place the insert itself is the guarantee and the pre-checks are skipped.
+	var result *mongo.InsertOneResult
+	err = s.runInTransaction(ctx, func(ctx context.Context) error {
+		if !s.IndexesReady() {
+			if err := s.checkDefinitionConflicts(ctx, api.Name, api.Method, api.Endpoint, primitive.NilObjectID); err != nil {
+				return err
+			}
+		}
 
-	// 4. Prepare for insertion
-	api.CreatedAt = time.Now().UTC() // Use UTC time
-	api.ID = primitive.NewObjectID() // Generate ID here for consistency
+		// 3. Prepare for insertion
+		api.CreatedAt = time.Now().UTC() // Use UTC time
+		api.ID = primitive.NewObjectID() // Generate ID here for consistency
 
-	// 5. Insert
-	result, err := s.apiDefCollection.InsertOne(ctx, api)
-	if err != nil {
-		if mongo.IsDuplicateKeyError(err) {
-			// This might happen due to race conditions if indexes enforce uniqueness differently
-			log.Printf("WARN: Duplicate key error on insert for API '%s' (likely race condition): %v", api.Name, err)
-			// Determine which constraint failed if possible from the error message
-			if strings.Contains(err.Error(), "name_1") { // Assuming index name for name
-				return primitive.NilObjectID, fmt.Errorf("%w: %s", ErrDuplicateName, api.Name)
-			}
-			if strings.Contains(err.Error(), "method_1_endpoint_1") { // Assuming index name for method/endpoint
-				return primitive.NilObjectID, fmt.Errorf("%w: %s %s", ErrDuplicateEndpoint, api.Method, api.Endpoint)
+		// 4. Insert
+		var err error
+		result, err = s.apiDefCollection.InsertOne(ctx, api)
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				log.Printf("WARN: Duplicate key error on insert for API '%s': %v", api.Name, err)
+				return duplicateKeyError(err, api.Name, api.Method, api.Endpoint)
 			}
-			return primitive.NilObjectID, ErrDuplicateKey
+			log.Printf("ERROR: Failed to insert API definition '%s': %v", api.Name, err)
+			return fmt.Errorf("database insert failed: %w", err)
 		}
-		log.Printf("ERROR: Failed to insert API definition '%s': %v", api.Name, err)
-		return primitive.NilObjectID, fmt.Errorf("database insert failed: %w", err)
+		return nil
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
 	}
 
 	// Check if InsertedID matches the one we generated (it should)
@@ -209,10 +235,15 @@ func (s *Store) CreateAPIDefinition(ctx context.Context, api *models.ApiDefiniti
 }
 
 // ListAPIDefinitions retrieves all API definitions
-func (s *Store) ListAPIDefinitions(ctx context.Context) ([]models.ApiDefinition, error) {
+func (s *Store) ListAPIDefinitions(ctx context.Context) (_ []models.ApiDefinition, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
 	var apis []models.ApiDefinition
 
-	cursor, err := s.apiDefCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{"name", 1}}).SetComment("List all API definitions")) // Sort by name
+	cursor, err := s.apiDefCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}).SetComment("List all API definitions")) // Sort by name
 	if err != nil {
 		log.Printf("ERROR: Failed to find APIs for list: %v", err)
 		return nil, fmt.Errorf("database query failed: %w", err)
@@ -233,11 +264,16 @@ func (s *Store) ListAPIDefinitions(ctx context.Context) ([]models.ApiDefinition,
 }
 
 // GetAPIDefinitionByName finds a single API definition by its unique name
-func (s *Store) GetAPIDefinitionByName(ctx context.Context, name string) (*models.ApiDefinition, error) {
+func (s *Store) GetAPIDefinitionByName(ctx context.Context, name string) (_ *models.ApiDefinition, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
 	var api models.ApiDefinition
 	filter := bson.M{"name": name}
 
-	err := s.apiDefCollection.FindOne(ctx, filter, options.FindOne().SetComment("Get API definition by name")).Decode(&api)
+	err = s.apiDefCollection.FindOne(ctx, filter, options.FindOne().SetComment("Get API definition by name")).Decode(&api)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, ErrNotFound // Return specific error for not found
@@ -248,8 +284,32 @@ func (s *Store) GetAPIDefinitionByName(ctx context.Context, name string) (*model
 	return &api, nil
 }
 
+// GetAPIDefinitionByRoute finds the definition serving a method and endpoint
+func (s *Store) GetAPIDefinitionByRoute(ctx context.Context, method, endpoint string) (_ *models.ApiDefinition, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	var api models.ApiDefinition
+	filter := bson.M{"method": method, "endpoint": endpoint}
+	err = s.apiDefCollection.FindOne(ctx, filter, options.FindOne().SetComment("Get API definition by route")).Decode(&api)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return &api, nil
+}
+
 // DeleteAPIDefinitionByName deletes an API definition by its name
-func (s *Store) DeleteAPIDefinitionByName(ctx context.Context, name string) (int64, error) {
+func (s *Store) DeleteAPIDefinitionByName(ctx context.Context, name string) (_ int64, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return 0, err
+	}
+	defer func() { s.breaker.record(err) }()
+
 	filter := bson.M{"name": name}
 	result, err := s.apiDefCollection.DeleteOne(ctx, filter, options.Delete().SetComment("Delete API definition by name"))
 	if err != nil {
@@ -266,94 +326,223 @@ func (s *Store) DeleteAPIDefinitionByName(ctx context.Context, name string) (int
 	return result.DeletedCount, nil
 }
 
-// UpdateAPIDefinition updates an existing API definition by name
-func (s *Store) UpdateAPIDefinition(ctx context.Context, name string, payload *models.ApiDefinition) (*models.ApiDefinition, error) {
+// UpdateAPIDefinition updates an existing API definition by name.
+// It returns the updated definition and the one it replaced (e.g. to find the old route key).
+func (s *Store) UpdateAPIDefinition(ctx context.Context, name string, payload *models.ApiDefinition) (_, _ *models.ApiDefinition, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
 	// 1. Validate payload required fields
 	if payload.Endpoint == "" || payload.Method == "" || payload.Database == "" || payload.Collection == "" {
-		return nil, ErrMissingRequiredFields
+		return nil, nil, ErrMissingRequiredFields
 	}
 
-	// 2. Get existing API to check if endpoint/method is changing and if it exists
-	filter := bson.M{"name": name}
-	var existingAPI models.ApiDefinition
-	err := s.apiDefCollection.FindOne(ctx, filter).Decode(&existingAPI)
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, ErrNotFound // API to update doesn't exist
+	var existingAPI, updatedAPI models.ApiDefinition
+	err = s.runInTransaction(ctx, func(ctx context.Context) error {
+		// 2. Get existing API to check if endpoint/method is changing and if it exists
+		filter := bson.M{"name": name}
+		err := s.apiDefCollection.FindOne(ctx, filter).Decode(&existingAPI)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return ErrNotFound // API to update doesn't exist
+			}
+			log.Printf("ERROR: Failed to retrieve existing API '%s' before update: %v", name, err)
+			return fmt.Errorf("failed to retrieve existing API: %w", err)
 		}
-		log.Printf("ERROR: Failed to retrieve existing API '%s' before update: %v", name, err)
-		return nil, fmt.Errorf("failed to retrieve existing API: %w", err)
-	}
 
-	// 3. If Method or Endpoint changed, check for conflicts with *other* documents
-	if existingAPI.Method != payload.Method || existingAPI.Endpoint != payload.Endpoint {
-		conflictFilter := bson.M{
-			"method":   payload.Method,
-			"endpoint": payload.Endpoint,
-			"_id":      bson.M{"$ne": existingAPI.ID}, // Exclude the current document
+		// 3. If Method or Endpoint changed, check for conflicts with *other* documents
+		// (the unique index enforces this when it exists)
+		if !s.IndexesReady() && (existingAPI.Method != payload.Method || existingAPI.Endpoint != payload.Endpoint) {
+			if err := s.checkDefinitionConflicts(ctx, "", payload.Method, payload.Endpoint, existingAPI.ID); err != nil {
+				return err
+			}
 		}
-		count, err := s.apiDefCollection.CountDocuments(ctx, conflictFilter, options.Count().SetLimit(1))
-		if err != nil {
-			log.Printf("ERROR: Failed to check for endpoint conflict during update for API '%s': %v", name, err)
-			return nil, fmt.Errorf("failed to check for endpoint conflict: %w", err)
+
+		// 4. Prepare update document ($set only allowed fields)
+		updateFields := bson.M{
+			"endpoint":         payload.Endpoint,
+			"method":           payload.Method,
+			"database":         payload.Database,
+			"collection":       payload.Collection,
+			"uniqueKey":        payload.UniqueKey, // Allow update
+			"parameters":       payload.Parameters,
+			"responseSchema":   payload.ResponseSchema,
+			"computed":         payload.Computed,
+			"conditionalFlow":  payload.ConditionalFlow,
+			"validations":      payload.Validations,
+			"namespacedParams": payload.NamespacedParams,
+			"queryMode":        payload.QueryMode,
+			"distinctField":    payload.DistinctField,
+			"searchFields":     payload.SearchFields,
+			"searchIndex":      payload.SearchIndex,
+			"cursorField":      payload.CursorField,
+			"timeSeries":       payload.TimeSeries,
+			"collectionSchema": payload.CollectionSchema,
+			"download":         payload.Download,
+			"delete":           payload.Delete,
+			"accessControl":    payload.AccessControl,
+			"maintenance":      payload.Maintenance,
+			"flowBudgetMs":     payload.FlowBudgetMs,
+			"coalesceGets":     payload.CoalesceGets,
+			"mock":             payload.Mock,
+			"sandbox":          payload.Sandbox,
+			"environments":     payload.Environments,
+			"tail":             payload.Tail,
+			"ingest":           payload.Ingest,
+			"events":           payload.Events,
+			"async":            payload.Async,
+			"concurrency":      payload.Concurrency,
+			"dataScope":        payload.DataScope,
+			"fieldPermissions": payload.FieldPermissions,
+			"team":             payload.Team, // owner is kept: it only changes through a new definition
+			"summary":          payload.Summary,
+			"description":      payload.Description,
+			"examples":         payload.Examples,
+			"contact":          payload.Contact,
+			"deprecated":       payload.Deprecated,
+			"format":           payload.Format,
+			"jsonApi":          payload.JSONAPI,
+			"protobuf":         payload.Protobuf,
+			"webhook":          payload.Webhook,
+			"updatedAt":        time.Now().UTC(), // Add/update timestamp
 		}
-		if count > 0 {
-			return nil, fmt.Errorf("%w: %s %s", ErrDuplicateEndpoint, payload.Method, payload.Endpoint)
+		update := bson.M{"$set": updateFields}
+
+		// 5. Perform the update and read back the result in one step
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetComment("Update API definition by name")
+		err = s.apiDefCollection.FindOneAndUpdate(ctx, bson.M{"_id": existingAPI.ID}, update, opts).Decode(&updatedAPI)
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				log.Printf("WARN: Duplicate key error on update for API '%s': %v", name, err)
+				return duplicateKeyError(err, name, payload.Method, payload.Endpoint)
+			}
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				log.Printf("WARN: No API found with name '%s' during update operation", name)
+				return ErrNotFound
+			}
+			log.Printf("ERROR: Failed to update API definition (name: %s): %v", name, err)
+			return fmt.Errorf("%w: %w", ErrUpdateFailed, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// 4. Prepare update document ($set only allowed fields)
-	updateFields := bson.M{
-		"endpoint":        payload.Endpoint,
-		"method":          payload.Method,
-		"database":        payload.Database,
-		"collection":      payload.Collection,
-		"uniqueKey":       payload.UniqueKey, // Allow update
-		"parameters":      payload.Parameters,
-		"responseSchema":  payload.ResponseSchema,
-		"conditionalFlow": payload.ConditionalFlow,
-		"updatedAt":       time.Now().UTC(), // Add/update timestamp
+	log.Printf("INFO: API '%s' updated", name)
+	return &updatedAPI, &existingAPI, nil
+}
+
+// checkDefinitionConflicts is the best-effort uniqueness check used when the unique
+// indexes are missing. An empty name skips the name check; excludeID skips the document itself.
+func (s *Store) checkDefinitionConflicts(ctx context.Context, name, method, endpoint string, excludeID primitive.ObjectID) error {
+	if name != "" {
+		countName, err := s.apiDefCollection.CountDocuments(ctx, bson.M{"name": name}, options.Count().SetLimit(1))
+		if err != nil {
+			log.Printf("ERROR: Failed to check existing API name '%s': %v", name, err)
+			return fmt.Errorf("failed to check existing API name: %w", err)
+		}
+		if countName > 0 {
+			return fmt.Errorf("%w: %s", ErrDuplicateName, name)
+		}
 	}
-	update := bson.M{"$set": updateFields}
 
-	// 5. Perform the update
-	result, err := s.apiDefCollection.UpdateOne(ctx, filter, update, options.Update().SetComment("Update API definition by name"))
+	endpointFilter := bson.M{"method": method, "endpoint": endpoint}
+	if !excludeID.IsZero() {
+		endpointFilter["_id"] = bson.M{"$ne": excludeID}
+	}
+	countEndpoint, err := s.apiDefCollection.CountDocuments(ctx, endpointFilter, options.Count().SetLimit(1))
 	if err != nil {
-		// Check for duplicate key errors again (race condition on unique indexes if name could be updated, though it's not here)
-		if mongo.IsDuplicateKeyError(err) {
-			log.Printf("WARN: Duplicate key error on update for API '%s': %v", name, err)
-			// Determine which constraint failed if possible
-			if strings.Contains(err.Error(), "method_1_endpoint_1") { // Assuming index name for method/endpoint
-				return nil, fmt.Errorf("%w: %s %s", ErrDuplicateEndpoint, payload.Method, payload.Endpoint)
-			}
-			return nil, ErrDuplicateKey
-		}
-		log.Printf("ERROR: Failed to update API definition (name: %s): %v", name, err)
-		return nil, fmt.Errorf("%w: %w", ErrUpdateFailed, err)
+		log.Printf("ERROR: Failed to check existing API endpoint '%s %s': %v", method, endpoint, err)
+		return fmt.Errorf("failed to check existing API endpoint: %w", err)
+	}
+	if countEndpoint > 0 {
+		return fmt.Errorf("%w: %s %s", ErrDuplicateEndpoint, method, endpoint)
 	}
+	return nil
+}
 
-	if result.MatchedCount == 0 {
-		// Should have been caught by FindOne earlier, but check again
-		log.Printf("WARN: No API found with name '%s' during update operation (MatchedCount=0)", name)
-		return nil, ErrNotFound
+// duplicateKeyError maps a duplicate key error to the sentinel of the violated unique index.
+func duplicateKeyError(err error, name, method, endpoint string) error {
+	switch {
+	case strings.Contains(err.Error(), "method_1_endpoint_1"):
+		return fmt.Errorf("%w: %s %s", ErrDuplicateEndpoint, method, endpoint)
+	case strings.Contains(err.Error(), "name_1"):
+		return fmt.Errorf("%w: %s", ErrDuplicateName, name)
+	}
+	return ErrDuplicateKey
+}
+
+// runInTransaction runs fn in a multi-document transaction when the deployment supports it
+// (replica set or sharded cluster); on a standalone server fn runs directly.
+func (s *Store) runInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !s.supportsTransactions {
+		return fn(ctx)
+	}
+	session, err := s.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
 	}
-	if result.ModifiedCount == 0 && result.MatchedCount == 1 {
-		log.Printf("INFO: Update request for API '%s' matched but resulted in no changes.", name)
-		// Return the existing (unchanged) data
-		return &existingAPI, nil
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
+// SetAPIMaintenance updates only the maintenance settings of an API definition and returns the updated definition
+func (s *Store) SetAPIMaintenance(ctx context.Context, name string, cfg *models.MaintenanceConfig) (_ *models.ApiDefinition, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
 	}
+	defer func() { s.breaker.record(err) }()
 
-	log.Printf("INFO: API '%s' update result: Matched=%d, Modified=%d", name, result.MatchedCount, result.ModifiedCount)
+	filter := bson.M{"name": name}
+	update := bson.M{"$set": bson.M{"maintenance": cfg, "updatedAt": time.Now().UTC()}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetComment("Set API maintenance mode")
 
-	// 6. Fetch the updated document to return it
 	var updatedAPI models.ApiDefinition
-	err = s.apiDefCollection.FindOne(ctx, bson.M{"_id": existingAPI.ID}).Decode(&updatedAPI) // Find by ID for certainty
+	err = s.apiDefCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updatedAPI)
 	if err != nil {
-		log.Printf("CRITICAL: Failed to retrieve updated API data after successful update (name: %s, ID: %s): %v.", name, existingAPI.ID.Hex(), err)
-		// This is problematic, the DB was updated but we can't return the result.
-		return nil, fmt.Errorf("database updated, but failed to retrieve result: %w", err)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		log.Printf("ERROR: Failed to set maintenance for API '%s': %v", name, err)
+		return nil, fmt.Errorf("%w: %w", ErrUpdateFailed, err)
 	}
+	return &updatedAPI, nil
+}
+
+// SetAPIExample adds an example to a definition, replacing any example with the same name
+func (s *Store) SetAPIExample(ctx context.Context, name string, example models.ApiExample) (_ *models.ApiDefinition, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	// Pipeline update so the replacement is atomic; $literal keeps "$" strings in the example as data
+	kept := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$examples", bson.A{}}},
+		"cond":  bson.M{"$ne": bson.A{"$$this.name", example.Name}},
+	}}
+	update := bson.A{bson.M{"$set": bson.M{
+		"examples":  bson.M{"$concatArrays": bson.A{kept, bson.A{bson.M{"$literal": example}}}},
+		"updatedAt": time.Now().UTC(),
+	}}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetComment("Set API example")
 
+	var updatedAPI models.ApiDefinition
+	err = s.apiDefCollection.FindOneAndUpdate(ctx, bson.M{"name": name}, update, opts).Decode(&updatedAPI)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		log.Printf("ERROR: Failed to set example '%s' for API '%s': %v", example.Name, name, err)
+		return nil, fmt.Errorf("%w: %w", ErrUpdateFailed, err)
+	}
 	return &updatedAPI, nil
 }
 
@@ -368,11 +557,49 @@ func (s *Store) getDynamicCollection(dbName, collName string) (*mongo.Collection
 	return s.client.Database(dbName).Collection(collName), nil
 }
 
-// SaveData performs an upsert or insert operation on a dynamic collection
-func (s *Store) SaveData(ctx context.Context, dbName, collName, uniqueKey string, data map[string]interface{}) error {
+// upsertFilter matches the document to upsert by its unique key and, for definitions with a
+// dataScope, by its scope fields too, so an upsert never overwrites another owner's document.
+func upsertFilter(doc map[string]interface{}, uniqueKey string, uniqueValue interface{}, scopeKeys []string) bson.M {
+	filter := bson.M{uniqueKey: uniqueValue}
+	for _, key := range scopeKeys {
+		filter[key] = doc[key]
+	}
+	return filter
+}
+
+// SaveResult describes what SaveData did with a document.
+type SaveResult struct {
+	Created  bool        `json:"created"`            // A new document was inserted
+	ID       interface{} `json:"id,omitempty"`       // _id of the saved document
+	Modified []string    `json:"modified,omitempty"` // Fields whose stored value an update changed
+}
+
+// Outcome names the result for clients: "created", "updated" or "unchanged".
+func (r *SaveResult) Outcome() string {
+	switch {
+	case r.Created:
+		return "created"
+	case len(r.Modified) > 0:
+		return "updated"
+	}
+	return "unchanged"
+}
+
+// SaveData performs an upsert or insert operation on a dynamic collection.
+// scopeKeys (the definition's dataScope fields) are added to the upsert filter.
+func (s *Store) SaveData(ctx context.Context, dbName, collName, uniqueKey string, data map[string]interface{}, scopeKeys ...string) (_ *SaveResult, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+	return s.saveData(ctx, dbName, collName, uniqueKey, data, scopeKeys...)
+}
+
+// saveData is SaveData without the circuit breaker, for operations that already hold it.
+func (s *Store) saveData(ctx context.Context, dbName, collName, uniqueKey string, data map[string]interface{}, scopeKeys ...string) (*SaveResult, error) {
 	collection, err := s.getDynamicCollection(dbName, collName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	log.Printf("DEBUG: Attempting to save data to %s.%s (UniqueKey: '%s')", dbName, collName, uniqueKey)
@@ -384,76 +611,176 @@ func (s *Store) SaveData(ctx context.Context, dbName, collName, uniqueKey string
 		uniqueValue, exists := data[uniqueKey]
 		// Check if unique key exists AND is not nil AND not an empty string representation
 		if exists && uniqueValue != nil && fmt.Sprintf("%v", uniqueValue) != "" {
-			filter := bson.M{uniqueKey: uniqueValue}
+			filter := upsertFilter(data, uniqueKey, uniqueValue, scopeKeys)
 
 			// Ensure _id is not part of the $set if it exists in data, as _id is immutable.
 			// Also remove the uniqueKey field itself from $set as it's used in the filter.
 			updateData := make(map[string]interface{})
-			hasOtherFields := false
 			for k, v := range data {
-				if k != "_id" && k != uniqueKey {
+				if _, inFilter := filter[k]; k != "_id" && !inFilter {
 					updateData[k] = v
-					hasOtherFields = true
 				}
 			}
 
-			// Check if there are any fields left to actually set
-			if !hasOtherFields {
-				log.Printf("INFO: Upsert for %v on %s.%s skipped, only key field present.", filter, dbName, collName)
-				// Maybe touch an updatedAt field? If not, just return success as there's nothing to change.
-				// Example: update := bson.M{"$currentDate": bson.M{"_updatedAt": true}}
-				// _, err := collection.UpdateOne(ctx, filter, update, options.Update()) ... handle error ...
-				return nil // Nothing to update except the key itself
+			// The ID of a created document is chosen here, so it can be reported
+			newID := data["_id"]
+			if newID == nil {
+				newID = primitive.NewObjectID()
+			}
+			update := bson.M{"$setOnInsert": bson.M{"_id": newID}}
+			if len(updateData) > 0 {
+				update["$set"] = updateData
+			} else {
+				log.Printf("DEBUG: Upsert for %v on %s.%s has only key fields, the document is only created if missing.", filter, dbName, collName)
 			}
 
-			update := bson.M{"$set": updateData}
-			// Optional: Add $setOnInsert for fields that should only be set on creation
-			// update["$setOnInsert"] = bson.M{"_createdAt": time.Now().UTC()}
-
-			opts := options.Update().SetUpsert(true).SetComment("Save data with upsert")
+			// The previous version of the set fields tells which of them actually changed
+			projection := bson.M{"_id": 1}
+			for k := range updateData {
+				projection[k] = 1
+			}
+			opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before).SetProjection(projection).SetComment("Save data with upsert")
 			log.Printf("DEBUG: Upserting data to %s.%s with filter %v", dbName, collName, filter)
-			result, err := collection.UpdateOne(ctx, filter, update, opts)
+			var before bson.M
+			err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&before)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				log.Printf("INFO: Data inserted via upsert to %s.%s with UniqueKey '%s'=%v (ID: %v)", dbName, collName, uniqueKey, uniqueValue, newID)
+				return &SaveResult{Created: true, ID: newID}, nil
+			}
 			if err != nil {
 				log.Printf("ERROR: Failed to upsert data to %s.%s using UniqueKey '%s': %v", dbName, collName, uniqueKey, err)
-				return fmt.Errorf("%w: upsert failed: %w", ErrSaveFailed, err)
+				return nil, fmt.Errorf("%w: upsert failed: %w", ErrSaveFailed, err)
 			}
-			if result.UpsertedCount > 0 {
-				log.Printf("INFO: Data inserted via upsert to %s.%s with UniqueKey '%s'=%v (ID: %v)", dbName, collName, uniqueKey, uniqueValue, result.UpsertedID)
-			} else if result.ModifiedCount > 0 {
+			result := &SaveResult{ID: before["_id"], Modified: changedFields(before, updateData)}
+			if len(result.Modified) > 0 {
 				log.Printf("INFO: Data updated via upsert to %s.%s with UniqueKey '%s'=%v", dbName, collName, uniqueKey, uniqueValue)
 			} else {
 				log.Printf("INFO: Upsert matched document but made no changes for UniqueKey '%s'=%v in %s.%s", uniqueKey, uniqueValue, dbName, collName)
 			}
+			return result, nil
 
 		} else {
 			// UniqueKey defined but value is missing/nil/empty in data -> Insert normally
 			log.Printf("DEBUG: UniqueKey '%s' defined but missing/empty in data, inserting normally into %s.%s", uniqueKey, dbName, collName)
 			// Add createdAt timestamp on insert?
 			// data["_createdAt"] = time.Now().UTC()
-			_, err := collection.InsertOne(ctx, data, options.InsertOne().SetComment("Save data via insert (unique key missing)"))
+			inserted, err := collection.InsertOne(ctx, data, options.InsertOne().SetComment("Save data via insert (unique key missing)"))
 			if err != nil {
 				log.Printf("ERROR: Failed to insert data (UniqueKey missing/empty) into %s.%s: %v", dbName, collName, err)
-				return fmt.Errorf("%w: insert failed (unique key missing): %w", ErrSaveFailed, err)
+				return nil, fmt.Errorf("%w: insert failed (unique key missing): %w", ErrSaveFailed, err)
 			}
 			log.Printf("INFO: Data inserted successfully (UniqueKey missing/empty) into %s.%s", dbName, collName)
+			return &SaveResult{Created: true, ID: inserted.InsertedID}, nil
 		}
-	} else {
-		// No UniqueKey defined -> Insert normally
-		log.Printf("DEBUG: No UniqueKey defined, inserting normally into %s.%s", dbName, collName)
-		// Add createdAt timestamp on insert?
-		// data["_createdAt"] = time.Now().UTC()
-		_, err := collection.InsertOne(ctx, data, options.InsertOne().SetComment("Save data via insert (no unique key)"))
-		if err != nil {
-			log.Printf("ERROR: Failed to insert data (no UniqueKey) into %s.%s: %v", dbName, collName, err)
-			return fmt.Errorf("%w: insert failed (no unique key): %w", ErrSaveFailed, err)
+	}
+	// No UniqueKey defined -> Insert normally
+	log.Printf("DEBUG: No UniqueKey defined, inserting normally into %s.%s", dbName, collName)
+	// Add createdAt timestamp on insert?
+	// data["_createdAt"] = time.Now().UTC()
+	inserted, err := collection.InsertOne(ctx, data, options.InsertOne().SetComment("Save data via insert (no unique key)"))
+	if err != nil {
+		log.Printf("ERROR: Failed to insert data (no UniqueKey) into %s.%s: %v", dbName, collName, err)
+		return nil, fmt.Errorf("%w: insert failed (no unique key): %w", ErrSaveFailed, err)
+	}
+	log.Printf("INFO: Data inserted successfully (no UniqueKey) into %s.%s", dbName, collName)
+	return &SaveResult{Created: true, ID: inserted.InsertedID}, nil
+}
+
+// changedFields returns the fields of update (sorted) whose value differs from the
+// stored one.
+func changedFields(before bson.M, update map[string]interface{}) []string {
+	var changed []string
+	for field, value := range update {
+		if old, existed := before[field]; !existed || !sameValue(old, value) {
+			changed = append(changed, field)
 		}
-		log.Printf("INFO: Data inserted successfully (no UniqueKey) into %s.%s", dbName, collName)
 	}
-	return nil
+	sort.Strings(changed)
+	return changed
+}
+
+// sameValue compares a stored value with a new one as they are stored: scalars by their
+// BSON encoding, documents key by key (their key order is not kept in a map).
+func sameValue(stored, value interface{}) bool {
+	storedDoc, ok1 := asDocument(stored)
+	valueDoc, ok2 := asDocument(value)
+	if ok1 || ok2 {
+		if !ok1 || !ok2 || len(storedDoc) != len(valueDoc) {
+			return false
+		}
+		for k, v := range valueDoc {
+			if sv, ok := storedDoc[k]; !ok || !sameValue(sv, v) {
+				return false
+			}
+		}
+		return true
+	}
+	storedList, ok1 := asList(stored)
+	valueList, ok2 := asList(value)
+	if ok1 && ok2 {
+		if len(storedList) != len(valueList) {
+			return false
+		}
+		for i := range valueList {
+			if !sameValue(storedList[i], valueList[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	storedRaw, err1 := bson.Marshal(bson.M{"v": stored})
+	valueRaw, err2 := bson.Marshal(bson.M{"v": value})
+	return err1 == nil && err2 == nil && bytes.Equal(storedRaw, valueRaw)
+}
+
+func asList(v interface{}) ([]interface{}, bool) {
+	switch l := v.(type) {
+	case bson.A:
+		return l, true
+	case []interface{}:
+		return l, true
+	}
+	return nil, false
+}
+
+func asDocument(v interface{}) (map[string]interface{}, bool) {
+	switch d := v.(type) {
+	case bson.M:
+		return d, true
+	case map[string]interface{}:
+		return d, true
+	}
+	return nil, false
+}
+
+// maxFindDocuments caps the documents a single find may load into memory (0 = no cap).
+var maxFindDocuments atomic.Int64
+
+// SetMaxFindDocuments sets the guardrail against unbounded finds; n <= 0 removes it.
+func SetMaxFindDocuments(n int64) {
+	maxFindDocuments.Store(max(n, 0))
+}
+
+// FindOptions shapes the result of FindDataWith. Zero values leave the Mongo defaults.
+type FindOptions struct {
+	Projection bson.M // Fields to include (or exclude)
+	Sort       bson.D
+	Skip       int64
+	Limit      int64
 }
 
 // FindData retrieves documents from a dynamic collection based on a filter
 func (s *Store) FindData(ctx context.Context, dbName, collName string, filter bson.M) ([]bson.M, error) {
+	return s.FindDataWith(ctx, dbName, collName, filter, FindOptions{})
+}
+
+// FindDataWith retrieves documents like FindData with projection, sort and paging applied
+func (s *Store) FindDataWith(ctx context.Context, dbName, collName string, filter bson.M, findOpts FindOptions) (_ []bson.M, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
 	collection, err := s.getDynamicCollection(dbName, collName)
 	if err != nil {
 		return nil, err
@@ -462,8 +789,24 @@ func (s *Store) FindData(ctx context.Context, dbName, collName string, filter bs
 	log.Printf("DEBUG: Finding data in %s.%s with filter: %v", dbName, collName, filter)
 	var results []bson.M
 
-	// Add options like sort, limit, projection if needed
 	opts := options.Find().SetComment("Find dynamic data")
+	if len(findOpts.Projection) > 0 {
+		opts.SetProjection(findOpts.Projection)
+	}
+	if len(findOpts.Sort) > 0 {
+		opts.SetSort(findOpts.Sort)
+	}
+	if findOpts.Skip > 0 {
+		opts.SetSkip(findOpts.Skip)
+	}
+	if findOpts.Limit > 0 {
+		opts.SetLimit(findOpts.Limit)
+	}
+	capped := false
+	if maxDocs := maxFindDocuments.Load(); maxDocs > 0 && (findOpts.Limit <= 0 || findOpts.Limit > maxDocs) {
+		opts.SetLimit(maxDocs + 1) // One more than allowed, to tell "exactly at the cap" from "over it"
+		capped = true
+	}
 
 	cursor, err := collection.Find(ctx, filter, opts)
 	if err != nil {
@@ -477,6 +820,11 @@ func (s *Store) FindData(ctx context.Context, dbName, collName string, filter bs
 		return nil, fmt.Errorf("database decode failed: %w", err)
 	}
 
+	if capped && int64(len(results)) > maxFindDocuments.Load() {
+		log.Printf("WARN: Find on %s.%s matches more than %d documents, rejected", dbName, collName, maxFindDocuments.Load())
+		return nil, fmt.Errorf("%w (limit %d, narrow the filter or page with $top)", ErrTooManyDocuments, maxFindDocuments.Load())
+	}
+
 	// Return empty slice if null
 	if results == nil {
 		results = []bson.M{}
@@ -486,8 +834,413 @@ func (s *Store) FindData(ctx context.Context, dbName, collName string, filter bs
 	return results, nil
 }
 
+// CountData counts documents in a dynamic collection matching a filter
+func (s *Store) CountData(ctx context.Context, dbName, collName string, filter bson.M) (_ int64, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return 0, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := collection.CountDocuments(ctx, filter, options.Count().SetComment("Count dynamic data"))
+	if err != nil {
+		log.Printf("ERROR: Failed to count documents in %s.%s: %v", dbName, collName, err)
+		return 0, fmt.Errorf("database count failed: %w", err)
+	}
+
+	log.Printf("DEBUG: Counted %d documents in %s.%s matching filter.", count, dbName, collName)
+	return count, nil
+}
+
+// DistinctData returns the distinct values of a field in a dynamic collection matching a filter
+func (s *Store) DistinctData(ctx context.Context, dbName, collName, field string, filter bson.M) (_ []interface{}, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := collection.Distinct(ctx, field, filter, options.Distinct().SetComment("Distinct dynamic data"))
+	if err != nil {
+		log.Printf("ERROR: Failed to get distinct '%s' from %s.%s: %v", field, dbName, collName, err)
+		return nil, fmt.Errorf("database distinct failed: %w", err)
+	}
+
+	// Return empty slice if null
+	if values == nil {
+		values = []interface{}{}
+	}
+	return values, nil
+}
+
+// AggregateData runs an aggregation pipeline against a dynamic collection
+func (s *Store) AggregateData(ctx context.Context, dbName, collName string, pipeline []bson.M) (_ []bson.M, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+	return s.aggregateData(ctx, dbName, collName, pipeline)
+}
+
+// aggregateData is AggregateData without the circuit breaker, for operations that already hold it.
+func (s *Store) aggregateData(ctx context.Context, dbName, collName string, pipeline []bson.M) ([]bson.M, error) {
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline, options.Aggregate().SetComment("Aggregate dynamic data"))
+	if err != nil {
+		log.Printf("ERROR: Failed to run aggregation on %s.%s: %v", dbName, collName, err)
+		return nil, fmt.Errorf("database aggregate failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []bson.M
+	if err := cursor.All(ctx, &results); err != nil {
+		log.Printf("ERROR: Failed to decode aggregation results from %s.%s: %v", dbName, collName, err)
+		return nil, fmt.Errorf("database decode failed: %w", err)
+	}
+
+	// Return empty slice if null
+	if results == nil {
+		results = []bson.M{}
+	}
+
+	log.Printf("DEBUG: Aggregation on %s.%s returned %d documents.", dbName, collName, len(results))
+	return results, nil
+}
+
+// EnsureTimeSeriesCollection creates a dynamic collection as a time-series collection if it does not exist yet.
+// An existing collection is left untouched (MongoDB cannot convert a regular collection).
+func (s *Store) EnsureTimeSeriesCollection(ctx context.Context, dbName, collName string, ts *models.TimeSeriesOptions) error {
+	if dbName == "" || collName == "" {
+		return fmt.Errorf("%w: Database and Collection names cannot be empty for dynamic operation", ErrConfigError)
+	}
+	if ts == nil || ts.TimeField == "" {
+		return fmt.Errorf("%w: time-series collections require a timeField", ErrConfigError)
+	}
+
+	db := s.client.Database(dbName)
+	names, err := db.ListCollectionNames(ctx, bson.M{"name": collName})
+	if err != nil {
+		return fmt.Errorf("failed to list collections: %w", err)
+	}
+	if len(names) > 0 {
+		return nil // Already exists
+	}
+
+	tsOpts := options.TimeSeries().SetTimeField(ts.TimeField)
+	if ts.MetaField != "" {
+		tsOpts.SetMetaField(ts.MetaField)
+	}
+	if ts.Granularity != "" {
+		tsOpts.SetGranularity(ts.Granularity)
+	}
+	if err := db.CreateCollection(ctx, collName, options.CreateCollection().SetTimeSeriesOptions(tsOpts)); err != nil {
+		return fmt.Errorf("failed to create time-series collection: %w", err)
+	}
+	log.Printf("INFO: Created time-series collection %s.%s (timeField: %s)", dbName, collName, ts.TimeField)
+	return nil
+}
+
+// EnsureTextIndex creates a text index over the given fields of a dynamic collection.
+// MongoDB allows only one text index per collection: the search_text index is rebuilt when the
+// search fields change, any other text index is reported as an error.
+func (s *Store) EnsureTextIndex(ctx context.Context, dbName, collName string, fields []string) error {
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return err
+	}
+
+	// A collection holds one text index and MongoDB refuses to redefine it under the same
+	// name, so one over a former set of search fields is dropped first.
+	current, err := textIndexFields(ctx, collection, "search_text")
+	if err != nil {
+		return fmt.Errorf("failed to list indexes: %w", err)
+	}
+	if current != nil {
+		if sameFieldSet(current, fields) {
+			return nil
+		}
+		if _, err := collection.Indexes().DropOne(ctx, "search_text"); err != nil {
+			return fmt.Errorf("failed to drop outdated text index: %w", err)
+		}
+		log.Printf("INFO: Dropped text index on %v of %s.%s, search fields changed", current, dbName, collName)
+	}
+
+	keys := bson.D{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: "text"})
+	}
+	model := mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetName("search_text"),
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, model); err != nil {
+		return fmt.Errorf("failed to create text index: %w", err)
+	}
+	log.Printf("INFO: Text index on %v checked/created for %s.%s", fields, dbName, collName)
+	return nil
+}
+
+// DropTextIndex removes the search_text index EnsureTextIndex created, if there is one.
+func (s *Store) DropTextIndex(ctx context.Context, dbName, collName string) error {
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return err
+	}
+	current, err := textIndexFields(ctx, collection, "search_text")
+	if err != nil {
+		return fmt.Errorf("failed to list indexes: %w", err)
+	}
+	if current == nil {
+		return nil
+	}
+	if _, err := collection.Indexes().DropOne(ctx, "search_text"); err != nil {
+		return fmt.Errorf("failed to drop text index: %w", err)
+	}
+	log.Printf("INFO: Dropped text index on %v of %s.%s, the definition has no search fields", current, dbName, collName)
+	return nil
+}
+
+// textIndexFields returns the fields the named text index covers, nil when it does not exist.
+// MongoDB stores text keys as _fts/_ftsx, the fields themselves are the index weights.
+func textIndexFields(ctx context.Context, collection *mongo.Collection, name string) ([]string, error) {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var index struct {
+			Name    string `bson:"name"`
+			Weights bson.M `bson:"weights"`
+		}
+		if err := cursor.Decode(&index); err != nil {
+			return nil, err
+		}
+		if index.Name != name {
+			continue
+		}
+		fields := make([]string, 0, len(index.Weights))
+		for field := range index.Weights {
+			fields = append(fields, field)
+		}
+		return fields, nil
+	}
+	return nil, cursor.Err()
+}
+
+func sameFieldSet(a, b []string) bool {
+	set := make(map[string]bool, len(a))
+	for _, field := range a {
+		set[field] = true
+	}
+	for _, field := range b {
+		if !set[field] {
+			return false
+		}
+		delete(set, field)
+	}
+	return len(set) == 0
+}
+
+// EnsureCursorIndex creates the {field: 1, _id: 1} index cursor pagination sorts and
+// seeks on, so every page is an index range scan however deep it is.
+func (s *Store) EnsureCursorIndex(ctx context.Context, dbName, collName, field string) error {
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return err
+	}
+
+	model := mongo.IndexModel{
+		Keys:    bson.D{{Key: field, Value: 1}, {Key: "_id", Value: 1}},
+		Options: options.Index().SetName("cursor_" + field),
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, model); err != nil {
+		return fmt.Errorf("failed to create cursor index: %w", err)
+	}
+	log.Printf("INFO: Cursor index on %s checked/created for %s.%s", field, dbName, collName)
+	return nil
+}
+
+// SearchData runs a full-text search sorted by relevance.
+// With an Atlas Search index name it uses the $search stage, otherwise the $text operator.
+// A non-empty projection limits the returned fields (the score is always included).
+func (s *Store) SearchData(ctx context.Context, dbName, collName string, fields []string, atlasIndex, query string, filter, projection bson.M) (_ []bson.M, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	if atlasIndex != "" {
+		pipeline := []bson.M{
+			{"$search": bson.M{"index": atlasIndex, "text": bson.M{"query": query, "path": fields}}},
+			{"$match": filter},
+			{"$addFields": bson.M{"_score": bson.M{"$meta": "searchScore"}}},
+		}
+		if len(projection) > 0 {
+			project := bson.M{"_score": 1}
+			for k, v := range projection {
+				project[k] = v
+			}
+			pipeline = append(pipeline, bson.M{"$project": project})
+		}
+		return s.aggregateData(ctx, dbName, collName, pipeline)
+	}
+
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return nil, err
+	}
+
+	textFilter := bson.M{"$text": bson.M{"$search": query}}
+	for k, v := range filter {
+		textFilter[k] = v
+	}
+	score := bson.M{"_score": bson.M{"$meta": "textScore"}}
+	project := bson.M{"_score": score["_score"]}
+	for k, v := range projection {
+		project[k] = v
+	}
+	opts := options.Find().SetProjection(project).SetSort(score).SetComment("Search dynamic data")
+
+	cursor, err := collection.Find(ctx, textFilter, opts)
+	if err != nil {
+		log.Printf("ERROR: Failed to execute text search on %s.%s: %v", dbName, collName, err)
+		return nil, fmt.Errorf("database search failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []bson.M
+	if err := cursor.All(ctx, &results); err != nil {
+		log.Printf("ERROR: Failed to decode search results from %s.%s: %v", dbName, collName, err)
+		return nil, fmt.Errorf("database decode failed: %w", err)
+	}
+
+	// Return empty slice if null
+	if results == nil {
+		results = []bson.M{}
+	}
+	return results, nil
+}
+
+// OpenFile finds the first GridFS file matching the filter in the given bucket and opens a download stream.
+// The caller is responsible for closing the returned stream.
+func (s *Store) OpenFile(ctx context.Context, dbName, bucketName string, filter bson.M) (_ *gridfs.DownloadStream, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	if dbName == "" || bucketName == "" {
+		return nil, fmt.Errorf("%w: Database and bucket names cannot be empty for file operation", ErrConfigError)
+	}
+
+	bucket, err := gridfs.NewBucket(s.client.Database(dbName), options.GridFSBucket().SetName(bucketName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GridFS bucket: %w", err)
+	}
+
+	cursor, err := bucket.FindContext(ctx, filter, options.GridFSFind().SetLimit(1))
+	if err != nil {
+		log.Printf("ERROR: Failed to find file in %s.%s with filter %v: %v", dbName, bucketName, filter, err)
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if !cursor.Next(ctx) {
+		if err := cursor.Err(); err != nil {
+			return nil, fmt.Errorf("database query failed: %w", err)
+		}
+		return nil, ErrNotFound
+	}
+	var file gridfs.File
+	if err := cursor.Decode(&file); err != nil {
+		return nil, fmt.Errorf("database decode failed: %w", err)
+	}
+
+	stream, err := bucket.OpenDownloadStream(file.ID)
+	if err != nil {
+		if errors.Is(err, gridfs.ErrFileNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to open download stream: %w", err)
+	}
+	log.Printf("DEBUG: Opened GridFS file '%s' (%d bytes) from %s.%s", file.Name, file.Length, dbName, bucketName)
+	return stream, nil
+}
+
+// SaveBatch writes many documents in one unordered bulk write, following the same
+// rules as SaveData: upsert by uniqueKey (and scopeKeys) when the document has a value for it, insert otherwise.
+func (s *Store) SaveBatch(ctx context.Context, dbName, collName, uniqueKey string, docs []map[string]interface{}, scopeKeys ...string) (err error) {
+	if err := s.breaker.allow(); err != nil {
+		return err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(docs))
+	for _, doc := range docs {
+		uniqueValue, exists := doc[uniqueKey]
+		if uniqueKey == "" || !exists || uniqueValue == nil || fmt.Sprintf("%v", uniqueValue) == "" {
+			writes = append(writes, mongo.NewInsertOneModel().SetDocument(doc))
+			continue
+		}
+		filter := upsertFilter(doc, uniqueKey, uniqueValue, scopeKeys)
+		updateData := make(map[string]interface{}, len(doc))
+		for k, v := range doc {
+			if _, inFilter := filter[k]; k != "_id" && !inFilter {
+				updateData[k] = v
+			}
+		}
+		if len(updateData) == 0 {
+			continue // Nothing to update except the key itself (same as SaveData)
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(bson.M{"$set": updateData}).
+			SetUpsert(true))
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+
+	opts := options.BulkWrite().SetOrdered(false).SetComment("Save data batch")
+	result, err := collection.BulkWrite(ctx, writes, opts)
+	if err != nil {
+		log.Printf("ERROR: Failed to bulk save %d documents to %s.%s: %v", len(writes), dbName, collName, err)
+		return fmt.Errorf("%w: bulk write failed: %w", ErrSaveFailed, err)
+	}
+	log.Printf("INFO: Bulk saved to %s.%s (inserted %d, upserted %d, modified %d)",
+		dbName, collName, result.InsertedCount, result.UpsertedCount, result.ModifiedCount)
+	return nil
+}
+
 // DeleteData deletes documents from a dynamic collection based on a filter
-func (s *Store) DeleteData(ctx context.Context, dbName, collName string, filter bson.M) (int64, error) {
+func (s *Store) DeleteData(ctx context.Context, dbName, collName string, filter bson.M) (_ int64, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return 0, err
+	}
+	defer func() { s.breaker.record(err) }()
+
 	collection, err := s.getDynamicCollection(dbName, collName)
 	if err != nil {
 		return 0, err
@@ -514,25 +1267,63 @@ func (s *Store) DeleteData(ctx context.Context, dbName, collName string, filter
 
 // --- Helper Functions ---
 
-// Optional: Function to create necessary indexes on startup
-// func createIndexes(ctx context.Context, apiDefCollection *mongo.Collection) {
-// 	models := []mongo.IndexModel{
-// 		{
-// 			Keys:    bson.D{{"name", 1}},
-// 			Options: options.Index().SetUnique(true).SetName("name_1"),
-// 		},
-// 		{
-// 			Keys:    bson.D{{"method", 1}, {"endpoint", 1}},
-// 			Options: options.Index().SetUnique(true).SetName("method_1_endpoint_1"),
-// 		},
-//      // Add other indexes as needed
-// 	}
-
-// 	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
-// 	_, err := apiDefCollection.Indexes().CreateMany(ctx, models, opts)
-// 	if err != nil {
-// 		log.Printf("WARN: Could not create indexes for api-definitions: %v", err)
-// 	} else {
-// 		log.Println("INFO: Indexes for api-definitions checked/created.")
-// 	}
-// }
+// EnsureIndexes makes sure the unique indexes on name and (method, endpoint) exist,
+// so duplicate definitions are rejected by MongoDB rather than by best-effort checks.
+func (s *Store) EnsureIndexes(ctx context.Context) error {
+	err := createIndexes(ctx, s.apiDefCollection)
+	s.indexesReady.Store(err == nil)
+	return err
+}
+
+// IndexesReady reports whether the last EnsureIndexes call succeeded.
+func (s *Store) IndexesReady() bool {
+	return s.indexesReady.Load()
+}
+
+// createIndexes creates the unique indexes on the api-definitions collection.
+// An existing index with the same name but different options (e.g. created without
+// unique) is dropped and recreated. Creation fails while duplicates are stored;
+// those are reported by LoadAPIs as conflicts.
+func createIndexes(ctx context.Context, apiDefCollection *mongo.Collection) error {
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("name_1"),
+		},
+		{
+			Keys:    bson.D{{Key: "method", Value: 1}, {Key: "endpoint", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("method_1_endpoint_1"),
+		},
+	}
+
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+	for _, model := range indexModels {
+		name := *model.Options.Name
+		_, err := apiDefCollection.Indexes().CreateOne(ctx, model, opts)
+		if isIndexConflict(err) {
+			log.Printf("WARN: Index '%s' on api-definitions exists with different options, recreating it", name)
+			if _, dropErr := apiDefCollection.Indexes().DropOne(ctx, name); dropErr != nil {
+				return fmt.Errorf("failed to drop conflicting index %s: %w", name, dropErr)
+			}
+			_, err = apiDefCollection.Indexes().CreateOne(ctx, model, opts)
+		}
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return fmt.Errorf("cannot create unique index %s, duplicate definitions are stored (see GET /api-generator/validate): %w", name, err)
+			}
+			return fmt.Errorf("failed to create index %s: %w", name, err)
+		}
+	}
+	log.Println("INFO: Indexes for api-definitions checked/created.")
+	return nil
+}
+
+// isIndexConflict reports whether an index creation failed because an index with the
+// same name or keys already exists with different options.
+func isIndexConflict(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Code == 85 || cmdErr.Code == 86 // IndexOptionsConflict, IndexKeySpecsConflict
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// namedCollection stores documents keyed by their name in _id, such as mapping profiles
+// or lookup tables. The typed Store methods are thin wrappers around it.
+type namedCollection[T any] struct {
+	collection string // MongoDB collection
+	kind       string // Document kind in errors and query comments (e.g. "mapping profile")
+}
+
+// save creates or replaces the document with the given name.
+func (n namedCollection[T]) save(ctx context.Context, s *Store, name string, doc *T) (err error) {
+	if err := s.breaker.allow(); err != nil {
+		return err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	opts := options.Replace().SetUpsert(true)
+	if _, err := s.db.Collection(n.collection).ReplaceOne(ctx, bson.M{"_id": name}, doc, opts); err != nil {
+		return fmt.Errorf("%w: %s save failed: %w", ErrSaveFailed, n.kind, err)
+	}
+	return nil
+}
+
+// get finds a document by name.
+func (n namedCollection[T]) get(ctx context.Context, s *Store, name string) (_ *T, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	var doc T
+	if err := s.db.Collection(n.collection).FindOne(ctx, bson.M{"_id": name}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return &doc, nil
+}
+
+// list returns all documents sorted by name.
+func (n namedCollection[T]) list(ctx context.Context, s *Store) (_ []T, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetComment("List " + n.kind + "s")
+	cursor, err := s.db.Collection(n.collection).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	docs := []T{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("database decode failed: %w", err)
+	}
+	return docs, nil
+}
+
+// delete removes a document by name.
+func (n namedCollection[T]) delete(ctx context.Context, s *Store, name string) (err error) {
+	if err := s.breaker.allow(); err != nil {
+		return err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	result, err := s.db.Collection(n.collection).DeleteOne(ctx, bson.M{"_id": name})
+	if err != nil {
+		return fmt.Errorf("database delete failed: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"api-genarator/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// otpCollection stores the hashed one-time codes of the generateOtp/verifyOtp actions.
+// A TTL index removes codes once they expire.
+const otpCollection = "otp-codes"
+
+// SaveOTP stores a code, replacing any earlier code issued for the same key.
+func (s *Store) SaveOTP(ctx context.Context, code *models.OTPCode) (err error) {
+	if err := s.breaker.allow(); err != nil {
+		return err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	s.otpIndexOnce.Do(func() {
+		model := mongo.IndexModel{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0).SetName("expiresAt_ttl"),
+		}
+		if _, err := s.db.Collection(otpCollection).Indexes().CreateOne(ctx, model); err != nil {
+			log.Printf("WARN: Failed to create TTL index on %s (expired codes are still rejected): %v", otpCollection, err)
+		}
+	})
+
+	opts := options.Replace().SetUpsert(true)
+	if _, err := s.db.Collection(otpCollection).ReplaceOne(ctx, bson.M{"_id": code.ID}, code, opts); err != nil {
+		return fmt.Errorf("%w: otp save failed: %w", ErrSaveFailed, err)
+	}
+	return nil
+}
+
+// ClaimOTPAttempt counts a guess against the code before it is compared, so parallel
+// guesses cannot all pass the attempt limit. It returns the code and whether a guess
+// was left; a locked code is returned with false.
+func (s *Store) ClaimOTPAttempt(ctx context.Context, id string) (_ *models.OTPCode, claimed bool, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, false, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	filter := bson.M{"_id": id, "$expr": bson.M{"$lt": bson.A{"$attempts", "$maxAttempts"}}}
+	update := bson.M{"$inc": bson.M{"attempts": 1}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var code models.OTPCode
+	err = s.db.Collection(otpCollection).FindOneAndUpdate(ctx, filter, update, opts).Decode(&code)
+	if err == nil {
+		return &code, true, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, false, fmt.Errorf("%w: %w", ErrUpdateFailed, err)
+	}
+	// No guess left, or no code at all
+	if err := s.db.Collection(otpCollection).FindOne(ctx, bson.M{"_id": id}).Decode(&code); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, false, ErrNotFound
+		}
+		return nil, false, fmt.Errorf("database query failed: %w", err)
+	}
+	return &code, false, nil
+}
+
+// ConsumeOTP deletes the code if it is still the one that was checked and within its
+// attempts (the claimed guess included). It reports false when a concurrent
+// verification (or a newly issued code) got there first, so a code is accepted at most
+// once.
+func (s *Store) ConsumeOTP(ctx context.Context, id, hash string) (_ bool, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return false, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	filter := bson.M{"_id": id, "hash": hash, "$expr": bson.M{"$lte": bson.A{"$attempts", "$maxAttempts"}}}
+	res, err := s.db.Collection(otpCollection).DeleteOne(ctx, filter)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrDeleteFailed, err)
+	}
+	return res.DeletedCount == 1, nil
+}
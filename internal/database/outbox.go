@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"api-genarator/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// outboxCollection holds events waiting to be relayed by the outbox dispatcher.
+const outboxCollection = "outbox"
+
+// SaveDataWithOutbox saves data and records its events in one transaction, so an event
+// exists if and only if the data was saved. On a standalone server (no transactions)
+// the writes run in sequence, data first.
+func (s *Store) SaveDataWithOutbox(ctx context.Context, dbName, collName, uniqueKey string, data map[string]interface{}, messages []models.OutboxMessage, scopeKeys ...string) (result *SaveResult, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	err = s.runInTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if result, err = s.saveData(ctx, dbName, collName, uniqueKey, data, scopeKeys...); err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+		now := time.Now().UTC()
+		docs := make([]interface{}, len(messages))
+		for i := range messages {
+			messages[i].ID = primitive.NewObjectID()
+			messages[i].Status = models.OutboxPending
+			messages[i].CreatedAt = now
+			messages[i].NextAttemptAt = now
+			docs[i] = messages[i]
+		}
+		if _, err := s.db.Collection(outboxCollection).InsertMany(ctx, docs); err != nil {
+			return fmt.Errorf("%w: outbox insert failed: %w", ErrSaveFailed, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ClaimOutboxMessage leases the next due message for delivery, or returns ErrNotFound when
+// none is due. The lease keeps other dispatchers (or instances) from sending it concurrently.
+func (s *Store) ClaimOutboxMessage(ctx context.Context, lease time.Duration) (_ *models.OutboxMessage, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	now := time.Now().UTC()
+	filter := bson.M{
+		"status":        models.OutboxPending,
+		"nextAttemptAt": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"lockedUntil": bson.M{"$exists": false}},
+			bson.M{"lockedUntil": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"lockedUntil": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).
+		SetReturnDocument(options.After).
+		SetComment("Claim outbox message")
+
+	var msg models.OutboxMessage
+	err = s.db.Collection(outboxCollection).FindOneAndUpdate(ctx, filter, update, opts).Decode(&msg)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return &msg, nil
+}
+
+// CompleteOutboxMessage marks a message as delivered
+func (s *Store) CompleteOutboxMessage(ctx context.Context, id primitive.ObjectID) error {
+	update := bson.M{
+		"$set":   bson.M{"status": models.OutboxDelivered, "deliveredAt": time.Now().UTC()},
+		"$inc":   bson.M{"attempts": 1},
+		"$unset": bson.M{"lockedUntil": ""},
+	}
+	if _, err := s.db.Collection(outboxCollection).UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		return fmt.Errorf("%w: %w", ErrUpdateFailed, err)
+	}
+	return nil
+}
+
+// FailOutboxMessage records a failed delivery; the message is retried at nextAttempt
+// unless giveUp is set, in which case it is marked failed.
+func (s *Store) FailOutboxMessage(ctx context.Context, id primitive.ObjectID, deliveryErr error, nextAttempt time.Time, giveUp bool) error {
+	set := bson.M{"lastError": deliveryErr.Error(), "nextAttemptAt": nextAttempt}
+	if giveUp {
+		set["status"] = models.OutboxFailed
+	}
+	update := bson.M{"$set": set, "$inc": bson.M{"attempts": 1}, "$unset": bson.M{"lockedUntil": ""}}
+	if _, err := s.db.Collection(outboxCollection).UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		return fmt.Errorf("%w: %w", ErrUpdateFailed, err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// QueryObserver receives the duration of every database command, with the context of
+// the operation (so request-scoped values such as the request ID are available).
+type QueryObserver func(ctx context.Context, database, collection, command string, d time.Duration, err error)
+
+var (
+	queryObserverMu sync.RWMutex
+	queryObserver   QueryObserver
+
+	// queryCollections maps in-flight driver request IDs to the collection they target;
+	// finished events do not carry the command document.
+	queryCollections sync.Map
+)
+
+// internalCommands are connection handshakes and housekeeping, not queries.
+var internalCommands = map[string]bool{
+	"hello": true, "isMaster": true, "ismaster": true, "ping": true, "buildInfo": true, "endSessions": true,
+	"saslStart": true, "saslContinue": true, "authenticate": true, "getnonce": true, "killCursors": true,
+}
+
+// SetQueryObserver installs the observer of database command timings (nil removes it).
+func SetQueryObserver(o QueryObserver) {
+	queryObserverMu.Lock()
+	queryObserver = o
+	queryObserverMu.Unlock()
+}
+
+// commandMonitor feeds the query observer from the driver's command events.
+func commandMonitor() *event.CommandMonitor {
+	finished := func(ctx context.Context, e event.CommandFinishedEvent, err error) {
+		coll, started := queryCollections.LoadAndDelete(e.RequestID)
+		if !started {
+			return
+		}
+		queryObserverMu.RLock()
+		observe := queryObserver
+		queryObserverMu.RUnlock()
+		if observe != nil {
+			observe(ctx, e.DatabaseName, coll.(string), e.CommandName, e.Duration, err)
+		}
+	}
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			if internalCommands[e.CommandName] {
+				return
+			}
+			coll := ""
+			if e.CommandName == "getMore" {
+				coll, _ = e.Command.Lookup("collection").StringValueOK()
+			} else if first, err := e.Command.IndexErr(0); err == nil {
+				coll, _ = first.Value().StringValueOK() // {"find": "orders", ...}
+			}
+			queryCollections.Store(e.RequestID, coll)
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			finished(ctx, e.CommandFinishedEvent, nil)
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			finished(ctx, e.CommandFinishedEvent, errors.New(e.Failure))
+		},
+	}
+}
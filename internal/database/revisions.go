@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"api-genarator/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// revisionCollection stores definition changes proposed under the approval workflow.
+const revisionCollection = "revisions"
+
+// ErrRevisionDecided is returned when a revision is no longer pending.
+var ErrRevisionDecided = errors.New("revision is no longer pending")
+
+// CreateRevision records a proposed change as pending, with its submission as the first audit event
+func (s *Store) CreateRevision(ctx context.Context, rev *models.Revision) (err error) {
+	if err := s.breaker.allow(); err != nil {
+		return err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	now := time.Now().UTC()
+	rev.ID = primitive.NewObjectID()
+	rev.Status = models.RevisionPending
+	rev.CreatedAt = now
+	rev.Events = []models.RevisionEvent{{Type: "submitted", Actor: rev.Author, At: now}}
+	if _, err := s.db.Collection(revisionCollection).InsertOne(ctx, rev); err != nil {
+		return fmt.Errorf("%w: revision insert failed: %w", ErrSaveFailed, err)
+	}
+	return nil
+}
+
+// ListRevisions returns revisions, newest first, optionally filtered by status and API name
+func (s *Store) ListRevisions(ctx context.Context, status, apiName string, limit int64) ([]models.Revision, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	if apiName != "" {
+		filter["apiName"] = apiName
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(limit).SetComment("List revisions")
+	cursor, err := s.db.Collection(revisionCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	revisions := []models.Revision{}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, fmt.Errorf("database decode failed: %w", err)
+	}
+	return revisions, nil
+}
+
+// GetRevision finds a revision by its hex ID
+func (s *Store) GetRevision(ctx context.Context, id string) (*models.Revision, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	var rev models.Revision
+	err = s.db.Collection(revisionCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(&rev)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return &rev, nil
+}
+
+// AddRevisionEvent appends an entry (e.g. a comment) to a revision's audit trail
+func (s *Store) AddRevisionEvent(ctx context.Context, id primitive.ObjectID, event models.RevisionEvent) error {
+	res, err := s.db.Collection(revisionCollection).UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$push": bson.M{"events": event}})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUpdateFailed, err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DecideRevision moves a pending revision to status and records the decision. Only one
+// decision wins when several approvers act at once; the others get ErrRevisionDecided.
+func (s *Store) DecideRevision(ctx context.Context, id primitive.ObjectID, status string, event models.RevisionEvent) (*models.Revision, error) {
+	update := bson.M{
+		"$set":  bson.M{"status": status, "decidedAt": event.At},
+		"$push": bson.M{"events": event},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var rev models.Revision
+	err := s.db.Collection(revisionCollection).FindOneAndUpdate(ctx, bson.M{"_id": id, "status": models.RevisionPending}, update, opts).Decode(&rev)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrRevisionDecided
+		}
+		return nil, fmt.Errorf("%w: %w", ErrUpdateFailed, err)
+	}
+	return &rev, nil
+}
+
+// ReopenRevision returns an approved revision to pending when publishing it failed
+func (s *Store) ReopenRevision(ctx context.Context, id primitive.ObjectID, event models.RevisionEvent) error {
+	update := bson.M{
+		"$set":   bson.M{"status": models.RevisionPending},
+		"$unset": bson.M{"decidedAt": ""},
+		"$push":  bson.M{"events": event},
+	}
+	if _, err := s.db.Collection(revisionCollection).UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		return fmt.Errorf("%w: %w", ErrUpdateFailed, err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"api-genarator/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// documentValidationFailure is the server error code of a write rejected by a validator.
+const documentValidationFailure = 121
+
+// IsDocumentInvalid reports whether a write failed the collection's $jsonSchema validator.
+// Retrying such a write cannot succeed until the document or the schema changes.
+func IsDocumentInvalid(err error) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(documentValidationFailure)
+}
+
+// ApplyCollectionSchema installs schema as the $jsonSchema validator of a dynamic
+// collection, creating the collection if it does not exist yet. The validator replaces
+// any previous one; documents already stored are not re-checked.
+func (s *Store) ApplyCollectionSchema(ctx context.Context, dbName, collName string, schema *models.CollectionSchema) error {
+	if dbName == "" || collName == "" {
+		return fmt.Errorf("%w: Database and Collection names cannot be empty for dynamic operation", ErrConfigError)
+	}
+	level, action := schema.ValidationLevel, schema.ValidationAction
+	if level == "" {
+		level = "strict"
+	}
+	if action == "" {
+		action = "error"
+	}
+	validator := bson.M{"$jsonSchema": schema.Schema}
+
+	db := s.client.Database(dbName)
+	names, err := db.ListCollectionNames(ctx, bson.M{"name": collName})
+	if err != nil {
+		return fmt.Errorf("failed to list collections: %w", err)
+	}
+	if len(names) == 0 {
+		opts := options.CreateCollection().SetValidator(validator).SetValidationLevel(level).SetValidationAction(action)
+		if err := db.CreateCollection(ctx, collName, opts); err != nil {
+			return fmt.Errorf("failed to create collection with validator: %w", err)
+		}
+		log.Printf("INFO: Created collection %s.%s with a $jsonSchema validator (%s, %s)", dbName, collName, level, action)
+		return nil
+	}
+
+	cmd := bson.D{
+		{Key: "collMod", Value: collName},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: level},
+		{Key: "validationAction", Value: action},
+	}
+	if err := db.RunCommand(ctx, cmd).Err(); err != nil {
+		return fmt.Errorf("failed to apply validator: %w", err)
+	}
+	log.Printf("INFO: $jsonSchema validator applied to %s.%s (%s, %s)", dbName, collName, level, action)
+	return nil
+}
+
+// RemoveCollectionSchema drops the validator of a dynamic collection.
+func (s *Store) RemoveCollectionSchema(ctx context.Context, dbName, collName string) error {
+	if dbName == "" || collName == "" {
+		return fmt.Errorf("%w: Database and Collection names cannot be empty for dynamic operation", ErrConfigError)
+	}
+	cmd := bson.D{{Key: "collMod", Value: collName}, {Key: "validator", Value: bson.M{}}}
+	if err := s.client.Database(dbName).RunCommand(ctx, cmd).Err(); err != nil {
+		return fmt.Errorf("failed to remove validator: %w", err)
+	}
+	log.Printf("INFO: Validator removed from %s.%s", dbName, collName)
+	return nil
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InsertDocuments inserts docs into a dynamic collection as they are and returns how
+// many were inserted; on a failure the documents before the failing one stay inserted.
+func (s *Store) InsertDocuments(ctx context.Context, dbName, collName string, docs []interface{}) (_ int64, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return 0, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return 0, err
+	}
+	if len(docs) == 0 {
+		return 0, nil
+	}
+	result, err := collection.InsertMany(ctx, docs, options.InsertMany().SetComment("Seed dynamic data"))
+	var inserted int64
+	if result != nil {
+		inserted = int64(len(result.InsertedIDs))
+	}
+	if err != nil {
+		return inserted, fmt.Errorf("%w: %w", ErrSaveFailed, err)
+	}
+	log.Printf("INFO: Inserted %d documents into %s.%s.", inserted, dbName, collName)
+	return inserted, nil
+}
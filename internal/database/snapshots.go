@@ -0,0 +1,218 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"api-genarator/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Snapshots are split into a summary document and one item per definition, so large
+// catalogs stay below the 16 MB document limit.
+const (
+	snapshotCollection     = "definition-snapshots"
+	snapshotItemCollection = "definition-snapshot-items"
+)
+
+// CreateSnapshot copies every stored definition, as stored, into a new snapshot.
+func (s *Store) CreateSnapshot(ctx context.Context, note, author string) (_ *models.DefinitionSnapshot, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	snapshot := &models.DefinitionSnapshot{
+		ID:        primitive.NewObjectID(),
+		Note:      note,
+		Author:    author,
+		CreatedAt: time.Now().UTC(),
+		Names:     []string{},
+	}
+	err = s.runInTransaction(ctx, func(ctx context.Context) error {
+		cursor, err := s.apiDefCollection.Find(ctx, bson.M{}, options.Find().SetComment("Snapshot API definitions"))
+		if err != nil {
+			return fmt.Errorf("database query failed: %w", err)
+		}
+		defer cursor.Close(ctx)
+
+		var items []interface{}
+		for cursor.Next(ctx) {
+			raw := append(bson.Raw(nil), cursor.Current...) // Raw keeps fields this version does not know
+			if name, ok := raw.Lookup("name").StringValueOK(); ok {
+				snapshot.Names = append(snapshot.Names, name)
+			}
+			items = append(items, bson.M{"snapshotId": snapshot.ID, "definition": raw})
+		}
+		if err := cursor.Err(); err != nil {
+			return fmt.Errorf("database decode failed: %w", err)
+		}
+		return s.saveSnapshot(ctx, snapshot, items)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// ImportSnapshot stores definitions from outside the collection (e.g. a backup bundle)
+// as a snapshot, so they can be restored like any other.
+func (s *Store) ImportSnapshot(ctx context.Context, note, author string, definitions []models.ApiDefinition) (_ *models.DefinitionSnapshot, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	snapshot := &models.DefinitionSnapshot{
+		ID:        primitive.NewObjectID(),
+		Note:      note,
+		Author:    author,
+		CreatedAt: time.Now().UTC(),
+		Names:     []string{},
+	}
+	items := make([]interface{}, 0, len(definitions))
+	for _, def := range definitions {
+		if def.ID.IsZero() {
+			def.ID = primitive.NewObjectID()
+		}
+		snapshot.Names = append(snapshot.Names, def.Name)
+		items = append(items, bson.M{"snapshotId": snapshot.ID, "definition": def})
+	}
+	err = s.runInTransaction(ctx, func(ctx context.Context) error {
+		return s.saveSnapshot(ctx, snapshot, items)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// saveSnapshot inserts the items and then the summary, which makes the snapshot visible.
+func (s *Store) saveSnapshot(ctx context.Context, snapshot *models.DefinitionSnapshot, items []interface{}) error {
+	sort.Strings(snapshot.Names)
+	snapshot.Count = len(items)
+	if len(items) > 0 {
+		if _, err := s.db.Collection(snapshotItemCollection).InsertMany(ctx, items); err != nil {
+			return fmt.Errorf("%w: snapshot items insert failed: %w", ErrSaveFailed, err)
+		}
+	}
+	if _, err := s.db.Collection(snapshotCollection).InsertOne(ctx, snapshot); err != nil {
+		return fmt.Errorf("%w: snapshot insert failed: %w", ErrSaveFailed, err)
+	}
+	return nil
+}
+
+// ListSnapshots returns the snapshots, newest first.
+func (s *Store) ListSnapshots(ctx context.Context, limit int64) ([]models.DefinitionSnapshot, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(limit).SetComment("List definition snapshots")
+	cursor, err := s.db.Collection(snapshotCollection).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	snapshots := []models.DefinitionSnapshot{}
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		return nil, fmt.Errorf("database decode failed: %w", err)
+	}
+	return snapshots, nil
+}
+
+// GetSnapshot finds a snapshot by its hex ID
+func (s *Store) GetSnapshot(ctx context.Context, id string) (*models.DefinitionSnapshot, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	var snapshot models.DefinitionSnapshot
+	err = s.db.Collection(snapshotCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(&snapshot)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// RestoreSnapshot replaces every stored definition with the snapshot's. On a replica set
+// this is one transaction; a standalone server applies the delete and insert in sequence.
+func (s *Store) RestoreSnapshot(ctx context.Context, id string) (_ *models.DefinitionSnapshot, err error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	snapshot, err := s.GetSnapshot(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := s.db.Collection(snapshotItemCollection).Find(ctx, bson.M{"snapshotId": snapshot.ID}, options.Find().SetComment("Read snapshot items"))
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	var definitions []interface{}
+	for cursor.Next(ctx) {
+		raw, ok := cursor.Current.Lookup("definition").DocumentOK()
+		if !ok {
+			cursor.Close(ctx)
+			return nil, fmt.Errorf("snapshot %s has an item without a definition", id)
+		}
+		definitions = append(definitions, append(bson.Raw(nil), raw...))
+	}
+	err = cursor.Err()
+	cursor.Close(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database decode failed: %w", err)
+	}
+	if len(definitions) != snapshot.Count {
+		return nil, fmt.Errorf("snapshot %s is incomplete: %d of %d definitions found", id, len(definitions), snapshot.Count)
+	}
+
+	if !s.supportsTransactions {
+		log.Printf("WARN: Restoring snapshot %s without a transaction (standalone server), a failure may leave a partial catalog", id)
+	}
+	err = s.runInTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.apiDefCollection.DeleteMany(ctx, bson.M{}); err != nil {
+			return fmt.Errorf("%w: %w", ErrDeleteFailed, err)
+		}
+		if len(definitions) == 0 {
+			return nil
+		}
+		if _, err := s.apiDefCollection.InsertMany(ctx, definitions); err != nil {
+			return fmt.Errorf("%w: definitions insert failed: %w", ErrSaveFailed, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// DeleteSnapshot removes a snapshot and its items.
+func (s *Store) DeleteSnapshot(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	res, err := s.db.Collection(snapshotCollection).DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDeleteFailed, err)
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	if _, err := s.db.Collection(snapshotItemCollection).DeleteMany(ctx, bson.M{"snapshotId": objectID}); err != nil {
+		return fmt.Errorf("%w: %w", ErrDeleteFailed, err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// tailAwaitTime is how long one poll of an insert stream waits for new events, so an
+// idle tail regularly gets a chance to check that its client is still connected.
+const tailAwaitTime = 5 * time.Second
+
+// ErrTailFilter is returned for filters using operators change streams cannot match.
+var ErrTailFilter = errors.New("filter operator not supported when tailing inserts")
+
+// InsertStream delivers the documents inserted into a dynamic collection that match a
+// filter, in insert order.
+type InsertStream struct {
+	stream *mongo.ChangeStream
+}
+
+// WatchInserts opens an insert stream on a dynamic collection. Open it before reading
+// the current matches, so no insert falls between the read and the stream. The filter
+// and projection use the collection's field names; $expr, $text and $where are not
+// supported by change streams. Change streams need a replica set or sharded cluster.
+func (s *Store) WatchInserts(ctx context.Context, dbName, collName string, filter, projection bson.M) (_ *InsertStream, err error) {
+	if !s.supportsTransactions {
+		return nil, fmt.Errorf("%w: change streams require a replica set or sharded cluster", ErrConfigError)
+	}
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { s.breaker.record(err) }()
+
+	collection, err := s.getDynamicCollection(dbName, collName)
+	if err != nil {
+		return nil, err
+	}
+	match, err := fullDocumentFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	match["operationType"] = "insert"
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: match}}}
+	if len(projection) > 0 {
+		project := bson.M{"_id": 1} // The resume token must be kept
+		for field, v := range projection {
+			project["fullDocument."+field] = v
+		}
+		project["fullDocument._id"] = 1
+		pipeline = append(pipeline, bson.D{{Key: "$project", Value: project}})
+	}
+
+	opts := options.ChangeStream().SetMaxAwaitTime(tailAwaitTime)
+	stream, err := collection.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open change stream: %w", err)
+	}
+	return &InsertStream{stream: stream}, nil
+}
+
+// Next waits up to the await time for the next inserted document. It returns nil
+// without an error when nothing was inserted in the meantime.
+func (is *InsertStream) Next(ctx context.Context) (bson.M, error) {
+	if !is.stream.TryNext(ctx) {
+		if err := is.stream.Err(); err != nil {
+			return nil, fmt.Errorf("change stream failed: %w", err)
+		}
+		return nil, ctx.Err()
+	}
+	var event struct {
+		FullDocument bson.M `bson:"fullDocument"`
+	}
+	if err := is.stream.Decode(&event); err != nil {
+		return nil, fmt.Errorf("failed to decode change event: %w", err)
+	}
+	return event.FullDocument, nil
+}
+
+// Close releases the server-side cursor of the stream.
+func (is *InsertStream) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = is.stream.Close(ctx)
+}
+
+// fullDocumentFilter rewrites a collection filter for change events, whose document
+// is nested under fullDocument.
+func fullDocumentFilter(filter bson.M) (bson.M, error) {
+	out := bson.M{}
+	for key, value := range filter {
+		switch key {
+		case "$and", "$or", "$nor":
+			var clauses []interface{}
+			switch v := value.(type) {
+			case []bson.M:
+				for _, clause := range v {
+					clauses = append(clauses, clause)
+				}
+			case bson.A:
+				clauses = v
+			case []interface{}:
+				clauses = v
+			default:
+				return nil, fmt.Errorf("%w: %s must be an array", ErrTailFilter, key)
+			}
+			rewritten := bson.A{}
+			for _, clause := range clauses {
+				m, ok := clause.(bson.M)
+				if !ok {
+					if mm, isMap := clause.(map[string]interface{}); isMap {
+						m, ok = bson.M(mm), true
+					}
+				}
+				if !ok {
+					return nil, fmt.Errorf("%w: %s clauses must be documents", ErrTailFilter, key)
+				}
+				r, err := fullDocumentFilter(m)
+				if err != nil {
+					return nil, err
+				}
+				rewritten = append(rewritten, r)
+			}
+			out[key] = rewritten
+		default:
+			if strings.HasPrefix(key, "$") {
+				return nil, fmt.Errorf("%w: %s", ErrTailFilter, key)
+			}
+			out["fullDocument."+key] = value
+		}
+	}
+	return out, nil
+}
@@ -10,7 +10,7 @@ import (
 // Condition defines a single condition for evaluation.
 type Condition struct {
 	Field      string      `json:"field" bson:"field"`                               // Field name in the data to check
-	Operator   string      `json:"operator" bson:"operator"`                         // Comparison operator (e.g., "eq", "gt", "contains")
+	Operator   string      `json:"operator" bson:"operator"`                         // Comparison operator (e.g., "eq", "gt", "contains", "exists", "bcryptVerify", "featureFlag")
 	Value      interface{} `json:"value" bson:"value"`                               // Value to compare against
 	Action     string      `json:"action,omitempty" bson:"action,omitempty"`         // (Optional) Legacy or specific use?
 	ReturnData interface{} `json:"returnData,omitempty" bson:"returnData,omitempty"` // (Optional) Legacy or specific use?
@@ -25,35 +25,433 @@ type ConditionalBlock struct {
 
 // ActionDefinition defines an action to perform after condition evaluation.
 type ActionDefinition struct {
-	Type            string            `json:"type" bson:"type"`                                           // Action type: "return", "continue", "conditionalBlock", "apiCall"
+	Type            string            `json:"type" bson:"type"`                                           // Action type: "return", "continue", "conditionalBlock", "apiCall", "presignUrl", "signJwt", "verifyCaptcha", "cacheGet", "cacheSet", "graphqlCall", "soapCall", "fileDrop", "ldapLookup", "payment", "push", "sendSms", "generateOtp", "verifyOtp", "callFlow", "dbUpdate", "findAndModify"
 	ReturnData      interface{}       `json:"returnData,omitempty" bson:"returnData,omitempty"`           // Data to return if type is "return"
+	ReturnError     string            `json:"returnError,omitempty" bson:"returnError,omitempty"`         // (Optional) Error catalog code the "return" action answers with; ReturnData becomes its details
 	ConditionalFlow *ConditionalBlock `json:"conditionalFlow,omitempty" bson:"conditionalFlow,omitempty"` // Next block if type is "conditionalBlock"
 	SaveData        bool              `json:"saveData" bson:"saveData"`                                   // Flag indicating if data should be saved
+	UseMappings     []string          `json:"useMappings,omitempty" bson:"useMappings,omitempty"`         // Named mapping profiles applied, in order, before Transform
 	Transform       []Transformation  `json:"transform,omitempty" bson:"transform,omitempty"`             // Data transformations to apply
-	ApiCall         *ApiCall          `json:"apiCall,omitempty" bson:"apiCall,omitempty"`                // API call configuration if type is "apiCall"
+	ApiCall         *ApiCall          `json:"apiCall,omitempty" bson:"apiCall,omitempty"`                 // API call configuration if type is "apiCall"
+	Presign         *PresignConfig    `json:"presign,omitempty" bson:"presign,omitempty"`                 // Signed URL configuration if type is "presignUrl"
+	Jwt             *JwtConfig        `json:"jwt,omitempty" bson:"jwt,omitempty"`                         // Token configuration if type is "signJwt"
+	Captcha         *CaptchaConfig    `json:"captcha,omitempty" bson:"captcha,omitempty"`                 // Bot-protection check if type is "verifyCaptcha"
+	Cache           *CacheConfig      `json:"cache,omitempty" bson:"cache,omitempty"`                     // Cache entry configuration if type is "cacheGet" or "cacheSet"
+	GraphQL         *GraphQLConfig    `json:"graphql,omitempty" bson:"graphql,omitempty"`                 // GraphQL request configuration if type is "graphqlCall"
+	SOAP            *SOAPConfig       `json:"soap,omitempty" bson:"soap,omitempty"`                       // SOAP request configuration if type is "soapCall"
+	FileDrop        *FileDropConfig   `json:"fileDrop,omitempty" bson:"fileDrop,omitempty"`               // File upload configuration if type is "fileDrop"
+	LDAP            *LDAPLookupConfig `json:"ldap,omitempty" bson:"ldap,omitempty"`                       // Directory lookup configuration if type is "ldapLookup"
+	Payment         *PaymentConfig    `json:"payment,omitempty" bson:"payment,omitempty"`                 // Payment operation if type is "payment"
+	Push            *PushConfig       `json:"push,omitempty" bson:"push,omitempty"`                       // Push notification configuration if type is "push"
+	SMS             *SMSConfig        `json:"sms,omitempty" bson:"sms,omitempty"`                         // Text message configuration if type is "sendSms"
+	OTP             *OTPConfig        `json:"otp,omitempty" bson:"otp,omitempty"`                         // One-time code configuration if type is "generateOtp" or "verifyOtp"
+	CallFlow        *CallFlowConfig   `json:"callFlow,omitempty" bson:"callFlow,omitempty"`               // Flow fragment invocation if type is "callFlow"
+	DbUpdate        *DbUpdateConfig   `json:"dbUpdate,omitempty" bson:"dbUpdate,omitempty"`               // Atomic update of the document with the request's unique key if type is "dbUpdate"
+	FindAndModify   *ModifyConfig     `json:"findAndModify,omitempty" bson:"findAndModify,omitempty"`     // Atomic find-and-update/delete if type is "findAndModify"
+	TimeoutMs       int               `json:"timeoutMs,omitempty" bson:"timeoutMs,omitempty"`             // (Optional) Time limit for this action, including nested flows
+}
+
+// PresignConfig configures a "presignUrl" action that generates a time-limited signed URL.
+type PresignConfig struct {
+	Target      string `json:"target,omitempty" bson:"target,omitempty"`           // "local" (default, this server's endpoints) or "s3"
+	Bucket      string `json:"bucket,omitempty" bson:"bucket,omitempty"`           // S3 bucket (supports {{field}} templates)
+	Key         string `json:"key" bson:"key"`                                     // S3 object key or local path (supports {{field}} templates)
+	Method      string `json:"method,omitempty" bson:"method,omitempty"`           // HTTP method the S3 URL is valid for (default GET)
+	ExpiresIn   int    `json:"expiresIn,omitempty" bson:"expiresIn,omitempty"`     // Validity in seconds (default 900)
+	ResultField string `json:"resultField,omitempty" bson:"resultField,omitempty"` // Field to store the URL in (default "url")
+}
+
+// JwtConfig configures a "signJwt" action that mints a token from the data state.
+type JwtConfig struct {
+	Claims      map[string]interface{} `json:"claims,omitempty" bson:"claims,omitempty"`           // Claims template ($variables are substituted)
+	TTL         int                    `json:"ttl,omitempty" bson:"ttl,omitempty"`                 // Lifetime in seconds (default 3600)
+	Key         string                 `json:"key,omitempty" bson:"key,omitempty"`                 // Name of the server-configured signing key (default "default")
+	Algorithm   string                 `json:"algorithm,omitempty" bson:"algorithm,omitempty"`     // HS256 (default), HS384 or HS512
+	ResultField string                 `json:"resultField,omitempty" bson:"resultField,omitempty"` // Field to store the token in (default "token")
+}
+
+// CaptchaConfig configures a "verifyCaptcha" action. On success the action's
+// ConditionalFlow/ReturnData continue as usual; on failure OnFailure runs instead.
+type CaptchaConfig struct {
+	Provider       string            `json:"provider" bson:"provider"`                                 // "recaptcha", "hcaptcha" or "turnstile"
+	TokenField     string            `json:"tokenField,omitempty" bson:"tokenField,omitempty"`         // Field holding the client token (default per provider)
+	RemoteIPField  string            `json:"remoteIpField,omitempty" bson:"remoteIpField,omitempty"`   // (Optional) Field holding the client IP
+	MinScore       float64           `json:"minScore,omitempty" bson:"minScore,omitempty"`             // (Optional) Minimum reCAPTCHA v3 score
+	ExpectedAction string            `json:"expectedAction,omitempty" bson:"expectedAction,omitempty"` // (Optional) Expected reCAPTCHA v3 action name
+	OnFailure      *ActionDefinition `json:"onFailure,omitempty" bson:"onFailure,omitempty"`           // (Optional) Action when verification fails (default 400 error)
+}
+
+// CacheConfig configures the "cacheGet" and "cacheSet" actions.
+// On a cache miss, cacheGet leaves ResultField unset (check it with the "exists" operator).
+type CacheConfig struct {
+	Key         string      `json:"key" bson:"key"`                                     // Cache key (supports {{field}} templates)
+	TTL         int         `json:"ttl,omitempty" bson:"ttl,omitempty"`                 // Lifetime in seconds for cacheSet (default 300)
+	Value       interface{} `json:"value,omitempty" bson:"value,omitempty"`             // Value to store for cacheSet ($variables are substituted)
+	ResultField string      `json:"resultField,omitempty" bson:"resultField,omitempty"` // Field to store a cacheGet hit in (default "cached")
+}
+
+// GraphQLConfig configures a "graphqlCall" action that posts a query or mutation to a
+// GraphQL endpoint and stores the response data in the data state.
+type GraphQLConfig struct {
+	Endpoint      string                 `json:"endpoint" bson:"endpoint"`                               // GraphQL endpoint URL (supports {{field}} templates)
+	Query         string                 `json:"query" bson:"query"`                                     // Query or mutation document (supports {{field}} templates; prefer variables)
+	OperationName string                 `json:"operationName,omitempty" bson:"operationName,omitempty"` // (Optional) Operation to run when the document has several
+	Variables     map[string]interface{} `json:"variables,omitempty" bson:"variables,omitempty"`         // (Optional) Variables ($variables are substituted)
+	Headers       map[string]string      `json:"headers,omitempty" bson:"headers,omitempty"`             // (Optional) Extra request headers (supports {{field}} templates)
+	Extract       string                 `json:"extract,omitempty" bson:"extract,omitempty"`             // (Optional) Path inside "data" to keep, e.g. "user.orders"
+	ResultField   string                 `json:"resultField,omitempty" bson:"resultField,omitempty"`     // Field to store the result in (default "graphql")
+}
+
+// SOAPConfig configures a "soapCall" action that posts an XML envelope and stores the
+// content of the response Body (converted to maps) in the data state.
+type SOAPConfig struct {
+	Endpoint    string            `json:"endpoint" bson:"endpoint"`                           // Service URL (supports {{field}} templates)
+	Action      string            `json:"action,omitempty" bson:"action,omitempty"`           // SOAPAction (1.1 header, or the 1.2 content type's action parameter)
+	Version     string            `json:"version,omitempty" bson:"version,omitempty"`         // "1.1" (default) or "1.2"
+	Envelope    string            `json:"envelope" bson:"envelope"`                           // Envelope template; {{field}} values are XML-escaped
+	Headers     map[string]string `json:"headers,omitempty" bson:"headers,omitempty"`         // (Optional) Extra request headers (supports {{field}} templates)
+	Extract     string            `json:"extract,omitempty" bson:"extract,omitempty"`         // (Optional) Path inside the Body, e.g. "GetOrderResponse.Order"
+	ResultField string            `json:"resultField,omitempty" bson:"resultField,omitempty"` // Field to store the result in (default "soap")
+}
+
+// FileDropConfig configures a "fileDrop" action that renders data from the state as a
+// file and writes it to a server-configured SFTP target (nightly feeds and the like).
+type FileDropConfig struct {
+	Target      string   `json:"target" bson:"target"`                               // Name of the SFTP target in the server settings (SFTP_TARGETS)
+	Path        string   `json:"path" bson:"path"`                                   // File path under the target's directory (supports {{field}} templates)
+	Format      string   `json:"format,omitempty" bson:"format,omitempty"`           // "json" (default), "csv" or "xml"
+	Source      string   `json:"source,omitempty" bson:"source,omitempty"`           // (Optional) Field holding the data to write (default: the whole state)
+	Fields      []string `json:"fields,omitempty" bson:"fields,omitempty"`           // (Optional) CSV columns (default: all keys, sorted)
+	Root        string   `json:"root,omitempty" bson:"root,omitempty"`               // (Optional) XML root element (default "records")
+	ResultField string   `json:"resultField,omitempty" bson:"resultField,omitempty"` // Field to store {"target", "path", "bytes"} in (default "file")
+}
+
+// LDAPLookupConfig configures an "ldapLookup" action that searches an LDAP / Active
+// Directory server and merges attributes of the first matching entry into the state.
+type LDAPLookupConfig struct {
+	Directory   string            `json:"directory,omitempty" bson:"directory,omitempty"`     // Name of the directory in the server settings (LDAP_DIRECTORIES, default "default")
+	BaseDN      string            `json:"baseDn,omitempty" bson:"baseDn,omitempty"`           // (Optional) Search base overriding the directory's (supports {{field}} templates)
+	Scope       string            `json:"scope,omitempty" bson:"scope,omitempty"`             // "sub" (default), "one" or "base"
+	Filter      string            `json:"filter" bson:"filter"`                               // Search filter, e.g. "(&(objectClass=user)(mail={{email}}))"; template values are escaped
+	Attributes  map[string]string `json:"attributes" bson:"attributes"`                       // LDAP attribute -> state field ("dn" maps the entry's DN)
+	MultiValued []string          `json:"multiValued,omitempty" bson:"multiValued,omitempty"` // (Optional) Attributes always stored as arrays (e.g. "memberOf")
+	Required    bool              `json:"required,omitempty" bson:"required,omitempty"`       // Fail the action when no entry matches (default: leave the fields unset)
+}
+
+// PaymentConfig configures a "payment" action against a Stripe-compatible provider.
+// The provider's object (PaymentIntent, Refund or webhook Event) is stored in
+// ResultField; a card decline or an invalid webhook signature runs OnFailure instead.
+type PaymentConfig struct {
+	Provider       string            `json:"provider,omitempty" bson:"provider,omitempty"`             // Name of the provider in the server settings (PAYMENT_PROVIDERS, default "default")
+	Operation      string            `json:"operation" bson:"operation"`                               // "createIntent", "charge" (create and confirm), "refund" or "verifyWebhook"
+	Amount         interface{}       `json:"amount,omitempty" bson:"amount,omitempty"`                 // Amount in the minor unit (e.g. cents), number or "$field"; optional for partial refunds
+	Currency       string            `json:"currency,omitempty" bson:"currency,omitempty"`             // ISO currency code, e.g. "thb" (supports {{field}} templates)
+	Customer       string            `json:"customer,omitempty" bson:"customer,omitempty"`             // (Optional) Provider customer ID (supports {{field}} templates)
+	PaymentMethod  string            `json:"paymentMethod,omitempty" bson:"paymentMethod,omitempty"`   // Payment method ID, required for "charge" (supports {{field}} templates)
+	PaymentIntent  string            `json:"paymentIntent,omitempty" bson:"paymentIntent,omitempty"`   // PaymentIntent to refund (supports {{field}} templates)
+	Description    string            `json:"description,omitempty" bson:"description,omitempty"`       // (Optional) Description shown on the payment (supports {{field}} templates)
+	Reason         string            `json:"reason,omitempty" bson:"reason,omitempty"`                 // (Optional) Refund reason: "duplicate", "fraudulent" or "requested_by_customer"
+	Metadata       map[string]string `json:"metadata,omitempty" bson:"metadata,omitempty"`             // (Optional) Metadata attached to the object (values support {{field}} templates)
+	IdempotencyKey string            `json:"idempotencyKey,omitempty" bson:"idempotencyKey,omitempty"` // (Optional) Idempotency key so retries charge once, e.g. "order-{{orderId}}"
+	ResultField    string            `json:"resultField,omitempty" bson:"resultField,omitempty"`       // Field to store the result in (default "payment")
+	OnFailure      *ActionDefinition `json:"onFailure,omitempty" bson:"onFailure,omitempty"`           // (Optional) Action on a decline or invalid signature (default 402 / 400 error)
+}
+
+// PushConfig configures a "push" action that sends a notification to device tokens taken
+// from the state and/or a collection. The per-token outcome is stored in ResultField.
+type PushConfig struct {
+	Provider    string            `json:"provider" bson:"provider"`                           // "fcm" or "apns"
+	Tokens      string            `json:"tokens,omitempty" bson:"tokens,omitempty"`           // "$field" holding a device token or an array of tokens
+	Lookup      *PushTokenLookup  `json:"lookup,omitempty" bson:"lookup,omitempty"`           // (Optional) Query for tokens stored in a collection
+	Title       string            `json:"title,omitempty" bson:"title,omitempty"`             // Notification title (supports {{field}} templates)
+	Body        string            `json:"body,omitempty" bson:"body,omitempty"`               // Notification text (supports {{field}} templates)
+	Data        map[string]string `json:"data,omitempty" bson:"data,omitempty"`               // (Optional) Custom key/value payload (values support {{field}} templates)
+	Sound       string            `json:"sound,omitempty" bson:"sound,omitempty"`             // (Optional) Sound name, e.g. "default"
+	Badge       int               `json:"badge,omitempty" bson:"badge,omitempty"`             // (Optional) iOS badge count
+	ResultField string            `json:"resultField,omitempty" bson:"resultField,omitempty"` // Field to store {"sent", "failed", "invalidTokens", "errors"} in (default "push")
+}
+
+// PushTokenLookup selects device tokens from a collection (distinct values of Field).
+type PushTokenLookup struct {
+	Database   string                 `json:"database,omitempty" bson:"database,omitempty"` // (Optional) Database (default: the API's database)
+	Collection string                 `json:"collection" bson:"collection"`                 // Collection holding the device registrations
+	Filter     map[string]interface{} `json:"filter,omitempty" bson:"filter,omitempty"`     // Query filter ($variables are substituted), e.g. {"userId": "$userId"}
+	Field      string                 `json:"field" bson:"field"`                           // Field holding the token
+}
+
+// SMSConfig configures a "sendSms" action. The gateway's message ID and status are
+// stored in ResultField; a recipient over the rate limit runs OnRateLimit instead.
+type SMSConfig struct {
+	Provider       string            `json:"provider,omitempty" bson:"provider,omitempty"`             // Name of the provider in the server settings (SMS_PROVIDERS, default "default")
+	To             string            `json:"to" bson:"to"`                                             // Recipient in E.164 format (supports {{field}} templates)
+	Body           string            `json:"body" bson:"body"`                                         // Message text (supports {{field}} templates)
+	From           string            `json:"from,omitempty" bson:"from,omitempty"`                     // (Optional) Sender overriding the provider's (supports {{field}} templates)
+	StatusCallback string            `json:"statusCallback,omitempty" bson:"statusCallback,omitempty"` // (Optional) URL the gateway posts delivery updates to
+	RateLimit      *SMSRateLimit     `json:"rateLimit,omitempty" bson:"rateLimit,omitempty"`           // (Optional) Messages allowed per recipient
+	ResultField    string            `json:"resultField,omitempty" bson:"resultField,omitempty"`       // Field to store {"id", "status", "to", "provider"} in (default "sms")
+	OnRateLimit    *ActionDefinition `json:"onRateLimit,omitempty" bson:"onRateLimit,omitempty"`       // (Optional) Action when the recipient's limit is reached (default 429 error)
+}
+
+// SMSRateLimit allows at most Max messages to one recipient per fixed window.
+type SMSRateLimit struct {
+	Max           int `json:"max" bson:"max"`                                         // Messages per window
+	WindowSeconds int `json:"windowSeconds,omitempty" bson:"windowSeconds,omitempty"` // Window length (default 3600)
+}
+
+// OTPConfig configures the "generateOtp" and "verifyOtp" actions. Both must use the same
+// Purpose and Subject. generateOtp stores {"code", "expiresAt"} in ResultField, so a
+// following sendSms can use {{otp.code}}; make sure the response does not return it.
+// verifyOtp stores {"status"} ("verified", "invalid", "expired" or "locked") and runs
+// OnFailure unless the code was verified.
+type OTPConfig struct {
+	Purpose     string            `json:"purpose" bson:"purpose"`                             // Separates codes of different flows, e.g. "login"
+	Subject     string            `json:"subject" bson:"subject"`                             // Who the code is for, e.g. "{{phone}}"
+	Length      int               `json:"length,omitempty" bson:"length,omitempty"`           // Code length (default 6)
+	Alphabet    string            `json:"alphabet,omitempty" bson:"alphabet,omitempty"`       // "numeric" (default) or "alphanumeric"
+	TTLSeconds  int               `json:"ttlSeconds,omitempty" bson:"ttlSeconds,omitempty"`   // Validity (default 300)
+	MaxAttempts int               `json:"maxAttempts,omitempty" bson:"maxAttempts,omitempty"` // Wrong guesses allowed (default 5)
+	CodeField   string            `json:"codeField,omitempty" bson:"codeField,omitempty"`     // verifyOtp: field holding the submitted code (default "code")
+	ResultField string            `json:"resultField,omitempty" bson:"resultField,omitempty"` // Field to store the result in (default "otp")
+	OnFailure   *ActionDefinition `json:"onFailure,omitempty" bson:"onFailure,omitempty"`     // (Optional) verifyOtp: action when the code is not accepted (default 400 error)
 }
 
 // Transformation defines a data transformation operation.
 type Transformation struct {
-	Operation string      `json:"operation" bson:"operation"`                 // Operation: "set", "remove", "append", "calculate"
-	Field     string      `json:"field" bson:"field"`                         // Target field for the operation
-	Value     interface{} `json:"value,omitempty" bson:"value,omitempty"`     // Value for "set", "append"
-	Formula   string      `json:"formula,omitempty" bson:"formula,omitempty"` // Formula for "calculate" (e.g., "add:field1,field2")
+	Operation string      `json:"operation" bson:"operation"`                     // Operation: "set", "remove", "append", "calculate", "bcryptHash", "geoip", "userAgent", "round", "floor", "ceil", "toFixed", "formatCurrency", "extract", "flatten", "unflatten", "rename", "age"
+	Field     string      `json:"field" bson:"field"`                             // Target field for the operation
+	Value     interface{} `json:"value,omitempty" bson:"value,omitempty"`         // Value for "set", "append"; date for "age" (default: Field itself); source IP for "geoip" / string for "userAgent" (default: from the request); destination for "flatten", "unflatten", "rename"; source for "bcryptHash" (e.g. "$password", removed once hashed)
+	Formula   string      `json:"formula,omitempty" bson:"formula,omitempty"`     // Formula for "calculate" (e.g., "add:field1,field2")
+	Path      string      `json:"path,omitempty" bson:"path,omitempty"`           // JSONPath for "extract" (e.g., "$.response.items[*].id", or jq-style ".items[].id")
+	Precision *int        `json:"precision,omitempty" bson:"precision,omitempty"` // Decimal places for "round", "floor", "ceil", "toFixed" (default 0) and "formatCurrency" (default: the currency's)
+	Currency  string      `json:"currency,omitempty" bson:"currency,omitempty"`   // ISO 4217 code for "formatCurrency" (e.g., "THB" or "{{currency}}")
+	Locale    string      `json:"locale,omitempty" bson:"locale,omitempty"`       // Locale for "formatCurrency" (e.g., "th-TH" or "{{locale}}"; default "en-US")
 }
 
 // ApiDefinition holds the metadata and logic for a dynamic API endpoint.
 type ApiDefinition struct {
-	ID              primitive.ObjectID     `json:"id,omitempty" bson:"_id,omitempty"`
-	Name            string                 `json:"name" bson:"name"`                                           // Unique name for the API definition
-	Endpoint        string                 `json:"endpoint" bson:"endpoint"`                                   // HTTP path (e.g., "/users/:id")
-	Method          string                 `json:"method" bson:"method"`                                       // HTTP method (e.g., "GET", "POST")
-	Database        string                 `json:"database" bson:"database"`                                   // Target database name for data operations
-	Collection      string                 `json:"collection" bson:"collection"`                               // Target collection name for data operations
-	Parameters      []Parameter            `json:"parameters,omitempty" bson:"parameters,omitempty"`           // Definition of expected parameters
-	ResponseSchema  map[string]interface{} `json:"responseSchema,omitempty" bson:"responseSchema,omitempty"`   // (Optional) Schema for validating response
-	ConditionalFlow *ConditionalBlock      `json:"conditionalFlow,omitempty" bson:"conditionalFlow,omitempty"` // Root conditional logic block
-	CreatedAt       time.Time              `json:"createdAt" bson:"createdAt"`                                 // Timestamp of creation
-	UniqueKey       string                 `json:"uniqueKey,omitempty" bson:"uniqueKey,omitempty"`             // Field name used as the unique key for Upsert operations
+	ID               primitive.ObjectID     `json:"id,omitempty" bson:"_id,omitempty"`
+	Name             string                 `json:"name" bson:"name"`                                             // Unique name for the API definition
+	Endpoint         string                 `json:"endpoint" bson:"endpoint"`                                     // HTTP path (e.g., "/users/:id")
+	Method           string                 `json:"method" bson:"method"`                                         // HTTP method (e.g., "GET", "POST")
+	Database         string                 `json:"database" bson:"database"`                                     // Target database name for data operations
+	Collection       string                 `json:"collection" bson:"collection"`                                 // Target collection name for data operations
+	Parameters       []Parameter            `json:"parameters,omitempty" bson:"parameters,omitempty"`             // Definition of expected parameters
+	ResponseSchema   map[string]interface{} `json:"responseSchema,omitempty" bson:"responseSchema,omitempty"`     // (Optional) Schema for validating response
+	ConditionalFlow  *ConditionalBlock      `json:"conditionalFlow,omitempty" bson:"conditionalFlow,omitempty"`   // Root conditional logic block
+	CreatedAt        time.Time              `json:"createdAt" bson:"createdAt"`                                   // Timestamp of creation
+	UniqueKey        string                 `json:"uniqueKey,omitempty" bson:"uniqueKey,omitempty"`               // Field name used as the unique key for Upsert operations
+	Validations      []ValidationRule       `json:"validations,omitempty" bson:"validations,omitempty"`           // Cross-field validation rules evaluated against the request data
+	NamespacedParams bool                   `json:"namespacedParams,omitempty" bson:"namespacedParams,omitempty"` // Also expose request data as $path.*, $query.*, $body.*
+	QueryMode        string                 `json:"queryMode,omitempty" bson:"queryMode,omitempty"`               // Default GET mode: "" (find), "count", or "distinct"
+	DistinctField    string                 `json:"distinctField,omitempty" bson:"distinctField,omitempty"`       // Field used when QueryMode is "distinct"
+	SearchFields     []string               `json:"searchFields,omitempty" bson:"searchFields,omitempty"`         // Fields covered by full-text search (?q=)
+	SearchIndex      string                 `json:"searchIndex,omitempty" bson:"searchIndex,omitempty"`           // (Optional) Atlas Search index name; uses $text when empty
+	Computed         []Transformation       `json:"computed,omitempty" bson:"computed,omitempty"`                 // (Optional) Virtual fields added to each document a GET returns, never stored (e.g. "calculate" total, "age")
+	CursorField      string                 `json:"cursorField,omitempty" bson:"cursorField,omitempty"`           // (Optional) Sort key of cursor pagination (?after=, ?limit=); "_id" when empty, ties broken by _id
+	TimeSeries       *TimeSeriesOptions     `json:"timeSeries,omitempty" bson:"timeSeries,omitempty"`             // (Optional) Target collection is a MongoDB time-series collection
+	CollectionSchema *CollectionSchema      `json:"collectionSchema,omitempty" bson:"collectionSchema,omitempty"` // (Optional) JSON Schema enforced by MongoDB on the target collection
+	Delete           *DeleteOptions         `json:"delete,omitempty" bson:"delete,omitempty"`                     // (Optional) What a default DELETE removes and how; deletes at most one document when empty
+	Download         *DownloadOptions       `json:"download,omitempty" bson:"download,omitempty"`                 // (Optional) Serve a GridFS file; Collection is the bucket name
+	AccessControl    *AccessControl         `json:"accessControl,omitempty" bson:"accessControl,omitempty"`       // (Optional) Client IP allow/deny lists
+	Maintenance      *MaintenanceConfig     `json:"maintenance,omitempty" bson:"maintenance,omitempty"`           // (Optional) Per-API maintenance mode
+	CoalesceGets     bool                   `json:"coalesceGets,omitempty" bson:"coalesceGets,omitempty"`         // Share one execution between concurrent identical GET requests
+	Tail             bool                   `json:"tail,omitempty" bson:"tail,omitempty"`                         // Default GET streams the current matches, then matching inserts, as NDJSON (needs a replica set)
+	Mock             bool                   `json:"mock,omitempty" bson:"mock,omitempty"`                         // Answer with data generated from ResponseSchema (x-faker annotations) without touching the database
+	Sandbox          *SandboxOptions        `json:"sandbox,omitempty" bson:"sandbox,omitempty"`                   // (Optional) Parallel data for requests with X-Environment: sandbox
+	Environments     map[string]EnvOverride `json:"environments,omitempty" bson:"environments,omitempty"`         // (Optional) Overrides per server environment (APP_ENV), e.g. {"staging": {"database": "shop_staging"}}
+	Events           []EventConfig          `json:"events,omitempty" bson:"events,omitempty"`                     // (Optional) Webhook events published (via the outbox) when data is saved
+	Ingest           *IngestOptions         `json:"ingest,omitempty" bson:"ingest,omitempty"`                     // (Optional) Buffer saves and write them in bulk (write-behind)
+	FlowBudgetMs     int                    `json:"flowBudgetMs,omitempty" bson:"flowBudgetMs,omitempty"`         // (Optional) Total time the conditional flow may take
+	Async            bool                   `json:"async,omitempty" bson:"async,omitempty"`                       // Run the flow as a background job and respond 202 with a job ID
+	Concurrency      *ConcurrencyLimit      `json:"concurrency,omitempty" bson:"concurrency,omitempty"`           // (Optional) Cap on simultaneous executions of this definition
+	DataScope        map[string]interface{} `json:"dataScope,omitempty" bson:"dataScope,omitempty"`               // (Optional) Row-level filter, e.g. {"ownerId": "$auth.sub"}, merged into queries and stamped on saves
+	FieldPermissions []FieldPermission      `json:"fieldPermissions,omitempty" bson:"fieldPermissions,omitempty"` // (Optional) Readable/writable fields per role or scope
+	Owner            string                 `json:"owner,omitempty" bson:"owner,omitempty"`                       // Subject of the operator who created the definition
+	Team             string                 `json:"team,omitempty" bson:"team,omitempty"`                         // (Optional) IdP group whose members may also change the definition
+	Summary          string                 `json:"summary,omitempty" bson:"summary,omitempty"`                   // (Optional) One-line description shown in the catalog and OpenAPI export
+	Description      string                 `json:"description,omitempty" bson:"description,omitempty"`           // (Optional) Longer description (Markdown)
+	Examples         []ApiExample           `json:"examples,omitempty" bson:"examples,omitempty"`                 // (Optional) Example requests and responses
+	Contact          *ContactInfo           `json:"contact,omitempty" bson:"contact,omitempty"`                   // (Optional) Who to ask about this API
+	Deprecated       *Deprecation           `json:"deprecated,omitempty" bson:"deprecated,omitempty"`             // (Optional) Retirement notice sent as Deprecation/Sunset/Link headers
+	Format           string                 `json:"format,omitempty" bson:"format,omitempty"`                     // Response format: "" (plain JSON) or "jsonapi"
+	JSONAPI          *JSONAPIOptions        `json:"jsonApi,omitempty" bson:"jsonApi,omitempty"`                   // (Optional) Resource mapping when Format is "jsonapi"
+	Protobuf         *ProtobufSchema        `json:"protobuf,omitempty" bson:"protobuf,omitempty"`                 // (Optional) Response message for clients accepting application/x-protobuf
+	Webhook          *WebhookVerification   `json:"webhook,omitempty" bson:"webhook,omitempty"`                   // (Optional) Signature check for inbound webhook calls, before the flow runs
+}
+
+// Deprecation announces that a definition is being retired. Its responses carry the
+// Deprecation, Sunset and Link (rel="successor-version") headers, and the OpenAPI export
+// marks the operation deprecated.
+type Deprecation struct {
+	Since      string `json:"since" bson:"since"`                               // Date of the deprecation (YYYY-MM-DD or RFC 3339)
+	SunsetDate string `json:"sunsetDate,omitempty" bson:"sunsetDate,omitempty"` // (Optional) Date after which the API may stop responding
+	Successor  string `json:"successor,omitempty" bson:"successor,omitempty"`   // (Optional) Path or URL of the replacement
+}
+
+// EnvOverride replaces settings of a definition on servers of one environment
+// (APP_ENV), so the same definition can be exported and imported unchanged between
+// dev, staging and production. Empty fields keep the definition's value.
+type EnvOverride struct {
+	Database     string            `json:"database,omitempty" bson:"database,omitempty"`         // Target database
+	Collection   string            `json:"collection,omitempty" bson:"collection,omitempty"`     // Target collection
+	FlowBudgetMs int               `json:"flowBudgetMs,omitempty" bson:"flowBudgetMs,omitempty"` // Time budget of the flow
+	Concurrency  *ConcurrencyLimit `json:"concurrency,omitempty" bson:"concurrency,omitempty"`   // Execution caps
+	BaseURLs     map[string]string `json:"baseUrls,omitempty" bson:"baseUrls,omitempty"`         // Endpoint prefixes of graphqlCall and soapCall actions to replace, e.g. {"https://api.example.com": "https://staging.example.com"}
+}
+
+// SandboxOptions names where a definition's sandbox requests read and write. Empty
+// names default to the definition's database and its collection with a "_sandbox" suffix.
+type SandboxOptions struct {
+	Database   string `json:"database,omitempty" bson:"database,omitempty"`     // (Optional) Sandbox database
+	Collection string `json:"collection,omitempty" bson:"collection,omitempty"` // (Optional) Sandbox collection
+}
+
+// Webhook signature schemes.
+const (
+	WebhookHMAC   = "hmac"
+	WebhookGitHub = "github"
+	WebhookStripe = "stripe"
+)
+
+// WebhookVerification checks the signature a webhook sender computed over the raw body.
+type WebhookVerification struct {
+	Scheme    string `json:"scheme,omitempty" bson:"scheme,omitempty"`       // "hmac" (default), "github" (X-Hub-Signature-256) or "stripe" (Stripe-Signature)
+	Secret    string `json:"secret" bson:"secret"`                           // Name of the shared secret in WEBHOOK_SECRETS (never the secret itself)
+	Header    string `json:"header,omitempty" bson:"header,omitempty"`       // hmac: header carrying the signature (default "X-Signature")
+	Algorithm string `json:"algorithm,omitempty" bson:"algorithm,omitempty"` // hmac: "sha256" (default), "sha1" or "sha512"
+	Encoding  string `json:"encoding,omitempty" bson:"encoding,omitempty"`   // hmac: "hex" (default) or "base64"
+	Prefix    string `json:"prefix,omitempty" bson:"prefix,omitempty"`       // hmac: text before the signature, e.g. "sha256="
+
+	TimestampHeader  string `json:"timestampHeader,omitempty" bson:"timestampHeader,omitempty"`   // hmac: header with the send time (unix seconds); the signed content becomes "<timestamp>.<body>"
+	ToleranceSeconds int    `json:"toleranceSeconds,omitempty" bson:"toleranceSeconds,omitempty"` // Allowed clock skew of the signed timestamp (default 300)
+	RejectReplays    bool   `json:"rejectReplays,omitempty" bson:"rejectReplays,omitempty"`       // Remember accepted calls and reject repeats within twice the tolerance (stripe, or hmac with timestampHeader)
+	NonceHeader      string `json:"nonceHeader,omitempty" bson:"nonceHeader,omitempty"`           // Header with a unique delivery ID (e.g. X-Webhook-Id), remembered besides the signature
+}
+
+// ProtobufSchema describes the response body as a protobuf message. Array responses are
+// sent as a "<Message>List" wrapper with the items in repeated field 1.
+type ProtobufSchema struct {
+	Message string       `json:"message" bson:"message"` // Message name
+	Fields  []ProtoField `json:"fields" bson:"fields"`
+}
+
+// ProtoField maps a response field to a protobuf field.
+type ProtoField struct {
+	Name     string       `json:"name" bson:"name"`                             // Response field (and proto field) name
+	Number   int          `json:"number" bson:"number"`                         // Field number
+	Type     string       `json:"type" bson:"type"`                             // double, float, int32, int64, uint32, uint64, sint32, sint64, bool, string, bytes (base64) or message
+	Repeated bool         `json:"repeated,omitempty" bson:"repeated,omitempty"` // Field holds an array
+	Message  string       `json:"message,omitempty" bson:"message,omitempty"`   // Nested message name when Type is "message"
+	Fields   []ProtoField `json:"fields,omitempty" bson:"fields,omitempty"`     // Nested message fields when Type is "message"
+}
+
+// Response formats of a definition.
+const (
+	FormatJSON    = ""
+	FormatJSONAPI = "jsonapi"
+)
+
+// JSONAPIOptions maps stored documents to JSON:API resource objects.
+type JSONAPIOptions struct {
+	Type          string            `json:"type,omitempty" bson:"type,omitempty"`                   // Resource type (default: the collection name)
+	IDField       string            `json:"idField,omitempty" bson:"idField,omitempty"`             // Field holding the resource ID (default "_id")
+	Relationships map[string]string `json:"relationships,omitempty" bson:"relationships,omitempty"` // Field holding related IDs -> related resource type
+}
+
+// ApiExample is a documented request/response pair for a definition.
+type ApiExample struct {
+	Name     string                 `json:"name" bson:"name"`                             // Short identifier (e.g. "success", "notFound")
+	Summary  string                 `json:"summary,omitempty" bson:"summary,omitempty"`   // (Optional) What the example shows
+	Request  map[string]interface{} `json:"request,omitempty" bson:"request,omitempty"`   // (Optional) Request parameters (path, query and body fields)
+	Status   int                    `json:"status,omitempty" bson:"status,omitempty"`     // Response status (default 200)
+	Response interface{}            `json:"response,omitempty" bson:"response,omitempty"` // (Optional) Response body
+}
+
+// ContactInfo names the people responsible for a definition.
+type ContactInfo struct {
+	Name  string `json:"name,omitempty" bson:"name,omitempty"`
+	Email string `json:"email,omitempty" bson:"email,omitempty"`
+	URL   string `json:"url,omitempty" bson:"url,omitempty"`
+}
+
+// TimeSeriesOptions holds the creation options for a time-series target collection.
+type TimeSeriesOptions struct {
+	TimeField   string `json:"timeField" bson:"timeField"`                         // Field holding the measurement timestamp
+	MetaField   string `json:"metaField,omitempty" bson:"metaField,omitempty"`     // (Optional) Field identifying the series (e.g. sensor ID)
+	Granularity string `json:"granularity,omitempty" bson:"granularity,omitempty"` // (Optional) "seconds", "minutes" or "hours"
+}
+
+// CollectionSchema is applied to the target collection as a MongoDB $jsonSchema validator,
+// so writes that bypass the API are checked too. Declare it on one definition per collection.
+type CollectionSchema struct {
+	Schema           map[string]interface{} `json:"schema" bson:"schema"`                                         // $jsonSchema document, e.g. {"bsonType": "object", "required": ["sku"]}
+	ValidationLevel  string                 `json:"validationLevel,omitempty" bson:"validationLevel,omitempty"`   // "strict" (default), "moderate" (existing invalid documents may still be updated) or "off"
+	ValidationAction string                 `json:"validationAction,omitempty" bson:"validationAction,omitempty"` // "error" (default, reject) or "warn" (only log on the server)
+}
+
+// Match modes of a default DELETE.
+const (
+	DeleteMatchOne  = "one"  // At most one document matching the request (default)
+	DeleteMatchKey  = "key"  // The document whose UniqueKey equals the request's value; other parameters are ignored
+	DeleteMatchMany = "many" // Every document matching the request parameters
+)
+
+// DeleteOptions configures the default DELETE of a definition.
+type DeleteOptions struct {
+	Match             string `json:"match,omitempty" bson:"match,omitempty"`                         // "one" (default), "key" or "many"
+	Soft              bool   `json:"soft,omitempty" bson:"soft,omitempty"`                           // Mark documents deleted instead of removing them; default GETs skip them
+	SoftField         string `json:"softField,omitempty" bson:"softField,omitempty"`                 // Field holding the deletion time of soft-deleted documents (default "deletedAt")
+	ArchiveCollection string `json:"archiveCollection,omitempty" bson:"archiveCollection,omitempty"` // (Optional) Move documents to this collection of the same database instead of dropping them
+}
+
+// DownloadOptions configures a definition that streams a stored GridFS file.
+type DownloadOptions struct {
+	IDParam          string                 `json:"idParam,omitempty" bson:"idParam,omitempty"`                   // Request field holding the file ID
+	Filter           map[string]interface{} `json:"filter,omitempty" bson:"filter,omitempty"`                     // Files filter with $variables (used when IDParam is empty)
+	Disposition      string                 `json:"disposition,omitempty" bson:"disposition,omitempty"`           // "attachment" (default) or "inline"
+	RequireSignature bool                   `json:"requireSignature,omitempty" bson:"requireSignature,omitempty"` // Only serve URLs signed by a "presignUrl" action
+}
+
+// AccessControl restricts which client IPs may call a definition.
+type AccessControl struct {
+	AllowCidrs []string `json:"allowCidrs,omitempty" bson:"allowCidrs,omitempty"` // If set, only these ranges are allowed
+	DenyCidrs  []string `json:"denyCidrs,omitempty" bson:"denyCidrs,omitempty"`   // Always rejected (takes precedence over allow)
+}
+
+// IngestOptions enables write-behind buffering for high-throughput ingestion endpoints.
+// Saved documents are queued and written with bulk inserts/upserts on size or interval.
+type IngestOptions struct {
+	BatchSize       int    `json:"batchSize,omitempty" bson:"batchSize,omitempty"`             // Documents per bulk write (default 500)
+	FlushIntervalMs int    `json:"flushIntervalMs,omitempty" bson:"flushIntervalMs,omitempty"` // Max time a document waits in the buffer (default 1000)
+	MaxQueue        int    `json:"maxQueue,omitempty" bson:"maxQueue,omitempty"`               // Buffer capacity (default 10000)
+	OnFull          string `json:"onFull,omitempty" bson:"onFull,omitempty"`                   // "reject" (default, 503 with Retry-After) or "block"
+	WaitForFlush    bool   `json:"waitForFlush,omitempty" bson:"waitForFlush,omitempty"`       // Respond only after the document is written (durable, slower)
+}
+
+// ConcurrencyLimit caps how many requests of one definition execute at the same time.
+// Excess requests wait up to QueueTimeoutMs for a slot, then receive 429 Too Many Requests.
+type ConcurrencyLimit struct {
+	MaxConcurrent  int `json:"maxConcurrent" bson:"maxConcurrent"`                       // Simultaneous executions allowed
+	QueueTimeoutMs int `json:"queueTimeoutMs,omitempty" bson:"queueTimeoutMs,omitempty"` // How long an excess request may wait (default 0, reject immediately)
+	RetryAfter     int `json:"retryAfter,omitempty" bson:"retryAfter,omitempty"`         // Seconds sent in the Retry-After header (default 1)
+}
+
+// FieldPermission grants the callers holding any of Roles (matched against the token's
+// roles/role/scope/scp claims) access to a set of top-level fields. "*" in Roles matches
+// every caller, including anonymous ones; "*" in Read or Write grants every field.
+// A caller's access is the union of all matching entries.
+type FieldPermission struct {
+	Roles []string `json:"roles" bson:"roles"`                     // Roles or scopes this entry applies to
+	Read  []string `json:"read,omitempty" bson:"read,omitempty"`   // Fields returned in responses ("_id" is always returned)
+	Write []string `json:"write,omitempty" bson:"write,omitempty"` // Fields the request body or query may set
+}
+
+// MaintenanceConfig describes a maintenance window returned as 503 Service Unavailable.
+type MaintenanceConfig struct {
+	Enabled    bool                   `json:"enabled" bson:"enabled"`                           // Whether maintenance mode is on
+	Message    string                 `json:"message,omitempty" bson:"message,omitempty"`       // Message for the default response body
+	RetryAfter int                    `json:"retryAfter,omitempty" bson:"retryAfter,omitempty"` // Seconds sent in the Retry-After header
+	Payload    map[string]interface{} `json:"payload,omitempty" bson:"payload,omitempty"`       // (Optional) Custom response body
 }
 
 // Parameter defines an expected parameter for an API endpoint.
@@ -63,6 +461,341 @@ type Parameter struct {
 	Required bool   `json:"required" bson:"required"` // Whether the parameter is mandatory
 }
 
+// ValidationRule defines a rule evaluated against the combined request data before the flow runs.
+type ValidationRule struct {
+	Field   string      `json:"field" bson:"field"`                         // Field being validated (supports dot paths)
+	Rule    string      `json:"rule" bson:"rule"`                           // Rule: "required", "after", "before", "eq", "neq", "gt", "gte", "lt", "lte"
+	Other   string      `json:"other,omitempty" bson:"other,omitempty"`     // Other field to compare against (cross-field rules)
+	Value   interface{} `json:"value,omitempty" bson:"value,omitempty"`     // Literal to compare against when Other is empty
+	When    []Condition `json:"when,omitempty" bson:"when,omitempty"`       // Rule applies only when all these conditions are met
+	Message string      `json:"message,omitempty" bson:"message,omitempty"` // Custom error message
+}
+
+// EventConfig describes a webhook event emitted through the outbox whenever the definition saves data.
+type EventConfig struct {
+	Name       string            `json:"name" bson:"name"`                           // Event name, sent as X-Event-Name
+	WebhookURL string            `json:"webhookUrl" bson:"webhookUrl"`               // Target URL (supports {{field}} templates)
+	Payload    interface{}       `json:"payload,omitempty" bson:"payload,omitempty"` // Body template ($variables); defaults to the saved document
+	Headers    map[string]string `json:"headers,omitempty" bson:"headers,omitempty"` // Extra request headers
+}
+
+// Outbox message statuses.
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxFailed    = "failed"
+)
+
+// OutboxMessage is an event stored alongside the data it describes, relayed by the outbox dispatcher.
+type OutboxMessage struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id"`
+	APIName       string             `json:"apiName" bson:"apiName"`
+	Event         string             `json:"event" bson:"event"`
+	URL           string             `json:"url" bson:"url"`
+	Headers       map[string]string  `json:"headers,omitempty" bson:"headers,omitempty"`
+	Payload       interface{}        `json:"payload" bson:"payload"`
+	RequestID     string             `json:"requestId,omitempty" bson:"requestId,omitempty"`     // X-Request-Id of the request that saved the document
+	TraceParent   string             `json:"traceParent,omitempty" bson:"traceParent,omitempty"` // traceparent sent with the delivery
+	Status        string             `json:"status" bson:"status"`
+	Attempts      int                `json:"attempts" bson:"attempts"`
+	LastError     string             `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	CreatedAt     time.Time          `json:"createdAt" bson:"createdAt"`
+	NextAttemptAt time.Time          `json:"nextAttemptAt" bson:"nextAttemptAt"`
+	DeliveredAt   *time.Time         `json:"deliveredAt,omitempty" bson:"deliveredAt,omitempty"`
+}
+
+// Async job statuses.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// Job tracks one execution of an async definition's flow.
+type Job struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id"`
+	APIName    string             `json:"apiName" bson:"apiName"`
+	Status     string             `json:"status" bson:"status"`                     // "queued", "running", "succeeded" or "failed"
+	Result     interface{}        `json:"result,omitempty" bson:"result,omitempty"` // Flow response once succeeded
+	Error      string             `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+	StartedAt  *time.Time         `json:"startedAt,omitempty" bson:"startedAt,omitempty"`
+	FinishedAt *time.Time         `json:"finishedAt,omitempty" bson:"finishedAt,omitempty"`
+}
+
+// MappingProfile is a named, reusable list of transformations (e.g. "externalOrder→internal")
+// that actions reference through UseMappings, so a shared mapping is maintained in one place.
+type MappingProfile struct {
+	Name        string           `json:"name" bson:"_id"`
+	Description string           `json:"description,omitempty" bson:"description,omitempty"`
+	Transform   []Transformation `json:"transform" bson:"transform"`
+	UpdatedAt   time.Time        `json:"updatedAt" bson:"updatedAt"`
+}
+
+// LookupTable is a managed key-value table (e.g. tax rates per region) that flows read
+// as "$lookup.<name>[<key>]" instead of repeating constants in every definition.
+type LookupTable struct {
+	Name        string                 `json:"name" bson:"_id"`
+	Description string                 `json:"description,omitempty" bson:"description,omitempty"`
+	Values      map[string]interface{} `json:"values" bson:"values"`
+	UpdatedAt   time.Time              `json:"updatedAt" bson:"updatedAt"`
+}
+
+// FeatureFlag toggles branches of flows through the "featureFlag" condition operator.
+// A tenant override wins over an environment override, which wins over Enabled.
+type FeatureFlag struct {
+	Name         string          `json:"name" bson:"_id"`
+	Description  string          `json:"description,omitempty" bson:"description,omitempty"`
+	Enabled      bool            `json:"enabled" bson:"enabled"`                               // Default state
+	Environments map[string]bool `json:"environments,omitempty" bson:"environments,omitempty"` // Per environment (APP_ENV), e.g. {"staging": true}
+	Tenants      map[string]bool `json:"tenants,omitempty" bson:"tenants,omitempty"`           // Per tenant, e.g. {"acme": false}
+	UpdatedAt    time.Time       `json:"updatedAt" bson:"updatedAt"`
+}
+
+// Consumer is a registered client of the generated APIs. Requests name their consumer
+// with the X-Consumer-Key header, and the calls are counted per definition so owners
+// know whom to notify before breaking changes. Only a hash of the key is stored.
+type Consumer struct {
+	Name      string       `json:"name" bson:"_id"`
+	Contact   *ContactInfo `json:"contact,omitempty" bson:"contact,omitempty"`
+	KeyHash   string       `json:"-" bson:"keyHash"`
+	KeyPrefix string       `json:"keyPrefix" bson:"keyPrefix"` // Start of the key, to recognize it in listings
+	CreatedBy string       `json:"createdBy,omitempty" bson:"createdBy,omitempty"`
+	CreatedAt time.Time    `json:"createdAt" bson:"createdAt"`
+	UpdatedAt time.Time    `json:"updatedAt" bson:"updatedAt"`
+}
+
+// ConsumerUsage counts the calls of one consumer to one definition.
+type ConsumerUsage struct {
+	Consumer      string    `json:"consumer" bson:"consumer"`
+	API           string    `json:"api" bson:"api"` // Definition name
+	Calls         int64     `json:"calls" bson:"calls"`
+	FirstCalledAt time.Time `json:"firstCalledAt" bson:"firstCalledAt"`
+	LastCalledAt  time.Time `json:"lastCalledAt" bson:"lastCalledAt"`
+}
+
+// MessageCatalog holds the user-facing messages of one locale (e.g. "th", "en-US") that
+// return data references as $t("key", {...}). Messages may use {{name}} placeholders.
+type MessageCatalog struct {
+	Locale      string            `json:"locale" bson:"_id"`
+	Description string            `json:"description,omitempty" bson:"description,omitempty"`
+	Messages    map[string]string `json:"messages" bson:"messages"`
+	UpdatedAt   time.Time         `json:"updatedAt" bson:"updatedAt"`
+}
+
+// ErrorDefinition is an entry of the error catalog: a stable code (e.g. "ORDER_NOT_FOUND")
+// that flows return through returnError, with its HTTP status and message template.
+type ErrorDefinition struct {
+	Code        string    `json:"code" bson:"_id"`
+	Status      int       `json:"status" bson:"status"`
+	Message     string    `json:"message" bson:"message"` // {{field}} placeholders or a $t("key") reference
+	Description string    `json:"description,omitempty" bson:"description,omitempty"`
+	UpdatedAt   time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+// DefinitionSnapshot is a saved copy of the whole definitions collection that can be
+// restored in one call. The definitions themselves are stored as snapshot items.
+type DefinitionSnapshot struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	Note      string             `json:"note,omitempty" bson:"note,omitempty"`
+	Author    string             `json:"author" bson:"author"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	Count     int                `json:"count" bson:"count"`
+	Names     []string           `json:"names" bson:"names"` // Definition names, sorted
+}
+
+// Migration step types and run statuses.
+const (
+	MigrationTransform   = "transform"
+	MigrationUpdate      = "update"
+	MigrationDelete      = "delete"
+	MigrationCreateIndex = "createIndex"
+	MigrationDropIndex   = "dropIndex"
+
+	MigrationRunning = "running"
+	MigrationApplied = "applied"
+	MigrationFailed  = "failed"
+)
+
+// Migration is a named data change for the collection of one definition, for when its
+// data shape changes (e.g. "0002-split-full-name"). Pending migrations of a definition
+// run in name order, and each is applied once, as recorded in the migration history.
+type Migration struct {
+	Name        string          `json:"name" bson:"_id"`
+	Definition  string          `json:"definition" bson:"definition"` // API definition whose database and collection are migrated
+	Description string          `json:"description,omitempty" bson:"description,omitempty"`
+	Steps       []MigrationStep `json:"steps" bson:"steps"`
+	UpdatedAt   time.Time       `json:"updatedAt" bson:"updatedAt"`
+	Run         *MigrationRun   `json:"run,omitempty" bson:"-"` // From the history, when listed
+}
+
+// MigrationStep is one operation of a migration. Steps are not transactional: a failed
+// migration resumes at the step that failed.
+type MigrationStep struct {
+	Type      string                 `json:"type" bson:"type"`                               // "transform", "update", "delete", "createIndex" or "dropIndex"
+	Filter    map[string]interface{} `json:"filter,omitempty" bson:"filter,omitempty"`       // Documents the step applies to (default: all; required for "delete")
+	Transform []Transformation       `json:"transform,omitempty" bson:"transform,omitempty"` // "transform": applied to each document, which is then replaced
+	Update    map[string]interface{} `json:"update,omitempty" bson:"update,omitempty"`       // "update": update operators, e.g. {"$rename": {"fullName": "name"}}
+	Index     *MigrationIndex        `json:"index,omitempty" bson:"index,omitempty"`         // "createIndex" and "dropIndex" (only Name is used to drop)
+}
+
+// MigrationIndex describes an index created or dropped by a migration step.
+type MigrationIndex struct {
+	Name   string   `json:"name" bson:"name"`
+	Fields []string `json:"fields,omitempty" bson:"fields,omitempty"` // In order; "-field" for descending
+	Unique bool     `json:"unique,omitempty" bson:"unique,omitempty"`
+}
+
+// MigrationRun is the history record of applying a migration.
+type MigrationRun struct {
+	Migration      string     `json:"migration" bson:"_id"`
+	Definition     string     `json:"definition" bson:"definition"`
+	Status         string     `json:"status" bson:"status"`                 // "running", "applied" or "failed"
+	CompletedSteps int        `json:"completedSteps" bson:"completedSteps"` // A failed run resumes here
+	Affected       []int64    `json:"affected" bson:"affected"`             // Documents changed per completed step
+	Error          string     `json:"error,omitempty" bson:"error,omitempty"`
+	AppliedBy      string     `json:"appliedBy" bson:"appliedBy"`
+	StartedAt      time.Time  `json:"startedAt" bson:"startedAt"`
+	FinishedAt     *time.Time `json:"finishedAt,omitempty" bson:"finishedAt,omitempty"`
+}
+
+// FlowFragment is a named, reusable flow (e.g. "validate-customer") that definitions run
+// through a "callFlow" action. It sees only the parameters passed to it.
+type FlowFragment struct {
+	Name        string            `json:"name" bson:"_id"`
+	Description string            `json:"description,omitempty" bson:"description,omitempty"`
+	Parameters  []string          `json:"parameters,omitempty" bson:"parameters,omitempty"` // (Optional) Parameters the caller must pass
+	Flow        *ConditionalBlock `json:"flow" bson:"flow"`
+	UpdatedAt   time.Time         `json:"updatedAt" bson:"updatedAt"`
+}
+
+// CallFlowConfig configures a "callFlow" action. The fragment's response is stored in
+// ResultField; a response with a statusCode of 400 or more ends the caller's flow with it.
+type CallFlowConfig struct {
+	Fragment    string                 `json:"fragment" bson:"fragment"`                           // Name of the FlowFragment
+	Parameters  map[string]interface{} `json:"parameters,omitempty" bson:"parameters,omitempty"`   // Fragment input, values may be "$field" references
+	ResultField string                 `json:"resultField,omitempty" bson:"resultField,omitempty"` // Field to store the fragment's response in (default "result")
+}
+
+// DbUpdateConfig configures a "dbUpdate" action: update operators applied atomically to
+// the document of the definition's collection whose UniqueKey has the given value, so
+// counters and lists change without a read-modify-write race. With Expect the update is
+// a compare-and-set: it only applies while the document still has the expected values,
+// otherwise OnConflict runs with the current document in ConflictField.
+type DbUpdateConfig struct {
+	Key           interface{}            `json:"key,omitempty" bson:"key,omitempty"`                     // Unique key value, literal or "$field" (default: the UniqueKey field of the data state)
+	Update        map[string]interface{} `json:"update" bson:"update"`                                   // Operators, e.g. {"$inc": {"views": 1}, "$addToSet": {"tags": "$tag"}}; values may be "$field" references
+	Expect        map[string]interface{} `json:"expect,omitempty" bson:"expect,omitempty"`               // (Optional) Expected current values, e.g. {"status": "pending"} or {"stock": {"$gte": "$quantity"}}
+	Upsert        bool                   `json:"upsert,omitempty" bson:"upsert,omitempty"`               // Create the document when none has the key (not with Expect)
+	ResultField   string                 `json:"resultField,omitempty" bson:"resultField,omitempty"`     // Field to store the updated document in (default "updated"); left unset when no document has the key
+	ConflictField string                 `json:"conflictField,omitempty" bson:"conflictField,omitempty"` // Field to store the current document in on a conflict (default "conflict")
+	OnConflict    *ActionDefinition      `json:"onConflict,omitempty" bson:"onConflict,omitempty"`       // (Optional) Action when the document does not have the expected values (default 409 error)
+}
+
+// ModifyConfig configures a "findAndModify" action: one document of the definition's
+// collection matching Filter is updated or deleted in a single atomic step, e.g. to
+// claim the next pending job or decrement stock only while some is left.
+type ModifyConfig struct {
+	Filter       map[string]interface{} `json:"filter" bson:"filter"`                                 // Query, values may be "$field" references, e.g. {"status": "pending"} or {"stock": {"$gte": "$quantity"}}
+	Sort         []string               `json:"sort,omitempty" bson:"sort,omitempty"`                 // Which document to pick when several match, "-field" for descending
+	Update       map[string]interface{} `json:"update,omitempty" bson:"update,omitempty"`             // Update operators as in dbUpdate; required unless Delete
+	Delete       bool                   `json:"delete,omitempty" bson:"delete,omitempty"`             // Remove the document instead of updating it
+	Upsert       bool                   `json:"upsert,omitempty" bson:"upsert,omitempty"`             // Insert a document when none matches (update only)
+	ReturnBefore bool                   `json:"returnBefore,omitempty" bson:"returnBefore,omitempty"` // Store the document as it was before the update instead of after
+	ResultField  string                 `json:"resultField,omitempty" bson:"resultField,omitempty"`   // Field to store the document in (default "document"); left unset when none matched
+}
+
+// OTPCode is a one-time code issued by a "generateOtp" action. Only a salted hash of
+// the code is stored; ID is derived from the purpose and subject.
+type OTPCode struct {
+	ID          string    `json:"id" bson:"_id"`
+	Purpose     string    `json:"purpose" bson:"purpose"`
+	Salt        string    `json:"-" bson:"salt"`
+	Hash        string    `json:"-" bson:"hash"`
+	Attempts    int       `json:"attempts" bson:"attempts"`       // Guesses so far
+	MaxAttempts int       `json:"maxAttempts" bson:"maxAttempts"` // Guesses allowed before the code is locked
+	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
+	ExpiresAt   time.Time `json:"expiresAt" bson:"expiresAt"` // Also drives the TTL index
+}
+
+// Dead letter statuses.
+const (
+	DeadLetterPending  = "pending"
+	DeadLetterReplayed = "replayed"
+)
+
+// DeadLetter holds a payload whose save or delivery failed after retries, for inspection and replay.
+type DeadLetter struct {
+	ID           primitive.ObjectID       `json:"id" bson:"_id"`
+	Source       string                   `json:"source" bson:"source"` // What failed, e.g. "save" or "ingest"
+	APIName      string                   `json:"apiName" bson:"apiName"`
+	Database     string                   `json:"database" bson:"database"`
+	Collection   string                   `json:"collection" bson:"collection"`
+	UniqueKey    string                   `json:"uniqueKey,omitempty" bson:"uniqueKey,omitempty"`
+	ScopeKeys    []string                 `json:"scopeKeys,omitempty" bson:"scopeKeys,omitempty"` // dataScope fields included in upsert filters
+	Documents    []map[string]interface{} `json:"documents" bson:"documents"`
+	Error        string                   `json:"error" bson:"error"`
+	Attempts     int                      `json:"attempts" bson:"attempts"`
+	Status       string                   `json:"status" bson:"status"` // "pending" or "replayed"
+	CreatedAt    time.Time                `json:"createdAt" bson:"createdAt"`
+	LastReplayAt *time.Time               `json:"lastReplayAt,omitempty" bson:"lastReplayAt,omitempty"`
+}
+
+// Revision statuses and actions of the approval workflow.
+const (
+	RevisionPending   = "pending"
+	RevisionPublished = "published"
+	RevisionRejected  = "rejected"
+
+	RevisionCreate = "create"
+	RevisionUpdate = "update"
+	RevisionDelete = "delete"
+)
+
+// Revision is a proposed definition change waiting for approval before it is published.
+type Revision struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id"`
+	APIName    string             `json:"apiName" bson:"apiName"`
+	Action     string             `json:"action" bson:"action"`                             // "create", "update" or "delete"
+	Definition *ApiDefinition     `json:"definition,omitempty" bson:"definition,omitempty"` // Proposed definition (nil for delete)
+	Status     string             `json:"status" bson:"status"`                             // "pending", "published" or "rejected"
+	Author     string             `json:"author" bson:"author"`                             // Operator who proposed the change
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+	DecidedAt  *time.Time         `json:"decidedAt,omitempty" bson:"decidedAt,omitempty"`
+	Events     []RevisionEvent    `json:"events" bson:"events"` // Audit trail: submission, comments and decisions
+}
+
+// RevisionEvent is one entry of a revision's audit trail.
+type RevisionEvent struct {
+	Type    string    `json:"type" bson:"type"` // "submitted", "commented", "approved", "rejected" or "publishFailed"
+	Actor   string    `json:"actor" bson:"actor"`
+	Comment string    `json:"comment,omitempty" bson:"comment,omitempty"`
+	At      time.Time `json:"at" bson:"at"`
+}
+
+// Instance is the heartbeat record of one server process sharing the definitions collection.
+type Instance struct {
+	ID           string    `json:"id" bson:"_id"`
+	Hostname     string    `json:"hostname" bson:"hostname"`
+	PID          int       `json:"pid" bson:"pid"`
+	Region       string    `json:"region,omitempty" bson:"region,omitempty"`
+	Invalidation string    `json:"invalidation" bson:"invalidation"` // "changestream", "redis" or "none"
+	Routes       int       `json:"routes" bson:"routes"`             // Route keys currently loaded
+	StartedAt    time.Time `json:"startedAt" bson:"startedAt"`
+	LastSeen     time.Time `json:"lastSeen" bson:"lastSeen"`
+}
+
+// RejectedDefinition records a stored definition that was not loaded into the route cache.
+type RejectedDefinition struct {
+	ID      string   `json:"id,omitempty"`
+	Name    string   `json:"name,omitempty"`
+	Key     string   `json:"key,omitempty"` // Method:Endpoint route key
+	Reasons []string `json:"reasons"`
+	// ConflictsWith names the definition that won a duplicate route key, if that is why it was rejected
+	ConflictsWith string `json:"conflictsWith,omitempty"`
+}
+
 // Represents an error type for "Not Found" scenarios in the database layer.
 type ErrNotFound struct {
 	Resource string